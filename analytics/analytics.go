@@ -0,0 +1,299 @@
+// Package analytics buffers product analytics events and writes them in
+// batches to a Sink (Mongo, Segment, BigQuery). Events are sampled and scrubbed
+// of PII before they are buffered, and the buffer is flushed on shutdown.
+//
+//	analytics.Init(analytics.Options{Sink: analytics.NewMongoSink("")})
+//	analytics.Track(ctx, analytics.Event{Name: "experience_viewed", UserID: id, Props: props})
+//	defer analytics.Shutdown(context.Background())
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// Event is a single analytics event
+type Event struct {
+	ID             string                 `bson:"_id" json:"id"`
+	Name           string                 `bson:"name" json:"name"`
+	UserID         string                 `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	OrganizationID string                 `bson:"organization_id,omitempty" json:"organization_id,omitempty"`
+	Props          map[string]interface{} `bson:"props,omitempty" json:"props,omitempty"`
+	Country        string                 `bson:"country,omitempty" json:"country,omitempty"`
+	Timestamp      time.Time              `bson:"timestamp" json:"timestamp"`
+}
+
+// ErrInvalidSampleRate is returned for sample rates outside 0 to 1
+var ErrInvalidSampleRate = errors.New("sample rate must be between 0 and 1")
+
+// Sink stores batches of events
+type Sink interface {
+	Name() string
+	Write(ctx context.Context, events []Event) error
+}
+
+// Options configures a Tracker
+type Options struct {
+	Sink          Sink
+	BatchSize     int           // Events per sink write (default 100)
+	FlushInterval time.Duration // Maximum time an event waits in the buffer (default 5s)
+	BufferSize    int           // Events held before new ones are dropped (default 10000)
+
+	// SampleRates keeps the given fraction (0 to 1) of events by name;
+	// DefaultSampleRate applies to other names (nil keeps everything, 0
+	// drops everything)
+	SampleRates       map[string]float64
+	DefaultSampleRate *float64
+
+	// Scrubber removes PII from props; nil uses DefaultScrubber
+	Scrubber *Scrubber
+
+	RetryPolicy *utils.RetryPolicy // Sink write retries (default utils.DefaultRetryPolicy)
+}
+
+// Tracker buffers events and writes them to its sink in the background
+type Tracker struct {
+	options Options
+	events  chan Event
+	flushes chan chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// NewTracker starts a tracker; call Close to flush and stop it. Sample rates
+// outside 0 to 1 are rejected with ErrInvalidSampleRate.
+func NewTracker(options Options) (*Tracker, error) {
+	if rate := options.DefaultSampleRate; rate != nil && (*rate < 0 || *rate > 1) {
+		return nil, fmt.Errorf("%w: default %v", ErrInvalidSampleRate, *rate)
+	}
+	for name, rate := range options.SampleRates {
+		if rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("%w: %s %v", ErrInvalidSampleRate, name, rate)
+		}
+	}
+
+	if options.BatchSize <= 0 {
+		options.BatchSize = 100
+	}
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = 5 * time.Second
+	}
+	if options.BufferSize <= 0 {
+		options.BufferSize = 10000
+	}
+	if options.DefaultSampleRate == nil {
+		keepAll := 1.0
+		options.DefaultSampleRate = &keepAll
+	}
+	if options.Scrubber == nil {
+		options.Scrubber = DefaultScrubber()
+	}
+	if options.RetryPolicy == nil {
+		policy := utils.DefaultRetryPolicy
+		options.RetryPolicy = &policy
+	}
+
+	tracker := &Tracker{
+		options: options,
+		events:  make(chan Event, options.BufferSize),
+		flushes: make(chan chan struct{}),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go tracker.run()
+	return tracker, nil
+}
+
+// Track samples, scrubs and buffers an event. It never blocks: when the buffer
+// is full the event is dropped and counted in analytics_events_dropped_total.
+func (t *Tracker) Track(ctx context.Context, event Event) {
+	if event.Name == "" {
+		return
+	}
+	if !t.sampled(event.Name) {
+		eventsSampledOut.WithLabelValues(event.Name).Inc()
+		return
+	}
+
+	if event.ID == "" {
+		event.ID = utils.NewID()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = utils.Now()
+	}
+	if location := utils.GeoLocationFromContext(ctx); location != nil && event.Country == "" {
+		event.Country = location.Country
+	}
+	event.Props = t.options.Scrubber.Scrub(event.Props)
+
+	select {
+	case <-t.done:
+		eventsDropped.WithLabelValues("closed").Inc()
+	default:
+		select {
+		case t.events <- event:
+		default:
+			eventsDropped.WithLabelValues("buffer_full").Inc()
+		}
+	}
+}
+
+// Flush writes all buffered events and waits until the sink has been called
+func (t *Tracker) Flush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case t.flushes <- ack:
+	case <-t.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close flushes remaining events and stops the tracker, giving up when ctx ends
+func (t *Tracker) Close(ctx context.Context) error {
+	t.once.Do(func() { close(t.done) })
+
+	select {
+	case <-t.stopped:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("analytics shutdown: %w", ctx.Err())
+	}
+}
+
+func (t *Tracker) sampled(name string) bool {
+	rate, ok := t.options.SampleRates[name]
+	if !ok {
+		rate = *t.options.DefaultSampleRate
+	}
+	return rate >= 1 || rand.Float64() < rate
+}
+
+func (t *Tracker) run() {
+	defer close(t.stopped)
+
+	ticker := time.NewTicker(t.options.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, t.options.BatchSize)
+	write := func() {
+		if len(batch) == 0 {
+			return
+		}
+		t.write(batch)
+		batch = make([]Event, 0, t.options.BatchSize)
+	}
+	drain := func() {
+		for {
+			select {
+			case event := <-t.events:
+				batch = append(batch, event)
+				if len(batch) >= t.options.BatchSize {
+					write()
+				}
+			default:
+				write()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case event := <-t.events:
+			batch = append(batch, event)
+			if len(batch) >= t.options.BatchSize {
+				write()
+			}
+		case <-ticker.C:
+			write()
+		case ack := <-t.flushes:
+			drain()
+			close(ack)
+		case <-t.done:
+			drain()
+			return
+		}
+	}
+}
+
+// write sends a batch to the sink with retries; failed batches are logged and dropped
+func (t *Tracker) write(batch []Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	sink := t.options.Sink
+	err := utils.Retry(ctx, *t.options.RetryPolicy, func() error {
+		return sink.Write(ctx, batch)
+	})
+	if err != nil {
+		eventsDropped.WithLabelValues("sink_error").Add(float64(len(batch)))
+		log.Printf("❌ Failed to write %d analytics events to %s: %v", len(batch), sink.Name(), err)
+		return
+	}
+	eventsWritten.WithLabelValues(sink.Name()).Add(float64(len(batch)))
+}
+
+var (
+	defaultTracker *Tracker
+	defaultMu      sync.RWMutex
+)
+
+// Init starts the package-level tracker used by Track
+func Init(options Options) error {
+	tracker, err := NewTracker(options)
+	if err != nil {
+		return err
+	}
+
+	defaultMu.Lock()
+	previous := defaultTracker
+	defaultTracker = tracker
+	defaultMu.Unlock()
+
+	if previous != nil {
+		previous.Close(context.Background())
+	}
+	log.Printf("📊 Analytics tracking enabled (sink: %s)", options.Sink.Name())
+	return nil
+}
+
+// Track records an event on the package-level tracker; it is a no-op before Init
+func Track(ctx context.Context, event Event) {
+	defaultMu.RLock()
+	tracker := defaultTracker
+	defaultMu.RUnlock()
+
+	if tracker != nil {
+		tracker.Track(ctx, event)
+	}
+}
+
+// Shutdown flushes and stops the package-level tracker
+func Shutdown(ctx context.Context) error {
+	defaultMu.Lock()
+	tracker := defaultTracker
+	defaultTracker = nil
+	defaultMu.Unlock()
+
+	if tracker == nil {
+		return nil
+	}
+	return tracker.Close(ctx)
+}
@@ -0,0 +1,23 @@
+package analytics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	eventsWritten = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "analytics_events_written_total",
+		Help: "Analytics events written, partitioned by sink.",
+	}, []string{"sink"})
+
+	eventsDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "analytics_events_dropped_total",
+		Help: "Analytics events dropped, partitioned by reason.",
+	}, []string{"reason"})
+
+	eventsSampledOut = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "analytics_events_sampled_out_total",
+		Help: "Analytics events skipped by sampling, partitioned by event name.",
+	}, []string{"event"})
+)
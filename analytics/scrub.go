@@ -0,0 +1,100 @@
+package analytics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces scrubbed values
+const Redacted = "[redacted]"
+
+var (
+	emailPattern = regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`)
+	phonePattern = regexp.MustCompile(`\+?\d[\d\s().\-]{8,}\d`)
+)
+
+// Scrubber removes personal data from event props before they leave the process
+type Scrubber struct {
+	// DropKeys are removed entirely (matched case-insensitively)
+	DropKeys []string
+	// HashKeys are replaced by a SHA-256 hash so they can still be joined on
+	HashKeys []string
+	// RedactPatterns replaces email addresses and phone numbers inside string values
+	RedactPatterns bool
+}
+
+// DefaultScrubber drops credentials and contact details, hashes identifiers and
+// redacts emails and phone numbers embedded in free text
+func DefaultScrubber() *Scrubber {
+	return &Scrubber{
+		DropKeys: []string{
+			"password", "token", "access_token", "refresh_token", "secret", "authorization",
+			"email", "phone", "phone_number", "address", "full_name", "first_name", "last_name",
+			"ssn", "credit_card", "card_number", "ip", "ip_address",
+		},
+		HashKeys:       []string{"user_email", "device_id"},
+		RedactPatterns: true,
+	}
+}
+
+// Scrub returns a cleaned copy of props; nested maps and slices are scrubbed too
+func (s *Scrubber) Scrub(props map[string]interface{}) map[string]interface{} {
+	if props == nil {
+		return nil
+	}
+
+	cleaned := make(map[string]interface{}, len(props))
+	for key, value := range props {
+		lower := strings.ToLower(key)
+		switch {
+		case containsKey(s.DropKeys, lower):
+			continue
+		case containsKey(s.HashKeys, lower):
+			cleaned[key] = hashValue(value)
+		default:
+			cleaned[key] = s.scrubValue(value)
+		}
+	}
+	return cleaned
+}
+
+func (s *Scrubber) scrubValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return s.Scrub(v)
+	case []interface{}:
+		cleaned := make([]interface{}, len(v))
+		for i, item := range v {
+			cleaned[i] = s.scrubValue(item)
+		}
+		return cleaned
+	case string:
+		if !s.RedactPatterns {
+			return v
+		}
+		v = emailPattern.ReplaceAllString(v, Redacted)
+		return phonePattern.ReplaceAllString(v, Redacted)
+	default:
+		return v
+	}
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, candidate := range keys {
+		if strings.ToLower(candidate) == key {
+			return true
+		}
+	}
+	return false
+}
+
+func hashValue(value interface{}) string {
+	text, ok := value.(string)
+	if !ok {
+		return Redacted
+	}
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(text))))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,154 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultCollection stores events written by MongoSink
+const DefaultCollection = "analytics_events"
+
+// MongoSink writes events to a Mongo collection
+type MongoSink struct {
+	Collection string
+}
+
+// NewMongoSink writes to collection, or DefaultCollection when empty
+func NewMongoSink(collection string) *MongoSink {
+	if collection == "" {
+		collection = DefaultCollection
+	}
+	return &MongoSink{Collection: collection}
+}
+
+// Name identifies the sink in metrics and logs
+func (s *MongoSink) Name() string {
+	return "mongo"
+}
+
+// Write inserts the batch; events already stored by an earlier retry are skipped
+func (s *MongoSink) Write(ctx context.Context, events []Event) error {
+	documents := make([]interface{}, len(events))
+	for i, event := range events {
+		documents[i] = event
+	}
+
+	_, err := config.GetCollection(s.Collection).InsertMany(ctx, documents, options.InsertMany().SetOrdered(false))
+	if err != nil && isOnlyDuplicateKeys(err) {
+		return nil
+	}
+	return err
+}
+
+func isOnlyDuplicateKeys(err error) bool {
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		return false
+	}
+	for _, writeErr := range bulkErr.WriteErrors {
+		if writeErr.Code != 11000 {
+			return false
+		}
+	}
+	return true
+}
+
+// SegmentEndpoint is the Segment HTTP tracking batch API
+const SegmentEndpoint = "https://api.segment.io/v1/batch"
+
+// SegmentSink forwards events to Segment as track calls
+type SegmentSink struct {
+	WriteKey string
+	Endpoint string
+	Client   *http.Client
+}
+
+// NewSegmentSink creates a sink using a Segment source write key
+func NewSegmentSink(writeKey string) *SegmentSink {
+	return &SegmentSink{
+		WriteKey: writeKey,
+		Endpoint: SegmentEndpoint,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name identifies the sink in metrics and logs
+func (s *SegmentSink) Name() string {
+	return "segment"
+}
+
+// Write sends the batch; 4xx responses other than 429 are not retried
+func (s *SegmentSink) Write(ctx context.Context, events []Event) error {
+	type segmentTrack struct {
+		Type        string                 `json:"type"`
+		MessageID   string                 `json:"messageId"`
+		Event       string                 `json:"event"`
+		UserID      string                 `json:"userId,omitempty"`
+		AnonymousID string                 `json:"anonymousId,omitempty"`
+		Properties  map[string]interface{} `json:"properties,omitempty"`
+		Context     map[string]interface{} `json:"context,omitempty"`
+		Timestamp   time.Time              `json:"timestamp"`
+	}
+
+	batch := make([]segmentTrack, len(events))
+	for i, event := range events {
+		track := segmentTrack{
+			Type:       "track",
+			MessageID:  event.ID,
+			Event:      event.Name,
+			UserID:     event.UserID,
+			Properties: event.Props,
+			Timestamp:  event.Timestamp,
+		}
+		// Segment requires either a user or an anonymous ID
+		if track.UserID == "" {
+			track.AnonymousID = event.ID
+		}
+		if event.OrganizationID != "" || event.Country != "" {
+			track.Context = map[string]interface{}{}
+			if event.OrganizationID != "" {
+				track.Context["groupId"] = event.OrganizationID
+			}
+			if event.Country != "" {
+				track.Context["location"] = map[string]string{"country": event.Country}
+			}
+		}
+		batch[i] = track
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"batch": batch})
+	if err != nil {
+		return utils.PermanentError(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return utils.PermanentError(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(s.WriteKey, "")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("segment returned status %d", resp.StatusCode)
+	}
+	if resp.StatusCode >= 300 {
+		return utils.PermanentError(fmt.Errorf("segment rejected batch with status %d", resp.StatusCode))
+	}
+	return nil
+}
@@ -0,0 +1,498 @@
+// Package antivirus scans uploaded files with ClamAV (clamd). Small uploads
+// can be checked before they are accepted, with ScanReader or the
+// middleware.ScanUploads middleware; stored files are scanned asynchronously
+// by workers that pick scans up from the message bus:
+//
+//	antivirus.RegisterOpener("gs", func(ctx context.Context, location string) (io.ReadCloser, error) {
+//		bucket, object, _ := strings.Cut(strings.TrimPrefix(location, "gs://"), "/")
+//		return gcs.Bucket(bucket).Object(object).NewReader(ctx)
+//	})
+//	antivirus.SetQuarantine(moveToQuarantineBucket)
+//
+//	scan, err := antivirus.Enqueue(ctx, antivirus.Spec{
+//		OrganizationID: organizationID,
+//		Location:       "gs://uploads/raw.glb",
+//		Filename:       "model.glb",
+//	}, userID)
+//
+// Worker processes call StartWorker and StartReaper, which picks up scans
+// whose worker died mid-scan once its lease expires. Services serve a file only once its scan
+// is clean (IsClean); verdicts are published on ScannedSubject, and infected
+// files additionally on DetectedSubject.
+package antivirus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/messaging"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/repo"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Message bus subjects and the queue group workers share
+const (
+	ScansSubject    = "antivirus.scans"
+	ScannedSubject  = "antivirus.scanned"  // Every verdict
+	DetectedSubject = "antivirus.detected" // Infected files
+	WorkerGroup     = "antivirus-workers"
+)
+
+// ErrUnknownOpener is returned for locations whose scheme has no registered opener
+var ErrUnknownOpener = errors.New("no opener for file location")
+
+// MaxAttempts is how often a scan is started before it fails for good
+var MaxAttempts = 3
+
+// LeaseTimeout is how long a worker may go without renewing its claim on a
+// scan before the reaper hands the scan to another worker
+var LeaseTimeout = 5 * time.Minute
+
+// Verdict is a scanner's report on a file
+type Verdict struct {
+	Infected  bool
+	Signature string // Malware name, when infected
+}
+
+// Scanner scans file contents; Clamd is the default
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (Verdict, error)
+}
+
+// Opener reads a stored file for scanning
+type Opener func(ctx context.Context, location string) (io.ReadCloser, error)
+
+// Quarantine moves an infected file out of reach, e.g. to a locked bucket,
+// and returns its new location
+type Quarantine func(ctx context.Context, scan *models.FileScan) (string, error)
+
+// Spec describes a stored file to scan
+type Spec struct {
+	OrganizationID string
+	Location       string // e.g. gs://uploads/raw.glb; its scheme selects the opener
+	Filename       string // As uploaded, for reviewers
+}
+
+var (
+	store = repo.New[models.FileScan](models.FileScan{}.CollectionName(), repo.WithULIDKeys())
+
+	scanner    Scanner
+	quarantine Quarantine
+	openers    = map[string]Opener{"file": openFile}
+	mu         sync.RWMutex
+)
+
+// scanMessage is published on ScansSubject
+type scanMessage struct {
+	ScanID string `json:"scan_id"`
+}
+
+// SetScanner replaces the scanner, which is otherwise a Clamd configured by
+// NewClamdFromConfig
+func SetScanner(s Scanner) {
+	mu.Lock()
+	defer mu.Unlock()
+	scanner = s
+}
+
+// SetQuarantine sets how infected files are quarantined. Without it infected
+// files stay where they are, marked infected, and services act on
+// DetectedSubject.
+func SetQuarantine(q Quarantine) {
+	mu.Lock()
+	defer mu.Unlock()
+	quarantine = q
+}
+
+// RegisterOpener makes files at locations of a URI scheme readable by workers.
+// "file" (local paths) is built in.
+func RegisterOpener(scheme string, opener Opener) {
+	mu.Lock()
+	defer mu.Unlock()
+	openers[scheme] = opener
+}
+
+func currentScanner() (Scanner, error) {
+	mu.RLock()
+	s := scanner
+	mu.RUnlock()
+	if s != nil {
+		return s, nil
+	}
+	return NewClamdFromConfig()
+}
+
+func lookupOpener(location string) (Opener, error) {
+	scheme := "file"
+	if parsed, err := url.Parse(location); err == nil && parsed.Scheme != "" {
+		scheme = parsed.Scheme
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	opener, ok := openers[scheme]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownOpener, location)
+	}
+	return opener, nil
+}
+
+func openFile(_ context.Context, location string) (io.ReadCloser, error) {
+	return os.Open(strings.TrimPrefix(location, "file://"))
+}
+
+// ScanReader scans content synchronously, e.g. an upload before it is stored
+func ScanReader(ctx context.Context, r io.Reader) (Verdict, error) {
+	s, err := currentScanner()
+	if err != nil {
+		return Verdict{}, err
+	}
+	return s.Scan(ctx, r)
+}
+
+// Enqueue stores a pending scan of a stored file and publishes it for the
+// workers
+func Enqueue(ctx context.Context, spec Spec, createdBy string) (*models.FileScan, error) {
+	if spec.Location == "" {
+		return nil, fmt.Errorf("file location is required")
+	}
+	if _, err := lookupOpener(spec.Location); err != nil {
+		return nil, err
+	}
+
+	now := utils.Now()
+	scan := &models.FileScan{
+		ID:             utils.NewID(),
+		OrganizationID: spec.OrganizationID,
+		Location:       spec.Location,
+		Filename:       spec.Filename,
+		Status:         models.FileScanPending,
+		CreatedBy:      createdBy,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := store.Insert(ctx, scan); err != nil {
+		return nil, fmt.Errorf("failed to store file scan: %w", err)
+	}
+
+	if err := messaging.Publish(ctx, ScansSubject, scanMessage{ScanID: scan.ID}); err != nil {
+		// Nothing will pick the scan up, so it must not stay pending
+		finish(ctx, scan, Verdict{}, "failed to enqueue: "+err.Error())
+		return nil, fmt.Errorf("failed to enqueue file scan: %w", err)
+	}
+	return scan, nil
+}
+
+// Get returns a scan of organizationID; others are repo.ErrNotFound
+func Get(ctx context.Context, id, organizationID string) (*models.FileScan, error) {
+	if !utils.IsValidID(id) {
+		return nil, repo.ErrNotFound
+	}
+	return store.FindOne(ctx, bson.M{"_id": id, "organization_id": organizationID})
+}
+
+// List returns a page of an organization's scans, optionally of one status,
+// newest first
+func List(ctx context.Context, organizationID, status string, page repo.Page) (*repo.PageResult[models.FileScan], error) {
+	filter := bson.M{"organization_id": organizationID}
+	if status != "" {
+		filter["status"] = status
+	}
+	page.Sort = bson.D{{Key: "created_at", Value: -1}}
+	return store.Find(ctx, filter, page)
+}
+
+// IsClean reports whether the latest scan of the file at location found it
+// clean. Files that are unscanned, pending or failed are not clean.
+func IsClean(ctx context.Context, location string) (bool, error) {
+	var scan models.FileScan
+	err := store.Collection().FindOne(ctx, bson.M{"location": location},
+		options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	).Decode(&scan)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return scan.Safe(), nil
+}
+
+// StartWorker consumes pending scans in WorkerGroup, so each file is scanned
+// by one worker
+func StartWorker() (messaging.Subscription, error) {
+	return messaging.Subscribe(ScansSubject, WorkerGroup, messaging.Typed(
+		func(ctx context.Context, message scanMessage, _ *messaging.Envelope) error {
+			return process(ctx, message.ScanID)
+		}))
+}
+
+// process claims a pending scan and runs it. A scanner that cannot be reached
+// puts the scan back and fails the message, so the bus retries it, until the
+// scan has been tried MaxAttempts times; files that cannot be read or scanned
+// fail the scan. Redelivered messages find the scan
+// already claimed or done and are ignored.
+func process(ctx context.Context, id string) error {
+	now := utils.Now()
+	var scan models.FileScan
+	err := store.Collection().FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "status": models.FileScanPending},
+		bson.M{
+			"$set": bson.M{"status": models.FileScanScanning, "updated_at": now, "lease_expires_at": now.Add(LeaseTimeout)},
+			"$inc": bson.M{"attempts": 1},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&scan)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	s, err := currentScanner()
+	if err != nil {
+		finish(ctx, &scan, Verdict{}, err.Error())
+		return nil
+	}
+	opener, err := lookupOpener(scan.Location)
+	if err != nil {
+		finish(ctx, &scan, Verdict{}, err.Error())
+		return nil
+	}
+	file, err := opener(ctx, scan.Location)
+	if err != nil {
+		finish(ctx, &scan, Verdict{}, "failed to open file: "+err.Error())
+		return nil
+	}
+	defer file.Close()
+
+	stopRenewing := renewLease(ctx, scan.ID)
+	verdict, err := s.Scan(ctx, file)
+	stopRenewing()
+	if errors.Is(err, ErrTooLarge) {
+		finish(ctx, &scan, Verdict{}, err.Error())
+		return nil
+	}
+	if err != nil && scan.Attempts >= MaxAttempts {
+		finish(ctx, &scan, Verdict{}, "scanner unavailable: "+err.Error())
+		return nil
+	}
+	if err != nil {
+		release := bson.M{
+			"$set":   bson.M{"status": models.FileScanPending, "updated_at": utils.Now()},
+			"$unset": bson.M{"lease_expires_at": ""},
+		}
+		if rerr := store.UpdateByID(ctx, scan.ID, release); rerr != nil {
+			utils.Log(ctx).Error("failed to release file scan", "scan_id", scan.ID, "error", rerr)
+		}
+		return fmt.Errorf("failed to scan %s: %w", scan.Location, err)
+	}
+
+	finish(ctx, &scan, verdict, "")
+	return nil
+}
+
+// renewLease extends the lease of a scan every third of LeaseTimeout until the
+// returned function is called
+func renewLease(ctx context.Context, id string) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(LeaseTimeout / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				err := store.UpdateByID(ctx, id, bson.M{"$set": bson.M{"lease_expires_at": utils.Now().Add(LeaseTimeout)}})
+				if err != nil {
+					utils.Log(ctx).Warn("failed to renew file scan lease", "scan_id", id, "error", err)
+				}
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// ReclaimStale puts scans whose worker stopped renewing the lease, e.g.
+// because it crashed mid-scan, back to pending and republishes them. Scans
+// out of attempts fail. It returns the number of scans reclaimed.
+func ReclaimStale(ctx context.Context) (int, error) {
+	now := utils.Now()
+	cursor, err := store.Collection().Find(ctx, bson.M{
+		"status": models.FileScanScanning,
+		"$or": bson.A{
+			bson.M{"lease_expires_at": bson.M{"$lt": now}},
+			// Claimed before scans had leases
+			bson.M{"lease_expires_at": bson.M{"$exists": false}, "updated_at": bson.M{"$lt": now.Add(-LeaseTimeout)}},
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var stale []models.FileScan
+	if err := cursor.All(ctx, &stale); err != nil {
+		return 0, err
+	}
+
+	reclaimed := 0
+	for i := range stale {
+		scan := &stale[i]
+		// Take the scan over only if its worker has not renewed in the meantime
+		filter := bson.M{"_id": scan.ID, "status": models.FileScanScanning, "lease_expires_at": scan.LeaseExpiresAt}
+		if scan.LeaseExpiresAt == nil {
+			filter["lease_expires_at"] = bson.M{"$exists": false}
+		}
+
+		if scan.Attempts >= MaxAttempts {
+			result, err := store.Collection().UpdateOne(ctx, filter,
+				bson.M{"$set": bson.M{"lease_expires_at": now.Add(LeaseTimeout)}})
+			if err != nil {
+				return reclaimed, err
+			}
+			if result.ModifiedCount > 0 {
+				finish(ctx, scan, Verdict{}, "scanner stopped responding")
+				reclaimed++
+			}
+			continue
+		}
+
+		result, err := store.Collection().UpdateOne(ctx, filter, bson.M{
+			"$set":   bson.M{"status": models.FileScanPending, "updated_at": now},
+			"$unset": bson.M{"lease_expires_at": ""},
+		})
+		if err != nil {
+			return reclaimed, err
+		}
+		if result.ModifiedCount == 0 {
+			continue
+		}
+		if err := messaging.Publish(ctx, ScansSubject, scanMessage{ScanID: scan.ID}); err != nil {
+			finish(ctx, scan, Verdict{}, "failed to enqueue: "+err.Error())
+		}
+		reclaimed++
+	}
+	return reclaimed, nil
+}
+
+// StartReaper runs ReclaimStale periodically. Call the returned function to
+// stop the reaper.
+func StartReaper(interval time.Duration) func() {
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				count, err := ReclaimStale(ctx)
+				cancel()
+				if err != nil {
+					utils.LogError(fmt.Sprintf("File scan lease check failed: %v", err))
+				} else if count > 0 {
+					log.Printf("🛡️  Reclaimed %d stalled file scans", count)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		stopOnce.Do(func() { close(stop) })
+	}
+}
+
+// finish records a verdict, quarantines infected files and publishes the
+// outcome. A non-empty failure marks the scan failed.
+func finish(ctx context.Context, scan *models.FileScan, verdict Verdict, failure string) {
+	now := utils.Now()
+	scan.UpdatedAt = now
+	scan.ScannedAt = &now
+
+	switch {
+	case failure != "":
+		scan.Status = models.FileScanFailed
+		scan.Error = failure
+	case verdict.Infected:
+		scan.Status = models.FileScanInfected
+		scan.Signature = verdict.Signature
+		quarantineFile(ctx, scan)
+	default:
+		scan.Status = models.FileScanClean
+	}
+
+	err := store.UpdateByID(ctx, scan.ID, bson.M{"$set": bson.M{
+		"status":         scan.Status,
+		"signature":      scan.Signature,
+		"error":          scan.Error,
+		"quarantined":    scan.Quarantined,
+		"quarantined_at": scan.QuarantinedAt,
+		"scanned_at":     now,
+		"updated_at":     now,
+	}})
+	if err != nil {
+		utils.Log(ctx).Error("failed to record file scan verdict", "scan_id", scan.ID, "error", err)
+	}
+
+	if scan.Status == models.FileScanInfected {
+		utils.LogAuditContext(ctx, "system", "malware_detected", scan.ID, map[string]interface{}{
+			"organization_id": scan.OrganizationID,
+			"location":        scan.Location,
+			"filename":        scan.Filename,
+			"signature":       scan.Signature,
+			"quarantined":     scan.Quarantined,
+			"uploaded_by":     scan.CreatedBy,
+		})
+	}
+
+	if messaging.Default() == nil {
+		return
+	}
+	if err := messaging.Publish(ctx, ScannedSubject, scan); err != nil {
+		utils.Log(ctx).Warn("failed to publish file scan verdict", "scan_id", scan.ID, "error", err)
+	}
+	if scan.Status == models.FileScanInfected {
+		if err := messaging.Publish(ctx, DetectedSubject, scan); err != nil {
+			utils.Log(ctx).Warn("failed to publish malware detection", "scan_id", scan.ID, "error", err)
+		}
+	}
+}
+
+// quarantineFile hands an infected file to the quarantine, if one is set
+func quarantineFile(ctx context.Context, scan *models.FileScan) {
+	mu.RLock()
+	q := quarantine
+	mu.RUnlock()
+	if q == nil {
+		return
+	}
+
+	location, err := q(ctx, scan)
+	if err != nil {
+		utils.Log(ctx).Error("failed to quarantine infected file", "scan_id", scan.ID, "location", scan.Location, "error", err)
+		return
+	}
+	scan.Quarantined = true
+	scan.QuarantinedAt = location
+}
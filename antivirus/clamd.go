@@ -0,0 +1,180 @@
+package antivirus
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/breaker"
+	"github.com/praleedsuvarna/shared-libs/config"
+)
+
+// DefaultClamdAddress is where clamd listens when CLAMD_ADDRESS is not set
+const DefaultClamdAddress = "tcp://localhost:3310"
+
+// chunkSize is the size of the INSTREAM chunks sent to clamd
+const chunkSize = 64 * 1024
+
+// ErrTooLarge is returned for files beyond clamd's StreamMaxLength. They
+// cannot be scanned, so callers must not accept them as clean.
+var ErrTooLarge = errors.New("file exceeds the clamd stream size limit")
+
+// clamdBreaker stops calling clamd while it is down; infected files are
+// verdicts, not failures
+var clamdBreaker = breaker.New("clamd", breaker.Options{})
+
+// Clamd scans files with a ClamAV daemon over its INSTREAM protocol
+type Clamd struct {
+	network string // "tcp" or "unix"
+	address string
+	timeout time.Duration
+}
+
+// NewClamd creates a client of the clamd at address, either
+// "tcp://host:port", "unix:///path/to/clamd.sock" or "host:port". timeout
+// bounds a whole scan, including the upload of the file.
+func NewClamd(address string, timeout time.Duration) (*Clamd, error) {
+	client := &Clamd{network: "tcp", address: address, timeout: timeout}
+	switch {
+	case strings.HasPrefix(address, "tcp://"):
+		client.address = strings.TrimPrefix(address, "tcp://")
+	case strings.HasPrefix(address, "unix://"):
+		client.network = "unix"
+		client.address = strings.TrimPrefix(address, "unix://")
+	case strings.Contains(address, "://"):
+		return nil, fmt.Errorf("unsupported clamd address %q", address)
+	}
+	if client.address == "" {
+		return nil, fmt.Errorf("clamd address is required")
+	}
+	return client, nil
+}
+
+// NewClamdFromConfig creates a client from CLAMD_ADDRESS and CLAMD_TIMEOUT
+// (default 2m)
+func NewClamdFromConfig() (*Clamd, error) {
+	timeout, err := time.ParseDuration(config.GetEnv("CLAMD_TIMEOUT", "2m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CLAMD_TIMEOUT: %w", err)
+	}
+	return NewClamd(config.GetEnv("CLAMD_ADDRESS", DefaultClamdAddress), timeout)
+}
+
+// Ping checks that clamd is up, e.g. for readiness checks
+func (c *Clamd) Ping(ctx context.Context) error {
+	reply, err := c.command(ctx, "zPING\x00", nil)
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("unexpected clamd reply %q", reply)
+	}
+	return nil
+}
+
+// Scan streams r to clamd and returns its verdict
+func (c *Clamd) Scan(ctx context.Context, r io.Reader) (Verdict, error) {
+	var reply string
+	err := clamdBreaker.Execute(func() error {
+		var err error
+		reply, err = c.command(ctx, "zINSTREAM\x00", r)
+		return err
+	})
+	if err != nil {
+		return Verdict{}, err
+	}
+	return parseReply(reply)
+}
+
+// command sends a null-terminated command, then the content of body as
+// INSTREAM chunks when given, and reads the reply
+func (c *Clamd) command(ctx context.Context, command string, body io.Reader) (string, error) {
+	dialer := net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return "", err
+	}
+
+	if _, err := io.WriteString(conn, command); err != nil {
+		return "", fmt.Errorf("failed to send clamd command: %w", err)
+	}
+	if body != nil {
+		if err := writeChunks(conn, body); err != nil {
+			// clamd closes the connection once the stream limit is reached;
+			// its reply says so
+			if reply, readErr := readReply(conn); readErr == nil {
+				return reply, nil
+			}
+			return "", fmt.Errorf("failed to stream file to clamd: %w", err)
+		}
+	}
+	return readReply(conn)
+}
+
+// writeChunks sends body as length-prefixed chunks, ended by an empty chunk
+func writeChunks(w io.Writer, body io.Reader) error {
+	buffer := make([]byte, chunkSize)
+	length := make([]byte, 4)
+	for {
+		n, err := body.Read(buffer)
+		if n > 0 {
+			binary.BigEndian.PutUint32(length, uint32(n))
+			if _, werr := w.Write(length); werr != nil {
+				return werr
+			}
+			if _, werr := w.Write(buffer[:n]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	binary.BigEndian.PutUint32(length, 0)
+	_, err := w.Write(length)
+	return err
+}
+
+// readReply reads a null-terminated reply
+func readReply(r io.Reader) (string, error) {
+	reply, err := bufio.NewReader(r).ReadBytes(0)
+	if err != nil && !(err == io.EOF && len(reply) > 0) {
+		return "", fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return string(bytes.TrimRight(reply, "\x00\n")), nil
+}
+
+// parseReply interprets an INSTREAM reply: "stream: OK",
+// "stream: <signature> FOUND" or "<message> ERROR"
+func parseReply(reply string) (Verdict, error) {
+	result := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case result == "OK":
+		return Verdict{}, nil
+	case strings.HasSuffix(result, " FOUND"):
+		return Verdict{Infected: true, Signature: strings.TrimSuffix(result, " FOUND")}, nil
+	case strings.Contains(result, "size limit exceeded"):
+		return Verdict{}, ErrTooLarge
+	default:
+		return Verdict{}, fmt.Errorf("clamd scan failed: %s", result)
+	}
+}
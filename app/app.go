@@ -0,0 +1,496 @@
+// Package app bootstraps a service with the shared configuration, connections,
+// middleware and health routes so that a service main() stays small:
+//
+//	func main() {
+//		service := app.New(app.Options{
+//			Name:        "user-management",
+//			SetupRoutes: routes.Setup,
+//		})
+//		if err := service.Run(); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/praleedsuvarna/shared-libs/analytics"
+	"github.com/praleedsuvarna/shared-libs/auditsink"
+	"github.com/praleedsuvarna/shared-libs/authz"
+	"github.com/praleedsuvarna/shared-libs/bqexport"
+	"github.com/praleedsuvarna/shared-libs/buildinfo"
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/domains"
+	"github.com/praleedsuvarna/shared-libs/messaging"
+	"github.com/praleedsuvarna/shared-libs/metrics"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+	"github.com/praleedsuvarna/shared-libs/migrate"
+	"github.com/praleedsuvarna/shared-libs/mtls"
+	"github.com/praleedsuvarna/shared-libs/operations"
+	"github.com/praleedsuvarna/shared-libs/params"
+	"github.com/praleedsuvarna/shared-libs/redact"
+	"github.com/praleedsuvarna/shared-libs/repo"
+	"github.com/praleedsuvarna/shared-libs/routes"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"github.com/praleedsuvarna/shared-libs/watchdog"
+)
+
+// Default timeout for draining in-flight requests on shutdown
+const DefaultShutdownTimeout = 15 * time.Second
+
+// Options configures how a service is bootstrapped
+type Options struct {
+	Name            string
+	SetupRoutes     func(app *fiber.App)
+	ConfigOptions   *config.ConfigOptions // nil uses LoadEnv() defaults
+	DisableDatabase bool                  // Skip MongoDB for services without persistence
+	ShutdownTimeout time.Duration
+
+	// RequestTimeout bounds handler execution (REQUEST_TIMEOUT env when zero);
+	// RouteTimeouts overrides it per route, see middleware.Timeout
+	RequestTimeout time.Duration
+	RouteTimeouts  map[string]time.Duration
+
+	// MaxInFlight sheds requests beyond this many concurrent ones with 503
+	// (MAX_IN_FLIGHT_REQUESTS env when zero), see middleware.LoadShedding
+	MaxInFlight int
+}
+
+// App wraps the Fiber application together with its lifecycle hooks
+type App struct {
+	Fiber         *fiber.App
+	options       Options
+	shutdownHooks []func()
+	credentials   *mtls.Credentials // Serves over mutual TLS when set
+}
+
+// New loads configuration, connects dependencies and builds the Fiber application
+func New(options Options) *App {
+	if options.Name == "" {
+		options.Name = "service"
+	}
+	if options.ShutdownTimeout == 0 {
+		options.ShutdownTimeout = DefaultShutdownTimeout
+	}
+
+	// Keep connection string credentials out of the standard logger as well
+	log.SetOutput(redact.Writer(log.Writer()))
+	log.Printf("📦 Starting %s %s", options.Name, buildinfo.Get())
+
+	// Load configuration
+	if options.ConfigOptions != nil {
+		config.LoadEnvWithOptions(*options.ConfigOptions)
+	} else {
+		config.LoadEnv()
+	}
+	if err := utils.ConfigureLogging(); err != nil {
+		log.Printf("⚠️  %v, keeping the default log level", err)
+	}
+
+	// Connect dependencies; NATS and Redis are only used when configured
+	if !options.DisableDatabase {
+		config.ConnectDB()
+	}
+	if config.GetNATSURL() != "" {
+		config.ConnectNATS()
+	}
+	if config.GetRedisURL() != "" {
+		config.ConnectRedis()
+	}
+
+	// Optional GeoIP enrichment of requests and audit entries
+	if path := config.GetEnv("GEOIP_DB_PATH", ""); path != "" {
+		if err := utils.OpenGeoIP(path); err != nil {
+			log.Printf("⚠️  GeoIP disabled: %v", err)
+		}
+	}
+
+	// Event bus on NATS or Pub/Sub, enabled by MESSAGING_DRIVER
+	if config.GetEnv("MESSAGING_DRIVER", "") != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		bus, err := messaging.NewFromConfig(ctx, options.Name)
+		cancel()
+		if err != nil {
+			log.Fatalf("❌ Failed to initialize messaging: %v", err)
+		}
+		messaging.Init(bus)
+		log.Printf("✅ Messaging enabled (driver: %s)", bus.Driver().Name())
+	}
+
+	// Business metrics can be mirrored to the analytics pipeline
+	if subject := config.GetEnv("METRICS_NATS_SUBJECT", ""); subject != "" {
+		metrics.MirrorToNATS(subject)
+	}
+
+	// Custom roles declared in ROLE_DEFINITIONS slot into the built-in hierarchy
+	if err := authz.LoadRolesFromEnv(); err != nil {
+		log.Fatalf("❌ Failed to load role definitions: %v", err)
+	}
+
+	// Record configuration changes and optionally poll for them
+	if !options.DisableDatabase {
+		utils.EnableConfigChangeAudit()
+
+		// Create indexes declared with models.RegisterIndexes; ENSURE_INDEXES=false
+		// leaves them to `sharedctl indexes` for large collections
+		if config.GetEnv("ENSURE_INDEXES", "true") != "false" {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			if _, err := migrate.EnsureIndexes(ctx); err != nil {
+				log.Printf("⚠️  Failed to ensure indexes: %v", err)
+			}
+			cancel()
+		}
+
+		// Compliance-sensitive deployments opt into the tamper-evident audit log
+		if config.GetEnv("AUDIT_HASH_CHAIN", "") == "true" {
+			if err := utils.EnableAuditHashChain(config.GetEnv("AUDIT_GCS_BUCKET", "")); err != nil {
+				log.Fatalf("❌ Failed to enable audit hash chain: %v", err)
+			}
+		}
+	}
+	var stopWatch func()
+	if interval := config.GetEnv("CONFIG_WATCH_INTERVAL", ""); interval != "" {
+		if duration, err := time.ParseDuration(interval); err != nil {
+			log.Printf("⚠️  Invalid CONFIG_WATCH_INTERVAL %q, configuration watching disabled: %v", interval, err)
+		} else {
+			stopWatch = config.Watch(duration)
+		}
+	}
+
+	fiberApp := fiber.New(fiber.Config{
+		AppName:      options.Name,
+		ErrorHandler: errorHandler,
+	})
+
+	// Standard middleware stack
+	fiberApp.Use(recover.New())
+	fiberApp.Use(requestid.New())
+	fiberApp.Use(middleware.ServiceVersion())
+	fiberApp.Use(middleware.RealIP())
+	fiberApp.Use(middleware.RequestLogger())
+	fiberApp.Use(middleware.GeoIP())
+	fiberApp.Use(logger.New(logger.Config{
+		Format: "${time} ${locals:requestid} ${status} - ${latency} ${method} ${path}\n",
+	}))
+	corsConfig := cors.Config{
+		AllowOrigins:     strings.ReplaceAll(config.GetAllowedOrigins(), " ", ""),
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, " + utils.HeaderRequestNonce + ", " + utils.HeaderRequestTimestamp,
+		ExposeHeaders:    strings.Join(middleware.UsageHeaders, ", "),
+		AllowCredentials: true,
+	}
+	if !options.DisableDatabase {
+		// White-label frontends call from their verified custom domains
+		corsConfig.AllowOriginsFunc = domains.AllowOrigin
+	}
+	fiberApp.Use(cors.New(corsConfig))
+	fiberApp.Use(middleware.Metrics())
+	if maxInFlight := resolveMaxInFlight(options.MaxInFlight); maxInFlight > 0 {
+		fiberApp.Use(middleware.LoadShedding(middleware.LoadSheddingOptions{MaxInFlight: maxInFlight}))
+	}
+	fiberApp.Use(middleware.Maintenance())
+	if requestTimeout := resolveRequestTimeout(options.RequestTimeout); requestTimeout > 0 || len(options.RouteTimeouts) > 0 {
+		fiberApp.Use(middleware.Timeout(requestTimeout, options.RouteTimeouts))
+	}
+
+	routes.SetupHealthRoutes(fiberApp)
+	routes.SetupLoggingRoutes(fiberApp)
+	if !options.DisableDatabase {
+		routes.SetupMaintenanceRoutes(fiberApp)
+		if messaging.Default() != nil {
+			routes.SetupDeadLetterRoutes(fiberApp)
+		}
+	}
+
+	if options.SetupRoutes != nil {
+		options.SetupRoutes(fiberApp)
+	}
+
+	service := &App{
+		Fiber:   fiberApp,
+		options: options,
+	}
+	if stopWatch != nil {
+		service.OnShutdown(stopWatch)
+	}
+
+	// Log level changes made on any instance reach all of them
+	if messaging.Default() != nil {
+		subscription, err := messaging.SubscribeLogControl(options.Name)
+		if err != nil {
+			log.Printf("⚠️  Runtime log control disabled: %v", err)
+		} else {
+			service.OnShutdown(func() { subscription.Unsubscribe() })
+		}
+	}
+
+	// Zero-trust deployments serve internal traffic over mutual TLS
+	if config.GetEnv("MTLS_ENABLED", "") == "true" {
+		credentials, err := mtls.NewFromConfig()
+		if err != nil {
+			log.Fatalf("❌ Failed to load mTLS certificates: %v", err)
+		}
+		service.credentials = credentials
+		service.OnShutdown(credentials.Close)
+	}
+	// Long-running workers guard against slow memory and goroutine leaks
+	if config.GetEnv("WATCHDOG_HEAP_LIMIT_MB", "") != "" || config.GetEnv("WATCHDOG_GOROUTINE_LIMIT", "") != "" {
+		if watchdogOptions, err := watchdog.OptionsFromEnv(); err != nil {
+			log.Printf("⚠️  Watchdog disabled: %v", err)
+		} else {
+			service.OnShutdown(watchdog.Start(watchdogOptions))
+		}
+	}
+	if interval := config.GetEnv("CUSTOM_DOMAIN_VERIFY_INTERVAL", ""); interval != "" && !options.DisableDatabase {
+		if duration, err := time.ParseDuration(interval); err != nil {
+			log.Printf("⚠️  Invalid CUSTOM_DOMAIN_VERIFY_INTERVAL %q, domain verification disabled: %v", interval, err)
+		} else {
+			service.OnShutdown(domains.StartVerifier(duration))
+		}
+	}
+	if interval := config.GetEnv("BIGQUERY_AUDIT_EXPORT_INTERVAL", ""); interval != "" && !options.DisableDatabase {
+		if stopExport := startAuditExport(interval); stopExport != nil {
+			service.OnShutdown(stopExport)
+		}
+	}
+	// SOC deployments mirror audit entries to their SIEM
+	if config.GetEnv("AUDIT_SINKS", "") != "" && !options.DisableDatabase {
+		sinks, err := auditsink.SinksFromConfig(options.Name)
+		if err != nil {
+			log.Fatalf("❌ Failed to configure audit sinks: %v", err)
+		}
+		auditsink.Enable(auditsink.Options{}, sinks...)
+		service.OnShutdown(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := auditsink.Shutdown(ctx); err != nil {
+				log.Printf("⚠️  %v", err)
+			}
+		})
+	}
+	if startAnalytics(options) {
+		service.OnShutdown(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := analytics.Shutdown(ctx); err != nil {
+				log.Printf("⚠️  %v", err)
+			}
+		})
+	}
+	if !options.DisableDatabase {
+		// Let background operations record their outcome before the database disconnects
+		service.OnShutdown(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := operations.Wait(ctx); err != nil {
+				log.Printf("⚠️  %v", err)
+			}
+		})
+	}
+
+	return service
+}
+
+// OnShutdown registers a function to run after the server stops accepting requests
+func (a *App) OnShutdown(fn func()) {
+	a.shutdownHooks = append(a.shutdownHooks, fn)
+}
+
+// Run starts the HTTP server and blocks until SIGINT/SIGTERM, then shuts down gracefully
+func (a *App) Run() error {
+	addr := ":" + config.GetPort()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		if a.credentials != nil {
+			log.Printf("🔐 %s listening on %s (mTLS)", a.options.Name, addr)
+			serverErr <- mtls.Listen(a.Fiber, addr, a.credentials)
+			return
+		}
+		log.Printf("🚀 %s listening on %s", a.options.Name, addr)
+		serverErr <- a.Fiber.Listen(addr)
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	select {
+	case err := <-serverErr:
+		a.cleanup()
+		return fmt.Errorf("server stopped: %w", err)
+	case sig := <-quit:
+		log.Printf("🛑 Received %s, shutting down %s...", sig, a.options.Name)
+	}
+
+	err := a.Fiber.ShutdownWithTimeout(a.options.ShutdownTimeout)
+	if err != nil {
+		log.Printf("⚠️  Error during server shutdown: %v", err)
+	}
+
+	a.cleanup()
+	log.Printf("✅ %s stopped", a.options.Name)
+	return err
+}
+
+// cleanup runs shutdown hooks in reverse registration order and closes connections
+func (a *App) cleanup() {
+	for i := len(a.shutdownHooks) - 1; i >= 0; i-- {
+		a.shutdownHooks[i]()
+	}
+
+	if err := messaging.Shutdown(); err != nil {
+		log.Printf("⚠️  Error closing messaging: %v", err)
+	}
+
+	utils.CloseGeoIP()
+	config.DisconnectRedis()
+	config.DisconnectNATS()
+	config.DisconnectDB()
+}
+
+// startAnalytics initializes analytics tracking from ANALYTICS_SINK ("mongo", "segment" or "bigquery")
+func startAnalytics(options Options) bool {
+	var sink analytics.Sink
+	switch config.GetEnv("ANALYTICS_SINK", "") {
+	case "":
+		return false
+	case "mongo":
+		if options.DisableDatabase {
+			log.Println("⚠️  ANALYTICS_SINK=mongo requires the database, analytics disabled")
+			return false
+		}
+		sink = analytics.NewMongoSink(config.GetEnv("ANALYTICS_COLLECTION", ""))
+	case "segment":
+		sink = analytics.NewSegmentSink(config.GetEnv("SEGMENT_WRITE_KEY", ""))
+	case "bigquery":
+		exporter, err := bigQueryExporter()
+		if err != nil {
+			log.Printf("⚠️  BigQuery unavailable, analytics disabled: %v", err)
+			return false
+		}
+		sink = bqexport.NewAnalyticsSink(exporter, "")
+	default:
+		log.Printf("⚠️  Unknown ANALYTICS_SINK %q, analytics disabled", config.GetEnv("ANALYTICS_SINK", ""))
+		return false
+	}
+
+	if err := analytics.Init(analytics.Options{Sink: sink}); err != nil {
+		log.Printf("⚠️  Analytics disabled: %v", err)
+		return false
+	}
+	return true
+}
+
+var (
+	bigQuery     *bqexport.Exporter
+	bigQueryErr  error
+	bigQueryOnce sync.Once
+)
+
+// bigQueryExporter connects to BigQuery once for analytics and audit export
+func bigQueryExporter() (*bqexport.Exporter, error) {
+	bigQueryOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		bigQuery, bigQueryErr = bqexport.NewExporterFromConfig(ctx)
+	})
+	return bigQuery, bigQueryErr
+}
+
+// startAuditExport streams audit logs to BigQuery every interval
+func startAuditExport(interval string) func() {
+	duration, err := time.ParseDuration(interval)
+	if err != nil {
+		log.Printf("⚠️  Invalid BIGQUERY_AUDIT_EXPORT_INTERVAL %q, audit export disabled: %v", interval, err)
+		return nil
+	}
+
+	exporter, err := bigQueryExporter()
+	if err != nil {
+		log.Printf("⚠️  BigQuery unavailable, audit export disabled: %v", err)
+		return nil
+	}
+	return exporter.StartAuditExport(duration)
+}
+
+// resolveRequestTimeout falls back to the REQUEST_TIMEOUT env var when no timeout is configured
+func resolveRequestTimeout(timeout time.Duration) time.Duration {
+	if timeout != 0 {
+		return timeout
+	}
+
+	value := config.GetEnv("REQUEST_TIMEOUT", "")
+	if value == "" {
+		return 0
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("⚠️  Invalid REQUEST_TIMEOUT %q, request timeouts disabled: %v", value, err)
+		return 0
+	}
+	return parsed
+}
+
+// resolveMaxInFlight falls back to the MAX_IN_FLIGHT_REQUESTS env var when no limit is configured
+func resolveMaxInFlight(limit int) int {
+	if limit != 0 {
+		return limit
+	}
+
+	value := config.GetEnv("MAX_IN_FLIGHT_REQUESTS", "")
+	if value == "" {
+		return 0
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("⚠️  Invalid MAX_IN_FLIGHT_REQUESTS %q, load shedding disabled: %v", value, err)
+		return 0
+	}
+	return parsed
+}
+
+// errorHandler renders unhandled errors using the standard {"error": "..."} response shape
+func errorHandler(c *fiber.Ctx, err error) error {
+	code := fiber.StatusInternalServerError
+	message := "Internal server error"
+
+	var paramErr *params.Error
+	if errors.As(err, &paramErr) || errors.Is(err, repo.ErrConflict) {
+		return params.Respond(c, err)
+	}
+
+	if errors.Is(err, repo.ErrOverloaded) {
+		c.Set(fiber.HeaderRetryAfter, "1")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Service is overloaded, please retry later",
+		})
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		code = fiberErr.Code
+		message = fiberErr.Message
+	} else {
+		log.Printf("❌ Unhandled error on %s %s: %v", c.Method(), c.Path(), err)
+	}
+
+	return c.Status(code).JSON(fiber.Map{
+		"error": message,
+	})
+}
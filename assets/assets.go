@@ -0,0 +1,118 @@
+// Package assets inspects uploaded 3D models, binary glTF (GLB) and USDZ, and
+// checks them against limits before an upload is accepted:
+//
+//	metadata, err := assets.Inspect(data)
+//	if err == nil {
+//		err = assets.LimitsFromEnv().Validate(metadata)
+//	}
+package assets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // Texture dimensions
+	_ "image/png"
+)
+
+// Format is the container format of a model
+type Format string
+
+// Supported formats
+const (
+	FormatGLB  Format = "glb"
+	FormatUSDZ Format = "usdz"
+)
+
+// ErrUnsupportedFormat is returned for files that are neither GLB nor USDZ
+var ErrUnsupportedFormat = errors.New("unsupported 3D asset format")
+
+// Texture describes an image embedded in a model. Width and Height are 0 for
+// encodings whose header is not understood, such as KTX2, WebP, EXR or AVIF;
+// such textures mark the model Incomplete, as their size cannot be checked.
+type Texture struct {
+	Name     string `json:"name,omitempty"`
+	MimeType string `json:"mime_type,omitempty"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Bytes    int64  `json:"bytes"`
+}
+
+// Metadata summarizes a model
+type Metadata struct {
+	Format        Format    `json:"format"`
+	Size          int64     `json:"size"`
+	Meshes        int       `json:"meshes"`
+	Vertices      int64     `json:"vertices"`
+	Triangles     int64     `json:"triangles"`
+	Materials     int       `json:"materials"`
+	Textures      []Texture `json:"textures"`
+	HasAnimations bool      `json:"has_animations"`
+
+	// Incomplete is set when part of the file could not be analyzed, such as
+	// the binary USD layers of a USDZ or textures in an encoding that is not
+	// decoded, so Triangles, HasAnimations and texture sizes may be
+	// undercounted; Notes says what was skipped
+	Incomplete bool     `json:"incomplete,omitempty"`
+	Notes      []string `json:"notes,omitempty"`
+}
+
+// MaxTextureDimension returns the largest width or height of the model's textures
+func (m *Metadata) MaxTextureDimension() int {
+	largest := 0
+	for _, texture := range m.Textures {
+		largest = max(largest, texture.Width, texture.Height)
+	}
+	return largest
+}
+
+// DetectFormat identifies a model by its leading bytes
+func DetectFormat(data []byte) (Format, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("glTF")):
+		return FormatGLB, nil
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return FormatUSDZ, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// Inspect reads the metadata of a GLB or USDZ file
+func Inspect(data []byte) (*Metadata, error) {
+	format, err := DetectFormat(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata *Metadata
+	switch format {
+	case FormatGLB:
+		metadata, err = inspectGLB(data)
+	case FormatUSDZ:
+		metadata, err = inspectUSDZ(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("inspect %s: %w", format, err)
+	}
+	metadata.Format = format
+	metadata.Size = int64(len(data))
+	return metadata, nil
+}
+
+// addTexture records an encoded image with its dimensions. Images whose
+// dimensions cannot be read mark the model incomplete.
+func (m *Metadata) addTexture(name, mimeType string, data []byte) {
+	texture := Texture{Name: name, MimeType: mimeType, Bytes: int64(len(data))}
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		m.Incomplete = true
+		m.Notes = append(m.Notes, fmt.Sprintf("dimensions of texture %s could not be read", name))
+	} else {
+		texture.Width, texture.Height = config.Width, config.Height
+		if texture.MimeType == "" {
+			texture.MimeType = "image/" + format
+		}
+	}
+	m.Textures = append(m.Textures, texture)
+}
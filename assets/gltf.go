@@ -0,0 +1,176 @@
+package assets
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// GLB chunk types
+const (
+	glbChunkJSON = 0x4E4F534A
+	glbChunkBIN  = 0x004E4942
+)
+
+// Primitive modes that produce triangles
+const (
+	gltfTriangles     = 4
+	gltfTriangleStrip = 5
+	gltfTriangleFan   = 6
+)
+
+// gltfDocument is the part of the glTF JSON needed for Metadata
+type gltfDocument struct {
+	Accessors []struct {
+		Count int64 `json:"count"`
+	} `json:"accessors"`
+	Meshes []struct {
+		Primitives []struct {
+			Attributes map[string]int `json:"attributes"`
+			Indices    *int           `json:"indices"`
+			Mode       *int           `json:"mode"`
+		} `json:"primitives"`
+	} `json:"meshes"`
+	Materials   []json.RawMessage `json:"materials"`
+	Animations  []json.RawMessage `json:"animations"`
+	BufferViews []struct {
+		Buffer     int   `json:"buffer"`
+		ByteOffset int64 `json:"byteOffset"`
+		ByteLength int64 `json:"byteLength"`
+	} `json:"bufferViews"`
+	Images []struct {
+		Name       string `json:"name"`
+		URI        string `json:"uri"`
+		MimeType   string `json:"mimeType"`
+		BufferView *int   `json:"bufferView"`
+	} `json:"images"`
+}
+
+// inspectGLB parses the GLB header and chunks, then the glTF JSON
+func inspectGLB(data []byte) (*Metadata, error) {
+	if len(data) < 12 {
+		return nil, errors.New("truncated header")
+	}
+	if version := binary.LittleEndian.Uint32(data[4:8]); version != 2 {
+		return nil, fmt.Errorf("glTF version %d is not supported", version)
+	}
+	if length := binary.LittleEndian.Uint32(data[8:12]); int64(length) > int64(len(data)) {
+		return nil, fmt.Errorf("header declares %d bytes, file has %d", length, len(data))
+	}
+
+	var jsonChunk, binChunk []byte
+	for offset := 12; offset+8 <= len(data); {
+		length := int(binary.LittleEndian.Uint32(data[offset : offset+4]))
+		chunkType := binary.LittleEndian.Uint32(data[offset+4 : offset+8])
+		start := offset + 8
+		if length < 0 || start+length > len(data) {
+			return nil, errors.New("truncated chunk")
+		}
+		switch chunkType {
+		case glbChunkJSON:
+			jsonChunk = data[start : start+length]
+		case glbChunkBIN:
+			if binChunk == nil {
+				binChunk = data[start : start+length]
+			}
+		}
+		offset = start + length
+	}
+	if jsonChunk == nil {
+		return nil, errors.New("missing JSON chunk")
+	}
+
+	var doc gltfDocument
+	if err := json.Unmarshal(jsonChunk, &doc); err != nil {
+		return nil, fmt.Errorf("decode JSON chunk: %w", err)
+	}
+
+	metadata := &Metadata{
+		Meshes:        len(doc.Meshes),
+		Materials:     len(doc.Materials),
+		HasAnimations: len(doc.Animations) > 0,
+		Textures:      []Texture{},
+	}
+
+	accessorCount := func(index int) int64 {
+		if index < 0 || index >= len(doc.Accessors) {
+			return 0
+		}
+		return doc.Accessors[index].Count
+	}
+	for _, mesh := range doc.Meshes {
+		for _, primitive := range mesh.Primitives {
+			positions := int64(0)
+			if index, ok := primitive.Attributes["POSITION"]; ok {
+				positions = accessorCount(index)
+			}
+			metadata.Vertices += positions
+
+			elements := positions
+			if primitive.Indices != nil {
+				elements = accessorCount(*primitive.Indices)
+			}
+			mode := gltfTriangles
+			if primitive.Mode != nil {
+				mode = *primitive.Mode
+			}
+			switch {
+			case mode == gltfTriangles:
+				metadata.Triangles += elements / 3
+			case (mode == gltfTriangleStrip || mode == gltfTriangleFan) && elements >= 3:
+				metadata.Triangles += elements - 2
+			}
+		}
+	}
+
+	for i, img := range doc.Images {
+		name := img.Name
+		if name == "" {
+			name = fmt.Sprintf("image %d", i)
+		}
+
+		switch {
+		case img.BufferView != nil:
+			encoded, err := glbBufferView(&doc, binChunk, *img.BufferView)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			metadata.addTexture(name, img.MimeType, encoded)
+		case strings.HasPrefix(img.URI, "data:"):
+			header, payload, _ := strings.Cut(img.URI, ",")
+			encoded, err := base64.StdEncoding.DecodeString(payload)
+			if err != nil || !strings.HasSuffix(header, ";base64") {
+				return nil, fmt.Errorf("%s: invalid data URI", name)
+			}
+			mimeType := img.MimeType
+			if mimeType == "" {
+				mimeType = strings.TrimSuffix(strings.TrimPrefix(header, "data:"), ";base64")
+			}
+			metadata.addTexture(name, mimeType, encoded)
+		default:
+			// A GLB is meant to be self-contained; a relative URI will not resolve once uploaded
+			metadata.Incomplete = true
+			metadata.Notes = append(metadata.Notes, fmt.Sprintf("%s references external file %q", name, img.URI))
+		}
+	}
+
+	return metadata, nil
+}
+
+// glbBufferView returns the bytes of a buffer view stored in the BIN chunk
+func glbBufferView(doc *gltfDocument, bin []byte, index int) ([]byte, error) {
+	if index < 0 || index >= len(doc.BufferViews) {
+		return nil, fmt.Errorf("buffer view %d does not exist", index)
+	}
+	view := doc.BufferViews[index]
+	if view.Buffer != 0 {
+		return nil, fmt.Errorf("buffer view %d is not in the GLB binary chunk", index)
+	}
+	if view.ByteOffset < 0 || view.ByteLength < 0 || view.ByteOffset+view.ByteLength > int64(len(bin)) {
+		return nil, fmt.Errorf("buffer view %d is out of bounds", index)
+	}
+	return bin[view.ByteOffset : view.ByteOffset+view.ByteLength], nil
+}
@@ -0,0 +1,124 @@
+package assets
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+)
+
+// Limits bounds what an upload may contain; zero fields are not checked
+type Limits struct {
+	MaxSize             int64 // Bytes
+	MaxTriangles        int64
+	MaxTextureDimension int // Largest width or height of any texture, in pixels
+	MaxTextures         int
+	AllowAnimations     bool
+	RejectIncomplete    bool // Reject files that could not be fully analyzed, as they may exceed the other limits unseen
+}
+
+// DefaultLimits suit mobile AR viewers
+var DefaultLimits = Limits{
+	MaxSize:             25 << 20,
+	MaxTriangles:        500_000,
+	MaxTextureDimension: 4096,
+	MaxTextures:         32,
+	AllowAnimations:     true,
+	RejectIncomplete:    true,
+}
+
+// LimitViolation reports the limits a model exceeds
+type LimitViolation struct {
+	Reasons []string
+}
+
+func (e *LimitViolation) Error() string {
+	return "asset exceeds limits: " + strings.Join(e.Reasons, "; ")
+}
+
+// LimitsFromEnv returns DefaultLimits overridden by ASSET_MAX_SIZE_MB,
+// ASSET_MAX_TRIANGLES, ASSET_MAX_TEXTURE_SIZE and ASSET_MAX_TEXTURES.
+// ASSET_ALLOW_INCOMPLETE=true accepts files that could not be fully analyzed.
+func LimitsFromEnv() Limits {
+	limits := DefaultLimits
+	if mb := envInt("ASSET_MAX_SIZE_MB"); mb > 0 {
+		limits.MaxSize = mb << 20
+	}
+	if triangles := envInt("ASSET_MAX_TRIANGLES"); triangles > 0 {
+		limits.MaxTriangles = triangles
+	}
+	if dimension := envInt("ASSET_MAX_TEXTURE_SIZE"); dimension > 0 {
+		limits.MaxTextureDimension = int(dimension)
+	}
+	if textures := envInt("ASSET_MAX_TEXTURES"); textures > 0 {
+		limits.MaxTextures = int(textures)
+	}
+	if config.GetEnv("ASSET_ALLOW_INCOMPLETE", "") == "true" {
+		limits.RejectIncomplete = false
+	}
+	return limits
+}
+
+// Validate returns a *LimitViolation listing every limit metadata exceeds
+func (l Limits) Validate(metadata *Metadata) error {
+	var reasons []string
+	if l.MaxSize > 0 && metadata.Size > l.MaxSize {
+		reasons = append(reasons, fmt.Sprintf("size %d bytes exceeds %d", metadata.Size, l.MaxSize))
+	}
+	if l.MaxTriangles > 0 && metadata.Triangles > l.MaxTriangles {
+		reasons = append(reasons, fmt.Sprintf("%d triangles exceed %d", metadata.Triangles, l.MaxTriangles))
+	}
+	if l.MaxTextures > 0 && len(metadata.Textures) > l.MaxTextures {
+		reasons = append(reasons, fmt.Sprintf("%d textures exceed %d", len(metadata.Textures), l.MaxTextures))
+	}
+	if l.MaxTextureDimension > 0 {
+		for _, texture := range metadata.Textures {
+			if texture.Width > l.MaxTextureDimension || texture.Height > l.MaxTextureDimension {
+				reasons = append(reasons, fmt.Sprintf("texture %s is %dx%d, larger than %d",
+					texture.Name, texture.Width, texture.Height, l.MaxTextureDimension))
+			}
+		}
+	}
+	if !l.AllowAnimations && metadata.HasAnimations {
+		reasons = append(reasons, "animations are not allowed")
+	}
+	if l.RejectIncomplete && metadata.Incomplete {
+		reasons = append(reasons, "file could not be fully analyzed: "+strings.Join(metadata.Notes, "; "))
+	}
+
+	if len(reasons) > 0 {
+		return &LimitViolation{Reasons: reasons}
+	}
+	return nil
+}
+
+// InspectAndValidate inspects data and checks it against limits, the usual
+// check before accepting an upload
+func InspectAndValidate(data []byte, limits Limits) (*Metadata, error) {
+	// Reject oversized files before parsing them
+	if limits.MaxSize > 0 && int64(len(data)) > limits.MaxSize {
+		return nil, &LimitViolation{Reasons: []string{fmt.Sprintf("size %d bytes exceeds %d", len(data), limits.MaxSize)}}
+	}
+
+	metadata, err := Inspect(data)
+	if err != nil {
+		return nil, err
+	}
+	return metadata, limits.Validate(metadata)
+}
+
+// envInt reads a positive integer setting, ignoring invalid values
+func envInt(key string) int64 {
+	value := config.GetEnv(key, "")
+	if value == "" {
+		return 0
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Printf("⚠️  Invalid %s %q, using the default: %v", key, value, err)
+		return 0
+	}
+	return parsed
+}
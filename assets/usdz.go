@@ -0,0 +1,113 @@
+package assets
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// usdcMagic starts binary ("crate") USD layers
+var usdcMagic = []byte("PXR-USDC")
+
+var (
+	faceVertexCountsPattern = regexp.MustCompile(`faceVertexCounts\s*=\s*\[([^\]]*)\]`)
+	pointsPattern           = regexp.MustCompile(`point3f\[\]\s+points\s*=\s*\[([^\]]*)\]`)
+	materialPattern         = regexp.MustCompile(`\bdef\s+Material\b`)
+	meshPattern             = regexp.MustCompile(`\bdef\s+Mesh\b`)
+	animationPattern        = regexp.MustCompile(`\.timeSamples\b|\bSkelAnimation\b`)
+)
+
+// inspectUSDZ reads a USDZ package: an uncompressed zip whose first entry is
+// the root layer. Text layers are analyzed fully; binary layers only
+// contribute their textures, as the crate format is not parsed.
+func inspectUSDZ(data []byte) (*Metadata, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("read archive: %w", err)
+	}
+	if len(archive.File) == 0 {
+		return nil, fmt.Errorf("empty package")
+	}
+	if ext := strings.ToLower(path.Ext(archive.File[0].Name)); ext != ".usd" && ext != ".usda" && ext != ".usdc" {
+		return nil, fmt.Errorf("first entry %q is not a USD layer", archive.File[0].Name)
+	}
+
+	metadata := &Metadata{Textures: []Texture{}}
+	for _, file := range archive.File {
+		if file.Method != zip.Store {
+			// Compressed entries cannot be memory-mapped, so AR Quick Look rejects them
+			return nil, fmt.Errorf("entry %q is compressed; USDZ entries must be stored", file.Name)
+		}
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		switch ext := strings.ToLower(path.Ext(file.Name)); ext {
+		case ".usd", ".usda", ".usdc":
+			content, err := readZipEntry(file)
+			if err != nil {
+				return nil, err
+			}
+			if bytes.HasPrefix(content, usdcMagic) {
+				metadata.Incomplete = true
+				metadata.Notes = append(metadata.Notes, fmt.Sprintf("binary layer %q was not analyzed", file.Name))
+				continue
+			}
+			inspectUSDA(string(content), metadata)
+		case ".png", ".jpg", ".jpeg", ".exr", ".avif":
+			content, err := readZipEntry(file)
+			if err != nil {
+				return nil, err
+			}
+			metadata.addTexture(file.Name, "", content)
+		}
+	}
+	return metadata, nil
+}
+
+// inspectUSDA adds the meshes, vertices, triangles, materials and animation of a text layer
+func inspectUSDA(layer string, metadata *Metadata) {
+	metadata.Meshes += len(meshPattern.FindAllStringIndex(layer, -1))
+	metadata.Materials += len(materialPattern.FindAllStringIndex(layer, -1))
+	if animationPattern.MatchString(layer) {
+		metadata.HasAnimations = true
+	}
+
+	for _, match := range pointsPattern.FindAllStringSubmatch(layer, -1) {
+		metadata.Vertices += int64(strings.Count(match[1], "("))
+	}
+
+	// Faces are polygons; a face of n vertices renders as n-2 triangles
+	for _, match := range faceVertexCountsPattern.FindAllStringSubmatch(layer, -1) {
+		for _, field := range strings.Split(match[1], ",") {
+			count, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+			if err != nil || count < 3 {
+				continue
+			}
+			metadata.Triangles += count - 2
+		}
+	}
+}
+
+// readZipEntry reads a stored entry, refusing entries larger than declared
+func readZipEntry(file *zip.File) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open %q: %w", file.Name, err)
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(io.LimitReader(reader, int64(file.UncompressedSize64)+1))
+	if err != nil {
+		return nil, fmt.Errorf("read %q: %w", file.Name, err)
+	}
+	if uint64(len(content)) > file.UncompressedSize64 {
+		return nil, fmt.Errorf("entry %q is larger than declared", file.Name)
+	}
+	return content, nil
+}
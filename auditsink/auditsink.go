@@ -0,0 +1,256 @@
+// Package auditsink mirrors audit entries to external systems (Splunk HEC,
+// Datadog logs, syslog) in near real time, in addition to Mongo. Each sink has
+// its own buffer and retries, so a slow or unavailable SIEM never delays
+// audit logging or the other sinks.
+//
+//	sinks, err := auditsink.SinksFromConfig("orders")
+//	auditsink.Enable(auditsink.Options{}, sinks...)
+//	defer auditsink.Shutdown(context.Background())
+package auditsink
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// Sink stores batches of audit entries in an external system
+type Sink interface {
+	Name() string
+	Write(ctx context.Context, entries []models.AuditLog) error
+}
+
+// DefaultRetryPolicy rides out short SIEM outages: five attempts over roughly a minute
+var DefaultRetryPolicy = utils.RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: time.Second,
+	MaxBackoff:     30 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// Options configures the forwarder of each sink
+type Options struct {
+	BatchSize     int                // Entries per sink write (default 50)
+	FlushInterval time.Duration      // Maximum time an entry waits in the buffer (default 2s)
+	BufferSize    int                // Entries held before new ones are dropped (default 10000)
+	RetryPolicy   *utils.RetryPolicy // Sink write retries (default DefaultRetryPolicy)
+}
+
+// Forwarder buffers audit entries and writes them to one sink in the background
+type Forwarder struct {
+	sink    Sink
+	options Options
+	entries chan models.AuditLog
+	flushes chan chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// NewForwarder starts a forwarder for sink; call Close to flush and stop it
+func NewForwarder(sink Sink, options Options) *Forwarder {
+	if options.BatchSize <= 0 {
+		options.BatchSize = 50
+	}
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = 2 * time.Second
+	}
+	if options.BufferSize <= 0 {
+		options.BufferSize = 10000
+	}
+	if options.RetryPolicy == nil {
+		policy := DefaultRetryPolicy
+		options.RetryPolicy = &policy
+	}
+
+	forwarder := &Forwarder{
+		sink:    sink,
+		options: options,
+		entries: make(chan models.AuditLog, options.BufferSize),
+		flushes: make(chan chan struct{}),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go forwarder.run()
+	return forwarder
+}
+
+// Send buffers an entry. It never blocks: when the buffer is full the entry is
+// dropped and counted in audit_sink_entries_dropped_total.
+func (f *Forwarder) Send(entry models.AuditLog) {
+	select {
+	case <-f.done:
+		entriesDropped.WithLabelValues(f.sink.Name(), "closed").Inc()
+	default:
+		select {
+		case f.entries <- entry:
+		default:
+			entriesDropped.WithLabelValues(f.sink.Name(), "buffer_full").Inc()
+		}
+	}
+}
+
+// Flush writes all buffered entries and waits until the sink has been called
+func (f *Forwarder) Flush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case f.flushes <- ack:
+	case <-f.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close flushes remaining entries and stops the forwarder, closing sinks that
+// implement io.Closer; it gives up when ctx ends
+func (f *Forwarder) Close(ctx context.Context) error {
+	f.once.Do(func() { close(f.done) })
+
+	select {
+	case <-f.stopped:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("audit sink %s shutdown: %w", f.sink.Name(), ctx.Err())
+	}
+}
+
+func (f *Forwarder) run() {
+	defer close(f.stopped)
+	if closer, ok := f.sink.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	ticker := time.NewTicker(f.options.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.AuditLog, 0, f.options.BatchSize)
+	write := func() {
+		if len(batch) == 0 {
+			return
+		}
+		f.write(batch)
+		batch = make([]models.AuditLog, 0, f.options.BatchSize)
+	}
+	drain := func() {
+		for {
+			select {
+			case entry := <-f.entries:
+				batch = append(batch, entry)
+				if len(batch) >= f.options.BatchSize {
+					write()
+				}
+			default:
+				write()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case entry := <-f.entries:
+			batch = append(batch, entry)
+			if len(batch) >= f.options.BatchSize {
+				write()
+			}
+		case <-ticker.C:
+			write()
+		case ack := <-f.flushes:
+			drain()
+			close(ack)
+		case <-f.done:
+			drain()
+			return
+		}
+	}
+}
+
+// write sends a batch to the sink with retries; failed batches are logged and
+// dropped, the entries remain in Mongo
+func (f *Forwarder) write(batch []models.AuditLog) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	name := f.sink.Name()
+	err := utils.Retry(ctx, *f.options.RetryPolicy, func() error {
+		return f.sink.Write(ctx, batch)
+	})
+	if err != nil {
+		entriesDropped.WithLabelValues(name, "sink_error").Add(float64(len(batch)))
+		log.Printf("❌ Failed to forward %d audit entries to %s: %v", len(batch), name, err)
+		return
+	}
+	entriesWritten.WithLabelValues(name).Add(float64(len(batch)))
+}
+
+var (
+	forwarders   []*Forwarder
+	forwardersMu sync.RWMutex
+	hookOnce     sync.Once
+)
+
+// Enable mirrors every audit entry logged from now on to sinks, replacing the
+// sinks of an earlier call
+func Enable(options Options, sinks ...Sink) {
+	started := make([]*Forwarder, len(sinks))
+	names := make([]string, len(sinks))
+	for i, sink := range sinks {
+		started[i] = NewForwarder(sink, options)
+		names[i] = sink.Name()
+	}
+
+	forwardersMu.Lock()
+	previous := forwarders
+	forwarders = started
+	forwardersMu.Unlock()
+
+	for _, forwarder := range previous {
+		forwarder.Close(context.Background())
+	}
+
+	hookOnce.Do(func() {
+		utils.OnAuditLogged(forward)
+	})
+	log.Printf("📤 Audit entries mirrored to %v", names)
+}
+
+// forward hands an entry to every enabled sink
+func forward(entry models.AuditLog) {
+	forwardersMu.RLock()
+	defer forwardersMu.RUnlock()
+
+	for _, forwarder := range forwarders {
+		forwarder.Send(entry)
+	}
+}
+
+// Shutdown flushes and stops every sink enabled by Enable
+func Shutdown(ctx context.Context) error {
+	forwardersMu.Lock()
+	stopping := forwarders
+	forwarders = nil
+	forwardersMu.Unlock()
+
+	var firstErr error
+	for _, forwarder := range stopping {
+		if err := forwarder.Close(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,57 @@
+package auditsink
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+)
+
+// SinksFromConfig creates the sinks listed in AUDIT_SINKS (comma separated
+// "splunk", "datadog", "syslog"), named after service:
+//
+//	splunk:  AUDIT_SPLUNK_HEC_URL, secret splunk-hec-token / SPLUNK_HEC_TOKEN, optional AUDIT_SPLUNK_INDEX
+//	datadog: secret datadog-api-key / DD_API_KEY, optional DD_SITE and AUDIT_DATADOG_TAGS
+//	syslog:  AUDIT_SYSLOG_ADDRESS, optional AUDIT_SYSLOG_NETWORK (udp, tcp or tls; default tcp)
+func SinksFromConfig(service string) ([]Sink, error) {
+	var sinks []Sink
+	for _, name := range strings.Split(config.GetEnv("AUDIT_SINKS", ""), ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+			continue
+		case "splunk":
+			url := config.GetEnv("AUDIT_SPLUNK_HEC_URL", "")
+			if url == "" {
+				return nil, fmt.Errorf("audit sink splunk: AUDIT_SPLUNK_HEC_URL is not set")
+			}
+			token, err := config.GetSecret("splunk-hec-token", "SPLUNK_HEC_TOKEN")
+			if err != nil {
+				return nil, fmt.Errorf("audit sink splunk: %w", err)
+			}
+			sink := NewSplunkSink(url, token, service)
+			sink.Index = config.GetEnv("AUDIT_SPLUNK_INDEX", "")
+			sinks = append(sinks, sink)
+		case "datadog":
+			apiKey, err := config.GetSecret("datadog-api-key", "DD_API_KEY")
+			if err != nil {
+				return nil, fmt.Errorf("audit sink datadog: %w", err)
+			}
+			sink := NewDatadogSink(apiKey, config.GetEnv("DD_SITE", ""), service)
+			sink.Tags = config.GetEnv("AUDIT_DATADOG_TAGS", "")
+			sinks = append(sinks, sink)
+		case "syslog":
+			address := config.GetEnv("AUDIT_SYSLOG_ADDRESS", "")
+			if address == "" {
+				return nil, fmt.Errorf("audit sink syslog: AUDIT_SYSLOG_ADDRESS is not set")
+			}
+			network := config.GetEnv("AUDIT_SYSLOG_NETWORK", "tcp")
+			if network != "udp" && network != "tcp" && network != "tls" {
+				return nil, fmt.Errorf("audit sink syslog: unsupported AUDIT_SYSLOG_NETWORK %q", network)
+			}
+			sinks = append(sinks, NewSyslogSink(network, address, service))
+		default:
+			return nil, fmt.Errorf("unknown audit sink %q", name)
+		}
+	}
+	return sinks, nil
+}
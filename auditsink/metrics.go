@@ -0,0 +1,18 @@
+package auditsink
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	entriesWritten = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "audit_sink_entries_written_total",
+		Help: "Audit entries forwarded to external sinks, partitioned by sink.",
+	}, []string{"sink"})
+
+	entriesDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "audit_sink_entries_dropped_total",
+		Help: "Audit entries not forwarded to external sinks, partitioned by sink and reason.",
+	}, []string{"sink", "reason"})
+)
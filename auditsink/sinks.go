@@ -0,0 +1,172 @@
+package auditsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// SplunkSink sends entries to a Splunk HTTP Event Collector
+type SplunkSink struct {
+	URL        string // HEC base URL, e.g. https://splunk.example.com:8088
+	Token      string
+	Index      string // Optional; the token's default index when empty
+	Source     string // Usually the service name
+	SourceType string // Default "oms:audit"
+	Client     *http.Client
+}
+
+// NewSplunkSink creates a sink for the HEC at url using a HEC token
+func NewSplunkSink(url, token, source string) *SplunkSink {
+	return &SplunkSink{
+		URL:        url,
+		Token:      token,
+		Source:     source,
+		SourceType: "oms:audit",
+		Client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name identifies the sink in metrics and logs
+func (s *SplunkSink) Name() string {
+	return "splunk"
+}
+
+// Write sends the batch as concatenated HEC events
+func (s *SplunkSink) Write(ctx context.Context, entries []models.AuditLog) error {
+	type hecEvent struct {
+		Time       float64         `json:"time"`
+		Host       string          `json:"host,omitempty"`
+		Source     string          `json:"source,omitempty"`
+		SourceType string          `json:"sourcetype,omitempty"`
+		Index      string          `json:"index,omitempty"`
+		Event      models.AuditLog `json:"event"`
+	}
+
+	host, _ := os.Hostname()
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, entry := range entries {
+		err := encoder.Encode(hecEvent{
+			Time:       float64(entry.Timestamp.UnixMilli()) / 1000,
+			Host:       host,
+			Source:     s.Source,
+			SourceType: s.SourceType,
+			Index:      s.Index,
+			Event:      entry,
+		})
+		if err != nil {
+			return utils.PermanentError(err)
+		}
+	}
+
+	endpoint := strings.TrimRight(s.URL, "/")
+	if !strings.Contains(endpoint, "/services/collector") {
+		endpoint += "/services/collector/event"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return utils.PermanentError(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Splunk "+s.Token)
+
+	return send(s.Client, req, "splunk")
+}
+
+// DatadogSink sends entries to the Datadog logs intake
+type DatadogSink struct {
+	APIKey  string
+	Site    string // Datadog site, e.g. datadoghq.eu (default datadoghq.com)
+	Service string // Usually the service name
+	Tags    string // Optional ddtags, e.g. env:prod,team:platform
+	Client  *http.Client
+}
+
+// NewDatadogSink creates a sink for the logs intake of site using an API key
+func NewDatadogSink(apiKey, site, service string) *DatadogSink {
+	if site == "" {
+		site = "datadoghq.com"
+	}
+	return &DatadogSink{
+		APIKey:  apiKey,
+		Site:    site,
+		Service: service,
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name identifies the sink in metrics and logs
+func (s *DatadogSink) Name() string {
+	return "datadog"
+}
+
+// Write sends the batch as one logs intake request; the entry is the message
+// and is also attached as the audit attribute for facets
+func (s *DatadogSink) Write(ctx context.Context, entries []models.AuditLog) error {
+	type datadogLog struct {
+		Source   string          `json:"ddsource"`
+		Tags     string          `json:"ddtags,omitempty"`
+		Hostname string          `json:"hostname,omitempty"`
+		Service  string          `json:"service,omitempty"`
+		Message  string          `json:"message"`
+		Audit    models.AuditLog `json:"audit"`
+	}
+
+	host, _ := os.Hostname()
+	logs := make([]datadogLog, len(entries))
+	for i, entry := range entries {
+		message, err := json.Marshal(entry)
+		if err != nil {
+			return utils.PermanentError(err)
+		}
+		logs[i] = datadogLog{
+			Source:   "oms-audit",
+			Tags:     s.Tags,
+			Hostname: host,
+			Service:  s.Service,
+			Message:  string(message),
+			Audit:    entry,
+		}
+	}
+
+	body, err := json.Marshal(logs)
+	if err != nil {
+		return utils.PermanentError(err)
+	}
+
+	endpoint := "https://http-intake.logs." + s.Site + "/api/v2/logs"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return utils.PermanentError(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("DD-API-KEY", s.APIKey)
+
+	return send(s.Client, req, "datadog")
+}
+
+// send performs req; 4xx responses other than 408 and 429 are not retried
+func send(client *http.Client, req *http.Request, name string) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout {
+		return fmt.Errorf("%s returned status %d", name, resp.StatusCode)
+	}
+	if resp.StatusCode >= 300 {
+		return utils.PermanentError(fmt.Errorf("%s rejected batch with status %d", name, resp.StatusCode))
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package auditsink
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// Syslog facility and severity of audit entries: "log audit", notice
+const (
+	syslogFacility = 13
+	syslogSeverity = 5
+)
+
+// SyslogSink sends entries as RFC 5424 messages over UDP, TCP or TLS. TCP and
+// TLS use octet-counting framing (RFC 6587), which rsyslog and syslog-ng accept.
+type SyslogSink struct {
+	Network   string // "udp", "tcp" or "tls"
+	Address   string // host:port
+	AppName   string // Usually the service name
+	TLSConfig *tls.Config
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogSink creates a sink for the collector at address
+func NewSyslogSink(network, address, appName string) *SyslogSink {
+	return &SyslogSink{Network: network, Address: address, AppName: appName}
+}
+
+// Name identifies the sink in metrics and logs
+func (s *SyslogSink) Name() string {
+	return "syslog"
+}
+
+// Write sends one message per entry, reconnecting on the next attempt after a failure
+func (s *SyslogSink) Write(ctx context.Context, entries []models.AuditLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		conn, err := s.dial(ctx)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+
+	host, _ := os.Hostname()
+	for _, entry := range entries {
+		message, err := s.format(entry, host)
+		if err != nil {
+			return utils.PermanentError(err)
+		}
+		if s.Network != "udp" {
+			message = fmt.Sprintf("%d %s", len(message), message)
+		}
+
+		if deadline, ok := ctx.Deadline(); ok {
+			s.conn.SetWriteDeadline(deadline)
+		}
+		if _, err := s.conn.Write([]byte(message)); err != nil {
+			s.conn.Close()
+			s.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the connection to the collector
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *SyslogSink) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if s.Network == "tls" {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: s.TLSConfig}
+		return tlsDialer.DialContext(ctx, "tcp", s.Address)
+	}
+	return dialer.DialContext(ctx, s.Network, s.Address)
+}
+
+// format renders an RFC 5424 message with the action as MSGID and the entry as JSON
+func (s *SyslogSink) format(entry models.AuditLog, host string) (string, error) {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s - %s",
+		syslogFacility*8+syslogSeverity,
+		entry.Timestamp.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogField(host, 255),
+		syslogField(s.AppName, 48),
+		os.Getpid(),
+		syslogField(entry.Action, 32),
+		body,
+	), nil
+}
+
+// syslogField makes a header field printable ASCII without spaces, "-" when empty
+func syslogField(value string, max int) string {
+	field := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, value)
+	if field == "" {
+		return "-"
+	}
+	if len(field) > max {
+		field = field[:max]
+	}
+	return field
+}
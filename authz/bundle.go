@@ -0,0 +1,98 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Policies take part in config bundles, keyed by the JSON array
+// ["subject","action","resource"] with the effect as value. JSON keeps the
+// parts apart whatever characters they contain.
+func init() {
+	config.RegisterBundleSection("policies", config.BundleSection{
+		Export: exportPolicyBundle,
+		Apply:  applyPolicyBundle,
+	})
+}
+
+func policyBundleKey(policy models.Policy) string {
+	key, _ := json.Marshal([]string{policy.Subject, policy.Action, policy.Resource})
+	return string(key)
+}
+
+// parsePolicyBundleKey splits a key made by policyBundleKey
+func parsePolicyBundleKey(key string) ([]string, error) {
+	var parts []string
+	if err := json.Unmarshal([]byte(key), &parts); err != nil || len(parts) != 3 {
+		return nil, fmt.Errorf("%w: policy key %q", config.ErrInvalidBundle, key)
+	}
+	return parts, nil
+}
+
+func exportPolicyBundle(ctx context.Context) (map[string]json.RawMessage, error) {
+	policies, err := ListPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	items := map[string]json.RawMessage{}
+	for _, policy := range policies {
+		effect, err := json.Marshal(policy.Effect)
+		if err != nil {
+			return nil, err
+		}
+		items[policyBundleKey(policy)] = effect
+	}
+	return items, nil
+}
+
+func applyPolicyBundle(ctx context.Context, changes []config.BundleChange) error {
+	collection := config.GetCollection(PoliciesCollection)
+	defer InvalidatePolicyCache()
+
+	for _, change := range changes {
+		parts, err := parsePolicyBundleKey(change.Key)
+		if err != nil {
+			return err
+		}
+		filter := bson.M{"subject": parts[0], "action": parts[1], "resource": parts[2]}
+
+		if change.Kind == config.BundleRemoved {
+			if _, err := collection.DeleteMany(ctx, filter); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var effect string
+		if err := json.Unmarshal(change.Bundle, &effect); err != nil {
+			return fmt.Errorf("%w: policy %q: %v", config.ErrInvalidBundle, change.Key, err)
+		}
+		if change.Kind == config.BundleAdded {
+			_, err := CreatePolicy(ctx, models.Policy{
+				Subject: parts[0], Action: parts[1], Resource: parts[2], Effect: effect, CreatedBy: "system",
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if effect != models.PolicyAllow && effect != models.PolicyDeny {
+			return fmt.Errorf("%w: effect must be %q or %q", ErrInvalidPolicy, models.PolicyAllow, models.PolicyDeny)
+		}
+		updateCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		_, err = collection.UpdateMany(updateCtx, filter, bson.M{"$set": bson.M{"effect": effect}})
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+package authz
+
+import "errors"
+
+// Sentinel errors returned by the authz package; match them with errors.Is
+var (
+	ErrForbidden     = errors.New("permission denied")
+	ErrInvalidPolicy = errors.New("invalid policy")
+	ErrInvalidRole   = errors.New("invalid role definition")
+)
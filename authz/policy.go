@@ -0,0 +1,193 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PoliciesCollection stores subject/action/resource rules
+const PoliciesCollection = "policies"
+
+// PolicyCacheTTL controls how long policies are cached before reloading from Mongo
+var PolicyCacheTTL = time.Minute
+
+// Subject identifies who is asking for access
+type Subject struct {
+	UserID string
+	Role   string
+}
+
+var (
+	policyCache    []models.Policy
+	policyLoadedAt time.Time
+	policyMux      sync.RWMutex
+)
+
+// Can reports whether subject may perform action on resource. Deny rules take
+// precedence over allow rules; with no matching rule access is denied.
+func Can(ctx context.Context, subject Subject, action, resource string) (bool, error) {
+	policies, err := cachedPolicies(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	allowed := false
+	for _, policy := range policies {
+		if !subjectMatches(policy.Subject, subject) ||
+			!patternMatches(policy.Action, action) ||
+			!patternMatches(policy.Resource, resource) {
+			continue
+		}
+
+		if policy.Effect == models.PolicyDeny {
+			return false, nil
+		}
+		allowed = true
+	}
+
+	return allowed, nil
+}
+
+// Enforce is Can expressed as an error: it returns an error wrapping ErrForbidden
+// when subject may not perform action on resource
+func Enforce(ctx context.Context, subject Subject, action, resource string) error {
+	allowed, err := Can(ctx, subject, action, resource)
+	if err != nil {
+		return fmt.Errorf("evaluate %s on %s: %w", action, resource, err)
+	}
+	if !allowed {
+		return fmt.Errorf("%w: %s on %s", ErrForbidden, action, resource)
+	}
+	return nil
+}
+
+// ListPolicies returns all stored policies
+func ListPolicies(ctx context.Context) ([]models.Policy, error) {
+	collection := config.GetCollection(PoliciesCollection)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var policies []models.Policy
+	if err = cursor.All(ctx, &policies); err != nil {
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+// CreatePolicy validates and stores a policy, invalidating the cache
+func CreatePolicy(ctx context.Context, policy models.Policy) (models.Policy, error) {
+	if policy.Subject == "" || policy.Action == "" || policy.Resource == "" {
+		return policy, fmt.Errorf("%w: subject, action and resource are required", ErrInvalidPolicy)
+	}
+	if policy.Effect == "" {
+		policy.Effect = models.PolicyAllow
+	}
+	if policy.Effect != models.PolicyAllow && policy.Effect != models.PolicyDeny {
+		return policy, fmt.Errorf("%w: effect must be %q or %q", ErrInvalidPolicy, models.PolicyAllow, models.PolicyDeny)
+	}
+
+	policy.ID = primitive.NewObjectID()
+	policy.CreatedAt = time.Now()
+
+	collection := config.GetCollection(PoliciesCollection)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := collection.InsertOne(ctx, policy); err != nil {
+		return policy, err
+	}
+
+	InvalidatePolicyCache()
+	return policy, nil
+}
+
+// DeletePolicy removes a policy by ID, invalidating the cache
+func DeletePolicy(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	collection := config.GetCollection(PoliciesCollection)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return false, err
+	}
+
+	InvalidatePolicyCache()
+	return result.DeletedCount > 0, nil
+}
+
+// InvalidatePolicyCache forces the next check to reload policies from Mongo
+func InvalidatePolicyCache() {
+	policyMux.Lock()
+	defer policyMux.Unlock()
+	policyCache = nil
+	policyLoadedAt = time.Time{}
+}
+
+// cachedPolicies returns policies from the cache, reloading them when stale
+func cachedPolicies(ctx context.Context) ([]models.Policy, error) {
+	policyMux.RLock()
+	if policyCache != nil && time.Since(policyLoadedAt) < PolicyCacheTTL {
+		policies := policyCache
+		policyMux.RUnlock()
+		return policies, nil
+	}
+	policyMux.RUnlock()
+
+	policies, err := ListPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if policies == nil {
+		policies = []models.Policy{}
+	}
+
+	policyMux.Lock()
+	policyCache = policies
+	policyLoadedAt = time.Now()
+	policyMux.Unlock()
+
+	return policies, nil
+}
+
+// subjectMatches checks a policy subject against the caller, honouring role inheritance
+func subjectMatches(policySubject string, subject Subject) bool {
+	switch {
+	case policySubject == "*":
+		return true
+	case strings.HasPrefix(policySubject, "user:"):
+		return subject.UserID != "" && strings.TrimPrefix(policySubject, "user:") == subject.UserID
+	case strings.HasPrefix(policySubject, "role:"):
+		return HasRole(subject.Role, strings.TrimPrefix(policySubject, "role:"))
+	default:
+		return false
+	}
+}
+
+// patternMatches supports "*", trailing "/*" prefixes and path.Match globs
+func patternMatches(pattern, value string) bool {
+	if pattern == "*" || pattern == value {
+		return true
+	}
+	if strings.HasSuffix(pattern, "/*") && strings.HasPrefix(value, strings.TrimSuffix(pattern, "*")) {
+		return true
+	}
+	matched, err := path.Match(pattern, value)
+	return err == nil && matched
+}
@@ -0,0 +1,150 @@
+// Package authz provides role hierarchy checks and a policy engine for access control.
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Built-in roles, from most to least privileged
+const (
+	RoleSuperAdmin = "super_admin"
+	RoleAdmin      = "admin"
+	RoleEditor     = "editor"
+	RoleViewer     = "viewer"
+)
+
+// RolesCollection is the Mongo collection custom role definitions are loaded from
+const RolesCollection = "roles"
+
+var (
+	roles    = map[string]models.Role{}
+	rolesMux sync.RWMutex
+)
+
+func init() {
+	RegisterRole(models.Role{Name: RoleViewer, Description: "Read-only access"})
+	RegisterRole(models.Role{Name: RoleEditor, Inherits: []string{RoleViewer}, Description: "Can modify content"})
+	RegisterRole(models.Role{Name: RoleAdmin, Inherits: []string{RoleEditor}, Description: "Organization administrator"})
+	RegisterRole(models.Role{Name: RoleSuperAdmin, Inherits: []string{RoleAdmin}, Description: "Platform administrator"})
+}
+
+// RegisterRole adds or replaces a role definition, e.g. org_owner inheriting admin
+func RegisterRole(role models.Role) {
+	rolesMux.Lock()
+	defer rolesMux.Unlock()
+	roles[role.Name] = role
+}
+
+// GetRole returns a registered role definition
+func GetRole(name string) (models.Role, bool) {
+	rolesMux.RLock()
+	defer rolesMux.RUnlock()
+	role, ok := roles[name]
+	return role, ok
+}
+
+// ListRoles returns all registered role definitions
+func ListRoles() []models.Role {
+	rolesMux.RLock()
+	defer rolesMux.RUnlock()
+
+	list := make([]models.Role, 0, len(roles))
+	for _, role := range roles {
+		list = append(list, role)
+	}
+	return list
+}
+
+// HasRole reports whether a user with role actual holds the privileges of required,
+// either directly or through inheritance
+func HasRole(actual, required string) bool {
+	if actual == "" {
+		return false
+	}
+	if actual == required {
+		return true
+	}
+
+	rolesMux.RLock()
+	defer rolesMux.RUnlock()
+
+	visited := map[string]bool{}
+	queue := []string{actual}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if visited[name] {
+			continue
+		}
+		visited[name] = true
+
+		for _, parent := range roles[name].Inherits {
+			if parent == required {
+				return true
+			}
+			queue = append(queue, parent)
+		}
+	}
+	return false
+}
+
+// LoadRolesFromJSON registers role definitions from a JSON array, such as the ROLE_DEFINITIONS env var
+func LoadRolesFromJSON(data []byte) error {
+	var definitions []models.Role
+	if err := json.Unmarshal(data, &definitions); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidRole, err)
+	}
+
+	for _, role := range definitions {
+		if role.Name == "" {
+			return fmt.Errorf("%w: missing name", ErrInvalidRole)
+		}
+		RegisterRole(role)
+	}
+
+	log.Printf("✅ Loaded %d custom role definitions", len(definitions))
+	return nil
+}
+
+// LoadRolesFromEnv registers role definitions from the ROLE_DEFINITIONS env var, if set
+func LoadRolesFromEnv() error {
+	definitions := config.GetEnv("ROLE_DEFINITIONS", "")
+	if definitions == "" {
+		return nil
+	}
+	return LoadRolesFromJSON([]byte(definitions))
+}
+
+// LoadRolesFromMongo registers role definitions stored in the roles collection
+func LoadRolesFromMongo() error {
+	collection := config.GetCollection(RolesCollection)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var definitions []models.Role
+	if err = cursor.All(ctx, &definitions); err != nil {
+		return err
+	}
+
+	for _, role := range definitions {
+		RegisterRole(role)
+	}
+
+	log.Printf("✅ Loaded %d role definitions from MongoDB", len(definitions))
+	return nil
+}
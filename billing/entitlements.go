@@ -0,0 +1,115 @@
+package billing
+
+import (
+	"context"
+	"sync"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FreePlan applies to organizations without an entitled subscription
+const FreePlan = "free"
+
+// Unlimited is the Limit of a quota the plan does not cap
+const Unlimited int64 = -1
+
+// Entitlements are the features and quotas a plan grants
+type Entitlements struct {
+	Plan     string           `json:"plan"`
+	Tier     int              `json:"tier"` // Plans of a higher tier satisfy a requirement for a lower one
+	Features map[string]bool  `json:"features"`
+	Limits   map[string]int64 `json:"limits"` // Quotas by name; absent means Unlimited
+}
+
+// Has reports whether the plan includes a feature
+func (e *Entitlements) Has(feature string) bool {
+	return e.Features[feature]
+}
+
+// Limit returns a quota of the plan, or Unlimited when it has none
+func (e *Entitlements) Limit(quota string) int64 {
+	if limit, ok := e.Limits[quota]; ok {
+		return limit
+	}
+	return Unlimited
+}
+
+var (
+	plans   = map[string]Entitlements{FreePlan: {Plan: FreePlan}}
+	plansMu sync.RWMutex
+)
+
+// RegisterPlan declares what a plan grants, usually from an init function:
+//
+//	billing.RegisterPlan("pro", billing.Entitlements{
+//		Tier:     1,
+//		Features: map[string]bool{"custom_domains": true},
+//		Limits:   map[string]int64{"experiences": 100},
+//	})
+func RegisterPlan(name string, entitlements Entitlements) {
+	plansMu.Lock()
+	defer plansMu.Unlock()
+	entitlements.Plan = name
+	plans[name] = entitlements
+}
+
+// PlanEntitlements returns what a plan grants; unknown plans grant nothing
+func PlanEntitlements(name string) Entitlements {
+	plansMu.RLock()
+	defer plansMu.RUnlock()
+	if entitlements, ok := plans[name]; ok {
+		return entitlements
+	}
+	return Entitlements{Plan: name}
+}
+
+// Includes reports whether the plan is, or is a higher tier than, the named plan
+func (e *Entitlements) Includes(plan string) bool {
+	if e.Plan == plan {
+		return true
+	}
+	required := PlanEntitlements(plan)
+	return required.Tier > 0 && e.Tier >= required.Tier
+}
+
+// ActiveSubscription returns the most recently updated entitled subscription
+// of an organization, or nil when it has none
+func ActiveSubscription(ctx context.Context, organizationID string) (*models.Subscription, error) {
+	var subscription models.Subscription
+	err := config.GetCollection(models.Subscription{}.CollectionName()).FindOne(ctx,
+		bson.M{
+			"organization_id": organizationID,
+			"status": bson.M{"$in": bson.A{
+				models.SubscriptionTrialing, models.SubscriptionActive, models.SubscriptionPastDue,
+			}},
+		},
+		options.FindOne().SetSort(bson.D{{Key: "updated_at", Value: -1}}),
+	).Decode(&subscription)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// ResolveEntitlements returns what an organization's subscription grants,
+// falling back to FreePlan
+func ResolveEntitlements(ctx context.Context, organizationID string) (*Entitlements, error) {
+	subscription, err := ActiveSubscription(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := FreePlan
+	if subscription != nil && subscription.Plan != "" {
+		plan = subscription.Plan
+	}
+	entitlements := PlanEntitlements(plan)
+	return &entitlements, nil
+}
@@ -0,0 +1,284 @@
+package billing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Lifecycle event types, also the NATS subjects they are published on
+const (
+	EventTrialExpiring        = "billing.trial_expiring"
+	EventTrialExpired         = "billing.trial_expired"
+	EventSubscriptionRenewed  = "billing.subscription_renewed"
+	EventSubscriptionCanceled = "billing.subscription_canceled"
+)
+
+// TrialExpiringNotice is how long before the end of a trial EventTrialExpiring is emitted
+var TrialExpiringNotice = 3 * 24 * time.Hour
+
+// LifecycleEvent reports a change in an organization's subscription
+type LifecycleEvent struct {
+	Type             string     `json:"type"`
+	OrganizationID   string     `json:"organization_id"`
+	SubscriptionID   string     `json:"subscription_id"`
+	Plan             string     `json:"plan"`
+	Status           string     `json:"status"`
+	TrialEnd         *time.Time `json:"trial_end,omitempty"`
+	CurrentPeriodEnd time.Time  `json:"current_period_end"`
+	OccurredAt       time.Time  `json:"occurred_at"`
+}
+
+// LifecycleHook reacts to a lifecycle event, e.g. downgrading an organization
+// whose trial expired
+type LifecycleHook func(ctx context.Context, event LifecycleEvent) error
+
+var (
+	lifecycleHooks   = map[string][]LifecycleHook{}
+	lifecycleHooksMu sync.RWMutex
+)
+
+// Email templates sent to the billing contact, with the LifecycleEvent as data
+var lifecycleTemplates = map[string]string{
+	EventTrialExpiring:        "billing_trial_expiring",
+	EventTrialExpired:         "billing_trial_expired",
+	EventSubscriptionRenewed:  "billing_subscription_renewed",
+	EventSubscriptionCanceled: "billing_subscription_canceled",
+}
+
+func init() {
+	sample := map[string]interface{}{
+		"Plan":             "pro",
+		"TrialEnd":         time.Now().Add(TrialExpiringNotice),
+		"CurrentPeriodEnd": time.Now().Add(30 * 24 * time.Hour),
+	}
+
+	utils.RegisterEmailTemplate(utils.EmailTemplate{
+		Name:    "billing_trial_expiring",
+		Subject: "Your {{.Plan}} trial ends soon",
+		HTML: `
+        <h1>Your trial ends on {{.TrialEnd.Format "Jan 2, 2006"}}</h1>
+        <p>Add a payment method before then to keep using the {{.Plan}} plan without interruption.</p>
+    `,
+		SampleData: sample,
+	})
+	utils.RegisterEmailTemplate(utils.EmailTemplate{
+		Name:    "billing_trial_expired",
+		Subject: "Your {{.Plan}} trial has ended",
+		HTML: `
+        <h1>Your trial has ended</h1>
+        <p>Subscribe to the {{.Plan}} plan to restore its features.</p>
+    `,
+		SampleData: sample,
+	})
+	utils.RegisterEmailTemplate(utils.EmailTemplate{
+		Name:    "billing_subscription_renewed",
+		Subject: "Your {{.Plan}} subscription has been renewed",
+		HTML: `
+        <h1>Thank you for staying with us</h1>
+        <p>Your {{.Plan}} subscription now runs until {{.CurrentPeriodEnd.Format "Jan 2, 2006"}}.</p>
+    `,
+		SampleData: sample,
+	})
+	utils.RegisterEmailTemplate(utils.EmailTemplate{
+		Name:    "billing_subscription_canceled",
+		Subject: "Your {{.Plan}} subscription has been canceled",
+		HTML: `
+        <h1>Your subscription has been canceled</h1>
+        <p>Your organization has moved to the free plan. You can subscribe again at any time.</p>
+    `,
+		SampleData: sample,
+	})
+}
+
+// OnLifecycle registers hook for a lifecycle event type
+func OnLifecycle(eventType string, hook LifecycleHook) {
+	lifecycleHooksMu.Lock()
+	defer lifecycleHooksMu.Unlock()
+	lifecycleHooks[eventType] = append(lifecycleHooks[eventType], hook)
+}
+
+// emitLifecycle runs the hooks for an event, publishes it on NATS and emails
+// the billing contact. Hook failures are returned so the caller can retry;
+// notification failures are only logged.
+func emitLifecycle(ctx context.Context, eventType string, subscription *models.Subscription) error {
+	event := LifecycleEvent{
+		Type:             eventType,
+		OrganizationID:   subscription.OrganizationID,
+		SubscriptionID:   subscription.ID,
+		Plan:             subscription.Plan,
+		Status:           subscription.Status,
+		TrialEnd:         subscription.TrialEnd,
+		CurrentPeriodEnd: subscription.CurrentPeriodEnd,
+		OccurredAt:       utils.Now(),
+	}
+
+	lifecycleHooksMu.RLock()
+	hooks := append([]LifecycleHook(nil), lifecycleHooks[eventType]...)
+	lifecycleHooksMu.RUnlock()
+	for _, hook := range hooks {
+		if err := hook(ctx, event); err != nil {
+			return fmt.Errorf("%s hook for organization %s: %w", eventType, event.OrganizationID, err)
+		}
+	}
+
+	if config.NATS != nil {
+		if err := utils.PublishEvent(eventType, event); err != nil {
+			utils.LogWarning(fmt.Sprintf("Failed to publish %s: %v", eventType, err))
+		}
+	}
+
+	var customer models.BillingCustomer
+	err := config.GetCollection(models.BillingCustomer{}.CollectionName()).
+		FindOne(ctx, bson.M{"_id": event.OrganizationID}).Decode(&customer)
+	if err == nil && customer.Email != "" {
+		if err := utils.SendTemplatedEmail(customer.Email, lifecycleTemplates[eventType], event); err != nil {
+			utils.LogWarning(fmt.Sprintf("Failed to email %s to organization %s: %v", eventType, event.OrganizationID, err))
+		}
+	} else if err != nil && err != mongo.ErrNoDocuments {
+		utils.LogWarning(fmt.Sprintf("Failed to load billing contact of organization %s: %v", event.OrganizationID, err))
+	}
+
+	return nil
+}
+
+// ProcessTrialEvents emits EventTrialExpiring for trials ending within
+// TrialExpiringNotice and EventTrialExpired for trials that ended within the
+// same window, once per subscription. It returns the number of events emitted.
+func ProcessTrialEvents(ctx context.Context) (int, error) {
+	now := utils.Now()
+	emitted := 0
+
+	expiring, err := emitTrialEvents(ctx, EventTrialExpiring, "trial_expiring_notified_at", bson.M{
+		"status":    models.SubscriptionTrialing,
+		"trial_end": bson.M{"$gt": now, "$lte": now.Add(TrialExpiringNotice)},
+	})
+	emitted += expiring
+	if err != nil {
+		return emitted, err
+	}
+
+	expired, err := emitTrialEvents(ctx, EventTrialExpired, "trial_expired_notified_at", bson.M{
+		"status":    models.SubscriptionTrialing,
+		"trial_end": bson.M{"$gt": now.Add(-TrialExpiringNotice), "$lte": now},
+	})
+	return emitted + expired, err
+}
+
+// emitTrialEvents emits eventType for the subscriptions matching filter that
+// have not been notified yet, recording the notification in notifiedField
+func emitTrialEvents(ctx context.Context, eventType, notifiedField string, filter bson.M) (int, error) {
+	collection := config.GetCollection(models.Subscription{}.CollectionName())
+	filter[notifiedField] = bson.M{"$exists": false}
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var due []models.Subscription
+	if err := cursor.All(ctx, &due); err != nil {
+		return 0, err
+	}
+
+	emitted := 0
+	for i := range due {
+		if err := emitLifecycle(ctx, eventType, &due[i]); err != nil {
+			// Left unmarked so the next run retries
+			utils.LogError(err.Error())
+			continue
+		}
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": due[i].ID},
+			bson.M{"$set": bson.M{notifiedField: utils.Now()}}); err != nil {
+			return emitted, err
+		}
+		emitted++
+	}
+	return emitted, nil
+}
+
+// StartLifecycleWorker runs ProcessTrialEvents periodically. Call the returned
+// function to stop the worker.
+func StartLifecycleWorker(interval time.Duration) func() {
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				count, err := ProcessTrialEvents(ctx)
+				cancel()
+				if err != nil {
+					utils.LogError(fmt.Sprintf("Billing lifecycle job failed: %v", err))
+				} else if count > 0 {
+					log.Printf("💳 Emitted %d trial lifecycle events", count)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		stopOnce.Do(func() { close(stop) })
+	}
+}
+
+// invoice is the part of a Stripe invoice needed to detect renewals
+type invoice struct {
+	Subscription  string `json:"subscription"`
+	BillingReason string `json:"billing_reason"`
+}
+
+// emitWebhookLifecycle emits the lifecycle events carried by Stripe webhooks:
+// renewals from paid subscription-cycle invoices and cancellations from
+// deleted subscriptions
+func emitWebhookLifecycle(ctx context.Context, event Event) error {
+	var subscriptionID, eventType string
+	switch event.Type {
+	case "invoice.paid":
+		var paid invoice
+		if err := json.Unmarshal(event.Data.Object, &paid); err != nil {
+			return fmt.Errorf("decode %s: %w", event.Type, err)
+		}
+		if paid.BillingReason != "subscription_cycle" || paid.Subscription == "" {
+			return nil
+		}
+		subscriptionID, eventType = paid.Subscription, EventSubscriptionRenewed
+	case "customer.subscription.deleted":
+		var deleted Subscription
+		if err := json.Unmarshal(event.Data.Object, &deleted); err != nil {
+			return fmt.Errorf("decode %s: %w", event.Type, err)
+		}
+		subscriptionID, eventType = deleted.ID, EventSubscriptionCanceled
+	default:
+		return nil
+	}
+
+	var subscription models.Subscription
+	err := config.GetCollection(models.Subscription{}.CollectionName()).
+		FindOne(ctx, bson.M{"_id": subscriptionID}).Decode(&subscription)
+	if err == mongo.ErrNoDocuments {
+		utils.LogWarning(fmt.Sprintf("Skipping %s for unknown subscription %s", eventType, subscriptionID))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return emitLifecycle(ctx, eventType, &subscription)
+}
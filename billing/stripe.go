@@ -0,0 +1,205 @@
+// Package billing integrates Stripe: a small API client, the webhook that
+// keeps customers and subscriptions in sync in Mongo, and the entitlement
+// resolver that turns an organization's subscription into plan features and
+// limits:
+//
+//	client, err := billing.ClientFromConfig()
+//	customer, err := billing.EnsureCustomer(ctx, client, organizationID, email, name)
+//
+//	// routes.SetupBillingRoutes mounts POST /webhooks/stripe
+//	entitlements, err := billing.ResolveEntitlements(ctx, organizationID)
+//	if entitlements.Limit("experiences") <= count { ... }
+package billing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// DefaultBaseURL is the Stripe API endpoint
+const DefaultBaseURL = "https://api.stripe.com"
+
+// Error is an error response from the Stripe API
+type Error struct {
+	StatusCode int
+	Type       string `json:"type"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("stripe: %s (status %d, %s)", e.Message, e.StatusCode, e.Type)
+}
+
+// Client calls the Stripe API with a secret key
+type Client struct {
+	SecretKey string
+	BaseURL   string // Default DefaultBaseURL
+	HTTP      *http.Client
+}
+
+// NewClient creates a client for a Stripe secret key
+func NewClient(secretKey string) *Client {
+	return &Client{
+		SecretKey: secretKey,
+		BaseURL:   DefaultBaseURL,
+		HTTP:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// ClientFromConfig creates a client from the stripe-secret-key secret (STRIPE_SECRET_KEY)
+func ClientFromConfig() (*Client, error) {
+	secretKey, err := config.GetSecret("stripe-secret-key", "STRIPE_SECRET_KEY")
+	if err != nil {
+		return nil, fmt.Errorf("billing: %w", err)
+	}
+	return NewClient(secretKey), nil
+}
+
+// Customer is a Stripe customer
+type Customer struct {
+	ID       string            `json:"id"`
+	Email    string            `json:"email"`
+	Name     string            `json:"name"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// Subscription is a Stripe subscription, reduced to the fields synced to Mongo
+type Subscription struct {
+	ID                string            `json:"id"`
+	Customer          string            `json:"customer"`
+	Status            string            `json:"status"`
+	CurrentPeriodEnd  int64             `json:"current_period_end"`
+	CancelAtPeriodEnd bool              `json:"cancel_at_period_end"`
+	TrialEnd          int64             `json:"trial_end"`
+	CanceledAt        int64             `json:"canceled_at"`
+	Created           int64             `json:"created"`
+	Metadata          map[string]string `json:"metadata"`
+	Items             struct {
+		Data []struct {
+			Price Price `json:"price"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+// Price is the price of a subscription item
+type Price struct {
+	ID        string            `json:"id"`
+	LookupKey string            `json:"lookup_key"`
+	Product   string            `json:"product"`
+	Metadata  map[string]string `json:"metadata"`
+}
+
+// Plan returns the plan name of a subscription: its "plan" metadata, else the
+// "plan" metadata or lookup key of its first price
+func (s *Subscription) Plan() string {
+	if plan := s.Metadata["plan"]; plan != "" {
+		return plan
+	}
+	for _, item := range s.Items.Data {
+		if plan := item.Price.Metadata["plan"]; plan != "" {
+			return plan
+		}
+		if item.Price.LookupKey != "" {
+			return item.Price.LookupKey
+		}
+	}
+	return ""
+}
+
+// CreateCustomer creates a customer tagged with the organization ID. The
+// organization ID doubles as idempotency key so a retried call cannot create
+// a second customer.
+func (c *Client) CreateCustomer(ctx context.Context, organizationID, email, name string) (*Customer, error) {
+	form := url.Values{}
+	form.Set("email", email)
+	form.Set("name", name)
+	form.Set("metadata[organization_id]", organizationID)
+
+	var customer Customer
+	if err := c.do(ctx, http.MethodPost, "/v1/customers", form, "customer-"+organizationID, &customer); err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+// GetSubscription fetches a subscription with its prices
+func (c *Client) GetSubscription(ctx context.Context, id string) (*Subscription, error) {
+	var subscription Subscription
+	if err := c.do(ctx, http.MethodGet, "/v1/subscriptions/"+url.PathEscape(id), nil, "", &subscription); err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// CancelSubscription cancels a subscription at the end of its current period
+func (c *Client) CancelSubscription(ctx context.Context, id string) (*Subscription, error) {
+	form := url.Values{}
+	form.Set("cancel_at_period_end", "true")
+
+	var subscription Subscription
+	if err := c.do(ctx, http.MethodPost, "/v1/subscriptions/"+url.PathEscape(id), form, "", &subscription); err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// do sends a form-encoded request, retrying rate limits and server errors, and decodes the response into out
+func (c *Client) do(ctx context.Context, method, path string, form url.Values, idempotencyKey string, out interface{}) error {
+	return utils.Retry(ctx, utils.DefaultRetryPolicy, func() error {
+		var body io.Reader
+		if form != nil {
+			body = strings.NewReader(form.Encode())
+		}
+		req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.BaseURL, "/")+path, body)
+		if err != nil {
+			return utils.PermanentError(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+c.SecretKey)
+		if form != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		if idempotencyKey != "" {
+			req.Header.Set("Idempotency-Key", idempotencyKey)
+		}
+
+		resp, err := c.HTTP.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= 300 {
+			var envelope struct {
+				Error Error `json:"error"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&envelope)
+			apiErr := &envelope.Error
+			apiErr.StatusCode = resp.StatusCode
+			if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+				return apiErr
+			}
+			return utils.PermanentError(apiErr)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return utils.PermanentError(fmt.Errorf("stripe: decode %s: %w", path, err))
+		}
+		return nil
+	})
+}
+
+// IsNotFound reports a Stripe 404, e.g. a deleted customer
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,332 @@
+package billing
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SignatureTolerance is how old a webhook signature timestamp may be
+var SignatureTolerance = 5 * time.Minute
+
+// EventClaimTimeout is how long an event claimed by a delivery that never
+// completed (e.g. the process crashed) blocks redeliveries
+var EventClaimTimeout = 5 * time.Minute
+
+// ErrInvalidSignature is returned for webhooks not signed with the endpoint secret
+var ErrInvalidSignature = errors.New("invalid Stripe webhook signature")
+
+// Event is a Stripe webhook event
+type Event struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// EventHandler reacts to a Stripe event after the built-in sync has run
+type EventHandler func(ctx context.Context, event Event) error
+
+var (
+	eventHandlers   = map[string][]EventHandler{}
+	eventHandlersMu sync.RWMutex
+)
+
+// OnEvent registers handler for an event type such as "invoice.payment_failed"
+func OnEvent(eventType string, handler EventHandler) {
+	eventHandlersMu.Lock()
+	defer eventHandlersMu.Unlock()
+	eventHandlers[eventType] = append(eventHandlers[eventType], handler)
+}
+
+// WebhookSecret loads the endpoint signing secret (stripe-webhook-secret / STRIPE_WEBHOOK_SECRET)
+func WebhookSecret() (string, error) {
+	return config.GetSecret("stripe-webhook-secret", "STRIPE_WEBHOOK_SECRET")
+}
+
+// VerifySignature checks the Stripe-Signature header ("t=...,v1=...") of a
+// webhook payload against the endpoint secret and rejects stale timestamps
+func VerifySignature(payload []byte, header, secret string) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if age := utils.Now().Sub(time.Unix(seconds, 0)); age > SignatureTolerance || age < -SignatureTolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+
+	for _, signature := range signatures {
+		decoded, err := hex.DecodeString(signature)
+		if err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// ProcessEvent syncs customers and subscriptions from an event and runs the
+// handlers registered with OnEvent. Stripe redelivers events, concurrently at
+// times, so an event is processed once: it is claimed by inserting its record
+// first, and the claim is released on failure so the redelivery retries it.
+// Lifecycle events are emitted at most once per event (see emitEventLifecycle).
+// Stripe does not deliver events in order, so subscription events older than
+// the stored state are not applied.
+func ProcessEvent(ctx context.Context, event Event) error {
+	claimed, err := claimEvent(ctx, event)
+	if err != nil || !claimed {
+		return err
+	}
+
+	events := config.GetCollection(models.BillingEvent{}.CollectionName())
+	if err := handleEvent(ctx, event); err != nil {
+		// Backdated rather than deleted, keeping the lifecycle marker
+		_, releaseErr := events.UpdateOne(ctx, bson.M{"_id": event.ID, "completed": false},
+			bson.M{"$set": bson.M{"processed_at": utils.Now().Add(-EventClaimTimeout - time.Second)}})
+		if releaseErr != nil {
+			utils.LogWarning(fmt.Sprintf("Failed to release Stripe event %s: %v", event.ID, releaseErr))
+		}
+		return err
+	}
+
+	_, err = events.UpdateOne(ctx, bson.M{"_id": event.ID},
+		bson.M{"$set": bson.M{"completed": true, "processed_at": utils.Now()}})
+	return err
+}
+
+// claimEvent records event as being processed, reporting false when it was
+// processed before or is being processed by another delivery. Claims older
+// than EventClaimTimeout are taken over.
+func claimEvent(ctx context.Context, event Event) (bool, error) {
+	events := config.GetCollection(models.BillingEvent{}.CollectionName())
+	now := utils.Now()
+
+	_, err := events.InsertOne(ctx, models.BillingEvent{ID: event.ID, Type: event.Type, ProcessedAt: now})
+	if err == nil {
+		return true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return false, err
+	}
+
+	result, err := events.UpdateOne(ctx,
+		bson.M{"_id": event.ID, "completed": false, "processed_at": bson.M{"$lt": now.Add(-EventClaimTimeout)}},
+		bson.M{"$set": bson.M{"processed_at": now}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// handleEvent applies a claimed event
+func handleEvent(ctx context.Context, event Event) error {
+	switch {
+	case strings.HasPrefix(event.Type, "customer.subscription."):
+		var subscription Subscription
+		if err := json.Unmarshal(event.Data.Object, &subscription); err != nil {
+			return fmt.Errorf("decode %s: %w", event.Type, err)
+		}
+		if err := syncSubscription(ctx, &subscription, time.Unix(event.Created, 0).UTC()); err != nil {
+			return err
+		}
+	case event.Type == "customer.created" || event.Type == "customer.updated":
+		var customer Customer
+		if err := json.Unmarshal(event.Data.Object, &customer); err != nil {
+			return fmt.Errorf("decode %s: %w", event.Type, err)
+		}
+		if err := syncCustomer(ctx, &customer); err != nil {
+			return err
+		}
+	}
+
+	if err := emitEventLifecycle(ctx, event); err != nil {
+		return err
+	}
+
+	eventHandlersMu.RLock()
+	handlers := append([]EventHandler(nil), eventHandlers[event.Type]...)
+	eventHandlersMu.RUnlock()
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			return fmt.Errorf("%s handler: %w", event.Type, err)
+		}
+	}
+	return nil
+}
+
+// emitEventLifecycle records on the event's claim that its lifecycle event is
+// emitted before emitting it, and skips events recorded earlier. The record is
+// cleared again when emitting fails, so the hooks are retried.
+func emitEventLifecycle(ctx context.Context, event Event) error {
+	events := config.GetCollection(models.BillingEvent{}.CollectionName())
+	result, err := events.UpdateOne(ctx,
+		bson.M{"_id": event.ID, "lifecycle_emitted": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"lifecycle_emitted": true}},
+	)
+	if err != nil || result.ModifiedCount == 0 {
+		return err
+	}
+
+	if err := emitWebhookLifecycle(ctx, event); err != nil {
+		if _, clearErr := events.UpdateOne(ctx, bson.M{"_id": event.ID},
+			bson.M{"$unset": bson.M{"lifecycle_emitted": ""}}); clearErr != nil {
+			utils.LogWarning(fmt.Sprintf("Failed to clear lifecycle record of Stripe event %s: %v", event.ID, clearErr))
+		}
+		return err
+	}
+	return nil
+}
+
+// EnsureCustomer returns the Stripe customer of an organization, creating it
+// on first use
+func EnsureCustomer(ctx context.Context, client *Client, organizationID, email, name string) (*models.BillingCustomer, error) {
+	customers := config.GetCollection(models.BillingCustomer{}.CollectionName())
+
+	var existing models.BillingCustomer
+	err := customers.FindOne(ctx, bson.M{"_id": organizationID}).Decode(&existing)
+	if err == nil {
+		return &existing, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	customer, err := client.CreateCustomer(ctx, organizationID, email, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := syncCustomer(ctx, customer); err != nil {
+		return nil, err
+	}
+
+	var stored models.BillingCustomer
+	if err := customers.FindOne(ctx, bson.M{"_id": organizationID}).Decode(&stored); err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// syncCustomer stores the organization link of a customer tagged with organization_id metadata
+func syncCustomer(ctx context.Context, customer *Customer) error {
+	organizationID := customer.Metadata["organization_id"]
+	if organizationID == "" {
+		return nil
+	}
+
+	now := utils.Now()
+	_, err := config.GetCollection(models.BillingCustomer{}.CollectionName()).UpdateOne(ctx,
+		bson.M{"_id": organizationID},
+		bson.M{
+			"$set": bson.M{
+				"stripe_customer_id": customer.ID,
+				"email":              customer.Email,
+				"name":               customer.Name,
+				"updated_at":         now,
+			},
+			"$setOnInsert": bson.M{"created_at": now},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// SyncSubscription stores a Stripe subscription for its organization, taken
+// from the subscription's organization_id metadata or its customer's link.
+// The subscription should be freshly read from the API, e.g. with
+// Client.GetSubscription; it replaces the state of any earlier event.
+func SyncSubscription(ctx context.Context, subscription *Subscription) error {
+	return syncSubscription(ctx, subscription, utils.Now())
+}
+
+// syncSubscription stores a subscription as of syncedAt, unless the stored
+// state comes from a later event
+func syncSubscription(ctx context.Context, subscription *Subscription, syncedAt time.Time) error {
+	organizationID := subscription.Metadata["organization_id"]
+	if organizationID == "" {
+		var customer models.BillingCustomer
+		err := config.GetCollection(models.BillingCustomer{}.CollectionName()).
+			FindOne(ctx, bson.M{"stripe_customer_id": subscription.Customer}).Decode(&customer)
+		if err == mongo.ErrNoDocuments {
+			return fmt.Errorf("subscription %s: customer %s is not linked to an organization", subscription.ID, subscription.Customer)
+		}
+		if err != nil {
+			return err
+		}
+		organizationID = customer.OrganizationID
+	}
+
+	set := bson.M{
+		"organization_id":      organizationID,
+		"stripe_customer_id":   subscription.Customer,
+		"plan":                 subscription.Plan(),
+		"status":               subscription.Status,
+		"current_period_end":   time.Unix(subscription.CurrentPeriodEnd, 0).UTC(),
+		"cancel_at_period_end": subscription.CancelAtPeriodEnd,
+		"trial_end":            unixTime(subscription.TrialEnd),
+		"canceled_at":          unixTime(subscription.CanceledAt),
+		"synced_at":            syncedAt,
+		"updated_at":           utils.Now(),
+	}
+	_, err := config.GetCollection(models.Subscription{}.CollectionName()).UpdateOne(ctx,
+		bson.M{"_id": subscription.ID, "$or": bson.A{
+			bson.M{"synced_at": bson.M{"$exists": false}},
+			bson.M{"synced_at": bson.M{"$lte": syncedAt}},
+		}},
+		bson.M{"$set": set, "$setOnInsert": bson.M{"created_at": time.Unix(subscription.Created, 0).UTC()}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		// The filter missed an existing subscription: a later state is stored
+		log.Printf("💳 Ignoring stale state of subscription %s from %s", subscription.ID, syncedAt.Format(time.RFC3339))
+		return nil
+	}
+	return err
+}
+
+// unixTime converts an optional Stripe timestamp; zero is nil
+func unixTime(seconds int64) *time.Time {
+	if seconds == 0 {
+		return nil
+	}
+	t := time.Unix(seconds, 0).UTC()
+	return &t
+}
@@ -0,0 +1,124 @@
+package bqexport
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CheckpointsCollection records how far each export has progressed
+const CheckpointsCollection = "bigquery_export_checkpoints"
+
+// exportCheckpoint is the last exported audit entry
+type exportCheckpoint struct {
+	ID        string             `bson:"_id"`
+	Timestamp time.Time          `bson:"timestamp"`
+	LastID    primitive.ObjectID `bson:"last_id"`
+	UpdatedAt time.Time          `bson:"updated_at"`
+}
+
+// ExportAuditLogs copies audit entries logged since the last checkpoint to table
+// and returns the number exported. Entries are read in (timestamp, _id) order
+// and the checkpoint advances after every batch, so an interrupted run resumes
+// where it stopped.
+func (e *Exporter) ExportAuditLogs(ctx context.Context, table string) (int, error) {
+	if table == "" {
+		table = AuditTable
+	}
+	if err := e.EnsureTable(ctx, table, AuditSchema); err != nil {
+		return 0, err
+	}
+
+	checkpoints := config.GetCollection(CheckpointsCollection)
+	checkpoint := exportCheckpoint{ID: table}
+	err := checkpoints.FindOne(ctx, bson.M{"_id": table}).Decode(&checkpoint)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return 0, fmt.Errorf("load export checkpoint: %w", err)
+	}
+
+	exported := 0
+	for {
+		filter := bson.M{"$or": bson.A{
+			bson.M{"timestamp": bson.M{"$gt": checkpoint.Timestamp}},
+			bson.M{"timestamp": checkpoint.Timestamp, "_id": bson.M{"$gt": checkpoint.LastID}},
+		}}
+		findOptions := options.Find().
+			SetSort(bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}}).
+			SetLimit(MaxBatchRows)
+
+		cursor, err := config.GetCollection("oms_audit_logs").Find(ctx, filter, findOptions)
+		if err != nil {
+			return exported, err
+		}
+		var entries []models.AuditLog
+		if err := cursor.All(ctx, &entries); err != nil {
+			return exported, err
+		}
+		if len(entries) == 0 {
+			return exported, nil
+		}
+
+		rows := make([]Row, len(entries))
+		for i, entry := range entries {
+			rows[i] = AuditRow(entry)
+		}
+		if err := e.Insert(ctx, table, rows); err != nil {
+			return exported, err
+		}
+		exported += len(entries)
+
+		last := entries[len(entries)-1]
+		checkpoint.Timestamp, checkpoint.LastID, checkpoint.UpdatedAt = last.Timestamp, last.ID, utils.Now()
+		_, err = checkpoints.ReplaceOne(ctx, bson.M{"_id": table}, checkpoint, options.Replace().SetUpsert(true))
+		if err != nil {
+			return exported, fmt.Errorf("save export checkpoint: %w", err)
+		}
+
+		if len(entries) < MaxBatchRows {
+			return exported, nil
+		}
+	}
+}
+
+// StartAuditExport exports new audit entries to AuditTable every interval. Call
+// the returned function to stop it.
+func (e *Exporter) StartAuditExport(interval time.Duration) func() {
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+				exported, err := e.ExportAuditLogs(ctx, AuditTable)
+				cancel()
+
+				if err != nil {
+					utils.LogError(fmt.Sprintf("BigQuery audit export failed after %d entries: %v", exported, err))
+				} else if exported > 0 {
+					log.Printf("📤 Exported %d audit entries to BigQuery", exported)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		stopOnce.Do(func() { close(stop) })
+	}
+}
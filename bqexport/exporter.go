@@ -0,0 +1,243 @@
+// Package bqexport streams audit logs and analytics events to BigQuery for
+// reporting. Tables are created (day-partitioned on timestamp) and new columns
+// are added automatically; inserts are batched and retried on quota errors.
+package bqexport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	bq "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// MaxBatchRows is the largest number of rows sent in one insertAll request
+const MaxBatchRows = 500
+
+// Options configures an Exporter
+type Options struct {
+	ProjectID       string
+	Dataset         string
+	Location        string // Dataset location when it has to be created (default "US")
+	CredentialsJSON []byte // Service account key; empty uses Application Default Credentials
+}
+
+// Exporter writes rows to BigQuery tables in one dataset
+type Exporter struct {
+	service *bq.Service
+	options Options
+}
+
+// Row is one BigQuery row; InsertID lets BigQuery drop duplicates from retries
+type Row struct {
+	InsertID string
+	Values   map[string]bq.JsonValue
+}
+
+// insertRetryPolicy backs off on quota and rate limit errors, which clear up slowly
+var insertRetryPolicy = utils.RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: time.Second,
+	MaxBackoff:     30 * time.Second,
+	Jitter:         0.3,
+	Retryable:      isRetryable,
+}
+
+// NewExporter connects to BigQuery and ensures the dataset exists
+func NewExporter(ctx context.Context, options Options) (*Exporter, error) {
+	if options.ProjectID == "" || options.Dataset == "" {
+		return nil, fmt.Errorf("bigquery project and dataset are required")
+	}
+	if options.Location == "" {
+		options.Location = "US"
+	}
+
+	clientOptions := []option.ClientOption{option.WithScopes(bq.BigqueryScope)}
+	if len(options.CredentialsJSON) > 0 {
+		clientOptions = append(clientOptions, option.WithCredentialsJSON(options.CredentialsJSON))
+	}
+
+	service, err := bq.NewService(ctx, clientOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("create bigquery client: %w", err)
+	}
+
+	exporter := &Exporter{service: service, options: options}
+	if err := exporter.ensureDataset(ctx); err != nil {
+		return nil, err
+	}
+	return exporter, nil
+}
+
+// NewExporterFromConfig builds an exporter from BIGQUERY_PROJECT (or
+// GOOGLE_CLOUD_PROJECT), BIGQUERY_DATASET and BIGQUERY_LOCATION. Credentials come
+// from the "bigquery-credentials" secret or BIGQUERY_CREDENTIALS, falling back
+// to Application Default Credentials.
+func NewExporterFromConfig(ctx context.Context) (*Exporter, error) {
+	options := Options{
+		ProjectID: config.GetEnv("BIGQUERY_PROJECT", config.GetEnv("GOOGLE_CLOUD_PROJECT", "")),
+		Dataset:   config.GetEnv("BIGQUERY_DATASET", "analytics"),
+		Location:  config.GetEnv("BIGQUERY_LOCATION", ""),
+	}
+
+	credentials, err := config.GetSecret("bigquery-credentials", "BIGQUERY_CREDENTIALS")
+	switch {
+	case err == nil:
+		options.CredentialsJSON = []byte(credentials)
+	case errors.Is(err, config.ErrSecretNotFound):
+		log.Println("🔐 No BigQuery credentials configured, using Application Default Credentials")
+	default:
+		return nil, err
+	}
+
+	return NewExporter(ctx, options)
+}
+
+func (e *Exporter) ensureDataset(ctx context.Context) error {
+	_, err := e.service.Datasets.Get(e.options.ProjectID, e.options.Dataset).Context(ctx).Do()
+	if err == nil {
+		return nil
+	}
+	if !isNotFound(err) {
+		return fmt.Errorf("get dataset %s: %w", e.options.Dataset, err)
+	}
+
+	_, err = e.service.Datasets.Insert(e.options.ProjectID, &bq.Dataset{
+		DatasetReference: &bq.DatasetReference{ProjectId: e.options.ProjectID, DatasetId: e.options.Dataset},
+		Location:         e.options.Location,
+	}).Context(ctx).Do()
+	if err != nil && !isAlreadyExists(err) {
+		return fmt.Errorf("create dataset %s: %w", e.options.Dataset, err)
+	}
+	log.Printf("✅ Created BigQuery dataset %s", e.options.Dataset)
+	return nil
+}
+
+// EnsureTable creates table with schema, partitioned by day on "timestamp", or
+// adds columns missing from an existing table. Columns are never removed or retyped.
+func (e *Exporter) EnsureTable(ctx context.Context, table string, schema []*bq.TableFieldSchema) error {
+	existing, err := e.service.Tables.Get(e.options.ProjectID, e.options.Dataset, table).Context(ctx).Do()
+	if isNotFound(err) {
+		_, err = e.service.Tables.Insert(e.options.ProjectID, e.options.Dataset, &bq.Table{
+			TableReference: &bq.TableReference{
+				ProjectId: e.options.ProjectID,
+				DatasetId: e.options.Dataset,
+				TableId:   table,
+			},
+			Schema:           &bq.TableSchema{Fields: schema},
+			TimePartitioning: &bq.TimePartitioning{Type: "DAY", Field: "timestamp"},
+		}).Context(ctx).Do()
+		if err != nil && !isAlreadyExists(err) {
+			return fmt.Errorf("create table %s: %w", table, err)
+		}
+		log.Printf("✅ Created BigQuery table %s.%s", e.options.Dataset, table)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get table %s: %w", table, err)
+	}
+
+	known := map[string]bool{}
+	fields := []*bq.TableFieldSchema{}
+	if existing.Schema != nil {
+		fields = existing.Schema.Fields
+		for _, field := range fields {
+			known[field.Name] = true
+		}
+	}
+
+	var added []string
+	for _, field := range schema {
+		if !known[field.Name] {
+			// New columns must be nullable to be added to an existing table
+			column := *field
+			column.Mode = "NULLABLE"
+			fields = append(fields, &column)
+			added = append(added, field.Name)
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+
+	_, err = e.service.Tables.Patch(e.options.ProjectID, e.options.Dataset, table, &bq.Table{
+		Schema: &bq.TableSchema{Fields: fields},
+	}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("add columns %v to %s: %w", added, table, err)
+	}
+	log.Printf("🔄 Added columns %v to BigQuery table %s.%s", added, e.options.Dataset, table)
+	return nil
+}
+
+// Insert streams rows into table in batches of MaxBatchRows, retrying quota errors
+func (e *Exporter) Insert(ctx context.Context, table string, rows []Row) error {
+	for start := 0; start < len(rows); start += MaxBatchRows {
+		end := min(start+MaxBatchRows, len(rows))
+		if err := e.insertBatch(ctx, table, rows[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Exporter) insertBatch(ctx context.Context, table string, rows []Row) error {
+	request := &bq.TableDataInsertAllRequest{
+		Rows: make([]*bq.TableDataInsertAllRequestRows, len(rows)),
+	}
+	for i, row := range rows {
+		request.Rows[i] = &bq.TableDataInsertAllRequestRows{InsertId: row.InsertID, Json: row.Values}
+	}
+
+	return utils.Retry(ctx, insertRetryPolicy, func() error {
+		response, err := e.service.Tabledata.InsertAll(e.options.ProjectID, e.options.Dataset, table, request).Context(ctx).Do()
+		if err != nil {
+			return err
+		}
+		if len(response.InsertErrors) > 0 {
+			first := response.InsertErrors[0]
+			reason := ""
+			if len(first.Errors) > 0 {
+				reason = first.Errors[0].Reason + ": " + first.Errors[0].Message
+			}
+			return utils.PermanentError(fmt.Errorf("bigquery rejected %d of %d rows in %s (row %d %s)",
+				len(response.InsertErrors), len(rows), table, first.Index, reason))
+		}
+		return nil
+	})
+}
+
+// isRetryable reports quota, rate limit and server errors
+func isRetryable(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return true // Network errors
+	}
+	if apiErr.Code == http.StatusTooManyRequests || apiErr.Code >= 500 {
+		return true
+	}
+	for _, item := range apiErr.Errors {
+		switch item.Reason {
+		case "quotaExceeded", "rateLimitExceeded", "backendError", "internalError":
+			return true
+		}
+	}
+	return false
+}
+
+func isNotFound(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
+
+func isAlreadyExists(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusConflict
+}
@@ -0,0 +1,121 @@
+package bqexport
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/analytics"
+	"github.com/praleedsuvarna/shared-libs/models"
+	bq "google.golang.org/api/bigquery/v2"
+)
+
+// Default table names
+const (
+	AuditTable     = "audit_logs"
+	AnalyticsTable = "analytics_events"
+)
+
+// AuditSchema is the BigQuery schema for audit logs; metadata is stored as JSON text
+var AuditSchema = []*bq.TableFieldSchema{
+	{Name: "id", Type: "STRING", Mode: "REQUIRED"},
+	{Name: "timestamp", Type: "TIMESTAMP", Mode: "REQUIRED"},
+	{Name: "admin_id", Type: "STRING"},
+	{Name: "organization_id", Type: "STRING"},
+	{Name: "action", Type: "STRING"},
+	{Name: "target_id", Type: "STRING"},
+	{Name: "metadata", Type: "STRING"},
+	{Name: "sequence", Type: "INTEGER"},
+	{Name: "hash", Type: "STRING"},
+}
+
+// AnalyticsSchema is the BigQuery schema for analytics events; props are stored as JSON text
+var AnalyticsSchema = []*bq.TableFieldSchema{
+	{Name: "id", Type: "STRING", Mode: "REQUIRED"},
+	{Name: "timestamp", Type: "TIMESTAMP", Mode: "REQUIRED"},
+	{Name: "name", Type: "STRING", Mode: "REQUIRED"},
+	{Name: "user_id", Type: "STRING"},
+	{Name: "organization_id", Type: "STRING"},
+	{Name: "country", Type: "STRING"},
+	{Name: "props", Type: "STRING"},
+}
+
+// AuditRow converts an audit log entry into a BigQuery row
+func AuditRow(entry models.AuditLog) Row {
+	values := map[string]bq.JsonValue{
+		"id":        entry.ID.Hex(),
+		"timestamp": entry.Timestamp.UTC().Format(time.RFC3339Nano),
+		"admin_id":  entry.AdminID,
+		"action":    entry.Action,
+		"target_id": entry.TargetID,
+	}
+	if entry.OrganizationID != "" {
+		values["organization_id"] = entry.OrganizationID
+	}
+	if len(entry.Metadata) > 0 {
+		if data, err := json.Marshal(entry.Metadata); err == nil {
+			values["metadata"] = string(data)
+		}
+	}
+	if entry.Sequence > 0 {
+		values["sequence"] = entry.Sequence
+		values["hash"] = entry.Hash
+	}
+	return Row{InsertID: entry.ID.Hex(), Values: values}
+}
+
+// AnalyticsRow converts an analytics event into a BigQuery row
+func AnalyticsRow(event analytics.Event) Row {
+	values := map[string]bq.JsonValue{
+		"id":              event.ID,
+		"timestamp":       event.Timestamp.UTC().Format(time.RFC3339Nano),
+		"name":            event.Name,
+		"user_id":         event.UserID,
+		"organization_id": event.OrganizationID,
+		"country":         event.Country,
+	}
+	if len(event.Props) > 0 {
+		if data, err := json.Marshal(event.Props); err == nil {
+			values["props"] = string(data)
+		}
+	}
+	return Row{InsertID: event.ID, Values: values}
+}
+
+// AnalyticsSink writes analytics events to BigQuery; use it as analytics.Options.Sink
+type AnalyticsSink struct {
+	exporter *Exporter
+	table    string
+	ensure   sync.Once
+	err      error
+}
+
+// NewAnalyticsSink writes to table, or AnalyticsTable when empty
+func NewAnalyticsSink(exporter *Exporter, table string) *AnalyticsSink {
+	if table == "" {
+		table = AnalyticsTable
+	}
+	return &AnalyticsSink{exporter: exporter, table: table}
+}
+
+// Name identifies the sink in metrics and logs
+func (s *AnalyticsSink) Name() string {
+	return "bigquery"
+}
+
+// Write inserts a batch, creating or migrating the table on first use
+func (s *AnalyticsSink) Write(ctx context.Context, events []analytics.Event) error {
+	s.ensure.Do(func() {
+		s.err = s.exporter.EnsureTable(ctx, s.table, AnalyticsSchema)
+	})
+	if s.err != nil {
+		return s.err
+	}
+
+	rows := make([]Row, len(events))
+	for i, event := range events {
+		rows[i] = AnalyticsRow(event)
+	}
+	return s.exporter.Insert(ctx, s.table, rows)
+}
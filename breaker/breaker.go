@@ -0,0 +1,169 @@
+// Package breaker provides a small circuit breaker for calls to external dependencies.
+package breaker
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ErrOpen is returned without calling the dependency while the breaker is open
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State of a circuit breaker
+type State int
+
+const (
+	StateClosed   State = iota // Calls flow normally
+	StateOpen                  // Calls are short-circuited with ErrOpen
+	StateHalfOpen              // A single trial call is allowed through
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half_open"
+	default:
+		return "unknown"
+	}
+}
+
+var (
+	breakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "circuit_breaker_state",
+		Help: "Current circuit breaker state (0 = closed, 1 = open, 2 = half-open).",
+	}, []string{"name"})
+
+	breakerRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "circuit_breaker_rejections_total",
+		Help: "Calls short-circuited because the breaker was open.",
+	}, []string{"name"})
+)
+
+// Options configures a Breaker
+type Options struct {
+	FailureThreshold int           // Consecutive failures before opening (default 5)
+	OpenTimeout      time.Duration // How long to stay open before a trial call (default 30s)
+}
+
+// Breaker trips after consecutive failures and short-circuits calls until a cool-down passes
+type Breaker struct {
+	name    string
+	options Options
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	trialing bool
+}
+
+// New creates a closed breaker identified by name in logs and metrics
+func New(name string, options Options) *Breaker {
+	if options.FailureThreshold <= 0 {
+		options.FailureThreshold = 5
+	}
+	if options.OpenTimeout <= 0 {
+		options.OpenTimeout = 30 * time.Second
+	}
+
+	b := &Breaker{name: name, options: options}
+	breakerState.WithLabelValues(name).Set(float64(StateClosed))
+	return b
+}
+
+// Name returns the breaker name
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State returns the current breaker state
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh()
+	return b.state
+}
+
+// Execute runs fn if the breaker allows it and records the outcome
+func (b *Breaker) Execute(fn func() error) error {
+	if !b.allow() {
+		breakerRejections.WithLabelValues(b.name).Inc()
+		return ErrOpen
+	}
+
+	err := fn()
+	b.record(err)
+	return err
+}
+
+// allow reports whether a call may proceed, claiming the trial slot when half-open
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh()
+
+	switch b.state {
+	case StateOpen:
+		return false
+	case StateHalfOpen:
+		if b.trialing {
+			return false
+		}
+		b.trialing = true
+	}
+	return true
+}
+
+// record updates the breaker with the result of a call
+func (b *Breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trialing = false
+	if err == nil {
+		b.failures = 0
+		b.transition(StateClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == StateHalfOpen || b.failures >= b.options.FailureThreshold {
+		b.openedAt = time.Now()
+		b.transition(StateOpen)
+	}
+}
+
+// refresh moves an open breaker to half-open once the timeout has passed; callers hold mu
+func (b *Breaker) refresh() {
+	if b.state == StateOpen && time.Since(b.openedAt) >= b.options.OpenTimeout {
+		b.transition(StateHalfOpen)
+	}
+}
+
+// transition changes state, logging and publishing the metric; callers hold mu
+func (b *Breaker) transition(to State) {
+	if b.state == to {
+		return
+	}
+
+	switch to {
+	case StateOpen:
+		log.Printf("⚡ Circuit breaker %s opened after %d consecutive failures", b.name, b.failures)
+	case StateHalfOpen:
+		log.Printf("🔍 Circuit breaker %s half-open, allowing a trial call", b.name)
+	case StateClosed:
+		log.Printf("✅ Circuit breaker %s closed", b.name)
+	}
+
+	b.state = to
+	breakerState.WithLabelValues(b.name).Set(float64(to))
+}
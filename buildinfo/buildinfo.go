@@ -0,0 +1,81 @@
+// Package buildinfo reports the version of the running service. Set the
+// values at link time:
+//
+//	go build -ldflags "\
+//		-X github.com/praleedsuvarna/shared-libs/buildinfo.Version=1.4.0 \
+//		-X github.com/praleedsuvarna/shared-libs/buildinfo.Commit=$(git rev-parse --short HEAD) \
+//		-X github.com/praleedsuvarna/shared-libs/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Commit and BuildTime fall back to the VCS stamp Go embeds in the binary.
+package buildinfo
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Populated via -ldflags -X
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+var (
+	info     Info
+	infoOnce sync.Once
+)
+
+// Get returns the build information
+func Get() Info {
+	infoOnce.Do(func() {
+		info = Info{
+			Version:   Version,
+			Commit:    Commit,
+			BuildTime: BuildTime,
+			GoVersion: runtime.Version(),
+		}
+
+		build, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		if info.Version == "dev" && build.Main.Version != "" && build.Main.Version != "(devel)" {
+			info.Version = build.Main.Version
+		}
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+				if len(info.Commit) > 12 {
+					info.Commit = info.Commit[:12]
+				}
+			case setting.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = setting.Value
+			}
+		}
+	})
+	return info
+}
+
+// String formats the build for logs, e.g. "1.4.0 (commit 3f2a9c1, built 2026-03-02T10:00:00Z)"
+func (i Info) String() string {
+	switch {
+	case i.Commit != "" && i.BuildTime != "":
+		return fmt.Sprintf("%s (commit %s, built %s)", i.Version, i.Commit, i.BuildTime)
+	case i.Commit != "":
+		return fmt.Sprintf("%s (commit %s)", i.Version, i.Commit)
+	default:
+		return i.Version
+	}
+}
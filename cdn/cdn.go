@@ -0,0 +1,272 @@
+// Package cdn purges cached paths from the CDN in front of media and storage
+// objects. Providers wrap Cloud CDN, Cloudflare and Fastly; an Invalidator
+// batches purge requests, deduplicates them and keeps within the provider's
+// rate limit:
+//
+//	invalidator, err := cdn.NewFromConfig(ctx)
+//	cdn.Init(invalidator)
+//	...
+//	cdn.Invalidate(cdn.ObjectPath(objectName)) // After overwriting an object
+//
+// Private content is served with signed URLs or cookies from a Signer.
+package cdn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/retry"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+)
+
+// Provider purges paths from one CDN
+type Provider interface {
+	Name() string
+	// MaxBatch is the largest number of paths Purge accepts at once
+	MaxBatch() int
+	// Purge invalidates paths, each starting with "/"; a trailing "*" purges a prefix
+	// where the provider supports it
+	Purge(ctx context.Context, paths []string) error
+}
+
+var cdnPurges = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "cdn_purged_paths_total",
+	Help: "Paths sent to the CDN for purging, partitioned by provider and result.",
+}, []string{"provider", "result"})
+
+// Options configures an Invalidator
+type Options struct {
+	FlushInterval  time.Duration // How long paths are collected before purging; default 5s
+	RequestsPerSec float64       // Purge calls per second; default 1
+	Burst          int           // Default 1
+}
+
+// Invalidator collects paths and purges them in batches in the background
+type Invalidator struct {
+	provider Provider
+	limiter  *rate.Limiter
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending map[string]struct{}
+
+	stop      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// New starts an Invalidator purging through provider
+func New(provider Provider, options Options) *Invalidator {
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = 5 * time.Second
+	}
+	if options.RequestsPerSec <= 0 {
+		options.RequestsPerSec = 1
+	}
+	if options.Burst <= 0 {
+		options.Burst = 1
+	}
+
+	i := &Invalidator{
+		provider: provider,
+		limiter:  rate.NewLimiter(rate.Limit(options.RequestsPerSec), options.Burst),
+		interval: options.FlushInterval,
+		pending:  map[string]struct{}{},
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go i.loop()
+	return i
+}
+
+// Invalidate queues paths for the next flush; repeated paths are purged once
+func (i *Invalidator) Invalidate(paths ...string) {
+	i.mu.Lock()
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		i.pending[path] = struct{}{}
+	}
+	i.mu.Unlock()
+}
+
+// Flush purges every queued path now, waiting for the rate limit. Batches
+// that fail with a transient error are queued again for the next flush;
+// batches the provider rejects permanently are dropped and reported.
+func (i *Invalidator) Flush(ctx context.Context) error {
+	i.mu.Lock()
+	paths := make([]string, 0, len(i.pending))
+	for path := range i.pending {
+		paths = append(paths, path)
+	}
+	i.pending = map[string]struct{}{}
+	i.mu.Unlock()
+
+	sort.Strings(paths)
+	batchSize := max(1, i.provider.MaxBatch())
+	var errs []error
+	for start := 0; start < len(paths); start += batchSize {
+		if ctx.Err() != nil {
+			i.Invalidate(paths[start:]...)
+			return errors.Join(append(errs, ctx.Err())...)
+		}
+
+		batch := paths[start:min(start+batchSize, len(paths))]
+		err := i.purge(ctx, batch)
+		switch {
+		case err == nil:
+		case ctx.Err() == nil && retry.IsPermanent(err):
+			utils.LogError(fmt.Sprintf("Dropping CDN purge of %s: %v", strings.Join(batch, ", "), err))
+			errs = append(errs, err)
+		default:
+			i.Invalidate(batch...)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close stops the background loop and purges what is still queued
+func (i *Invalidator) Close(ctx context.Context) error {
+	i.closeOnce.Do(func() { close(i.stop) })
+	<-i.stopped
+	return i.Flush(ctx)
+}
+
+// purge sends one batch, retrying transient provider errors. Errors the
+// provider marked permanent stay marked.
+func (i *Invalidator) purge(ctx context.Context, batch []string) error {
+	var providerErr error
+	err := utils.Retry(ctx, utils.DefaultRetryPolicy, func() error {
+		if err := i.limiter.Wait(ctx); err != nil {
+			return utils.PermanentError(err)
+		}
+		providerErr = i.provider.Purge(ctx, batch)
+		return providerErr
+	})
+
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	cdnPurges.WithLabelValues(i.provider.Name(), result).Add(float64(len(batch)))
+	if err != nil {
+		err = fmt.Errorf("%s purge of %d paths: %w", i.provider.Name(), len(batch), err)
+		if retry.IsPermanent(providerErr) {
+			return utils.PermanentError(err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (i *Invalidator) loop() {
+	defer close(i.stopped)
+
+	ticker := time.NewTicker(i.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-i.stop:
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		if err := i.Flush(ctx); err != nil {
+			utils.LogError(fmt.Sprintf("CDN purge failed: %v", err))
+		}
+		cancel()
+	}
+}
+
+var (
+	defaultInvalidator *Invalidator
+	defaultMu          sync.RWMutex
+)
+
+// Init sets the package-level Invalidator used by Invalidate
+func Init(invalidator *Invalidator) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultInvalidator = invalidator
+}
+
+// Invalidate queues paths on the package-level Invalidator; without Init it
+// does nothing, so services without a CDN need no special casing
+func Invalidate(paths ...string) {
+	defaultMu.RLock()
+	invalidator := defaultInvalidator
+	defaultMu.RUnlock()
+
+	if invalidator != nil {
+		invalidator.Invalidate(paths...)
+	}
+}
+
+// Shutdown closes the package-level Invalidator, purging queued paths
+func Shutdown(ctx context.Context) error {
+	defaultMu.Lock()
+	invalidator := defaultInvalidator
+	defaultInvalidator = nil
+	defaultMu.Unlock()
+
+	if invalidator == nil {
+		return nil
+	}
+	return invalidator.Close(ctx)
+}
+
+// ObjectPath returns the CDN path serving a storage object, under
+// CDN_PATH_PREFIX (e.g. "/media")
+func ObjectPath(object string) string {
+	prefix := strings.TrimRight(config.GetEnv("CDN_PATH_PREFIX", ""), "/")
+	return prefix + "/" + strings.TrimLeft(object, "/")
+}
+
+// NewFromConfig builds an Invalidator for CDN_PROVIDER ("cloudcdn",
+// "cloudflare" or "fastly"); see each provider's FromConfig function for its
+// settings. CDN_PURGE_RATE sets the purge calls per second.
+func NewFromConfig(ctx context.Context) (*Invalidator, error) {
+	var provider Provider
+	var err error
+	switch name := config.GetEnv("CDN_PROVIDER", ""); name {
+	case "cloudcdn":
+		provider, err = CloudCDNFromConfig(ctx)
+	case "cloudflare":
+		provider, err = CloudflareFromConfig()
+	case "fastly":
+		provider, err = FastlyFromConfig()
+	default:
+		return nil, fmt.Errorf("unknown CDN_PROVIDER %q", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	options := Options{}
+	if value := config.GetEnv("CDN_PURGE_RATE", ""); value != "" {
+		perSecond, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			log.Printf("⚠️  Invalid CDN_PURGE_RATE %q, using the default: %v", value, err)
+		}
+		options.RequestsPerSec = perSecond
+	}
+	return New(provider, options), nil
+}
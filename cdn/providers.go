@@ -0,0 +1,211 @@
+package cdn
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+)
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// CloudCDN purges through the URL map of a Google Cloud load balancer. The
+// API takes one path per call, so every path is a rate-limited request;
+// prefer wildcard paths ("/media/123/*") for many objects.
+type CloudCDN struct {
+	ProjectID string
+	URLMap    string
+	Host      string // Limits the purge to one host; empty purges every host
+	service   *compute.Service
+}
+
+// NewCloudCDN creates a Compute API client using Application Default
+// Credentials, or credentialsJSON when given
+func NewCloudCDN(ctx context.Context, projectID, urlMap, host string, credentialsJSON []byte) (*CloudCDN, error) {
+	if projectID == "" || urlMap == "" {
+		return nil, fmt.Errorf("cloud CDN project and URL map are required")
+	}
+
+	clientOptions := []option.ClientOption{option.WithScopes(compute.ComputeScope)}
+	if len(credentialsJSON) > 0 {
+		clientOptions = append(clientOptions, option.WithCredentialsJSON(credentialsJSON))
+	}
+	service, err := compute.NewService(ctx, clientOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("create compute client: %w", err)
+	}
+	return &CloudCDN{ProjectID: projectID, URLMap: urlMap, Host: host, service: service}, nil
+}
+
+// CloudCDNFromConfig reads CDN_PROJECT (or GOOGLE_CLOUD_PROJECT), CDN_URL_MAP
+// and CDN_HOST, with credentials from the "cdn-credentials" secret or
+// CDN_CREDENTIALS
+func CloudCDNFromConfig(ctx context.Context) (*CloudCDN, error) {
+	credentials, err := config.GetSecret("cdn-credentials", "CDN_CREDENTIALS")
+	if err != nil && !errors.Is(err, config.ErrSecretNotFound) {
+		return nil, err
+	}
+	return NewCloudCDN(ctx,
+		config.GetEnv("CDN_PROJECT", config.GetEnv("GOOGLE_CLOUD_PROJECT", "")),
+		config.GetEnv("CDN_URL_MAP", ""),
+		config.GetEnv("CDN_HOST", ""),
+		[]byte(credentials),
+	)
+}
+
+// Name implements Provider
+func (p *CloudCDN) Name() string { return "cloudcdn" }
+
+// MaxBatch implements Provider
+func (p *CloudCDN) MaxBatch() int { return 1 }
+
+// Purge implements Provider
+func (p *CloudCDN) Purge(ctx context.Context, paths []string) error {
+	for _, path := range paths {
+		_, err := p.service.UrlMaps.InvalidateCache(p.ProjectID, p.URLMap, &compute.CacheInvalidationRule{
+			Host: p.Host,
+			Path: path,
+		}).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("invalidate %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// Cloudflare purges files, or prefixes for paths ending in "*", from a zone
+type Cloudflare struct {
+	ZoneID   string
+	APIToken string
+	BaseURL  string // Scheme and host the paths are served from, e.g. https://cdn.example.com
+}
+
+// CloudflareFromConfig reads CLOUDFLARE_ZONE_ID and CDN_BASE_URL, with the
+// API token from the "cloudflare-api-token" secret or CLOUDFLARE_API_TOKEN
+func CloudflareFromConfig() (*Cloudflare, error) {
+	token, err := config.GetSecret("cloudflare-api-token", "CLOUDFLARE_API_TOKEN")
+	if err != nil {
+		return nil, err
+	}
+	provider := &Cloudflare{
+		ZoneID:   config.GetEnv("CLOUDFLARE_ZONE_ID", ""),
+		APIToken: token,
+		BaseURL:  config.GetEnv("CDN_BASE_URL", ""),
+	}
+	if provider.ZoneID == "" || provider.BaseURL == "" {
+		return nil, fmt.Errorf("CLOUDFLARE_ZONE_ID and CDN_BASE_URL are required")
+	}
+	return provider, nil
+}
+
+// Name implements Provider
+func (p *Cloudflare) Name() string { return "cloudflare" }
+
+// MaxBatch implements Provider; Cloudflare accepts 30 URLs per request
+func (p *Cloudflare) MaxBatch() int { return 30 }
+
+// Purge implements Provider
+func (p *Cloudflare) Purge(ctx context.Context, paths []string) error {
+	base := strings.TrimRight(p.BaseURL, "/")
+	body := map[string][]string{}
+	for _, path := range paths {
+		if prefix, ok := strings.CutSuffix(path, "*"); ok {
+			// Prefixes are given without a scheme
+			body["prefixes"] = append(body["prefixes"], strings.TrimPrefix(strings.TrimPrefix(base, "https://"), "http://")+prefix)
+		} else {
+			body["files"] = append(body["files"], base+path)
+		}
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return utils.PermanentError(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		"https://api.cloudflare.com/client/v4/zones/"+url.PathEscape(p.ZoneID)+"/purge_cache", bytes.NewReader(payload))
+	if err != nil {
+		return utils.PermanentError(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+p.APIToken)
+	req.Header.Set("Content-Type", "application/json")
+	return sendPurge(req)
+}
+
+// Fastly purges single URLs from a service
+type Fastly struct {
+	APIKey  string
+	BaseURL string // Scheme and host the paths are served from, e.g. https://cdn.example.com
+}
+
+// FastlyFromConfig reads CDN_BASE_URL, with the API key from the
+// "fastly-api-key" secret or FASTLY_API_KEY
+func FastlyFromConfig() (*Fastly, error) {
+	key, err := config.GetSecret("fastly-api-key", "FASTLY_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+	provider := &Fastly{APIKey: key, BaseURL: config.GetEnv("CDN_BASE_URL", "")}
+	if provider.BaseURL == "" {
+		return nil, fmt.Errorf("CDN_BASE_URL is required")
+	}
+	return provider, nil
+}
+
+// Name implements Provider
+func (p *Fastly) Name() string { return "fastly" }
+
+// MaxBatch implements Provider; each path is its own cheap API call
+func (p *Fastly) MaxBatch() int { return 20 }
+
+// Purge implements Provider. Fastly purges URLs one at a time and has no
+// prefix purge, so wildcard paths are rejected.
+func (p *Fastly) Purge(ctx context.Context, paths []string) error {
+	host := strings.TrimPrefix(strings.TrimPrefix(strings.TrimRight(p.BaseURL, "/"), "https://"), "http://")
+	for _, path := range paths {
+		if strings.HasSuffix(path, "*") {
+			return utils.PermanentError(fmt.Errorf("fastly cannot purge prefix %s; use surrogate keys", path))
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.fastly.com/purge/"+host+path, nil)
+		if err != nil {
+			return utils.PermanentError(err)
+		}
+		req.Header.Set("Fastly-Key", p.APIKey)
+		req.Header.Set("Accept", "application/json")
+		if err := sendPurge(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendPurge performs a purge request; throttling and server errors are
+// retryable, other failures permanent
+func sendPurge(req *http.Request) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("%s returned status %d: %s", req.URL.Host, resp.StatusCode, strings.TrimSpace(string(body)))
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return err
+	}
+	return utils.PermanentError(err)
+}
@@ -0,0 +1,260 @@
+package cdn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+)
+
+// Signer grants time-limited access to private CDN content, so assets can be
+// served from a non-public bucket
+type Signer interface {
+	// SignURL returns rawURL signed to be valid until expires
+	SignURL(rawURL string, expires time.Time) (string, error)
+	// SignPrefix returns query parameters that authorize any URL starting with
+	// urlPrefix; append them to each asset URL
+	SignPrefix(urlPrefix string, expires time.Time) (string, error)
+	// SignedCookies authorize every URL starting with urlPrefix for the
+	// browser, e.g. all files of one experience
+	SignedCookies(urlPrefix string, expires time.Time) ([]*http.Cookie, error)
+}
+
+// SignerFromConfig builds a Signer for CDN_SIGNING_PROVIDER: "cloudcdn" uses
+// CDN_SIGNING_KEY_NAME and the "cdn-signing-key" secret (CDN_SIGNING_KEY);
+// "cloudfront" uses CLOUDFRONT_KEY_PAIR_ID and the "cloudfront-private-key"
+// secret (CLOUDFRONT_PRIVATE_KEY)
+func SignerFromConfig() (Signer, error) {
+	switch provider := config.GetEnv("CDN_SIGNING_PROVIDER", ""); provider {
+	case "cloudcdn":
+		key, err := config.GetSecret("cdn-signing-key", "CDN_SIGNING_KEY")
+		if err != nil {
+			return nil, err
+		}
+		return NewCloudCDNSigner(config.GetEnv("CDN_SIGNING_KEY_NAME", ""), key)
+	case "cloudfront":
+		key, err := config.GetSecret("cloudfront-private-key", "CLOUDFRONT_PRIVATE_KEY")
+		if err != nil {
+			return nil, err
+		}
+		return NewCloudFrontSigner(config.GetEnv("CLOUDFRONT_KEY_PAIR_ID", ""), []byte(key))
+	default:
+		return nil, fmt.Errorf("unknown CDN_SIGNING_PROVIDER %q", provider)
+	}
+}
+
+// CloudCDNSigner signs with a Cloud CDN signed request key (HMAC-SHA1)
+type CloudCDNSigner struct {
+	KeyName string
+	key     []byte
+}
+
+// NewCloudCDNSigner takes the key name and its base64url value as created
+// with gcloud compute backend-buckets add-signed-url-key
+func NewCloudCDNSigner(keyName, base64Key string) (*CloudCDNSigner, error) {
+	if keyName == "" {
+		return nil, errors.New("cloud CDN key name is required")
+	}
+	key, err := base64.URLEncoding.DecodeString(strings.TrimSpace(base64Key))
+	if err != nil {
+		return nil, fmt.Errorf("invalid cloud CDN signing key: %w", err)
+	}
+	return &CloudCDNSigner{KeyName: keyName, key: key}, nil
+}
+
+// SignURL implements Signer
+func (s *CloudCDNSigner) SignURL(rawURL string, expires time.Time) (string, error) {
+	separator := "?"
+	if strings.Contains(rawURL, "?") {
+		separator = "&"
+	}
+	unsigned := fmt.Sprintf("%s%sExpires=%d&KeyName=%s", rawURL, separator, expires.Unix(), s.KeyName)
+	return unsigned + "&Signature=" + s.sign(unsigned), nil
+}
+
+// SignPrefix implements Signer
+func (s *CloudCDNSigner) SignPrefix(urlPrefix string, expires time.Time) (string, error) {
+	unsigned := s.prefixPolicy(urlPrefix, expires, "&")
+	return unsigned + "&Signature=" + s.sign(unsigned), nil
+}
+
+// SignedCookies implements Signer with one Cloud-CDN-Cookie
+func (s *CloudCDNSigner) SignedCookies(urlPrefix string, expires time.Time) ([]*http.Cookie, error) {
+	unsigned := s.prefixPolicy(urlPrefix, expires, ":")
+	return []*http.Cookie{
+		signedCookie("Cloud-CDN-Cookie", unsigned+":Signature="+s.sign(unsigned), urlPrefix, expires),
+	}, nil
+}
+
+// prefixPolicy renders the URLPrefix, Expires and KeyName fields joined by separator
+func (s *CloudCDNSigner) prefixPolicy(urlPrefix string, expires time.Time, separator string) string {
+	return strings.Join([]string{
+		"URLPrefix=" + base64.URLEncoding.EncodeToString([]byte(urlPrefix)),
+		"Expires=" + strconv.FormatInt(expires.Unix(), 10),
+		"KeyName=" + s.KeyName,
+	}, separator)
+}
+
+func (s *CloudCDNSigner) sign(value string) string {
+	mac := hmac.New(sha1.New, s.key)
+	mac.Write([]byte(value))
+	return base64.URLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// CloudFrontSigner signs with a CloudFront key pair (RSA-SHA1 policies)
+type CloudFrontSigner struct {
+	KeyPairID string
+	key       *rsa.PrivateKey
+}
+
+// NewCloudFrontSigner takes the public key ID registered with CloudFront and
+// its PEM-encoded RSA private key (PKCS#1 or PKCS#8)
+func NewCloudFrontSigner(keyPairID string, privateKeyPEM []byte) (*CloudFrontSigner, error) {
+	if keyPairID == "" {
+		return nil, errors.New("cloudfront key pair ID is required")
+	}
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, errors.New("cloudfront private key is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return &CloudFrontSigner{KeyPairID: keyPairID, key: key}, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cloudfront private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("cloudfront private key is not an RSA key")
+	}
+	return &CloudFrontSigner{KeyPairID: keyPairID, key: key}, nil
+}
+
+// cloudFrontPolicy is the policy statement CloudFront verifies
+type cloudFrontPolicy struct {
+	Statement []cloudFrontStatement `json:"Statement"`
+}
+
+type cloudFrontStatement struct {
+	Resource  string `json:"Resource"`
+	Condition struct {
+		DateLessThan struct {
+			EpochTime int64 `json:"AWS:EpochTime"`
+		} `json:"DateLessThan"`
+	} `json:"Condition"`
+}
+
+// SignURL implements Signer with a canned policy
+func (s *CloudFrontSigner) SignURL(rawURL string, expires time.Time) (string, error) {
+	policy, err := s.policy(rawURL, expires)
+	if err != nil {
+		return "", err
+	}
+	signature, err := s.sign(policy)
+	if err != nil {
+		return "", err
+	}
+
+	separator := "?"
+	if strings.Contains(rawURL, "?") {
+		separator = "&"
+	}
+	return fmt.Sprintf("%s%sExpires=%d&Signature=%s&Key-Pair-Id=%s",
+		rawURL, separator, expires.Unix(), signature, s.KeyPairID), nil
+}
+
+// SignPrefix implements Signer with a custom policy on urlPrefix*
+func (s *CloudFrontSigner) SignPrefix(urlPrefix string, expires time.Time) (string, error) {
+	policy, err := s.policy(urlPrefix+"*", expires)
+	if err != nil {
+		return "", err
+	}
+	signature, err := s.sign(policy)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Policy=%s&Signature=%s&Key-Pair-Id=%s", cloudFrontEncode(policy), signature, s.KeyPairID), nil
+}
+
+// SignedCookies implements Signer with the three CloudFront cookies
+func (s *CloudFrontSigner) SignedCookies(urlPrefix string, expires time.Time) ([]*http.Cookie, error) {
+	policy, err := s.policy(urlPrefix+"*", expires)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := s.sign(policy)
+	if err != nil {
+		return nil, err
+	}
+	return []*http.Cookie{
+		signedCookie("CloudFront-Policy", cloudFrontEncode(policy), urlPrefix, expires),
+		signedCookie("CloudFront-Signature", signature, urlPrefix, expires),
+		signedCookie("CloudFront-Key-Pair-Id", s.KeyPairID, urlPrefix, expires),
+	}, nil
+}
+
+// policy renders the policy document. The resource is written verbatim:
+// json.Marshal would escape "&" in query strings as \u0026, and CloudFront
+// compares canned policies with the URL it rebuilds.
+func (s *CloudFrontSigner) policy(resource string, expires time.Time) ([]byte, error) {
+	statement := cloudFrontStatement{Resource: resource}
+	statement.Condition.DateLessThan.EpochTime = expires.Unix()
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(cloudFrontPolicy{Statement: []cloudFrontStatement{statement}}); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+}
+
+func (s *CloudFrontSigner) sign(policy []byte) (string, error) {
+	digest := sha1.Sum(policy)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA1, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("sign cloudfront policy: %w", err)
+	}
+	return cloudFrontEncode(signature), nil
+}
+
+// cloudFrontEncode is base64 with the URL-safe substitutions CloudFront expects
+func cloudFrontEncode(data []byte) string {
+	return strings.NewReplacer("+", "-", "=", "_", "/", "~").Replace(base64.StdEncoding.EncodeToString(data))
+}
+
+// signedCookie scopes a cookie to the host and path of urlPrefix, and to
+// CDN_COOKIE_DOMAIN when the CDN serves a sibling subdomain
+func signedCookie(name, value, urlPrefix string, expires time.Time) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteNoneMode, // Sent on requests from the app to the CDN domain
+	}
+	if parsed, err := url.Parse(urlPrefix); err == nil && parsed.Path != "" {
+		cookie.Path = parsed.Path
+	}
+	cookie.Domain = config.GetEnv("CDN_COOKIE_DOMAIN", "")
+	return cookie
+}
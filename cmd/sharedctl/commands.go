@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/authz"
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/messaging"
+	"github.com/praleedsuvarna/shared-libs/migrate"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// operatorID identifies sharedctl in audit entries and dead letter replays
+const operatorID = "sharedctl"
+
+func runToken(args []string) error {
+	flags := flag.NewFlagSet("token", flag.ExitOnError)
+	userID := flags.String("user", "", "User ID (required)")
+	organizationID := flags.String("org", "", "Organization ID")
+	role := flags.String("role", authz.RoleViewer, "Role claim")
+	flags.Parse(args)
+	if *userID == "" {
+		return errors.New("-user is required")
+	}
+
+	config.LoadEnv()
+	// Token helpers read the secret from the environment, which is empty when
+	// it came from Secret Manager
+	if os.Getenv("JWT_SECRET") == "" {
+		os.Setenv("JWT_SECRET", config.GetJWTSecret())
+	}
+
+	accessToken, refreshToken, err := utils.GenerateTokenPair(*userID, *organizationID, *role)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("access_token:  %s\nrefresh_token: %s\n", accessToken, refreshToken)
+	return nil
+}
+
+func runHashPassword(args []string) error {
+	password, err := readPassword(args)
+	if err != nil {
+		return err
+	}
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	fmt.Println(hash)
+	return nil
+}
+
+// readPassword takes the password from the first argument or the first line of stdin
+func readPassword(args []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.New("password required as argument or on stdin")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func runMigrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "List pending migrations without applying them")
+	flags.Parse(args)
+
+	connect()
+	defer config.DisconnectDB()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	if *dryRun {
+		pending, err := migrate.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		for _, migration := range pending {
+			fmt.Printf("%s  %s\n", migration.ID, migration.Description)
+		}
+		fmt.Printf("%d pending migration(s)\n", len(pending))
+		return nil
+	}
+
+	applied, err := migrate.Run(ctx)
+	fmt.Printf("✅ Applied %d migration(s)\n", len(applied))
+	return err
+}
+
+func runIndexes(args []string) error {
+	flags := flag.NewFlagSet("indexes", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "Only report differences")
+	flags.Parse(args)
+
+	connect()
+	defer config.DisconnectDB()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	ensure := migrate.EnsureIndexes
+	if *dryRun {
+		ensure = migrate.DiffIndexes
+	}
+	diffs, err := ensure(ctx)
+	for _, diff := range diffs {
+		for _, spec := range diff.Missing {
+			verb := "created"
+			if *dryRun {
+				verb = "missing"
+			}
+			fmt.Printf("  %-9s %-32s %s\n", verb, diff.Collection, models.KeySignature(spec.Keys))
+		}
+		for _, name := range diff.Extra {
+			fmt.Printf("  %-9s %-32s %s\n", "extra", diff.Collection, name)
+		}
+		for _, name := range diff.Conflicts {
+			fmt.Printf("  %-9s %-32s %s\n", "conflict", diff.Collection, name)
+		}
+	}
+	return err
+}
+
+func runSeedAdmin(args []string) error {
+	flags := flag.NewFlagSet("seed-admin", flag.ExitOnError)
+	email := flags.String("email", "", "Admin email (required)")
+	organizationID := flags.String("org", "", "Organization ID")
+	role := flags.String("role", authz.RoleSuperAdmin, "Role to grant")
+	collection := flags.String("collection", "users", "Users collection of the service")
+	flags.Parse(args)
+	if *email == "" {
+		return errors.New("-email is required")
+	}
+
+	// Only read from stdin so the password stays out of shell history
+	fmt.Fprint(os.Stderr, "Password (one line on stdin): ")
+	password, err := readPassword(nil)
+	if err != nil {
+		return err
+	}
+	if len(password) < 12 {
+		return errors.New("admin passwords must be at least 12 characters")
+	}
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	connect()
+	defer config.DisconnectDB()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	now := utils.Now()
+	result, err := config.GetCollection(*collection).UpdateOne(ctx,
+		bson.M{"email": strings.ToLower(*email)},
+		bson.M{
+			"$set": bson.M{
+				"password":        hash,
+				"role":            *role,
+				"organization_id": *organizationID,
+				"updated_at":      now,
+			},
+			"$setOnInsert": bson.M{"created_at": now},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("seed admin: %w", err)
+	}
+
+	action := "admin_seeded"
+	if result.UpsertedCount == 0 {
+		action = "admin_reset"
+	}
+	utils.LogAuditWithMetadata(operatorID, action, strings.ToLower(*email), map[string]interface{}{
+		"role":       *role,
+		"collection": *collection,
+	})
+	fmt.Printf("✅ %s %s as %s\n", strings.TrimPrefix(action, "admin_"), *email, *role)
+	return nil
+}
+
+func runVerifySecrets(args []string) error {
+	flags := flag.NewFlagSet("verify-secrets", flag.ExitOnError)
+	compare := flags.String("compare", "", "Snapshot of another environment (from sharedctl snapshot)")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: sharedctl verify-secrets [-compare snapshot.json] [secret-key:ENV_KEY ...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	config.LoadEnv()
+
+	options := config.DoctorOptions{}
+	if *compare != "" {
+		file, err := os.Open(*compare)
+		if err != nil {
+			return err
+		}
+		options.Compare, err = config.ReadSnapshot(file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	report := config.Doctor(options)
+
+	// Ad-hoc secrets read with config.GetSecret, e.g. service-signing-keys:SERVICE_SIGNING_KEYS
+	failed := 0
+	for _, arg := range flags.Args() {
+		secretKey, envKey, _ := strings.Cut(arg, ":")
+		if _, err := config.GetSecret(secretKey, envKey); err != nil {
+			fmt.Printf("  MISSING   %-32s %v\n", secretKey, err)
+			failed++
+		} else {
+			fmt.Printf("  OK        %s\n", secretKey)
+		}
+	}
+
+	if !report.OK() || failed > 0 {
+		return fmt.Errorf("%d issue(s) found", len(report.Issues)+failed)
+	}
+	return nil
+}
+
+func runSnapshot(args []string) error {
+	config.LoadEnv()
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(config.TakeSnapshot(nil))
+}
+
+func runDeadLetters(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: sharedctl dlq list [-subject s] [-all] | sharedctl dlq replay <id>")
+	}
+
+	switch args[0] {
+	case "list":
+		flags := flag.NewFlagSet("dlq list", flag.ExitOnError)
+		subject := flags.String("subject", "", "Only dead letters from this subject")
+		all := flags.Bool("all", false, "Include dead letters that were already replayed")
+		limit := flags.Int64("limit", 50, "Maximum number of dead letters")
+		flags.Parse(args[1:])
+
+		connect()
+		defer config.DisconnectDB()
+
+		filter := utils.DeadLetterFilter{Subject: *subject, Limit: *limit}
+		if !*all {
+			replayed := false
+			filter.Replayed = &replayed
+		}
+		deadLetters, err := utils.ListDeadLetters(filter)
+		if err != nil {
+			return err
+		}
+		for _, deadLetter := range deadLetters {
+			fmt.Printf("%s  %s  %-30s  %s\n", deadLetter.ID.Hex(), deadLetter.CreatedAt.Format(time.RFC3339),
+				deadLetter.Subject, deadLetter.Reason)
+		}
+		return nil
+
+	case "replay":
+		if len(args) < 2 {
+			return errors.New("usage: sharedctl dlq replay <id> [<id> ...]")
+		}
+
+		connect()
+		defer config.DisconnectDB()
+		if config.GetNATSURL() != "" {
+			config.ConnectNATS()
+			defer config.DisconnectNATS()
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		bus, err := messaging.NewFromConfig(ctx, operatorID)
+		if err != nil {
+			return fmt.Errorf("connect messaging: %w", err)
+		}
+		defer bus.Close()
+
+		for _, hex := range args[1:] {
+			if err := replayDeadLetter(ctx, bus, hex); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown dlq command %q", args[0])
+	}
+}
+
+func replayDeadLetter(ctx context.Context, bus *messaging.Bus, hex string) error {
+	id, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return fmt.Errorf("invalid dead letter ID %q", hex)
+	}
+	deadLetter, err := utils.GetDeadLetter(id)
+	if err != nil {
+		return fmt.Errorf("dead letter %s: %w", hex, err)
+	}
+
+	if err := bus.Replay(ctx, *deadLetter); err != nil {
+		return fmt.Errorf("replay %s: %w", hex, err)
+	}
+	if err := utils.MarkDeadLetterReplayed(id, operatorID); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Replayed %s but failed to mark it: %v\n", hex, err)
+	}
+	utils.LogAuditWithMetadata(operatorID, "dead_letter_replayed", hex, map[string]interface{}{
+		"subject":     deadLetter.Subject,
+		"envelope_id": deadLetter.EnvelopeID,
+		"reason":      deadLetter.Reason,
+	})
+	fmt.Printf("✅ Replayed %s to %s\n", hex, deadLetter.Subject)
+	return nil
+}
+
+// connect loads configuration and connects to MongoDB the way services do
+func connect() {
+	config.LoadEnv()
+	config.ConnectDB()
+}
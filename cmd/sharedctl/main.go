@@ -0,0 +1,67 @@
+// Command sharedctl runs operational tasks against a service's environment,
+// loading configuration exactly like the services do (env, .env in
+// development, or Secret Manager):
+//
+//	sharedctl token -user 64f... -org 650... -role admin
+//	sharedctl hash-password < password.txt
+//	sharedctl migrate
+//	sharedctl indexes -dry-run
+//	sharedctl seed-admin -email ops@example.com -org 650...
+//	sharedctl verify-secrets -compare staging.json
+//	sharedctl snapshot > staging.json
+//	sharedctl dlq list | sharedctl dlq replay <id>
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// command is one sharedctl subcommand
+type command struct {
+	summary string
+	run     func(args []string) error
+}
+
+var commands = map[string]command{
+	"token":          {"Generate an access/refresh token pair for testing", runToken},
+	"hash-password":  {"Hash a password (argument or stdin) for direct database writes", runHashPassword},
+	"migrate":        {"Apply pending database migrations", runMigrate},
+	"seed-admin":     {"Create or reset an admin user", runSeedAdmin},
+	"verify-secrets": {"Check configuration and secrets, optionally against another environment", runVerifySecrets},
+	"snapshot":       {"Print this environment's configuration snapshot as JSON", runSnapshot},
+	"dlq":            {"List or replay dead-lettered messages", runDeadLetters},
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: sharedctl <command> [flags]\n\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-15s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun sharedctl <command> -h for the flags of a command.")
+}
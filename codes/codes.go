@@ -0,0 +1,267 @@
+// Package codes generates and redeems shareable codes such as beta invites
+// and promotions. Codes are short, unambiguous and case-insensitive
+// ("K7QM-4XPA"); each may be limited in uses, expiry and organization, and
+// redemption is atomic, so concurrent redeemers can never exceed MaxUses:
+//
+//	code, err := codes.Create(ctx, codes.Spec{Kind: "beta_invite", MaxUses: 50}, adminID)
+//	redemption, code, err := codes.Redeem(ctx, "k7qm-4xpa", userID, organizationID)
+package codes
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Alphabet leaves out characters that are easily confused: 0/O, 1/I/L
+const Alphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// Default code shape: two groups of four characters
+const (
+	DefaultGroups    = 2
+	DefaultGroupSize = 4
+)
+
+// Redemption errors
+var (
+	ErrNotFound        = errors.New("code not found")
+	ErrExpired         = errors.New("code expired")
+	ErrExhausted       = errors.New("code has no uses left")
+	ErrDisabled        = errors.New("code is disabled")
+	ErrWrongOrg        = errors.New("code is not valid for this organization")
+	ErrAlreadyRedeemed = errors.New("code already redeemed")
+)
+
+// Spec describes codes to create
+type Spec struct {
+	Kind           string
+	OrganizationID string        // Limits redemption to one organization
+	MaxUses        int           // 0 means unlimited
+	TTL            time.Duration // 0 means the code does not expire
+	Prefix         string        // Prepended to the generated code, e.g. "BETA"
+	Metadata       map[string]interface{}
+}
+
+// Generate returns a random code of groups of groupSize characters from Alphabet
+func Generate(groups, groupSize int) (string, error) {
+	max := big.NewInt(int64(len(Alphabet)))
+	parts := make([]string, groups)
+	for i := range parts {
+		part := make([]byte, groupSize)
+		for j := range part {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", err
+			}
+			part[j] = Alphabet[n.Int64()]
+		}
+		parts[i] = string(part)
+	}
+	return strings.Join(parts, "-"), nil
+}
+
+// Normalize canonicalizes user input so "k7qm 4xpa" matches "K7QM-4XPA"
+func Normalize(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	return strings.Join(strings.FieldsFunc(code, func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_'
+	}), "-")
+}
+
+// Create generates a unique code, retrying the rare collision with an existing one
+func Create(ctx context.Context, spec Spec, createdBy string) (*models.Code, error) {
+	if spec.Kind == "" {
+		return nil, fmt.Errorf("code kind is required")
+	}
+
+	now := utils.Now()
+	code := models.Code{
+		Kind:           spec.Kind,
+		OrganizationID: spec.OrganizationID,
+		MaxUses:        spec.MaxUses,
+		Metadata:       spec.Metadata,
+		CreatedBy:      createdBy,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if spec.TTL > 0 {
+		expiresAt := now.Add(spec.TTL)
+		code.ExpiresAt = &expiresAt
+	}
+
+	collection := config.GetCollection(models.Code{}.CollectionName())
+	for attempt := 0; attempt < 5; attempt++ {
+		generated, err := Generate(DefaultGroups, DefaultGroupSize)
+		if err != nil {
+			return nil, err
+		}
+		if spec.Prefix != "" {
+			generated = Normalize(spec.Prefix) + "-" + generated
+		}
+
+		code.ID = primitive.NewObjectID()
+		code.Code = generated
+		_, err = collection.InsertOne(ctx, code)
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		utils.LogAuditContext(ctx, createdBy, "code_created", code.ID.Hex(), map[string]interface{}{
+			"kind":            code.Kind,
+			"organization_id": code.OrganizationID,
+			"max_uses":        code.MaxUses,
+		})
+		return &code, nil
+	}
+	return nil, fmt.Errorf("failed to generate a unique code")
+}
+
+// Get returns a code by its value
+func Get(ctx context.Context, value string) (*models.Code, error) {
+	var code models.Code
+	err := config.GetCollection(models.Code{}.CollectionName()).
+		FindOne(ctx, bson.M{"code": Normalize(value)}).Decode(&code)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+// List returns codes of a kind (all kinds when empty), newest first
+func List(ctx context.Context, kind string, limit int64) ([]models.Code, error) {
+	filter := bson.M{}
+	if kind != "" {
+		filter["kind"] = kind
+	}
+
+	cursor, err := config.GetCollection(models.Code{}.CollectionName()).Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	codes := []models.Code{}
+	if err := cursor.All(ctx, &codes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable stops a code from being redeemed
+func Disable(ctx context.Context, value, adminID string) error {
+	result, err := config.GetCollection(models.Code{}.CollectionName()).UpdateOne(ctx,
+		bson.M{"code": Normalize(value)},
+		bson.M{"$set": bson.M{"disabled": true, "updated_at": utils.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	utils.LogAuditContext(ctx, adminID, "code_disabled", Normalize(value), nil)
+	return nil
+}
+
+// Redeem uses a code for userID in organizationID. The use is claimed with a
+// single conditional increment, so concurrent redemptions never exceed
+// MaxUses, and a user can redeem each code once.
+func Redeem(ctx context.Context, value, userID, organizationID string) (*models.CodeRedemption, *models.Code, error) {
+	collection := config.GetCollection(models.Code{}.CollectionName())
+	now := utils.Now()
+	normalized := Normalize(value)
+
+	var code models.Code
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{
+			"code":     normalized,
+			"disabled": false,
+			"$and": bson.A{
+				bson.M{"$or": bson.A{
+					bson.M{"expires_at": bson.M{"$exists": false}},
+					bson.M{"expires_at": bson.M{"$gt": now}},
+				}},
+				bson.M{"$or": bson.A{
+					bson.M{"max_uses": 0},
+					bson.M{"$expr": bson.M{"$lt": bson.A{"$uses", "$max_uses"}}},
+				}},
+				bson.M{"$or": bson.A{
+					bson.M{"organization_id": bson.M{"$exists": false}},
+					bson.M{"organization_id": organizationID},
+				}},
+			},
+		},
+		bson.M{"$inc": bson.M{"uses": 1}, "$set": bson.M{"updated_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&code)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil, rejection(ctx, normalized, organizationID)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	redemption := models.CodeRedemption{
+		ID:             primitive.NewObjectID(),
+		CodeID:         code.ID,
+		Code:           code.Code,
+		UserID:         userID,
+		OrganizationID: organizationID,
+		RedeemedAt:     now,
+	}
+	if _, err := config.GetCollection(models.CodeRedemption{}.CollectionName()).InsertOne(ctx, redemption); err != nil {
+		// Give the claimed use back
+		if _, undoErr := collection.UpdateOne(ctx, bson.M{"_id": code.ID}, bson.M{"$inc": bson.M{"uses": -1}}); undoErr != nil {
+			utils.LogError(fmt.Sprintf("Failed to release use of code %s: %v", code.Code, undoErr))
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, nil, ErrAlreadyRedeemed
+		}
+		return nil, nil, err
+	}
+
+	utils.LogAuditContext(ctx, userID, "code_redeemed", code.ID.Hex(), map[string]interface{}{
+		"kind":            code.Kind,
+		"organization_id": organizationID,
+	})
+	return &redemption, &code, nil
+}
+
+// rejection explains why a code could not be redeemed
+func rejection(ctx context.Context, value, organizationID string) error {
+	code, err := Get(ctx, value)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case code.Disabled:
+		return ErrDisabled
+	case code.ExpiresAt != nil && !utils.Now().Before(*code.ExpiresAt):
+		return ErrExpired
+	case code.OrganizationID != "" && code.OrganizationID != organizationID:
+		return ErrWrongOrg
+	default:
+		return ErrExhausted
+	}
+}
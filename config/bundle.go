@@ -0,0 +1,397 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BundleVersion is the format version written to bundles
+const BundleVersion = 1
+
+// SourceBundle is the ConfigChange source of values applied by ImportBundle
+const SourceBundle = "bundle"
+
+// BundleImportsCollection records the latest bundle imported, so older
+// bundles cannot roll the configuration back
+const BundleImportsCollection = "config_bundle_imports"
+
+var (
+	ErrInvalidBundle   = errors.New("invalid config bundle")
+	ErrBundleSignature = errors.New("config bundle signature does not verify")
+	ErrBundleStale     = errors.New("config bundle is older than the last one imported")
+)
+
+// Bundle is the non-secret configuration of one environment, signed so that
+// it can be promoted from staging to production unchanged. Each section maps
+// item keys (e.g. an environment variable or organization ID) to JSON values.
+type Bundle struct {
+	Version     int                                   `json:"version"`
+	Environment string                                `json:"environment"`
+	CreatedAt   time.Time                             `json:"created_at"`
+	Sections    map[string]map[string]json.RawMessage `json:"sections"`
+	KeyID       string                                `json:"key_id"`
+	Signature   string                                `json:"signature"` // Base64 HMAC-SHA256 of the bundle without key_id and signature
+}
+
+// BundleSection exports and applies one kind of configuration
+type BundleSection struct {
+	// Export returns the current items by key
+	Export func(ctx context.Context) (map[string]json.RawMessage, error)
+	// Apply writes changed items; removals are only passed when pruning
+	Apply func(ctx context.Context, changes []BundleChange) error
+	// KeepMissing never removes items missing from a bundle, for sections
+	// keyed by IDs that differ between environments
+	KeepMissing bool
+}
+
+// Kinds of BundleChange
+const (
+	BundleAdded   = "added"
+	BundleChanged = "changed"
+	BundleRemoved = "removed"
+)
+
+// BundleChange is one difference between a bundle and this environment
+type BundleChange struct {
+	Section string          `json:"section"`
+	Key     string          `json:"key"`
+	Kind    string          `json:"kind"`
+	Current json.RawMessage `json:"current,omitempty"` // Value in this environment
+	Bundle  json.RawMessage `json:"bundle,omitempty"`  // Value in the bundle
+}
+
+// BundleImportOptions configures ImportBundle
+type BundleImportOptions struct {
+	DryRun bool // Only compute the changes, i.e. preview the import
+	Prune  bool // Remove items missing from the bundle; by default they are kept
+}
+
+var (
+	bundleSections   = map[string]BundleSection{}
+	bundleSectionMux sync.RWMutex
+)
+
+// RegisterBundleSection adds a section to exported bundles. The shared
+// packages register "flags" (config), "branding" (utils) and "policies"
+// (authz); services add their own.
+func RegisterBundleSection(name string, section BundleSection) {
+	bundleSectionMux.Lock()
+	defer bundleSectionMux.Unlock()
+	bundleSections[name] = section
+}
+
+func init() {
+	RegisterBundleSection("flags", BundleSection{Export: exportFlags, Apply: applyFlags})
+}
+
+// BundleSigningKeys loads the keys shared by the environments from the
+// "config-bundle-keys" secret (CONFIG_BUNDLE_KEYS) as "id:base64key" entries
+func BundleSigningKeys() (map[string][]byte, error) {
+	return GetKeySet("config-bundle-keys", "CONFIG_BUNDLE_KEYS")
+}
+
+// ExportBundle captures every registered section and signs the result with
+// the key named by CONFIG_BUNDLE_KEY_ID
+func ExportBundle(ctx context.Context) (*Bundle, error) {
+	keys, err := BundleSigningKeys()
+	if err != nil {
+		return nil, err
+	}
+	keyID := GetEnv("CONFIG_BUNDLE_KEY_ID", "")
+	key, ok := keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("config bundle signing key %q is not configured", keyID)
+	}
+
+	bundle := &Bundle{
+		Version:     BundleVersion,
+		Environment: GetEnv("APP_ENV", ""),
+		CreatedAt:   time.Now().UTC(),
+		Sections:    map[string]map[string]json.RawMessage{},
+		KeyID:       keyID,
+	}
+	for name, section := range registeredBundleSections() {
+		items, err := section.Export(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %w", name, err)
+		}
+		bundle.Sections[name] = items
+	}
+
+	signature, err := signBundle(bundle, key)
+	if err != nil {
+		return nil, err
+	}
+	bundle.Signature = signature
+	return bundle, nil
+}
+
+// ReadBundle decodes a bundle saved as JSON
+func ReadBundle(r io.Reader) (*Bundle, error) {
+	var bundle Bundle
+	if err := json.NewDecoder(r).Decode(&bundle); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	return &bundle, nil
+}
+
+// ImportBundle verifies a bundle and applies how it differs from this
+// environment, returning the changes. With DryRun nothing is written, which
+// gives a diff preview. Sections that are not registered here are ignored.
+// Only bundles exported by the environment named in CONFIG_BUNDLE_SOURCE
+// are accepted, and none older than the last bundle imported.
+func ImportBundle(ctx context.Context, bundle *Bundle, options BundleImportOptions) ([]BundleChange, error) {
+	if err := VerifyBundle(bundle); err != nil {
+		return nil, err
+	}
+	source := GetEnv("CONFIG_BUNDLE_SOURCE", "")
+	if source == "" {
+		return nil, fmt.Errorf("%w: CONFIG_BUNDLE_SOURCE is not configured", ErrInvalidBundle)
+	}
+	if bundle.Environment != source {
+		return nil, fmt.Errorf("%w: exported by %q, expected %q", ErrInvalidBundle, bundle.Environment, source)
+	}
+	if err := checkBundleFresh(ctx, bundle); err != nil {
+		return nil, err
+	}
+
+	sections := registeredBundleSections()
+	changes := []BundleChange{}
+	bySection := map[string][]BundleChange{}
+	for name, items := range bundle.Sections {
+		section, ok := sections[name]
+		if !ok {
+			continue
+		}
+		current, err := section.Export(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %w", name, err)
+		}
+		for _, change := range diffBundleItems(name, current, items) {
+			if change.Kind == BundleRemoved && (!options.Prune || section.KeepMissing) {
+				continue
+			}
+			bySection[name] = append(bySection[name], change)
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Section != changes[j].Section {
+			return changes[i].Section < changes[j].Section
+		}
+		return changes[i].Key < changes[j].Key
+	})
+
+	if options.DryRun {
+		return changes, nil
+	}
+	if err := recordBundleImport(ctx, bundle); err != nil {
+		return nil, err
+	}
+	for name, sectionChanges := range bySection {
+		if err := sections[name].Apply(ctx, sectionChanges); err != nil {
+			return changes, fmt.Errorf("apply %s: %w", name, err)
+		}
+	}
+	return changes, nil
+}
+
+// checkBundleFresh returns ErrBundleStale for a bundle created before the
+// last one imported; importing the same bundle again is allowed
+func checkBundleFresh(ctx context.Context, bundle *Bundle) error {
+	var last struct {
+		CreatedAt time.Time `bson:"created_at"`
+	}
+	err := GetCollection(BundleImportsCollection).FindOne(ctx, bson.M{"_id": "last"}).Decode(&last)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("load last bundle import: %w", err)
+	}
+	if bundle.CreatedAt.Before(last.CreatedAt) {
+		return fmt.Errorf("%w: created %s, last import created %s", ErrBundleStale,
+			bundle.CreatedAt.Format(time.RFC3339), last.CreatedAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// recordBundleImport records bundle as the last one imported, unless a newer
+// bundle was recorded concurrently
+func recordBundleImport(ctx context.Context, bundle *Bundle) error {
+	_, err := GetCollection(BundleImportsCollection).UpdateOne(ctx,
+		bson.M{"_id": "last", "$or": bson.A{
+			bson.M{"created_at": bson.M{"$exists": false}},
+			bson.M{"created_at": bson.M{"$lte": bundle.CreatedAt}},
+		}},
+		bson.M{"$set": bson.M{
+			"created_at":  bundle.CreatedAt,
+			"environment": bundle.Environment,
+			"imported_at": time.Now().UTC(),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrBundleStale
+	}
+	return err
+}
+
+// VerifyBundle checks a bundle's version and signature
+func VerifyBundle(bundle *Bundle) error {
+	if bundle == nil || bundle.Version != BundleVersion {
+		return fmt.Errorf("%w: unsupported version", ErrInvalidBundle)
+	}
+	keys, err := BundleSigningKeys()
+	if err != nil {
+		return err
+	}
+	key, ok := keys[bundle.KeyID]
+	if !ok {
+		return fmt.Errorf("%w: unknown key %q", ErrBundleSignature, bundle.KeyID)
+	}
+
+	expected, err := signBundle(bundle, key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(expected), []byte(bundle.Signature)) {
+		return ErrBundleSignature
+	}
+	return nil
+}
+
+// signBundle returns the signature of everything but the key ID and
+// signature; encoding/json sorts map keys and compacts raw values, so the
+// encoding is stable
+func signBundle(bundle *Bundle, key []byte) (string, error) {
+	unsigned := *bundle
+	unsigned.KeyID = ""
+	unsigned.Signature = ""
+	payload, err := json.Marshal(unsigned)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+func registeredBundleSections() map[string]BundleSection {
+	bundleSectionMux.RLock()
+	defer bundleSectionMux.RUnlock()
+
+	sections := make(map[string]BundleSection, len(bundleSections))
+	for name, section := range bundleSections {
+		sections[name] = section
+	}
+	return sections
+}
+
+// diffBundleItems compares the items of one section, ignoring formatting
+func diffBundleItems(section string, current, desired map[string]json.RawMessage) []BundleChange {
+	var changes []BundleChange
+	for key, value := range desired {
+		existing, ok := current[key]
+		switch {
+		case !ok:
+			changes = append(changes, BundleChange{Section: section, Key: key, Kind: BundleAdded, Bundle: value})
+		case !sameJSON(existing, value):
+			changes = append(changes, BundleChange{Section: section, Key: key, Kind: BundleChanged, Current: existing, Bundle: value})
+		}
+	}
+	for key, value := range current {
+		if _, ok := desired[key]; !ok {
+			changes = append(changes, BundleChange{Section: section, Key: key, Kind: BundleRemoved, Current: value})
+		}
+	}
+	return changes
+}
+
+func sameJSON(a, b json.RawMessage) bool {
+	var compactA, compactB bytes.Buffer
+	if json.Compact(&compactA, a) != nil || json.Compact(&compactB, b) != nil {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(compactA.Bytes(), compactB.Bytes())
+}
+
+// exportFlags captures the non-secret boolean keys of DefaultSchema
+func exportFlags(ctx context.Context) (map[string]json.RawMessage, error) {
+	items := map[string]json.RawMessage{}
+	for _, spec := range DefaultSchema() {
+		if spec.Type != TypeBool || spec.Secret {
+			continue
+		}
+		value := lookupConfigValue(spec.Key)
+		if value == "" {
+			continue
+		}
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			continue // Reported by Doctor
+		}
+		items[spec.Key] = json.RawMessage(strconv.FormatBool(enabled))
+	}
+	return items, nil
+}
+
+// applyFlags sets flags in the process environment and announces them to
+// OnChange handlers. The values last until the next restart, so persist them
+// in the deployment configuration as well.
+func applyFlags(ctx context.Context, changes []BundleChange) error {
+	var applied []ConfigChange
+	for _, change := range changes {
+		spec, ok := DefaultSchema().lookup(change.Key)
+		if !ok || spec.Type != TypeBool || spec.Secret {
+			return fmt.Errorf("%w: %s is not a flag", ErrUnknownKey, change.Key)
+		}
+
+		old := os.Getenv(change.Key)
+		value := ""
+		if change.Kind != BundleRemoved {
+			var enabled bool
+			if err := json.Unmarshal(change.Bundle, &enabled); err != nil {
+				return fmt.Errorf("%w: flag %s: %v", ErrInvalidBundle, change.Key, err)
+			}
+			value = strconv.FormatBool(enabled)
+		}
+
+		var err error
+		if value == "" {
+			err = os.Unsetenv(change.Key)
+		} else {
+			err = os.Setenv(change.Key, value)
+		}
+		if err != nil {
+			return err
+		}
+		applied = append(applied, ConfigChange{
+			Key:       change.Key,
+			OldHash:   hashConfigValue(old),
+			NewHash:   hashConfigValue(value),
+			Source:    SourceBundle,
+			Timestamp: time.Now(),
+		})
+	}
+
+	announceChanges(applied)
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/praleedsuvarna/shared-libs/redact"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -42,25 +43,51 @@ func ConnectDB() {
 	clientOptions.SetMinPoolSize(2)
 	clientOptions.SetMaxConnIdleTime(30 * time.Second)
 
-	// Connect to MongoDB
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	client, err := mongo.Connect(ctx, clientOptions)
-	if err != nil {
-		log.Fatalf("❌ Failed to create MongoDB client: %v", err)
+	// Opt-in slow query logging, e.g. MONGO_SLOW_QUERY_THRESHOLD=200ms
+	if thresholdValue := GetEnv("MONGO_SLOW_QUERY_THRESHOLD", ""); thresholdValue != "" {
+		threshold, err := time.ParseDuration(thresholdValue)
+		if err != nil {
+			log.Printf("⚠️  Invalid MONGO_SLOW_QUERY_THRESHOLD %q, slow query logging disabled: %v", thresholdValue, err)
+		} else {
+			clientOptions.SetMonitor(newSlowQueryMonitor(threshold))
+			log.Printf("🐢 Slow query logging enabled (threshold: %s)", threshold)
+		}
 	}
 
-	// Ping the database to verify connection
-	pingCtx, pingCancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer pingCancel()
+	// Short-lived credentials from the secret provider replace any in the URI
+	connectOptions := clientOptions
+	var credentials *MongoCredentials
+	if dynamicMongoCredentialsEnabled() {
+		var err error
+		if credentials, err = loadMongoCredentials(); err != nil {
+			log.Fatalf("❌ Failed to load MongoDB credentials: %v", err)
+		}
+		connectOptions = withMongoCredentials(clientOptions, credentials)
+	}
 
-	err = client.Ping(pingCtx, nil)
+	// Connect to MongoDB, retrying while the database or network is still warming up
+	var client *mongo.Client
+	err := waitForDependency("MongoDB", func() error {
+		var connectErr error
+		client, connectErr = connectMongo(connectOptions)
+		return connectErr
+	})
 	if err != nil {
-		log.Fatalf("❌ Failed to connect to MongoDB: %v", err)
+		log.Fatalf("❌ Failed to connect to MongoDB: %s", redact.Error(err))
 	}
 
+	dbMu.Lock()
 	DB = client
+	dbMu.Unlock()
+
+	if credentials != nil {
+		dbRotationMu.Lock()
+		dbClientOptions = clientOptions
+		dbStopRotation = make(chan struct{})
+		go renewMongoCredentials(credentials.ExpiresAt, dbStopRotation)
+		dbRotationMu.Unlock()
+		log.Printf("🔐 Using dynamic MongoDB credentials (user: %s, expires: %s)", credentials.Username, credentials.ExpiresAt.Format(time.RFC3339))
+	}
 	configMode := "environment variables"
 	if IsSecretManagerEnabled() {
 		configMode = "Secret Manager (cached)"
@@ -70,19 +97,52 @@ func ConnectDB() {
 	log.Println("🚀 Database connection pool configured and ready")
 }
 
-// GetCollection returns a MongoDB collection using cached database name
-func GetCollection(collectionName string) *mongo.Collection {
-	if DB == nil {
-		log.Fatal("❌ Database not connected. Call ConnectDB() first")
+// connectMongo creates a client and verifies it with a ping
+func connectMongo(clientOptions *options.ClientOptions) (*mongo.Client, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, clientOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MongoDB client: %w", err)
+	}
+
+	// Ping the database to verify connection
+	pingCtx, pingCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer pingCancel()
+
+	if err := client.Ping(pingCtx, nil); err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
 	}
 
+	return client, nil
+}
+
+// GetCollection returns a MongoDB collection using cached database name
+func GetCollection(collectionName string) *mongo.Collection {
 	// Use cached database name from configuration
-	dbName := GetDBName()
-	return DB.Database(dbName).Collection(collectionName)
+	return GetDatabase().Collection(CollectionName(collectionName))
+}
+
+// CollectionName returns the stored name of a collection, with COLLECTION_PREFIX
+// applied. Use it wherever a collection is named without GetCollection, such
+// as db.Collection in migrations or the from of a $lookup stage.
+func CollectionName(collectionName string) string {
+	return GetCollectionPrefix() + collectionName
 }
 
 // DisconnectDB closes the MongoDB connection gracefully
 func DisconnectDB() {
+	dbRotationMu.Lock()
+	if dbStopRotation != nil {
+		close(dbStopRotation)
+		dbStopRotation, dbClientOptions = nil, nil
+	}
+	dbRotationMu.Unlock()
+
+	dbMu.Lock()
+	defer dbMu.Unlock()
 	if DB != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
@@ -98,22 +158,28 @@ func DisconnectDB() {
 
 // GetDatabase returns the MongoDB database instance using cached name
 func GetDatabase() *mongo.Database {
-	if DB == nil {
+	dbMu.RLock()
+	client := DB
+	dbMu.RUnlock()
+	if client == nil {
 		log.Fatal("❌ Database not connected. Call ConnectDB() first")
 	}
 
 	dbName := GetDBName()
-	return DB.Database(dbName)
+	return client.Database(dbName)
 }
 
 // HealthCheckDB performs a quick health check on the database connection
 func HealthCheckDB() error {
-	if DB == nil {
-		return fmt.Errorf("database not connected")
+	dbMu.RLock()
+	client := DB
+	dbMu.RUnlock()
+	if client == nil {
+		return fmt.Errorf("mongodb: %w", ErrNotConnected)
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	return DB.Ping(ctx, nil)
+	return client.Ping(ctx, nil)
 }
@@ -0,0 +1,292 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KeyType is the expected format of a configuration value
+type KeyType string
+
+const (
+	TypeString   KeyType = "string"
+	TypeInt      KeyType = "int"
+	TypeBool     KeyType = "bool"
+	TypeDuration KeyType = "duration"
+	TypeURL      KeyType = "url"
+)
+
+// KeySpec declares one configuration key, by its environment variable name
+type KeySpec struct {
+	Key      string
+	Type     KeyType
+	Required bool
+	Secret   bool // Never copied into snapshots
+}
+
+// Schema declares the configuration a service expects
+type Schema []KeySpec
+
+// DefaultSchema covers the keys read by the shared libraries; services append their own
+func DefaultSchema() Schema {
+	return Schema{
+		{Key: "APP_ENV", Type: TypeString},
+		{Key: "PORT", Type: TypeInt},
+		{Key: "MONGO_URI", Type: TypeURL, Required: true, Secret: true},
+		{Key: "DB_NAME", Type: TypeString},
+		{Key: "COLLECTION_PREFIX", Type: TypeString},
+		{Key: "JWT_SECRET", Type: TypeString, Required: true, Secret: true},
+		{Key: "NATS_URL", Type: TypeURL, Secret: true},
+		{Key: "REDIS_URL", Type: TypeURL, Secret: true},
+		{Key: "ALLOWED_ORIGINS", Type: TypeString},
+		{Key: "SHORT_LINK_ALLOWED_HOSTS", Type: TypeString},
+		{Key: "TRUSTED_PROXIES", Type: TypeString},
+		{Key: "CLIENT_IP_HEADER", Type: TypeString},
+		{Key: "REPLAY_MAX_SKEW", Type: TypeDuration},
+		{Key: "STATE_KEY_ID", Type: TypeString},
+		{Key: "LOG_LEVEL", Type: TypeString},
+		{Key: "LOG_SAMPLE_RATE", Type: TypeString},
+		{Key: "WATCHDOG_HEAP_LIMIT_MB", Type: TypeInt},
+		{Key: "WATCHDOG_GOROUTINE_LIMIT", Type: TypeInt},
+		{Key: "WATCHDOG_INTERVAL", Type: TypeDuration},
+		{Key: "WATCHDOG_SUSTAIN", Type: TypeInt},
+		{Key: "WATCHDOG_RESTART", Type: TypeBool},
+		{Key: "REQUEST_TIMEOUT", Type: TypeDuration},
+		{Key: "MAX_IN_FLIGHT_REQUESTS", Type: TypeInt},
+		{Key: "ASSET_MAX_SIZE_MB", Type: TypeInt},
+		{Key: "ASSET_MAX_TRIANGLES", Type: TypeInt},
+		{Key: "ASSET_MAX_TEXTURE_SIZE", Type: TypeInt},
+		{Key: "ASSET_MAX_TEXTURES", Type: TypeInt},
+		{Key: "ASSET_ALLOW_INCOMPLETE", Type: TypeBool},
+		{Key: "TRANSCODE_ADAPTER", Type: TypeString},
+		{Key: "TRANSCODER_PROJECT", Type: TypeString},
+		{Key: "TRANSCODER_LOCATION", Type: TypeString},
+		{Key: "CDN_PROVIDER", Type: TypeString},
+		{Key: "CDN_BASE_URL", Type: TypeURL},
+		{Key: "CDN_PATH_PREFIX", Type: TypeString},
+		{Key: "CDN_PURGE_RATE", Type: TypeString},
+		{Key: "CDN_SIGNING_PROVIDER", Type: TypeString},
+		{Key: "CDN_SIGNING_KEY_NAME", Type: TypeString},
+		{Key: "CLOUDFRONT_KEY_PAIR_ID", Type: TypeString},
+		{Key: "CDN_COOKIE_DOMAIN", Type: TypeString},
+		{Key: "LOGIN_STEP_UP_NEW_DEVICE", Type: TypeBool},
+		{Key: "LOGIN_STEP_UP_NEW_COUNTRY", Type: TypeBool},
+		{Key: "SAML_BASE_URL", Type: TypeURL},
+		{Key: "EMAIL_SENDER_NAME", Type: TypeString},
+		{Key: "CUSTOM_DOMAIN_RESERVED", Type: TypeString},
+		{Key: "CUSTOM_DOMAIN_VERIFY_INTERVAL", Type: TypeDuration},
+		{Key: "CAPTCHA_PROVIDER", Type: TypeString},
+		{Key: "CAPTCHA_SECRET", Type: TypeString, Secret: true},
+		{Key: "CAPTCHA_SITE_KEY", Type: TypeString},
+		{Key: "CAPTCHA_PROJECT", Type: TypeString},
+		{Key: "CAPTCHA_MIN_SCORE", Type: TypeString},
+		{Key: "CAPTCHA_MIN_SCORES", Type: TypeString},
+		{Key: "CLAMD_ADDRESS", Type: TypeString},
+		{Key: "CLAMD_TIMEOUT", Type: TypeDuration},
+		{Key: "MODERATION_ADAPTER", Type: TypeString},
+		{Key: "MODERATION_THRESHOLD", Type: TypeString},
+		{Key: "OPENAI_MODERATION_MODEL", Type: TypeString},
+		{Key: "CONFIG_WATCH_INTERVAL", Type: TypeDuration},
+		{Key: "CONFIG_BUNDLE_KEY_ID", Type: TypeString},
+		{Key: "CONFIG_BUNDLE_SOURCE", Type: TypeString},
+		{Key: "MONGO_SLOW_QUERY_THRESHOLD", Type: TypeDuration},
+		{Key: "MONGO_DYNAMIC_CREDENTIALS", Type: TypeBool},
+		{Key: "ENSURE_INDEXES", Type: TypeBool},
+		{Key: "MAINTENANCE_MODE", Type: TypeBool},
+		{Key: "MESSAGING_DRIVER", Type: TypeString},
+		{Key: "MTLS_ENABLED", Type: TypeBool},
+		{Key: "AUDIT_HASH_CHAIN", Type: TypeBool},
+		{Key: "AUDIT_SINKS", Type: TypeString},
+	}
+}
+
+// Snapshot is the configuration of one environment, keyed by environment
+// variable. Secret values are replaced by SnapshotSecret. Save one with
+// TakeSnapshot in staging and compare it in production with Doctor.
+type Snapshot map[string]string
+
+// SnapshotSecret stands in for secret values in snapshots
+const SnapshotSecret = "<secret>"
+
+// Issue kinds reported by Doctor
+const (
+	IssueMissing  = "missing"  // Required, or set in the other environment, but not here
+	IssueMistyped = "mistyped" // Value does not parse as the declared type
+	IssueExtra    = "extra"    // Set here but not in the other environment
+)
+
+// DoctorIssue is one problem found by Doctor
+type DoctorIssue struct {
+	Key    string `json:"key"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail"`
+}
+
+// DoctorReport lists every issue found, sorted by key
+type DoctorReport struct {
+	Issues []DoctorIssue `json:"issues"`
+}
+
+// OK reports whether no issues were found
+func (r DoctorReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// DoctorOptions configures Doctor
+type DoctorOptions struct {
+	Schema  Schema    // Defaults to DefaultSchema()
+	Compare Snapshot  // Other environment to compare against; nil skips the comparison
+	Output  io.Writer // Where the report is printed (default os.Stdout)
+}
+
+// Doctor checks the loaded configuration against the schema and, optionally,
+// another environment's snapshot, printing missing, extra and mistyped keys
+func Doctor(options DoctorOptions) DoctorReport {
+	if options.Schema == nil {
+		options.Schema = DefaultSchema()
+	}
+	if options.Output == nil {
+		options.Output = os.Stdout
+	}
+
+	report := DoctorReport{Issues: []DoctorIssue{}}
+	add := func(key, kind, detail string) {
+		report.Issues = append(report.Issues, DoctorIssue{Key: key, Kind: kind, Detail: detail})
+	}
+
+	for _, spec := range options.Schema {
+		value := lookupConfigValue(spec.Key)
+		switch {
+		case value == "" && spec.Required:
+			add(spec.Key, IssueMissing, "required key is not set")
+		case value != "":
+			if !validType(spec.Type, value) {
+				add(spec.Key, IssueMistyped, fmt.Sprintf("expected %s", spec.Type))
+			}
+		}
+	}
+
+	if options.Compare != nil {
+		current := TakeSnapshot(options.Schema)
+		for key, other := range options.Compare {
+			if _, ok := current[key]; !ok {
+				add(key, IssueMissing, "set in the other environment")
+				continue
+			}
+			if spec, ok := options.Schema.lookup(key); ok && other != SnapshotSecret {
+				if !validType(spec.Type, other) {
+					add(key, IssueMistyped, fmt.Sprintf("expected %s in the other environment", spec.Type))
+				}
+			}
+		}
+		for key := range current {
+			if _, ok := options.Compare[key]; !ok {
+				add(key, IssueExtra, "not set in the other environment")
+			}
+		}
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		return report.Issues[i].Key < report.Issues[j].Key
+	})
+	printDoctorReport(options.Output, report)
+	return report
+}
+
+// TakeSnapshot captures the keys of schema that are set in this environment
+func TakeSnapshot(schema Schema) Snapshot {
+	if schema == nil {
+		schema = DefaultSchema()
+	}
+
+	snapshot := Snapshot{}
+	for _, spec := range schema {
+		value := lookupConfigValue(spec.Key)
+		if value == "" {
+			continue
+		}
+		if spec.Secret {
+			value = SnapshotSecret
+		}
+		snapshot[spec.Key] = value
+	}
+	return snapshot
+}
+
+// ReadSnapshot decodes a snapshot saved as JSON
+func ReadSnapshot(r io.Reader) (Snapshot, error) {
+	snapshot := Snapshot{}
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("read config snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+func (s Schema) lookup(key string) (KeySpec, bool) {
+	for _, spec := range s {
+		if spec.Key == key {
+			return spec, true
+		}
+	}
+	return KeySpec{}, false
+}
+
+// lookupConfigValue returns the effective value of an environment key,
+// preferring the loaded configuration so that Secret Manager values count
+func lookupConfigValue(key string) string {
+	configMux.RLock()
+	loaded := Config
+	configMux.RUnlock()
+
+	if loaded != nil {
+		for _, binding := range secretBindings {
+			if binding.envKey == key {
+				return *binding.field(loaded)
+			}
+		}
+		switch key {
+		case "APP_ENV":
+			return loaded.AppEnv
+		case "PORT":
+			return loaded.Port
+		}
+	}
+	return GetEnv(key, "")
+}
+
+// validType reports whether value parses as keyType
+func validType(keyType KeyType, value string) bool {
+	var err error
+	switch keyType {
+	case TypeInt:
+		_, err = strconv.Atoi(value)
+	case TypeBool:
+		_, err = strconv.ParseBool(value)
+	case TypeDuration:
+		_, err = time.ParseDuration(value)
+	case TypeURL:
+		var parsed *url.URL
+		parsed, err = url.Parse(value)
+		return err == nil && parsed.Scheme != "" && parsed.Host != ""
+	}
+	return err == nil
+}
+
+func printDoctorReport(w io.Writer, report DoctorReport) {
+	if report.OK() {
+		fmt.Fprintln(w, "✅ Configuration looks good")
+		return
+	}
+
+	fmt.Fprintf(w, "❌ %d configuration issue(s):\n", len(report.Issues))
+	for _, issue := range report.Issues {
+		fmt.Fprintf(w, "  %-9s %-32s %s\n", strings.ToUpper(issue.Kind), issue.Key, issue.Detail)
+	}
+}
@@ -1,18 +1,22 @@
 package config
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/praleedsuvarna/shared-libs/breaker"
+	"github.com/praleedsuvarna/shared-libs/buildinfo"
+	"github.com/praleedsuvarna/shared-libs/retry"
 )
 
-// Version of the shared-libs config package
-const ConfigVersion = "2.0.0"
-
 // Configuration modes
 type ConfigMode string
 
@@ -24,24 +28,27 @@ const (
 
 // Configuration struct to hold all cached secrets and settings
 type AppConfig struct {
-	Mode           ConfigMode
-	AppEnv         string
-	ProjectID      string
-	MongoURI       string
-	DBName         string
-	JWTSecret      string
-	NATSURL        string
-	AllowedOrigins string
-	Port           string
-	Version        string
-	LoadTime       time.Time
+	Mode             ConfigMode
+	AppEnv           string
+	ProjectID        string
+	MongoURI         string
+	DBName           string
+	CollectionPrefix string // Prepended to every collection name so services or tenants can share a database
+	JWTSecret        string
+	NATSURL          string
+	RedisURL         string
+	AllowedOrigins   string
+	Port             string
+	Version          string
+	LoadTime         time.Time
 }
 
 // Global variables
 var (
-	Config    *AppConfig
-	configMux sync.RWMutex
-	once      sync.Once
+	Config      *AppConfig
+	configMux   sync.RWMutex
+	once        sync.Once
+	loadOptions ConfigOptions
 )
 
 // ConfigOptions allows applications to configure how config is loaded
@@ -52,6 +59,7 @@ type ConfigOptions struct {
 	RequiredSecrets      []string
 	OptionalSecrets      []string
 	FallbackToEnv        bool
+	StartupRetry         StartupRetryOptions // Zero value uses STARTUP_RETRY_* env vars or defaults
 }
 
 // LoadEnv loads configuration with default options (backward compatible)
@@ -77,13 +85,13 @@ func LoadEnvWithSecretManager(projectID string, requiredSecrets []string) {
 // LoadEnvWithOptions provides full control over configuration loading
 func LoadEnvWithOptions(options ConfigOptions) {
 	once.Do(func() {
-		log.Printf("🔧 Loading configuration (shared-libs v%s)...", ConfigVersion)
+		log.Printf("🔧 Loading configuration (build %s)...", buildinfo.Get())
 
 		config := &AppConfig{
 			Mode:     options.Mode,
 			AppEnv:   GetEnv("APP_ENV", "development"),
 			Port:     GetEnv("PORT", "8080"),
-			Version:  ConfigVersion,
+			Version:  buildinfo.Get().Version,
 			LoadTime: time.Now(),
 		}
 
@@ -101,6 +109,8 @@ func LoadEnvWithOptions(options ConfigOptions) {
 			}
 		}
 
+		config.CollectionPrefix = GetEnv("COLLECTION_PREFIX", "")
+
 		// Load configuration based on mode
 		var err error
 		switch config.Mode {
@@ -120,6 +130,9 @@ func LoadEnvWithOptions(options ConfigOptions) {
 			log.Fatalf("❌ Failed to load configuration: %v", err)
 		}
 
+		startupRetry = resolveStartupRetry(options.StartupRetry)
+		loadOptions = options
+
 		// Thread-safe assignment
 		configMux.Lock()
 		Config = config
@@ -155,6 +168,7 @@ func loadBasicConfig(config *AppConfig) error {
 	config.DBName = GetEnv("DB_NAME", "mrexperiences_service")
 	config.JWTSecret = GetEnv("JWT_SECRET", "")
 	config.NATSURL = GetEnv("NATS_URL", "")
+	config.RedisURL = GetEnv("REDIS_URL", "")
 	config.AllowedOrigins = getDefaultAllowedOrigins(config.AppEnv)
 
 	if envOrigins := GetEnv("ALLOWED_ORIGINS", ""); envOrigins != "" {
@@ -174,40 +188,23 @@ func loadSecretsFromManager(config *AppConfig, options ConfigOptions) error {
 	}
 
 	// Load secrets based on requirements
-	secretMap := map[string]string{
-		"mongo-uri":  "MONGO_URI",
-		"db-name":    "DB_NAME",
-		"jwt-secret": "JWT_SECRET",
-		"nats-url":   "NATS_URL",
-	}
-
-	// Load required secrets
-	for secretKey, envKey := range secretMap {
-		value, err := getSecretOrEnv(config.ProjectID, secretKey, envKey, "", options.FallbackToEnv)
+	for _, binding := range secretBindings {
+		value, _, err := resolveSecretBinding(config, binding, options)
 		if err != nil {
 			// Check if this is a required secret
-			isRequired := contains(options.RequiredSecrets, secretKey)
+			isRequired := contains(options.RequiredSecrets, binding.secretKey)
 			if isRequired {
-				return fmt.Errorf("required secret %s failed to load: %v", secretKey, err)
+				return fmt.Errorf("required secret %s failed to load: %w", binding.secretKey, err)
 			}
-			log.Printf("⚠️  Optional secret %s not available: %v", secretKey, err)
+			log.Printf("⚠️  Optional secret %s not available: %v", binding.secretKey, err)
 			value = ""
 		}
 
 		// Assign to config
-		switch secretKey {
-		case "mongo-uri":
-			config.MongoURI = value
-		case "db-name":
-			config.DBName = value
-			if config.DBName == "" {
-				config.DBName = "mrexperiences_service" // Default
-			}
-		case "jwt-secret":
-			config.JWTSecret = value
-		case "nats-url":
-			config.NATSURL = value
+		if value == "" {
+			value = binding.fallback
 		}
+		*binding.field(config) = value
 	}
 
 	// Load CORS origins
@@ -220,13 +217,45 @@ func loadSecretsFromManager(config *AppConfig, options ConfigOptions) error {
 	return nil
 }
 
-// getSecretOrEnv tries Secret Manager first, then falls back to environment variables
-func getSecretOrEnv(projectID, secretKey, envKey, fallback string, allowFallback bool) (string, error) {
+// Sources a configuration value can be loaded from
+const (
+	SourceSecretManager = "secret_manager"
+	SourceEnv           = "env"
+)
+
+// secretBinding ties a Secret Manager key to its environment variable and config field
+type secretBinding struct {
+	secretKey string
+	envKey    string
+	fallback  string
+	field     func(*AppConfig) *string
+}
+
+// secretBindings lists every value loaded from Secret Manager (or env in basic mode)
+var secretBindings = []secretBinding{
+	{"mongo-uri", "MONGO_URI", "", func(c *AppConfig) *string { return &c.MongoURI }},
+	{"db-name", "DB_NAME", "mrexperiences_service", func(c *AppConfig) *string { return &c.DBName }},
+	{"jwt-secret", "JWT_SECRET", "", func(c *AppConfig) *string { return &c.JWTSecret }},
+	{"nats-url", "NATS_URL", "", func(c *AppConfig) *string { return &c.NATSURL }},
+	{"redis-url", "REDIS_URL", "", func(c *AppConfig) *string { return &c.RedisURL }},
+}
+
+// resolveSecretBinding loads the current value of a binding for the config's mode
+func resolveSecretBinding(config *AppConfig, binding secretBinding, options ConfigOptions) (string, string, error) {
+	if config.Mode != ModeSecretManager {
+		return GetEnv(binding.envKey, binding.fallback), SourceEnv, nil
+	}
+	return getSecretOrEnv(config.ProjectID, binding.secretKey, binding.envKey, "", options.FallbackToEnv)
+}
+
+// getSecretOrEnv tries Secret Manager first, then falls back to environment variables,
+// reporting which source the value came from
+func getSecretOrEnv(projectID, secretKey, envKey, fallback string, allowFallback bool) (string, string, error) {
 	// Try Secret Manager first
 	if value, err := fetchSecretFromManager(projectID, secretKey); err == nil {
-		return value, nil
+		return value, SourceSecretManager, nil
 	} else if !allowFallback {
-		return "", err
+		return "", "", err
 	} else {
 		log.Printf("⚠️  Secret Manager failed for %s, falling back to env var %s", secretKey, envKey)
 	}
@@ -234,16 +263,45 @@ func getSecretOrEnv(projectID, secretKey, envKey, fallback string, allowFallback
 	// Fall back to environment variable
 	envValue := GetEnv(envKey, fallback)
 	if envValue == "" {
-		return "", fmt.Errorf("both secret %s and environment variable %s are empty", secretKey, envKey)
+		return "", "", fmt.Errorf("%w: both secret %s and environment variable %s are empty", ErrSecretNotFound, secretKey, envKey)
 	}
 
-	return envValue, nil
+	return envValue, SourceEnv, nil
+}
+
+// secretManagerBreaker short-circuits Secret Manager calls after repeated failures so
+// that loading falls back to environment variables without waiting on each timeout
+var secretManagerBreaker = breaker.New("secret_manager", breaker.Options{
+	FailureThreshold: 3,
+	OpenTimeout:      60 * time.Second,
+})
+
+// secretFetchRetry retries transient Secret Manager failures briefly; an open
+// breaker or a build without Secret Manager support is not worth retrying
+var secretFetchRetry = retry.Policy{
+	MaxAttempts:    3,
+	InitialBackoff: 250 * time.Millisecond,
+	Jitter:         0.2,
+	Retryable: func(err error) bool {
+		return !errors.Is(err, breaker.ErrOpen) && !errors.Is(err, ErrSecretManagerUnavailable)
+	},
 }
 
 // fetchSecretFromManager retrieves a secret from Google Cloud Secret Manager
 func fetchSecretFromManager(projectID, secretName string) (string, error) {
 	// This function will be implemented in secret_manager.go with build tags
-	return getSecretFromGoogleSecretManager(projectID, secretName)
+	var value string
+	err := retry.Do(context.Background(), secretFetchRetry, func() error {
+		return secretManagerBreaker.Execute(func() error {
+			var fetchErr error
+			value, fetchErr = getSecretFromGoogleSecretManager(projectID, secretName)
+			return fetchErr
+		})
+	})
+	if errors.Is(err, breaker.ErrOpen) {
+		return "", fmt.Errorf("secret %s skipped: %w", secretName, err)
+	}
+	return value, err
 }
 
 // getDefaultAllowedOrigins returns default CORS origins based on environment
@@ -296,6 +354,16 @@ func GetDBName() string {
 	return Config.DBName
 }
 
+// GetCollectionPrefix returns the namespace prepended to collection names
+func GetCollectionPrefix() string {
+	configMux.RLock()
+	defer configMux.RUnlock()
+	if Config == nil {
+		log.Fatal("Configuration not loaded. Call LoadEnv() first")
+	}
+	return Config.CollectionPrefix
+}
+
 func GetJWTSecret() string {
 	configMux.RLock()
 	defer configMux.RUnlock()
@@ -314,6 +382,15 @@ func GetNATSURL() string {
 	return Config.NATSURL
 }
 
+func GetRedisURL() string {
+	configMux.RLock()
+	defer configMux.RUnlock()
+	if Config == nil {
+		log.Fatal("Configuration not loaded. Call LoadEnv() first")
+	}
+	return Config.RedisURL
+}
+
 func GetAllowedOrigins() string {
 	configMux.RLock()
 	defer configMux.RUnlock()
@@ -366,3 +443,55 @@ func GetConfigMode() ConfigMode {
 func IsSecretManagerEnabled() bool {
 	return GetConfigMode() == ModeSecretManager
 }
+
+// GetSecret loads an ad-hoc secret that is not part of AppConfig, such as service
+// credentials: from Secret Manager (falling back to envKey) in Secret Manager mode,
+// otherwise from envKey. Values are not cached.
+func GetSecret(secretKey, envKey string) (string, error) {
+	configMux.RLock()
+	loaded := Config
+	configMux.RUnlock()
+	if loaded == nil {
+		return "", ErrNotLoaded
+	}
+
+	if loaded.Mode != ModeSecretManager {
+		if value := GetEnv(envKey, ""); value != "" {
+			return value, nil
+		}
+		return "", fmt.Errorf("%w: environment variable %s is empty", ErrSecretNotFound, envKey)
+	}
+
+	value, _, err := getSecretOrEnv(loaded.ProjectID, secretKey, envKey, "", true)
+	return value, err
+}
+
+// GetKeySet loads a comma separated "id:base64key" secret (see GetSecret) as keys
+// by ID, so keys can be rotated by adding a new ID; a missing secret is an empty set
+func GetKeySet(secretKey, envKey string) (map[string][]byte, error) {
+	value, err := GetSecret(secretKey, envKey)
+	if errors.Is(err, ErrSecretNotFound) {
+		return map[string][]byte{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string][]byte{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("%s: entries must be id:base64key", envKey)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%s: key %q: %w", envKey, id, err)
+		}
+		keys[id] = key
+	}
+	return keys, nil
+}
@@ -0,0 +1,12 @@
+package config
+
+import "errors"
+
+// Sentinel errors returned by the config package; match them with errors.Is
+var (
+	ErrNotLoaded                = errors.New("configuration not loaded")
+	ErrUnknownKey               = errors.New("unknown configuration key")
+	ErrNotConnected             = errors.New("not connected")
+	ErrSecretNotFound           = errors.New("secret not found")
+	ErrSecretManagerUnavailable = errors.New("secret manager not available")
+)
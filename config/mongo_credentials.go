@@ -0,0 +1,156 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Timing of dynamic credential rotation
+const (
+	DefaultMongoCredentialsTTL = time.Hour
+	mongoRotationRetry         = 30 * time.Second
+	mongoDrainGrace            = 10 * time.Second // Lets operations that already hold the old client start
+	mongoDrainTimeout          = 30 * time.Second // Then waits this long for them to finish
+)
+
+// MongoCredentials are short-lived database credentials issued by the secret
+// provider (e.g. a Vault database secrets engine synced to Secret Manager)
+type MongoCredentials struct {
+	Username      string    `json:"username"`
+	Password      string    `json:"password"`
+	LeaseDuration int       `json:"lease_duration,omitempty"` // Seconds the credentials are valid for
+	ExpiresAt     time.Time `json:"expires_at,omitempty"`     // Takes precedence over LeaseDuration
+}
+
+var (
+	dbClientOptions *options.ClientOptions
+	dbRotationMu    sync.Mutex
+	dbStopRotation  chan struct{}
+	dbMu            sync.RWMutex
+)
+
+// dynamicMongoCredentialsEnabled reports whether MONGO_DYNAMIC_CREDENTIALS is set
+func dynamicMongoCredentialsEnabled() bool {
+	return GetEnv("MONGO_DYNAMIC_CREDENTIALS", "") == "true"
+}
+
+// loadMongoCredentials reads the "mongo-credentials" secret (MONGO_CREDENTIALS),
+// a JSON object with username, password and lease_duration or expires_at. The
+// lifetime defaults to MONGO_CREDENTIALS_TTL.
+func loadMongoCredentials() (*MongoCredentials, error) {
+	value, err := GetSecret("mongo-credentials", "MONGO_CREDENTIALS")
+	if err != nil {
+		return nil, fmt.Errorf("mongo credentials: %w", err)
+	}
+
+	var credentials MongoCredentials
+	if err := json.Unmarshal([]byte(value), &credentials); err != nil {
+		return nil, fmt.Errorf("mongo credentials: %w", err)
+	}
+	if credentials.Username == "" || credentials.Password == "" {
+		return nil, errors.New("mongo credentials: username and password are required")
+	}
+
+	if credentials.ExpiresAt.IsZero() {
+		ttl := time.Duration(credentials.LeaseDuration) * time.Second
+		if ttl <= 0 {
+			ttl = DefaultMongoCredentialsTTL
+			if value := GetEnv("MONGO_CREDENTIALS_TTL", ""); value != "" {
+				parsed, err := time.ParseDuration(value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid MONGO_CREDENTIALS_TTL %q: %w", value, err)
+				}
+				ttl = parsed
+			}
+		}
+		credentials.ExpiresAt = time.Now().Add(ttl)
+	}
+	return &credentials, nil
+}
+
+// withMongoCredentials returns a copy of base authenticating as credentials
+func withMongoCredentials(base *options.ClientOptions, credentials *MongoCredentials) *options.ClientOptions {
+	clientOptions := *base
+	clientOptions.SetAuth(options.Credential{
+		AuthSource: GetEnv("MONGO_AUTH_SOURCE", "admin"),
+		Username:   credentials.Username,
+		Password:   credentials.Password,
+	})
+	return &clientOptions
+}
+
+// RotateDBCredentials fetches fresh credentials, connects a new pool with them
+// and swaps it in for DB. The previous pool is disconnected in the background
+// once the operations already using it have had time to finish, so rotation
+// does not fail in-flight requests. It returns the expiry of the new credentials.
+func RotateDBCredentials() (time.Time, error) {
+	dbRotationMu.Lock()
+	defer dbRotationMu.Unlock()
+
+	if dbClientOptions == nil {
+		return time.Time{}, fmt.Errorf("mongodb: %w", ErrNotConnected)
+	}
+
+	credentials, err := loadMongoCredentials()
+	if err != nil {
+		return time.Time{}, err
+	}
+	client, err := connectMongo(withMongoCredentials(dbClientOptions, credentials))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("connect with rotated credentials: %w", err)
+	}
+
+	dbMu.Lock()
+	previous := DB
+	DB = client
+	dbMu.Unlock()
+
+	log.Printf("🔄 MongoDB credentials rotated (user: %s, expires: %s)", credentials.Username, credentials.ExpiresAt.Format(time.RFC3339))
+	if previous != nil {
+		go drainMongoClient(previous)
+	}
+	return credentials.ExpiresAt, nil
+}
+
+// drainMongoClient disconnects a replaced client; Disconnect waits for checked
+// out connections to be returned before closing them
+func drainMongoClient(client *mongo.Client) {
+	time.Sleep(mongoDrainGrace)
+
+	ctx, cancel := context.WithTimeout(context.Background(), mongoDrainTimeout)
+	defer cancel()
+	if err := client.Disconnect(ctx); err != nil {
+		log.Printf("⚠️  Error disconnecting rotated MongoDB client: %v", err)
+	}
+}
+
+// renewMongoCredentials rotates credentials when 80% of their lifetime has
+// passed, retrying failed rotations until stop is closed
+func renewMongoCredentials(expiresAt time.Time, stop chan struct{}) {
+	wait := time.Until(expiresAt) * 4 / 5
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-stop:
+			timer.Stop()
+			return
+		}
+
+		next, err := RotateDBCredentials()
+		if err != nil {
+			log.Printf("⚠️  Failed to rotate MongoDB credentials, retrying in %s: %v", mongoRotationRetry, err)
+			wait = mongoRotationRetry
+			continue
+		}
+		wait = time.Until(next) * 4 / 5
+	}
+}
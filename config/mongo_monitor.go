@@ -0,0 +1,137 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+var mongoCommandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "mongo_command_duration_seconds",
+	Help:    "MongoDB command latency in seconds, partitioned by command and collection.",
+	Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+}, []string{"command", "collection"})
+
+// commandInfo captures the parts of a started command needed once it finishes
+type commandInfo struct {
+	collection string
+	filter     string
+}
+
+// newSlowQueryMonitor returns a command monitor that records latency for every
+// command and logs the ones slower than threshold with a redacted filter
+func newSlowQueryMonitor(threshold time.Duration) *event.CommandMonitor {
+	var started sync.Map // request ID -> commandInfo
+
+	finish := func(requestID int64, commandName string, duration time.Duration, failure string) {
+		value, _ := started.LoadAndDelete(requestID)
+		info, _ := value.(commandInfo)
+
+		mongoCommandDuration.WithLabelValues(commandName, info.collection).Observe(duration.Seconds())
+
+		if duration < threshold {
+			return
+		}
+		if failure != "" {
+			log.Printf("🐢 Slow MongoDB command (failed): %s on %s took %s, filter=%s, error=%s",
+				commandName, info.collection, duration, info.filter, failure)
+			return
+		}
+		log.Printf("🐢 Slow MongoDB command: %s on %s took %s, filter=%s",
+			commandName, info.collection, duration, info.filter)
+	}
+
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, evt *event.CommandStartedEvent) {
+			started.Store(evt.RequestID, commandInfo{
+				collection: commandCollection(evt.CommandName, evt.Command),
+				filter:     redactedFilter(evt.Command),
+			})
+		},
+		Succeeded: func(_ context.Context, evt *event.CommandSucceededEvent) {
+			finish(evt.RequestID, evt.CommandName, evt.Duration, "")
+		},
+		Failed: func(_ context.Context, evt *event.CommandFailedEvent) {
+			finish(evt.RequestID, evt.CommandName, evt.Duration, evt.Failure)
+		},
+	}
+}
+
+// commandCollection extracts the target collection, which is the value of the command's name key
+func commandCollection(commandName string, command bson.Raw) string {
+	value, err := command.LookupErr(commandName)
+	if err != nil {
+		return ""
+	}
+	collection, ok := value.StringValueOK()
+	if !ok {
+		return ""
+	}
+	return collection
+}
+
+// redactedFilter returns the command's filter (or pipeline) with all literal values replaced
+func redactedFilter(command bson.Raw) string {
+	for _, key := range []string{"filter", "query", "q", "pipeline"} {
+		value, err := command.LookupErr(key)
+		if err != nil {
+			continue
+		}
+		out, err := json.Marshal(redactValue(value))
+		if err != nil {
+			return ""
+		}
+		return string(out)
+	}
+
+	// Updates and deletes carry their filters inside a statements array
+	for _, key := range []string{"updates", "deletes"} {
+		value, err := command.LookupErr(key, "0", "q")
+		if err != nil {
+			continue
+		}
+		out, err := json.Marshal(redactValue(value))
+		if err != nil {
+			return ""
+		}
+		return string(out)
+	}
+
+	return ""
+}
+
+// redactValue keeps the shape (keys and operators) of a BSON value but hides its literals
+func redactValue(value bson.RawValue) interface{} {
+	switch value.Type {
+	case bsontype.EmbeddedDocument:
+		elements, err := value.Document().Elements()
+		if err != nil {
+			return "?"
+		}
+		redacted := make(map[string]interface{}, len(elements))
+		for _, element := range elements {
+			redacted[element.Key()] = redactValue(element.Value())
+		}
+		return redacted
+	case bsontype.Array:
+		values, err := value.Array().Values()
+		if err != nil {
+			return "?"
+		}
+		redacted := make([]interface{}, 0, len(values))
+		for _, v := range values {
+			redacted = append(redacted, redactValue(v))
+		}
+		return redacted
+	default:
+		return "?"
+	}
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/praleedsuvarna/shared-libs/buildinfo"
+	"github.com/praleedsuvarna/shared-libs/redact"
+)
+
+var NATS *nats.Conn
+
+// ConnectNATS connects to the NATS server using cached configuration
+func ConnectNATS() {
+	if Config == nil {
+		log.Fatal("❌ Configuration not loaded. Call LoadEnv() first before ConnectNATS()")
+	}
+
+	natsURL := GetNATSURL()
+	if natsURL == "" {
+		log.Fatal("❌ NATS URL is required. Please set NATS_URL environment variable or configure Secret Manager")
+	}
+
+	log.Println("🔗 Connecting to NATS...")
+
+	var conn *nats.Conn
+	err := waitForDependency("NATS", func() error {
+		var connectErr error
+		conn, connectErr = nats.Connect(natsURL,
+			nats.Name(fmt.Sprintf("shared-libs/%s", buildinfo.Get().Version)),
+			nats.Timeout(10*time.Second),
+			nats.MaxReconnects(-1),
+			nats.ReconnectWait(2*time.Second),
+			nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+				if err != nil {
+					log.Printf("⚠️  NATS disconnected: %v", err)
+				}
+			}),
+			nats.ReconnectHandler(func(nc *nats.Conn) {
+				log.Printf("✅ NATS reconnected to %s", nc.ConnectedServerId())
+			}),
+		)
+		return connectErr
+	})
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to NATS: %s", redact.Error(err))
+	}
+
+	NATS = conn
+	log.Println("✅ Connected to NATS")
+}
+
+// DisconnectNATS drains and closes the NATS connection gracefully
+func DisconnectNATS() {
+	if NATS != nil {
+		if err := NATS.Drain(); err != nil {
+			log.Printf("⚠️  Error draining NATS connection: %v", err)
+			NATS.Close()
+		} else {
+			log.Println("✅ Disconnected from NATS")
+		}
+		NATS = nil
+	}
+}
+
+// HealthCheckNATS reports whether the NATS connection is currently usable
+func HealthCheckNATS() error {
+	if NATS == nil {
+		return fmt.Errorf("nats: %w", ErrNotConnected)
+	}
+	if !NATS.IsConnected() {
+		return fmt.Errorf("nats connection status: %s", NATS.Status())
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/redact"
+	"github.com/redis/go-redis/v9"
+)
+
+var Redis *redis.Client
+
+// ConnectRedis connects to Redis using cached configuration
+func ConnectRedis() {
+	if Config == nil {
+		log.Fatal("❌ Configuration not loaded. Call LoadEnv() first before ConnectRedis()")
+	}
+
+	redisURL := GetRedisURL()
+	if redisURL == "" {
+		log.Fatal("❌ Redis URL is required. Please set REDIS_URL environment variable or configure Secret Manager")
+	}
+
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		log.Fatalf("❌ Invalid Redis URL: %s", redact.Error(err))
+	}
+
+	log.Println("🔗 Connecting to Redis...")
+
+	opts.DialTimeout = 10 * time.Second
+	opts.ReadTimeout = 5 * time.Second
+	opts.WriteTimeout = 5 * time.Second
+
+	client := redis.NewClient(opts)
+
+	err = waitForDependency("Redis", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return client.Ping(ctx).Err()
+	})
+	if err != nil {
+		client.Close()
+		log.Fatalf("❌ Failed to connect to Redis: %s", redact.Error(err))
+	}
+
+	Redis = client
+	log.Println("✅ Connected to Redis")
+}
+
+// DisconnectRedis closes the Redis client gracefully
+func DisconnectRedis() {
+	if Redis != nil {
+		if err := Redis.Close(); err != nil {
+			log.Printf("⚠️  Error disconnecting from Redis: %v", err)
+		} else {
+			log.Println("✅ Disconnected from Redis")
+		}
+		Redis = nil
+	}
+}
+
+// HealthCheckRedis performs a quick health check on the Redis connection
+func HealthCheckRedis() error {
+	if Redis == nil {
+		return fmt.Errorf("redis: %w", ErrNotConnected)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return Redis.Ping(ctx).Err()
+}
@@ -20,7 +20,7 @@ func getSecretFromGoogleSecretManager(projectID, secretName string) (string, err
 	// Create Secret Manager client
 	client, err := secretmanager.NewClient(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to create secret manager client: %v", err)
+		return "", fmt.Errorf("failed to create secret manager client: %w", err)
 	}
 	defer client.Close()
 
@@ -32,7 +32,7 @@ func getSecretFromGoogleSecretManager(projectID, secretName string) (string, err
 	// Call the API
 	result, err := client.AccessSecretVersion(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("failed to access secret %s: %v", secretName, err)
+		return "", fmt.Errorf("failed to access secret %s: %w", secretName, err)
 	}
 
 	// Extract the secret data
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/redact"
+	"github.com/praleedsuvarna/shared-libs/retry"
+)
+
+// StartupRetryOptions controls how long dependency connections are retried at startup
+type StartupRetryOptions struct {
+	InitialBackoff time.Duration // Delay before the second attempt
+	MaxBackoff     time.Duration // Upper bound for a single delay
+	MaxWait        time.Duration // Total time budget before giving up; negative (or 0 from the env) disables retries
+}
+
+// Default startup retry settings, overridable with STARTUP_RETRY_* environment variables
+var defaultStartupRetry = StartupRetryOptions{
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	MaxWait:        60 * time.Second,
+}
+
+var startupRetry StartupRetryOptions
+
+// resolveStartupRetry merges explicit options with environment overrides and defaults
+func resolveStartupRetry(options StartupRetryOptions) StartupRetryOptions {
+	resolved := defaultStartupRetry
+
+	resolved.InitialBackoff = durationEnv("STARTUP_RETRY_INITIAL_BACKOFF", resolved.InitialBackoff)
+	resolved.MaxBackoff = durationEnv("STARTUP_RETRY_MAX_BACKOFF", resolved.MaxBackoff)
+	resolved.MaxWait = durationEnv("STARTUP_RETRY_MAX_WAIT", resolved.MaxWait)
+
+	if options.InitialBackoff != 0 {
+		resolved.InitialBackoff = options.InitialBackoff
+	}
+	if options.MaxBackoff != 0 {
+		resolved.MaxBackoff = options.MaxBackoff
+	}
+	if options.MaxWait != 0 {
+		resolved.MaxWait = options.MaxWait
+	}
+
+	return resolved
+}
+
+// durationEnv parses a duration environment variable, keeping the fallback on error
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	value := GetEnv(key, "")
+	if value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("⚠️  Invalid %s %q, using %s: %v", key, value, fallback, err)
+		return fallback
+	}
+	return parsed
+}
+
+// waitForDependency calls connect until it succeeds or the startup retry budget is spent
+func waitForDependency(name string, connect func() error) error {
+	options := startupRetry
+	if options == (StartupRetryOptions{}) {
+		options = resolveStartupRetry(StartupRetryOptions{})
+	}
+
+	policy := retry.Policy{
+		InitialBackoff: options.InitialBackoff,
+		MaxBackoff:     options.MaxBackoff,
+		Jitter:         0.2,
+		MaxElapsed:     options.MaxWait,
+		OnRetry: func(attempt int, err error, delay time.Duration) {
+			log.Printf("⏳ %s not ready (attempt %d): %s, retrying in %s", name, attempt, redact.Error(err), delay.Round(time.Millisecond))
+		},
+	}
+	// MaxElapsed 0 would retry forever
+	if options.MaxWait <= 0 {
+		policy.MaxAttempts = 1
+	}
+
+	attempts := 0
+	err := retry.Do(context.Background(), policy, func() error {
+		attempts++
+		return connect()
+	})
+	if err != nil {
+		return fmt.Errorf("%s unavailable after %d attempts: %w", name, attempts, err)
+	}
+	if attempts > 1 {
+		log.Printf("✅ %s became available after %d attempts", name, attempts)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ConfigChange describes a configuration value that changed after the initial load.
+// Values are never exposed; only their SHA-256 hashes are reported.
+type ConfigChange struct {
+	Key       string    `json:"key"`
+	OldHash   string    `json:"old_hash"`
+	NewHash   string    `json:"new_hash"`
+	Source    string    `json:"source"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+var (
+	changeHandlers   []func(ConfigChange)
+	changeHandlerMux sync.RWMutex
+)
+
+// OnChange registers a handler called for every configuration value change
+func OnChange(handler func(ConfigChange)) {
+	changeHandlerMux.Lock()
+	defer changeHandlerMux.Unlock()
+	changeHandlers = append(changeHandlers, handler)
+}
+
+// Watch periodically reloads secrets and env values, applying and announcing changes.
+// Call the returned function to stop watching.
+func Watch(interval time.Duration) func() {
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		log.Printf("👀 Watching configuration for changes every %s", interval)
+		for {
+			select {
+			case <-ticker.C:
+				reloadBindings(nil)
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		stopOnce.Do(func() { close(stop) })
+	}
+}
+
+// reloadBindings re-resolves the given secret keys (all when empty), stores changed
+// values and notifies change handlers. It returns the changes that were applied.
+func reloadBindings(keys []string) []ConfigChange {
+	configMux.RLock()
+	if Config == nil {
+		configMux.RUnlock()
+		return nil
+	}
+	snapshot := *Config
+	configMux.RUnlock()
+
+	var changes []ConfigChange
+	updated := snapshot
+
+	for _, binding := range secretBindings {
+		if len(keys) > 0 && !contains(keys, binding.secretKey) {
+			continue
+		}
+
+		value, source, err := resolveSecretBinding(&snapshot, binding, loadOptions)
+		if err != nil {
+			log.Printf("⚠️  Failed to reload %s: %v", binding.secretKey, err)
+			continue
+		}
+		if value == "" {
+			value = binding.fallback
+		}
+
+		current := *binding.field(&snapshot)
+		if value == current {
+			continue
+		}
+
+		*binding.field(&updated) = value
+		changes = append(changes, ConfigChange{
+			Key:       binding.secretKey,
+			OldHash:   hashConfigValue(current),
+			NewHash:   hashConfigValue(value),
+			Source:    source,
+			Timestamp: time.Now(),
+		})
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	configMux.Lock()
+	for _, change := range changes {
+		for _, binding := range secretBindings {
+			if binding.secretKey == change.Key {
+				*binding.field(Config) = *binding.field(&updated)
+			}
+		}
+	}
+	configMux.Unlock()
+
+	announceChanges(changes)
+	return changes
+}
+
+// announceChanges logs changes and passes them to the OnChange handlers
+func announceChanges(changes []ConfigChange) {
+	changeHandlerMux.RLock()
+	handlers := append([]func(ConfigChange){}, changeHandlers...)
+	changeHandlerMux.RUnlock()
+
+	for _, change := range changes {
+		log.Printf("🔄 Configuration value %s changed (source: %s)", change.Key, change.Source)
+		for _, handler := range handlers {
+			handler(change)
+		}
+	}
+}
+
+// hashConfigValue returns a hex SHA-256 of a value, or empty for an empty value
+func hashConfigValue(value string) string {
+	if value == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// Refresh reloads the given secret keys (all when none are given) from the provider
+// without a restart and returns the values that changed
+func Refresh(keys ...string) ([]ConfigChange, error) {
+	configMux.RLock()
+	loaded := Config != nil
+	configMux.RUnlock()
+	if !loaded {
+		return nil, ErrNotLoaded
+	}
+
+	for _, key := range keys {
+		if !isSecretKey(key) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownKey, key)
+		}
+	}
+
+	log.Printf("🔄 Refreshing configuration (keys: %v)", keys)
+	return reloadBindings(keys), nil
+}
+
+// SecretKeys returns the names of all refreshable configuration keys
+func SecretKeys() []string {
+	keys := make([]string, 0, len(secretBindings))
+	for _, binding := range secretBindings {
+		keys = append(keys, binding.secretKey)
+	}
+	return keys
+}
+
+func isSecretKey(key string) bool {
+	for _, binding := range secretBindings {
+		if binding.secretKey == key {
+			return true
+		}
+	}
+	return false
+}
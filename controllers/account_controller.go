@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// DeactivateAccountRequest is the body for deactivating an account
+type DeactivateAccountRequest struct {
+	Email  string `json:"email"`
+	Reason string `json:"reason"`
+}
+
+// GetMyAccountStatus reports whether the authenticated user's account is pending deletion
+func GetMyAccountStatus(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(string)
+
+	record, err := utils.GetActiveAccountLifecycle(userID)
+	if errors.Is(err, utils.ErrAccountNotDeactivated) {
+		return c.JSON(fiber.Map{"status": "active"})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch account status",
+		})
+	}
+
+	return c.JSON(record)
+}
+
+// DeactivateMyAccount deactivates the authenticated user's account
+func DeactivateMyAccount(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(string)
+	return deactivateAccount(c, userID, userID)
+}
+
+// CancelMyAccountDeletion restores the authenticated user's account during the grace period
+func CancelMyAccountDeletion(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(string)
+	return cancelAccountDeletion(c, userID, userID)
+}
+
+// DeactivateUserAccount deactivates another user's account (admin)
+func DeactivateUserAccount(c *fiber.Ctx) error {
+	adminID, _ := c.Locals("user_id").(string)
+	return deactivateAccount(c, c.Params("userId"), adminID)
+}
+
+// CancelUserAccountDeletion restores another user's account (admin)
+func CancelUserAccountDeletion(c *fiber.Ctx) error {
+	adminID, _ := c.Locals("user_id").(string)
+	return cancelAccountDeletion(c, c.Params("userId"), adminID)
+}
+
+func deactivateAccount(c *fiber.Ctx, userID, requestedBy string) error {
+	var req DeactivateAccountRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	record, err := utils.DeactivateAccount(userID, req.Email, req.Reason, requestedBy)
+	if errors.Is(err, utils.ErrAccountAlreadyDeactivated) {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{
+			"error": "Account is already deactivated",
+		})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to deactivate account",
+		})
+	}
+
+	return c.JSON(record)
+}
+
+func cancelAccountDeletion(c *fiber.Ctx, userID, requestedBy string) error {
+	err := utils.CancelAccountDeletion(userID, requestedBy)
+	if errors.Is(err, utils.ErrAccountNotDeactivated) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Account is not pending deletion",
+		})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to restore account",
+		})
+	}
+
+	return c.JSON(fiber.Map{"status": "active"})
+}
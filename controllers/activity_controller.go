@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MarkActivityReadRequest lists feed items to mark read; empty marks everything
+type MarkActivityReadRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// GetActivityFeed returns the caller's organization feed with read markers
+func GetActivityFeed(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(string)
+	organizationID, _ := c.Locals("organization_id").(string)
+
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 20)
+
+	items, total, err := utils.GetActivityFeed(organizationID, userID, c.QueryBool("unread_only"), page, limit)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch activity feed",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"items": items,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// GetUnreadActivityCount returns the number of unread feed items
+func GetUnreadActivityCount(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(string)
+	organizationID, _ := c.Locals("organization_id").(string)
+
+	count, err := utils.CountUnreadActivity(organizationID, userID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to count unread activity",
+		})
+	}
+
+	return c.JSON(fiber.Map{"unread": count})
+}
+
+// MarkActivityRead marks feed items as read for the caller
+func MarkActivityRead(c *fiber.Ctx) error {
+	var req MarkActivityReadRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		objID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid activity ID: " + id,
+			})
+		}
+		ids = append(ids, objID)
+	}
+
+	userID, _ := c.Locals("user_id").(string)
+	organizationID, _ := c.Locals("organization_id").(string)
+
+	updated, err := utils.MarkActivityRead(organizationID, userID, ids)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to mark activity as read",
+		})
+	}
+
+	return c.JSON(fiber.Map{"updated": updated})
+}
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	_ "embed"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/buildinfo"
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+//go:embed admin_dashboard.html
+var adminDashboardPage []byte
+
+// AdminDashboardPage serves the dashboard shell. It holds no data: the page
+// asks for a super admin token and loads everything from GetAdminDashboardSummary.
+func AdminDashboardPage(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	c.Set("Content-Security-Policy", "default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'")
+	c.Set("X-Frame-Options", "DENY")
+	return c.Send(adminDashboardPage)
+}
+
+// GetAdminDashboardSummary gathers health, configuration, flags, dead letters
+// and audit activity for the dashboard. Sections that cannot be loaded carry
+// an "error" instead of failing the whole response.
+func GetAdminDashboardSummary(c *fiber.Ctx) error {
+	checks, healthy := checkDependencies()
+
+	snapshot := config.TakeSnapshot(nil)
+	flags := fiber.Map{}
+	for _, spec := range config.DefaultSchema() {
+		if spec.Type != config.TypeBool {
+			continue
+		}
+		enabled, _ := strconv.ParseBool(snapshot[spec.Key])
+		flags[spec.Key] = enabled
+	}
+
+	summary := fiber.Map{
+		"build":       buildinfo.Get(),
+		"health":      fiber.Map{"healthy": healthy, "checks": checks},
+		"config":      snapshot,
+		"flags":       flags,
+		"maintenance": utils.GetMaintenanceState(),
+	}
+
+	if config.DB == nil {
+		summary["dead_letters"] = fiber.Map{"error": "Database not configured"}
+		summary["audit_stats"] = fiber.Map{"error": "Database not configured"}
+		return c.JSON(summary)
+	}
+
+	replayed := false
+	if deadLetters, err := utils.ListDeadLetters(utils.DeadLetterFilter{Replayed: &replayed, Limit: 20}); err != nil {
+		summary["dead_letters"] = fiber.Map{"error": "Failed to fetch dead letters"}
+	} else {
+		summary["dead_letters"] = deadLetters
+	}
+
+	if stats, err := utils.GetAuditStats("action", 24*time.Hour, bson.M{}); err != nil {
+		summary["audit_stats"] = fiber.Map{"error": "Failed to fetch audit stats"}
+	} else {
+		summary["audit_stats"] = stats
+	}
+
+	return c.JSON(summary)
+}
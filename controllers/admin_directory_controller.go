@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/params"
+	"github.com/praleedsuvarna/shared-libs/stream"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// directoryQuery reads the search filters shared by listing and export:
+// q, role, status, organization_id, created_from, created_to and sort
+// (newest or oldest)
+func directoryQuery(c *fiber.Ctx) (utils.DirectoryQuery, error) {
+	query := utils.DirectoryQuery{
+		Search:         c.Query("q"),
+		Role:           c.Query("role"),
+		Status:         c.Query("status"),
+		OrganizationID: c.Query("organization_id"),
+	}
+
+	var err error
+	if query.CreatedFrom, err = params.Date(c, "created_from"); err != nil {
+		return query, err
+	}
+	if query.CreatedTo, err = params.Date(c, "created_to"); err != nil {
+		return query, err
+	}
+	sort, err := params.Enum(c, "sort", "newest", "oldest")
+	if err != nil {
+		return query, err
+	}
+	query.OldestFirst = sort == "oldest"
+	return query, nil
+}
+
+// searchDirectory returns a handler listing a directory with pagination
+func searchDirectory(directory *utils.Directory, name string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		query, err := directoryQuery(c)
+		if err != nil {
+			return params.Respond(c, err)
+		}
+		if query.Page, err = params.IntBetween(c, "page", 1, 1, 10000); err != nil {
+			return params.Respond(c, err)
+		}
+		if query.Limit, err = params.IntBetween(c, "limit", 50, 1, 200); err != nil {
+			return params.Respond(c, err)
+		}
+
+		items, total, err := directory.Search(c.UserContext(), query)
+		if err != nil {
+			utils.LogError(fmt.Sprintf("Failed to search %s: %v", name, err))
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": fmt.Sprintf("Failed to search %s", name),
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"items": items,
+			"total": total,
+			"page":  query.Page,
+			"limit": query.Limit,
+		})
+	}
+}
+
+// exportDirectory returns a handler streaming a directory as JSON or CSV
+// (?format=csv); mask=true masks the PII fields. ownOrganization limits the
+// export to the caller's organization.
+func exportDirectory(directory *utils.Directory, name string, ownOrganization bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		query, err := directoryQuery(c)
+		if err != nil {
+			return params.Respond(c, err)
+		}
+		if ownOrganization {
+			query.OrganizationID, _ = c.Locals("organization_id").(string)
+			if query.OrganizationID == "" {
+				return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Exports are scoped to an organization"})
+			}
+		}
+		format, err := params.Enum(c, "format", "json", "csv")
+		if err != nil {
+			return params.Respond(c, err)
+		}
+		if format == "" {
+			format = "json"
+		}
+		mask, err := params.Bool(c, "mask", false)
+		if err != nil {
+			return params.Respond(c, err)
+		}
+		write := func(document bson.M) bson.M {
+			if mask {
+				directory.Mask(document)
+			}
+			return document
+		}
+
+		adminID, _ := c.Locals("user_id").(string)
+		utils.LogAuditContext(c.UserContext(), adminID, name+"_exported", "", map[string]interface{}{
+			"format": format,
+			"search": query.Search,
+			"role":   query.Role,
+			"status": query.Status,
+			"masked": mask,
+		})
+
+		if format == "csv" {
+			return stream.CSV(c, name+".csv", directory.ExportFields, func(ctx context.Context, w *stream.CSVWriter) error {
+				return directory.Stream(ctx, query, func(document bson.M) error {
+					return w.Write(directory.ExportRow(write(document)))
+				})
+			})
+		}
+
+		return stream.JSON(c, name+".json", func(ctx context.Context, w *stream.JSONWriter) error {
+			return directory.Stream(ctx, query, func(document bson.M) error {
+				return w.Write(write(document))
+			})
+		})
+	}
+}
+
+// SearchUsers lists users of every organization, filtered by q (email or
+// name), role, status, organization_id and creation date, with pagination
+var SearchUsers = searchDirectory(&utils.UsersDirectory, "users")
+
+// ExportUsers streams the users matching the SearchUsers filters
+var ExportUsers = exportDirectory(&utils.UsersDirectory, "users", false)
+
+// ExportOrganizationUsers streams the users of the caller's organization for
+// organization admins, with the SearchUsers filters
+var ExportOrganizationUsers = exportDirectory(&utils.UsersDirectory, "users", true)
+
+// SearchOrganizations lists organizations, filtered by q (name or slug),
+// status and creation date, with pagination
+var SearchOrganizations = searchDirectory(&utils.OrganizationsDirectory, "organizations")
+
+// ExportOrganizations streams the organizations matching the SearchOrganizations filters
+var ExportOrganizations = exportDirectory(&utils.OrganizationsDirectory, "organizations", false)
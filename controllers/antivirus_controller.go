@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/antivirus"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/params"
+	"github.com/praleedsuvarna/shared-libs/repo"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// GetFileScan returns the verdict of a file scan in the caller's organization
+func GetFileScan(c *fiber.Ctx) error {
+	organizationID, _ := c.Locals("organization_id").(string)
+
+	scan, err := antivirus.Get(c.UserContext(), c.Params("scanId"), organizationID)
+	if errors.Is(err, repo.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "File scan not found"})
+	}
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to load file scan: %v", err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch file scan"})
+	}
+	return c.JSON(scan)
+}
+
+// ListFileScans returns the caller's organization's file scans, infected
+// files by default. Query: status (infected, failed, pending, clean or all),
+// page, limit.
+func ListFileScans(c *fiber.Ctx) error {
+	organizationID, _ := c.Locals("organization_id").(string)
+	status, err := params.Enum(c, "status", models.FileScanInfected,
+		models.FileScanFailed, models.FileScanPending, models.FileScanClean, "all")
+	if err != nil {
+		return params.Respond(c, err)
+	}
+	if status == "all" {
+		status = ""
+	}
+	page, err := params.IntBetween(c, "page", 1, 1, 10000)
+	if err != nil {
+		return params.Respond(c, err)
+	}
+	limit, err := params.IntBetween(c, "limit", 50, 1, 200)
+	if err != nil {
+		return params.Respond(c, err)
+	}
+
+	scans, err := antivirus.List(c.UserContext(), organizationID, status, repo.Page{Page: page, Limit: limit})
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to list file scans of %s: %v", organizationID, err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch file scans"})
+	}
+	return c.JSON(scans)
+}
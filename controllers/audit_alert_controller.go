@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/params"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetAnomalySettings returns the caller's organization anomaly thresholds
+func GetAnomalySettings(c *fiber.Ctx) error {
+	organizationID, _ := c.Locals("organization_id").(string)
+
+	settings, err := utils.GetAnomalySettings(organizationID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch anomaly settings",
+		})
+	}
+
+	return c.JSON(settings)
+}
+
+// UpdateAnomalySettings replaces the caller's organization anomaly thresholds
+func UpdateAnomalySettings(c *fiber.Ctx) error {
+	var settings models.AnomalySettings
+	if err := c.BodyParser(&settings); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if settings.MassDeletionCount < 0 || settings.MassDeletionWindow < 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Thresholds must not be negative",
+		})
+	}
+	if settings.BusinessStartHour < 0 || settings.BusinessEndHour > 24 || settings.BusinessStartHour >= settings.BusinessEndHour {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid business hours",
+		})
+	}
+	if settings.WebhookURL != "" {
+		if err := utils.ValidateWebhookURL(settings.WebhookURL); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Webhook URL must use https and a public host",
+			})
+		}
+	}
+
+	organizationID, _ := c.Locals("organization_id").(string)
+	adminID, _ := c.Locals("user_id").(string)
+	settings.OrganizationID = organizationID
+
+	if err := utils.SaveAnomalySettings(settings); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save anomaly settings",
+		})
+	}
+
+	utils.LogAuditWithMetadata(adminID, "anomaly_settings_updated", organizationID, map[string]interface{}{
+		"organization_id": organizationID,
+	})
+
+	return c.JSON(settings)
+}
+
+// ListAuditAlerts returns the caller's organization anomaly alerts
+func ListAuditAlerts(c *fiber.Ctx) error {
+	organizationID, _ := c.Locals("organization_id").(string)
+	limit, err := params.IntBetween(c, "limit", 50, 1, 200)
+	if err != nil {
+		return params.Respond(c, err)
+	}
+
+	alerts, err := utils.ListAuditAlerts(organizationID, c.QueryBool("unacknowledged"), int64(limit))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch audit alerts",
+		})
+	}
+
+	return c.JSON(alerts)
+}
+
+// AcknowledgeAuditAlert marks an anomaly alert as handled
+func AcknowledgeAuditAlert(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("alertId"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid alert ID",
+		})
+	}
+
+	organizationID, _ := c.Locals("organization_id").(string)
+	found, err := utils.AcknowledgeAuditAlert(organizationID, id)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to acknowledge audit alert",
+		})
+	}
+	if !found {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Audit alert not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{"acknowledged": true})
+}
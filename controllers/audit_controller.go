@@ -3,16 +3,69 @@ package controllers
 import (
 
 	// "UserManagement/utils"
+	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/authz"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/params"
+	"github.com/praleedsuvarna/shared-libs/stream"
 	"github.com/praleedsuvarna/shared-libs/utils"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
-// GetAuditLogs retrieves all audit logs (for super admin)
+// auditOrganization returns the organization audit queries are limited to: the
+// caller's own, or for super admins the organization_id parameter. Super admins
+// pass all_organizations=true to query across organizations, which returns "".
+func auditOrganization(c *fiber.Ctx) (string, error) {
+	organizationID, _ := c.Locals("organization_id").(string)
+	role, _ := c.Locals("role").(string)
+
+	allOrganizations, err := params.Bool(c, "all_organizations", false)
+	if err != nil {
+		return "", err
+	}
+	requested := c.Query("organization_id")
+	if allOrganizations || (requested != "" && requested != organizationID) {
+		if !authz.HasRole(role, authz.RoleSuperAdmin) {
+			return "", fiber.NewError(http.StatusForbidden, "Super admin privileges required for cross-organization audit queries")
+		}
+		if allOrganizations {
+			return "", nil
+		}
+		organizationID = requested
+	}
+
+	if organizationID == "" {
+		return "", fiber.NewError(http.StatusForbidden, "Audit logs are scoped to an organization")
+	}
+	return organizationID, nil
+}
+
+// auditFilter returns a filter limited to the organization from auditOrganization
+func auditFilter(c *fiber.Ctx) (bson.M, error) {
+	organizationID, err := auditOrganization(c)
+	if err != nil {
+		return nil, err
+	}
+	if organizationID == "" {
+		return bson.M{}, nil
+	}
+	return utils.AuditOrganizationFilter(organizationID), nil
+}
+
+// GetAuditLogs retrieves the audit logs of the caller's organization, or of
+// every organization for super admins passing all_organizations=true
 func GetAuditLogs(c *fiber.Ctx) error {
-	logs, err := utils.GetAuditLogs(bson.M{})
+	filter, err := auditFilter(c)
+	if err != nil {
+		return params.Respond(c, err)
+	}
+
+	logs, err := utils.GetAuditLogs(filter)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch audit logs",
@@ -31,7 +84,13 @@ func GetAdminAuditLogs(c *fiber.Ctx) error {
 		})
 	}
 
-	logs, err := utils.GetAuditLogs(bson.M{"admin_id": adminID})
+	filter, err := auditFilter(c)
+	if err != nil {
+		return params.Respond(c, err)
+	}
+	filter["admin_id"] = adminID
+
+	logs, err := utils.GetAuditLogs(filter)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch admin audit logs",
@@ -50,7 +109,13 @@ func GetResourceAuditLogs(c *fiber.Ctx) error {
 		})
 	}
 
-	logs, err := utils.GetAuditLogs(bson.M{"target_id": targetID})
+	filter, err := auditFilter(c)
+	if err != nil {
+		return params.Respond(c, err)
+	}
+	filter["target_id"] = targetID
+
+	logs, err := utils.GetAuditLogs(filter)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch resource audit logs",
@@ -59,3 +124,226 @@ func GetResourceAuditLogs(c *fiber.Ctx) error {
 
 	return c.JSON(logs)
 }
+
+// GetResourcesAuditLogs retrieves the audit trails of several resources in
+// one call, e.g. /audit/resources?ids=a,b,c&page=1&limit=50
+func GetResourcesAuditLogs(c *fiber.Ctx) error {
+	targetIDs := params.List(c, "ids")
+	if len(targetIDs) == 0 {
+		return params.Respond(c, &params.Error{Field: "ids", Message: "is required"})
+	}
+	if len(targetIDs) > utils.MaxAuditTargets {
+		return params.Respond(c, &params.Error{
+			Field:   "ids",
+			Message: fmt.Sprintf("must list at most %d IDs", utils.MaxAuditTargets),
+		})
+	}
+
+	organizationID, err := auditOrganization(c)
+	if err != nil {
+		return params.Respond(c, err)
+	}
+	page, err := params.IntBetween(c, "page", 1, 1, 10000)
+	if err != nil {
+		return params.Respond(c, err)
+	}
+	limit, err := params.IntBetween(c, "limit", 50, 1, 200)
+	if err != nil {
+		return params.Respond(c, err)
+	}
+	from, err := params.Date(c, "from")
+	if err != nil {
+		return params.Respond(c, err)
+	}
+	to, err := params.Date(c, "to")
+	if err != nil {
+		return params.Respond(c, err)
+	}
+
+	logs, total, err := utils.GetAuditLogsForTargets(targetIDs, utils.AuditTargetQuery{
+		OrganizationID: organizationID,
+		Page:           page,
+		Limit:          limit,
+		Action:         c.Query("action"),
+		From:           from,
+		To:             to,
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch resource audit logs",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"items": logs,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// GetAuditStats returns audit counts grouped by action, admin, target or day
+// over a period, e.g. /audit/stats?group_by=action&period=7d
+func GetAuditStats(c *fiber.Ctx) error {
+	groupBy, err := params.Enum(c, "group_by", utils.AuditGroupings()...)
+	if err != nil {
+		return params.Respond(c, err)
+	}
+	if groupBy == "" {
+		groupBy = "action"
+	}
+	period, err := params.Period(c, "period", "7d")
+	if err != nil {
+		return params.Respond(c, err)
+	}
+
+	filter, err := auditFilter(c)
+	if err != nil {
+		return params.Respond(c, err)
+	}
+	if action := c.Query("action"); action != "" {
+		filter["action"] = action
+	}
+
+	stats, err := utils.GetAuditStats(groupBy, period, filter)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch audit stats",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"group_by": groupBy,
+		"period":   c.Query("period", "7d"),
+		"stats":    stats,
+	})
+}
+
+// VerifyAuditChain checks the tamper-evident audit log for broken links
+func VerifyAuditChain(c *fiber.Ctx) error {
+	report, err := utils.VerifyAuditChain(c.UserContext())
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to verify audit chain",
+		})
+	}
+
+	if !report.Valid {
+		return c.Status(http.StatusConflict).JSON(report)
+	}
+	return c.JSON(report)
+}
+
+// ExportAuditLogs streams the organization's audit logs as JSON or CSV
+// (?format=csv), optionally filtered by admin_id, action, from and to
+// (RFC 3339 or YYYY-MM-DD)
+func ExportAuditLogs(c *fiber.Ctx) error {
+	filter, err := auditFilter(c)
+	if err != nil {
+		return params.Respond(c, err)
+	}
+	if adminID := c.Query("admin_id"); adminID != "" {
+		filter["admin_id"] = adminID
+	}
+	if action := c.Query("action"); action != "" {
+		filter["action"] = action
+	}
+
+	timestamp := bson.M{}
+	for param, operator := range map[string]string{"from": "$gte", "to": "$lte"} {
+		parsed, err := params.Date(c, param)
+		if err != nil {
+			return params.Respond(c, err)
+		}
+		if !parsed.IsZero() {
+			timestamp[operator] = parsed
+		}
+	}
+	if len(timestamp) > 0 {
+		filter["timestamp"] = timestamp
+	}
+
+	format, err := params.Enum(c, "format", "json", "csv")
+	if err != nil {
+		return params.Respond(c, err)
+	}
+	if format == "" {
+		format = "json"
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+	utils.LogAuditContext(c.UserContext(), adminID, "audit_exported", "", map[string]interface{}{
+		"format": format,
+	})
+
+	if format == "csv" {
+		header := []string{"id", "timestamp", "admin_id", "action", "target_id", "metadata"}
+		return stream.CSV(c, "audit-logs.csv", header, func(ctx context.Context, w *stream.CSVWriter) error {
+			return utils.StreamAuditLogs(ctx, filter, func(entry models.AuditLog) error {
+				metadata := ""
+				if len(entry.Metadata) > 0 {
+					encoded, _ := json.Marshal(entry.Metadata)
+					metadata = string(encoded)
+				}
+				return w.Write([]string{
+					entry.ID.Hex(),
+					utils.FormatTime(entry.Timestamp),
+					entry.AdminID,
+					entry.Action,
+					entry.TargetID,
+					metadata,
+				})
+			})
+		})
+	}
+
+	return stream.JSON(c, "audit-logs.json", func(ctx context.Context, w *stream.JSONWriter) error {
+		return utils.StreamAuditLogs(ctx, filter, func(entry models.AuditLog) error {
+			return w.Write(entry)
+		})
+	})
+}
+
+// TailAuditLogs streams new audit entries of the organization as server-sent
+// events while the client stays connected, optionally filtered by admin_id,
+// action and target_id. Each event carries the entry as JSON; reconnecting
+// clients resume from Last-Event-ID.
+func TailAuditLogs(c *fiber.Ctx) error {
+	filter, err := auditFilter(c)
+	if err != nil {
+		return params.Respond(c, err)
+	}
+	conditions := map[string]interface{}{}
+	for _, param := range []string{"admin_id", "action", "target_id"} {
+		if value := c.Query(param); value != "" {
+			filter[param] = value
+			conditions[param] = value
+		}
+	}
+
+	// Open the change stream before responding so that failures get a status
+	ctx := context.WithoutCancel(c.UserContext())
+	tail, err := utils.TailAuditLogs(ctx, filter, c.Get("Last-Event-ID"))
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to tail audit logs: %v", err))
+		return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Live audit stream unavailable",
+		})
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+	utils.LogAuditContext(c.UserContext(), adminID, "audit_stream_opened", "", conditions)
+
+	return stream.SSE(c, func(ctx context.Context, w *stream.SSEWriter) error {
+		defer tail.Close(context.Background())
+		for {
+			entry, token, err := tail.Next(ctx)
+			if err != nil {
+				return err
+			}
+			if err := w.Event("audit", token, entry); err != nil {
+				return err
+			}
+		}
+	})
+}
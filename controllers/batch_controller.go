@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+	"github.com/valyala/fasthttp"
+)
+
+// MaxBatchSize is the largest number of sub-requests accepted in one batch
+const MaxBatchSize = 20
+
+// Request headers forwarded from the batch to every sub-request, so they share its authentication
+var batchForwardedHeaders = []string{
+	fiber.HeaderAuthorization,
+	fiber.HeaderCookie,
+	fiber.HeaderAcceptLanguage,
+	fiber.HeaderXRequestID,
+}
+
+// batchSubRequestKey marks the request context of a sub-request. It is set on
+// the fasthttp context rather than as a header, so clients cannot forge it.
+const batchSubRequestKey = "batch_sub_request"
+
+// Item headers that are dropped: forwarding headers would let a sub-request
+// claim another client address, and hop-by-hop headers describe the batch's
+// own connection
+var batchStrippedHeaders = []string{
+	middleware.HeaderForwarded,
+	fiber.HeaderXForwardedFor,
+	fiber.HeaderXForwardedHost,
+	fiber.HeaderXForwardedProto,
+	fiber.HeaderXForwardedProtocol,
+	fiber.HeaderXForwardedSsl,
+	"X-Forwarded-Port",
+	"X-Real-IP",
+	"CF-Connecting-IP",
+	"True-Client-IP",
+	fiber.HeaderHost,
+	fiber.HeaderConnection,
+	fiber.HeaderKeepAlive,
+	fiber.HeaderProxyAuthorization,
+	fiber.HeaderProxyAuthenticate,
+	fiber.HeaderTE,
+	fiber.HeaderTrailer,
+	fiber.HeaderTransferEncoding,
+	fiber.HeaderUpgrade,
+	fiber.HeaderContentLength,
+}
+
+// BatchItem is one sub-request of a batch
+type BatchItem struct {
+	ID      string            `json:"id,omitempty"` // Echoed back so clients can match results
+	Method  string            `json:"method"`
+	Path    string            `json:"path"` // Including any query string, e.g. /experiences?page=2
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// BatchResult is the outcome of one sub-request
+type BatchResult struct {
+	ID      string            `json:"id,omitempty"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    interface{}       `json:"body,omitempty"`
+}
+
+// BatchRequests returns a handler that runs an array of sub-requests through
+// app's full handler stack, one after another, and responds with each status
+// and body in order. Sub-requests carry the batch's Authorization and cookies,
+// so route middleware authenticates them as usual. A failing item does not
+// stop the batch; streaming endpoints and nested batches are rejected per item.
+func BatchRequests(app *fiber.App) fiber.Handler {
+	handler := app.Handler()
+
+	return func(c *fiber.Ctx) error {
+		if nested, _ := c.Locals(batchSubRequestKey).(bool); nested {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Batches cannot be nested",
+			})
+		}
+
+		var items []BatchItem
+		if err := json.Unmarshal(c.Body(), &items); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Request body must be an array of sub-requests",
+			})
+		}
+		if len(items) == 0 || len(items) > MaxBatchSize {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("A batch must contain between 1 and %d sub-requests", MaxBatchSize),
+			})
+		}
+
+		// Sub-requests come from the batch's client, whatever proxies it passed
+		remoteAddr := c.Context().RemoteAddr()
+		if ip := net.ParseIP(middleware.ClientIP(c)); ip != nil {
+			remoteAddr = &net.TCPAddr{IP: ip}
+		}
+
+		results := make([]BatchResult, 0, len(items))
+		for _, item := range items {
+			if err := c.UserContext().Err(); err != nil {
+				return err
+			}
+			results = append(results, runBatchItem(c, handler, remoteAddr, item))
+		}
+
+		return c.JSON(fiber.Map{"responses": results})
+	}
+}
+
+// runBatchItem executes one sub-request in a fresh request context
+func runBatchItem(c *fiber.Ctx, handler fasthttp.RequestHandler, remoteAddr net.Addr, item BatchItem) BatchResult {
+	result := BatchResult{ID: item.ID}
+	method := strings.ToUpper(item.Method)
+	if method == "" {
+		method = fiber.MethodGet
+	}
+
+	if !strings.HasPrefix(item.Path, "/") {
+		return batchError(result, http.StatusBadRequest, "Sub-request path must start with /")
+	}
+
+	var request fasthttp.Request
+	request.Header.SetMethod(method)
+	request.SetRequestURI(item.Path)
+	for _, name := range batchForwardedHeaders {
+		if value := c.Get(name); value != "" {
+			request.Header.Set(name, value)
+		}
+	}
+	for name, value := range item.Headers {
+		if !isStrippedBatchHeader(name) {
+			request.Header.Set(name, value)
+		}
+	}
+	if len(item.Body) > 0 {
+		request.Header.SetContentType(fiber.MIMEApplicationJSON)
+		request.SetBody(item.Body)
+	}
+
+	var ctx fasthttp.RequestCtx
+	ctx.Init(&request, remoteAddr, nil)
+	ctx.SetUserValue(batchSubRequestKey, true)
+	handler(&ctx)
+
+	response := &ctx.Response
+	if response.IsBodyStream() {
+		response.ResetBody()
+		return batchError(result, http.StatusBadRequest, "Streaming endpoints cannot be batched")
+	}
+
+	result.Status = response.StatusCode()
+	for _, name := range []string{fiber.HeaderETag, fiber.HeaderLocation, fiber.HeaderRetryAfter} {
+		if value := response.Header.Peek(name); len(value) > 0 {
+			if result.Headers == nil {
+				result.Headers = map[string]string{}
+			}
+			result.Headers[name] = string(value)
+		}
+	}
+
+	body := response.Body()
+	if json.Valid(body) {
+		result.Body = json.RawMessage(append([]byte(nil), body...))
+	} else if len(body) > 0 {
+		result.Body = string(body)
+	}
+	return result
+}
+
+func isStrippedBatchHeader(name string) bool {
+	for _, stripped := range batchStrippedHeaders {
+		if strings.EqualFold(name, stripped) {
+			return true
+		}
+	}
+	return strings.HasPrefix(strings.ToLower(name), "proxy-")
+}
+
+// batchError fills result with an error of the same shape as a regular response
+func batchError(result BatchResult, status int, message string) BatchResult {
+	result.Status = status
+	result.Body = fiber.Map{"error": message}
+	return result
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/billing"
+	"github.com/praleedsuvarna/shared-libs/entitlements"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// HandleStripeWebhook syncs billing state from Stripe events after verifying their signature
+func HandleStripeWebhook(c *fiber.Ctx) error {
+	body := c.Body()
+
+	secret, err := billing.WebhookSecret()
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Stripe webhook secret unavailable: %v", err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Webhook not configured",
+		})
+	}
+	if err := billing.VerifySignature(body, c.Get("Stripe-Signature"), secret); err != nil {
+		utils.LogWarning("Rejected Stripe webhook: " + err.Error())
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid signature",
+		})
+	}
+
+	var event billing.Event
+	if err := json.Unmarshal(body, &event); err != nil || event.ID == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid event payload",
+		})
+	}
+
+	if err := billing.ProcessEvent(c.UserContext(), event); err != nil {
+		// A 5xx makes Stripe redeliver the event later
+		utils.LogError(fmt.Sprintf("Failed to process Stripe event %s (%s): %v", event.ID, event.Type, err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to process event",
+		})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// GetEntitlements returns the plan, features and limits of the caller's organization
+func GetEntitlements(c *fiber.Ctx) error {
+	organizationID, _ := c.Locals("organization_id").(string)
+
+	granted, err := entitlements.ForOrganization(c.UserContext(), organizationID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to resolve entitlements",
+		})
+	}
+
+	return c.JSON(granted)
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// GetPublicBranding returns an organization's branding for frontends, e.g. to
+// style a sign-in page before the user is authenticated
+func GetPublicBranding(c *fiber.Ctx) error {
+	return publicBranding(c, c.Params("organizationId"))
+}
+
+// GetHostBranding returns the branding of the organization whose custom
+// domain the request was sent to; mount it after middleware.CustomDomain
+func GetHostBranding(c *fiber.Ctx) error {
+	organizationID := middleware.HostOrganization(c)
+	if organizationID == "" {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Unknown domain"})
+	}
+	return publicBranding(c, organizationID)
+}
+
+func publicBranding(c *fiber.Ctx, organizationID string) error {
+	branding, err := utils.GetOrganizationBranding(organizationID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch branding"})
+	}
+
+	branding.UpdatedBy = ""
+	c.Set(fiber.HeaderCacheControl, fmt.Sprintf("public, max-age=%d", int(utils.BrandingCacheTTL.Seconds())))
+	return c.JSON(branding)
+}
+
+// GetBranding returns the branding of the caller's organization
+func GetBranding(c *fiber.Ctx) error {
+	organizationID, _ := c.Locals("organization_id").(string)
+
+	branding, err := utils.GetOrganizationBranding(organizationID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch branding"})
+	}
+	return c.JSON(branding)
+}
+
+// UpdateBranding replaces the branding of the caller's organization
+func UpdateBranding(c *fiber.Ctx) error {
+	var branding models.Branding
+	if err := c.BodyParser(&branding); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	organizationID, _ := c.Locals("organization_id").(string)
+	if organizationID == "" {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Branding is scoped to an organization"})
+	}
+	adminID, _ := c.Locals("user_id").(string)
+	branding.OrganizationID = organizationID
+	branding.UpdatedBy = adminID
+
+	updated, err := utils.UpdateOrganizationBranding(branding)
+	if errors.Is(err, utils.ErrInvalidBranding) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to update branding of %s: %v", organizationID, err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update branding"})
+	}
+
+	utils.LogAuditContext(c.UserContext(), adminID, "branding_updated", organizationID, nil)
+	return c.JSON(updated)
+}
+
+// ResetBranding deletes the branding of the caller's organization so defaults apply
+func ResetBranding(c *fiber.Ctx) error {
+	organizationID, _ := c.Locals("organization_id").(string)
+
+	if err := utils.DeleteOrganizationBranding(organizationID); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reset branding"})
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+	utils.LogAuditContext(c.UserContext(), adminID, "branding_reset", organizationID, nil)
+	return c.JSON(utils.DefaultBranding(organizationID))
+}
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/codes"
+	"github.com/praleedsuvarna/shared-libs/params"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// CreateCodeRequest is the body for creating a code
+type CreateCodeRequest struct {
+	Kind           string                 `json:"kind"`
+	OrganizationID string                 `json:"organization_id"`
+	MaxUses        int                    `json:"max_uses"`
+	ExpiresIn      string                 `json:"expires_in"` // Period such as 7d or 48h; empty never expires
+	Prefix         string                 `json:"prefix"`
+	Metadata       map[string]interface{} `json:"metadata"`
+}
+
+// RedeemCodeRequest is the body for redeeming a code
+type RedeemCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// CreateCode generates a beta invite, promotion or other code
+func CreateCode(c *fiber.Ctx) error {
+	var req CreateCodeRequest
+	if err := c.BodyParser(&req); err != nil || req.Kind == "" || req.MaxUses < 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Kind is required and max_uses cannot be negative",
+		})
+	}
+
+	var ttl time.Duration
+	if req.ExpiresIn != "" {
+		parsed, err := utils.ParsePeriod(req.ExpiresIn)
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "expires_in must be a period like 24h, 7d or 4w",
+			})
+		}
+		ttl = parsed
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+	code, err := codes.Create(c.UserContext(), codes.Spec{
+		Kind:           req.Kind,
+		OrganizationID: req.OrganizationID,
+		MaxUses:        req.MaxUses,
+		TTL:            ttl,
+		Prefix:         req.Prefix,
+		Metadata:       req.Metadata,
+	}, adminID)
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to create code: %v", err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create code",
+		})
+	}
+
+	return c.Status(http.StatusCreated).JSON(code)
+}
+
+// ListCodes lists codes, optionally of one kind (?kind=beta_invite)
+func ListCodes(c *fiber.Ctx) error {
+	limit, err := params.IntBetween(c, "limit", 100, 1, 200)
+	if err != nil {
+		return params.Respond(c, err)
+	}
+
+	list, err := codes.List(c.UserContext(), c.Query("kind"), int64(limit))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch codes",
+		})
+	}
+
+	return c.JSON(list)
+}
+
+// DisableCode stops a code from being redeemed
+func DisableCode(c *fiber.Ctx) error {
+	adminID, _ := c.Locals("user_id").(string)
+
+	err := codes.Disable(c.UserContext(), c.Params("code"), adminID)
+	if errors.Is(err, codes.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Code not found",
+		})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to disable code",
+		})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// RedeemCode redeems a code for the caller
+func RedeemCode(c *fiber.Ctx) error {
+	var req RedeemCodeRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Code is required",
+		})
+	}
+
+	userID, _ := c.Locals("user_id").(string)
+	organizationID, _ := c.Locals("organization_id").(string)
+
+	redemption, code, err := codes.Redeem(c.UserContext(), req.Code, userID, organizationID)
+	switch {
+	case errors.Is(err, codes.ErrNotFound):
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Code not found"})
+	case errors.Is(err, codes.ErrAlreadyRedeemed):
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "You have already redeemed this code"})
+	case errors.Is(err, codes.ErrExpired), errors.Is(err, codes.ErrExhausted),
+		errors.Is(err, codes.ErrDisabled), errors.Is(err, codes.ErrWrongOrg):
+		return c.Status(http.StatusGone).JSON(fiber.Map{"error": "This code is no longer valid"})
+	case err != nil:
+		utils.LogError(fmt.Sprintf("Failed to redeem code: %v", err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to redeem code"})
+	}
+
+	return c.JSON(fiber.Map{
+		"redemption": redemption,
+		"kind":       code.Kind,
+		"metadata":   code.Metadata,
+	})
+}
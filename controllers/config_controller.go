@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/params"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// RefreshConfigRequest lists the secret keys to reload; empty reloads all of them
+type RefreshConfigRequest struct {
+	Keys []string `json:"keys"`
+}
+
+// RefreshConfig reloads secrets from the provider without restarting the service
+func RefreshConfig(c *fiber.Ctx) error {
+	var req RefreshConfigRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	changes, err := config.Refresh(req.Keys...)
+	if err != nil {
+		if errors.Is(err, config.ErrUnknownKey) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error":          err.Error(),
+				"available_keys": config.SecretKeys(),
+			})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to refresh configuration",
+		})
+	}
+
+	userID, _ := c.Locals("user_id").(string)
+	utils.LogAuditWithMetadata(userID, "config_refresh", strings.Join(req.Keys, ","), map[string]interface{}{
+		"changed": len(changes),
+	})
+
+	changedKeys := make([]string, 0, len(changes))
+	for _, change := range changes {
+		changedKeys = append(changedKeys, change.Key)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Configuration refreshed",
+		"changed": changedKeys,
+	})
+}
+
+// ExportConfigBundle downloads the signed non-secret configuration of this
+// environment (flags, branding, policies) for promotion to another one
+func ExportConfigBundle(c *fiber.Ctx) error {
+	bundle, err := config.ExportBundle(c.UserContext())
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to export config bundle: %v", err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to export configuration bundle",
+		})
+	}
+
+	userID, _ := c.Locals("user_id").(string)
+	utils.LogAuditContext(c.UserContext(), userID, "config_bundle_exported", bundle.Environment, nil)
+
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="config-%s.json"`, bundle.Environment))
+	return c.JSON(bundle)
+}
+
+// ImportConfigBundle applies a bundle sent as the request body and returns
+// the changes. Query: dry_run to preview the diff, prune to also remove items
+// missing from the bundle.
+func ImportConfigBundle(c *fiber.Ctx) error {
+	dryRun, err := params.Bool(c, "dry_run", false)
+	if err != nil {
+		return params.Respond(c, err)
+	}
+	prune, err := params.Bool(c, "prune", false)
+	if err != nil {
+		return params.Respond(c, err)
+	}
+
+	bundle, err := config.ReadBundle(bytes.NewReader(c.Body()))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	changes, err := config.ImportBundle(c.UserContext(), bundle, config.BundleImportOptions{DryRun: dryRun, Prune: prune})
+	switch {
+	case errors.Is(err, config.ErrBundleSignature), errors.Is(err, config.ErrInvalidBundle):
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, config.ErrBundleStale):
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		utils.LogError(fmt.Sprintf("Failed to import config bundle from %s: %v", bundle.Environment, err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to import configuration bundle",
+			"changes": changes,
+		})
+	}
+
+	if !dryRun {
+		userID, _ := c.Locals("user_id").(string)
+		utils.LogAuditContext(c.UserContext(), userID, "config_bundle_imported", bundle.Environment, map[string]interface{}{
+			"changed": len(changes),
+			"pruned":  prune,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"dry_run": dryRun,
+		"changes": changes,
+	})
+}
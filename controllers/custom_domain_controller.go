@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/domains"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AddDomainRequest is the body for adding a custom domain
+type AddDomainRequest struct {
+	Domain string `json:"domain"`
+}
+
+// DomainResponse is a custom domain with the TXT record that verifies it
+type DomainResponse struct {
+	models.CustomDomain
+	RecordName  string `json:"record_name"`
+	RecordValue string `json:"record_value"`
+}
+
+func domainResponse(domain *models.CustomDomain) DomainResponse {
+	return DomainResponse{
+		CustomDomain: *domain,
+		RecordName:   domains.RecordName(domain.Domain),
+		RecordValue:  domains.RecordValue(domain),
+	}
+}
+
+// ListDomains returns the custom domains of the caller's organization
+func ListDomains(c *fiber.Ctx) error {
+	organizationID, _ := c.Locals("organization_id").(string)
+
+	list, err := domains.List(c.UserContext(), organizationID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch domains"})
+	}
+	response := make([]DomainResponse, 0, len(list))
+	for i := range list {
+		response = append(response, domainResponse(&list[i]))
+	}
+	return c.JSON(response)
+}
+
+// AddDomain registers a custom domain for the caller's organization and
+// returns the TXT record to publish
+func AddDomain(c *fiber.Ctx) error {
+	var req AddDomainRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	organizationID, _ := c.Locals("organization_id").(string)
+	if organizationID == "" {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Domains are scoped to an organization"})
+	}
+	adminID, _ := c.Locals("user_id").(string)
+
+	domain, err := domains.Add(c.UserContext(), organizationID, req.Domain, adminID)
+	switch {
+	case errors.Is(err, domains.ErrInvalidDomain), errors.Is(err, domains.ErrTooMany):
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, domains.ErrDomainTaken):
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		utils.LogError(fmt.Sprintf("Failed to add domain for %s: %v", organizationID, err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to add domain"})
+	}
+
+	utils.LogAuditContext(c.UserContext(), adminID, "custom_domain_added", domain.ID.Hex(), map[string]interface{}{
+		"domain": domain.Domain,
+	})
+	return c.Status(http.StatusCreated).JSON(domainResponse(domain))
+}
+
+// VerifyDomain checks the TXT record of a domain now instead of waiting for
+// the background verifier
+func VerifyDomain(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("domainId"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid domain ID"})
+	}
+
+	organizationID, _ := c.Locals("organization_id").(string)
+	domain, err := domains.Get(c.UserContext(), organizationID, id)
+	if errors.Is(err, domains.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Domain not found"})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch domain"})
+	}
+
+	err = domains.Verify(c.UserContext(), domain)
+	if errors.Is(err, domains.ErrNotVerified) {
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+	}
+	if errors.Is(err, domains.ErrDomainTaken) {
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Domain is already verified by another organization"})
+	}
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to verify domain %s: %v", domain.Domain, err))
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{"error": "DNS lookup failed, try again later"})
+	}
+	return c.JSON(domainResponse(domain))
+}
+
+// RemoveDomain deletes a custom domain of the caller's organization
+func RemoveDomain(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("domainId"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid domain ID"})
+	}
+
+	organizationID, _ := c.Locals("organization_id").(string)
+	err = domains.Remove(c.UserContext(), organizationID, id)
+	if errors.Is(err, domains.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Domain not found"})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to remove domain"})
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+	utils.LogAuditContext(c.UserContext(), adminID, "custom_domain_removed", id.Hex(), nil)
+	return c.SendStatus(http.StatusNoContent)
+}
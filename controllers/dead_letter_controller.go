@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/messaging"
+	"github.com/praleedsuvarna/shared-libs/params"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListDeadLetters returns dead-lettered messages, optionally filtered by
+// ?subject= and ?replayed=true|false
+func ListDeadLetters(c *fiber.Ctx) error {
+	limit, err := params.IntBetween(c, "limit", 50, 1, 200)
+	if err != nil {
+		return params.Respond(c, err)
+	}
+
+	filter := utils.DeadLetterFilter{
+		Subject: c.Query("subject"),
+		Limit:   int64(limit),
+	}
+	if replayed := c.Query("replayed"); replayed != "" {
+		value := replayed == "true"
+		filter.Replayed = &value
+	}
+
+	deadLetters, err := utils.ListDeadLetters(filter)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch dead letters",
+		})
+	}
+
+	return c.JSON(deadLetters)
+}
+
+// GetDeadLetter returns a dead letter with its full envelope
+func GetDeadLetter(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("deadLetterId"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid dead letter ID",
+		})
+	}
+
+	deadLetter, err := utils.GetDeadLetter(id)
+	if errors.Is(err, utils.ErrDeadLetterNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Dead letter not found",
+		})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch dead letter",
+		})
+	}
+
+	return c.JSON(deadLetter)
+}
+
+// ReplayDeadLetter republishes a dead letter on its original subject
+func ReplayDeadLetter(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("deadLetterId"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid dead letter ID",
+		})
+	}
+
+	bus := messaging.Default()
+	if bus == nil {
+		return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Messaging is not configured",
+		})
+	}
+
+	deadLetter, err := utils.GetDeadLetter(id)
+	if errors.Is(err, utils.ErrDeadLetterNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Dead letter not found",
+		})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch dead letter",
+		})
+	}
+
+	if err := bus.Replay(c.UserContext(), *deadLetter); err != nil {
+		utils.LogError(fmt.Sprintf("Failed to replay dead letter %s: %v", id.Hex(), err))
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+			"error": "Failed to replay dead letter",
+		})
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+	if err := utils.MarkDeadLetterReplayed(id, adminID); err != nil {
+		utils.LogError(fmt.Sprintf("Failed to mark dead letter %s replayed: %v", id.Hex(), err))
+	}
+
+	utils.LogAuditContext(c.UserContext(), adminID, "dead_letter_replayed", id.Hex(), map[string]interface{}{
+		"subject":     deadLetter.Subject,
+		"envelope_id": deadLetter.EnvelopeID,
+		"reason":      deadLetter.Reason,
+	})
+
+	return c.JSON(fiber.Map{"replayed": true, "subject": deadLetter.Subject})
+}
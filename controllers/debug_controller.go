@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/buildinfo"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// GetGoroutineDump returns the stacks of all goroutines as text, the way a
+// crashing process prints them
+func GetGoroutineDump(c *fiber.Ctx) error {
+	var dump bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&dump, 2); err != nil {
+		utils.LogError(fmt.Sprintf("Failed to dump goroutines: %v", err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to dump goroutines"})
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+	return c.Send(dump.Bytes())
+}
+
+// GetRuntimeStats reports goroutine, memory and garbage collector statistics
+func GetRuntimeStats(c *fiber.Ctx) error {
+	var memory runtime.MemStats
+	runtime.ReadMemStats(&memory)
+
+	gc := debug.GCStats{PauseQuantiles: make([]time.Duration, 5)}
+	debug.ReadGCStats(&gc)
+	pauses := make([]string, len(gc.PauseQuantiles))
+	for i, pause := range gc.PauseQuantiles {
+		pauses[i] = pause.String()
+	}
+
+	return c.JSON(fiber.Map{
+		"build":      buildinfo.Get(),
+		"go_version": runtime.Version(),
+		"gomaxprocs": runtime.GOMAXPROCS(0),
+		"num_cpu":    runtime.NumCPU(),
+		"goroutines": runtime.NumGoroutine(),
+		"memory": fiber.Map{
+			"heap_alloc_bytes":    memory.HeapAlloc,
+			"heap_inuse_bytes":    memory.HeapInuse,
+			"heap_idle_bytes":     memory.HeapIdle,
+			"heap_released_bytes": memory.HeapReleased,
+			"heap_objects":        memory.HeapObjects,
+			"stack_inuse_bytes":   memory.StackInuse,
+			"sys_bytes":           memory.Sys,
+			"total_alloc_bytes":   memory.TotalAlloc,
+			"mallocs":             memory.Mallocs,
+			"frees":               memory.Frees,
+		},
+		"gc": fiber.Map{
+			"num_gc":          gc.NumGC,
+			"last_gc":         gc.LastGC,
+			"pause_total":     gc.PauseTotal.String(),
+			"pause_quantiles": pauses, // Min, 25%, 50%, 75%, max
+			"next_gc_bytes":   memory.NextGC,
+			"gc_cpu_fraction": memory.GCCPUFraction,
+		},
+	})
+}
@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/devices"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RegisterDeviceRequest is the body for registering a device
+type RegisterDeviceRequest struct {
+	devices.Registration
+	SessionID string `json:"session_id"` // Optional session to associate with the device
+}
+
+// UpdatePushTokenRequest is the body for changing a device's push token
+type UpdatePushTokenRequest struct {
+	PushToken    string `json:"push_token"` // Empty disables push for the device
+	PushProvider string `json:"push_provider"`
+}
+
+// RegisterDevice registers or refreshes the caller's current device. A sign-in
+// from a device the user has not used before is audit logged.
+func RegisterDevice(c *fiber.Ctx) error {
+	var req RegisterDeviceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	userID, _ := c.Locals("user_id").(string)
+	organizationID, _ := c.Locals("organization_id").(string)
+
+	// Reject another user's session before anything is stored
+	if req.SessionID != "" {
+		err := devices.VerifySessionOwner(c.UserContext(), userID, req.SessionID)
+		if errors.Is(err, devices.ErrSessionNotOwned) {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Session belongs to another user"})
+		}
+		if err != nil {
+			utils.LogError(fmt.Sprintf("Failed to verify session of user %s: %v", userID, err))
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to register device"})
+		}
+	}
+
+	device, isNew, err := devices.Register(c.UserContext(), userID, organizationID, req.Registration, middleware.ClientIP(c))
+	if errors.Is(err, devices.ErrInvalidRegistration) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to register device for user %s: %v", userID, err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to register device"})
+	}
+
+	if req.SessionID != "" {
+		if err := devices.AttachSession(c.UserContext(), userID, device.ID, req.SessionID); err != nil {
+			utils.LogError(fmt.Sprintf("Failed to attach session to device %s: %v", device.ID.Hex(), err))
+		}
+	}
+
+	status := http.StatusOK
+	if isNew {
+		status = http.StatusCreated
+		utils.LogAuditContext(c.UserContext(), userID, "device_registered", device.ID.Hex(), map[string]interface{}{
+			"platform": device.Platform,
+			"model":    device.Model,
+			"ip":       device.LastIP,
+		})
+	}
+	return c.Status(status).JSON(device)
+}
+
+// ListDevices returns the caller's devices
+func ListDevices(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(string)
+
+	list, err := devices.List(c.UserContext(), userID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch devices"})
+	}
+	return c.JSON(list)
+}
+
+// UpdateDevicePushToken sets or clears the push token of one of the caller's devices
+func UpdateDevicePushToken(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("deviceId"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid device ID"})
+	}
+
+	var req UpdatePushTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	userID, _ := c.Locals("user_id").(string)
+	err = devices.UpdatePushToken(c.UserContext(), userID, id, req.PushToken, req.PushProvider)
+	if errors.Is(err, devices.ErrInvalidRegistration) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if errors.Is(err, devices.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Device not found"})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update push token"})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// DeleteDevice removes one of the caller's devices
+func DeleteDevice(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("deviceId"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid device ID"})
+	}
+
+	userID, _ := c.Locals("user_id").(string)
+	err = devices.Delete(c.UserContext(), userID, id)
+	if errors.Is(err, devices.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Device not found"})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete device"})
+	}
+
+	utils.LogAuditContext(c.UserContext(), userID, "device_removed", id.Hex(), nil)
+	return c.SendStatus(http.StatusNoContent)
+}
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/params"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// HandleSendGridWebhook ingests SendGrid delivery events after verifying their signature
+func HandleSendGridWebhook(c *fiber.Ctx) error {
+	body := c.Body()
+
+	err := utils.VerifySendGridSignature(body,
+		c.Get("X-Twilio-Email-Event-Webhook-Signature"),
+		c.Get("X-Twilio-Email-Event-Webhook-Timestamp"),
+	)
+	if err != nil {
+		utils.LogWarning("Rejected SendGrid webhook: " + err.Error())
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid signature",
+		})
+	}
+
+	var events []utils.SendGridEvent
+	if err := json.Unmarshal(body, &events); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid event payload",
+		})
+	}
+
+	if err := utils.RecordSendGridEvents(events); err != nil {
+		// A 5xx makes SendGrid retry the batch later
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to record events",
+		})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// ListEmailSuppressions returns addresses that will not be emailed
+func ListEmailSuppressions(c *fiber.Ctx) error {
+	limit, err := params.IntBetween(c, "limit", 100, 1, 200)
+	if err != nil {
+		return params.Respond(c, err)
+	}
+
+	suppressions, err := utils.ListEmailSuppressions(int64(limit))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch suppressions",
+		})
+	}
+
+	return c.JSON(suppressions)
+}
+
+// DeleteEmailSuppression re-enables sending to an address
+func DeleteEmailSuppression(c *fiber.Ctx) error {
+	email := c.Params("email")
+
+	removed, err := utils.RemoveEmailSuppression(email)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to remove suppression",
+		})
+	}
+	if !removed {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Suppression not found",
+		})
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+	utils.LogAudit(adminID, "email_suppression_removed", email)
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// EmailTemplateRequest carries template data and, for test sends, the recipient
+type EmailTemplateRequest struct {
+	To   string                 `json:"to"`
+	Data map[string]interface{} `json:"data"`
+}
+
+// ListEmailTemplates returns the names of all registered email templates
+func ListEmailTemplates(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"templates": utils.ListEmailTemplates(),
+	})
+}
+
+// PreviewEmailTemplate renders a template with sample data. With ?format=html the
+// rendered HTML is returned directly so it can be opened in a browser.
+func PreviewEmailTemplate(c *fiber.Ctx) error {
+	var req EmailTemplateRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	name := c.Params("name")
+	if _, ok := utils.GetEmailTemplate(name); !ok {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Email template not found",
+		})
+	}
+
+	subject, html, err := utils.PreviewEmailTemplate(name, req.Data)
+	if err != nil {
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if c.Query("format") == "html" {
+		c.Type("html")
+		return c.SendString(html)
+	}
+
+	return c.JSON(fiber.Map{
+		"template": name,
+		"subject":  subject,
+		"html":     html,
+	})
+}
+
+// SendTestEmail sends a rendered template to the given address
+func SendTestEmail(c *fiber.Ctx) error {
+	var req EmailTemplateRequest
+	if err := c.BodyParser(&req); err != nil || req.To == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Recipient address is required",
+		})
+	}
+	if err := utils.ValidateEmailSyntax(req.To); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid recipient address",
+		})
+	}
+
+	name := c.Params("name")
+	if _, ok := utils.GetEmailTemplate(name); !ok {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Email template not found",
+		})
+	}
+
+	if err := utils.SendTestEmail(req.To, name, req.Data); err != nil {
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+			"error": "Failed to send test email: " + err.Error(),
+		})
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+	utils.LogAuditWithMetadata(adminID, "email_test_sent", name, map[string]interface{}{"to": req.To})
+
+	return c.JSON(fiber.Map{
+		"message": "Test email sent",
+	})
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/buildinfo"
+	"github.com/praleedsuvarna/shared-libs/config"
+)
+
+// Liveness reports that the process is up and able to serve requests, and which build it runs
+func Liveness(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"status": "ok",
+		"build":  buildinfo.Get(),
+	})
+}
+
+// Readiness checks every connected dependency and reports 503 if any is unhealthy
+func Readiness(c *fiber.Ctx) error {
+	checks, healthy := checkDependencies()
+
+	status := "ok"
+	code := http.StatusOK
+	if !healthy {
+		status = "unavailable"
+		code = http.StatusServiceUnavailable
+	}
+
+	return c.Status(code).JSON(fiber.Map{
+		"status": status,
+		"checks": checks,
+	})
+}
+
+// checkDependencies pings every connected dependency
+func checkDependencies() (fiber.Map, bool) {
+	checks := fiber.Map{}
+	healthy := true
+
+	record := func(name string, err error) {
+		if err != nil {
+			healthy = false
+			checks[name] = err.Error()
+			return
+		}
+		checks[name] = "ok"
+	}
+
+	if config.DB != nil {
+		record("mongo", config.HealthCheckDB())
+	}
+	if config.NATS != nil {
+		record("nats", config.HealthCheckNATS())
+	}
+	if config.Redis != nil {
+		record("redis", config.HealthCheckRedis())
+	}
+	return checks, healthy
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/authz"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InviteRequest is the body for creating an invitation
+type InviteRequest struct {
+	Email            string `json:"email"`
+	Role             string `json:"role"`
+	OrganizationName string `json:"organization_name"`
+}
+
+// AcceptInvitationRequest is the body for accepting an invitation
+type AcceptInvitationRequest struct {
+	Token string `json:"token"`
+}
+
+// CreateInvitation invites a user into the caller's organization
+func CreateInvitation(c *fiber.Ctx) error {
+	var req InviteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if req.Email == "" || req.Role == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Email and role are required",
+		})
+	}
+
+	if _, ok := authz.GetRole(req.Role); !ok {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Unknown role",
+		})
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+	organizationID, _ := c.Locals("organization_id").(string)
+	callerRole, _ := c.Locals("role").(string)
+
+	// Admins can only invite at or below their own role
+	if !authz.HasRole(callerRole, req.Role) {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{
+			"error": "Cannot invite a user with a higher role than your own",
+		})
+	}
+
+	invitation, err := utils.CreateInvitation(organizationID, req.OrganizationName, req.Email, req.Role, adminID)
+	if err != nil && invitation == nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create invitation",
+		})
+	}
+	if err != nil {
+		// Invitation exists but the email could not be delivered; it can be resent
+		return c.Status(http.StatusAccepted).JSON(fiber.Map{
+			"invitation": invitation,
+			"warning":    "Invitation created but email delivery failed",
+		})
+	}
+
+	return c.Status(http.StatusCreated).JSON(invitation)
+}
+
+// ListInvitations lists invitations of the caller's organization
+func ListInvitations(c *fiber.Ctx) error {
+	organizationID, _ := c.Locals("organization_id").(string)
+
+	invitations, err := utils.ListInvitations(organizationID, c.Query("status"))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch invitations",
+		})
+	}
+
+	return c.JSON(invitations)
+}
+
+// ResendInvitation sends a fresh invitation link
+func ResendInvitation(c *fiber.Ctx) error {
+	invitationID, err := primitive.ObjectIDFromHex(c.Params("invitationId"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid invitation ID",
+		})
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+	organizationID, _ := c.Locals("organization_id").(string)
+
+	invitation, err := utils.ResendInvitation(organizationID, c.Query("organization_name"), invitationID, adminID)
+	if errors.Is(err, utils.ErrInvitationNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Pending invitation not found",
+		})
+	}
+	if err != nil && invitation == nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to resend invitation",
+		})
+	}
+	if err != nil {
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{
+			"error": "Invitation email delivery failed",
+		})
+	}
+
+	return c.JSON(invitation)
+}
+
+// RevokeInvitation cancels a pending invitation
+func RevokeInvitation(c *fiber.Ctx) error {
+	invitationID, err := primitive.ObjectIDFromHex(c.Params("invitationId"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid invitation ID",
+		})
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+	organizationID, _ := c.Locals("organization_id").(string)
+
+	if err := utils.RevokeInvitation(organizationID, invitationID, adminID); err != nil {
+		if errors.Is(err, utils.ErrInvitationNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"error": "Pending invitation not found",
+			})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke invitation",
+		})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// AcceptInvitation redeems an invitation token for the authenticated user
+func AcceptInvitation(c *fiber.Ctx) error {
+	var req AcceptInvitationRequest
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invitation token is required",
+		})
+	}
+
+	userID, _ := c.Locals("user_id").(string)
+
+	membership, err := utils.AcceptInvitation(req.Token, userID)
+	switch {
+	case errors.Is(err, utils.ErrInvitationNotFound):
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Invitation not found"})
+	case errors.Is(err, utils.ErrInvitationExpired):
+		return c.Status(http.StatusGone).JSON(fiber.Map{"error": "Invitation has expired"})
+	case errors.Is(err, utils.ErrInvitationUsed):
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Invitation is no longer valid"})
+	case errors.Is(err, utils.ErrAlreadyMember):
+		return c.Status(http.StatusConflict).JSON(fiber.Map{"error": "Already a member of this organization"})
+	case err != nil:
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to accept invitation"})
+	}
+
+	return c.Status(http.StatusCreated).JSON(membership)
+}
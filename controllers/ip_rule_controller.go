@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListIPRules returns all stored IP allow/deny rules
+func ListIPRules(c *fiber.Ctx) error {
+	rules, err := utils.ListIPRules()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch IP rules",
+		})
+	}
+
+	return c.JSON(rules)
+}
+
+// CreateIPRule adds a CIDR allow or deny rule
+func CreateIPRule(c *fiber.Ctx) error {
+	var rule models.IPRule
+	if err := c.BodyParser(&rule); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+	rule.CreatedBy = adminID
+
+	created, err := utils.CreateIPRule(rule)
+	if errors.Is(err, utils.ErrInvalidIPRule) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create IP rule",
+		})
+	}
+
+	utils.LogAuditWithMetadata(adminID, "ip_rule_created", created.ID.Hex(), map[string]interface{}{
+		"cidr":   created.CIDR,
+		"action": created.Action,
+		"scope":  created.Scope,
+	})
+	return c.Status(http.StatusCreated).JSON(created)
+}
+
+// DeleteIPRule removes an IP rule
+func DeleteIPRule(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("ruleId"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid rule ID",
+		})
+	}
+
+	deleted, err := utils.DeleteIPRule(id)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete IP rule",
+		})
+	}
+	if !deleted {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "IP rule not found",
+		})
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+	utils.LogAudit(adminID, "ip_rule_deleted", id.Hex())
+	return c.JSON(fiber.Map{"message": "IP rule deleted"})
+}
@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/links"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// CreateLinkRequest is the body for creating a short link
+type CreateLinkRequest struct {
+	Target    string `json:"target"`
+	ExpiresIn string `json:"expires_in"` // Period such as 30d; empty never expires
+}
+
+// FollowLink redirects a short link to its target
+func FollowLink(c *fiber.Ctx) error {
+	link, err := links.Resolve(c.UserContext(), c.Params("token"))
+	if errors.Is(err, links.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Link not found"})
+	}
+	if errors.Is(err, links.ErrExpired) {
+		return c.Status(http.StatusGone).JSON(fiber.Map{"error": "Link has expired"})
+	}
+	if errors.Is(err, links.ErrInvalidTarget) {
+		utils.LogWarning(fmt.Sprintf("Refusing to follow link %s: %v", c.Params("token"), err))
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Link not found"})
+	}
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to resolve link: %v", err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to resolve link"})
+	}
+
+	// Temporary redirect so caches do not keep following a link that may expire
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Redirect(link.Target, http.StatusFound)
+}
+
+// MaxQRCodeSize caps the pixel size callers may request from LinkQRCode
+const MaxQRCodeSize = 2048
+
+// LinkQRCode serves a QR code of a link's short URL, for printing and sharing
+// experiences. Query: format (png or svg), size in pixels and ecc (L, M, Q, H).
+func LinkQRCode(c *fiber.Ctx) error {
+	link, err := links.Get(c.UserContext(), c.Params("token"), "")
+	if errors.Is(err, links.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Link not found"})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch link"})
+	}
+	if link.ExpiresAt != nil && !utils.Now().Before(*link.ExpiresAt) {
+		return c.Status(http.StatusGone).JSON(fiber.Map{"error": "Link has expired"})
+	}
+
+	opts := utils.QRCodeOptions{
+		Format:          utils.QRFormat(c.Query("format", string(utils.QRFormatPNG))),
+		Size:            c.QueryInt("size", 256),
+		ErrorCorrection: utils.QRCorrectionMedium,
+	}
+	if opts.Format != utils.QRFormatPNG && opts.Format != utils.QRFormatSVG {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "format must be png or svg"})
+	}
+	if opts.Size <= 0 || opts.Size > MaxQRCodeSize {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("size must be between 1 and %d", MaxQRCodeSize),
+		})
+	}
+	if level := c.Query("ecc"); level != "" {
+		if opts.ErrorCorrection, err = utils.ParseQRErrorCorrection(level); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "ecc must be L, M, Q or H"})
+		}
+	}
+
+	qr, err := utils.GenerateQRCode(links.URL(link.Token), opts)
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to generate QR code for link %s: %v", link.Token, err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate QR code"})
+	}
+
+	c.Set(fiber.HeaderContentType, opts.Format.ContentType())
+	return c.Send(qr)
+}
+
+// CreateLink creates a short link in the caller's organization
+func CreateLink(c *fiber.Ctx) error {
+	var req CreateLinkRequest
+	if err := c.BodyParser(&req); err != nil || req.Target == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Target is required",
+		})
+	}
+
+	var ttl time.Duration
+	if req.ExpiresIn != "" {
+		parsed, err := utils.ParsePeriod(req.ExpiresIn)
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "expires_in must be a period like 24h, 7d or 4w",
+			})
+		}
+		ttl = parsed
+	}
+
+	userID, _ := c.Locals("user_id").(string)
+	organizationID, _ := c.Locals("organization_id").(string)
+
+	link, err := links.Create(c.UserContext(), links.Spec{
+		Target:         req.Target,
+		OrganizationID: organizationID,
+		TTL:            ttl,
+	}, userID)
+	if errors.Is(err, links.ErrInvalidTarget) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to create link: %v", err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create link"})
+	}
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"link": link,
+		"url":  links.URL(link.Token),
+	})
+}
+
+// GetLink returns a link of the caller's organization with its hit count
+func GetLink(c *fiber.Ctx) error {
+	organizationID, _ := c.Locals("organization_id").(string)
+
+	link, err := links.Get(c.UserContext(), c.Params("token"), organizationID)
+	if errors.Is(err, links.ErrNotFound) || (err == nil && organizationID == "") {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Link not found"})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch link"})
+	}
+
+	return c.JSON(link)
+}
+
+// DeleteLink removes a link of the caller's organization
+func DeleteLink(c *fiber.Ctx) error {
+	organizationID, _ := c.Locals("organization_id").(string)
+
+	err := links.Delete(c.UserContext(), c.Params("token"), organizationID)
+	if errors.Is(err, links.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Link not found"})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete link"})
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/messaging"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// GetLogSettings returns the log level and sampling of the instance serving the request
+func GetLogSettings(c *fiber.Ctx) error {
+	return c.JSON(utils.CurrentLogSettings())
+}
+
+// UpdateLogSettings changes the log level and sampling at runtime, e.g.
+// {"level": "debug", "duration": "5m"}; debug always reverts, within
+// utils.MaxDebugLogDuration. The change is applied here and, when messaging is
+// enabled, broadcast to every instance; "service" limits it to one service,
+// "reset" returns to LOG_LEVEL and LOG_SAMPLE_RATE.
+func UpdateLogSettings(c *fiber.Ctx) error {
+	var req utils.LogControl
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	settings, err := utils.ApplyLogControl(req, c.App().Config().AppName)
+	if errors.Is(err, utils.ErrInvalidLogSettings) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to change log settings: %v", err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to change log settings"})
+	}
+
+	broadcast := false
+	if messaging.Default() != nil {
+		if err := messaging.PublishLogControl(c.UserContext(), req); err != nil {
+			utils.LogWarning(fmt.Sprintf("Failed to broadcast log settings: %v", err))
+		} else {
+			broadcast = true
+		}
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+	utils.LogAuditContext(c.UserContext(), adminID, "log_settings_changed", "logging", map[string]interface{}{
+		"level":       req.Level,
+		"sample_rate": req.SampleRate,
+		"duration":    req.Duration,
+		"service":     req.Service,
+		"reset":       req.Reset,
+	})
+	return c.JSON(fiber.Map{"settings": settings, "broadcast": broadcast})
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// EnableMaintenanceRequest configures maintenance mode; Duration (e.g. "30m") schedules its end
+type EnableMaintenanceRequest struct {
+	Message    string `json:"message"`
+	RetryAfter int    `json:"retry_after"`
+	Duration   string `json:"duration"`
+}
+
+// GetMaintenanceState returns the current maintenance state
+func GetMaintenanceState(c *fiber.Ctx) error {
+	return c.JSON(utils.GetMaintenanceState())
+}
+
+// EnableMaintenance puts every instance into maintenance mode
+func EnableMaintenance(c *fiber.Ctx) error {
+	var req EnableMaintenanceRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+	state := models.MaintenanceState{
+		Enabled:    true,
+		Message:    req.Message,
+		RetryAfter: req.RetryAfter,
+		UpdatedBy:  adminID,
+	}
+
+	if req.Duration != "" {
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil || duration <= 0 {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid duration",
+			})
+		}
+		endsAt := utils.Now().Add(duration)
+		state.EndsAt = &endsAt
+		if state.RetryAfter == 0 {
+			state.RetryAfter = int(duration.Seconds())
+		}
+	}
+
+	state, err := utils.SetMaintenanceState(state)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to enable maintenance mode",
+		})
+	}
+
+	utils.LogAuditWithMetadata(adminID, "maintenance_enabled", "maintenance", map[string]interface{}{
+		"message":     state.Message,
+		"retry_after": state.RetryAfter,
+	})
+	return c.JSON(state)
+}
+
+// DisableMaintenance reopens the service to all traffic
+func DisableMaintenance(c *fiber.Ctx) error {
+	adminID, _ := c.Locals("user_id").(string)
+
+	state, err := utils.SetMaintenanceState(models.MaintenanceState{UpdatedBy: adminID})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to disable maintenance mode",
+		})
+	}
+
+	utils.LogAudit(adminID, "maintenance_disabled", "maintenance")
+	return c.JSON(state)
+}
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/moderation"
+	"github.com/praleedsuvarna/shared-libs/params"
+	"github.com/praleedsuvarna/shared-libs/repo"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// ReviewModerationRequest is an admin's decision on moderated content
+type ReviewModerationRequest struct {
+	Decision string `json:"decision"` // "approved" or "rejected"
+	Note     string `json:"note"`
+}
+
+// ListModerationItems returns the caller's organization's moderated content,
+// quarantined items by default. Query: status (quarantined, approved,
+// rejected or all), page, limit.
+func ListModerationItems(c *fiber.Ctx) error {
+	organizationID, _ := c.Locals("organization_id").(string)
+	status, err := params.Enum(c, "status", models.ModerationQuarantined,
+		models.ModerationApproved, models.ModerationRejected, "all")
+	if err != nil {
+		return params.Respond(c, err)
+	}
+	if status == "all" {
+		status = ""
+	}
+	page, err := params.IntBetween(c, "page", 1, 1, 10000)
+	if err != nil {
+		return params.Respond(c, err)
+	}
+	limit, err := params.IntBetween(c, "limit", 50, 1, 200)
+	if err != nil {
+		return params.Respond(c, err)
+	}
+
+	items, err := moderation.List(c.UserContext(), organizationID, status, repo.Page{Page: page, Limit: limit})
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to list moderation items of %s: %v", organizationID, err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch moderation items"})
+	}
+	return c.JSON(items)
+}
+
+// GetModerationItem returns one moderated item with its flags
+func GetModerationItem(c *fiber.Ctx) error {
+	organizationID, _ := c.Locals("organization_id").(string)
+
+	item, err := moderation.Get(c.UserContext(), c.Params("itemId"), organizationID)
+	if errors.Is(err, repo.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Moderation item not found"})
+	}
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to load moderation item: %v", err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch moderation item"})
+	}
+	return c.JSON(item)
+}
+
+// ReviewModerationItem approves or rejects moderated content; the owning
+// service hears of it on moderation.DecidedSubject
+func ReviewModerationItem(c *fiber.Ctx) error {
+	var req ReviewModerationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	organizationID, _ := c.Locals("organization_id").(string)
+	adminID, _ := c.Locals("user_id").(string)
+	item, err := moderation.Review(c.UserContext(), c.Params("itemId"), organizationID, req.Decision, adminID, req.Note)
+	switch {
+	case errors.Is(err, moderation.ErrInvalidDecision):
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, repo.ErrNotFound):
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Moderation item not found"})
+	case err != nil:
+		utils.LogError(fmt.Sprintf("Failed to review moderation item: %v", err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to review moderation item"})
+	}
+
+	utils.LogAuditContext(c.UserContext(), adminID, "moderation_reviewed", item.ID, map[string]interface{}{
+		"decision":    item.Status,
+		"object_type": item.ObjectType,
+		"object_id":   item.ObjectID,
+	})
+	return c.JSON(item)
+}
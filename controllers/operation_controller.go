@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/operations"
+	"github.com/praleedsuvarna/shared-libs/repo"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// GetOperation returns the status, progress and outcome of an operation in the caller's organization
+func GetOperation(c *fiber.Ctx) error {
+	organizationID, _ := c.Locals("organization_id").(string)
+
+	op, err := operations.Get(c.UserContext(), c.Params("operationId"), organizationID)
+	if errors.Is(err, repo.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Operation not found",
+		})
+	}
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to load operation: %v", err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load operation",
+		})
+	}
+
+	return c.JSON(op)
+}
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/authz"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListPolicies returns all access policies
+func ListPolicies(c *fiber.Ctx) error {
+	policies, err := authz.ListPolicies(c.UserContext())
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch policies",
+		})
+	}
+
+	return c.JSON(policies)
+}
+
+// CreatePolicy adds a new access policy
+func CreatePolicy(c *fiber.Ctx) error {
+	var policy models.Policy
+	if err := c.BodyParser(&policy); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	userID, _ := c.Locals("user_id").(string)
+	policy.CreatedBy = userID
+
+	created, err := authz.CreatePolicy(c.UserContext(), policy)
+	if errors.Is(err, authz.ErrInvalidPolicy) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create policy",
+		})
+	}
+
+	utils.LogAudit(userID, "policy_created", created.ID.Hex())
+	return c.Status(http.StatusCreated).JSON(created)
+}
+
+// DeletePolicy removes an access policy
+func DeletePolicy(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("policyId"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid policy ID",
+		})
+	}
+
+	deleted, err := authz.DeletePolicy(c.UserContext(), id)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete policy",
+		})
+	}
+	if !deleted {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Policy not found",
+		})
+	}
+
+	userID, _ := c.Locals("user_id").(string)
+	utils.LogAudit(userID, "policy_deleted", id.Hex())
+	return c.SendStatus(http.StatusNoContent)
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// GetMyPreferences returns the authenticated user's preferences
+func GetMyPreferences(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(string)
+
+	preferences, err := utils.GetUserPreferences(userID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch preferences",
+		})
+	}
+
+	return c.JSON(preferences)
+}
+
+// UpdateMyPreferences replaces the authenticated user's preferences
+func UpdateMyPreferences(c *fiber.Ctx) error {
+	var preferences models.UserPreferences
+	if err := c.BodyParser(&preferences); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	userID, _ := c.Locals("user_id").(string)
+	preferences.UserID = userID
+
+	updated, err := utils.UpdateUserPreferences(preferences)
+	if errors.Is(err, utils.ErrInvalidPreferences) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update preferences",
+		})
+	}
+
+	return c.JSON(updated)
+}
+
+// ResetMyPreferences deletes stored preferences so defaults apply
+func ResetMyPreferences(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(string)
+
+	if err := utils.DeleteUserPreferences(userID); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to reset preferences",
+		})
+	}
+
+	return c.JSON(utils.DefaultUserPreferences(userID))
+}
+
+// GetUserPreferences returns another user's preferences (admin)
+func GetUserPreferences(c *fiber.Ctx) error {
+	userID := c.Params("userId")
+	if userID == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "User ID is required",
+		})
+	}
+
+	preferences, err := utils.GetUserPreferences(userID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch preferences",
+		})
+	}
+
+	return c.JSON(preferences)
+}
@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/saml"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// SAMLMetadata serves the service provider metadata of an organization
+func SAMLMetadata(c *fiber.Ctx) error {
+	metadata, err := saml.Metadata(c.Params("organizationId"))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to render metadata"})
+	}
+	c.Set(fiber.HeaderContentType, "application/samlmetadata+xml")
+	return c.Send(metadata)
+}
+
+// SAMLLogin redirects the browser to the organization's identity provider.
+// Query: relay_state, returned to the ACS unchanged.
+func SAMLLogin(c *fiber.Ctx) error {
+	loginURL, err := saml.LoginURL(c.UserContext(), c.Params("organizationId"), c.Query("relay_state"))
+	if errors.Is(err, saml.ErrNotConfigured) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start SAML sign-in"})
+	}
+	return c.Redirect(loginURL, http.StatusFound)
+}
+
+// SAMLAssertionConsumer verifies the SAMLResponse posted by the identity
+// provider and signs the user in. With a connection redirect URL the tokens
+// are passed in its fragment, together with the RelayState; otherwise they
+// are returned as JSON.
+func SAMLAssertionConsumer(c *fiber.Ctx) error {
+	organizationID := c.Params("organizationId")
+
+	identity, err := saml.ParseResponse(c.UserContext(), organizationID, c.FormValue("SAMLResponse"))
+	if errors.Is(err, saml.ErrNotConfigured) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if errors.Is(err, saml.ErrInvalidResponse) || errors.Is(err, saml.ErrInvalidSignature) || errors.Is(err, saml.ErrReplayed) {
+		utils.Log(c.UserContext()).Warn("Rejected SAML response", "organization_id", organizationID, "error", err.Error())
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid SAML response"})
+	}
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to verify SAML response for %s: %v", organizationID, err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify SAML response"})
+	}
+
+	tokens, err := saml.SignIn(c.UserContext(), identity)
+	if errors.Is(err, saml.ErrUnknownUser) || errors.Is(err, saml.ErrUserDisabled) ||
+		errors.Is(err, saml.ErrNotSAMLUser) || errors.Is(err, saml.ErrRoleNotAllowed) {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed SAML sign-in for %s: %v", organizationID, err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to sign in"})
+	}
+
+	if redirectURL := identity.RedirectURL(c.UserContext()); redirectURL != "" {
+		fragment := url.Values{
+			"access_token":  {tokens.AccessToken},
+			"refresh_token": {tokens.RefreshToken},
+		}
+		if relayState := c.FormValue("RelayState"); relayState != "" {
+			fragment.Set("relay_state", relayState)
+		}
+		return c.Redirect(redirectURL+"#"+fragment.Encode(), http.StatusSeeOther)
+	}
+	return c.JSON(tokens)
+}
+
+// GetSAMLConnection returns the SAML connection of the caller's organization,
+// with the service provider URLs to configure in the identity provider
+func GetSAMLConnection(c *fiber.Ctx) error {
+	organizationID, _ := c.Locals("organization_id").(string)
+
+	connection, err := saml.GetConnection(c.UserContext(), organizationID)
+	if errors.Is(err, saml.ErrNotConfigured) {
+		connection, err = &models.SAMLConnection{OrganizationID: organizationID}, nil
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch SAML connection"})
+	}
+	return c.JSON(fiber.Map{
+		"connection": connection,
+		"entity_id":  saml.EntityID(organizationID),
+		"acs_url":    saml.ACSURL(organizationID),
+	})
+}
+
+// SaveSAMLConnection creates or replaces the SAML connection of the caller's organization
+func SaveSAMLConnection(c *fiber.Ctx) error {
+	var connection models.SAMLConnection
+	if err := c.BodyParser(&connection); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	organizationID, _ := c.Locals("organization_id").(string)
+	if organizationID == "" {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "SAML connections are scoped to an organization"})
+	}
+	if existing, err := saml.GetConnection(c.UserContext(), organizationID); err == nil {
+		connection.ID, connection.CreatedAt = existing.ID, existing.CreatedAt
+	}
+	connection.OrganizationID = organizationID
+
+	err := saml.SaveConnection(c.UserContext(), &connection)
+	if errors.Is(err, saml.ErrInvalidConnection) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to save SAML connection for %s: %v", organizationID, err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save SAML connection"})
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+	utils.LogAuditContext(c.UserContext(), adminID, "saml_connection_saved", organizationID, map[string]interface{}{
+		"idp_entity_id": connection.IdPEntityID,
+		"enabled":       connection.Enabled,
+	})
+	return c.JSON(connection)
+}
+
+// DeleteSAMLConnection removes the SAML connection of the caller's organization
+func DeleteSAMLConnection(c *fiber.Ctx) error {
+	organizationID, _ := c.Locals("organization_id").(string)
+
+	err := saml.DeleteConnection(c.UserContext(), organizationID)
+	if errors.Is(err, saml.ErrNotConfigured) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "SAML connection not found"})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete SAML connection"})
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+	utils.LogAuditContext(c.UserContext(), adminID, "saml_connection_deleted", organizationID, nil)
+	return c.SendStatus(http.StatusNoContent)
+}
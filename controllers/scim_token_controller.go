@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/scim"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateSCIMTokenRequest is the body for issuing a SCIM token
+type CreateSCIMTokenRequest struct {
+	Description string `json:"description"` // E.g. "Okta"
+}
+
+// SCIMTokenResponse returns a new SCIM token; the token is not shown again
+type SCIMTokenResponse struct {
+	Token string            `json:"token"`
+	SCIM  *models.SCIMToken `json:"scim_token"`
+}
+
+// CreateSCIMToken issues a SCIM token for the caller's organization, to
+// configure in the identity provider
+func CreateSCIMToken(c *fiber.Ctx) error {
+	var req CreateSCIMTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	organizationID, _ := c.Locals("organization_id").(string)
+	if organizationID == "" {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "SCIM tokens are scoped to an organization"})
+	}
+	adminID, _ := c.Locals("user_id").(string)
+
+	token, record, err := scim.CreateToken(c.UserContext(), organizationID, req.Description, adminID)
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to create SCIM token for %s: %v", organizationID, err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create SCIM token"})
+	}
+
+	utils.LogAuditContext(c.UserContext(), adminID, "scim_token_created", record.ID.Hex(), map[string]interface{}{
+		"organization_id": organizationID,
+		"description":     req.Description,
+	})
+	return c.Status(http.StatusCreated).JSON(SCIMTokenResponse{Token: token, SCIM: record})
+}
+
+// ListSCIMTokens returns the SCIM tokens of the caller's organization
+func ListSCIMTokens(c *fiber.Ctx) error {
+	organizationID, _ := c.Locals("organization_id").(string)
+
+	list, err := scim.ListTokens(c.UserContext(), organizationID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch SCIM tokens"})
+	}
+	return c.JSON(list)
+}
+
+// RevokeSCIMToken deletes a SCIM token of the caller's organization
+func RevokeSCIMToken(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("tokenId"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid token ID"})
+	}
+
+	organizationID, _ := c.Locals("organization_id").(string)
+	err = scim.RevokeToken(c.UserContext(), organizationID, id)
+	if errors.Is(err, scim.ErrTokenNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "SCIM token not found"})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke SCIM token"})
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+	utils.LogAuditContext(c.UserContext(), adminID, "scim_token_revoked", id.Hex(), nil)
+	return c.SendStatus(http.StatusNoContent)
+}
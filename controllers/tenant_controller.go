@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/operations"
+	"github.com/praleedsuvarna/shared-libs/params"
+	"github.com/praleedsuvarna/shared-libs/stream"
+	"github.com/praleedsuvarna/shared-libs/tenants"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// ExportOrganizationData streams every registered collection of an
+// organization as a tenant archive (gzipped tar)
+func ExportOrganizationData(c *fiber.Ctx) error {
+	organizationID := c.Params("organizationId")
+	adminID, _ := c.Locals("user_id").(string)
+
+	utils.LogAuditContext(c.UserContext(), adminID, "organization_data_exported", organizationID, nil)
+
+	filename := fmt.Sprintf("organization-%s.tar.gz", organizationID)
+	return stream.Stream(c, filename, "application/gzip", func(ctx context.Context, w io.Writer, flush func() error) error {
+		if _, err := tenants.Export(ctx, organizationID, w); err != nil {
+			return err
+		}
+		return flush()
+	})
+}
+
+// ImportOrganizationData imports a tenant archive, sent as the "archive" form
+// field, into the organization in the path. The import runs as an operation
+// whose result is the tenants.ImportReport. Query: dry_run.
+func ImportOrganizationData(c *fiber.Ctx) error {
+	organizationID := c.Params("organizationId")
+	dryRun, err := params.Bool(c, "dry_run", false)
+	if err != nil {
+		return params.Respond(c, err)
+	}
+
+	header, err := c.FormFile("archive")
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "An archive file is required"})
+	}
+
+	// The import reads the archive once per pass and outlives the request,
+	// so keep a copy of its own
+	archive, err := os.CreateTemp("", "tenant-import-*.tar.gz")
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to stage tenant archive: %v", err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to store archive"})
+	}
+	if err := c.SaveFile(header, archive.Name()); err != nil {
+		archive.Close()
+		os.Remove(archive.Name())
+		utils.LogError(fmt.Sprintf("Failed to stage tenant archive: %v", err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to store archive"})
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+	adminOrganizationID, _ := c.Locals("organization_id").(string)
+	op, err := operations.Start(c.UserContext(), operations.Spec{
+		Type:           "tenants.import",
+		OrganizationID: adminOrganizationID,
+		CreatedBy:      adminID,
+	}, func(ctx context.Context, progress *operations.Progress) (interface{}, error) {
+		defer func() {
+			archive.Close()
+			os.Remove(archive.Name())
+		}()
+
+		report, err := tenants.Import(ctx, archive, tenants.ImportOptions{
+			OrganizationID: organizationID,
+			DryRun:         dryRun,
+			Progress: func(done, total int) {
+				if total > 0 {
+					progress.Update(done*100/total, fmt.Sprintf("%d of %d documents", done, total))
+				}
+			},
+		})
+		if err == nil && !dryRun {
+			utils.LogAuditContext(ctx, adminID, "organization_data_imported", organizationID, map[string]interface{}{
+				"source_organization_id": report.SourceOrganizationID,
+			})
+		}
+		if errors.Is(err, tenants.ErrInvalidArchive) {
+			return report, &operations.Error{Message: err.Error()}
+		}
+		return report, err
+	})
+	if err != nil {
+		archive.Close()
+		os.Remove(archive.Name())
+		utils.LogError(fmt.Sprintf("Failed to start import into %s: %v", organizationID, err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start import"})
+	}
+
+	return operations.Accepted(c, op)
+}
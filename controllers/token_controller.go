@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// TokenGrant describes the scoped tokens an endpoint built by IssueScopedToken
+// hands out, e.g. upload-only tokens for a Unity plugin or viewer tokens for
+// one experience
+type TokenGrant struct {
+	Scopes []string
+	TTL    time.Duration // Default and maximum utils.MaxScopedTokenTTL
+	// ResourceParam names the route parameter the token is limited to; empty
+	// issues tokens valid for any resource
+	ResourceParam string
+	// Authorize checks that the signed-in user may act on the resource, and
+	// returns an error to refuse the token. Nil allows every user.
+	Authorize func(c *fiber.Ctx, resource string) error
+}
+
+// ScopedTokenResponse is returned by scoped token endpoints
+type ScopedTokenResponse struct {
+	Token     string    `json:"token"`
+	TokenType string    `json:"token_type"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Scopes    []string  `json:"scopes"`
+	Resource  string    `json:"resource,omitempty"`
+}
+
+// IssueScopedToken returns a handler that exchanges the caller's session for a
+// short-lived token carrying only the grant's scopes. Mount it behind
+// AuthMiddleware; see routes.SetupTokenEndpoint.
+func IssueScopedToken(grant TokenGrant) fiber.Handler {
+	if len(grant.Scopes) == 0 {
+		panic("IssueScopedToken: grant needs at least one scope")
+	}
+
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(string)
+		if !ok || userID == "" {
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+		organizationID, _ := c.Locals("organization_id").(string)
+
+		resource := ""
+		if grant.ResourceParam != "" {
+			if resource = c.Params(grant.ResourceParam); resource == "" {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": grant.ResourceParam + " is required"})
+			}
+		}
+		if grant.Authorize != nil {
+			if err := grant.Authorize(c, resource); err != nil {
+				return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+			}
+		}
+
+		claims := &utils.ScopedClaims{
+			UserID:         userID,
+			OrganizationID: organizationID,
+			Scopes:         grant.Scopes,
+			Resource:       resource,
+		}
+		token, err := utils.GenerateScopedToken(claims, grant.TTL)
+		if err != nil {
+			utils.LogError(fmt.Sprintf("Failed to issue scoped token for user %s: %v", userID, err))
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to issue token"})
+		}
+
+		utils.LogAuditContext(c.UserContext(), userID, "scoped_token_issued", claims.ID, map[string]interface{}{
+			"scopes":   claims.Scopes,
+			"resource": resource,
+		})
+
+		// Tokens must not be cached by the browser or intermediaries
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		return c.JSON(ScopedTokenResponse{
+			Token:     token,
+			TokenType: "Bearer",
+			ExpiresAt: claims.ExpiresAt,
+			Scopes:    claims.Scopes,
+			Resource:  resource,
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/repo"
+	"github.com/praleedsuvarna/shared-libs/transcode"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// GetTranscodeJob returns the status and progress of a transcode job in the caller's organization
+func GetTranscodeJob(c *fiber.Ctx) error {
+	organizationID, _ := c.Locals("organization_id").(string)
+
+	job, err := transcode.Get(c.UserContext(), c.Params("jobId"), organizationID)
+	if errors.Is(err, repo.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "Transcode job not found",
+		})
+	}
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to load transcode job: %v", err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load transcode job",
+		})
+	}
+
+	return c.JSON(job)
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/params"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// MaxUserImportBytes caps the size of an import file
+const MaxUserImportBytes = 5 << 20
+
+// ImportUsers invites users into the caller's organization from a CSV or JSON
+// file, sent as the "file" form field or as the raw body (text/csv or
+// application/json). Query: dry_run, on_duplicate (skip, update or fail),
+// default_role and organization_name for the invitation emails.
+func ImportUsers(c *fiber.Ctx) error {
+	organizationID, _ := c.Locals("organization_id").(string)
+	if organizationID == "" {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "Imports are scoped to an organization"})
+	}
+
+	dryRun, err := params.Bool(c, "dry_run", false)
+	if err != nil {
+		return params.Respond(c, err)
+	}
+	onDuplicate, err := params.Enum(c, "on_duplicate",
+		utils.ImportSkipDuplicates, utils.ImportUpdateDuplicates, utils.ImportFailOnDuplicates)
+	if err != nil {
+		return params.Respond(c, err)
+	}
+
+	content, isCSV, err := importFile(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var records []utils.UserImportRecord
+	if isCSV {
+		records, err = utils.ParseUserImportCSV(bytes.NewReader(content))
+	} else {
+		records, err = utils.ParseUserImportJSON(bytes.NewReader(content))
+	}
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+	role, _ := c.Locals("role").(string)
+	report, err := utils.ImportUsers(c.UserContext(), records, utils.UserImportOptions{
+		OrganizationID:   organizationID,
+		OrganizationName: c.Query("organization_name"),
+		ImportedBy:       adminID,
+		ImporterRole:     role,
+		DefaultRole:      c.Query("default_role"),
+		OnDuplicate:      onDuplicate,
+		DryRun:           dryRun,
+	})
+	if errors.Is(err, utils.ErrImportDuplicates) {
+		return c.Status(http.StatusConflict).JSON(report)
+	}
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to import users into %s: %v", organizationID, err))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to import users"})
+	}
+
+	return c.JSON(report)
+}
+
+// importFile returns the uploaded file or raw body and whether it is CSV
+func importFile(c *fiber.Ctx) ([]byte, bool, error) {
+	if header, err := c.FormFile("file"); err == nil {
+		if header.Size > MaxUserImportBytes {
+			return nil, false, fmt.Errorf("import files are limited to %d MB", MaxUserImportBytes>>20)
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, false, err
+		}
+		defer file.Close()
+
+		content, err := io.ReadAll(io.LimitReader(file, MaxUserImportBytes))
+		if err != nil {
+			return nil, false, err
+		}
+		isCSV := strings.EqualFold(filepath.Ext(header.Filename), ".csv") ||
+			strings.HasPrefix(header.Header.Get(fiber.HeaderContentType), "text/csv")
+		return content, isCSV, nil
+	}
+
+	body := c.Body()
+	if len(body) == 0 {
+		return nil, false, errors.New("an import file is required")
+	}
+	if len(body) > MaxUserImportBytes {
+		return nil, false, fmt.Errorf("import files are limited to %d MB", MaxUserImportBytes>>20)
+	}
+	return body, strings.HasPrefix(string(c.Request().Header.ContentType()), "text/csv"), nil
+}
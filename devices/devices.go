@@ -0,0 +1,344 @@
+// Package devices registers the app installations and browsers users sign in
+// from. Each device carries a fingerprint derived from stable client traits,
+// its push token, and the sessions opened on it:
+//
+//	device, isNew, err := devices.Register(ctx, userID, orgID, registration, ip)
+//	devices.AttachSession(ctx, userID, device.ID, sessionID) // After login
+//	if isNew { /* notify the user of a sign-in from a new device */ }
+//
+// Push notifications are sent to PushTargets; login flows use IsKnown to flag
+// sign-ins from unrecognized devices. Services that track sessions set
+// SessionOwner, so a session can only be attached to its own user's devices.
+package devices
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MaxSessionsPerDevice caps the session IDs kept per device; the oldest are dropped
+const MaxSessionsPerDevice = 20
+
+// Platforms are the accepted device platforms
+var Platforms = []string{
+	models.PlatformIOS, models.PlatformAndroid, models.PlatformWeb,
+	models.PlatformWindows, models.PlatformMacOS, models.PlatformLinux, models.PlatformVisionOS,
+}
+
+// PushProviders are the accepted push providers
+var PushProviders = []string{models.PushAPNs, models.PushFCM, models.PushWebPush}
+
+// Device errors
+var (
+	ErrNotFound            = errors.New("device not found")
+	ErrInvalidRegistration = errors.New("invalid device registration")
+	ErrSessionNotOwned     = errors.New("session belongs to another user")
+)
+
+// SessionOwner, when set, returns the user a session was issued to, so
+// AttachSession can refuse sessions of other users. Without it only sessions
+// already attached to another user's device are refused.
+var SessionOwner func(ctx context.Context, sessionID string) (string, error)
+
+// Registration is what a client reports about itself
+type Registration struct {
+	Platform     string `json:"platform"`
+	Model        string `json:"model"`
+	OSVersion    string `json:"os_version"`
+	AppVersion   string `json:"app_version"`
+	PushToken    string `json:"push_token"`
+	PushProvider string `json:"push_provider"`
+	// Traits are stable device attributes hashed into the fingerprint, e.g. an
+	// install ID or Unity's deviceUniqueIdentifier. Versions and addresses
+	// change and do not belong here.
+	Traits map[string]string `json:"traits"`
+}
+
+// Validate checks the platform, push provider and traits
+func (r *Registration) Validate() error {
+	if !slices.Contains(Platforms, r.Platform) {
+		return fmt.Errorf("%w: platform must be one of %s", ErrInvalidRegistration, strings.Join(Platforms, ", "))
+	}
+	if r.PushToken != "" && !slices.Contains(PushProviders, r.PushProvider) {
+		return fmt.Errorf("%w: push_provider must be one of %s", ErrInvalidRegistration, strings.Join(PushProviders, ", "))
+	}
+	if len(r.Traits) == 0 {
+		return fmt.Errorf("%w: traits are required to fingerprint the device", ErrInvalidRegistration)
+	}
+	return nil
+}
+
+// Fingerprint hashes the platform and traits into a stable identifier; the
+// order of traits does not matter
+func Fingerprint(platform string, traits map[string]string) string {
+	keys := make([]string, 0, len(traits))
+	for key := range traits {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	hash.Write([]byte(platform))
+	for _, key := range keys {
+		fmt.Fprintf(hash, "\x00%s=%s", key, traits[key])
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func collection() *mongo.Collection {
+	return config.GetCollection(models.Device{}.CollectionName())
+}
+
+// Register creates or refreshes the user's device matching the registration's
+// fingerprint. isNew reports a device the user has not signed in from before.
+func Register(ctx context.Context, userID, organizationID string, registration Registration, ip string) (device *models.Device, isNew bool, err error) {
+	if err := registration.Validate(); err != nil {
+		return nil, false, err
+	}
+
+	fingerprint := Fingerprint(registration.Platform, registration.Traits)
+	now := utils.Now()
+	set := bson.M{
+		"organization_id": organizationID,
+		"platform":        registration.Platform,
+		"model":           registration.Model,
+		"os_version":      registration.OSVersion,
+		"app_version":     registration.AppVersion,
+		"last_ip":         ip,
+		"last_seen_at":    now,
+	}
+	if registration.PushToken != "" {
+		// A push token belongs to one installation; take it from devices it
+		// was registered to before, e.g. when another user signed in on the phone
+		if err := releasePushToken(ctx, registration.PushToken, userID, fingerprint); err != nil {
+			return nil, false, err
+		}
+		set["push_token"] = registration.PushToken
+		set["push_provider"] = registration.PushProvider
+	}
+
+	filter := bson.M{"user_id": userID, "fingerprint": fingerprint}
+	result, err := collection().UpdateOne(ctx, filter,
+		bson.M{
+			"$set":         set,
+			"$setOnInsert": bson.M{"_id": primitive.NewObjectID(), "created_at": now},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent registration of the same device inserted it first
+		result, err = collection().UpdateOne(ctx, filter, bson.M{"$set": set})
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("register device: %w", err)
+	}
+
+	device = &models.Device{}
+	err = collection().FindOne(ctx, bson.M{"user_id": userID, "fingerprint": fingerprint}).Decode(device)
+	if err != nil {
+		return nil, false, fmt.Errorf("load device: %w", err)
+	}
+	return device, result.UpsertedCount > 0, nil
+}
+
+// releasePushToken removes a push token from the devices it was registered
+// to before: the user's own, or the same installation registered by another
+// user. Devices of unrelated users keep it, so a caller cannot disable
+// their notifications by registering a token it has seen.
+func releasePushToken(ctx context.Context, token, userID, fingerprint string) error {
+	_, err := collection().UpdateMany(ctx,
+		bson.M{"push_token": token, "$or": bson.A{
+			bson.M{"user_id": userID},
+			bson.M{"fingerprint": fingerprint},
+		}},
+		bson.M{"$unset": bson.M{"push_token": "", "push_provider": ""}},
+	)
+	return err
+}
+
+// Get returns a device of the user
+func Get(ctx context.Context, userID string, id primitive.ObjectID) (*models.Device, error) {
+	var device models.Device
+	err := collection().FindOne(ctx, bson.M{"_id": id, "user_id": userID}).Decode(&device)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// ByFingerprint returns the user's device with a fingerprint
+func ByFingerprint(ctx context.Context, userID, fingerprint string) (*models.Device, error) {
+	var device models.Device
+	err := collection().FindOne(ctx, bson.M{"user_id": userID, "fingerprint": fingerprint}).Decode(&device)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// List returns the user's devices, most recently seen first
+func List(ctx context.Context, userID string) ([]models.Device, error) {
+	return find(ctx, bson.M{"user_id": userID})
+}
+
+// PushTargets returns the user's devices that can receive push notifications
+func PushTargets(ctx context.Context, userID string) ([]models.Device, error) {
+	return find(ctx, bson.M{"user_id": userID, "push_token": bson.M{"$exists": true, "$ne": ""}})
+}
+
+func find(ctx context.Context, filter bson.M) ([]models.Device, error) {
+	cursor, err := collection().Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "last_seen_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	devices := []models.Device{}
+	if err := cursor.All(ctx, &devices); err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// IsKnown reports whether the user has signed in from a device with this
+// fingerprint before; an unknown device is a signal for suspicious-login checks
+func IsKnown(ctx context.Context, userID, fingerprint string) (bool, error) {
+	count, err := collection().CountDocuments(ctx,
+		bson.M{"user_id": userID, "fingerprint": fingerprint},
+		options.Count().SetLimit(1),
+	)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// UpdatePushToken sets or, with an empty token, clears a device's push token
+func UpdatePushToken(ctx context.Context, userID string, id primitive.ObjectID, token, provider string) error {
+	update := bson.M{"$unset": bson.M{"push_token": "", "push_provider": ""}}
+	if token != "" {
+		if !slices.Contains(PushProviders, provider) {
+			return fmt.Errorf("%w: push_provider must be one of %s", ErrInvalidRegistration, strings.Join(PushProviders, ", "))
+		}
+		device, err := Get(ctx, userID, id)
+		if err != nil {
+			return err
+		}
+		if err := releasePushToken(ctx, token, userID, device.Fingerprint); err != nil {
+			return err
+		}
+		update = bson.M{"$set": bson.M{"push_token": token, "push_provider": provider}}
+	}
+
+	result, err := collection().UpdateOne(ctx, bson.M{"_id": id, "user_id": userID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// AttachSession records that sessionID was opened on the device. Sessions of
+// other users are refused with ErrSessionNotOwned.
+func AttachSession(ctx context.Context, userID string, id primitive.ObjectID, sessionID string) error {
+	if err := VerifySessionOwner(ctx, userID, sessionID); err != nil {
+		return err
+	}
+
+	result, err := collection().UpdateOne(ctx,
+		bson.M{"_id": id, "user_id": userID},
+		bson.M{
+			"$push": bson.M{"session_ids": bson.M{"$each": bson.A{sessionID}, "$slice": -MaxSessionsPerDevice}},
+			"$set":  bson.M{"last_seen_at": utils.Now()},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// VerifySessionOwner checks that sessionID was issued to userID, by
+// SessionOwner when set, and that no other user's device holds it
+func VerifySessionOwner(ctx context.Context, userID, sessionID string) error {
+	if SessionOwner != nil {
+		owner, err := SessionOwner(ctx, sessionID)
+		if err != nil {
+			return fmt.Errorf("look up session owner: %w", err)
+		}
+		if owner != userID {
+			return ErrSessionNotOwned
+		}
+	}
+
+	count, err := collection().CountDocuments(ctx,
+		bson.M{"session_ids": sessionID, "user_id": bson.M{"$ne": userID}},
+		options.Count().SetLimit(1),
+	)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrSessionNotOwned
+	}
+	return nil
+}
+
+// DetachSession removes a session of the user, e.g. on logout
+func DetachSession(ctx context.Context, userID, sessionID string) error {
+	_, err := collection().UpdateMany(ctx,
+		bson.M{"user_id": userID, "session_ids": sessionID},
+		bson.M{"$pull": bson.M{"session_ids": sessionID}},
+	)
+	return err
+}
+
+// ForSession returns the user's device a session was opened on
+func ForSession(ctx context.Context, userID, sessionID string) (*models.Device, error) {
+	var device models.Device
+	err := collection().FindOne(ctx, bson.M{"user_id": userID, "session_ids": sessionID}).Decode(&device)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// Delete removes a device of the user, e.g. when they sign out of it remotely
+func Delete(ctx context.Context, userID string, id primitive.ObjectID) error {
+	result, err := collection().DeleteOne(ctx, bson.M{"_id": id, "user_id": userID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,440 @@
+// Package domains lets white-label organizations serve their frontend from
+// their own domains. An admin adds a domain, publishes the TXT record it
+// returns, and a background verifier marks the domain verified once the
+// record resolves:
+//
+//	domain, err := domains.Add(ctx, orgID, "app.customer.com", userID)
+//	// Publish TXT domains.RecordName(domain.Domain) = domains.RecordValue(domain)
+//	stop := domains.StartVerifier(5 * time.Minute)
+//
+// Verified domains map the Host header to their organization with
+// middleware.CustomDomain, and their origins pass CORS through AllowOrigin.
+package domains
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RecordPrefix is the label under which the verification TXT record is published
+const RecordPrefix = "_domain-verification"
+
+// MaxDomainsPerOrganization caps the custom domains of one organization
+const MaxDomainsPerOrganization = 20
+
+// VerificationWindow is how long a domain stays pending before it is marked
+// failed; Verify can still succeed afterwards
+var VerificationWindow = 7 * 24 * time.Hour
+
+// CacheTTL controls how long host lookups are cached in memory
+var CacheTTL = time.Minute
+
+// MaxCachedHosts bounds the host cache, which also holds misses for arbitrary
+// Host headers
+var MaxCachedHosts = 10000
+
+// Domain errors
+var (
+	ErrNotFound      = errors.New("domain not found")
+	ErrInvalidDomain = errors.New("invalid domain")
+	ErrDomainTaken   = errors.New("domain is already registered")
+	ErrTooMany       = errors.New("too many custom domains")
+	ErrNotVerified   = errors.New("verification record not found")
+)
+
+// lookupTXT resolves TXT records
+var lookupTXT = net.DefaultResolver.LookupTXT
+
+func collection() *mongo.Collection {
+	return config.GetCollection(models.CustomDomain{}.CollectionName())
+}
+
+// RecordName is the DNS name of the verification TXT record for domain
+func RecordName(domain string) string {
+	return RecordPrefix + "." + domain
+}
+
+// RecordValue is the content of the verification TXT record
+func RecordValue(domain *models.CustomDomain) string {
+	return "verification=" + domain.VerificationToken
+}
+
+// Normalize lowercases a host name and checks that it can be a custom domain
+func Normalize(domain string) (string, error) {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if domain == "" || len(domain) > 253 || net.ParseIP(domain) != nil {
+		return "", fmt.Errorf("%w: %q", ErrInvalidDomain, domain)
+	}
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return "", fmt.Errorf("%w: %q needs a top-level domain", ErrInvalidDomain, domain)
+	}
+	for _, label := range labels {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return "", fmt.Errorf("%w: %q", ErrInvalidDomain, domain)
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+				return "", fmt.Errorf("%w: %q", ErrInvalidDomain, domain)
+			}
+		}
+	}
+	for _, reserved := range reservedDomains() {
+		if domain == reserved || strings.HasSuffix(domain, "."+reserved) {
+			return "", fmt.Errorf("%w: %q is reserved", ErrInvalidDomain, domain)
+		}
+	}
+	return domain, nil
+}
+
+// reservedDomains are our own domains, from CUSTOM_DOMAIN_RESERVED
+// (comma-separated), which organizations cannot claim
+func reservedDomains() []string {
+	var reserved []string
+	for _, domain := range strings.Split(config.GetEnv("CUSTOM_DOMAIN_RESERVED", ""), ",") {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			reserved = append(reserved, domain)
+		}
+	}
+	return reserved
+}
+
+// Add registers a pending domain for an organization. Several organizations
+// may claim the same domain; the first to publish its record verifies it and
+// the other claims are dropped.
+func Add(ctx context.Context, organizationID, domain, createdBy string) (*models.CustomDomain, error) {
+	domain, err := Normalize(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	err = collection().FindOne(ctx, bson.M{"domain": domain, "status": models.DomainVerified}).Err()
+	if err == nil {
+		return nil, ErrDomainTaken
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	count, err := collection().CountDocuments(ctx, bson.M{"organization_id": organizationID})
+	if err != nil {
+		return nil, err
+	}
+	if count >= MaxDomainsPerOrganization {
+		return nil, fmt.Errorf("%w: at most %d per organization", ErrTooMany, MaxDomainsPerOrganization)
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	now := utils.Now()
+	record := &models.CustomDomain{
+		ID:                primitive.NewObjectID(),
+		OrganizationID:    organizationID,
+		Domain:            domain,
+		Status:            models.DomainPending,
+		VerificationToken: hex.EncodeToString(token),
+		CreatedBy:         createdBy,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	if _, err := collection().InsertOne(ctx, record); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrDomainTaken
+		}
+		return nil, err
+	}
+	return record, nil
+}
+
+// List returns the domains of an organization
+func List(ctx context.Context, organizationID string) ([]models.CustomDomain, error) {
+	cursor, err := collection().Find(ctx, bson.M{"organization_id": organizationID},
+		options.Find().SetSort(bson.D{{Key: "domain", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	list := []models.CustomDomain{}
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Get returns a domain of an organization
+func Get(ctx context.Context, organizationID string, id primitive.ObjectID) (*models.CustomDomain, error) {
+	var domain models.CustomDomain
+	err := collection().FindOne(ctx, bson.M{"_id": id, "organization_id": organizationID}).Decode(&domain)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &domain, nil
+}
+
+// Remove deletes a domain of an organization
+func Remove(ctx context.Context, organizationID string, id primitive.ObjectID) error {
+	domain, err := Get(ctx, organizationID, id)
+	if err != nil {
+		return err
+	}
+	if _, err := collection().DeleteOne(ctx, bson.M{"_id": domain.ID}); err != nil {
+		return err
+	}
+	invalidate(domain.Domain)
+	return nil
+}
+
+// Verify looks up the TXT record of a domain and records the outcome. It
+// returns ErrNotVerified when the record is missing or wrong, and
+// ErrDomainTaken when another organization verified the domain first.
+func Verify(ctx context.Context, domain *models.CustomDomain) error {
+	if domain.Status == models.DomainVerified {
+		return nil
+	}
+
+	checkErr := checkRecord(ctx, domain)
+	now := utils.Now()
+	set := bson.M{"last_checked_at": now, "updated_at": now}
+	switch {
+	case checkErr == nil:
+		set["status"] = models.DomainVerified
+		set["verified_at"] = now
+		set["last_error"] = ""
+	case errors.Is(checkErr, ErrNotVerified):
+		set["last_error"] = checkErr.Error()
+		if now.Sub(domain.CreatedAt) > VerificationWindow {
+			set["status"] = models.DomainFailed
+		}
+	default:
+		// DNS failures are retried on the next run without counting against the domain
+		return checkErr
+	}
+
+	_, err := collection().UpdateOne(ctx, bson.M{"_id": domain.ID}, bson.M{
+		"$set": set,
+		"$inc": bson.M{"checks": 1},
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDomainTaken
+	}
+	if err != nil {
+		return err
+	}
+
+	if checkErr == nil {
+		domain.Status = models.DomainVerified
+		domain.VerifiedAt = &now
+		invalidate(domain.Domain)
+		utils.LogAuditContext(ctx, "system", "custom_domain_verified", domain.ID.Hex(), map[string]interface{}{
+			"organization_id": domain.OrganizationID,
+			"domain":          domain.Domain,
+		})
+		dropCompetingClaims(ctx, domain)
+	}
+	return checkErr
+}
+
+// dropCompetingClaims deletes the claims other organizations hold on a domain
+// that has just been verified; failures are logged, as the claims can no
+// longer verify
+func dropCompetingClaims(ctx context.Context, domain *models.CustomDomain) {
+	result, err := collection().DeleteMany(ctx, bson.M{
+		"domain": domain.Domain,
+		"_id":    bson.M{"$ne": domain.ID},
+		"status": bson.M{"$ne": models.DomainVerified},
+	})
+	if err != nil {
+		utils.LogWarning(fmt.Sprintf("Failed to drop competing claims on domain %s: %v", domain.Domain, err))
+		return
+	}
+	if result.DeletedCount > 0 {
+		utils.LogAuditContext(ctx, "system", "custom_domain_claims_dropped", domain.ID.Hex(), map[string]interface{}{
+			"domain": domain.Domain,
+			"count":  result.DeletedCount,
+		})
+	}
+}
+
+// checkRecord returns nil when the verification record of domain resolves
+func checkRecord(ctx context.Context, domain *models.CustomDomain) error {
+	records, err := lookupTXT(ctx, RecordName(domain.Domain))
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return fmt.Errorf("%w: no TXT record at %s", ErrNotVerified, RecordName(domain.Domain))
+	}
+	if err != nil {
+		return fmt.Errorf("lookup %s: %w", RecordName(domain.Domain), err)
+	}
+
+	want := RecordValue(domain)
+	for _, record := range records {
+		if strings.TrimSpace(record) == want {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s has no %q record", ErrNotVerified, RecordName(domain.Domain), want)
+}
+
+// VerifyPending checks every pending domain and returns how many were verified
+func VerifyPending(ctx context.Context) (int, error) {
+	cursor, err := collection().Find(ctx, bson.M{"status": models.DomainPending},
+		options.Find().SetSort(bson.D{{Key: "last_checked_at", Value: 1}}))
+	if err != nil {
+		return 0, err
+	}
+	var pending []models.CustomDomain
+	if err := cursor.All(ctx, &pending); err != nil {
+		return 0, err
+	}
+
+	verified := 0
+	for i := range pending {
+		err := Verify(ctx, &pending[i])
+		switch {
+		case err == nil:
+			verified++
+		case errors.Is(err, ErrNotVerified):
+		case errors.Is(err, ErrDomainTaken):
+			utils.LogWarning(fmt.Sprintf("Domain %s was verified by another organization first", pending[i].Domain))
+		case ctx.Err() != nil:
+			return verified, ctx.Err()
+		default:
+			utils.LogWarning(fmt.Sprintf("Failed to verify domain %s: %v", pending[i].Domain, err))
+		}
+	}
+	return verified, nil
+}
+
+// StartVerifier runs VerifyPending periodically. Call the returned function
+// to stop the verifier.
+func StartVerifier(interval time.Duration) func() {
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				count, err := VerifyPending(ctx)
+				cancel()
+				if err != nil {
+					utils.LogError(fmt.Sprintf("Custom domain verification failed: %v", err))
+				} else if count > 0 {
+					log.Printf("🌐 Verified %d custom domains", count)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		stopOnce.Do(func() { close(stop) })
+	}
+}
+
+type cachedHost struct {
+	organizationID string // Empty for hosts that are not verified custom domains
+	loadedAt       time.Time
+}
+
+var (
+	hostCache    = map[string]cachedHost{}
+	hostCacheMux sync.RWMutex
+)
+
+// Resolve returns the organization a verified custom domain belongs to. Host
+// may include a port.
+func Resolve(ctx context.Context, host string) (string, bool) {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	// Hosts that can never be custom domains are neither looked up nor cached
+	host, err := Normalize(host)
+	if err != nil {
+		return "", false
+	}
+
+	hostCacheMux.RLock()
+	cached, ok := hostCache[host]
+	hostCacheMux.RUnlock()
+	if ok && time.Since(cached.loadedAt) < CacheTTL {
+		return cached.organizationID, cached.organizationID != ""
+	}
+
+	var domain models.CustomDomain
+	err = collection().FindOne(ctx, bson.M{"domain": host, "status": models.DomainVerified}).Decode(&domain)
+	if err != nil && err != mongo.ErrNoDocuments {
+		// Not cached, so the lookup is retried with the next request
+		utils.LogWarning(fmt.Sprintf("Failed to resolve custom domain %s: %v", host, err))
+		return "", false
+	}
+
+	hostCacheMux.Lock()
+	if len(hostCache) >= MaxCachedHosts {
+		pruneHostCache()
+	}
+	hostCache[host] = cachedHost{organizationID: domain.OrganizationID, loadedAt: time.Now()}
+	hostCacheMux.Unlock()
+	return domain.OrganizationID, domain.OrganizationID != ""
+}
+
+// AllowOrigin reports whether a CORS origin is an https verified custom
+// domain; use it as cors.Config.AllowOriginsFunc
+func AllowOrigin(origin string) bool {
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Scheme != "https" {
+		return false
+	}
+	ctx, cancel := utils.GetContext()
+	defer cancel()
+	_, ok := Resolve(ctx, parsed.Host)
+	return ok
+}
+
+// pruneHostCache drops expired hosts, or every host when none has expired;
+// the caller holds hostCacheMux
+func pruneHostCache() {
+	for host, cached := range hostCache {
+		if time.Since(cached.loadedAt) >= CacheTTL {
+			delete(hostCache, host)
+		}
+	}
+	if len(hostCache) >= MaxCachedHosts {
+		hostCache = map[string]cachedHost{}
+	}
+}
+
+// invalidate drops a cached host after its domain changes
+func invalidate(domain string) {
+	hostCacheMux.Lock()
+	defer hostCacheMux.Unlock()
+	delete(hostCache, domain)
+}
@@ -0,0 +1,109 @@
+// Package entitlements answers what an organization's plan allows, so premium
+// features are gated the same way in every service:
+//
+//	if !entitlements.Has(c.UserContext(), "custom_domains") {
+//		return c.Status(http.StatusPaymentRequired).JSON(...)
+//	}
+//
+// The organization comes from the request context (set by
+// middleware.AuthMiddleware). Entitlements are resolved from the subscription
+// records kept by the billing package and cached for CacheTTL; see
+// middleware.RequirePlan and middleware.RequireFeature to gate whole routes.
+package entitlements
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/billing"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// CacheTTL controls how long an organization's entitlements are cached in memory
+var CacheTTL = time.Minute
+
+type cachedEntitlements struct {
+	entitlements *billing.Entitlements
+	loadedAt     time.Time
+}
+
+var (
+	cache   = map[string]cachedEntitlements{}
+	cacheMu sync.RWMutex
+)
+
+func init() {
+	// The process that receives a subscription change sees it immediately,
+	// others within CacheTTL
+	for _, eventType := range []string{
+		"customer.subscription.created",
+		"customer.subscription.updated",
+		"customer.subscription.deleted",
+	} {
+		billing.OnEvent(eventType, func(ctx context.Context, event billing.Event) error {
+			InvalidateAll()
+			return nil
+		})
+	}
+}
+
+// ForOrganization returns the entitlements of an organization
+func ForOrganization(ctx context.Context, organizationID string) (*billing.Entitlements, error) {
+	cacheMu.RLock()
+	cached, ok := cache[organizationID]
+	cacheMu.RUnlock()
+	if ok && time.Since(cached.loadedAt) < CacheTTL {
+		return cached.entitlements, nil
+	}
+
+	entitlements, err := billing.ResolveEntitlements(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheMu.Lock()
+	cache[organizationID] = cachedEntitlements{entitlements: entitlements, loadedAt: time.Now()}
+	cacheMu.Unlock()
+	return entitlements, nil
+}
+
+// Get returns the entitlements of the organization in ctx
+func Get(ctx context.Context) (*billing.Entitlements, error) {
+	return ForOrganization(ctx, utils.AuditOrganizationFromContext(ctx))
+}
+
+// Has reports whether the plan of the organization in ctx includes a feature.
+// It fails closed: an error resolving the plan is logged and reported as false.
+func Has(ctx context.Context, feature string) bool {
+	entitlements, err := Get(ctx)
+	if err != nil {
+		utils.Log(ctx).Warn("failed to resolve entitlements", "feature", feature, "error", err)
+		return false
+	}
+	return entitlements.Has(feature)
+}
+
+// Limit returns a quota of the plan of the organization in ctx, or
+// billing.Unlimited when the plan does not cap it
+func Limit(ctx context.Context, quota string) (int64, error) {
+	entitlements, err := Get(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return entitlements.Limit(quota), nil
+}
+
+// Invalidate drops an organization's cached entitlements
+func Invalidate(organizationID string) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	delete(cache, organizationID)
+}
+
+// InvalidateAll drops every cached entitlement
+func InvalidateAll() {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	cache = map[string]cachedEntitlements{}
+}
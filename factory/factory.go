@@ -0,0 +1,160 @@
+// Package factory builds model values with defaults for tests and stores them
+// through the repository, deleting them again when the test ends:
+//
+//	func init() {
+//		factory.Define(func(seq int) User {
+//			return User{Email: fmt.Sprintf("user%d@example.com", seq), Role: "viewer"}
+//		})
+//	}
+//
+//	func WithRole(role string) factory.Option[User] {
+//		return func(u *User) { u.Role = role }
+//	}
+//
+//	admin := factory.Create[User](t, WithRole("admin"))
+//
+// The collection comes from the model's CollectionName method (see
+// models.Collection) or DefineIn.
+package factory
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/repo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Option overrides defaults of a built value
+type Option[T any] func(*T)
+
+// definition is the registered factory of one model type
+type definition struct {
+	collection string
+	defaults   interface{} // func(seq int) T
+	sequence   atomic.Int64
+}
+
+var (
+	definitions   = map[reflect.Type]*definition{}
+	definitionsMu sync.RWMutex
+)
+
+// Define registers the defaults of T; seq counts builds from 1 so defaults
+// can be unique, e.g. emails. T must implement models.Collection to be
+// stored with Create.
+func Define[T any](defaults func(seq int) T) {
+	var zero T
+	collection := ""
+	if named, ok := interface{}(zero).(models.Collection); ok {
+		collection = named.CollectionName()
+	}
+	DefineIn(collection, defaults)
+}
+
+// DefineIn registers the defaults of T stored in collection
+func DefineIn[T any](collection string, defaults func(seq int) T) {
+	definitionsMu.Lock()
+	defer definitionsMu.Unlock()
+	definitions[typeOf[T]()] = &definition{collection: collection, defaults: defaults}
+}
+
+// Build returns T with its defaults and opts applied, without storing it
+func Build[T any](opts ...Option[T]) T {
+	def := lookup[T]()
+	value := def.defaults.(func(int) T)(int(def.sequence.Add(1)))
+	for _, opt := range opts {
+		opt(&value)
+	}
+	return value
+}
+
+// BuildMany returns n values built like Build
+func BuildMany[T any](n int, opts ...Option[T]) []T {
+	values := make([]T, n)
+	for i := range values {
+		values[i] = Build(opts...)
+	}
+	return values
+}
+
+// Create builds T, inserts it with the repository (which assigns its ID) and
+// deletes it when the test ends. It needs a database connection.
+func Create[T any](t testing.TB, opts ...Option[T]) *T {
+	t.Helper()
+
+	def := lookup[T]()
+	if def.collection == "" {
+		t.Fatalf("factory: %v has no collection; implement CollectionName or use DefineIn", typeOf[T]())
+	}
+
+	value := Build(opts...)
+	repository := repo.New[T](def.collection)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := repository.Insert(ctx, &value); err != nil {
+		t.Fatalf("factory: create %v: %v", typeOf[T](), err)
+	}
+
+	id, err := storedID(&value)
+	if err != nil {
+		t.Fatalf("factory: create %v: %v", typeOf[T](), err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if _, err := repository.Collection().DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+			t.Logf("factory: clean up %v %v: %v", typeOf[T](), id, err)
+		}
+	})
+	return &value
+}
+
+// CreateMany stores n values created like Create
+func CreateMany[T any](t testing.TB, n int, opts ...Option[T]) []*T {
+	t.Helper()
+	values := make([]*T, n)
+	for i := range values {
+		values[i] = Create(t, opts...)
+	}
+	return values
+}
+
+func lookup[T any]() *definition {
+	definitionsMu.RLock()
+	def, ok := definitions[typeOf[T]()]
+	definitionsMu.RUnlock()
+	if !ok {
+		panic(fmt.Sprintf("factory: no definition for %v; call factory.Define first", typeOf[T]()))
+	}
+	return def
+}
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// storedID reads the _id the repository wrote back into value
+func storedID(value interface{}) (interface{}, error) {
+	raw, err := bson.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var stored struct {
+		ID interface{} `bson:"_id"`
+	}
+	if err := bson.Unmarshal(raw, &stored); err != nil {
+		return nil, err
+	}
+	if stored.ID == nil {
+		return nil, fmt.Errorf("document has no _id")
+	}
+	return stored.ID, nil
+}
@@ -0,0 +1,32 @@
+package factory
+
+import (
+	"fmt"
+
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// Factories for the models owned by the shared libraries
+func init() {
+	Define(func(seq int) models.AuditLog {
+		return models.AuditLog{
+			OrganizationID: "org-1",
+			AdminID:        "admin-1",
+			Action:         "test_action",
+			TargetID:       fmt.Sprintf("target-%d", seq),
+			Timestamp:      utils.Now(),
+		}
+	})
+	Define(func(seq int) models.ActivityItem {
+		return models.ActivityItem{
+			OrganizationID: "org-1",
+			ActorID:        "user-1",
+			Action:         "test_action",
+			TargetID:       fmt.Sprintf("target-%d", seq),
+			Message:        fmt.Sprintf("Test activity %d", seq),
+			ReadBy:         []string{},
+			CreatedAt:      utils.Now(),
+		}
+	})
+}
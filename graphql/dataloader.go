@@ -0,0 +1,169 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/repo"
+)
+
+// BatchFunc fetches many keys at once; keys missing from the result resolve
+// to repo.ErrNotFound
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// LoaderOptions tunes batching
+type LoaderOptions struct {
+	Wait     time.Duration // How long to collect keys before fetching (default 2ms)
+	MaxBatch int           // Fetch as soon as this many keys are queued (default 100)
+}
+
+// Loader batches and caches lookups made while resolving one operation, turning
+// N resolver calls into a single query. Create one per request (see
+// Options.RequestContext) so that results are never shared between callers.
+type Loader[K comparable, V any] struct {
+	fetch   BatchFunc[K, V]
+	options LoaderOptions
+
+	cache map[K]*loaderResult[V]
+	batch *loaderBatch[K, V]
+	mu    sync.Mutex
+}
+
+type loaderResult[V any] struct {
+	value V
+	err   error
+	done  chan struct{}
+}
+
+type loaderBatch[K comparable, V any] struct {
+	keys    []K
+	results []*loaderResult[V]
+	ctx     context.Context
+	started bool
+}
+
+// NewLoader creates a loader around fetch
+func NewLoader[K comparable, V any](fetch BatchFunc[K, V], options LoaderOptions) *Loader[K, V] {
+	if options.Wait <= 0 {
+		options.Wait = 2 * time.Millisecond
+	}
+	if options.MaxBatch <= 0 {
+		options.MaxBatch = 100
+	}
+	return &Loader[K, V]{fetch: fetch, options: options, cache: map[K]*loaderResult[V]{}}
+}
+
+// RepositoryLoader loads documents from a repository by ID
+func RepositoryLoader[T any](repository *repo.Repository[T], options LoaderOptions) *Loader[string, *T] {
+	return NewLoader(func(ctx context.Context, ids []string) (map[string]*T, error) {
+		return repository.FindByIDs(ctx, ids)
+	}, options)
+}
+
+// Load returns the value for key, waiting for the batch it joins
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	result := l.enqueue(ctx, key)
+
+	select {
+	case <-result.done:
+		return result.value, result.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// LoadMany loads keys in one batch, returning values in key order
+func (l *Loader[K, V]) LoadMany(ctx context.Context, keys []K) ([]V, []error) {
+	results := make([]*loaderResult[V], len(keys))
+	for i, key := range keys {
+		results[i] = l.enqueue(ctx, key)
+	}
+
+	values := make([]V, len(keys))
+	errs := make([]error, len(keys))
+	for i, result := range results {
+		select {
+		case <-result.done:
+			values[i], errs[i] = result.value, result.err
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+		}
+	}
+	return values, errs
+}
+
+// Prime caches a value loaded elsewhere, e.g. from a list query
+func (l *Loader[K, V]) Prime(key K, value V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.cache[key]; !ok {
+		result := &loaderResult[V]{value: value, done: make(chan struct{})}
+		close(result.done)
+		l.cache[key] = result
+	}
+}
+
+// enqueue returns the cached result for key or adds key to the pending batch
+func (l *Loader[K, V]) enqueue(ctx context.Context, key K) *loaderResult[V] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if result, ok := l.cache[key]; ok {
+		return result
+	}
+
+	result := &loaderResult[V]{done: make(chan struct{})}
+	l.cache[key] = result
+
+	if l.batch == nil {
+		// The batch outlives the first caller's cancellation, since later callers share it
+		l.batch = &loaderBatch[K, V]{ctx: context.WithoutCancel(ctx)}
+	}
+	batch := l.batch
+	batch.keys = append(batch.keys, key)
+	batch.results = append(batch.results, result)
+
+	switch {
+	case len(batch.keys) >= l.options.MaxBatch:
+		l.batch = nil
+		go l.run(batch)
+	case !batch.started:
+		batch.started = true
+		time.AfterFunc(l.options.Wait, func() {
+			l.mu.Lock()
+			if l.batch != batch {
+				// Already dispatched because it filled up
+				l.mu.Unlock()
+				return
+			}
+			l.batch = nil
+			l.mu.Unlock()
+			l.run(batch)
+		})
+	}
+	return result
+}
+
+func (l *Loader[K, V]) run(batch *loaderBatch[K, V]) {
+	values, err := l.fetch(batch.ctx, batch.keys)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, key := range batch.keys {
+		result := batch.results[i]
+		if err != nil {
+			result.err = err
+			// Failures are not cached so a later Load can retry
+			delete(l.cache, key)
+		} else if value, ok := values[key]; ok {
+			result.value = value
+		} else {
+			result.err = fmt.Errorf("%w: %v", repo.ErrNotFound, key)
+		}
+		close(result.done)
+	}
+}
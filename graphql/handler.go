@@ -0,0 +1,155 @@
+// Package graphql serves gqlgen schemas from Fiber with the shared JWT auth:
+//
+//	graphql.Mount(app, "/graphql", graphql.Options{
+//		Schema: generated.NewExecutableSchema(generated.Config{
+//			Resolvers:  &resolvers.Resolver{},
+//			Directives: generated.DirectiveRoot{Auth: graphql.Auth, HasRole: graphql.HasRole},
+//		}),
+//		RequestContext: func(ctx context.Context) context.Context {
+//			return context.WithValue(ctx, loadersKey, newLoaders())
+//		},
+//	})
+//
+// Declare the directives in the schema with DirectivesSDL.
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	gql "github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/99designs/gqlgen/graphql/playground"
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// DefaultComplexityLimit caps operation complexity when Options.ComplexityLimit is zero
+const DefaultComplexityLimit = 500
+
+// Options configures a GraphQL endpoint
+type Options struct {
+	Schema gql.ExecutableSchema
+
+	// ComplexityLimit rejects operations above this complexity (default
+	// DefaultComplexityLimit, negative disables)
+	ComplexityLimit int
+
+	// RateLimit is the complexity each user (or client IP when anonymous) may
+	// spend per second, with bursts up to RateBurst (at least ComplexityLimit);
+	// zero disables rate limiting
+	RateLimit float64
+	RateBurst int
+
+	Introspection bool // Allow schema introspection queries
+	Playground    bool // Serve GraphiQL at <path>/playground
+
+	// RequestContext adds request-scoped values such as dataloaders
+	RequestContext func(ctx context.Context) context.Context
+}
+
+// Mount serves the schema at path (GET and POST) behind middleware.OptionalAuth,
+// so resolvers see the caller through UserFromContext and the Auth/HasRole
+// directives decide access per field
+func Mount(router fiber.Router, path string, options Options) {
+	h := Handler(options)
+	router.Get(path, middleware.OptionalAuth, h)
+	router.Post(path, middleware.OptionalAuth, h)
+
+	if options.Playground {
+		page := playground.Handler("GraphQL", path)
+		router.Get(path+"/playground", func(c *fiber.Ctx) error {
+			return serveHTTP(c, c.UserContext(), page)
+		})
+	}
+}
+
+// Handler returns a Fiber handler executing operations against options.Schema.
+// It expects the user locals set by middleware.AuthMiddleware or OptionalAuth.
+func Handler(options Options) fiber.Handler {
+	server := handler.New(options.Schema)
+	server.AddTransport(transport.Options{})
+	server.AddTransport(transport.GET{})
+	server.AddTransport(transport.POST{})
+	server.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	server.SetRecoverFunc(func(ctx context.Context, err interface{}) error {
+		log.Printf("❌ GraphQL resolver panic: %v", err)
+		return gqlerror.Errorf("Internal server error")
+	})
+
+	if options.Introspection {
+		server.Use(extension.Introspection{})
+	}
+	if options.ComplexityLimit == 0 {
+		options.ComplexityLimit = DefaultComplexityLimit
+	}
+	if options.ComplexityLimit > 0 {
+		server.Use(extension.FixedComplexityLimit(options.ComplexityLimit))
+	}
+	if options.RateLimit > 0 {
+		server.Use(newRateLimiter(options.RateLimit, options.RateBurst, options.ComplexityLimit))
+	}
+
+	return func(c *fiber.Ctx) error {
+		user := User{}
+		user.ID, _ = c.Locals("user_id").(string)
+		user.OrganizationID, _ = c.Locals("organization_id").(string)
+		user.Role, _ = c.Locals("role").(string)
+
+		ctx := withClientIP(c.UserContext(), middleware.ClientIP(c))
+		if user.ID != "" {
+			ctx = WithUser(ctx, user)
+		}
+		if options.RequestContext != nil {
+			ctx = options.RequestContext(ctx)
+		}
+
+		return serveHTTP(c, ctx, server)
+	}
+}
+
+// serveHTTP runs a net/http handler for a Fiber request with ctx as the request context
+func serveHTTP(c *fiber.Ctx, ctx context.Context, h http.Handler) error {
+	var request http.Request
+	if err := fasthttpadaptor.ConvertRequest(c.Context(), &request, true); err != nil {
+		return fmt.Errorf("convert graphql request: %w", err)
+	}
+
+	writer := &responseWriter{header: http.Header{}, status: http.StatusOK, c: c}
+	h.ServeHTTP(writer, request.WithContext(ctx))
+
+	c.Status(writer.status)
+	for key, values := range writer.header {
+		for _, value := range values {
+			c.Response().Header.Add(key, value)
+		}
+	}
+	return nil
+}
+
+// responseWriter adapts fiber.Ctx to http.ResponseWriter
+type responseWriter struct {
+	header http.Header
+	status int
+	c      *fiber.Ctx
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	return w.c.Write(p)
+}
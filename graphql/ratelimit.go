@@ -0,0 +1,110 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	gql "github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"golang.org/x/time/rate"
+)
+
+// rateLimiter charges each operation its complexity against a per-caller token
+// bucket. It runs after the complexity extension, whose stats it reads; without
+// them every operation costs 1.
+type rateLimiter struct {
+	limit rate.Limit
+	burst int
+	idle  time.Duration
+
+	limiters  map[string]*callerLimiter
+	lastPrune time.Time
+	mu        sync.Mutex
+}
+
+type callerLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+var _ interface {
+	gql.HandlerExtension
+	gql.OperationContextMutator
+} = &rateLimiter{}
+
+// limiterIdleTimeout is how long an unused caller bucket is kept
+const limiterIdleTimeout = 10 * time.Minute
+
+// newRateLimiter creates the limiter; the burst is raised to at least
+// maxCost so that every operation allowed by the complexity limit can run
+func newRateLimiter(perSecond float64, burst, maxCost int) *rateLimiter {
+	if burst <= 0 {
+		burst = int(perSecond)
+	}
+	if burst < maxCost {
+		burst = maxCost
+	}
+	return &rateLimiter{
+		limit:    rate.Limit(perSecond),
+		burst:    burst,
+		idle:     limiterIdleTimeout,
+		limiters: map[string]*callerLimiter{},
+	}
+}
+
+func (r *rateLimiter) ExtensionName() string {
+	return "ComplexityRateLimit"
+}
+
+func (r *rateLimiter) Validate(schema gql.ExecutableSchema) error {
+	return nil
+}
+
+func (r *rateLimiter) MutateOperationContext(ctx context.Context, opCtx *gql.OperationContext) *gqlerror.Error {
+	cost := 1
+	if stats, ok := opCtx.Stats.GetExtension("ComplexityLimit").(*extension.ComplexityStats); ok && stats.Complexity > cost {
+		cost = stats.Complexity
+	}
+
+	if !r.allow(callerKey(ctx), cost) {
+		err := gqlerror.Errorf("Rate limit exceeded, retry later")
+		errcode.Set(err, CodeRateLimited)
+		return err
+	}
+	return nil
+}
+
+func (r *rateLimiter) allow(key string, cost int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(r.lastPrune) > r.idle {
+		for k, caller := range r.limiters {
+			if now.Sub(caller.lastSeen) > r.idle {
+				delete(r.limiters, k)
+			}
+		}
+		r.lastPrune = now
+	}
+
+	caller, ok := r.limiters[key]
+	if !ok {
+		caller = &callerLimiter{limiter: rate.NewLimiter(r.limit, r.burst)}
+		r.limiters[key] = caller
+	}
+	caller.lastSeen = now
+	return caller.limiter.AllowN(now, cost)
+}
+
+// callerKey identifies who is charged: the user, or the client IP when anonymous
+func callerKey(ctx context.Context) string {
+	if user, ok := UserFromContext(ctx); ok {
+		return "user:" + user.ID
+	}
+	ip, _ := ctx.Value(clientIPKey).(string)
+	return "ip:" + ip
+}
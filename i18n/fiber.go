@@ -0,0 +1,75 @@
+package i18n
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// LocaleLocalsKey is the fiber.Ctx locals key holding the resolved locale
+const LocaleLocalsKey = "locale"
+
+// Middleware resolves the request locale and stores it in locals and the user context.
+// A ?locale= query parameter wins, then the authenticated user's preferences (when
+// installed after AuthMiddleware), then Accept-Language.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		locale := MatchLocale(c.Query("locale"))
+
+		if locale == "" {
+			if userID, ok := c.Locals("user_id").(string); ok && userID != "" {
+				locale = MatchLocale(utils.GetUserLocale(userID))
+			}
+		}
+		if locale == "" {
+			locale = ResolveLocale(c.Get(fiber.HeaderAcceptLanguage))
+		}
+
+		c.Locals(LocaleLocalsKey, locale)
+		c.SetUserContext(WithLocale(c.UserContext(), locale))
+		c.Set(fiber.HeaderContentLanguage, locale)
+		return c.Next()
+	}
+}
+
+// Locale returns the locale resolved for the request
+func Locale(c *fiber.Ctx) string {
+	if locale, ok := c.Locals(LocaleLocalsKey).(string); ok {
+		return locale
+	}
+	return LocaleFromContext(c.UserContext())
+}
+
+// Error responds with the standard {"error": "..."} envelope, translating key
+func Error(c *fiber.Ctx, status int, key string, args map[string]interface{}) error {
+	return c.Status(status).JSON(fiber.Map{
+		"error": Translate(Locale(c), key, args),
+		"code":  key,
+	})
+}
+
+// FieldError is a validation failure on one field, identified by a message key
+type FieldError struct {
+	Field string
+	Key   string
+	Args  map[string]interface{}
+}
+
+// ValidationError responds with 400 and translated per-field messages
+func ValidationError(c *fiber.Ctx, errors []FieldError) error {
+	locale := Locale(c)
+
+	fields := make(map[string]string, len(errors))
+	for _, fieldErr := range errors {
+		args := map[string]interface{}{"field": fieldErr.Field}
+		for name, value := range fieldErr.Args {
+			args[name] = value
+		}
+		fields[fieldErr.Field] = Translate(locale, fieldErr.Key, args)
+	}
+
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error":  Translate(locale, "validation.failed", nil),
+		"code":   "validation.failed",
+		"fields": fields,
+	})
+}
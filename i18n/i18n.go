@@ -0,0 +1,205 @@
+// Package i18n provides message catalogs, locale resolution and translated
+// error responses. Catalogs are JSON objects mapping keys to messages; a message
+// may be a string or a plural object with "zero", "one" and "other" forms.
+// Placeholders use the {name} syntax.
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLocale is used when no requested locale is supported
+const DefaultLocale = "en"
+
+//go:embed locales/*.json
+var builtinLocales embed.FS
+
+type localeContextKey struct{}
+
+// message is either a plain string or a set of plural forms
+type message struct {
+	text   string
+	plural map[string]string
+}
+
+func (m *message) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &m.text); err == nil {
+		return nil
+	}
+	return json.Unmarshal(data, &m.plural)
+}
+
+var (
+	catalogs   = map[string]map[string]message{}
+	catalogMux sync.RWMutex
+)
+
+func init() {
+	if err := LoadFS(builtinLocales, "locales"); err != nil {
+		panic(fmt.Sprintf("i18n: failed to load built-in catalogs: %v", err))
+	}
+}
+
+// LoadCatalog merges JSON messages into a locale's catalog; later keys override earlier ones
+func LoadCatalog(locale string, data []byte) error {
+	var messages map[string]message
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return fmt.Errorf("invalid catalog for %s: %w", locale, err)
+	}
+
+	locale = normalizeLocale(locale)
+
+	catalogMux.Lock()
+	defer catalogMux.Unlock()
+
+	catalog, ok := catalogs[locale]
+	if !ok {
+		catalog = map[string]message{}
+		catalogs[locale] = catalog
+	}
+	for key, msg := range messages {
+		catalog[key] = msg
+	}
+	return nil
+}
+
+// LoadFS loads every <locale>.json file in dir, e.g. a service's embedded catalogs
+func LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		if err := LoadCatalog(strings.TrimSuffix(entry.Name(), ".json"), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SupportedLocales returns the locales with a loaded catalog
+func SupportedLocales() []string {
+	catalogMux.RLock()
+	defer catalogMux.RUnlock()
+
+	locales := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// WithLocale returns a copy of ctx carrying locale
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeContextKey{}, normalizeLocale(locale))
+}
+
+// LocaleFromContext returns the locale stored in ctx, or DefaultLocale
+func LocaleFromContext(ctx context.Context) string {
+	if ctx != nil {
+		if locale, ok := ctx.Value(localeContextKey{}).(string); ok && locale != "" {
+			return locale
+		}
+	}
+	return DefaultLocale
+}
+
+// T translates key for the locale in ctx, substituting args into {placeholders}.
+// A "count" arg selects the plural form. Missing keys fall back to DefaultLocale
+// and then to the key itself.
+func T(ctx context.Context, key string, args map[string]interface{}) string {
+	return Translate(LocaleFromContext(ctx), key, args)
+}
+
+// Translate is T with an explicit locale
+func Translate(locale, key string, args map[string]interface{}) string {
+	msg, ok := lookup(normalizeLocale(locale), key)
+	if !ok {
+		msg, ok = lookup(DefaultLocale, key)
+	}
+	if !ok {
+		return key
+	}
+
+	text := msg.text
+	if msg.plural != nil {
+		text = msg.plural[pluralForm(args)]
+		if text == "" {
+			text = msg.plural["other"]
+		}
+	}
+
+	return interpolate(text, args)
+}
+
+// lookup finds a message in a locale, trying the base language for regional locales
+func lookup(locale, key string) (message, bool) {
+	catalogMux.RLock()
+	defer catalogMux.RUnlock()
+
+	if msg, ok := catalogs[locale][key]; ok {
+		return msg, true
+	}
+	if base, _, found := strings.Cut(locale, "-"); found {
+		if msg, ok := catalogs[base][key]; ok {
+			return msg, true
+		}
+	}
+	return message{}, false
+}
+
+// pluralForm applies zero/one/other rules based on the "count" argument
+func pluralForm(args map[string]interface{}) string {
+	count, ok := args["count"]
+	if !ok {
+		return "other"
+	}
+
+	n, err := strconv.ParseFloat(fmt.Sprint(count), 64)
+	if err != nil {
+		return "other"
+	}
+	switch n {
+	case 0:
+		return "zero"
+	case 1:
+		return "one"
+	default:
+		return "other"
+	}
+}
+
+func interpolate(text string, args map[string]interface{}) string {
+	if len(args) == 0 || !strings.Contains(text, "{") {
+		return text
+	}
+
+	replacements := make([]string, 0, len(args)*2)
+	for name, value := range args {
+		replacements = append(replacements, "{"+name+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(replacements...).Replace(text)
+}
+
+// normalizeLocale lowercases locales and uses "-" as the region separator
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
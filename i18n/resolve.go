@@ -0,0 +1,61 @@
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ResolveLocale picks the best supported locale from an Accept-Language header
+func ResolveLocale(acceptLanguage string) string {
+	type candidate struct {
+		locale  string
+		quality float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				quality = parsed
+			}
+		}
+		candidates = append(candidates, candidate{locale: normalizeLocale(tag), quality: quality})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+
+	for _, c := range candidates {
+		if supported := MatchLocale(c.locale); supported != "" {
+			return supported
+		}
+	}
+	return DefaultLocale
+}
+
+// MatchLocale returns the supported locale for a requested one (exact match, then
+// base language), or empty if neither is supported
+func MatchLocale(locale string) string {
+	locale = normalizeLocale(locale)
+
+	catalogMux.RLock()
+	defer catalogMux.RUnlock()
+
+	if _, ok := catalogs[locale]; ok {
+		return locale
+	}
+	if base, _, found := strings.Cut(locale, "-"); found {
+		if _, ok := catalogs[base]; ok {
+			return base
+		}
+	}
+	return ""
+}
@@ -0,0 +1,235 @@
+// Package links creates short tokens that redirect to deep links, such as
+// experience URLs or AR Quick Look files, for QR codes and sharing. Links are
+// stored in Mongo, count their hits and may expire:
+//
+//	link, err := links.Create(ctx, links.Spec{Target: experienceURL, OrganizationID: orgID}, userID)
+//	qr := links.URL(link.Token) // https://short.example.com/l/Xk3P9aZ
+//
+// routes.SetupLinkRoutes serves the redirect at GET /l/:token.
+package links
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/domains"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TokenLength is the length of generated tokens; 62^7 leaves room for billions of links
+const TokenLength = 7
+
+const tokenAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// AllowedSchemes are the target schemes links may redirect to. Add app
+// schemes (e.g. "myapp") to deep link into native apps. Web targets must also
+// be on an allowed host, see allowedHost.
+var AllowedSchemes = []string{"https", "http"}
+
+// Link errors
+var (
+	ErrNotFound      = errors.New("link not found")
+	ErrExpired       = errors.New("link expired")
+	ErrInvalidTarget = errors.New("invalid link target")
+)
+
+// Spec describes a link to create
+type Spec struct {
+	Target         string
+	OrganizationID string
+	TTL            time.Duration // 0 means the link does not expire
+}
+
+// Create stores a link to spec.Target under a new random token
+func Create(ctx context.Context, spec Spec, createdBy string) (*models.ShortLink, error) {
+	if err := validateTarget(ctx, spec.Target, spec.OrganizationID); err != nil {
+		return nil, err
+	}
+
+	now := utils.Now()
+	link := models.ShortLink{
+		Target:         spec.Target,
+		OrganizationID: spec.OrganizationID,
+		CreatedBy:      createdBy,
+		CreatedAt:      now,
+	}
+	if spec.TTL > 0 {
+		expiresAt := now.Add(spec.TTL)
+		link.ExpiresAt = &expiresAt
+	}
+
+	collection := config.GetCollection(models.ShortLink{}.CollectionName())
+	for attempt := 0; attempt < 5; attempt++ {
+		token, err := generateToken()
+		if err != nil {
+			return nil, err
+		}
+
+		link.ID = primitive.NewObjectID()
+		link.Token = token
+		_, err = collection.InsertOne(ctx, link)
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &link, nil
+	}
+	return nil, fmt.Errorf("failed to generate a unique link token")
+}
+
+// Resolve returns the link for token and counts the hit
+func Resolve(ctx context.Context, token string) (*models.ShortLink, error) {
+	now := utils.Now()
+
+	var link models.ShortLink
+	err := config.GetCollection(models.ShortLink{}.CollectionName()).FindOneAndUpdate(ctx,
+		bson.M{"token": token, "$or": bson.A{
+			bson.M{"expires_at": bson.M{"$exists": false}},
+			bson.M{"expires_at": bson.M{"$gt": now}},
+		}},
+		bson.M{"$inc": bson.M{"hits": 1}, "$set": bson.M{"last_hit_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&link)
+	if err == mongo.ErrNoDocuments {
+		// Tell an expired link from one that never existed
+		if existing, getErr := Get(ctx, token, ""); getErr == nil && existing.ExpiresAt != nil {
+			return nil, ErrExpired
+		}
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Links created before targets were restricted are checked on use
+	if err := validateTarget(ctx, link.Target, link.OrganizationID); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// Get returns a link without counting a hit; a non-empty organizationID
+// limits the lookup to that organization's links
+func Get(ctx context.Context, token, organizationID string) (*models.ShortLink, error) {
+	filter := bson.M{"token": token}
+	if organizationID != "" {
+		filter["organization_id"] = organizationID
+	}
+
+	var link models.ShortLink
+	err := config.GetCollection(models.ShortLink{}.CollectionName()).FindOne(ctx, filter).Decode(&link)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// Delete removes a link of an organization
+func Delete(ctx context.Context, token, organizationID string) error {
+	result, err := config.GetCollection(models.ShortLink{}.CollectionName()).
+		DeleteOne(ctx, bson.M{"token": token, "organization_id": organizationID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// URL returns the public short URL of a token, based on SHORT_LINK_BASE_URL
+// (default FRONTEND_URL)
+func URL(token string) string {
+	base := config.GetEnv("SHORT_LINK_BASE_URL", config.GetEnv("FRONTEND_URL", ""))
+	return strings.TrimRight(base, "/") + "/l/" + token
+}
+
+// validateTarget accepts absolute URLs with an allowed scheme; web URLs must
+// also point to an allowed host, so links cannot redirect to arbitrary sites
+func validateTarget(ctx context.Context, target, organizationID string) error {
+	parsed, err := url.Parse(target)
+	if err != nil || parsed.Scheme == "" {
+		return fmt.Errorf("%w: %q is not an absolute URL", ErrInvalidTarget, target)
+	}
+	for _, scheme := range AllowedSchemes {
+		if !strings.EqualFold(parsed.Scheme, scheme) {
+			continue
+		}
+		if scheme != "http" && scheme != "https" {
+			return nil
+		}
+		if parsed.Host == "" {
+			return fmt.Errorf("%w: %q has no host", ErrInvalidTarget, target)
+		}
+		if !allowedHost(ctx, parsed.Hostname(), organizationID) {
+			return fmt.Errorf("%w: host %q is not allowed", ErrInvalidTarget, parsed.Hostname())
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: scheme %q is not allowed", ErrInvalidTarget, parsed.Scheme)
+}
+
+// allowedHost reports whether web links may point to host: the hosts of
+// FRONTEND_URL and SHORT_LINK_BASE_URL, those listed in
+// SHORT_LINK_ALLOWED_HOSTS ("*.example.com" admits subdomains), and the
+// verified custom domains of the link's organization
+func allowedHost(ctx context.Context, host, organizationID string) bool {
+	host = strings.ToLower(host)
+	allowed := strings.Split(config.GetEnv("SHORT_LINK_ALLOWED_HOSTS", ""), ",")
+	for _, key := range []string{"FRONTEND_URL", "SHORT_LINK_BASE_URL"} {
+		if parsed, err := url.Parse(config.GetEnv(key, "")); err == nil {
+			allowed = append(allowed, parsed.Hostname())
+		}
+	}
+	for _, entry := range allowed {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if suffix, ok := strings.CutPrefix(entry, "*"); ok {
+			if strings.HasPrefix(suffix, ".") && strings.HasSuffix(host, suffix) {
+				return true
+			}
+			continue
+		}
+		if host == entry {
+			return true
+		}
+	}
+
+	if organizationID == "" {
+		return false
+	}
+	owner, ok := domains.Resolve(ctx, host)
+	return ok && owner == organizationID
+}
+
+// generateToken returns a random token of TokenLength characters
+func generateToken() (string, error) {
+	max := big.NewInt(int64(len(tokenAlphabet)))
+	token := make([]byte, TokenLength)
+	for i := range token {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		token[i] = tokenAlphabet[n.Int64()]
+	}
+	return string(token), nil
+}
@@ -0,0 +1,334 @@
+// Package logins compares each sign-in with the user's history and flags
+// sign-ins from a new device or country. Flagged sign-ins alert the user by
+// email and push, and can require step-up MFA before the session is granted:
+//
+//	assessment, err := logins.Assess(ctx, logins.Attempt{
+//		UserID: user.ID, Email: user.Email, IP: middleware.ClientIP(c),
+//		Location: middleware.GetGeoLocation(c), Fingerprint: fingerprint,
+//	})
+//	if err == nil && assessment.StepUpRequired {
+//		return requireMFA(c, user) // Issue tokens only after the second factor
+//	}
+//
+//	// Once the second factor is verified
+//	err = logins.CompleteStepUp(ctx, user.ID, assessment.EventID)
+package logins
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/devices"
+	"github.com/praleedsuvarna/shared-libs/messaging"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SuspiciousSubject is published for flagged sign-ins with a SuspiciousLogin
+// payload; push delivery services send it to the user's devices
+const SuspiciousSubject = "security.login.suspicious"
+
+// Attempt is the context of a successful password or OAuth sign-in
+type Attempt struct {
+	UserID         string
+	OrganizationID string
+	Email          string // Alert recipient; empty skips the email
+	IP             string
+	Location       *utils.GeoLocation // From middleware.GeoIP; nil when unknown
+	Fingerprint    string             // devices.Fingerprint of the signing-in device; empty when unknown
+}
+
+// Assessment is the outcome of comparing an attempt with the history
+type Assessment struct {
+	EventID        string   `json:"event_id"`          // Pass to CompleteStepUp once step-up MFA succeeds
+	Reasons        []string `json:"reasons,omitempty"` // models.LoginNewDevice, models.LoginNewCountry
+	StepUpRequired bool     `json:"step_up_required"`
+	FirstLogin     bool     `json:"first_login"` // No history to compare with; never flagged
+}
+
+// Suspicious reports whether the sign-in was flagged
+func (a *Assessment) Suspicious() bool {
+	return len(a.Reasons) > 0
+}
+
+// SuspiciousLogin is the payload published on SuspiciousSubject
+type SuspiciousLogin struct {
+	UserID     string            `json:"user_id"`
+	Reasons    []string          `json:"reasons"`
+	IP         string            `json:"ip,omitempty"`
+	Country    string            `json:"country,omitempty"`
+	City       string            `json:"city,omitempty"`
+	PushTokens []PushDestination `json:"push_tokens,omitempty"`
+	At         time.Time         `json:"at"`
+}
+
+// PushDestination is one device a push alert is sent to
+type PushDestination struct {
+	Token    string `json:"token"`
+	Provider string `json:"provider"`
+}
+
+// Policy decides which reasons require step-up MFA. The defaults come from
+// LOGIN_STEP_UP_NEW_DEVICE and LOGIN_STEP_UP_NEW_COUNTRY ("true" to enable).
+type Policy struct {
+	StepUpOnNewDevice  bool
+	StepUpOnNewCountry bool
+}
+
+// PolicyFromEnv reads the step-up policy from the environment
+func PolicyFromEnv() Policy {
+	return Policy{
+		StepUpOnNewDevice:  config.GetEnv("LOGIN_STEP_UP_NEW_DEVICE", "") == "true",
+		StepUpOnNewCountry: config.GetEnv("LOGIN_STEP_UP_NEW_COUNTRY", "") == "true",
+	}
+}
+
+func init() {
+	utils.RegisterEmailTemplate(utils.EmailTemplate{
+		Name:    "suspicious_login",
+		Subject: "New sign-in to your account",
+		HTML: `
+        <h1>New sign-in detected</h1>
+        <p>Your account was signed in to from {{if .NewDevice}}a device you have not used before{{else}}a new location{{end}}{{if .Location}} in {{.Location}}{{end}}.</p>
+        <p>IP address: {{.IP}}<br>Time: {{.At.Format "Jan 2, 2006 15:04 MST"}}</p>
+        <p>If this was you, no action is needed. Otherwise change your password and sign out of your other devices.</p>
+    `,
+		SampleData: map[string]interface{}{
+			"NewDevice": true,
+			"Location":  "Berlin, Germany",
+			"IP":        "203.0.113.7",
+			"At":        time.Now(),
+		},
+	})
+}
+
+func collection() *mongo.Collection {
+	return config.GetCollection(models.LoginEvent{}.CollectionName())
+}
+
+// Assess checks attempt against the user's history using PolicyFromEnv,
+// records it, and alerts the user when it is suspicious
+func Assess(ctx context.Context, attempt Attempt) (*Assessment, error) {
+	return AssessWithPolicy(ctx, attempt, PolicyFromEnv())
+}
+
+// AssessWithPolicy is Assess with an explicit step-up policy
+func AssessWithPolicy(ctx context.Context, attempt Attempt, policy Policy) (*Assessment, error) {
+	assessment, err := evaluate(ctx, attempt)
+	if err != nil {
+		return nil, err
+	}
+	for _, reason := range assessment.Reasons {
+		if (reason == models.LoginNewDevice && policy.StepUpOnNewDevice) ||
+			(reason == models.LoginNewCountry && policy.StepUpOnNewCountry) {
+			assessment.StepUpRequired = true
+		}
+	}
+
+	event := models.LoginEvent{
+		ID:             primitive.NewObjectID(),
+		UserID:         attempt.UserID,
+		OrganizationID: attempt.OrganizationID,
+		IP:             attempt.IP,
+		Fingerprint:    attempt.Fingerprint,
+		Reasons:        assessment.Reasons,
+		StepUpRequired: assessment.StepUpRequired,
+		Granted:        !assessment.StepUpRequired,
+		CreatedAt:      utils.Now(),
+	}
+	if attempt.Location != nil {
+		event.Country = attempt.Location.Country
+		event.City = attempt.Location.City
+	}
+	if attempt.Fingerprint != "" {
+		if device, err := devices.ByFingerprint(ctx, attempt.UserID, attempt.Fingerprint); err == nil {
+			event.DeviceID = &device.ID
+		}
+	}
+	if _, err := collection().InsertOne(ctx, event); err != nil {
+		return nil, fmt.Errorf("record login: %w", err)
+	}
+	assessment.EventID = event.ID.Hex()
+
+	if assessment.Suspicious() {
+		utils.LogAuditContext(ctx, attempt.UserID, "suspicious_login", event.ID.Hex(), map[string]interface{}{
+			"reasons":          assessment.Reasons,
+			"ip":               attempt.IP,
+			"country":          event.Country,
+			"step_up_required": assessment.StepUpRequired,
+		})
+		alert(ctx, attempt, event)
+	}
+	return assessment, nil
+}
+
+// CompleteStepUp marks a sign-in that required step-up MFA as granted, once
+// the second factor is verified. Until then its device and country do not
+// count as known, so retrying a sign-in does not skip step-up.
+func CompleteStepUp(ctx context.Context, userID, eventID string) error {
+	id, err := primitive.ObjectIDFromHex(eventID)
+	if err != nil {
+		return fmt.Errorf("invalid login event ID: %w", err)
+	}
+	result, err := collection().UpdateOne(ctx, bson.M{"_id": id, "user_id": userID}, bson.M{"$set": bson.M{"granted": true}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// grantedHistory limits a history query to granted sign-ins; events stored
+// before step-up tracking have no granted field and count
+func grantedHistory(filter bson.M) bson.M {
+	filter["granted"] = bson.M{"$ne": false}
+	return filter
+}
+
+// evaluate compares the attempt with earlier granted logins and known devices
+func evaluate(ctx context.Context, attempt Attempt) (*Assessment, error) {
+	assessment := &Assessment{}
+
+	previous, err := collection().CountDocuments(ctx, grantedHistory(bson.M{"user_id": attempt.UserID}), options.Count().SetLimit(1))
+	if err != nil {
+		return nil, fmt.Errorf("load login history: %w", err)
+	}
+	if previous == 0 {
+		assessment.FirstLogin = true
+		return assessment, nil
+	}
+
+	// A device that does not identify itself is treated as a new one
+	known := false
+	if attempt.Fingerprint != "" {
+		if known, err = knownFingerprint(ctx, attempt.UserID, attempt.Fingerprint); err != nil {
+			return nil, err
+		}
+	}
+	if !known {
+		assessment.Reasons = append(assessment.Reasons, models.LoginNewDevice)
+	}
+
+	if attempt.Location != nil && attempt.Location.Country != "" {
+		seen, err := collection().CountDocuments(ctx,
+			grantedHistory(bson.M{"user_id": attempt.UserID, "country": attempt.Location.Country}),
+			options.Count().SetLimit(1),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("load login countries: %w", err)
+		}
+		if seen == 0 {
+			assessment.Reasons = append(assessment.Reasons, models.LoginNewCountry)
+		}
+	}
+	return assessment, nil
+}
+
+// knownFingerprint reports whether the device is registered or was used to
+// sign in before. Devices register after sign-in, so the history also counts.
+func knownFingerprint(ctx context.Context, userID, fingerprint string) (bool, error) {
+	known, err := devices.IsKnown(ctx, userID, fingerprint)
+	if err != nil || known {
+		return known, err
+	}
+	count, err := collection().CountDocuments(ctx,
+		grantedHistory(bson.M{"user_id": userID, "fingerprint": fingerprint}),
+		options.Count().SetLimit(1),
+	)
+	return count > 0, err
+}
+
+// alert emails the user and publishes a push alert; failures are logged, as
+// the sign-in itself has succeeded
+func alert(ctx context.Context, attempt Attempt, event models.LoginEvent) {
+	location := strings.Trim(strings.Join([]string{event.City, countryName(attempt.Location)}, ", "), ", ")
+	data := map[string]interface{}{
+		"NewDevice": slices.Contains(event.Reasons, models.LoginNewDevice),
+		"Location":  location,
+		"IP":        event.IP,
+		"At":        event.CreatedAt,
+	}
+	err := utils.NotifyUser(utils.Notification{
+		UserID:         attempt.UserID,
+		Email:          attempt.Email,
+		Category:       "security",
+		Template:       "suspicious_login",
+		Data:           data,
+		OrganizationID: attempt.OrganizationID,
+	})
+	if err != nil {
+		utils.Log(ctx).Warn("failed to email suspicious login alert", "user_id", attempt.UserID, "error", err)
+	}
+
+	if messaging.Default() == nil || !utils.WantsNotification(attempt.UserID, utils.ChannelPush, "security") {
+		return
+	}
+	targets, err := devices.PushTargets(ctx, attempt.UserID)
+	if err != nil {
+		utils.Log(ctx).Warn("failed to load push targets", "user_id", attempt.UserID, "error", err)
+		return
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	payload := SuspiciousLogin{
+		UserID:  attempt.UserID,
+		Reasons: event.Reasons,
+		IP:      event.IP,
+		Country: event.Country,
+		City:    event.City,
+		At:      event.CreatedAt,
+	}
+	for _, target := range targets {
+		if event.DeviceID != nil && target.ID == *event.DeviceID {
+			continue // Not the device that just signed in
+		}
+		payload.PushTokens = append(payload.PushTokens, PushDestination{Token: target.PushToken, Provider: target.PushProvider})
+	}
+	if len(payload.PushTokens) == 0 {
+		return
+	}
+	if err := messaging.Publish(ctx, SuspiciousSubject, payload); err != nil {
+		utils.Log(ctx).Warn("failed to publish suspicious login alert", "user_id", attempt.UserID, "error", err)
+	}
+}
+
+// History returns the user's most recent logins
+func History(ctx context.Context, userID string, limit int64) ([]models.LoginEvent, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	cursor, err := collection().Find(ctx, bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	events := []models.LoginEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func countryName(location *utils.GeoLocation) string {
+	if location == nil {
+		return ""
+	}
+	if location.CountryName != "" {
+		return location.CountryName
+	}
+	return location.Country
+}
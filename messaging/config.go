@@ -0,0 +1,231 @@
+package messaging
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+)
+
+// NewDriverFromConfig creates the driver named by MESSAGING_DRIVER ("nats", the
+// default, "pubsub", "kafka" or "rabbitmq"). NATS uses the config.NATS
+// connection; Pub/Sub reads PUBSUB_PROJECT (or GOOGLE_CLOUD_PROJECT),
+// PUBSUB_TOPIC_PREFIX and the "pubsub-credentials" secret (PUBSUB_CREDENTIALS),
+// falling back to Application Default Credentials; Kafka and RabbitMQ read the
+// KAFKA_* and RABBITMQ_* variables, see kafkaOptionsFromConfig and
+// rabbitMQOptionsFromConfig.
+func NewDriverFromConfig(ctx context.Context) (Driver, error) {
+	switch name := config.GetEnv("MESSAGING_DRIVER", "nats"); name {
+	case "nats":
+		if config.NATS == nil {
+			return nil, fmt.Errorf("nats driver: %w", config.ErrNotConnected)
+		}
+		return NewNATSDriver(config.NATS), nil
+	case "pubsub":
+		options := PubSubOptions{
+			ProjectID:   config.GetEnv("PUBSUB_PROJECT", config.GetEnv("GOOGLE_CLOUD_PROJECT", "")),
+			TopicPrefix: config.GetEnv("PUBSUB_TOPIC_PREFIX", ""),
+		}
+		credentials, err := config.GetSecret("pubsub-credentials", "PUBSUB_CREDENTIALS")
+		switch {
+		case err == nil:
+			options.CredentialsJSON = []byte(credentials)
+		case errors.Is(err, config.ErrSecretNotFound):
+			log.Println("🔐 No Pub/Sub credentials configured, using Application Default Credentials")
+		default:
+			return nil, err
+		}
+		return NewPubSubDriver(ctx, options)
+	case "kafka":
+		options, err := kafkaOptionsFromConfig()
+		if err != nil {
+			return nil, err
+		}
+		return NewKafkaDriver(options)
+	case "rabbitmq":
+		options, err := rabbitMQOptionsFromConfig()
+		if err != nil {
+			return nil, err
+		}
+		return NewRabbitMQDriver(options)
+	default:
+		return nil, fmt.Errorf("unknown MESSAGING_DRIVER %q", name)
+	}
+}
+
+// NewFromConfig creates a bus on the configured driver; MESSAGING_DLQ_SUFFIX
+// overrides the dead letter suffix. Dead letters are stored with
+// StoreDeadLetter when MongoDB is connected.
+func NewFromConfig(ctx context.Context, source string) (*Bus, error) {
+	driver, err := NewDriverFromConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	protection, err := ProtectionFromConfig()
+	if err != nil {
+		return nil, err
+	}
+	options := Options{
+		Source:           source,
+		DeadLetterSuffix: config.GetEnv("MESSAGING_DLQ_SUFFIX", ""),
+		Protection:       protection,
+	}
+	// Keep dead letters for inspection and replay when a database is connected
+	if config.DB != nil {
+		options.OnDeadLetter = StoreDeadLetter
+	}
+	return New(driver, options), nil
+}
+
+// ProtectionFromConfig builds message protection from the secret provider, or
+// returns nil when neither encryption nor signing is configured. Key sets are
+// comma separated "id:base64key" pairs:
+//
+//   - "messaging-encryption-keys" (MESSAGING_ENCRYPTION_KEYS): AES keys; new
+//     payloads are encrypted with MESSAGING_ENCRYPTION_KEY_ID
+//   - "messaging-signing-keys" (MESSAGING_SIGNING_KEYS): HMAC secrets, or
+//     Ed25519 private keys (or seeds) when MESSAGING_SIGNING_ALG=ed25519; new
+//     envelopes are signed with MESSAGING_SIGNING_KEY_ID
+//   - "messaging-verify-keys" (MESSAGING_VERIFY_KEYS): Ed25519 public keys of
+//     other publishers
+//
+// MESSAGING_REQUIRE_ENCRYPTION and MESSAGING_REQUIRE_SIGNATURE reject
+// unprotected envelopes.
+func ProtectionFromConfig() (*Protection, error) {
+	encryptionKeys, err := config.GetKeySet("messaging-encryption-keys", "MESSAGING_ENCRYPTION_KEYS")
+	if err != nil {
+		return nil, err
+	}
+	signingKeys, err := config.GetKeySet("messaging-signing-keys", "MESSAGING_SIGNING_KEYS")
+	if err != nil {
+		return nil, err
+	}
+	verifyKeys, err := config.GetKeySet("messaging-verify-keys", "MESSAGING_VERIFY_KEYS")
+	if err != nil {
+		return nil, err
+	}
+
+	protection := &Protection{
+		EncryptionKeyID:   config.GetEnv("MESSAGING_ENCRYPTION_KEY_ID", ""),
+		EncryptionKeys:    encryptionKeys,
+		Verifiers:         map[string]Verifier{},
+		RequireEncryption: config.GetEnv("MESSAGING_REQUIRE_ENCRYPTION", "") == "true",
+		RequireSignature:  config.GetEnv("MESSAGING_REQUIRE_SIGNATURE", "") == "true",
+	}
+	if protection.EncryptionKeyID != "" {
+		if _, ok := encryptionKeys[protection.EncryptionKeyID]; !ok {
+			return nil, fmt.Errorf("%w: MESSAGING_ENCRYPTION_KEY_ID %q", ErrUnknownKey, protection.EncryptionKeyID)
+		}
+	}
+
+	switch algorithm := config.GetEnv("MESSAGING_SIGNING_ALG", "hmac"); algorithm {
+	case "hmac":
+		for id, secret := range signingKeys {
+			protection.Verifiers[id] = NewHMACKey(id, secret)
+		}
+	case "ed25519":
+		for id, key := range signingKeys {
+			var signer *Ed25519Signer
+			switch len(key) {
+			case ed25519.SeedSize:
+				signer = NewEd25519Signer(id, ed25519.NewKeyFromSeed(key))
+			case ed25519.PrivateKeySize:
+				signer = NewEd25519Signer(id, ed25519.PrivateKey(key))
+			default:
+				return nil, fmt.Errorf("ed25519 signing key %q has invalid length %d", id, len(key))
+			}
+			protection.Verifiers[id] = signer.Verifier()
+			if id == config.GetEnv("MESSAGING_SIGNING_KEY_ID", "") {
+				protection.Signer = signer
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown MESSAGING_SIGNING_ALG %q", algorithm)
+	}
+	for id, key := range verifyKeys {
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("ed25519 verify key %q has invalid length %d", id, len(key))
+		}
+		protection.Verifiers[id] = Ed25519Verifier(key)
+	}
+
+	if keyID := config.GetEnv("MESSAGING_SIGNING_KEY_ID", ""); keyID != "" && protection.Signer == nil {
+		verifier, ok := protection.Verifiers[keyID].(*HMACKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: MESSAGING_SIGNING_KEY_ID %q", ErrUnknownKey, keyID)
+		}
+		protection.Signer = verifier
+	}
+
+	if protection.EncryptionKeyID == "" && protection.Signer == nil && len(encryptionKeys) == 0 &&
+		len(protection.Verifiers) == 0 && !protection.RequireEncryption && !protection.RequireSignature {
+		return nil, nil
+	}
+	return protection, nil
+}
+
+// kafkaOptionsFromConfig reads KAFKA_BROKERS (comma separated), KAFKA_CLIENT_ID,
+// KAFKA_TOPIC_PREFIX, KAFKA_TLS, KAFKA_SASL_MECHANISM, KAFKA_USERNAME and the
+// "kafka-password" secret (KAFKA_PASSWORD)
+func kafkaOptionsFromConfig() (KafkaOptions, error) {
+	options := KafkaOptions{
+		ClientID:      config.GetEnv("KAFKA_CLIENT_ID", ""),
+		TopicPrefix:   config.GetEnv("KAFKA_TOPIC_PREFIX", ""),
+		TLS:           config.GetEnv("KAFKA_TLS", "") == "true",
+		SASLMechanism: config.GetEnv("KAFKA_SASL_MECHANISM", ""),
+		Username:      config.GetEnv("KAFKA_USERNAME", ""),
+	}
+	for _, broker := range strings.Split(config.GetEnv("KAFKA_BROKERS", ""), ",") {
+		if broker = strings.TrimSpace(broker); broker != "" {
+			options.Brokers = append(options.Brokers, broker)
+		}
+	}
+
+	if options.SASLMechanism != "" {
+		password, err := config.GetSecret("kafka-password", "KAFKA_PASSWORD")
+		if err != nil {
+			return options, fmt.Errorf("kafka password: %w", err)
+		}
+		options.Password = password
+	}
+	return options, nil
+}
+
+// rabbitMQOptionsFromConfig reads the "rabbitmq-url" secret (RABBITMQ_URL),
+// RABBITMQ_EXCHANGE, RABBITMQ_QUEUE_PREFIX, RABBITMQ_PREFETCH,
+// RABBITMQ_CONCURRENCY and RABBITMQ_RETRY_DELAY
+func rabbitMQOptionsFromConfig() (RabbitMQOptions, error) {
+	options := RabbitMQOptions{
+		Exchange:    config.GetEnv("RABBITMQ_EXCHANGE", ""),
+		QueuePrefix: config.GetEnv("RABBITMQ_QUEUE_PREFIX", ""),
+	}
+
+	url, err := config.GetSecret("rabbitmq-url", "RABBITMQ_URL")
+	if err != nil {
+		return options, fmt.Errorf("rabbitmq url: %w", err)
+	}
+	options.URL = url
+
+	if value := config.GetEnv("RABBITMQ_PREFETCH", ""); value != "" {
+		if options.Prefetch, err = strconv.Atoi(value); err != nil {
+			return options, fmt.Errorf("invalid RABBITMQ_PREFETCH %q: %w", value, err)
+		}
+	}
+	if value := config.GetEnv("RABBITMQ_CONCURRENCY", ""); value != "" {
+		if options.Concurrency, err = strconv.Atoi(value); err != nil {
+			return options, fmt.Errorf("invalid RABBITMQ_CONCURRENCY %q: %w", value, err)
+		}
+	}
+	if value := config.GetEnv("RABBITMQ_RETRY_DELAY", ""); value != "" {
+		if options.RetryDelay, err = time.ParseDuration(value); err != nil {
+			return options, fmt.Errorf("invalid RABBITMQ_RETRY_DELAY %q: %w", value, err)
+		}
+	}
+	return options, nil
+}
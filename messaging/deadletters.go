@@ -0,0 +1,68 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// Header set on replayed envelopes
+const HeaderReplayedFrom = "x-replayed-from"
+
+// StoreDeadLetter is an Options.OnDeadLetter hook that records dead letters in
+// Mongo (utils.DeadLettersCollection) for the dead letter admin endpoints
+func StoreDeadLetter(ctx context.Context, deadLetter *Envelope, reason error) {
+	data, err := json.Marshal(deadLetter)
+	if err != nil {
+		log.Printf("❌ Failed to encode dead letter %s: %v", deadLetter.ID, err)
+		return
+	}
+
+	err = utils.SaveDeadLetter(ctx, models.DeadLetter{
+		EnvelopeID:        deadLetter.ID,
+		Subject:           deadLetter.Headers[HeaderOriginalSubject],
+		DeadLetterSubject: deadLetter.Subject,
+		Source:            deadLetter.Source,
+		Reason:            reason.Error(),
+		Attempts:          deadLetter.Attempt,
+		Envelope:          data,
+	})
+	if err != nil {
+		log.Printf("❌ Failed to store dead letter %s: %v", deadLetter.ID, err)
+	}
+}
+
+// Replay republishes a stored dead letter on its original subject. The envelope
+// keeps its ID, payload and protection headers, so consumers can deduplicate
+// and signatures still verify; dead letter headers are removed and the
+// attempt count is reset.
+func (b *Bus) Replay(ctx context.Context, deadLetter models.DeadLetter) error {
+	var envelope Envelope
+	if err := json.Unmarshal(deadLetter.Envelope, &envelope); err != nil {
+		return fmt.Errorf("decode dead letter %s: %w", deadLetter.ID.Hex(), err)
+	}
+
+	subject := envelope.Headers[HeaderOriginalSubject]
+	if subject == "" {
+		subject = deadLetter.Subject
+	}
+	if subject == "" {
+		return fmt.Errorf("dead letter %s has no original subject", deadLetter.ID.Hex())
+	}
+
+	envelope.Subject = subject
+	envelope.Attempt = 0
+	delete(envelope.Headers, HeaderOriginalSubject)
+	delete(envelope.Headers, HeaderDeadLetterReason)
+	delete(envelope.Headers, HeaderDeadLetteredAt)
+	if envelope.Headers == nil {
+		envelope.Headers = map[string]string{}
+	}
+	envelope.Headers[HeaderReplayedFrom] = deadLetter.ID.Hex()
+
+	return b.PublishEnvelope(ctx, &envelope)
+}
@@ -0,0 +1,253 @@
+package messaging
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+// HeaderKafkaKey, when set on a message, becomes its Kafka record key so that
+// related messages land on the same partition and keep their order
+const HeaderKafkaKey = "x-kafka-key"
+
+// KafkaOptions configures a KafkaDriver
+type KafkaOptions struct {
+	Brokers     []string
+	ClientID    string
+	TopicPrefix string // Prepended to topic names, e.g. "prod."
+	TLS         bool
+
+	// SASLMechanism is "plain", "scram-sha-256" or "scram-sha-512"; empty disables SASL
+	SASLMechanism string
+	Username      string
+	Password      string
+
+	RetryBackoff time.Duration // Wait before redelivering a failed record (default 5s)
+}
+
+// KafkaDriver maps subjects to Kafka topics and groups to consumer groups.
+// Records are handled one at a time per subscription and their offsets are
+// committed only after the handler succeeds; a failed record is retried in
+// place, holding back the records behind it so that ordering is preserved.
+// Subscribing without a group reads new records on every subscriber without
+// committing offsets.
+type KafkaDriver struct {
+	options  KafkaOptions
+	producer *kgo.Client
+}
+
+var invalidKafkaTopicChars = regexp.MustCompile(`[^a-zA-Z0-9._\-]`)
+
+// NewKafkaDriver creates the producer client; brokers are contacted lazily
+func NewKafkaDriver(options KafkaOptions) (*KafkaDriver, error) {
+	if len(options.Brokers) == 0 {
+		return nil, fmt.Errorf("kafka brokers are required")
+	}
+	if options.RetryBackoff <= 0 {
+		options.RetryBackoff = 5 * time.Second
+	}
+
+	driver := &KafkaDriver{options: options}
+	clientOptions, err := driver.clientOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	producer, err := kgo.NewClient(append(clientOptions, kgo.AllowAutoTopicCreation())...)
+	if err != nil {
+		return nil, fmt.Errorf("create kafka producer: %w", err)
+	}
+	driver.producer = producer
+	return driver, nil
+}
+
+// Name returns "kafka"
+func (d *KafkaDriver) Name() string {
+	return "kafka"
+}
+
+// Publish produces msg and waits for the brokers to acknowledge it
+func (d *KafkaDriver) Publish(ctx context.Context, msg RawMessage) error {
+	topic, err := d.topic(msg.Subject)
+	if err != nil {
+		return err
+	}
+
+	record := &kgo.Record{Topic: topic, Value: msg.Data}
+	for key, value := range msg.Headers {
+		if key == HeaderKafkaKey {
+			record.Key = []byte(value)
+		}
+		record.Headers = append(record.Headers, kgo.RecordHeader{Key: key, Value: []byte(value)})
+	}
+
+	if err := d.producer.ProduceSync(ctx, record).FirstErr(); err != nil {
+		return fmt.Errorf("produce to %s: %w", topic, err)
+	}
+	return nil
+}
+
+// Subscribe starts a consumer for subject in consumer group group
+func (d *KafkaDriver) Subscribe(ctx context.Context, subject, group string, handler RawHandler) (Subscription, error) {
+	topic, err := d.topic(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	clientOptions, err := d.clientOptions()
+	if err != nil {
+		return nil, err
+	}
+	clientOptions = append(clientOptions, kgo.ConsumeTopics(topic))
+	if group != "" {
+		clientOptions = append(clientOptions,
+			kgo.ConsumerGroup(group),
+			kgo.AutoCommitMarks(),
+			kgo.OnPartitionsRevoked(func(ctx context.Context, client *kgo.Client, _ map[string][]int32) {
+				if err := client.CommitMarkedOffsets(ctx); err != nil {
+					log.Printf("⚠️  Failed to commit Kafka offsets for group %s: %v", group, err)
+				}
+			}),
+		)
+	} else {
+		clientOptions = append(clientOptions, kgo.ConsumeResetOffset(kgo.NewOffset().AtEnd()))
+	}
+
+	consumer, err := kgo.NewClient(clientOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("create kafka consumer for %s: %w", topic, err)
+	}
+
+	consumeCtx, cancel := context.WithCancel(ctx)
+	sub := &kafkaSubscription{client: consumer, grouped: group != "", cancel: cancel, done: make(chan struct{})}
+	go d.consume(consumeCtx, consumer, subject, group != "", handler, sub.done)
+	return sub, nil
+}
+
+func (d *KafkaDriver) consume(ctx context.Context, client *kgo.Client, subject string, commit bool, handler RawHandler, done chan struct{}) {
+	defer close(done)
+
+	for {
+		fetches := client.PollFetches(ctx)
+		if fetches.IsClientClosed() || ctx.Err() != nil {
+			return
+		}
+		fetches.EachError(func(topic string, partition int32, err error) {
+			log.Printf("⚠️  Kafka fetch from %s[%d] failed: %v", topic, partition, err)
+		})
+
+		iter := fetches.RecordIter()
+		for !iter.Done() {
+			record := iter.Next()
+			if !d.handle(ctx, subject, record, handler) {
+				return
+			}
+			if commit {
+				client.MarkCommitRecords(record)
+			}
+		}
+
+		if commit {
+			if err := client.CommitMarkedOffsets(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("⚠️  Failed to commit Kafka offsets for %s: %v", subject, err)
+			}
+		}
+	}
+}
+
+// handle runs handler until it succeeds; it returns false when ctx ends first
+func (d *KafkaDriver) handle(ctx context.Context, subject string, record *kgo.Record, handler RawHandler) bool {
+	msg := RawMessage{Subject: subject, Data: record.Value, Headers: map[string]string{}}
+	for _, header := range record.Headers {
+		msg.Headers[header.Key] = string(header.Value)
+	}
+
+	for {
+		err := handler(ctx, msg)
+		if err == nil {
+			return true
+		}
+		log.Printf("❌ Kafka record %s[%d]@%d failed, retrying in %s: %v", record.Topic, record.Partition, record.Offset, d.options.RetryBackoff, err)
+
+		select {
+		case <-time.After(d.options.RetryBackoff):
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// Close flushes and closes the producer
+func (d *KafkaDriver) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := d.producer.Flush(ctx)
+	d.producer.Close()
+	return err
+}
+
+func (d *KafkaDriver) topic(subject string) (string, error) {
+	if strings.ContainsAny(subject, "*>") {
+		return "", fmt.Errorf("kafka does not support wildcard subject %q", subject)
+	}
+	return d.options.TopicPrefix + invalidKafkaTopicChars.ReplaceAllString(subject, "-"), nil
+}
+
+func (d *KafkaDriver) clientOptions() ([]kgo.Opt, error) {
+	options := []kgo.Opt{kgo.SeedBrokers(d.options.Brokers...)}
+	if d.options.ClientID != "" {
+		options = append(options, kgo.ClientID(d.options.ClientID))
+	}
+	if d.options.TLS {
+		options = append(options, kgo.DialTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12}))
+	}
+
+	var mechanism sasl.Mechanism
+	switch d.options.SASLMechanism {
+	case "":
+	case "plain":
+		mechanism = plain.Auth{User: d.options.Username, Pass: d.options.Password}.AsMechanism()
+	case "scram-sha-256":
+		mechanism = scram.Auth{User: d.options.Username, Pass: d.options.Password}.AsSha256Mechanism()
+	case "scram-sha-512":
+		mechanism = scram.Auth{User: d.options.Username, Pass: d.options.Password}.AsSha512Mechanism()
+	default:
+		return nil, fmt.Errorf("unsupported kafka SASL mechanism %q", d.options.SASLMechanism)
+	}
+	if mechanism != nil {
+		options = append(options, kgo.SASL(mechanism))
+	}
+	return options, nil
+}
+
+type kafkaSubscription struct {
+	client  *kgo.Client
+	grouped bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// Unsubscribe stops consuming, commits handled offsets and leaves the group
+func (s *kafkaSubscription) Unsubscribe() error {
+	s.cancel()
+	<-s.done
+
+	var err error
+	if s.grouped {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err = s.client.CommitMarkedOffsets(ctx)
+		cancel()
+	}
+	s.client.Close()
+	return err
+}
@@ -0,0 +1,46 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// ErrLogControlUnsigned is returned by SubscribeLogControl on buses that do
+// not verify signatures
+var ErrLogControlUnsigned = errors.New("log control requires a bus that verifies message signatures")
+
+// PublishLogControl sends control to every instance subscribed with
+// SubscribeLogControl
+func PublishLogControl(ctx context.Context, control utils.LogControl) error {
+	return Publish(ctx, utils.LogControlSubject, control)
+}
+
+// SubscribeLogControl applies utils.LogControl messages on this instance.
+// Every instance subscribes, so one message reaches them all. Log settings
+// can expose sensitive data, so only envelopes signed with a key the bus
+// verifies are applied.
+func SubscribeLogControl(service string) (Subscription, error) {
+	bus := Default()
+	if bus == nil {
+		return nil, ErrNotInitialized
+	}
+	if bus.options.Protection == nil || len(bus.options.Protection.Verifiers) == 0 {
+		return nil, ErrLogControlUnsigned
+	}
+
+	return bus.Subscribe(utils.LogControlSubject, "", Typed(
+		func(_ context.Context, control utils.LogControl, envelope *Envelope) error {
+			// Open has verified the signature, when there is one
+			if envelope.Headers[HeaderSignature] == "" {
+				log.Printf("⚠️  Ignored unsigned log control %s", envelope.ID)
+				return nil
+			}
+			if _, err := utils.ApplyLogControl(control, service); err != nil {
+				log.Printf("⚠️  Failed to apply log control %s: %v", envelope.ID, err)
+			}
+			return nil
+		}))
+}
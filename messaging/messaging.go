@@ -0,0 +1,350 @@
+// Package messaging publishes and consumes events over a pluggable broker
+// (NATS, Google Cloud Pub/Sub, Kafka, RabbitMQ). Every message travels in the
+// same JSON Envelope, and retries and dead-lettering are handled here rather
+// than by the broker, so handlers behave identically whichever driver is
+// configured.
+//
+//	bus := messaging.New(messaging.NewNATSDriver(config.NATS), messaging.Options{Source: "user-service"})
+//	bus.Publish(ctx, "user.created", user)
+//	bus.Subscribe("user.created", "mailer", messaging.Typed(func(ctx context.Context, user User, env *messaging.Envelope) error {
+//		return sendWelcome(ctx, user)
+//	}))
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// Headers set on dead-lettered messages
+const (
+	HeaderOriginalSubject  = "x-original-subject"
+	HeaderDeadLetterReason = "x-dead-letter-reason"
+	HeaderDeadLetteredAt   = "x-dead-lettered-at"
+)
+
+// DefaultDeadLetterSuffix is appended to a subject to name its dead letter subject
+const DefaultDeadLetterSuffix = ".dlq"
+
+// ErrNotInitialized is returned by the package-level functions before Init
+var ErrNotInitialized = errors.New("messaging not initialized")
+
+// Envelope wraps every payload with routing and tracing metadata
+type Envelope struct {
+	ID        string            `json:"id"`
+	Subject   string            `json:"subject"`
+	Source    string            `json:"source,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+	Attempt   int               `json:"attempt,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Data      json.RawMessage   `json:"data"`
+}
+
+// Decode unmarshals the payload into v
+func (e *Envelope) Decode(v interface{}) error {
+	return json.Unmarshal(e.Data, v)
+}
+
+// RawMessage is what drivers move across the broker
+type RawMessage struct {
+	Subject string
+	Data    []byte
+	Headers map[string]string
+}
+
+// RawHandler processes a delivery; drivers acknowledge it when nil is returned
+// and arrange redelivery (where the broker supports it) otherwise
+type RawHandler func(ctx context.Context, msg RawMessage) error
+
+// Driver adapts a broker to the messaging API
+type Driver interface {
+	Name() string
+	Publish(ctx context.Context, msg RawMessage) error
+	// Subscribe delivers messages on subject; deliveries are load balanced
+	// between subscribers sharing the same non-empty group
+	Subscribe(ctx context.Context, subject, group string, handler RawHandler) (Subscription, error)
+	Close() error
+}
+
+// Subscription is an active subscription
+type Subscription interface {
+	Unsubscribe() error
+}
+
+// Handler processes a decoded envelope
+type Handler func(ctx context.Context, envelope *Envelope) error
+
+// Typed adapts a handler for a concrete payload type
+func Typed[T any](fn func(ctx context.Context, payload T, envelope *Envelope) error) Handler {
+	return func(ctx context.Context, envelope *Envelope) error {
+		var payload T
+		if err := envelope.Decode(&payload); err != nil {
+			return utils.PermanentError(fmt.Errorf("decode %s payload: %w", envelope.Subject, err))
+		}
+		return fn(ctx, payload, envelope)
+	}
+}
+
+// Options configures a Bus
+type Options struct {
+	Source           string             // Service name recorded in envelopes
+	PublishRetry     *utils.RetryPolicy // Default utils.DefaultRetryPolicy
+	HandlerRetry     *utils.RetryPolicy // Default 5 attempts starting at 500ms
+	DeadLetterSuffix string             // Default ".dlq"; "-" disables dead-lettering
+
+	// Protection encrypts and signs published envelopes and verifies received
+	// ones; nil sends plaintext, unsigned envelopes
+	Protection *Protection
+
+	// OnDeadLetter is called after an envelope has been dead-lettered, e.g.
+	// StoreDeadLetter to keep it for inspection and replay
+	OnDeadLetter func(ctx context.Context, deadLetter *Envelope, reason error)
+}
+
+// Bus publishes envelopes and runs handlers with retry and dead-lettering
+type Bus struct {
+	driver  Driver
+	options Options
+}
+
+// New creates a bus on driver
+func New(driver Driver, options Options) *Bus {
+	if options.PublishRetry == nil {
+		policy := utils.DefaultRetryPolicy
+		options.PublishRetry = &policy
+	}
+	if options.HandlerRetry == nil {
+		options.HandlerRetry = &utils.RetryPolicy{
+			MaxAttempts:    5,
+			InitialBackoff: 500 * time.Millisecond,
+			MaxBackoff:     30 * time.Second,
+			Jitter:         0.2,
+		}
+	}
+	if options.DeadLetterSuffix == "" {
+		options.DeadLetterSuffix = DefaultDeadLetterSuffix
+	}
+	return &Bus{driver: driver, options: options}
+}
+
+// Driver returns the underlying driver
+func (b *Bus) Driver() Driver {
+	return b.driver
+}
+
+// PublishOption customizes a published envelope
+type PublishOption func(*Envelope)
+
+// WithHeader sets an envelope header
+func WithHeader(key, value string) PublishOption {
+	return func(e *Envelope) {
+		if e.Headers == nil {
+			e.Headers = map[string]string{}
+		}
+		e.Headers[key] = value
+	}
+}
+
+// WithID sets the envelope ID, e.g. to make a publish idempotent for consumers
+func WithID(id string) PublishOption {
+	return func(e *Envelope) { e.ID = id }
+}
+
+// Publish wraps payload in an Envelope and publishes it on subject
+func (b *Bus) Publish(ctx context.Context, subject string, payload interface{}, opts ...PublishOption) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal %s payload: %w", subject, err)
+	}
+
+	envelope := &Envelope{
+		ID:        utils.NewID(),
+		Subject:   subject,
+		Source:    b.options.Source,
+		Timestamp: utils.Now(),
+		Data:      data,
+	}
+	for _, opt := range opts {
+		opt(envelope)
+	}
+	if b.options.Protection != nil {
+		if err := b.options.Protection.Seal(envelope); err != nil {
+			return fmt.Errorf("protect %s: %w", subject, err)
+		}
+	}
+
+	return b.PublishEnvelope(ctx, envelope)
+}
+
+// PublishEnvelope publishes a prepared envelope as is, e.g. when replaying a
+// dead letter; it is not encrypted or signed again
+func (b *Bus) PublishEnvelope(ctx context.Context, envelope *Envelope) error {
+	msg, err := encodeEnvelope(envelope)
+	if err != nil {
+		return err
+	}
+
+	err = utils.Retry(ctx, *b.options.PublishRetry, func() error {
+		return b.driver.Publish(ctx, msg)
+	})
+	if err != nil {
+		return fmt.Errorf("publish %s via %s: %w", envelope.Subject, b.driver.Name(), err)
+	}
+	return nil
+}
+
+// Subscribe runs handler for each message on subject. Failed handlers are
+// retried with backoff; once retries are exhausted, or on a permanent error,
+// the envelope is published to the subject's dead letter subject and the
+// delivery is acknowledged. With Protection, envelopes that fail verification
+// are dead-lettered without reaching handler, and dead letters keep their
+// encrypted form.
+func (b *Bus) Subscribe(subject, group string, handler Handler) (Subscription, error) {
+	return b.driver.Subscribe(context.Background(), subject, group, func(ctx context.Context, msg RawMessage) error {
+		envelope, err := decodeEnvelope(msg)
+		if err != nil {
+			return b.deadLetter(ctx, &Envelope{ID: utils.NewID(), Subject: msg.Subject, Data: msg.Data, Headers: msg.Headers, Timestamp: utils.Now()}, err)
+		}
+
+		opened := envelope
+		if b.options.Protection != nil {
+			err = checkSubject(envelope, msg.Subject, b.options.DeadLetterSuffix)
+			if err == nil {
+				opened, err = b.options.Protection.Open(envelope)
+			}
+			if err != nil {
+				log.Printf("❌ Rejected envelope %s on %s: %v", envelope.ID, msg.Subject, err)
+				return b.deadLetter(ctx, envelope, err)
+			}
+		}
+
+		err = utils.Retry(ctx, *b.options.HandlerRetry, func() error {
+			opened.Attempt++
+			return handler(ctx, opened)
+		})
+		if err == nil {
+			return nil
+		}
+
+		log.Printf("❌ Handler for %s failed after %d attempts: %v", msg.Subject, opened.Attempt, err)
+		envelope.Attempt = opened.Attempt
+		return b.deadLetter(ctx, envelope, err)
+	})
+}
+
+// deadLetter moves an envelope to its dead letter subject; a failure is returned
+// so the driver can redeliver rather than lose the message
+func (b *Bus) deadLetter(ctx context.Context, envelope *Envelope, reason error) error {
+	if b.options.DeadLetterSuffix == "-" {
+		return nil
+	}
+
+	dead := *envelope
+	dead.Headers = map[string]string{}
+	for key, value := range envelope.Headers {
+		dead.Headers[key] = value
+	}
+	dead.Headers[HeaderOriginalSubject] = envelope.Subject
+	dead.Headers[HeaderDeadLetterReason] = reason.Error()
+	dead.Headers[HeaderDeadLetteredAt] = utils.FormatTime(utils.Now())
+	dead.Subject = DeadLetterSubject(envelope.Subject, b.options.DeadLetterSuffix)
+
+	if err := b.PublishEnvelope(ctx, &dead); err != nil {
+		return err
+	}
+	if b.options.OnDeadLetter != nil {
+		b.options.OnDeadLetter(ctx, &dead, reason)
+	}
+	return nil
+}
+
+// DeadLetterSubject returns the dead letter subject for subject
+func DeadLetterSubject(subject, suffix string) string {
+	if suffix == "" {
+		suffix = DefaultDeadLetterSuffix
+	}
+	return subject + suffix
+}
+
+// Close closes the driver
+func (b *Bus) Close() error {
+	return b.driver.Close()
+}
+
+// encodeEnvelope turns an envelope into a driver message; headers are carried
+// both in the envelope and as broker headers so they can be routed on
+func encodeEnvelope(envelope *Envelope) (RawMessage, error) {
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return RawMessage{}, fmt.Errorf("marshal envelope: %w", err)
+	}
+	return RawMessage{Subject: envelope.Subject, Data: data, Headers: envelope.Headers}, nil
+}
+
+func decodeEnvelope(msg RawMessage) (*Envelope, error) {
+	var envelope Envelope
+	if err := json.Unmarshal(msg.Data, &envelope); err != nil {
+		return nil, fmt.Errorf("decode envelope on %s: %w", msg.Subject, err)
+	}
+	if envelope.Subject == "" {
+		envelope.Subject = msg.Subject
+	}
+	return &envelope, nil
+}
+
+var (
+	defaultBus *Bus
+	defaultMu  sync.RWMutex
+)
+
+// Init sets the package-level bus used by Publish and Subscribe
+func Init(bus *Bus) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultBus = bus
+}
+
+// Default returns the package-level bus, or nil before Init
+func Default() *Bus {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultBus
+}
+
+// Publish publishes on the package-level bus
+func Publish(ctx context.Context, subject string, payload interface{}, opts ...PublishOption) error {
+	bus := Default()
+	if bus == nil {
+		return ErrNotInitialized
+	}
+	return bus.Publish(ctx, subject, payload, opts...)
+}
+
+// Subscribe subscribes on the package-level bus
+func Subscribe(subject, group string, handler Handler) (Subscription, error) {
+	bus := Default()
+	if bus == nil {
+		return nil, ErrNotInitialized
+	}
+	return bus.Subscribe(subject, group, handler)
+}
+
+// Shutdown closes the package-level bus
+func Shutdown() error {
+	defaultMu.Lock()
+	bus := defaultBus
+	defaultBus = nil
+	defaultMu.Unlock()
+
+	if bus == nil {
+		return nil
+	}
+	return bus.Close()
+}
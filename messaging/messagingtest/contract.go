@@ -0,0 +1,289 @@
+package messagingtest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/praleedsuvarna/shared-libs/messaging"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// UpdateEnv names the environment variable that makes AssertGolden and
+// SaveEvents rewrite their files instead of comparing against them
+const UpdateEnv = "UPDATE_GOLDEN"
+
+// ignoredValue replaces fields excluded from golden comparisons
+const ignoredValue = "<ignored>"
+
+var (
+	compiledSchemas   = map[string]*jsonschema.Schema{}
+	compiledSchemasMu sync.Mutex
+)
+
+// updating reports whether golden files should be rewritten
+func updating() bool {
+	value := os.Getenv(UpdateEnv)
+	return value != "" && value != "0" && value != "false"
+}
+
+// AssertSchema fails the test when payload does not match the JSON Schema
+// stored at schemaPath (draft 2020-12 unless it declares another $schema).
+// payload may be raw JSON ([]byte or json.RawMessage), an envelope, whose
+// Data is checked, or any value to marshal.
+func AssertSchema(t testing.TB, schemaPath string, payload interface{}) {
+	t.Helper()
+
+	schema, err := loadSchema(schemaPath)
+	if err != nil {
+		t.Fatalf("messagingtest: %v", err)
+	}
+	data, err := payloadJSON(payload)
+	if err != nil {
+		t.Fatalf("messagingtest: %v", err)
+	}
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("messagingtest: parse payload: %v", err)
+	}
+	if err := schema.Validate(instance); err != nil {
+		t.Errorf("messagingtest: payload does not match %s:\n%v\npayload: %s", schemaPath, err, data)
+	}
+}
+
+func loadSchema(path string) (*jsonschema.Schema, error) {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	compiledSchemasMu.Lock()
+	defer compiledSchemasMu.Unlock()
+	if schema, ok := compiledSchemas[absolute]; ok {
+		return schema, nil
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.DefaultDraft(jsonschema.Draft2020)
+	compiler.AssertFormat()
+	schema, err := compiler.Compile(absolute)
+	if err != nil {
+		return nil, fmt.Errorf("compile schema %s: %w", path, err)
+	}
+	compiledSchemas[absolute] = schema
+	return schema, nil
+}
+
+// AssertGolden fails the test when payload differs from the JSON stored at
+// goldenPath. Keys are compared regardless of order; ignore lists fields
+// whose values change between runs, such as IDs and timestamps, as dotted
+// paths ("id", "user.created_at"). With UPDATE_GOLDEN=1 the file is written.
+func AssertGolden(t testing.TB, goldenPath string, payload interface{}, ignore ...string) {
+	t.Helper()
+
+	data, err := payloadJSON(payload)
+	if err != nil {
+		t.Fatalf("messagingtest: %v", err)
+	}
+	actual, err := normalizeJSON(data, ignore)
+	if err != nil {
+		t.Fatalf("messagingtest: %v", err)
+	}
+
+	if updating() {
+		if err := writeFile(goldenPath, actual); err != nil {
+			t.Fatalf("messagingtest: %v", err)
+		}
+		return
+	}
+
+	stored, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("messagingtest: read golden file (run with %s=1 to create it): %v", UpdateEnv, err)
+	}
+	expected, err := normalizeJSON(stored, ignore)
+	if err != nil {
+		t.Fatalf("messagingtest: golden file %s: %v", goldenPath, err)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("messagingtest: payload differs from %s (run with %s=1 to update)\nwant:\n%s\ngot:\n%s",
+			goldenPath, UpdateEnv, expected, actual)
+	}
+}
+
+// payloadJSON marshals payload unless it already is JSON
+func payloadJSON(payload interface{}) ([]byte, error) {
+	switch p := payload.(type) {
+	case json.RawMessage:
+		return p, nil
+	case []byte:
+		return p, nil
+	case *messaging.Envelope:
+		return p.Data, nil
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal payload: %w", err)
+	}
+	return data, nil
+}
+
+// normalizeJSON re-encodes data indented with sorted keys and ignored fields
+// masked, so equivalent documents compare equal byte for byte
+func normalizeJSON(data []byte, ignore []string) ([]byte, error) {
+	var document interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("parse JSON: %w", err)
+	}
+	for _, path := range ignore {
+		maskField(document, strings.Split(path, "."))
+	}
+
+	var normalized bytes.Buffer
+	encoder := json.NewEncoder(&normalized)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return nil, err
+	}
+	return normalized.Bytes(), nil
+}
+
+// maskField replaces the value at path, in every element when it crosses an array
+func maskField(document interface{}, path []string) {
+	switch value := document.(type) {
+	case map[string]interface{}:
+		child, ok := value[path[0]]
+		if !ok {
+			return
+		}
+		if len(path) == 1 {
+			value[path[0]] = ignoredValue
+			return
+		}
+		maskField(child, path[1:])
+	case []interface{}:
+		for _, element := range value {
+			maskField(element, path)
+		}
+	}
+}
+
+// SaveEvents stores the envelopes published on subject at path, one JSON
+// envelope per line, for consumers to replay with Replay or Deliver. Like
+// golden files it is only written with UPDATE_GOLDEN=1; otherwise the test
+// fails when the file is missing.
+func (r *Recorder) SaveEvents(t testing.TB, subject, path string) {
+	t.Helper()
+
+	if !updating() {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("messagingtest: events file (run with %s=1 to create it): %v", UpdateEnv, err)
+		}
+		return
+	}
+
+	envelopes := r.Envelopes(subject)
+	if len(envelopes) == 0 {
+		t.Fatalf("messagingtest: nothing published on %s", subject)
+	}
+	if err := WriteEvents(path, envelopes); err != nil {
+		t.Fatalf("messagingtest: %v", err)
+	}
+}
+
+// WriteEvents stores envelopes at path, one JSON envelope per line
+func WriteEvents(path string, envelopes []*messaging.Envelope) error {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	for _, envelope := range envelopes {
+		if err := encoder.Encode(envelope); err != nil {
+			return fmt.Errorf("encode envelope %s: %w", envelope.ID, err)
+		}
+	}
+	return writeFile(path, buffer.Bytes())
+}
+
+// ReadEvents loads envelopes stored by WriteEvents; blank lines are skipped
+func ReadEvents(path string) ([]*messaging.Envelope, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var envelopes []*messaging.Envelope
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var envelope messaging.Envelope
+		if err := json.Unmarshal(text, &envelope); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		envelopes = append(envelopes, &envelope)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return envelopes, nil
+}
+
+// Replay publishes the envelopes stored at path on bus as they were
+// recorded, so subscribed handlers run with the bus's retries and
+// dead-lettering
+func Replay(t testing.TB, bus *messaging.Bus, path string) {
+	t.Helper()
+
+	envelopes, err := ReadEvents(path)
+	if err != nil {
+		t.Fatalf("messagingtest: %v", err)
+	}
+	for _, envelope := range envelopes {
+		if err := bus.PublishEnvelope(context.Background(), envelope); err != nil {
+			t.Fatalf("messagingtest: replay %s: %v", envelope.ID, err)
+		}
+	}
+}
+
+// Deliver calls handler with each envelope stored at path, without retries,
+// and fails the test for every envelope it rejects
+func Deliver(t testing.TB, path string, handler messaging.Handler) {
+	t.Helper()
+
+	envelopes, err := ReadEvents(path)
+	if err != nil {
+		t.Fatalf("messagingtest: %v", err)
+	}
+	if len(envelopes) == 0 {
+		t.Fatalf("messagingtest: no events in %s", path)
+	}
+	for _, envelope := range envelopes {
+		envelope.Attempt = 1
+		if err := handler(context.Background(), envelope); err != nil {
+			t.Errorf("messagingtest: handler rejected %s event %s: %v", envelope.Subject, envelope.ID, err)
+		}
+	}
+}
+
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+// Package messagingtest verifies event contracts between services in their own
+// tests. A Recorder stands in for the broker: it keeps every published message
+// and delivers it synchronously to in-process subscribers.
+//
+// Producers check what they publish against a stored JSON schema or golden file:
+//
+//	bus, recorder := messagingtest.NewBus(t, messaging.Options{Source: "user-service"})
+//	createUser(ctx, bus, user)
+//	event := recorder.Last(t, "user.created")
+//	messagingtest.AssertSchema(t, "testdata/contracts/user.created.schema.json", event.Data)
+//	messagingtest.AssertGolden(t, "testdata/contracts/user.created.json", event.Data, "id", "created_at")
+//
+// and consumers replay recorded events into their handlers:
+//
+//	messagingtest.Deliver(t, "testdata/contracts/user.created.events.jsonl", handleUserCreated)
+//
+// Run the tests with UPDATE_GOLDEN=1 to rewrite golden files.
+package messagingtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/messaging"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// ErrClosed is returned by a closed Recorder
+var ErrClosed = errors.New("recorder closed")
+
+// Recorder is an in-memory messaging.Driver that records published messages.
+// Subscribers run synchronously inside Publish; of several subscribers sharing
+// a group, only the first receives each message.
+type Recorder struct {
+	mu          sync.Mutex
+	messages    []messaging.RawMessage
+	subscribers []*recorderSubscription
+	closed      bool
+}
+
+type recorderSubscription struct {
+	recorder *Recorder
+	subject  string
+	group    string
+	handler  messaging.RawHandler
+}
+
+// NewRecorder creates an empty Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Name identifies the driver in errors
+func (r *Recorder) Name() string {
+	return "recorder"
+}
+
+// Publish records msg and hands it to the subscribers of its subject, returning
+// the first handler error
+func (r *Recorder) Publish(ctx context.Context, msg messaging.RawMessage) error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return ErrClosed
+	}
+	r.messages = append(r.messages, msg)
+
+	var handlers []messaging.RawHandler
+	groups := map[string]bool{}
+	for _, sub := range r.subscribers {
+		if sub.subject != msg.Subject {
+			continue
+		}
+		if sub.group != "" {
+			if groups[sub.group] {
+				continue
+			}
+			groups[sub.group] = true
+		}
+		handlers = append(handlers, sub.handler)
+	}
+	r.mu.Unlock()
+
+	// Handlers may publish, e.g. dead letters, so run them without the lock
+	var firstErr error
+	for _, handler := range handlers {
+		if err := handler(ctx, msg); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Subscribe registers handler for messages published on subject from now on
+func (r *Recorder) Subscribe(ctx context.Context, subject, group string, handler messaging.RawHandler) (messaging.Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil, ErrClosed
+	}
+
+	sub := &recorderSubscription{recorder: r, subject: subject, group: group, handler: handler}
+	r.subscribers = append(r.subscribers, sub)
+	return sub, nil
+}
+
+func (s *recorderSubscription) Unsubscribe() error {
+	r := s.recorder
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, sub := range r.subscribers {
+		if sub == s {
+			r.subscribers = append(r.subscribers[:i], r.subscribers[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Close drops the subscriptions; recorded messages stay readable
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	r.subscribers = nil
+	return nil
+}
+
+// Messages returns every message published so far, in order
+func (r *Recorder) Messages() []messaging.RawMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]messaging.RawMessage(nil), r.messages...)
+}
+
+// Envelopes returns the envelopes published on subject, in order. Messages
+// that are not envelopes are skipped.
+func (r *Recorder) Envelopes(subject string) []*messaging.Envelope {
+	var envelopes []*messaging.Envelope
+	for _, msg := range r.Messages() {
+		if msg.Subject != subject {
+			continue
+		}
+		var envelope messaging.Envelope
+		if err := json.Unmarshal(msg.Data, &envelope); err != nil {
+			continue
+		}
+		envelopes = append(envelopes, &envelope)
+	}
+	return envelopes
+}
+
+// Last returns the latest envelope published on subject, failing the test when
+// there is none
+func (r *Recorder) Last(t testing.TB, subject string) *messaging.Envelope {
+	t.Helper()
+	envelopes := r.Envelopes(subject)
+	if len(envelopes) == 0 {
+		t.Fatalf("messagingtest: nothing published on %s", subject)
+	}
+	return envelopes[len(envelopes)-1]
+}
+
+// Reset forgets the recorded messages
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = nil
+}
+
+// NewBus creates a bus on a new Recorder, closed when the test ends. Unless
+// options set them, publishes are not retried and handlers get 3 attempts a
+// millisecond apart, so dead-lettering can be tested without waiting.
+func NewBus(t testing.TB, options messaging.Options) (*messaging.Bus, *Recorder) {
+	t.Helper()
+	if options.PublishRetry == nil {
+		options.PublishRetry = &utils.RetryPolicy{MaxAttempts: 1}
+	}
+	if options.HandlerRetry == nil {
+		options.HandlerRetry = &utils.RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     time.Millisecond,
+		}
+	}
+
+	recorder := NewRecorder()
+	bus := messaging.New(recorder, options)
+	t.Cleanup(func() { bus.Close() })
+	return bus, recorder
+}
+
+// Install makes a bus from NewBus the package-level bus for the rest of the
+// test, for code that calls messaging.Publish directly
+func Install(t testing.TB, options messaging.Options) *Recorder {
+	t.Helper()
+	previous := messaging.Default()
+	bus, recorder := NewBus(t, options)
+	messaging.Init(bus)
+	t.Cleanup(func() { messaging.Init(previous) })
+	return recorder
+}
@@ -0,0 +1,76 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/nats-io/nats.go"
+	"github.com/praleedsuvarna/shared-libs/config"
+)
+
+// NATSDriver publishes and subscribes on a core NATS connection. Core NATS does
+// not redeliver, so a message whose handler and dead-lettering both fail is lost.
+type NATSDriver struct {
+	conn *nats.Conn
+}
+
+// NewNATSDriver creates a driver on conn (usually config.NATS)
+func NewNATSDriver(conn *nats.Conn) *NATSDriver {
+	return &NATSDriver{conn: conn}
+}
+
+// Name returns "nats"
+func (d *NATSDriver) Name() string {
+	return "nats"
+}
+
+// Publish sends msg with its headers as NATS message headers
+func (d *NATSDriver) Publish(ctx context.Context, msg RawMessage) error {
+	if d.conn == nil {
+		return fmt.Errorf("nats: %w", config.ErrNotConnected)
+	}
+
+	out := nats.NewMsg(msg.Subject)
+	out.Data = msg.Data
+	for key, value := range msg.Headers {
+		out.Header.Set(key, value)
+	}
+	return d.conn.PublishMsg(out)
+}
+
+// Subscribe uses a queue subscription when group is set
+func (d *NATSDriver) Subscribe(ctx context.Context, subject, group string, handler RawHandler) (Subscription, error) {
+	if d.conn == nil {
+		return nil, fmt.Errorf("nats: %w", config.ErrNotConnected)
+	}
+
+	callback := func(m *nats.Msg) {
+		msg := RawMessage{Subject: m.Subject, Data: m.Data, Headers: map[string]string{}}
+		for key := range m.Header {
+			msg.Headers[key] = m.Header.Get(key)
+		}
+		if err := handler(ctx, msg); err != nil {
+			log.Printf("❌ Dropping NATS message on %s: %v", m.Subject, err)
+		}
+	}
+
+	var (
+		sub *nats.Subscription
+		err error
+	)
+	if group != "" {
+		sub, err = d.conn.QueueSubscribe(subject, group, callback)
+	} else {
+		sub, err = d.conn.Subscribe(subject, callback)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("subscribe %s: %w", subject, err)
+	}
+	return sub, nil
+}
+
+// Close is a no-op; the connection is owned by config
+func (d *NATSDriver) Close() error {
+	return nil
+}
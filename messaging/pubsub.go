@@ -0,0 +1,218 @@
+package messaging
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	pubsub "google.golang.org/api/pubsub/v1"
+)
+
+// PubSubOptions configures a PubSubDriver
+type PubSubOptions struct {
+	ProjectID       string
+	CredentialsJSON []byte // Empty uses Application Default Credentials
+	TopicPrefix     string // Prepended to topic names, e.g. "prod-"
+	AckDeadline     int64  // Seconds before an unacknowledged message is redelivered (default 60)
+	MaxMessages     int64  // Messages per pull (default 10)
+}
+
+// PubSubDriver maps subjects to Google Cloud Pub/Sub topics and groups to
+// subscriptions, creating both on first use. Pub/Sub has no wildcards, so a
+// subject must name exactly one topic.
+type PubSubDriver struct {
+	service *pubsub.Service
+	options PubSubOptions
+
+	topics   map[string]bool
+	topicsMu sync.Mutex
+}
+
+var invalidTopicChars = regexp.MustCompile(`[^a-zA-Z0-9\-_.~+%]`)
+
+// NewPubSubDriver connects to the Pub/Sub API
+func NewPubSubDriver(ctx context.Context, options PubSubOptions) (*PubSubDriver, error) {
+	if options.ProjectID == "" {
+		return nil, fmt.Errorf("pubsub project is required")
+	}
+	if options.AckDeadline <= 0 {
+		options.AckDeadline = 60
+	}
+	if options.MaxMessages <= 0 {
+		options.MaxMessages = 10
+	}
+
+	clientOptions := []option.ClientOption{option.WithScopes(pubsub.PubsubScope)}
+	if len(options.CredentialsJSON) > 0 {
+		clientOptions = append(clientOptions, option.WithCredentialsJSON(options.CredentialsJSON))
+	}
+
+	service, err := pubsub.NewService(ctx, clientOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("create pubsub client: %w", err)
+	}
+	return &PubSubDriver{service: service, options: options, topics: map[string]bool{}}, nil
+}
+
+// Name returns "pubsub"
+func (d *PubSubDriver) Name() string {
+	return "pubsub"
+}
+
+// Publish sends msg with its headers as message attributes
+func (d *PubSubDriver) Publish(ctx context.Context, msg RawMessage) error {
+	topic, err := d.ensureTopic(ctx, msg.Subject)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.service.Projects.Topics.Publish(topic, &pubsub.PublishRequest{
+		Messages: []*pubsub.PubsubMessage{{
+			Data:       base64.StdEncoding.EncodeToString(msg.Data),
+			Attributes: msg.Headers,
+		}},
+	}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("publish to %s: %w", topic, err)
+	}
+	return nil
+}
+
+// Subscribe pulls from the subscription "<topic>--<group>" until Unsubscribe.
+// A failed handler nacks the message so Pub/Sub redelivers it.
+func (d *PubSubDriver) Subscribe(ctx context.Context, subject, group string, handler RawHandler) (Subscription, error) {
+	topic, err := d.ensureTopic(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	if group == "" {
+		group = "default"
+	}
+	name := fmt.Sprintf("projects/%s/subscriptions/%s--%s", d.options.ProjectID, d.topicID(subject), invalidTopicChars.ReplaceAllString(group, "-"))
+	_, err = d.service.Projects.Subscriptions.Create(name, &pubsub.Subscription{
+		Topic:              topic,
+		AckDeadlineSeconds: d.options.AckDeadline,
+	}).Context(ctx).Do()
+	if err != nil && !isGoogleAPIStatus(err, http.StatusConflict) {
+		return nil, fmt.Errorf("create subscription %s: %w", name, err)
+	}
+
+	pullCtx, cancel := context.WithCancel(ctx)
+	sub := &pubSubSubscription{cancel: cancel, done: make(chan struct{})}
+	go d.pull(pullCtx, name, subject, handler, sub.done)
+	return sub, nil
+}
+
+func (d *PubSubDriver) pull(ctx context.Context, name, subject string, handler RawHandler, done chan struct{}) {
+	defer close(done)
+
+	for ctx.Err() == nil {
+		response, err := d.service.Projects.Subscriptions.Pull(name, &pubsub.PullRequest{
+			MaxMessages: d.options.MaxMessages,
+		}).Context(ctx).Do()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("⚠️  Pub/Sub pull from %s failed: %v", name, err)
+			select {
+			case <-time.After(5 * time.Second):
+			case <-ctx.Done():
+			}
+			continue
+		}
+
+		for _, received := range response.ReceivedMessages {
+			d.handle(ctx, name, subject, received, handler)
+		}
+	}
+}
+
+func (d *PubSubDriver) handle(ctx context.Context, name, subject string, received *pubsub.ReceivedMessage, handler RawHandler) {
+	data, err := base64.StdEncoding.DecodeString(received.Message.Data)
+	if err == nil {
+		err = handler(ctx, RawMessage{Subject: subject, Data: data, Headers: received.Message.Attributes})
+	}
+
+	// Use a fresh context so shutting down mid-handler still settles the message
+	settleCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	if err != nil {
+		log.Printf("❌ Pub/Sub message %s on %s failed, requesting redelivery: %v", received.Message.MessageId, subject, err)
+		_, err = d.service.Projects.Subscriptions.ModifyAckDeadline(name, &pubsub.ModifyAckDeadlineRequest{
+			AckIds:             []string{received.AckId},
+			AckDeadlineSeconds: 0,
+			ForceSendFields:    []string{"AckDeadlineSeconds"},
+		}).Context(settleCtx).Do()
+	} else {
+		_, err = d.service.Projects.Subscriptions.Acknowledge(name, &pubsub.AcknowledgeRequest{
+			AckIds: []string{received.AckId},
+		}).Context(settleCtx).Do()
+	}
+	if err != nil {
+		log.Printf("⚠️  Failed to settle Pub/Sub message %s: %v", received.Message.MessageId, err)
+	}
+}
+
+// Close is a no-op; subscriptions are stopped individually
+func (d *PubSubDriver) Close() error {
+	return nil
+}
+
+// ensureTopic returns the full topic name for subject, creating the topic once
+func (d *PubSubDriver) ensureTopic(ctx context.Context, subject string) (string, error) {
+	if strings.ContainsAny(subject, "*>") {
+		return "", fmt.Errorf("pubsub does not support wildcard subject %q", subject)
+	}
+	topic := fmt.Sprintf("projects/%s/topics/%s", d.options.ProjectID, d.topicID(subject))
+
+	d.topicsMu.Lock()
+	defer d.topicsMu.Unlock()
+	if d.topics[topic] {
+		return topic, nil
+	}
+
+	_, err := d.service.Projects.Topics.Create(topic, &pubsub.Topic{}).Context(ctx).Do()
+	if err != nil && !isGoogleAPIStatus(err, http.StatusConflict) {
+		return "", fmt.Errorf("create topic %s: %w", topic, err)
+	}
+	d.topics[topic] = true
+	return topic, nil
+}
+
+// topicID converts a subject into a valid topic ID, which must start with a letter
+func (d *PubSubDriver) topicID(subject string) string {
+	id := d.options.TopicPrefix + invalidTopicChars.ReplaceAllString(subject, "-")
+	if id == "" || !(id[0] >= 'a' && id[0] <= 'z' || id[0] >= 'A' && id[0] <= 'Z') {
+		id = "t-" + id
+	}
+	return id
+}
+
+type pubSubSubscription struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Unsubscribe stops pulling and waits for the in-flight batch to settle
+func (s *pubSubSubscription) Unsubscribe() error {
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+func isGoogleAPIStatus(err error, code int) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
@@ -0,0 +1,379 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/utils"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// RabbitMQOptions configures a RabbitMQDriver
+type RabbitMQOptions struct {
+	URL         string
+	Exchange    string        // Topic exchange messages are published to (default "events")
+	QueuePrefix string        // Prepended to queue names, e.g. "prod."
+	Prefetch    int           // Unacknowledged deliveries per consumer (default 20)
+	Concurrency int           // Handlers running in parallel per subscription (default 1)
+	RetryDelay  time.Duration // Wait before a failed delivery is redelivered (default 30s)
+}
+
+// RabbitMQDriver publishes to a topic exchange with publisher confirms and
+// consumes from one durable queue per subject and group. Each group queue is
+// paired with a "<queue>.retry" queue through a dead letter exchange: a failed
+// delivery is rejected into the retry queue and returns to the group queue
+// once RetryDelay has passed. Subscribing without a group uses an exclusive
+// queue that is deleted when the subscription ends; its failed deliveries are
+// requeued after RetryDelay instead.
+type RabbitMQDriver struct {
+	options RabbitMQOptions
+
+	conn      *amqp.Connection
+	publisher *amqp.Channel
+	mu        sync.Mutex
+}
+
+var queueNameReplacer = strings.NewReplacer("*", "any", ">", "all")
+
+// NewRabbitMQDriver connects and declares the exchange
+func NewRabbitMQDriver(options RabbitMQOptions) (*RabbitMQDriver, error) {
+	if options.URL == "" {
+		return nil, fmt.Errorf("rabbitmq URL is required")
+	}
+	if options.Exchange == "" {
+		options.Exchange = "events"
+	}
+	if options.Prefetch <= 0 {
+		options.Prefetch = 20
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = 1
+	}
+	if options.RetryDelay <= 0 {
+		options.RetryDelay = 30 * time.Second
+	}
+
+	driver := &RabbitMQDriver{options: options}
+	if err := driver.DeclareExchange(options.Exchange, amqp.ExchangeTopic); err != nil {
+		return nil, err
+	}
+	return driver, nil
+}
+
+// Name returns "rabbitmq"
+func (d *RabbitMQDriver) Name() string {
+	return "rabbitmq"
+}
+
+// Publish sends a persistent message routed by subject and waits for the
+// broker to confirm it
+func (d *RabbitMQDriver) Publish(ctx context.Context, msg RawMessage) error {
+	headers := amqp.Table{}
+	for key, value := range msg.Headers {
+		headers[key] = value
+	}
+
+	d.mu.Lock()
+	channel, err := d.publishChannel()
+	if err != nil {
+		d.mu.Unlock()
+		return err
+	}
+	confirmation, err := channel.PublishWithDeferredConfirmWithContext(ctx, d.options.Exchange, msg.Subject, false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    utils.Now(),
+		Headers:      headers,
+		Body:         msg.Data,
+	})
+	d.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("publish %s: %w", msg.Subject, err)
+	}
+
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("confirm %s: %w", msg.Subject, err)
+	}
+	if !acked {
+		return fmt.Errorf("publish %s: rejected by broker", msg.Subject)
+	}
+	return nil
+}
+
+// Subscribe consumes subject from the group's queue, reconnecting when the
+// connection drops
+func (d *RabbitMQDriver) Subscribe(ctx context.Context, subject, group string, handler RawHandler) (Subscription, error) {
+	queue := ""
+	if group != "" {
+		queue = d.options.QueuePrefix + queueNameReplacer.Replace(subject) + "." + group
+		if err := d.DeclareQueue(queue, d.options.RetryDelay); err != nil {
+			return nil, err
+		}
+		if err := d.BindQueue(queue, subject); err != nil {
+			return nil, err
+		}
+	}
+
+	consumeCtx, cancel := context.WithCancel(ctx)
+	sub := &rabbitMQSubscription{cancel: cancel, done: make(chan struct{})}
+
+	// Open the first consumer synchronously so setup errors reach the caller
+	deliveries, channel, err := d.consume(consumeCtx, subject, queue)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	go d.run(consumeCtx, subject, queue, handler, deliveries, channel, sub.done)
+	return sub, nil
+}
+
+func (d *RabbitMQDriver) run(ctx context.Context, subject, queue string, handler RawHandler, deliveries <-chan amqp.Delivery, channel *amqp.Channel, done chan struct{}) {
+	defer close(done)
+
+	for {
+		var wg sync.WaitGroup
+		for i := 0; i < d.options.Concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for delivery := range deliveries {
+					d.handle(ctx, queue != "", delivery, handler)
+				}
+			}()
+		}
+		wg.Wait()
+		channel.Close()
+
+		// Deliveries stop when ctx ends or the channel/connection is lost
+		for ctx.Err() == nil {
+			log.Printf("⚠️  RabbitMQ consumer for %s stopped, reconnecting...", subject)
+			select {
+			case <-time.After(5 * time.Second):
+			case <-ctx.Done():
+				return
+			}
+
+			var err error
+			deliveries, channel, err = d.consume(ctx, subject, queue)
+			if err == nil {
+				log.Printf("✅ RabbitMQ consumer for %s reconnected", subject)
+				break
+			}
+			log.Printf("❌ RabbitMQ reconnect for %s failed: %v", subject, err)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// consume opens a channel with the configured prefetch and starts consuming
+// queue, declaring an exclusive queue when queue is empty
+func (d *RabbitMQDriver) consume(ctx context.Context, subject, queue string) (<-chan amqp.Delivery, *amqp.Channel, error) {
+	d.mu.Lock()
+	conn, err := d.connection()
+	d.mu.Unlock()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		return nil, nil, fmt.Errorf("open rabbitmq channel: %w", err)
+	}
+	if err := channel.Qos(d.options.Prefetch, 0, false); err != nil {
+		channel.Close()
+		return nil, nil, fmt.Errorf("set rabbitmq prefetch: %w", err)
+	}
+
+	if queue == "" {
+		declared, err := channel.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			channel.Close()
+			return nil, nil, fmt.Errorf("declare exclusive queue for %s: %w", subject, err)
+		}
+		if err := channel.QueueBind(declared.Name, routingKey(subject), d.options.Exchange, false, nil); err != nil {
+			channel.Close()
+			return nil, nil, fmt.Errorf("bind exclusive queue for %s: %w", subject, err)
+		}
+		queue = declared.Name
+	}
+
+	deliveries, err := channel.ConsumeWithContext(ctx, queue, "", false, false, false, false, nil)
+	if err != nil {
+		channel.Close()
+		return nil, nil, fmt.Errorf("consume %s: %w", queue, err)
+	}
+	return deliveries, channel, nil
+}
+
+func (d *RabbitMQDriver) handle(ctx context.Context, durable bool, delivery amqp.Delivery, handler RawHandler) {
+	msg := RawMessage{Subject: delivery.RoutingKey, Data: delivery.Body, Headers: map[string]string{}}
+	for key, value := range delivery.Headers {
+		if text, ok := value.(string); ok {
+			msg.Headers[key] = text
+		}
+	}
+
+	err := handler(ctx, msg)
+	if err == nil {
+		if ackErr := delivery.Ack(false); ackErr != nil {
+			log.Printf("⚠️  Failed to ack RabbitMQ delivery on %s: %v", delivery.RoutingKey, ackErr)
+		}
+		return
+	}
+
+	log.Printf("❌ RabbitMQ delivery on %s failed, retrying in %s: %v", delivery.RoutingKey, d.options.RetryDelay, err)
+	if durable {
+		// Dead-lettered into the retry queue, which returns it after RetryDelay
+		err = delivery.Nack(false, false)
+	} else {
+		select {
+		case <-time.After(d.options.RetryDelay):
+		case <-ctx.Done():
+		}
+		err = delivery.Nack(false, true)
+	}
+	if err != nil {
+		log.Printf("⚠️  Failed to reject RabbitMQ delivery on %s: %v", delivery.RoutingKey, err)
+	}
+}
+
+// DeclareExchange declares a durable exchange of kind ("topic", "direct", "fanout", "headers")
+func (d *RabbitMQDriver) DeclareExchange(name, kind string) error {
+	return d.withChannel(func(channel *amqp.Channel) error {
+		if err := channel.ExchangeDeclare(name, kind, true, false, false, false, nil); err != nil {
+			return fmt.Errorf("declare exchange %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// DeclareQueue declares a durable queue together with its "<name>.retry" queue:
+// rejected messages wait there for retryDelay, then return to name
+func (d *RabbitMQDriver) DeclareQueue(name string, retryDelay time.Duration) error {
+	retryQueue := name + ".retry"
+	return d.withChannel(func(channel *amqp.Channel) error {
+		_, err := channel.QueueDeclare(name, true, false, false, false, amqp.Table{
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": retryQueue,
+		})
+		if err != nil {
+			return fmt.Errorf("declare queue %s: %w", name, err)
+		}
+
+		_, err = channel.QueueDeclare(retryQueue, true, false, false, false, amqp.Table{
+			"x-message-ttl":             retryDelay.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": name,
+		})
+		if err != nil {
+			return fmt.Errorf("declare queue %s: %w", retryQueue, err)
+		}
+		return nil
+	})
+}
+
+// BindQueue routes subject (NATS-style wildcards allowed) from the driver's exchange to queue
+func (d *RabbitMQDriver) BindQueue(queue, subject string) error {
+	return d.withChannel(func(channel *amqp.Channel) error {
+		if err := channel.QueueBind(queue, routingKey(subject), d.options.Exchange, false, nil); err != nil {
+			return fmt.Errorf("bind queue %s to %s: %w", queue, subject, err)
+		}
+		return nil
+	})
+}
+
+// Close closes the connection, which also stops all subscriptions
+func (d *RabbitMQDriver) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.conn == nil || d.conn.IsClosed() {
+		return nil
+	}
+	return d.conn.Close()
+}
+
+// withChannel runs fn on a short-lived channel so that a failed declaration,
+// which closes its channel, does not affect publishing
+func (d *RabbitMQDriver) withChannel(fn func(channel *amqp.Channel) error) error {
+	d.mu.Lock()
+	conn, err := d.connection()
+	d.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("open rabbitmq channel: %w", err)
+	}
+	defer channel.Close()
+	return fn(channel)
+}
+
+// connection dials when there is no open connection; callers hold d.mu
+func (d *RabbitMQDriver) connection() (*amqp.Connection, error) {
+	if d.conn != nil && !d.conn.IsClosed() {
+		return d.conn, nil
+	}
+
+	conn, err := amqp.Dial(d.options.URL)
+	if err != nil {
+		return nil, fmt.Errorf("connect to rabbitmq: %w", err)
+	}
+	d.conn = conn
+	d.publisher = nil
+	return conn, nil
+}
+
+// publishChannel returns the confirm-mode publishing channel; callers hold d.mu
+func (d *RabbitMQDriver) publishChannel() (*amqp.Channel, error) {
+	conn, err := d.connection()
+	if err != nil {
+		return nil, err
+	}
+	if d.publisher != nil && !d.publisher.IsClosed() {
+		return d.publisher, nil
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("open rabbitmq channel: %w", err)
+	}
+	if err := channel.Confirm(false); err != nil {
+		channel.Close()
+		return nil, fmt.Errorf("enable publisher confirms: %w", err)
+	}
+	d.publisher = channel
+	return channel, nil
+}
+
+// routingKey converts NATS-style wildcards to AMQP topic wildcards
+func routingKey(subject string) string {
+	if subject == ">" {
+		return "#"
+	}
+	if strings.HasSuffix(subject, ".>") {
+		return strings.TrimSuffix(subject, ">") + "#"
+	}
+	return subject
+}
+
+type rabbitMQSubscription struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Unsubscribe cancels the consumer and waits for in-flight handlers
+func (s *rabbitMQSubscription) Unsubscribe() error {
+	s.cancel()
+	<-s.done
+	return nil
+}
@@ -0,0 +1,309 @@
+package messaging
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Headers describing how an envelope is protected
+const (
+	HeaderEncryptionAlg   = "x-enc-alg"
+	HeaderEncryptionKeyID = "x-enc-kid"
+	HeaderSignatureAlg    = "x-sig-alg"
+	HeaderSignatureKeyID  = "x-sig-kid"
+	HeaderSignature       = "x-signature"
+)
+
+// Protection algorithms
+const (
+	AlgorithmAESGCM     = "AES-GCM"
+	AlgorithmHMACSHA256 = "HMAC-SHA256"
+	AlgorithmEd25519    = "Ed25519"
+)
+
+var (
+	ErrUnknownKey       = errors.New("unknown message key")
+	ErrInvalidSignature = errors.New("invalid message signature")
+	ErrNotSigned        = errors.New("message is not signed")
+	ErrNotEncrypted     = errors.New("message is not encrypted")
+	ErrSubjectMismatch  = errors.New("message was delivered on another subject than it was published on")
+)
+
+// unsignedHeaders are added after an envelope is sealed, when it is dead-lettered
+// or replayed, so the signature cannot cover them
+var unsignedHeaders = map[string]bool{
+	HeaderSignature:        true,
+	HeaderOriginalSubject:  true,
+	HeaderDeadLetterReason: true,
+	HeaderDeadLetteredAt:   true,
+	HeaderReplayedFrom:     true,
+}
+
+// Signer signs envelopes with one key
+type Signer interface {
+	KeyID() string
+	Algorithm() string
+	Sign(data []byte) ([]byte, error)
+}
+
+// Verifier checks signatures made with one key
+type Verifier interface {
+	Algorithm() string
+	Verify(data, signature []byte) error
+}
+
+// Protection encrypts and signs published envelopes and opens received ones.
+// Keys are looked up by the IDs carried in the envelope headers, so keys can be
+// rotated by publishing with a new ID while older IDs remain available.
+type Protection struct {
+	EncryptionKeyID string            // Key used to encrypt published payloads; empty publishes plaintext
+	EncryptionKeys  map[string][]byte // AES keys (16, 24 or 32 bytes) by ID
+
+	Signer    Signer              // Signs published envelopes; nil publishes unsigned
+	Verifiers map[string]Verifier // Verification keys by ID
+
+	RequireEncryption bool // Reject received envelopes that are not encrypted
+	RequireSignature  bool // Reject received envelopes that are not signed
+}
+
+// Seal encrypts and signs envelope in place. The payload is replaced with a
+// JSON string holding base64(nonce || ciphertext).
+func (p *Protection) Seal(envelope *Envelope) error {
+	if envelope.Headers == nil {
+		envelope.Headers = map[string]string{}
+	}
+
+	if p.EncryptionKeyID != "" {
+		aead, err := p.aead(p.EncryptionKeyID)
+		if err != nil {
+			return err
+		}
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return fmt.Errorf("generate nonce: %w", err)
+		}
+		sealed := aead.Seal(nonce, nonce, envelope.Data, associatedData(envelope))
+
+		data, err := json.Marshal(base64.StdEncoding.EncodeToString(sealed))
+		if err != nil {
+			return err
+		}
+		envelope.Data = data
+		envelope.Headers[HeaderEncryptionAlg] = AlgorithmAESGCM
+		envelope.Headers[HeaderEncryptionKeyID] = p.EncryptionKeyID
+	}
+
+	if p.Signer != nil {
+		envelope.Headers[HeaderSignatureAlg] = p.Signer.Algorithm()
+		envelope.Headers[HeaderSignatureKeyID] = p.Signer.KeyID()
+		signature, err := p.Signer.Sign(signingInput(envelope))
+		if err != nil {
+			return fmt.Errorf("sign envelope: %w", err)
+		}
+		envelope.Headers[HeaderSignature] = base64.StdEncoding.EncodeToString(signature)
+	}
+	return nil
+}
+
+// Open verifies and decrypts envelope, returning a plaintext copy
+func (p *Protection) Open(envelope *Envelope) (*Envelope, error) {
+	headers := envelope.Headers
+
+	if signature := headers[HeaderSignature]; signature != "" {
+		keyID := headers[HeaderSignatureKeyID]
+		verifier, ok := p.Verifiers[keyID]
+		if !ok {
+			return nil, fmt.Errorf("%w: signing key %q", ErrUnknownKey, keyID)
+		}
+		if verifier.Algorithm() != headers[HeaderSignatureAlg] {
+			return nil, fmt.Errorf("%w: key %q does not use %s", ErrInvalidSignature, keyID, headers[HeaderSignatureAlg])
+		}
+		decoded, err := base64.StdEncoding.DecodeString(signature)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+		}
+		if err := verifier.Verify(signingInput(envelope), decoded); err != nil {
+			return nil, err
+		}
+	} else if p.RequireSignature {
+		return nil, ErrNotSigned
+	}
+
+	opened := *envelope
+	keyID := headers[HeaderEncryptionKeyID]
+	if keyID == "" {
+		if p.RequireEncryption {
+			return nil, ErrNotEncrypted
+		}
+		return &opened, nil
+	}
+	if headers[HeaderEncryptionAlg] != AlgorithmAESGCM {
+		return nil, fmt.Errorf("unsupported encryption algorithm %q", headers[HeaderEncryptionAlg])
+	}
+
+	aead, err := p.aead(keyID)
+	if err != nil {
+		return nil, err
+	}
+	var encoded string
+	if err := json.Unmarshal(envelope.Data, &encoded); err != nil {
+		return nil, fmt.Errorf("decode encrypted payload: %w", err)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode encrypted payload: %w", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("decrypt payload: ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, associatedData(envelope))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt payload: %w", err)
+	}
+	opened.Data = plaintext
+	return &opened, nil
+}
+
+func (p *Protection) aead(keyID string) (cipher.AEAD, error) {
+	key, ok := p.EncryptionKeys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: encryption key %q", ErrUnknownKey, keyID)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key %q: %w", keyID, err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// protectedSubject is the subject an envelope was first published on, so that
+// dead-lettered and replayed envelopes still open
+func protectedSubject(envelope *Envelope) string {
+	if original := envelope.Headers[HeaderOriginalSubject]; original != "" {
+		return original
+	}
+	return envelope.Subject
+}
+
+// associatedData binds a ciphertext to its envelope
+func associatedData(envelope *Envelope) []byte {
+	return []byte(envelope.ID + "\n" + protectedSubject(envelope))
+}
+
+// checkSubject rejects an envelope delivered on a subject other than the one it
+// was published on, or for dead letters, that subject's dead letter subject.
+// The signature covers the published subject, so this stops a signed envelope
+// from being replayed onto another subject.
+func checkSubject(envelope *Envelope, delivered, deadLetterSuffix string) error {
+	expected := envelope.Subject
+	if original := envelope.Headers[HeaderOriginalSubject]; original != "" {
+		expected = DeadLetterSubject(original, deadLetterSuffix)
+	}
+	if envelope.Subject != delivered || expected != delivered {
+		return fmt.Errorf("%w: %q on %q", ErrSubjectMismatch, protectedSubject(envelope), delivered)
+	}
+	return nil
+}
+
+// signingInput covers the envelope identity, the (possibly encrypted) payload
+// and every header set by the publisher, key IDs included
+func signingInput(envelope *Envelope) []byte {
+	fields := []string{
+		envelope.ID,
+		protectedSubject(envelope),
+		envelope.Source,
+		envelope.Timestamp.UTC().Format(time.RFC3339Nano),
+		string(envelope.Data),
+	}
+
+	names := make([]string, 0, len(envelope.Headers))
+	for name := range envelope.Headers {
+		if !unsignedHeaders[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fields = append(fields, name+":"+envelope.Headers[name])
+	}
+	return []byte(strings.Join(fields, "\n"))
+}
+
+// HMACKey signs and verifies with a shared secret
+type HMACKey struct {
+	id     string
+	secret []byte
+}
+
+// NewHMACKey creates an HMAC-SHA256 key
+func NewHMACKey(id string, secret []byte) *HMACKey {
+	return &HMACKey{id: id, secret: secret}
+}
+
+func (k *HMACKey) KeyID() string     { return k.id }
+func (k *HMACKey) Algorithm() string { return AlgorithmHMACSHA256 }
+
+// Sign returns the HMAC-SHA256 of data
+func (k *HMACKey) Sign(data []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, k.secret)
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
+
+// Verify compares signature with the HMAC of data in constant time
+func (k *HMACKey) Verify(data, signature []byte) error {
+	expected, _ := k.Sign(data)
+	if !hmac.Equal(expected, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Ed25519Signer signs with an Ed25519 private key
+type Ed25519Signer struct {
+	id  string
+	key ed25519.PrivateKey
+}
+
+// NewEd25519Signer creates a signer from a private key
+func NewEd25519Signer(id string, key ed25519.PrivateKey) *Ed25519Signer {
+	return &Ed25519Signer{id: id, key: key}
+}
+
+func (s *Ed25519Signer) KeyID() string     { return s.id }
+func (s *Ed25519Signer) Algorithm() string { return AlgorithmEd25519 }
+
+// Sign signs data
+func (s *Ed25519Signer) Sign(data []byte) ([]byte, error) {
+	return ed25519.Sign(s.key, data), nil
+}
+
+// Verifier returns the matching public key verifier
+func (s *Ed25519Signer) Verifier() Ed25519Verifier {
+	return Ed25519Verifier(s.key.Public().(ed25519.PublicKey))
+}
+
+// Ed25519Verifier verifies with an Ed25519 public key
+type Ed25519Verifier ed25519.PublicKey
+
+func (v Ed25519Verifier) Algorithm() string { return AlgorithmEd25519 }
+
+// Verify checks an Ed25519 signature of data
+func (v Ed25519Verifier) Verify(data, signature []byte) error {
+	if len(v) != ed25519.PublicKeySize || !ed25519.Verify(ed25519.PublicKey(v), data, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
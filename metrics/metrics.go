@@ -0,0 +1,203 @@
+// Package metrics records product/business events as Prometheus metrics and can
+// mirror them to NATS for the analytics pipeline:
+//
+//	metrics.Count("experience_published", metrics.Labels{"plan": "pro"})
+//	metrics.Observe("checkout_amount", 49.99, metrics.Labels{"currency": "USD"})
+//
+// Metrics are registered on first use as business_<name>_total counters and
+// business_<name> histograms. Every call for a name must use the same label keys.
+package metrics
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Labels are the dimensions of a business metric
+type Labels map[string]string
+
+// Event types mirrored to NATS
+const (
+	EventCount   = "count"
+	EventObserve = "observe"
+)
+
+// Event is the NATS representation of a recorded metric
+type Event struct {
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Value     float64   `json:"value"`
+	Labels    Labels    `json:"labels,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Registerer is where business metrics are registered (the default Prometheus registry)
+var Registerer prometheus.Registerer = prometheus.DefaultRegisterer
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+var (
+	counters   = map[string]*prometheus.CounterVec{}
+	histograms = map[string]*prometheus.HistogramVec{}
+	labelKeys  = map[string][]string{}
+	buckets    = map[string][]float64{}
+	registryMu sync.Mutex
+
+	mirrorSubject string
+	mirrorMu      sync.RWMutex
+)
+
+// Count increments the counter name by one
+func Count(name string, labels Labels) {
+	Add(name, 1, labels)
+}
+
+// Add increases the counter name by delta, which must not be negative
+func Add(name string, delta float64, labels Labels) {
+	if delta < 0 {
+		log.Printf("⚠️  Ignoring negative increment %v for metric %s", delta, name)
+		return
+	}
+
+	counter, keys, err := counterFor(name, labels)
+	if err != nil {
+		log.Printf("⚠️  Metric %s not recorded: %v", name, err)
+		return
+	}
+	counter.WithLabelValues(labelValues(keys, labels)...).Add(delta)
+	mirror(Event{Name: name, Type: EventCount, Value: delta, Labels: labels})
+}
+
+// Observe records value in the histogram name
+func Observe(name string, value float64, labels Labels) {
+	histogram, keys, err := histogramFor(name, labels)
+	if err != nil {
+		log.Printf("⚠️  Metric %s not recorded: %v", name, err)
+		return
+	}
+	histogram.WithLabelValues(labelValues(keys, labels)...).Observe(value)
+	mirror(Event{Name: name, Type: EventObserve, Value: value, Labels: labels})
+}
+
+// SetBuckets configures histogram buckets for name; call it before the first Observe
+func SetBuckets(name string, values []float64) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	buckets[metricName(name)] = values
+}
+
+// MirrorToNATS publishes every recorded metric as an Event on subject; an empty
+// subject stops mirroring. Events are dropped while NATS is not connected.
+func MirrorToNATS(subject string) {
+	mirrorMu.Lock()
+	defer mirrorMu.Unlock()
+	mirrorSubject = subject
+}
+
+func mirror(event Event) {
+	mirrorMu.RLock()
+	subject := mirrorSubject
+	mirrorMu.RUnlock()
+	if subject == "" || config.NATS == nil {
+		return
+	}
+
+	event.Timestamp = utils.Now()
+	if err := utils.PublishEvent(subject, event); err != nil {
+		utils.LogWarning(fmt.Sprintf("Failed to mirror metric %s: %v", event.Name, err))
+	}
+}
+
+func counterFor(name string, labels Labels) (*prometheus.CounterVec, []string, error) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	key := metricName(name)
+	keys, err := checkLabels(key, labels)
+	if err != nil {
+		return nil, nil, err
+	}
+	if counter, ok := counters[key]; ok {
+		return counter, keys, nil
+	}
+	if _, ok := histograms[key]; ok {
+		return nil, nil, fmt.Errorf("already registered as a histogram")
+	}
+
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "business_" + key + "_total",
+		Help: "Business event counter " + name + ".",
+	}, keys)
+	if err := Registerer.Register(counter); err != nil {
+		return nil, nil, err
+	}
+	counters[key] = counter
+	labelKeys[key] = keys
+	return counter, keys, nil
+}
+
+func histogramFor(name string, labels Labels) (*prometheus.HistogramVec, []string, error) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	key := metricName(name)
+	keys, err := checkLabels(key, labels)
+	if err != nil {
+		return nil, nil, err
+	}
+	if histogram, ok := histograms[key]; ok {
+		return histogram, keys, nil
+	}
+	if _, ok := counters[key]; ok {
+		return nil, nil, fmt.Errorf("already registered as a counter")
+	}
+
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "business_" + key,
+		Help:    "Business event observations for " + name + ".",
+		Buckets: buckets[key],
+	}, keys)
+	if err := Registerer.Register(histogram); err != nil {
+		return nil, nil, err
+	}
+	histograms[key] = histogram
+	labelKeys[key] = keys
+	return histogram, keys, nil
+}
+
+// checkLabels returns the sorted label keys, rejecting sets that differ from the first use
+func checkLabels(key string, labels Labels) ([]string, error) {
+	keys := make([]string, 0, len(labels))
+	for label := range labels {
+		keys = append(keys, label)
+	}
+	sort.Strings(keys)
+
+	registered, ok := labelKeys[key]
+	if ok && strings.Join(registered, ",") != strings.Join(keys, ",") {
+		return nil, fmt.Errorf("label keys %v do not match registered keys %v", keys, registered)
+	}
+	return keys, nil
+}
+
+func labelValues(keys []string, labels Labels) []string {
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i] = labels[key]
+	}
+	return values
+}
+
+// metricName converts a free-form event name into a valid Prometheus name fragment
+func metricName(name string) string {
+	return strings.ToLower(invalidNameChars.ReplaceAllString(name, "_"))
+}
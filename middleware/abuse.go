@@ -0,0 +1,308 @@
+package middleware
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var httpRequestsAbuse = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "http_requests_abuse_total",
+	Help: "Total number of HTTP requests acted on by abuse detection, by action.",
+}, []string{"action"})
+
+// Actions taken by AbuseDetection, from mildest to harshest
+const (
+	AbuseAllow     = "allow"
+	AbuseTarpit    = "tarpit"    // Served after a delay, which slows scrapers down
+	AbuseChallenge = "challenge" // Served only when AbuseOptions.Challenge passes
+	AbuseBlock     = "block"
+)
+
+// DefaultBotUserAgents match the user agents of scripts, HTTP libraries and
+// headless browsers
+var DefaultBotUserAgents = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(curl|wget|httpie|python-requests|python-urllib|aiohttp|go-http-client|okhttp|java/|libwww|scrapy|httpclient|node-fetch|axios)\b`),
+	regexp.MustCompile(`(?i)(headlesschrome|phantomjs|puppeteer|playwright|selenium)`),
+	regexp.MustCompile(`(?i)(bot|crawler|spider|scraper)\b`),
+}
+
+// AbuseOptions configures AbuseDetection. Scores run from 0 to 100.
+type AbuseOptions struct {
+	TarpitScore    int           // Default 30
+	ChallengeScore int           // Default 60
+	BlockScore     int           // Default 90
+	TarpitDelay    time.Duration // Default 2s
+
+	BurstRequests int           // Requests per BurstWindow a person plausibly makes; default 30
+	BurstWindow   time.Duration // Default 10s
+
+	// HoneypotPaths are linked invisibly or listed as disallowed in
+	// robots.txt; callers that request one are flagged for HoneypotTTL
+	HoneypotPaths []string
+	HoneypotTTL   time.Duration // Default one hour
+
+	UserAgents      []*regexp.Regexp // Default DefaultBotUserAgents
+	AllowUserAgents []*regexp.Regexp // Welcome crawlers, e.g. Googlebot, exempt from user agent scoring
+
+	// Challenge reports whether a request passed a challenge, e.g. carries a
+	// valid captcha token. Without it challenged requests are refused with a
+	// body frontends can use to show one.
+	Challenge func(c *fiber.Ctx) bool
+	// Key identifies the caller; the default is the client IP
+	Key func(c *fiber.Ctx) string
+}
+
+// AbuseScore is the verdict on a request, available to handlers through
+// GetAbuseScore, e.g. to trim responses to suspected scrapers
+type AbuseScore struct {
+	Score   int      `json:"score"`
+	Action  string   `json:"action"`
+	Reasons []string `json:"reasons"`
+}
+
+// abuseScoreKey is the Locals key of the request's AbuseScore
+const abuseScoreKey = "abuse_score"
+
+// GetAbuseScore returns the verdict of AbuseDetection on the request; requests
+// it did not see score zero
+func GetAbuseScore(c *fiber.Ctx) AbuseScore {
+	if score, ok := c.Locals(abuseScoreKey).(AbuseScore); ok {
+		return score
+	}
+	return AbuseScore{Action: AbuseAllow}
+}
+
+type abuseCaller struct {
+	windowStart time.Time
+	requests    int
+	flaggedAt   time.Time // Last honeypot hit
+	auditedAt   time.Time
+	lastSeen    time.Time
+}
+
+// AbuseDetection scores each request from its user agent and headers, the
+// caller's request rate and honeypot visits, then tarpits, challenges or
+// blocks it by score. Honeypot hits, challenges and blocks are recorded in the
+// audit log, at most once per caller and BurstWindow. It is meant for public endpoints such as catalogs; state is per instance.
+func AbuseDetection(options AbuseOptions) fiber.Handler {
+	if options.TarpitScore <= 0 {
+		options.TarpitScore = 30
+	}
+	if options.ChallengeScore <= 0 {
+		options.ChallengeScore = 60
+	}
+	if options.BlockScore <= 0 {
+		options.BlockScore = 90
+	}
+	if options.TarpitDelay <= 0 {
+		options.TarpitDelay = 2 * time.Second
+	}
+	if options.BurstRequests <= 0 {
+		options.BurstRequests = 30
+	}
+	if options.BurstWindow <= 0 {
+		options.BurstWindow = 10 * time.Second
+	}
+	if options.HoneypotTTL <= 0 {
+		options.HoneypotTTL = time.Hour
+	}
+	if options.UserAgents == nil {
+		options.UserAgents = DefaultBotUserAgents
+	}
+	if options.Key == nil {
+		options.Key = ClientIP
+	}
+
+	var (
+		callers   = map[string]*abuseCaller{}
+		lastPrune time.Time
+		mu        sync.Mutex
+	)
+	idle := max(options.HoneypotTTL, options.BurstWindow)
+
+	// observe counts a request of key and returns its requests in the current
+	// window and whether it visited a honeypot recently
+	observe := func(key string, honeypot bool) (int, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		now := time.Now()
+		if now.Sub(lastPrune) > idle {
+			for k, caller := range callers {
+				if now.Sub(caller.lastSeen) > idle {
+					delete(callers, k)
+				}
+			}
+			lastPrune = now
+		}
+
+		caller, ok := callers[key]
+		if !ok {
+			caller = &abuseCaller{windowStart: now}
+			callers[key] = caller
+		}
+		if now.Sub(caller.windowStart) > options.BurstWindow {
+			caller.windowStart = now
+			caller.requests = 0
+		}
+		caller.requests++
+		caller.lastSeen = now
+		if honeypot {
+			caller.flaggedAt = now
+		}
+		return caller.requests, !caller.flaggedAt.IsZero() && now.Sub(caller.flaggedAt) < options.HoneypotTTL
+	}
+
+	// shouldAudit limits audit entries to one per caller and BurstWindow, so
+	// a flood does not flood the audit log too
+	shouldAudit := func(key string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		caller, ok := callers[key]
+		if !ok || time.Since(caller.auditedAt) < options.BurstWindow {
+			return false
+		}
+		caller.auditedAt = time.Now()
+		return true
+	}
+	audit := func(c *fiber.Ctx, key, action string, verdict AbuseScore) {
+		GetLogger(c).Warn("abusive request", "action", action, "score", verdict.Score, "reasons", verdict.Reasons)
+		if shouldAudit(key) {
+			auditAbuse(c, key, action, verdict)
+		}
+	}
+
+	return func(c *fiber.Ctx) error {
+		key := options.Key(c)
+		honeypot := isHoneypot(c.Path(), options.HoneypotPaths)
+		requests, flagged := observe(key, honeypot)
+
+		verdict := scoreRequest(c, options, requests, flagged)
+		switch {
+		case verdict.Score >= options.BlockScore:
+			verdict.Action = AbuseBlock
+		case verdict.Score >= options.ChallengeScore:
+			verdict.Action = AbuseChallenge
+		case verdict.Score >= options.TarpitScore:
+			verdict.Action = AbuseTarpit
+		default:
+			verdict.Action = AbuseAllow
+		}
+		c.Locals(abuseScoreKey, verdict)
+
+		if honeypot {
+			// Look like any missing page, so the trap is not obvious
+			audit(c, key, "abuse_honeypot_hit", verdict)
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Not found"})
+		}
+
+		switch verdict.Action {
+		case AbuseBlock:
+			httpRequestsAbuse.WithLabelValues(AbuseBlock).Inc()
+			audit(c, key, "abuse_blocked", verdict)
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Request blocked",
+			})
+		case AbuseChallenge:
+			if options.Challenge != nil && options.Challenge(c) {
+				break
+			}
+			httpRequestsAbuse.WithLabelValues(AbuseChallenge).Inc()
+			audit(c, key, "abuse_challenged", verdict)
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":     "Please verify you are human",
+				"challenge": true,
+			})
+		case AbuseTarpit:
+			httpRequestsAbuse.WithLabelValues(AbuseTarpit).Inc()
+			timer := time.NewTimer(options.TarpitDelay)
+			select {
+			case <-timer.C:
+			case <-c.UserContext().Done():
+				timer.Stop()
+			}
+		}
+
+		return c.Next()
+	}
+}
+
+// scoreRequest adds up the heuristics that fire for a request, capped at 100
+func scoreRequest(c *fiber.Ctx, options AbuseOptions, requests int, flagged bool) AbuseScore {
+	verdict := AbuseScore{Reasons: []string{}}
+	add := func(points int, reason string) {
+		verdict.Score += points
+		verdict.Reasons = append(verdict.Reasons, reason)
+	}
+
+	userAgent := c.Get(fiber.HeaderUserAgent)
+	switch {
+	case userAgent == "":
+		add(40, "missing_user_agent")
+	case matchesAny(userAgent, options.AllowUserAgents):
+	case matchesAny(userAgent, options.UserAgents):
+		add(50, "bot_user_agent")
+	}
+	// Browsers always send these
+	if c.Get(fiber.HeaderAcceptLanguage) == "" {
+		add(10, "missing_accept_language")
+	}
+	if c.Get(fiber.HeaderAccept) == "" {
+		add(10, "missing_accept")
+	}
+
+	switch {
+	case requests > 3*options.BurstRequests:
+		add(70, "request_burst")
+	case requests > options.BurstRequests:
+		add(40, "request_burst")
+	}
+	if flagged {
+		add(100, "honeypot")
+	}
+
+	verdict.Score = min(verdict.Score, 100)
+	return verdict
+}
+
+func matchesAny(value string, patterns []*regexp.Regexp) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func isHoneypot(path string, honeypots []string) bool {
+	for _, honeypot := range honeypots {
+		if path == honeypot || strings.HasPrefix(path, strings.TrimSuffix(honeypot, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// auditAbuse records an abuse verdict in the audit log
+func auditAbuse(c *fiber.Ctx, key, action string, verdict AbuseScore) {
+	userID, _ := c.Locals("user_id").(string)
+	if userID == "" {
+		userID = "anonymous"
+	}
+	utils.LogAuditContext(c.UserContext(), userID, action, c.Path(), map[string]interface{}{
+		"caller":     key,
+		"ip":         ClientIP(c),
+		"method":     c.Method(),
+		"user_agent": c.Get(fiber.HeaderUserAgent),
+		"score":      verdict.Score,
+		"reasons":    verdict.Reasons,
+	})
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/antivirus"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// ScanUploads scans every file of multipart requests with antivirus.ScanReader
+// before the handler sees them. Infected files are refused with 422 and
+// recorded in the audit log; when the scanner cannot be reached the request
+// is refused with 503 rather than let an unscanned file through. Use it on
+// routes taking uploads small enough to scan inline; enqueue larger files
+// with antivirus.Enqueue once stored.
+func ScanUploads() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+			return c.Next()
+		}
+		form, err := c.MultipartForm()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid multipart form"})
+		}
+
+		for field, headers := range form.File {
+			for _, header := range headers {
+				file, err := header.Open()
+				if err != nil {
+					return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Failed to read uploaded file"})
+				}
+				verdict, err := antivirus.ScanReader(c.UserContext(), file)
+				file.Close()
+
+				if errors.Is(err, antivirus.ErrTooLarge) {
+					return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+						"error": "File is too large to scan",
+						"field": field,
+					})
+				}
+				if err != nil {
+					GetLogger(c).Error("upload scan failed", "field", field, "filename", header.Filename, "error", err)
+					return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+						"error": "File scanning is unavailable, please retry later",
+					})
+				}
+				if verdict.Infected {
+					auditInfectedUpload(c, field, header.Filename, verdict)
+					return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+						"error": "File was rejected by the virus scanner",
+						"field": field,
+					})
+				}
+			}
+		}
+		return c.Next()
+	}
+}
+
+// auditInfectedUpload records a rejected upload in the audit log
+func auditInfectedUpload(c *fiber.Ctx, field, filename string, verdict antivirus.Verdict) {
+	userID, _ := c.Locals("user_id").(string)
+	if userID == "" {
+		userID = "anonymous"
+	}
+	organizationID, _ := c.Locals("organization_id").(string)
+	GetLogger(c).Warn("infected upload rejected", "field", field, "filename", filename, "signature", verdict.Signature)
+	utils.LogAuditContext(c.UserContext(), userID, "malware_upload_rejected", c.Path(), map[string]interface{}{
+		"organization_id": organizationID,
+		"field":           field,
+		"filename":        filename,
+		"signature":       verdict.Signature,
+		"ip":              ClientIP(c),
+	})
+}
@@ -1,11 +1,14 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/praleedsuvarna/shared-libs/authz"
+	"github.com/praleedsuvarna/shared-libs/utils"
 )
 
 // AuthMiddleware verifies the JWT token
@@ -16,25 +19,60 @@ func AuthMiddleware(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
-	claims := jwt.MapClaims{}
-	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
+	claims, err := parseToken(tokenString)
 	if err != nil {
 		fmt.Println(err)
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
 	}
 
-	userID := claims["user_id"].(string)
-	organizationID := claims["organization_id"].(string)
+	setAuthLocals(c, claims)
+	// c.Locals("user_id", claims["user_id"])
+	return c.Next()
+}
+
+// OptionalAuth sets the user locals like AuthMiddleware when a token is sent,
+// and lets anonymous requests through for handlers (such as GraphQL) that
+// decide access per field. An invalid token is still rejected.
+func OptionalAuth(c *fiber.Ctx) error {
+	tokenString := c.Get("Authorization")
+	if tokenString == "" {
+		return c.Next()
+	}
+
+	claims, err := parseToken(tokenString)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+	}
+
+	setAuthLocals(c, claims)
+	return c.Next()
+}
+
+// setAuthLocals copies the user identity from token claims into locals
+func setAuthLocals(c *fiber.Ctx, claims jwt.MapClaims) {
+	userID, _ := claims["user_id"].(string)
+	organizationID, _ := claims["organization_id"].(string)
 	role, _ := claims["role"].(string)
 
 	// Set user info in context
 	c.Locals("user_id", userID)
 	c.Locals("organization_id", organizationID)
 	c.Locals("role", role)
-	// c.Locals("user_id", claims["user_id"])
-	return c.Next()
+	c.SetUserContext(utils.WithAuditOrganization(c.UserContext(), organizationID))
+	enrichRequestLogger(c, userID, organizationID)
+}
+
+// parseToken validates a JWT and returns its claims. Scoped tokens are
+// rejected; they are only accepted by RequireScope.
+func parseToken(tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	})
+	if err == nil && claims["type"] == utils.ScopedTokenType {
+		err = errors.New("scoped token not accepted here")
+	}
+	return claims, err
 }
 
 // AdminOnly ensures the user has admin role
@@ -42,7 +80,7 @@ func AdminOnly() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Get role from locals (set by AuthRequired middleware)
 		role, ok := c.Locals("role").(string)
-		if !ok || (role != "admin" && role != "super_admin") {
+		if !ok || !authz.HasRole(role, authz.RoleAdmin) {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 				"error": "Admin privileges required",
 			})
@@ -57,7 +95,7 @@ func SuperAdminOnly() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Get role from locals (set by AuthRequired middleware)
 		role, ok := c.Locals("role").(string)
-		if !ok || role != "super_admin" {
+		if !ok || !authz.HasRole(role, authz.RoleSuperAdmin) {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 				"error": "Super admin privileges required",
 			})
@@ -67,6 +105,20 @@ func SuperAdminOnly() fiber.Handler {
 	}
 }
 
+// RequireRole ensures the user holds the given role directly or through inheritance
+func RequireRole(required string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, ok := c.Locals("role").(string)
+		if !ok || !authz.HasRole(role, required) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": fmt.Sprintf("Role %s required", required),
+			})
+		}
+
+		return c.Next()
+	}
+}
+
 // AuthDebugger is a middleware that logs auth token details for debugging
 func AuthDebugger() fiber.Handler {
 	return func(c *fiber.Ctx) error {
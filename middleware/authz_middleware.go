@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/authz"
+)
+
+// Authorize checks the policy engine before calling the handler. Resource may
+// reference route params, e.g. Authorize("update", "experiences/:id").
+func Authorize(action, resource string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("user_id").(string)
+		role, _ := c.Locals("role").(string)
+
+		err := authz.Enforce(c.UserContext(), authz.Subject{UserID: userID, Role: role}, action, expandResource(c, resource))
+		if errors.Is(err, authz.ErrForbidden) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Permission denied",
+			})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to evaluate permissions",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// expandResource replaces ":param" segments with values from the route
+func expandResource(c *fiber.Ctx, resource string) string {
+	segments := strings.Split(resource, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = c.Params(strings.TrimPrefix(segment, ":"))
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CachePolicy describes the Cache-Control header of a response
+type CachePolicy struct {
+	MaxAge               time.Duration // How long clients and shared caches may reuse the response
+	StaleWhileRevalidate time.Duration // Extra time a stale response may be served while revalidating
+	Private              bool          // Only the client may cache, not CDNs or proxies
+	NoCache              bool          // Cache but always revalidate with the ETag before reuse
+	NoStore              bool          // Never cache; overrides every other field
+}
+
+// String renders the policy as a Cache-Control value
+func (p CachePolicy) String() string {
+	if p.NoStore {
+		return "no-store"
+	}
+
+	directives := []string{"public"}
+	if p.Private {
+		directives[0] = "private"
+	}
+	if p.NoCache {
+		directives = append(directives, "no-cache")
+	}
+	directives = append(directives, "max-age="+strconv.Itoa(int(p.MaxAge.Seconds())))
+	if p.StaleWhileRevalidate > 0 {
+		directives = append(directives, "stale-while-revalidate="+strconv.Itoa(int(p.StaleWhileRevalidate.Seconds())))
+	}
+	return strings.Join(directives, ", ")
+}
+
+// SetCachePolicy sets Cache-Control on the response, for controllers whose
+// policy depends on what they return:
+//
+//	middleware.SetCachePolicy(c, middleware.CachePolicy{MaxAge: 5 * time.Minute})
+func SetCachePolicy(c *fiber.Ctx, policy CachePolicy) {
+	c.Set(fiber.HeaderCacheControl, policy.String())
+}
+
+// SetLastModified sets Last-Modified so ETag can answer If-Modified-Since
+func SetLastModified(c *fiber.Ctx, modified time.Time) {
+	c.Set(fiber.HeaderLastModified, modified.UTC().Format(http.TimeFormat))
+}
+
+// CacheControl applies policy to successful GET and HEAD responses of the
+// routes it is mounted on, unless the handler set its own Cache-Control
+func CacheControl(policy CachePolicy) fiber.Handler {
+	value := policy.String()
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+		if isCacheable(c) && len(c.Response().Header.Peek(fiber.HeaderCacheControl)) == 0 {
+			c.Set(fiber.HeaderCacheControl, value)
+		}
+		return err
+	}
+}
+
+// ETag adds a strong ETag computed from the body of successful GET and HEAD
+// responses and answers 304 Not Modified when it matches If-None-Match, or
+// when the handler's Last-Modified satisfies If-Modified-Since. Handlers may
+// set their own ETag, e.g. from a document version, which is kept as is.
+func ETag() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+		if !isCacheable(c) || c.Response().IsBodyStream() {
+			return nil
+		}
+
+		if len(c.Response().Header.Peek(fiber.HeaderETag)) == 0 {
+			sum := sha256.Sum256(c.Response().Body())
+			c.Set(fiber.HeaderETag, `"`+base64.RawURLEncoding.EncodeToString(sum[:16])+`"`)
+		}
+
+		// Fresh compares If-None-Match with ETag, falling back to If-Modified-Since and Last-Modified
+		if c.Fresh() {
+			c.Status(fiber.StatusNotModified)
+			c.Response().ResetBody()
+		}
+		return nil
+	}
+}
+
+// isCacheable reports whether a response may be cached and revalidated
+func isCacheable(c *fiber.Ctx) bool {
+	method := c.Method()
+	return (method == fiber.MethodGet || method == fiber.MethodHead) &&
+		c.Response().StatusCode() == fiber.StatusOK
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// HeaderCaptchaToken carries the captcha token of API requests
+const HeaderCaptchaToken = "X-Captcha-Token"
+
+// RequireCaptcha verifies the captcha token of each request for action (e.g.
+// "login" or "register") with utils.VerifyCaptcha, answering 403 when it is
+// missing or rejected and 503 when the provider cannot be reached. The token
+// is read from the X-Captcha-Token header, the widget's form field or a
+// "captcha_token" JSON field. Without CAPTCHA_PROVIDER it lets every request
+// through, so development environments need no captcha keys.
+func RequireCaptcha(action string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if utils.CaptchaProvider() == "" {
+			return c.Next()
+		}
+
+		_, err := utils.VerifyCaptcha(c.UserContext(), captchaToken(c), action)
+		switch {
+		case err == nil:
+			return c.Next()
+		case errors.Is(err, utils.ErrCaptchaRequired), errors.Is(err, utils.ErrCaptchaFailed):
+			GetLogger(c).Warn("captcha rejected", "action", action, "error", err.Error())
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":   "Captcha verification failed",
+				"captcha": true,
+			})
+		default:
+			GetLogger(c).Error("captcha verification unavailable", "action", action, "error", err.Error())
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Unable to verify captcha, please retry later",
+			})
+		}
+	}
+}
+
+// CaptchaChallenge reports whether a request carries a captcha token valid
+// for action; use it as AbuseOptions.Challenge
+func CaptchaChallenge(action string) func(c *fiber.Ctx) bool {
+	return func(c *fiber.Ctx) bool {
+		_, err := utils.VerifyCaptcha(c.UserContext(), captchaToken(c), action)
+		return err == nil
+	}
+}
+
+// captchaToken finds the token in the header, the form fields the reCAPTCHA
+// and hCaptcha widgets submit, or the JSON body
+func captchaToken(c *fiber.Ctx) string {
+	if token := c.Get(HeaderCaptchaToken); token != "" {
+		return token
+	}
+	for _, field := range []string{"g-recaptcha-response", "h-captcha-response"} {
+		if token := c.FormValue(field); token != "" {
+			return token
+		}
+	}
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEApplicationJSON) {
+		var body struct {
+			CaptchaToken string `json:"captcha_token"`
+		}
+		if json.Unmarshal(c.Body(), &body) == nil {
+			return body.CaptchaToken
+		}
+	}
+	return ""
+}
@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"net/netip"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// HeaderForwarded is the standard forwarding header (RFC 7239)
+const HeaderForwarded = "Forwarded"
+
+// clientIPLocalsKey is the Locals key of the client IP derived by RealIP
+const clientIPLocalsKey = "client_ip"
+
+// proxyPresets are names usable in TRUSTED_PROXIES in place of CIDRs
+var proxyPresets = map[string][]netip.Prefix{
+	"loopback": {netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")},
+	"private": {
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("172.16.0.0/12"),
+		netip.MustParsePrefix("192.168.0.0/16"),
+		netip.MustParsePrefix("fc00::/7"),
+	},
+}
+
+// ProxyOptions declares the proxies in front of a service and how they report
+// the client address
+type ProxyOptions struct {
+	// TrustedProxies are the networks of the load balancers and proxies whose
+	// forwarding headers are believed. Headers from anyone else are ignored.
+	TrustedProxies []netip.Prefix
+	// Header carries the client address: "Forwarded", "X-Forwarded-For", or a
+	// header the edge sets to the client address alone, such as "X-Real-IP"
+	// or "CF-Connecting-IP". Empty means X-Forwarded-For; Forwarded is only
+	// read when chosen here, as proxies that set one rarely strip the other.
+	Header string
+}
+
+// Trusts reports whether ip belongs to a trusted proxy
+func (o ProxyOptions) Trusts(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	for _, prefix := range o.TrustedProxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	proxyOptions   *ProxyOptions
+	proxyOptionsMu sync.RWMutex
+)
+
+// ProxyOptionsFromEnv reads TRUSTED_PROXIES, comma-separated CIDRs or the
+// presets "loopback" and "private", and CLIENT_IP_HEADER
+func ProxyOptionsFromEnv() ProxyOptions {
+	var (
+		prefixes []netip.Prefix
+		cidrs    []string
+	)
+	for _, entry := range strings.Split(config.GetEnv("TRUSTED_PROXIES", ""), ",") {
+		entry = strings.TrimSpace(entry)
+		if preset, ok := proxyPresets[strings.ToLower(entry)]; ok {
+			prefixes = append(prefixes, preset...)
+			continue
+		}
+		cidrs = append(cidrs, entry)
+	}
+	prefixes = append(prefixes, utils.ParsePrefixList(strings.Join(cidrs, ","))...)
+
+	return ProxyOptions{TrustedProxies: prefixes, Header: config.GetEnv("CLIENT_IP_HEADER", "")}
+}
+
+// ConfigureProxies replaces the proxy options, which otherwise come from
+// ProxyOptionsFromEnv
+func ConfigureProxies(options ProxyOptions) {
+	proxyOptionsMu.Lock()
+	defer proxyOptionsMu.Unlock()
+	proxyOptions = &options
+}
+
+func currentProxyOptions() ProxyOptions {
+	proxyOptionsMu.RLock()
+	options := proxyOptions
+	proxyOptionsMu.RUnlock()
+	if options != nil {
+		return *options
+	}
+
+	proxyOptionsMu.Lock()
+	defer proxyOptionsMu.Unlock()
+	if proxyOptions == nil {
+		loaded := ProxyOptionsFromEnv()
+		proxyOptions = &loaded
+	}
+	return *proxyOptions
+}
+
+// RealIP derives the client IP once per request and stores it for ClientIP
+// and, through the user context, for utils.LogAuditContext. The app package
+// installs it ahead of rate limiting, GeoIP and logging.
+func RealIP() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ip := resolveClientIP(c, currentProxyOptions())
+		c.Locals(clientIPLocalsKey, ip)
+		c.SetUserContext(utils.WithClientIP(c.UserContext(), ip))
+		return c.Next()
+	}
+}
+
+// ClientIP returns the address of the client that made the request.
+// Forwarding headers are only honoured when the connection comes from a
+// trusted proxy (see ProxyOptions); their hops are then walked from the
+// right, skipping trusted proxies, so clients cannot spoof them.
+func ClientIP(c *fiber.Ctx) string {
+	if ip, ok := c.Locals(clientIPLocalsKey).(string); ok && ip != "" {
+		return ip
+	}
+	return resolveClientIP(c, currentProxyOptions())
+}
+
+func resolveClientIP(c *fiber.Ctx, options ProxyOptions) string {
+	remote, ok := netip.AddrFromSlice(c.Context().RemoteIP())
+	if !ok {
+		return c.Context().RemoteIP().String()
+	}
+	remote = remote.Unmap()
+	if !options.Trusts(remote) {
+		return remote.String()
+	}
+
+	var hops []string
+	switch header := options.Header; {
+	case strings.EqualFold(header, HeaderForwarded):
+		hops = forwardedFor(headerValues(c, HeaderForwarded))
+	case header == "", strings.EqualFold(header, fiber.HeaderXForwardedFor):
+		hops = headerValues(c, fiber.HeaderXForwardedFor)
+	default:
+		// Set by the edge to the client address alone
+		if ip, ok := parseHop(c.Get(header)); ok {
+			return ip.String()
+		}
+		return remote.String()
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		if strings.TrimSpace(hops[i]) == "" {
+			continue
+		}
+		ip, ok := parseHop(hops[i])
+		if !ok {
+			// Obfuscated or malformed; nothing left of it can be believed
+			break
+		}
+		remote = ip
+		if !options.Trusts(ip) {
+			break
+		}
+	}
+	return remote.String()
+}
+
+// headerValues returns the comma-separated elements of every occurrence of a header
+func headerValues(c *fiber.Ctx, name string) []string {
+	var values []string
+	c.Request().Header.VisitAll(func(key, value []byte) {
+		if strings.EqualFold(string(key), name) {
+			values = append(values, strings.Split(string(value), ",")...)
+		}
+	})
+	return values
+}
+
+// forwardedFor extracts the for= parameter of each Forwarded element, e.g.
+// `for=192.0.2.60;proto=https` or `for="[2001:db8::17]:4711"`
+func forwardedFor(elements []string) []string {
+	hops := make([]string, 0, len(elements))
+	for _, element := range elements {
+		hop := "unknown"
+		for _, pair := range strings.Split(element, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if ok && strings.EqualFold(key, "for") {
+				hop = strings.Trim(value, `"`)
+			}
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// parseHop parses an address with an optional port, as found in forwarding
+// headers: "192.0.2.60", "192.0.2.60:4711", "[2001:db8::17]:4711"
+func parseHop(hop string) (netip.Addr, bool) {
+	hop = strings.TrimSpace(hop)
+	if addrPort, err := netip.ParseAddrPort(hop); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(hop, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/domains"
+)
+
+// HostOrganizationLocalsKey is the fiber.Ctx locals key holding the
+// organization whose verified custom domain the request was sent to
+const HostOrganizationLocalsKey = "host_organization_id"
+
+// CustomDomain maps the Host header to the organization owning it as a
+// verified custom domain, for white-label frontends that load branding or
+// sign-in options before the user is authenticated. Requests to other hosts
+// pass through without the local.
+func CustomDomain() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if organizationID, ok := domains.Resolve(c.UserContext(), c.Hostname()); ok {
+			c.Locals(HostOrganizationLocalsKey, organizationID)
+		}
+		return c.Next()
+	}
+}
+
+// HostOrganization returns the organization resolved by CustomDomain, or ""
+func HostOrganization(c *fiber.Ctx) string {
+	organizationID, _ := c.Locals(HostOrganizationLocalsKey).(string)
+	return organizationID
+}
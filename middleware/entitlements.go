@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/billing"
+	"github.com/praleedsuvarna/shared-libs/entitlements"
+)
+
+// RequirePlan ensures the caller's organization subscribes to plan or a
+// higher tier, answering 402 Payment Required otherwise. Use it after
+// AuthMiddleware.
+func RequirePlan(plan string) fiber.Handler {
+	return requireEntitlement(func(granted *billing.Entitlements) bool {
+		return granted.Includes(plan)
+	}, fiber.Map{
+		"error":         fmt.Sprintf("The %s plan is required", plan),
+		"required_plan": plan,
+	})
+}
+
+// RequireFeature ensures the plan of the caller's organization includes
+// feature, answering 402 Payment Required otherwise. Use it after
+// AuthMiddleware.
+func RequireFeature(feature string) fiber.Handler {
+	return requireEntitlement(func(granted *billing.Entitlements) bool {
+		return granted.Has(feature)
+	}, fiber.Map{
+		"error":            fmt.Sprintf("Your plan does not include %s", feature),
+		"required_feature": feature,
+	})
+}
+
+// requireEntitlement rejects requests whose organization's entitlements fail allowed
+func requireEntitlement(allowed func(*billing.Entitlements) bool, denied fiber.Map) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		organizationID, _ := c.Locals("organization_id").(string)
+		if organizationID == "" {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Organization membership required",
+			})
+		}
+
+		granted, err := entitlements.ForOrganization(c.UserContext(), organizationID)
+		if err != nil {
+			GetLogger(c).Error("failed to resolve entitlements", "error", err)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Unable to verify your plan, please retry later",
+			})
+		}
+		if !allowed(granted) {
+			return c.Status(fiber.StatusPaymentRequired).JSON(denied)
+		}
+
+		return c.Next()
+	}
+}
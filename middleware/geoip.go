@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// GeoLocationLocalsKey is the fiber.Ctx locals key holding the request's *utils.GeoLocation
+const GeoLocationLocalsKey = "geo"
+
+// GeoIP resolves the client IP to a country and city and stores the result in
+// the fiber locals, the user context (for utils.LogAuditContext) and the
+// request logger. The client IP is recorded even when no database is loaded.
+func GeoIP() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ip := ClientIP(c)
+
+		location, err := utils.LookupGeoIP(ip)
+		if err != nil {
+			GetLogger(c).Debug("geoip lookup failed", "ip", ip, "error", err.Error())
+		}
+		if location == nil {
+			location = &utils.GeoLocation{IP: ip}
+		}
+
+		c.Locals(GeoLocationLocalsKey, location)
+		c.SetUserContext(utils.WithGeoLocation(c.UserContext(), location))
+		if location.Country != "" {
+			setRequestLogger(c, GetLogger(c).With(slog.String("country", location.Country)))
+		}
+
+		return c.Next()
+	}
+}
+
+// GetGeoLocation returns the location resolved by GeoIP, or nil
+func GetGeoLocation(c *fiber.Ctx) *utils.GeoLocation {
+	location, _ := c.Locals(GeoLocationLocalsKey).(*utils.GeoLocation)
+	return location
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// IPFilter rejects requests whose client IP is not permitted for scope, e.g.
+// IPFilter("admin") on admin groups to restrict them to office/VPN ranges.
+// Blocked requests are recorded in the audit log.
+func IPFilter(scope string) fiber.Handler {
+	return ipFilter(scope, utils.CheckIP)
+}
+
+// IPAllowlist is IPFilter for scopes closed by default: requests are rejected
+// until an allow rule for scope (e.g. IP_ALLOWLIST_DEBUG) admits them
+func IPAllowlist(scope string) fiber.Handler {
+	return ipFilter(scope, utils.CheckIPAllowlisted)
+}
+
+func ipFilter(scope string, check func(ip, scope string) (bool, error)) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ip := ClientIP(c)
+
+		allowed, err := check(ip, scope)
+		if err != nil {
+			GetLogger(c).Error("ip rule check failed", "ip", ip, "error", err.Error())
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to evaluate access rules",
+			})
+		}
+		if allowed {
+			return c.Next()
+		}
+
+		userID, _ := c.Locals("user_id").(string)
+		if userID == "" {
+			userID = "anonymous"
+		}
+		utils.LogAuditContext(c.UserContext(), userID, "ip_blocked", c.Path(), map[string]interface{}{
+			"ip":     ip,
+			"scope":  scope,
+			"method": c.Method(),
+		})
+
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access from this network is not allowed",
+		})
+	}
+}
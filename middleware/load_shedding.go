@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Paths that are never shed, so probes keep reporting during a spike
+var loadSheddingExemptPaths = []string{"/healthz", "/readyz", "/metrics"}
+
+var httpRequestsShed = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "http_requests_shed_total",
+	Help: "Total number of HTTP requests rejected because too many were in flight.",
+})
+
+// LoadSheddingOptions configures LoadShedding
+type LoadSheddingOptions struct {
+	MaxInFlight int           // Concurrent requests served before rejecting; zero disables shedding
+	RetryAfter  time.Duration // Sent in Retry-After, default 1s
+	ExemptPaths []string      // Extra path prefixes that are never shed
+}
+
+// LoadShedding rejects requests with 503 and Retry-After while MaxInFlight
+// requests are already being served, so a traffic spike degrades into fast
+// failures instead of timeouts for everyone. Health and metrics endpoints are
+// exempt and not counted.
+func LoadShedding(options LoadSheddingOptions) fiber.Handler {
+	if options.MaxInFlight <= 0 {
+		return func(c *fiber.Ctx) error { return c.Next() }
+	}
+	if options.RetryAfter <= 0 {
+		options.RetryAfter = time.Second
+	}
+	retryAfter := strconv.Itoa(int(math.Ceil(options.RetryAfter.Seconds())))
+	exempt := append(append([]string{}, loadSheddingExemptPaths...), options.ExemptPaths...)
+	limit := int64(options.MaxInFlight)
+	var inFlight atomic.Int64
+
+	return func(c *fiber.Ctx) error {
+		for _, prefix := range exempt {
+			if strings.HasPrefix(c.Path(), prefix) {
+				return c.Next()
+			}
+		}
+
+		if inFlight.Add(1) > limit {
+			inFlight.Add(-1)
+			httpRequestsShed.Inc()
+			GetLogger(c).Warn("request shed", "in_flight_limit", limit)
+			c.Set(fiber.HeaderRetryAfter, retryAfter)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Service is overloaded, please retry later",
+			})
+		}
+		defer inFlight.Add(-1)
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/authz"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// Paths that stay reachable during maintenance so probes and the toggle keep working
+var maintenanceExemptPaths = []string{"/healthz", "/readyz", "/metrics", "/admin/maintenance"}
+
+// Maintenance returns 503 with Retry-After to all but super admin traffic
+// while maintenance mode is on. Super admins are recognised from their bearer
+// token so they can verify a deployment before reopening it; organization
+// admins are held back like everyone else. Extra exempt path prefixes may be
+// given.
+func Maintenance(exemptPaths ...string) fiber.Handler {
+	exempt := append(append([]string{}, maintenanceExemptPaths...), exemptPaths...)
+
+	return func(c *fiber.Ctx) error {
+		state := utils.GetMaintenanceState()
+		if !state.Enabled {
+			return c.Next()
+		}
+
+		for _, prefix := range exempt {
+			if strings.HasPrefix(c.Path(), prefix) {
+				return c.Next()
+			}
+		}
+
+		if claims, err := parseToken(c.Get("Authorization")); err == nil {
+			if role, _ := claims["role"].(string); authz.HasRole(role, authz.RoleSuperAdmin) {
+				return c.Next()
+			}
+		}
+
+		message := state.Message
+		if message == "" {
+			message = "Service is undergoing maintenance"
+		}
+
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(state.RetryAfter))
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": message,
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+)
+
+var httpRequestsRateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "http_requests_rate_limited_total",
+	Help: "Total number of HTTP requests rejected by a per-route rate limit.",
+}, []string{"route"})
+
+// rateLimitIdleTimeout is how long an unused caller bucket is kept
+const rateLimitIdleTimeout = 10 * time.Minute
+
+// RateLimitOptions configures RateLimit
+type RateLimitOptions struct {
+	Requests int           // Requests allowed per Per; zero disables the limit
+	Per      time.Duration // Default one minute
+	Burst    int           // Default Requests
+	// Key identifies the caller; the default is the user ID, or the client IP
+	// for anonymous requests
+	Key func(c *fiber.Ctx) string
+}
+
+// RateLimit limits each caller to Requests per Per on the routes it is mounted
+// on, answering 429 with Retry-After when exceeded. Every response carries the
+// caller's RateLimit-* headers (see RateLimitStatus). Mount it after the auth
+// middleware so callers are keyed by user. Limits are per instance.
+func RateLimit(options RateLimitOptions) fiber.Handler {
+	if options.Requests <= 0 {
+		return func(c *fiber.Ctx) error { return c.Next() }
+	}
+	if options.Per <= 0 {
+		options.Per = time.Minute
+	}
+	if options.Burst <= 0 {
+		options.Burst = options.Requests
+	}
+	if options.Key == nil {
+		options.Key = rateLimitKey
+	}
+
+	limit := rate.Limit(float64(options.Requests) / options.Per.Seconds())
+	var (
+		limiters  = map[string]*callerLimiter{}
+		lastPrune time.Time
+		mu        sync.Mutex
+	)
+
+	window := int(options.Per.Seconds())
+
+	// reserve takes a token for key and returns the caller's status, and how
+	// long to wait when no token was available
+	reserve := func(key string) (RateLimitStatus, time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		now := time.Now()
+		if now.Sub(lastPrune) > rateLimitIdleTimeout {
+			for k, caller := range limiters {
+				if now.Sub(caller.lastSeen) > rateLimitIdleTimeout {
+					delete(limiters, k)
+				}
+			}
+			lastPrune = now
+		}
+
+		caller, ok := limiters[key]
+		if !ok {
+			caller = &callerLimiter{limiter: rate.NewLimiter(limit, options.Burst)}
+			limiters[key] = caller
+		}
+		caller.lastSeen = now
+		allowed := caller.limiter.AllowN(now, 1)
+
+		tokens := caller.limiter.TokensAt(now)
+		status := RateLimitStatus{
+			Limit:     options.Requests,
+			Remaining: int(math.Floor(tokens)),
+			Reset:     int(math.Ceil((float64(options.Burst) - tokens) / float64(limit))),
+			Window:    window,
+		}
+		if allowed {
+			return status, 0
+		}
+		// Time until the next token is available
+		return status, time.Duration(float64(time.Second) / float64(limit))
+	}
+
+	return func(c *fiber.Ctx) error {
+		status, wait := reserve(options.Key(c))
+		SetRateLimitHeaders(c, status)
+		if wait > 0 {
+			route := c.Route().Path
+			httpRequestsRateLimited.WithLabelValues(route).Inc()
+			GetLogger(c).Warn("request rate limited", "route", route)
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "Too many requests, please retry later",
+			})
+		}
+		return c.Next()
+	}
+}
+
+type callerLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimitKey charges the user, or the client IP when anonymous
+func rateLimitKey(c *fiber.Ctx) string {
+	if userID, ok := c.Locals("user_id").(string); ok && userID != "" {
+		return "user:" + userID
+	}
+	return "ip:" + ClientIP(c)
+}
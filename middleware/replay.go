@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// ReplayOptions configures ReplayProtection
+type ReplayOptions struct {
+	MaxSkew time.Duration // Default REPLAY_MAX_SKEW, or 5m
+	// Key identifies the caller nonces belong to; the default is the user
+	// after AuthMiddleware, otherwise the client IP
+	Key func(c *fiber.Ctx) string
+}
+
+// ReplayProtection rejects requests that are resubmitted or stale, for
+// sensitive endpoints such as password changes and payment confirmations.
+// Clients send a fresh random nonce and the current time with every request:
+//
+//	X-Request-Nonce: 3f9c2a7e1b8d4c6f9a0e5d2b7c4a1f8e
+//	X-Request-Timestamp: 1767225600
+//
+//	users.Post("/password", middleware.ReplayProtection(middleware.ReplayOptions{}), ChangePassword)
+//
+// A nonce is used up once accepted, even when the handler then fails, so
+// retries need a new one. Stale responses carry the server time so clients
+// with a skewed clock can correct it.
+//
+// The nonce and timestamp are not bound to the request, so this is
+// idempotency protection: it stops a client, proxy or retry loop from
+// submitting the same request twice, not an attacker who captured a request
+// and sends it again with a new nonce. Callers that hold a shared key should
+// use VerifySignedRequest, whose signature covers the nonce, timestamp and body.
+func ReplayProtection(options ReplayOptions) fiber.Handler {
+	if options.Key == nil {
+		options.Key = func(c *fiber.Ctx) string {
+			if userID, ok := c.Locals("user_id").(string); ok && userID != "" {
+				return "user:" + userID
+			}
+			return "ip:" + ClientIP(c)
+		}
+	}
+
+	return func(c *fiber.Ctx) error {
+		maxSkew := options.MaxSkew
+		if maxSkew <= 0 {
+			maxSkew = utils.ReplayMaxSkew()
+		}
+
+		err := utils.VerifyRequestFreshness(c.UserContext(), options.Key(c),
+			c.Get(utils.HeaderRequestNonce), c.Get(utils.HeaderRequestTimestamp), maxSkew)
+		switch {
+		case err == nil:
+			return c.Next()
+		case errors.Is(err, utils.ErrReplayHeadersMissing):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request nonce and timestamp are required"})
+		case errors.Is(err, utils.ErrInvalidRequestNonce):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request nonce"})
+		case errors.Is(err, utils.ErrStaleRequest):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":       "Request timestamp is too far from the server time",
+				"server_time": utils.Now().Unix(),
+			})
+		case errors.Is(err, utils.ErrRequestReplayed):
+			GetLogger(c).Warn("replayed request rejected", "path", c.Path(), "ip", ClientIP(c))
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Request already processed"})
+		default:
+			GetLogger(c).Error("replay check failed", "error", err.Error())
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Failed to verify request, please retry",
+			})
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// LoggerLocalsKey is the fiber.Ctx locals key holding the request-scoped logger
+const LoggerLocalsKey = "logger"
+
+// RequestLogger attaches a request-scoped logger carrying the request ID to the
+// fiber locals and the user context. Run it after the requestid middleware.
+func RequestLogger() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		logger := utils.Logger
+		if requestID, ok := c.Locals("requestid").(string); ok && requestID != "" {
+			logger = logger.With(slog.String("request_id", requestID))
+		}
+
+		setRequestLogger(c, logger)
+		return c.Next()
+	}
+}
+
+// GetLogger returns the request-scoped logger for a fiber context
+func GetLogger(c *fiber.Ctx) *slog.Logger {
+	if logger, ok := c.Locals(LoggerLocalsKey).(*slog.Logger); ok {
+		return logger
+	}
+	return utils.Log(c.UserContext())
+}
+
+// enrichRequestLogger adds authenticated user fields to the request-scoped logger
+func enrichRequestLogger(c *fiber.Ctx, userID, organizationID string) {
+	logger := GetLogger(c).With(
+		slog.String("user_id", userID),
+		slog.String("org_id", organizationID),
+	)
+	setRequestLogger(c, logger)
+}
+
+func setRequestLogger(c *fiber.Ctx, logger *slog.Logger) {
+	c.Locals(LoggerLocalsKey, logger)
+	c.SetUserContext(utils.WithLogger(c.UserContext(), logger))
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// VerifySignedRequest authenticates internal service-to-service calls signed
+// with utils.SignRequest (or a utils.NewSigningClient) instead of a JWT:
+//
+//	keys, err := utils.RequestSigningKeys()
+//	internal := app.Group("/internal", middleware.VerifySignedRequest(keys))
+//
+// Each nonce is accepted once within the replay window. The signing key ID is
+// stored in the "service_id" local.
+func VerifySignedRequest(keys map[string][]byte) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		header := func(name string) string { return c.Get(name) }
+		keyID, err := utils.VerifyRequestSignature(keys, c.Method(), c.OriginalURL(), header, c.Body())
+		if err == nil {
+			err = utils.ClaimRequestNonce(c.UserContext(), keyID, c.Get(utils.HeaderSignatureNonce))
+		}
+
+		switch {
+		case err == nil:
+			c.Locals("service_id", keyID)
+			return c.Next()
+		case errors.Is(err, utils.ErrRequestNotSigned):
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Request signature required"})
+		case errors.Is(err, utils.ErrRequestReplayed):
+			GetLogger(c).Warn("replayed signed request", "key_id", keyID)
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Request already processed"})
+		case errors.Is(err, utils.ErrUnknownSigningKey),
+			errors.Is(err, utils.ErrSignatureExpired),
+			errors.Is(err, utils.ErrBodyHashMismatch),
+			errors.Is(err, utils.ErrInvalidRequestSignature):
+			GetLogger(c).Warn("rejected signed request", "error", err.Error())
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid request signature"})
+		default:
+			GetLogger(c).Error("request signature check failed", "error", err.Error())
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to verify request signature",
+			})
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// FieldError describes one schema violation in a request body
+type FieldError struct {
+	Field   string `json:"field"` // Dotted path of the offending value, empty for the body itself
+	Message string `json:"message"`
+}
+
+var (
+	schemaCompiler = newSchemaCompiler()
+	schemas        = map[string]*jsonschema.Schema{}
+	schemasMu      sync.RWMutex
+
+	schemaPrinter = message.NewPrinter(language.English)
+)
+
+func newSchemaCompiler() *jsonschema.Compiler {
+	compiler := jsonschema.NewCompiler()
+	compiler.DefaultDraft(jsonschema.Draft2020)
+	compiler.AssertFormat()
+	return compiler
+}
+
+// RegisterSchema compiles a JSON Schema (draft 2020-12 unless it declares
+// another $schema) under name for use with ValidateBody. Other registered
+// schemas can be referenced as {"$ref": "<name>"}; register them first.
+func RegisterSchema(name string, schema []byte) error {
+	document, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+	if err != nil {
+		return fmt.Errorf("parse schema %s: %w", name, err)
+	}
+
+	schemasMu.Lock()
+	defer schemasMu.Unlock()
+
+	if err := schemaCompiler.AddResource(name, document); err != nil {
+		return fmt.Errorf("add schema %s: %w", name, err)
+	}
+	compiled, err := schemaCompiler.Compile(name)
+	if err != nil {
+		return fmt.Errorf("compile schema %s: %w", name, err)
+	}
+	schemas[name] = compiled
+	return nil
+}
+
+// MustRegisterSchema is RegisterSchema for schemas embedded at build time; it panics on error
+func MustRegisterSchema(name string, schema []byte) {
+	if err := RegisterSchema(name, schema); err != nil {
+		panic(err)
+	}
+}
+
+// SchemaOption customizes ValidateBody
+type SchemaOption func(*schemaConfig)
+
+type schemaConfig struct {
+	maxBytes int
+}
+
+// WithMaxBodySize rejects bodies larger than maxBytes with 413
+func WithMaxBodySize(maxBytes int) SchemaOption {
+	return func(config *schemaConfig) { config.maxBytes = maxBytes }
+}
+
+// ValidateBody rejects requests whose JSON body does not match the registered
+// schema name, before the handler runs:
+//
+//	middleware.MustRegisterSchema("create-experience", createExperienceSchema)
+//	api.Post("/experiences", middleware.ValidateBody("create-experience", middleware.WithMaxBodySize(64<<10)), handler)
+//
+// Malformed JSON gets a 400, and schema violations a 422 listing every
+// offending field.
+func ValidateBody(name string, options ...SchemaOption) fiber.Handler {
+	config := schemaConfig{}
+	for _, option := range options {
+		option(&config)
+	}
+
+	return func(c *fiber.Ctx) error {
+		schemasMu.RLock()
+		schema, ok := schemas[name]
+		schemasMu.RUnlock()
+		if !ok {
+			GetLogger(c).Error("schema not registered", "schema", name)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Request validation is misconfigured",
+			})
+		}
+
+		body := c.Body()
+		if config.maxBytes > 0 && len(body) > config.maxBytes {
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+				"error": fmt.Sprintf("Request body exceeds %d bytes", config.maxBytes),
+			})
+		}
+
+		instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid JSON body",
+			})
+		}
+
+		if err := schema.Validate(instance); err != nil {
+			var validationErr *jsonschema.ValidationError
+			if !errors.As(err, &validationErr) {
+				return err
+			}
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error":  "Request body failed validation",
+				"fields": fieldErrors(validationErr),
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// fieldErrors flattens a validation error tree into its leaf violations
+func fieldErrors(err *jsonschema.ValidationError) []FieldError {
+	if len(err.Causes) == 0 {
+		return []FieldError{{
+			Field:   strings.Join(err.InstanceLocation, "."),
+			Message: err.ErrorKind.LocalizedString(schemaPrinter),
+		}}
+	}
+
+	fields := []FieldError{}
+	for _, cause := range err.Causes {
+		fields = append(fields, fieldErrors(cause)...)
+	}
+	return fields
+}
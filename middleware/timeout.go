@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Timeout enforces a deadline on handler execution. The deadline is carried by
+// c.UserContext(), so Mongo and HTTP calls made with that context are cancelled
+// when it passes, and the client receives a 504 instead of whatever the handler
+// produced.
+//
+// Override keys are route patterns, optionally prefixed with a method:
+// "/reports/export", "POST /uploads/*" or "GET /experiences/:id/render".
+// ":param" matches one path segment and a trailing "*" matches the rest. A zero
+// override disables the deadline for that route.
+func Timeout(defaultTimeout time.Duration, overrides map[string]time.Duration) fiber.Handler {
+	rules := make([]timeoutRule, 0, len(overrides))
+	for key, timeout := range overrides {
+		rule := timeoutRule{pattern: key, timeout: timeout}
+		if method, pattern, ok := strings.Cut(key, " "); ok {
+			rule.method = strings.ToUpper(method)
+			rule.pattern = strings.TrimSpace(pattern)
+		}
+		rules = append(rules, rule)
+	}
+
+	// Check method-specific and longer patterns first so overlapping keys resolve predictably
+	sort.Slice(rules, func(i, j int) bool {
+		if (rules[i].method != "") != (rules[j].method != "") {
+			return rules[i].method != ""
+		}
+		if len(rules[i].pattern) != len(rules[j].pattern) {
+			return len(rules[i].pattern) > len(rules[j].pattern)
+		}
+		return rules[i].pattern < rules[j].pattern
+	})
+
+	return func(c *fiber.Ctx) error {
+		timeout := defaultTimeout
+		for _, rule := range rules {
+			if rule.matches(c.Method(), c.Path()) {
+				timeout = rule.timeout
+				break
+			}
+		}
+		if timeout <= 0 {
+			return c.Next()
+		}
+
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			GetLogger(c).Warn("request timed out", "timeout", timeout.String())
+			return c.Status(fiber.StatusGatewayTimeout).JSON(fiber.Map{
+				"error": "Request timed out",
+			})
+		}
+		return err
+	}
+}
+
+// timeoutRule is a parsed Timeout override
+type timeoutRule struct {
+	method  string
+	pattern string
+	timeout time.Duration
+}
+
+// matches reports whether the rule applies to a request
+func (r timeoutRule) matches(method, path string) bool {
+	if r.method != "" && r.method != method {
+		return false
+	}
+	return routeMatches(r.pattern, path)
+}
+
+// routeMatches compares a route pattern with ":param" and trailing "*" segments to a path
+func routeMatches(pattern, path string) bool {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+
+	for i, segment := range patternSegments {
+		if segment == "*" && i == len(patternSegments)-1 {
+			return true
+		}
+		if i >= len(pathSegments) {
+			return false
+		}
+		if strings.HasPrefix(segment, ":") {
+			continue
+		}
+		if segment != pathSegments[i] {
+			return false
+		}
+	}
+	return len(patternSegments) == len(pathSegments)
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/billing"
+	"github.com/praleedsuvarna/shared-libs/entitlements"
+)
+
+// Headers describing rate limits (IETF RateLimit header fields draft) and plan
+// quotas. Browsers only let frontends read them when CORS exposes them; the
+// app package exposes UsageHeaders.
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"     // Requests allowed per window
+	HeaderRateLimitRemaining = "RateLimit-Remaining" // Requests left right now
+	HeaderRateLimitReset     = "RateLimit-Reset"     // Seconds until the full limit is available again
+	HeaderRateLimitPolicy    = "RateLimit-Policy"    // "<requests>;w=<window seconds>"
+
+	HeaderQuotaName      = "X-Quota-Name"
+	HeaderQuotaLimit     = "X-Quota-Limit" // A number, or "unlimited"
+	HeaderQuotaUsed      = "X-Quota-Used"
+	HeaderQuotaRemaining = "X-Quota-Remaining" // Absent when unlimited
+	HeaderQuotaReset     = "X-Quota-Reset"     // RFC 3339 time the usage resets, absent when it does not
+)
+
+// UsageHeaders lists the rate limit and quota headers, for CORS ExposeHeaders
+var UsageHeaders = []string{
+	HeaderRateLimitLimit, HeaderRateLimitRemaining, HeaderRateLimitReset, HeaderRateLimitPolicy,
+	HeaderQuotaName, HeaderQuotaLimit, HeaderQuotaUsed, HeaderQuotaRemaining, HeaderQuotaReset,
+}
+
+// RateLimitStatus is the state of a caller's rate limit. Responses of routes
+// behind RateLimit carry it as RateLimit-* headers.
+type RateLimitStatus struct {
+	Limit     int `json:"limit"`     // Requests allowed per Window
+	Remaining int `json:"remaining"` // Requests that can be made right now
+	Reset     int `json:"reset"`     // Seconds until the full limit is available again
+	Window    int `json:"window"`    // Seconds
+}
+
+// QuotaStatus is the usage of a plan quota, for usage meters: render
+// Used out of Limit, or Used alone when Unlimited
+type QuotaStatus struct {
+	Name      string     `json:"name"`
+	Limit     int64      `json:"limit"` // billing.Unlimited (-1) when the plan does not cap it
+	Used      int64      `json:"used"`
+	Remaining int64      `json:"remaining"` // Zero when exhausted; -1 when unlimited
+	Unlimited bool       `json:"unlimited"`
+	Reset     *time.Time `json:"reset,omitempty"` // When usage starts again from zero, e.g. the billing period end
+}
+
+// Exceeded reports whether usage has reached the limit
+func (q QuotaStatus) Exceeded() bool {
+	return !q.Unlimited && q.Used >= q.Limit
+}
+
+// SetRateLimitHeaders adds the RateLimit-* headers for status to the response
+func SetRateLimitHeaders(c *fiber.Ctx, status RateLimitStatus) {
+	c.Set(HeaderRateLimitLimit, strconv.Itoa(status.Limit))
+	c.Set(HeaderRateLimitRemaining, strconv.Itoa(max(status.Remaining, 0)))
+	c.Set(HeaderRateLimitReset, strconv.Itoa(status.Reset))
+	c.Set(HeaderRateLimitPolicy, fmt.Sprintf("%d;w=%d", status.Limit, status.Window))
+}
+
+// SetQuotaHeaders adds the X-Quota-* headers for status to the response. A
+// response describes one quota; report the one the route consumes.
+func SetQuotaHeaders(c *fiber.Ctx, status QuotaStatus) {
+	c.Set(HeaderQuotaName, status.Name)
+	c.Set(HeaderQuotaUsed, strconv.FormatInt(status.Used, 10))
+	if status.Unlimited {
+		c.Set(HeaderQuotaLimit, "unlimited")
+	} else {
+		c.Set(HeaderQuotaLimit, strconv.FormatInt(status.Limit, 10))
+		c.Set(HeaderQuotaRemaining, strconv.FormatInt(status.Remaining, 10))
+	}
+	if status.Reset != nil {
+		c.Set(HeaderQuotaReset, status.Reset.UTC().Format(time.RFC3339))
+	}
+}
+
+// NewQuotaStatus computes the status of a quota from its limit and usage
+func NewQuotaStatus(name string, limit, used int64, reset *time.Time) QuotaStatus {
+	status := QuotaStatus{Name: name, Limit: limit, Used: used, Reset: reset}
+	if limit == billing.Unlimited {
+		status.Unlimited = true
+		status.Remaining = -1
+		return status
+	}
+	status.Remaining = max(limit-used, 0)
+	return status
+}
+
+// Quota resolves a quota of the caller's plan, given the usage counted by the
+// service, and adds its X-Quota-* headers to the response. Use it after
+// AuthMiddleware.
+//
+//	status, err := middleware.Quota(c, "experiences", count, nil)
+//	if err == nil && status.Exceeded() {
+//		return c.Status(http.StatusPaymentRequired).JSON(status)
+//	}
+func Quota(c *fiber.Ctx, name string, used int64, reset *time.Time) (QuotaStatus, error) {
+	organizationID, _ := c.Locals("organization_id").(string)
+	granted, err := entitlements.ForOrganization(c.UserContext(), organizationID)
+	if err != nil {
+		return QuotaStatus{}, err
+	}
+
+	status := NewQuotaStatus(name, granted.Limit(name), used, reset)
+	SetQuotaHeaders(c, status)
+	return status, nil
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/buildinfo"
+)
+
+// HeaderServiceVersion carries the build version on every response
+const HeaderServiceVersion = "X-Service-Version"
+
+// ServiceVersion adds the X-Service-Version header so clients and load
+// balancers can tell which build served a request, e.g. during a rollout
+func ServiceVersion() fiber.Handler {
+	version := buildinfo.Get().Version
+	return func(c *fiber.Ctx) error {
+		c.Set(HeaderServiceVersion, version)
+		return c.Next()
+	}
+}
@@ -0,0 +1,168 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// IndexDiff compares the declared indexes of a collection (see
+// models.RegisterIndexes) with the ones in the database
+type IndexDiff struct {
+	Collection string
+	Missing    []models.IndexSpec // Declared but not in the database
+	Extra      []string           // In the database but not declared
+	Conflicts  []string           // Same keys, different options; drop and recreate by hand
+}
+
+// existingIndex is an entry of listIndexes
+type existingIndex struct {
+	Name                    string `bson:"name"`
+	Key                     bson.D `bson:"key"`
+	Unique                  bool   `bson:"unique"`
+	Sparse                  bool   `bson:"sparse"`
+	ExpireAfterSeconds      *int32 `bson:"expireAfterSeconds"`
+	PartialFilterExpression bson.M `bson:"partialFilterExpression"`
+}
+
+// DiffIndexes compares every collection with declared indexes against the database
+func DiffIndexes(ctx context.Context) ([]IndexDiff, error) {
+	registered := models.RegisteredIndexes()
+	var diffs []IndexDiff
+	for _, collection := range models.IndexedCollections() {
+		diff, err := diffCollectionIndexes(ctx, collection, registered[collection])
+		if err != nil {
+			return diffs, err
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs, nil
+}
+
+func diffCollectionIndexes(ctx context.Context, collection string, declared []models.IndexSpec) (IndexDiff, error) {
+	diff := IndexDiff{Collection: collection}
+
+	cursor, err := config.GetCollection(collection).Indexes().List(ctx)
+	if err != nil {
+		return diff, fmt.Errorf("list indexes of %s: %w", collection, err)
+	}
+	var existing []existingIndex
+	if err := cursor.All(ctx, &existing); err != nil {
+		return diff, fmt.Errorf("list indexes of %s: %w", collection, err)
+	}
+
+	bySignature := map[string]existingIndex{}
+	for _, index := range existing {
+		bySignature[models.KeySignature(index.Key)] = index
+	}
+
+	declaredSignatures := map[string]bool{}
+	for _, spec := range declared {
+		signature := models.KeySignature(spec.Keys)
+		declaredSignatures[signature] = true
+
+		index, ok := bySignature[signature]
+		if !ok {
+			diff.Missing = append(diff.Missing, spec)
+			continue
+		}
+		if !sameIndexOptions(spec, index) {
+			diff.Conflicts = append(diff.Conflicts, index.Name)
+		}
+	}
+
+	for _, index := range existing {
+		if index.Name != "_id_" && !declaredSignatures[models.KeySignature(index.Key)] {
+			diff.Extra = append(diff.Extra, index.Name)
+		}
+	}
+	return diff, nil
+}
+
+// sameIndexOptions reports whether an existing index has the declared options
+func sameIndexOptions(spec models.IndexSpec, index existingIndex) bool {
+	expireAfter := time.Duration(0)
+	if index.ExpireAfterSeconds != nil {
+		expireAfter = time.Duration(*index.ExpireAfterSeconds) * time.Second
+	}
+	return spec.IsUnique == index.Unique &&
+		spec.IsSparse == index.Sparse &&
+		spec.ExpireAfter.Truncate(time.Second) == expireAfter &&
+		samePartialFilter(spec.PartialFilter, index.PartialFilterExpression)
+}
+
+// samePartialFilter compares filters after a BSON round trip, which normalizes
+// number types the way the server returns them
+func samePartialFilter(declared, existing bson.M) bool {
+	if len(declared) == 0 || len(existing) == 0 {
+		return len(declared) == len(existing)
+	}
+	raw, err := bson.Marshal(declared)
+	if err != nil {
+		return false
+	}
+	var normalized bson.M
+	if err := bson.Unmarshal(raw, &normalized); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(normalized, existing)
+}
+
+// EnsureIndexes creates missing declared indexes and logs extra and
+// conflicting ones, which are left for an operator to drop
+func EnsureIndexes(ctx context.Context) ([]IndexDiff, error) {
+	diffs, err := DiffIndexes(ctx)
+	if err != nil {
+		return diffs, err
+	}
+
+	for _, diff := range diffs {
+		if len(diff.Missing) > 0 {
+			indexModels := make([]mongo.IndexModel, len(diff.Missing))
+			for i, spec := range diff.Missing {
+				indexModels[i] = indexModel(spec)
+			}
+			names, err := config.GetCollection(diff.Collection).Indexes().CreateMany(ctx, indexModels)
+			if err != nil {
+				return diffs, fmt.Errorf("create indexes on %s: %w", diff.Collection, err)
+			}
+			log.Printf("🔧 Created indexes on %s: %v", diff.Collection, names)
+		}
+		for _, name := range diff.Extra {
+			log.Printf("⚠️  Index %s on %s is not declared", name, diff.Collection)
+		}
+		for _, name := range diff.Conflicts {
+			log.Printf("⚠️  Index %s on %s differs from its declaration; drop it to recreate", name, diff.Collection)
+		}
+	}
+	return diffs, nil
+}
+
+// indexModel converts a declaration for the driver
+func indexModel(spec models.IndexSpec) mongo.IndexModel {
+	indexOptions := options.Index()
+	if spec.Name != "" {
+		indexOptions.SetName(spec.Name)
+	}
+	if spec.IsUnique {
+		indexOptions.SetUnique(true)
+	}
+	if spec.IsSparse {
+		indexOptions.SetSparse(true)
+	}
+	if spec.ExpireAfter > 0 {
+		indexOptions.SetExpireAfterSeconds(int32(spec.ExpireAfter / time.Second))
+	}
+	if len(spec.PartialFilter) > 0 {
+		indexOptions.SetPartialFilterExpression(spec.PartialFilter)
+	}
+	return mongo.IndexModel{Keys: spec.Keys, Options: indexOptions}
+}
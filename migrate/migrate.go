@@ -0,0 +1,133 @@
+// Package migrate applies one-time database changes, such as index builds or
+// backfills, in order and records which have run:
+//
+//	func init() {
+//		migrate.Register(migrate.Migration{
+//			ID:          "0002_experiences_owner_index",
+//			Description: "Index experiences by owner",
+//			Up: func(ctx context.Context, db *mongo.Database) error {
+//				_, err := db.Collection(config.CollectionName("experiences")).Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "owner_id", Value: 1}}})
+//				return err
+//			},
+//		})
+//	}
+//
+// Name collections with config.CollectionName so COLLECTION_PREFIX applies.
+// Run them with `sharedctl migrate` or Run at deploy time.
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MigrationsCollection records applied migrations
+const MigrationsCollection = "schema_migrations"
+
+// Migration is one versioned change; IDs sort in the order they must run
+type Migration struct {
+	ID          string
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+// Record is stored for every applied migration
+type Record struct {
+	ID          string    `bson:"_id" json:"id"`
+	Description string    `bson:"description" json:"description"`
+	AppliedAt   time.Time `bson:"applied_at" json:"applied_at"`
+}
+
+var (
+	registry   = map[string]Migration{}
+	registryMu sync.RWMutex
+)
+
+// Register adds a migration; registering the same ID twice panics
+func Register(migration Migration) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := registry[migration.ID]; exists {
+		panic(fmt.Sprintf("migrate: duplicate migration %q", migration.ID))
+	}
+	registry[migration.ID] = migration
+}
+
+// Registered returns every registered migration in ID order
+func Registered() []Migration {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	migrations := make([]Migration, 0, len(registry))
+	for _, migration := range registry {
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].ID < migrations[j].ID })
+	return migrations
+}
+
+// Pending returns the registered migrations that have not been applied
+func Pending(ctx context.Context) ([]Migration, error) {
+	cursor, err := config.GetCollection(MigrationsCollection).Find(ctx, bson.M{},
+		options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("list applied migrations: %w", err)
+	}
+	var applied []Record
+	if err := cursor.All(ctx, &applied); err != nil {
+		return nil, fmt.Errorf("list applied migrations: %w", err)
+	}
+
+	done := make(map[string]bool, len(applied))
+	for _, record := range applied {
+		done[record.ID] = true
+	}
+
+	var pending []Migration
+	for _, migration := range Registered() {
+		if !done[migration.ID] {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Run applies pending migrations in order, stopping at the first failure, and
+// returns the IDs that were applied
+func Run(ctx context.Context) ([]string, error) {
+	pending, err := Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	db := config.GetDatabase()
+	collection := config.GetCollection(MigrationsCollection)
+	applied := []string{}
+	for _, migration := range pending {
+		log.Printf("🔧 Applying migration %s: %s", migration.ID, migration.Description)
+		if err := migration.Up(ctx, db); err != nil {
+			return applied, fmt.Errorf("migration %s: %w", migration.ID, err)
+		}
+
+		_, err := collection.InsertOne(ctx, Record{
+			ID:          migration.ID,
+			Description: migration.Description,
+			AppliedAt:   time.Now(),
+		})
+		if err != nil {
+			return applied, fmt.Errorf("record migration %s: %w", migration.ID, err)
+		}
+		applied = append(applied, migration.ID)
+	}
+	return applied, nil
+}
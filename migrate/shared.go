@@ -0,0 +1,50 @@
+package migrate
+
+import (
+	"context"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Migrations for the collections owned by the shared libraries. New indexes
+// are declared on the models instead (see models.RegisterIndexes).
+func init() {
+	Register(Migration{
+		ID:          "0001_shared_indexes",
+		Description: "Index audit logs and dead letters for the admin queries",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			indexes := map[string][]mongo.IndexModel{
+				"oms_audit_logs": {
+					{Keys: bson.D{{Key: "timestamp", Value: -1}}},
+					{Keys: bson.D{{Key: "admin_id", Value: 1}, {Key: "timestamp", Value: -1}}},
+					{Keys: bson.D{{Key: "target_id", Value: 1}, {Key: "timestamp", Value: -1}}},
+				},
+				utils.DeadLettersCollection: {
+					{Keys: bson.D{{Key: "created_at", Value: -1}}},
+					{Keys: bson.D{{Key: "subject", Value: 1}, {Key: "created_at", Value: -1}}},
+				},
+			}
+			for collection, models := range indexes {
+				if _, err := db.Collection(config.CollectionName(collection)).Indexes().CreateMany(ctx, models); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+
+	Register(Migration{
+		ID:          "0002_audit_organization_index",
+		Description: "Index audit logs by organization for org-scoped queries",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection(config.CollectionName("oms_audit_logs")).Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{Keys: bson.D{{Key: "organization_id", Value: 1}, {Key: "timestamp", Value: -1}}},
+				{Keys: bson.D{{Key: "metadata.organization_id", Value: 1}, {Key: "timestamp", Value: -1}}},
+			})
+			return err
+		},
+	})
+}
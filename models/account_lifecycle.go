@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Account lifecycle statuses
+const (
+	AccountDeactivated = "deactivated" // Deactivated, hard deletion scheduled
+	AccountReactivated = "reactivated" // Deletion cancelled during the grace period
+	AccountDeleted     = "deleted"     // Hard deletion completed
+)
+
+// AccountLifecycle tracks a user's deactivation and scheduled hard deletion
+type AccountLifecycle struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID        string             `bson:"user_id" json:"user_id"`
+	Email         string             `bson:"email,omitempty" json:"email,omitempty"`
+	Status        string             `bson:"status" json:"status"`
+	Reason        string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	RequestedBy   string             `bson:"requested_by" json:"requested_by"`
+	DeactivatedAt time.Time          `bson:"deactivated_at" json:"deactivated_at"`
+	ScheduledFor  time.Time          `bson:"scheduled_for" json:"scheduled_for"`
+	CancelledAt   *time.Time         `bson:"cancelled_at,omitempty" json:"cancelled_at,omitempty"`
+	DeletedAt     *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	HookErrors    map[string]string  `bson:"hook_errors,omitempty" json:"hook_errors,omitempty"`
+}
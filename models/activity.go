@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ActivityItem is a human-readable entry in an organization's activity feed
+type ActivityItem struct {
+	ID             primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	OrganizationID string                 `bson:"organization_id" json:"organization_id"`
+	ActorID        string                 `bson:"actor_id" json:"actor_id"`
+	Action         string                 `bson:"action" json:"action"`
+	TargetID       string                 `bson:"target_id,omitempty" json:"target_id,omitempty"`
+	Message        string                 `bson:"message" json:"message"`
+	Metadata       map[string]interface{} `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	ReadBy         []string               `bson:"read_by" json:"-"`
+	Read           bool                   `bson:"-" json:"read"`
+	CreatedAt      time.Time              `bson:"created_at" json:"created_at"`
+}
+
+// CollectionName returns the collection of the activity feed
+func (ActivityItem) CollectionName() string {
+	return "activity_feed"
+}
+
+func init() {
+	RegisterIndexes(ActivityItem{}, Index("organization_id", "-created_at"))
+}
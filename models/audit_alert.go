@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Audit anomaly rules
+const (
+	AnomalyMassDeletion = "mass_deletion"
+	AnomalyOffHours     = "off_hours_admin_action"
+	AnomalyNewCountry   = "login_new_country"
+)
+
+// AnomalySettings holds per-organization thresholds for audit anomaly detection
+type AnomalySettings struct {
+	ID                 primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrganizationID     string             `bson:"organization_id" json:"organization_id"`
+	MassDeletionCount  int                `bson:"mass_deletion_count" json:"mass_deletion_count"`
+	MassDeletionWindow int                `bson:"mass_deletion_window_minutes" json:"mass_deletion_window_minutes"`
+	OffHoursEnabled    bool               `bson:"off_hours_enabled" json:"off_hours_enabled"`
+	BusinessStartHour  int                `bson:"business_start_hour" json:"business_start_hour"`
+	BusinessEndHour    int                `bson:"business_end_hour" json:"business_end_hour"`
+	Timezone           string             `bson:"timezone" json:"timezone"`
+	NewCountryEnabled  bool               `bson:"new_country_enabled" json:"new_country_enabled"`
+	NotificationEmails []string           `bson:"notification_emails,omitempty" json:"notification_emails,omitempty"`
+	WebhookURL         string             `bson:"webhook_url,omitempty" json:"webhook_url,omitempty"`
+	UpdatedAt          time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// AuditAlert records a suspicious audit pattern
+type AuditAlert struct {
+	ID             primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	OrganizationID string                 `bson:"organization_id" json:"organization_id"`
+	Rule           string                 `bson:"rule" json:"rule"`
+	AdminID        string                 `bson:"admin_id" json:"admin_id"`
+	Message        string                 `bson:"message" json:"message"`
+	Details        map[string]interface{} `bson:"details,omitempty" json:"details,omitempty"`
+	Acknowledged   bool                   `bson:"acknowledged" json:"acknowledged"`
+	CreatedAt      time.Time              `bson:"created_at" json:"created_at"`
+}
+
+// CollectionName returns the collection audit alerts are stored in
+func (AuditAlert) CollectionName() string {
+	return "audit_alerts"
+}
+
+func init() {
+	RegisterIndexes(AuditAlert{}, Index("organization_id", "-created_at"))
+}
@@ -3,13 +3,38 @@ package models
 import (
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type AuditLog struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	AdminID   string             `bson:"admin_id" json:"admin_id"`
-	Action    string             `bson:"action" json:"action"`
-	TargetID  string             `bson:"target_id" json:"target_id"`
-	Timestamp time.Time          `bson:"timestamp" json:"timestamp"`
+	ID             primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	OrganizationID string                 `bson:"organization_id,omitempty" json:"organization_id,omitempty"`
+	AdminID        string                 `bson:"admin_id" json:"admin_id"`
+	Action         string                 `bson:"action" json:"action"`
+	TargetID       string                 `bson:"target_id" json:"target_id"`
+	Metadata       map[string]interface{} `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	Timestamp      time.Time              `bson:"timestamp" json:"timestamp"`
+
+	// Hash chain fields, set only when the tamper-evident audit log is enabled
+	Sequence int64  `bson:"sequence,omitempty" json:"sequence,omitempty"`
+	PrevHash string `bson:"prev_hash,omitempty" json:"prev_hash,omitempty"`
+	Hash     string `bson:"hash,omitempty" json:"hash,omitempty"`
+}
+
+// CollectionName returns the collection audit entries are stored in
+func (AuditLog) CollectionName() string {
+	return "oms_audit_logs"
+}
+
+func init() {
+	RegisterIndexes(AuditLog{},
+		Index("-timestamp"),
+		Index("admin_id", "-timestamp"),
+		Index("target_id", "-timestamp"),
+		Index("organization_id", "-timestamp"),
+		Index("metadata.organization_id", "-timestamp"),
+		// Lets concurrent instances detect hash chain sequence collisions
+		Index("sequence").Unique().Partial(bson.M{"sequence": bson.M{"$exists": true}}),
+	)
 }
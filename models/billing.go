@@ -0,0 +1,100 @@
+package models
+
+import (
+	"time"
+)
+
+// Subscription statuses, as reported by Stripe
+const (
+	SubscriptionTrialing          = "trialing"
+	SubscriptionActive            = "active"
+	SubscriptionPastDue           = "past_due"
+	SubscriptionCanceled          = "canceled"
+	SubscriptionUnpaid            = "unpaid"
+	SubscriptionIncomplete        = "incomplete"
+	SubscriptionIncompleteExpired = "incomplete_expired"
+	SubscriptionPaused            = "paused"
+)
+
+// BillingCustomer links an organization to its Stripe customer
+type BillingCustomer struct {
+	OrganizationID   string    `bson:"_id" json:"organization_id"`
+	StripeCustomerID string    `bson:"stripe_customer_id" json:"stripe_customer_id"`
+	Email            string    `bson:"email,omitempty" json:"email,omitempty"`
+	Name             string    `bson:"name,omitempty" json:"name,omitempty"`
+	CreatedAt        time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// CollectionName returns the collection billing customers are stored in
+func (BillingCustomer) CollectionName() string {
+	return "billing_customers"
+}
+
+// Subscription mirrors a Stripe subscription of an organization, kept in sync
+// by the billing webhook
+type Subscription struct {
+	ID                string     `bson:"_id" json:"id"` // Stripe subscription ID
+	OrganizationID    string     `bson:"organization_id" json:"organization_id"`
+	StripeCustomerID  string     `bson:"stripe_customer_id" json:"stripe_customer_id"`
+	Plan              string     `bson:"plan" json:"plan"`
+	Status            string     `bson:"status" json:"status"`
+	CurrentPeriodEnd  time.Time  `bson:"current_period_end" json:"current_period_end"`
+	CancelAtPeriodEnd bool       `bson:"cancel_at_period_end" json:"cancel_at_period_end"`
+	TrialEnd          *time.Time `bson:"trial_end,omitempty" json:"trial_end,omitempty"`
+	CanceledAt        *time.Time `bson:"canceled_at,omitempty" json:"canceled_at,omitempty"`
+	CreatedAt         time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt         time.Time  `bson:"updated_at" json:"updated_at"`
+
+	// Creation time of the Stripe event (or API read) the state was taken
+	// from; older events are not applied over it
+	SyncedAt *time.Time `bson:"synced_at,omitempty" json:"-"`
+
+	// Set once the scheduled lifecycle job has emitted the trial events
+	TrialExpiringNotifiedAt *time.Time `bson:"trial_expiring_notified_at,omitempty" json:"-"`
+	TrialExpiredNotifiedAt  *time.Time `bson:"trial_expired_notified_at,omitempty" json:"-"`
+}
+
+// CollectionName returns the collection subscriptions are stored in
+func (Subscription) CollectionName() string {
+	return "billing_subscriptions"
+}
+
+// Entitled reports whether the subscription currently grants its plan; past
+// due subscriptions keep access while Stripe retries the payment
+func (s Subscription) Entitled() bool {
+	switch s.Status {
+	case SubscriptionTrialing, SubscriptionActive, SubscriptionPastDue:
+		return true
+	default:
+		return false
+	}
+}
+
+// BillingEvent records a Stripe webhook event so redeliveries are ignored. It
+// is inserted when processing starts, claiming the event, and completed after.
+type BillingEvent struct {
+	ID          string    `bson:"_id" json:"id"` // Stripe event ID
+	Type        string    `bson:"type" json:"type"`
+	Completed   bool      `bson:"completed" json:"completed"`
+	ProcessedAt time.Time `bson:"processed_at" json:"processed_at"` // When claimed, then when completed
+
+	// Set before the event's lifecycle event is emitted, so a delivery retried
+	// after a later failure does not emit it again
+	LifecycleEmitted bool `bson:"lifecycle_emitted,omitempty" json:"-"`
+}
+
+// CollectionName returns the collection processed billing events are stored in
+func (BillingEvent) CollectionName() string {
+	return "billing_events"
+}
+
+func init() {
+	RegisterIndexes(BillingCustomer{}, Index("stripe_customer_id").Unique())
+	RegisterIndexes(Subscription{},
+		Index("organization_id", "-updated_at"),
+		Index("trial_end").Sparse(),
+	)
+	// Stripe stops redelivering events after three days
+	RegisterIndexes(BillingEvent{}, Index("processed_at").TTL(30*24*time.Hour))
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Branding is the white-label look of an organization, applied to its emails
+// and served to frontends
+type Branding struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	OrganizationID string             `bson:"organization_id" json:"organization_id"`
+	LogoURL        string             `bson:"logo_url,omitempty" json:"logo_url,omitempty"`
+	PrimaryColor   string             `bson:"primary_color,omitempty" json:"primary_color,omitempty"` // Hex, e.g. "#1a73e8"
+	AccentColor    string             `bson:"accent_color,omitempty" json:"accent_color,omitempty"`
+	EmailFooter    string             `bson:"email_footer,omitempty" json:"email_footer,omitempty"` // Plain text
+	SenderName     string             `bson:"sender_name,omitempty" json:"sender_name,omitempty"`   // "From" name of emails
+	UpdatedBy      string             `bson:"updated_by,omitempty" json:"updated_by,omitempty"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// CollectionName returns the collection branding is stored in
+func (Branding) CollectionName() string {
+	return "organization_branding"
+}
+
+func init() {
+	RegisterIndexes(Branding{},
+		Index("organization_id").Unique(),
+	)
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Code is a shareable code, such as a beta invite or a promotion, redeemable
+// a limited number of times
+type Code struct {
+	ID             primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	Code           string                 `bson:"code" json:"code"`
+	Kind           string                 `bson:"kind" json:"kind"`                                           // e.g. "beta_invite", "promotion"
+	OrganizationID string                 `bson:"organization_id,omitempty" json:"organization_id,omitempty"` // Only redeemable within this organization when set
+	MaxUses        int                    `bson:"max_uses" json:"max_uses"`                                   // 0 means unlimited
+	Uses           int                    `bson:"uses" json:"uses"`
+	ExpiresAt      *time.Time             `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	Disabled       bool                   `bson:"disabled" json:"disabled"`
+	Metadata       map[string]interface{} `bson:"metadata,omitempty" json:"metadata,omitempty"` // e.g. the discount of a promotion
+	CreatedBy      string                 `bson:"created_by" json:"created_by"`
+	CreatedAt      time.Time              `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time              `bson:"updated_at" json:"updated_at"`
+}
+
+// CollectionName returns the collection codes are stored in
+func (Code) CollectionName() string {
+	return "codes"
+}
+
+// CodeRedemption records that a user redeemed a code; each user redeems a code once
+type CodeRedemption struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CodeID         primitive.ObjectID `bson:"code_id" json:"code_id"`
+	Code           string             `bson:"code" json:"code"`
+	UserID         string             `bson:"user_id" json:"user_id"`
+	OrganizationID string             `bson:"organization_id,omitempty" json:"organization_id,omitempty"`
+	RedeemedAt     time.Time          `bson:"redeemed_at" json:"redeemed_at"`
+}
+
+// CollectionName returns the collection code redemptions are stored in
+func (CodeRedemption) CollectionName() string {
+	return "code_redemptions"
+}
+
+func init() {
+	RegisterIndexes(Code{},
+		Index("code").Unique(),
+		Index("kind", "-created_at"),
+	)
+	RegisterIndexes(CodeRedemption{},
+		Index("code_id", "user_id").Unique(),
+		Index("user_id", "-redeemed_at"),
+	)
+}
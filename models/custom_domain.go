@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Custom domain statuses
+const (
+	DomainPending  = "pending"
+	DomainVerified = "verified"
+	DomainFailed   = "failed" // Not verified within the verification window
+)
+
+// CustomDomain is a customer-owned domain serving an organization's
+// white-label frontend
+type CustomDomain struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrganizationID    string             `bson:"organization_id" json:"organization_id"`
+	Domain            string             `bson:"domain" json:"domain"` // Lowercase host name, e.g. "app.customer.com"
+	Status            string             `bson:"status" json:"status"`
+	VerificationToken string             `bson:"verification_token" json:"verification_token"`
+	LastError         string             `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	Checks            int                `bson:"checks" json:"checks"`
+	LastCheckedAt     *time.Time         `bson:"last_checked_at,omitempty" json:"last_checked_at,omitempty"`
+	VerifiedAt        *time.Time         `bson:"verified_at,omitempty" json:"verified_at,omitempty"`
+	CreatedBy         string             `bson:"created_by" json:"created_by"`
+	CreatedAt         time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt         time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// CollectionName returns the collection custom domains are stored in
+func (CustomDomain) CollectionName() string {
+	return "custom_domains"
+}
+
+func init() {
+	RegisterIndexes(CustomDomain{},
+		// Any organization may claim a domain; only one can verify it
+		Index("domain").Unique().Partial(bson.M{"status": DomainVerified}),
+		Index("organization_id", "domain").Unique(),
+		Index("organization_id"),
+		Index("status", "last_checked_at"),
+	)
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DeadLetter records a message that exhausted its retries, so it can be
+// inspected and replayed. Envelope holds the message exactly as it was
+// dead-lettered, still encrypted and signed when protection is enabled.
+type DeadLetter struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	EnvelopeID        string             `bson:"envelope_id" json:"envelope_id"`
+	Subject           string             `bson:"subject" json:"subject"`
+	DeadLetterSubject string             `bson:"dead_letter_subject" json:"dead_letter_subject"`
+	Source            string             `bson:"source,omitempty" json:"source,omitempty"`
+	Reason            string             `bson:"reason" json:"reason"`
+	Attempts          int                `bson:"attempts" json:"attempts"`
+	Envelope          json.RawMessage    `bson:"envelope" json:"envelope,omitempty"`
+	CreatedAt         time.Time          `bson:"created_at" json:"created_at"`
+	ReplayCount       int                `bson:"replay_count" json:"replay_count"`
+	LastReplayedAt    *time.Time         `bson:"last_replayed_at,omitempty" json:"last_replayed_at,omitempty"`
+	LastReplayedBy    string             `bson:"last_replayed_by,omitempty" json:"last_replayed_by,omitempty"`
+}
+
+// CollectionName returns the collection dead letters are stored in
+func (DeadLetter) CollectionName() string {
+	return "messaging_dead_letters"
+}
+
+func init() {
+	RegisterIndexes(DeadLetter{},
+		Index("-created_at"),
+		Index("subject", "-created_at"),
+	)
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Device platforms
+const (
+	PlatformIOS      = "ios"
+	PlatformAndroid  = "android"
+	PlatformWeb      = "web"
+	PlatformWindows  = "windows"
+	PlatformMacOS    = "macos"
+	PlatformLinux    = "linux"
+	PlatformVisionOS = "visionos"
+)
+
+// Push providers
+const (
+	PushAPNs    = "apns"
+	PushFCM     = "fcm"
+	PushWebPush = "webpush"
+)
+
+// Device is an app installation or browser a user signs in from. The
+// fingerprint identifies it across sessions, so a login from an unknown
+// fingerprint can be treated as suspicious.
+type Device struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID         string             `bson:"user_id" json:"user_id"`
+	OrganizationID string             `bson:"organization_id,omitempty" json:"organization_id,omitempty"`
+	Fingerprint    string             `bson:"fingerprint" json:"fingerprint"`
+	Platform       string             `bson:"platform" json:"platform"`
+	Model          string             `bson:"model,omitempty" json:"model,omitempty"`
+	OSVersion      string             `bson:"os_version,omitempty" json:"os_version,omitempty"`
+	AppVersion     string             `bson:"app_version,omitempty" json:"app_version,omitempty"`
+	PushToken      string             `bson:"push_token,omitempty" json:"-"` // Never returned to clients
+	PushProvider   string             `bson:"push_provider,omitempty" json:"push_provider,omitempty"`
+	SessionIDs     []string           `bson:"session_ids,omitempty" json:"-"`
+	LastIP         string             `bson:"last_ip,omitempty" json:"last_ip,omitempty"`
+	LastSeenAt     time.Time          `bson:"last_seen_at" json:"last_seen_at"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// CollectionName returns the collection devices are stored in
+func (Device) CollectionName() string {
+	return "devices"
+}
+
+// HasPush reports whether push notifications can be sent to the device
+func (d *Device) HasPush() bool {
+	return d.PushToken != "" && d.PushProvider != ""
+}
+
+func init() {
+	RegisterIndexes(Device{},
+		Index("user_id", "fingerprint").Unique(),
+		Index("push_token").Sparse(),
+		Index("session_ids"),
+	)
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EmailEvent is a delivery event reported by the SendGrid event webhook
+type EmailEvent struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	EventID    string             `bson:"sg_event_id" json:"sg_event_id"`
+	MessageID  string             `bson:"sg_message_id,omitempty" json:"sg_message_id,omitempty"`
+	Email      string             `bson:"email" json:"email"`
+	Event      string             `bson:"event" json:"event"` // delivered, bounce, dropped, spamreport, ...
+	Type       string             `bson:"type,omitempty" json:"type,omitempty"`
+	Reason     string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	Status     string             `bson:"status,omitempty" json:"status,omitempty"`
+	OccurredAt time.Time          `bson:"occurred_at" json:"occurred_at"`
+	ReceivedAt time.Time          `bson:"received_at" json:"received_at"`
+}
+
+// EmailSuppression blocks further sends to an address
+type EmailSuppression struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Email     string             `bson:"email" json:"email"`
+	Event     string             `bson:"event" json:"event"`
+	Reason    string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+)
+
+// File scan statuses
+const (
+	FileScanPending  = "pending"
+	FileScanScanning = "scanning"
+	FileScanClean    = "clean"
+	FileScanInfected = "infected"
+	FileScanFailed   = "failed" // The file could not be read or scanned; treat it as unsafe
+)
+
+// FileScan tracks the antivirus scan of one uploaded file
+type FileScan struct {
+	ID             string     `bson:"_id" json:"id"`
+	OrganizationID string     `bson:"organization_id" json:"organization_id"`
+	Location       string     `bson:"location" json:"location"` // Where the file is stored, e.g. gs://uploads/raw.glb
+	Filename       string     `bson:"filename,omitempty" json:"filename,omitempty"`
+	Status         string     `bson:"status" json:"status"`
+	Signature      string     `bson:"signature,omitempty" json:"signature,omitempty"` // Malware name reported by ClamAV
+	Quarantined    bool       `bson:"quarantined" json:"quarantined"`
+	QuarantinedAt  string     `bson:"quarantined_at,omitempty" json:"quarantined_at,omitempty"` // Location the file was moved to
+	Error          string     `bson:"error,omitempty" json:"error,omitempty"`
+	Attempts       int        `bson:"attempts" json:"attempts"`
+	CreatedBy      string     `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt      time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time  `bson:"updated_at" json:"updated_at"`
+	ScannedAt      *time.Time `bson:"scanned_at,omitempty" json:"scanned_at,omitempty"`
+
+	// Renewed by the worker scanning the file; a scan whose lease expired lost
+	// its worker and is picked up again
+	LeaseExpiresAt *time.Time `bson:"lease_expires_at,omitempty" json:"-"`
+}
+
+// CollectionName returns the collection file scans are stored in
+func (FileScan) CollectionName() string {
+	return "file_scans"
+}
+
+// Safe reports whether the file was scanned and found clean
+func (s FileScan) Safe() bool {
+	return s.Status == FileScanClean
+}
+
+func init() {
+	RegisterIndexes(FileScan{},
+		Index("organization_id", "status", "-created_at"),
+		// Scans whose worker stopped responding
+		Index("status", "lease_expires_at"),
+		// Latest scan of a file
+		Index("location", "-created_at"),
+	)
+}
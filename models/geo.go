@@ -0,0 +1,82 @@
+package models
+
+import (
+	"fmt"
+)
+
+// GeoJSON geometry types
+const (
+	GeoTypePoint   = "Point"
+	GeoTypePolygon = "Polygon"
+)
+
+// GeoPoint is a GeoJSON point. Coordinates are [longitude, latitude], the
+// order Mongo expects; declare a models.GeoIndex on fields holding one.
+type GeoPoint struct {
+	Type        string     `bson:"type" json:"type"`
+	Coordinates [2]float64 `bson:"coordinates" json:"coordinates"`
+}
+
+// NewGeoPoint returns the point at a longitude and latitude
+func NewGeoPoint(longitude, latitude float64) GeoPoint {
+	return GeoPoint{Type: GeoTypePoint, Coordinates: [2]float64{longitude, latitude}}
+}
+
+// Longitude returns the point's longitude
+func (p GeoPoint) Longitude() float64 {
+	return p.Coordinates[0]
+}
+
+// Latitude returns the point's latitude
+func (p GeoPoint) Latitude() float64 {
+	return p.Coordinates[1]
+}
+
+// Validate reports coordinates outside the valid range, the most common sign
+// of swapped latitude and longitude
+func (p GeoPoint) Validate() error {
+	if p.Longitude() < -180 || p.Longitude() > 180 {
+		return fmt.Errorf("longitude %v out of range [-180, 180]", p.Longitude())
+	}
+	if p.Latitude() < -90 || p.Latitude() > 90 {
+		return fmt.Errorf("latitude %v out of range [-90, 90]", p.Latitude())
+	}
+	return nil
+}
+
+// GeoPolygon is a GeoJSON polygon: an outer ring followed by optional holes,
+// each a closed list of [longitude, latitude] positions
+type GeoPolygon struct {
+	Type        string         `bson:"type" json:"type"`
+	Coordinates [][][2]float64 `bson:"coordinates" json:"coordinates"`
+}
+
+// NewGeoPolygon returns the polygon enclosed by points, closing the ring when
+// the last point differs from the first
+func NewGeoPolygon(points ...GeoPoint) (GeoPolygon, error) {
+	if len(points) < 3 {
+		return GeoPolygon{}, fmt.Errorf("a polygon needs at least 3 points, got %d", len(points))
+	}
+
+	ring := make([][2]float64, 0, len(points)+1)
+	for _, point := range points {
+		if err := point.Validate(); err != nil {
+			return GeoPolygon{}, err
+		}
+		ring = append(ring, point.Coordinates)
+	}
+	if ring[0] != ring[len(ring)-1] {
+		ring = append(ring, ring[0])
+	}
+	return GeoPolygon{Type: GeoTypePolygon, Coordinates: [][][2]float64{ring}}, nil
+}
+
+// NewGeoBox returns the rectangle between two corners, e.g. the visible area of a map
+func NewGeoBox(southWest, northEast GeoPoint) (GeoPolygon, error) {
+	return NewGeoPolygon(
+		southWest,
+		NewGeoPoint(northEast.Longitude(), southWest.Latitude()),
+		northEast,
+		NewGeoPoint(southWest.Longitude(), northEast.Latitude()),
+	)
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Collection is implemented by models stored in a known collection
+type Collection interface {
+	CollectionName() string
+}
+
+// IndexSpec declares one index of a collection; build it with Index
+type IndexSpec struct {
+	Keys          bson.D
+	Name          string        // Defaults to the server's name, see KeySignature
+	IsUnique      bool          // Set by Unique
+	IsSparse      bool          // Set by Sparse
+	ExpireAfter   time.Duration // Set by TTL
+	PartialFilter bson.M        // Set by Partial
+}
+
+// Index declares an index on fields, descending when prefixed with "-":
+//
+//	models.Index("admin_id", "-timestamp")
+func Index(fields ...string) IndexSpec {
+	keys := make(bson.D, len(fields))
+	for i, field := range fields {
+		if name, ok := strings.CutPrefix(field, "-"); ok {
+			keys[i] = bson.E{Key: name, Value: -1}
+		} else {
+			keys[i] = bson.E{Key: field, Value: 1}
+		}
+	}
+	return IndexSpec{Keys: keys}
+}
+
+// GeoIndex declares a 2dsphere index on a GeoJSON field, needed by $near
+// and speeding up $geoWithin, followed by optional fields as in Index:
+//
+//	models.GeoIndex("location", "organization_id")
+func GeoIndex(field string, fields ...string) IndexSpec {
+	spec := Index(fields...)
+	spec.Keys = append(bson.D{{Key: field, Value: "2dsphere"}}, spec.Keys...)
+	return spec
+}
+
+// Unique rejects documents with duplicate keys
+func (s IndexSpec) Unique() IndexSpec {
+	s.IsUnique = true
+	return s
+}
+
+// Sparse skips documents without the indexed fields
+func (s IndexSpec) Sparse() IndexSpec {
+	s.IsSparse = true
+	return s
+}
+
+// TTL deletes documents once the indexed date is older than expireAfter
+func (s IndexSpec) TTL(expireAfter time.Duration) IndexSpec {
+	s.ExpireAfter = expireAfter
+	return s
+}
+
+// Partial only indexes documents matching filter
+func (s IndexSpec) Partial(filter bson.M) IndexSpec {
+	s.PartialFilter = filter
+	return s
+}
+
+// Named overrides the generated index name
+func (s IndexSpec) Named(name string) IndexSpec {
+	s.Name = name
+	return s
+}
+
+// KeySignature renders index keys the way the server names indexes, e.g. admin_id_1_timestamp_-1
+func KeySignature(keys bson.D) string {
+	parts := make([]string, 0, len(keys)*2)
+	for _, key := range keys {
+		parts = append(parts, key.Key, fmt.Sprint(key.Value))
+	}
+	return strings.Join(parts, "_")
+}
+
+var (
+	indexRegistry   = map[string][]IndexSpec{}
+	indexRegistryMu sync.RWMutex
+)
+
+// RegisterIndexes declares the indexes of a model's collection, usually from
+// an init function; migrate.EnsureIndexes creates them at startup
+//
+//	models.RegisterIndexes(Experience{},
+//		models.Index("organization_id", "-created_at"),
+//		models.Index("slug").Unique(),
+//	)
+func RegisterIndexes(model Collection, indexes ...IndexSpec) {
+	indexRegistryMu.Lock()
+	defer indexRegistryMu.Unlock()
+
+	name := model.CollectionName()
+	indexRegistry[name] = append(indexRegistry[name], indexes...)
+}
+
+// RegisteredIndexes returns the declared indexes by collection
+func RegisteredIndexes() map[string][]IndexSpec {
+	indexRegistryMu.RLock()
+	defer indexRegistryMu.RUnlock()
+
+	registered := make(map[string][]IndexSpec, len(indexRegistry))
+	for collection, indexes := range indexRegistry {
+		registered[collection] = append([]IndexSpec(nil), indexes...)
+	}
+	return registered
+}
+
+// IndexedCollections returns the collections with declared indexes, sorted
+func IndexedCollections() []string {
+	indexRegistryMu.RLock()
+	defer indexRegistryMu.RUnlock()
+
+	collections := make([]string, 0, len(indexRegistry))
+	for collection := range indexRegistry {
+		collections = append(collections, collection)
+	}
+	sort.Strings(collections)
+	return collections
+}
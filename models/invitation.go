@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Invitation statuses
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+)
+
+// Invitation invites an email address to join an organization with a role
+type Invitation struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrganizationID string             `bson:"organization_id" json:"organization_id"`
+	Email          string             `bson:"email" json:"email"`
+	Role           string             `bson:"role" json:"role"`
+	TokenHash      string             `bson:"token_hash" json:"-"`
+	Status         string             `bson:"status" json:"status"`
+	InvitedBy      string             `bson:"invited_by" json:"invited_by"`
+	AcceptedBy     string             `bson:"accepted_by,omitempty" json:"accepted_by,omitempty"`
+	ExpiresAt      time.Time          `bson:"expires_at" json:"expires_at"`
+	AcceptedAt     *time.Time         `bson:"accepted_at,omitempty" json:"accepted_at,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Membership links a user to an organization with a role
+type Membership struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrganizationID string             `bson:"organization_id" json:"organization_id"`
+	UserID         string             `bson:"user_id" json:"user_id"`
+	Role           string             `bson:"role" json:"role"`
+	InvitationID   primitive.ObjectID `bson:"invitation_id,omitempty" json:"invitation_id,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// IP rule actions
+const (
+	IPRuleAllow = "allow"
+	IPRuleDeny  = "deny"
+)
+
+// IPRule allows or denies a CIDR range for a scope such as "admin"; "*" applies everywhere
+type IPRule struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CIDR        string             `bson:"cidr" json:"cidr"`
+	Action      string             `bson:"action" json:"action"`
+	Scope       string             `bson:"scope" json:"scope"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	CreatedBy   string             `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
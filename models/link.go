@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ShortLink maps a short token to a deep link, e.g. an experience URL printed as a QR code
+type ShortLink struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Token          string             `bson:"token" json:"token"`
+	Target         string             `bson:"target" json:"target"`
+	OrganizationID string             `bson:"organization_id,omitempty" json:"organization_id,omitempty"`
+	CreatedBy      string             `bson:"created_by" json:"created_by"`
+	Hits           int64              `bson:"hits" json:"hits"`
+	LastHitAt      *time.Time         `bson:"last_hit_at,omitempty" json:"last_hit_at,omitempty"`
+	ExpiresAt      *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// CollectionName returns the collection short links are stored in
+func (ShortLink) CollectionName() string {
+	return "short_links"
+}
+
+func init() {
+	RegisterIndexes(ShortLink{},
+		Index("token").Unique(),
+		Index("organization_id", "-created_at"),
+	)
+}
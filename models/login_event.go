@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Suspicious login reasons
+const (
+	LoginNewDevice  = "new_device"
+	LoginNewCountry = "new_country"
+)
+
+// LoginEvent records the context of a successful sign-in, the history new
+// sign-ins are compared against
+type LoginEvent struct {
+	ID             primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID         string              `bson:"user_id" json:"user_id"`
+	OrganizationID string              `bson:"organization_id,omitempty" json:"organization_id,omitempty"`
+	IP             string              `bson:"ip,omitempty" json:"ip,omitempty"`
+	Country        string              `bson:"country,omitempty" json:"country,omitempty"`
+	City           string              `bson:"city,omitempty" json:"city,omitempty"`
+	DeviceID       *primitive.ObjectID `bson:"device_id,omitempty" json:"device_id,omitempty"`
+	Fingerprint    string              `bson:"fingerprint,omitempty" json:"-"`
+	Reasons        []string            `bson:"reasons,omitempty" json:"reasons,omitempty"` // Why the login was flagged
+	StepUpRequired bool                `bson:"step_up_required,omitempty" json:"step_up_required,omitempty"`
+	Granted        bool                `bson:"granted" json:"granted"` // False until step-up MFA is passed; only granted sign-ins count as history
+	CreatedAt      time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// CollectionName returns the collection login history is stored in
+func (LoginEvent) CollectionName() string {
+	return "login_events"
+}
+
+// LoginHistoryRetention is how long login events are kept
+const LoginHistoryRetention = 180 * 24 * time.Hour
+
+func init() {
+	RegisterIndexes(LoginEvent{},
+		Index("user_id", "country"),
+		Index("user_id", "-created_at"),
+		Index("created_at").TTL(LoginHistoryRetention),
+	)
+}
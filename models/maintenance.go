@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// MaintenanceState describes whether the service is in maintenance mode
+type MaintenanceState struct {
+	Enabled    bool       `bson:"enabled" json:"enabled"`
+	Message    string     `bson:"message,omitempty" json:"message,omitempty"`
+	RetryAfter int        `bson:"retry_after" json:"retry_after"` // Seconds clients should wait before retrying
+	EndsAt     *time.Time `bson:"ends_at,omitempty" json:"ends_at,omitempty"`
+	UpdatedBy  string     `bson:"updated_by,omitempty" json:"updated_by,omitempty"`
+	UpdatedAt  time.Time  `bson:"updated_at" json:"updated_at"`
+	Source     string     `bson:"-" json:"source,omitempty"` // "env" when forced by MAINTENANCE_MODE
+}
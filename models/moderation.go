@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+)
+
+// Moderation statuses of a stored object
+const (
+	ModerationApproved    = "approved"    // Passed the scan or a review
+	ModerationQuarantined = "quarantined" // Flagged, or could not be scanned; hidden until reviewed
+	ModerationRejected    = "rejected"    // Confirmed by a reviewer; the owning service removes it
+)
+
+// Kinds of moderated content
+const (
+	ModerationImage = "image"
+	ModerationText  = "text"
+)
+
+// ModerationFlag is a category of unwanted content and how likely a scan
+// found it, from 0 to 1
+type ModerationFlag struct {
+	Category string  `bson:"category" json:"category"`
+	Score    float64 `bson:"score" json:"score"`
+}
+
+// ModerationItem records the moderation of one piece of content of an object
+// stored by a service, such as the cover image or description of an experience
+type ModerationItem struct {
+	ID             string           `bson:"_id" json:"id"`
+	OrganizationID string           `bson:"organization_id" json:"organization_id"`
+	ObjectType     string           `bson:"object_type" json:"object_type"` // e.g. "experience"
+	ObjectID       string           `bson:"object_id" json:"object_id"`
+	Field          string           `bson:"field,omitempty" json:"field,omitempty"` // e.g. "cover_image"
+	Kind           string           `bson:"kind" json:"kind"`
+	URI            string           `bson:"uri,omitempty" json:"uri,omitempty"`   // Image location, gs:// or https://
+	Text           string           `bson:"text,omitempty" json:"text,omitempty"` // Text content
+	Adapter        string           `bson:"adapter" json:"adapter"`
+	Status         string           `bson:"status" json:"status"`
+	Flags          []ModerationFlag `bson:"flags,omitempty" json:"flags,omitempty"`
+	Error          string           `bson:"error,omitempty" json:"error,omitempty"` // Why the scan failed
+	ReviewedBy     string           `bson:"reviewed_by,omitempty" json:"reviewed_by,omitempty"`
+	ReviewNote     string           `bson:"review_note,omitempty" json:"review_note,omitempty"`
+	ReviewedAt     *time.Time       `bson:"reviewed_at,omitempty" json:"reviewed_at,omitempty"`
+	CreatedBy      string           `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt      time.Time        `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time        `bson:"updated_at" json:"updated_at"`
+}
+
+// CollectionName returns the collection moderation items are stored in
+func (ModerationItem) CollectionName() string {
+	return "moderation_items"
+}
+
+func init() {
+	RegisterIndexes(ModerationItem{},
+		// Review queue
+		Index("organization_id", "status", "-created_at"),
+		Index("object_type", "object_id"),
+	)
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Operation statuses
+const (
+	OperationPending   = "pending"
+	OperationRunning   = "running"
+	OperationSucceeded = "succeeded"
+	OperationFailed    = "failed"
+)
+
+// Operation tracks a long-running task, such as media processing or a bulk
+// import, that clients poll by ID instead of waiting on the request
+type Operation struct {
+	ID             string          `bson:"_id" json:"id"`
+	OrganizationID string          `bson:"organization_id" json:"organization_id"`
+	Type           string          `bson:"type" json:"type"`
+	Status         string          `bson:"status" json:"status"`
+	Progress       int             `bson:"progress" json:"progress"` // Percent complete, 0-100
+	Message        string          `bson:"message,omitempty" json:"message,omitempty"`
+	Result         json.RawMessage `bson:"result,omitempty" json:"result,omitempty"`
+	Error          string          `bson:"error,omitempty" json:"error,omitempty"`
+	WebhookURL     string          `bson:"webhook_url,omitempty" json:"-"`
+	CreatedBy      string          `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt      time.Time       `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time       `bson:"updated_at" json:"updated_at"`
+	CompletedAt    *time.Time      `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+}
+
+// CollectionName returns the collection operations are stored in
+func (Operation) CollectionName() string {
+	return "operations"
+}
+
+// Done reports whether the operation has finished, successfully or not
+func (o Operation) Done() bool {
+	return o.Status == OperationSucceeded || o.Status == OperationFailed
+}
+
+func init() {
+	RegisterIndexes(Operation{},
+		Index("organization_id", "-created_at"),
+		// Finished operations are kept for a month
+		Index("completed_at").TTL(30*24*time.Hour),
+	)
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Policy effects
+const (
+	PolicyAllow = "allow"
+	PolicyDeny  = "deny"
+)
+
+// Policy grants or denies a subject an action on a resource.
+// Subject is "user:<id>", "role:<name>" or "*"; Action and Resource accept "*" wildcards.
+type Policy struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Subject   string             `bson:"subject" json:"subject"`
+	Action    string             `bson:"action" json:"action"`
+	Resource  string             `bson:"resource" json:"resource"`
+	Effect    string             `bson:"effect" json:"effect"`
+	CreatedBy string             `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// Role is a named role that inherits the privileges of the roles it lists
+type Role struct {
+	Name        string   `bson:"name" json:"name"`
+	Inherits    []string `bson:"inherits" json:"inherits"`
+	Description string   `bson:"description,omitempty" json:"description,omitempty"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SAMLConnection configures SAML single sign-on for one organization
+type SAMLConnection struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrganizationID string             `bson:"organization_id" json:"organization_id"`
+	IdPEntityID    string             `bson:"idp_entity_id" json:"idp_entity_id"`
+	SSOURL         string             `bson:"sso_url" json:"sso_url"` // HTTP-Redirect binding endpoint of the IdP
+	// Certificates are the PEM signing certificates of the IdP; more than one
+	// allows rotation
+	Certificates []string `bson:"certificates" json:"certificates"`
+	// AttributeMap maps claims (email, name, role, groups) to assertion
+	// attribute names; unmapped claims use common attribute names
+	AttributeMap map[string]string `bson:"attribute_map,omitempty" json:"attribute_map,omitempty"`
+	// RoleMap maps role or group attribute values to roles
+	RoleMap       map[string]string `bson:"role_map,omitempty" json:"role_map,omitempty"`
+	DefaultRole   string            `bson:"default_role,omitempty" json:"default_role,omitempty"`
+	AutoProvision bool              `bson:"auto_provision" json:"auto_provision"` // Create users on first sign-in
+	RedirectURL   string            `bson:"redirect_url,omitempty" json:"redirect_url,omitempty"`
+	Enabled       bool              `bson:"enabled" json:"enabled"`
+	CreatedAt     time.Time         `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time         `bson:"updated_at" json:"updated_at"`
+}
+
+// CollectionName returns the collection SAML connections are stored in
+func (SAMLConnection) CollectionName() string {
+	return "saml_connections"
+}
+
+// SAMLAssertion records a consumed assertion ID until it expires, so an
+// assertion cannot be replayed
+type SAMLAssertion struct {
+	ID             string    `bson:"_id" json:"id"`
+	OrganizationID string    `bson:"organization_id" json:"organization_id"`
+	ExpiresAt      time.Time `bson:"expires_at" json:"expires_at"`
+}
+
+// CollectionName returns the collection consumed SAML assertions are stored in
+func (SAMLAssertion) CollectionName() string {
+	return "saml_assertions"
+}
+
+func init() {
+	RegisterIndexes(SAMLConnection{},
+		Index("organization_id").Unique(),
+	)
+	RegisterIndexes(SAMLAssertion{},
+		Index("expires_at").TTL(0),
+	)
+}
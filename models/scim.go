@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SCIMToken authenticates an identity provider provisioning one organization
+type SCIMToken struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrganizationID string             `bson:"organization_id" json:"organization_id"`
+	Description    string             `bson:"description,omitempty" json:"description,omitempty"` // e.g. "Okta"
+	TokenHash      string             `bson:"token_hash" json:"-"`
+	CreatedBy      string             `bson:"created_by" json:"created_by"`
+	LastUsedAt     *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// CollectionName returns the collection SCIM tokens are stored in
+func (SCIMToken) CollectionName() string {
+	return "scim_tokens"
+}
+
+// SCIMGroup is a group provisioned over SCIM; Members holds user IDs
+type SCIMGroup struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrganizationID string             `bson:"organization_id" json:"organization_id"`
+	DisplayName    string             `bson:"display_name" json:"display_name"`
+	ExternalID     string             `bson:"external_id,omitempty" json:"external_id,omitempty"`
+	Members        []string           `bson:"members" json:"members"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// CollectionName returns the collection SCIM groups are stored in
+func (SCIMGroup) CollectionName() string {
+	return "scim_groups"
+}
+
+func init() {
+	RegisterIndexes(SCIMToken{},
+		Index("token_hash").Unique(),
+		Index("organization_id"),
+	)
+	RegisterIndexes(SCIMGroup{},
+		Index("organization_id", "display_name").Unique(),
+		Index("organization_id", "members"),
+	)
+}
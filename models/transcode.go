@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+)
+
+// Transcode job statuses
+const (
+	TranscodeQueued    = "queued"
+	TranscodeRunning   = "running"
+	TranscodeSucceeded = "succeeded"
+	TranscodeFailed    = "failed"
+)
+
+// Transcode job kinds
+const (
+	TranscodeVideo = "video"
+	TranscodeModel = "model" // 3D model optimization
+)
+
+// TranscodeJob tracks one media processing job through the shared transcode pipeline
+type TranscodeJob struct {
+	ID             string            `bson:"_id" json:"id"`
+	OrganizationID string            `bson:"organization_id" json:"organization_id"`
+	Kind           string            `bson:"kind" json:"kind"`
+	Preset         string            `bson:"preset" json:"preset"`
+	Input          string            `bson:"input" json:"input"`   // Path or URI the adapter reads, e.g. gs://bucket/raw.mp4
+	Output         string            `bson:"output" json:"output"` // Path or URI the adapter writes
+	Adapter        string            `bson:"adapter" json:"adapter"`
+	ExternalID     string            `bson:"external_id,omitempty" json:"external_id,omitempty"` // Job name at the provider
+	Status         string            `bson:"status" json:"status"`
+	Progress       int               `bson:"progress" json:"progress"` // Percent complete, 0-100
+	Error          string            `bson:"error,omitempty" json:"error,omitempty"`
+	Attempts       int               `bson:"attempts" json:"attempts"`
+	Metadata       map[string]string `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	CreatedBy      string            `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt      time.Time         `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time         `bson:"updated_at" json:"updated_at"`
+	StartedAt      *time.Time        `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	CompletedAt    *time.Time        `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+
+	// Renewed by the worker running the job in-process; a running job whose
+	// lease expired lost its worker and is queued again
+	LeaseExpiresAt *time.Time `bson:"lease_expires_at,omitempty" json:"-"`
+}
+
+// CollectionName returns the collection transcode jobs are stored in
+func (TranscodeJob) CollectionName() string {
+	return "transcode_jobs"
+}
+
+// Done reports whether the job has finished, successfully or not
+func (j TranscodeJob) Done() bool {
+	return j.Status == TranscodeSucceeded || j.Status == TranscodeFailed
+}
+
+func init() {
+	RegisterIndexes(TranscodeJob{},
+		Index("organization_id", "-created_at"),
+		// Lets the poller find jobs running at a provider
+		Index("status", "updated_at"),
+		Index("completed_at").TTL(90*24*time.Hour),
+	)
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NotificationSettings controls which channels and categories a user receives
+type NotificationSettings struct {
+	Email      bool            `bson:"email" json:"email"`
+	Push       bool            `bson:"push" json:"push"`
+	SMS        bool            `bson:"sms" json:"sms"`
+	Categories map[string]bool `bson:"categories,omitempty" json:"categories,omitempty"` // Missing categories are enabled
+}
+
+// UserPreferences holds per-user localization and notification settings
+type UserPreferences struct {
+	ID            primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	UserID        string               `bson:"user_id" json:"user_id"`
+	Timezone      string               `bson:"timezone" json:"timezone"`
+	Locale        string               `bson:"locale" json:"locale"`
+	Notifications NotificationSettings `bson:"notifications" json:"notifications"`
+	UpdatedAt     time.Time            `bson:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,265 @@
+// Package moderation scans user content, such as the images and descriptions
+// of experiences, with a moderation provider: Google Vision SafeSearch
+// (VisionAdapter, images) or the OpenAI moderation API (OpenAIAdapter, text
+// and images). Flagged content is quarantined until an admin reviews it:
+//
+//	adapter, err := moderation.NewOpenAIAdapterFromConfig()
+//	moderation.RegisterAdapter("openai", adapter)
+//
+//	item, err := moderation.Scan(ctx, moderation.Spec{
+//		OrganizationID: organizationID,
+//		ObjectType:     "experience",
+//		ObjectID:       experience.ID,
+//		Field:          "description",
+//		Kind:           models.ModerationText,
+//		Text:           experience.Description,
+//	}, userID)
+//
+// Services hide objects whose Status is not approved and listen on
+// DecidedSubject for scan results and review decisions.
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/messaging"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/repo"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DecidedSubject carries every item whose status was set by a scan or review
+const DecidedSubject = "moderation.decided"
+
+// DefaultThreshold is the flag score from which content is quarantined when
+// MODERATION_THRESHOLD is not set
+const DefaultThreshold = 0.7
+
+var (
+	ErrUnknownAdapter     = errors.New("unknown moderation adapter")
+	ErrUnsupportedContent = errors.New("content kind not supported by the moderation adapter")
+	ErrInvalidDecision    = errors.New("invalid moderation decision")
+)
+
+// Content is what an adapter scans: an image URI or a text
+type Content struct {
+	Kind string // models.ModerationImage or models.ModerationText
+	URI  string
+	Text string
+}
+
+// Adapter scans content with a moderation provider, returning a flag for
+// every category it scores
+type Adapter interface {
+	Scan(ctx context.Context, content Content) ([]models.ModerationFlag, error)
+}
+
+// Spec describes content to scan
+type Spec struct {
+	OrganizationID string
+	ObjectType     string
+	ObjectID       string
+	Field          string
+	Kind           string
+	URI            string
+	Text           string
+	Adapter        string // Default MODERATION_ADAPTER
+}
+
+var (
+	store = repo.New[models.ModerationItem](models.ModerationItem{}.CollectionName(), repo.WithULIDKeys())
+
+	adapters   = map[string]Adapter{}
+	adaptersMu sync.RWMutex
+)
+
+// RegisterAdapter makes an adapter available under name
+func RegisterAdapter(name string, adapter Adapter) {
+	adaptersMu.Lock()
+	defer adaptersMu.Unlock()
+	adapters[name] = adapter
+}
+
+func lookupAdapter(name string) (Adapter, error) {
+	adaptersMu.RLock()
+	defer adaptersMu.RUnlock()
+	adapter, ok := adapters[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownAdapter, name)
+	}
+	return adapter, nil
+}
+
+// Threshold returns the flag score from which content is quarantined
+func Threshold() float64 {
+	if threshold, err := strconv.ParseFloat(config.GetEnv("MODERATION_THRESHOLD", ""), 64); err == nil {
+		return threshold
+	}
+	return DefaultThreshold
+}
+
+// Scan moderates content and stores the outcome. Content scoring at least
+// Threshold in any category is quarantined; so is content the provider could
+// not scan, which fails safe rather than publishing it unchecked.
+func Scan(ctx context.Context, spec Spec, createdBy string) (*models.ModerationItem, error) {
+	if spec.ObjectType == "" || spec.ObjectID == "" {
+		return nil, fmt.Errorf("moderation object type and ID are required")
+	}
+	content := Content{Kind: spec.Kind, URI: spec.URI, Text: spec.Text}
+	switch {
+	case spec.Kind == models.ModerationImage && spec.URI == "",
+		spec.Kind == models.ModerationText && spec.Text == "":
+		return nil, fmt.Errorf("moderation content is empty")
+	case spec.Kind != models.ModerationImage && spec.Kind != models.ModerationText:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedContent, spec.Kind)
+	}
+	if spec.Adapter == "" {
+		spec.Adapter = config.GetEnv("MODERATION_ADAPTER", "")
+	}
+	adapter, err := lookupAdapter(spec.Adapter)
+	if err != nil {
+		return nil, err
+	}
+
+	now := utils.Now()
+	item := &models.ModerationItem{
+		ID:             utils.NewID(),
+		OrganizationID: spec.OrganizationID,
+		ObjectType:     spec.ObjectType,
+		ObjectID:       spec.ObjectID,
+		Field:          spec.Field,
+		Kind:           spec.Kind,
+		URI:            spec.URI,
+		Text:           spec.Text,
+		Adapter:        spec.Adapter,
+		Status:         models.ModerationApproved,
+		CreatedBy:      createdBy,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	flags, err := adapter.Scan(ctx, content)
+	if err != nil {
+		utils.Log(ctx).Warn("moderation scan failed", "object_type", spec.ObjectType, "object_id", spec.ObjectID, "error", err)
+		item.Status = models.ModerationQuarantined
+		item.Error = err.Error()
+	}
+	threshold := Threshold()
+	for _, flag := range flags {
+		if flag.Score >= threshold {
+			item.Status = models.ModerationQuarantined
+		}
+	}
+	item.Flags = flags
+
+	if err := store.Insert(ctx, item); err != nil {
+		return nil, fmt.Errorf("failed to store moderation item: %w", err)
+	}
+	publish(ctx, item)
+	return item, nil
+}
+
+// Status returns the moderation status of an object from the latest item of
+// each of its fields, as content replaced since is no longer shown: rejected
+// when any of them was rejected, quarantined when any is quarantined,
+// otherwise approved. Objects that were never scanned have an empty status.
+func Status(ctx context.Context, objectType, objectID string) (string, error) {
+	cursor, err := store.Collection().Find(ctx, bson.M{"object_type": objectType, "object_id": objectID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}))
+	if err != nil {
+		return "", err
+	}
+	defer cursor.Close(ctx)
+
+	var items []models.ModerationItem
+	if err := cursor.All(ctx, &items); err != nil {
+		return "", err
+	}
+
+	status := ""
+	latest := map[string]bool{}
+	for _, item := range items {
+		if latest[item.Field] {
+			continue
+		}
+		latest[item.Field] = true
+
+		switch {
+		case item.Status == models.ModerationRejected:
+			return models.ModerationRejected, nil
+		case item.Status == models.ModerationQuarantined:
+			status = models.ModerationQuarantined
+		case status == "":
+			status = models.ModerationApproved
+		}
+	}
+	return status, nil
+}
+
+// List returns a page of an organization's items, optionally of one status,
+// newest first
+func List(ctx context.Context, organizationID, status string, page repo.Page) (*repo.PageResult[models.ModerationItem], error) {
+	filter := bson.M{"organization_id": organizationID}
+	if status != "" {
+		filter["status"] = status
+	}
+	page.Sort = bson.D{{Key: "created_at", Value: -1}}
+	return store.Find(ctx, filter, page)
+}
+
+// Get returns an item of organizationID; others are repo.ErrNotFound
+func Get(ctx context.Context, id, organizationID string) (*models.ModerationItem, error) {
+	if !utils.IsValidID(id) {
+		return nil, repo.ErrNotFound
+	}
+	return store.FindOne(ctx, bson.M{"_id": id, "organization_id": organizationID})
+}
+
+// Review records a reviewer's decision, models.ModerationApproved or
+// models.ModerationRejected, on an item of organizationID
+func Review(ctx context.Context, id, organizationID, decision, reviewerID, note string) (*models.ModerationItem, error) {
+	if decision != models.ModerationApproved && decision != models.ModerationRejected {
+		return nil, fmt.Errorf("%w: must be %q or %q", ErrInvalidDecision, models.ModerationApproved, models.ModerationRejected)
+	}
+	item, err := Get(ctx, id, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := utils.Now()
+	item.Status = decision
+	item.ReviewedBy = reviewerID
+	item.ReviewNote = note
+	item.ReviewedAt = &now
+	item.UpdatedAt = now
+	err = store.UpdateByID(ctx, item.ID, bson.M{"$set": bson.M{
+		"status":      item.Status,
+		"reviewed_by": item.ReviewedBy,
+		"review_note": item.ReviewNote,
+		"reviewed_at": now,
+		"updated_at":  now,
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	publish(ctx, item)
+	return item, nil
+}
+
+// publish announces an item's status on DecidedSubject
+func publish(ctx context.Context, item *models.ModerationItem) {
+	if messaging.Default() == nil {
+		return
+	}
+	if err := messaging.Publish(ctx, DecidedSubject, item); err != nil {
+		utils.Log(ctx).Warn("failed to publish moderation decision", "item_id", item.ID, "error", err)
+	}
+}
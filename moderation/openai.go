@@ -0,0 +1,111 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/breaker"
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+)
+
+// openAIModerationURL is the OpenAI moderation endpoint
+const openAIModerationURL = "https://api.openai.com/v1/moderations"
+
+// openAIBreaker stops calling the OpenAI API while it is failing
+var openAIBreaker = breaker.New("openai_moderation", breaker.Options{})
+
+// OpenAIAdapter scans text and images with the OpenAI moderation API. Images
+// must be https:// URLs the API can fetch.
+type OpenAIAdapter struct {
+	apiKey string
+	model  string
+	client *http.Client
+}
+
+// NewOpenAIAdapter creates an adapter; model defaults to "omni-moderation-latest"
+func NewOpenAIAdapter(apiKey, model string) *OpenAIAdapter {
+	if model == "" {
+		model = "omni-moderation-latest"
+	}
+	return &OpenAIAdapter{apiKey: apiKey, model: model, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// NewOpenAIAdapterFromConfig builds an adapter with the key from the
+// "openai-api-key" secret or OPENAI_API_KEY and OPENAI_MODERATION_MODEL
+func NewOpenAIAdapterFromConfig() (*OpenAIAdapter, error) {
+	apiKey, err := config.GetSecret("openai-api-key", "OPENAI_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+	return NewOpenAIAdapter(apiKey, config.GetEnv("OPENAI_MODERATION_MODEL", "")), nil
+}
+
+// Scan asks the moderation API for category scores
+func (a *OpenAIAdapter) Scan(ctx context.Context, content Content) ([]models.ModerationFlag, error) {
+	var input interface{}
+	switch content.Kind {
+	case models.ModerationText:
+		input = content.Text
+	case models.ModerationImage:
+		if !strings.HasPrefix(content.URI, "https://") {
+			return nil, fmt.Errorf("%w: OpenAI moderation needs an https:// image URL", ErrUnsupportedContent)
+		}
+		input = []map[string]interface{}{{
+			"type":      "image_url",
+			"image_url": map[string]string{"url": content.URI},
+		}}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedContent, content.Kind)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"model": a.model, "input": input})
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Results []struct {
+			CategoryScores map[string]float64 `json:"category_scores"`
+		} `json:"results"`
+	}
+	err = openAIBreaker.Execute(func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, openAIModerationURL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+a.apiKey)
+
+		resp, err := a.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("moderation API returned status %d", resp.StatusCode)
+		}
+		return json.NewDecoder(resp.Body).Decode(&response)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Results) == 0 {
+		return nil, fmt.Errorf("moderation API returned no results")
+	}
+
+	scores := response.Results[0].CategoryScores
+	flags := make([]models.ModerationFlag, 0, len(scores))
+	for category, score := range scores {
+		flags = append(flags, models.ModerationFlag{Category: category, Score: score})
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Category < flags[j].Category })
+	return flags, nil
+}
@@ -0,0 +1,94 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"google.golang.org/api/option"
+	vision "google.golang.org/api/vision/v1"
+)
+
+// visionLikelihoods converts SafeSearch likelihoods into flag scores
+var visionLikelihoods = map[string]float64{
+	"VERY_UNLIKELY": 0,
+	"UNLIKELY":      0.25,
+	"POSSIBLE":      0.5,
+	"LIKELY":        0.75,
+	"VERY_LIKELY":   1,
+}
+
+// VisionAdapter scans images with Google Cloud Vision SafeSearch detection.
+// It flags adult, racy and violent content; spoof and medical likelihoods are
+// not moderation concerns and are left out.
+type VisionAdapter struct {
+	service *vision.Service
+}
+
+// NewVisionAdapter creates a Vision API client; empty credentials use
+// Application Default Credentials
+func NewVisionAdapter(ctx context.Context, credentialsJSON []byte) (*VisionAdapter, error) {
+	clientOptions := []option.ClientOption{option.WithScopes(vision.CloudVisionScope)}
+	if len(credentialsJSON) > 0 {
+		clientOptions = append(clientOptions, option.WithCredentialsJSON(credentialsJSON))
+	}
+
+	service, err := vision.NewService(ctx, clientOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("create vision client: %w", err)
+	}
+	return &VisionAdapter{service: service}, nil
+}
+
+// NewVisionAdapterFromConfig builds an adapter with credentials from the
+// "vision-credentials" secret or VISION_CREDENTIALS, falling back to
+// Application Default Credentials
+func NewVisionAdapterFromConfig(ctx context.Context) (*VisionAdapter, error) {
+	credentials, err := config.GetSecret("vision-credentials", "VISION_CREDENTIALS")
+	switch {
+	case err == nil:
+		return NewVisionAdapter(ctx, []byte(credentials))
+	case errors.Is(err, config.ErrSecretNotFound):
+		log.Println("🔐 No Vision credentials configured, using Application Default Credentials")
+		return NewVisionAdapter(ctx, nil)
+	default:
+		return nil, err
+	}
+}
+
+// Scan runs SafeSearch detection on an image at a gs:// or https:// URI
+func (a *VisionAdapter) Scan(ctx context.Context, content Content) ([]models.ModerationFlag, error) {
+	if content.Kind != models.ModerationImage {
+		return nil, fmt.Errorf("%w: vision scans images only", ErrUnsupportedContent)
+	}
+
+	response, err := a.service.Images.Annotate(&vision.BatchAnnotateImagesRequest{
+		Requests: []*vision.AnnotateImageRequest{{
+			Image:    &vision.Image{Source: &vision.ImageSource{ImageUri: content.URI}},
+			Features: []*vision.Feature{{Type: "SAFE_SEARCH_DETECTION"}},
+		}},
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("annotate image: %w", err)
+	}
+	if len(response.Responses) == 0 {
+		return nil, fmt.Errorf("annotate image: empty response")
+	}
+	result := response.Responses[0]
+	if result.Error != nil {
+		return nil, fmt.Errorf("annotate image: %s", result.Error.Message)
+	}
+	if result.SafeSearchAnnotation == nil {
+		return nil, fmt.Errorf("annotate image: no SafeSearch annotation")
+	}
+
+	annotation := result.SafeSearchAnnotation
+	return []models.ModerationFlag{
+		{Category: "adult", Score: visionLikelihoods[annotation.Adult]},
+		{Category: "racy", Score: visionLikelihoods[annotation.Racy]},
+		{Category: "violence", Score: visionLikelihoods[annotation.Violence]},
+	}, nil
+}
@@ -0,0 +1,41 @@
+package money
+
+import "strings"
+
+// Currency describes an ISO 4217 currency
+type Currency struct {
+	Code     string
+	Exponent int // Number of minor-unit digits, e.g. 2 for cents
+	Symbol   string
+}
+
+var currencies = map[string]Currency{
+	"USD": {Code: "USD", Exponent: 2, Symbol: "$"},
+	"EUR": {Code: "EUR", Exponent: 2, Symbol: "€"},
+	"GBP": {Code: "GBP", Exponent: 2, Symbol: "£"},
+	"INR": {Code: "INR", Exponent: 2, Symbol: "₹"},
+	"AUD": {Code: "AUD", Exponent: 2, Symbol: "A$"},
+	"CAD": {Code: "CAD", Exponent: 2, Symbol: "CA$"},
+	"SGD": {Code: "SGD", Exponent: 2, Symbol: "S$"},
+	"AED": {Code: "AED", Exponent: 2, Symbol: "AED"},
+	"CHF": {Code: "CHF", Exponent: 2, Symbol: "CHF"},
+	"CNY": {Code: "CNY", Exponent: 2, Symbol: "CN¥"},
+	"BRL": {Code: "BRL", Exponent: 2, Symbol: "R$"},
+	"MXN": {Code: "MXN", Exponent: 2, Symbol: "MX$"},
+	"JPY": {Code: "JPY", Exponent: 0, Symbol: "¥"},
+	"KRW": {Code: "KRW", Exponent: 0, Symbol: "₩"},
+	"KWD": {Code: "KWD", Exponent: 3, Symbol: "KWD"},
+	"BHD": {Code: "BHD", Exponent: 3, Symbol: "BHD"},
+}
+
+// RegisterCurrency adds or replaces a currency definition
+func RegisterCurrency(currency Currency) {
+	currency.Code = strings.ToUpper(currency.Code)
+	currencies[currency.Code] = currency
+}
+
+// LookupCurrency returns the definition of an ISO 4217 code
+func LookupCurrency(code string) (Currency, bool) {
+	currency, ok := currencies[strings.ToUpper(code)]
+	return currency, ok
+}
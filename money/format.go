@@ -0,0 +1,84 @@
+package money
+
+import (
+	"strings"
+)
+
+// numberFormat describes how a locale writes numbers and currency symbols
+type numberFormat struct {
+	decimal      string
+	group        string
+	indianGroups bool // 12,34,567 grouping
+	symbolAfter  bool
+}
+
+var numberFormats = map[string]numberFormat{
+	"en":    {decimal: ".", group: ","},
+	"en-in": {decimal: ".", group: ",", indianGroups: true},
+	"hi":    {decimal: ".", group: ",", indianGroups: true},
+	"de":    {decimal: ",", group: ".", symbolAfter: true},
+	"es":    {decimal: ",", group: ".", symbolAfter: true},
+	"it":    {decimal: ",", group: ".", symbolAfter: true},
+	"fr":    {decimal: ",", group: " ", symbolAfter: true},
+	"pt-br": {decimal: ",", group: ".", symbolAfter: false},
+	"ja":    {decimal: ".", group: ","},
+}
+
+// Format renders m for display in a locale, e.g. "$1,234.50" (en) or "1.234,50 €" (de)
+func (m Money) Format(locale string) string {
+	def, ok := LookupCurrency(m.Currency)
+	if !ok {
+		return m.String()
+	}
+
+	format := lookupNumberFormat(locale)
+	whole, fraction := m.split(def.Exponent)
+
+	number := groupDigits(whole, format)
+	if def.Exponent > 0 {
+		number += format.decimal + fraction
+	}
+
+	sign := ""
+	if m.Amount < 0 {
+		sign = "-"
+	}
+	if format.symbolAfter {
+		return sign + number + " " + def.Symbol
+	}
+	return sign + def.Symbol + number
+}
+
+func lookupNumberFormat(locale string) numberFormat {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	if format, ok := numberFormats[locale]; ok {
+		return format
+	}
+	if base, _, found := strings.Cut(locale, "-"); found {
+		if format, ok := numberFormats[base]; ok {
+			return format
+		}
+	}
+	return numberFormats["en"]
+}
+
+// groupDigits inserts group separators into a string of digits
+func groupDigits(digits string, format numberFormat) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+
+	head, tail := digits[:len(digits)-3], digits[len(digits)-3:]
+	size := 3
+	if format.indianGroups {
+		size = 2
+	}
+
+	var groups []string
+	for len(head) > size {
+		groups = append([]string{head[len(head)-size:]}, groups...)
+		head = head[:len(head)-size]
+	}
+	groups = append([]string{head}, groups...)
+	return strings.Join(append(groups, tail), format.group)
+}
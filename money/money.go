@@ -0,0 +1,262 @@
+// Package money represents monetary amounts as integer minor units (e.g. cents)
+// with a currency code, so prices are never handled as floats.
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Errors returned by money operations
+var (
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrOverflow         = errors.New("amount overflow")
+	ErrInvalidAmount    = errors.New("invalid amount")
+)
+
+// Money is an amount in the currency's minor unit
+type Money struct {
+	Amount   int64  `bson:"amount" json:"amount"`
+	Currency string `bson:"currency" json:"currency"`
+}
+
+// New creates Money from an amount in minor units
+func New(amount int64, currency string) (Money, error) {
+	code := strings.ToUpper(currency)
+	if _, ok := LookupCurrency(code); !ok {
+		return Money{}, fmt.Errorf("%w: %s", ErrUnknownCurrency, currency)
+	}
+	return Money{Amount: amount, Currency: code}, nil
+}
+
+// Zero returns a zero amount in currency
+func Zero(currency string) (Money, error) {
+	return New(0, currency)
+}
+
+// Parse reads a decimal major-unit string such as "12.34" or "-0.5" exactly
+func Parse(value, currency string) (Money, error) {
+	def, ok := LookupCurrency(currency)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %s", ErrUnknownCurrency, currency)
+	}
+
+	value = strings.TrimSpace(value)
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimLeft(value, "+-")
+
+	whole, fraction, _ := strings.Cut(value, ".")
+	if whole == "" && fraction == "" {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, value)
+	}
+	if len(fraction) > def.Exponent {
+		return Money{}, fmt.Errorf("%w: %s allows %d decimal places", ErrInvalidAmount, def.Code, def.Exponent)
+	}
+	fraction += strings.Repeat("0", def.Exponent-len(fraction))
+
+	var amount int64
+	for _, r := range whole + fraction {
+		if r < '0' || r > '9' {
+			return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, value)
+		}
+		if amount > (math.MaxInt64-int64(r-'0'))/10 {
+			return Money{}, ErrOverflow
+		}
+		amount = amount*10 + int64(r-'0')
+	}
+
+	if negative {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: def.Code}, nil
+}
+
+// MustParse is Parse that panics on error, for constants and tests
+func MustParse(value, currency string) Money {
+	m, err := Parse(value, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Add returns m + other
+func (m Money) Add(other Money) (Money, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return Money{}, err
+	}
+	sum := m.Amount + other.Amount
+	if (other.Amount > 0 && sum < m.Amount) || (other.Amount < 0 && sum > m.Amount) {
+		return Money{}, ErrOverflow
+	}
+	return Money{Amount: sum, Currency: m.Currency}, nil
+}
+
+// Sub returns m - other
+func (m Money) Sub(other Money) (Money, error) {
+	if other.Amount == math.MinInt64 {
+		return Money{}, ErrOverflow
+	}
+	return m.Add(other.Negate())
+}
+
+// Multiply returns m * factor
+func (m Money) Multiply(factor int64) (Money, error) {
+	if m.Amount != 0 && factor != 0 {
+		product := m.Amount * factor
+		if product/factor != m.Amount || (m.Amount == -1 && factor == math.MinInt64) {
+			return Money{}, ErrOverflow
+		}
+		return Money{Amount: product, Currency: m.Currency}, nil
+	}
+	return Money{Amount: 0, Currency: m.Currency}, nil
+}
+
+// Percent returns basisPoints/10000 of m, rounded half away from zero (e.g. 1850 = 18.5%)
+func (m Money) Percent(basisPoints int64) (Money, error) {
+	product, err := m.Multiply(basisPoints)
+	if err != nil {
+		return Money{}, err
+	}
+	amount := product.Amount / 10000
+	if remainder := product.Amount % 10000; remainder >= 5000 {
+		amount++
+	} else if remainder <= -5000 {
+		amount--
+	}
+	return Money{Amount: amount, Currency: m.Currency}, nil
+}
+
+// Allocate splits m by ratios without losing minor units; leftovers go to the first parts
+func (m Money) Allocate(ratios ...int64) ([]Money, error) {
+	var total int64
+	for _, ratio := range ratios {
+		if ratio < 0 {
+			return nil, fmt.Errorf("%w: negative ratio", ErrInvalidAmount)
+		}
+		total += ratio
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("%w: ratios sum to zero", ErrInvalidAmount)
+	}
+
+	parts := make([]Money, len(ratios))
+	remainder := m.Amount
+	for i, ratio := range ratios {
+		share, err := m.Multiply(ratio)
+		if err != nil {
+			return nil, err
+		}
+		parts[i] = Money{Amount: share.Amount / total, Currency: m.Currency}
+		remainder -= parts[i].Amount
+	}
+
+	step := int64(1)
+	if remainder < 0 {
+		step = -1
+	}
+	for i := 0; remainder != 0; i = (i + 1) % len(parts) {
+		parts[i].Amount += step
+		remainder -= step
+	}
+	return parts, nil
+}
+
+// Negate returns -m
+func (m Money) Negate() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsNegative reports whether the amount is below zero
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Compare returns -1, 0 or 1 comparing m to other
+func (m Money) Compare(other Money) (int, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.Amount < other.Amount:
+		return -1, nil
+	case m.Amount > other.Amount:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// Equal reports whether m and other have the same amount and currency
+func (m Money) Equal(other Money) bool {
+	return m.Currency == other.Currency && m.Amount == other.Amount
+}
+
+// Decimal returns the amount as a plain major-unit string, e.g. "1234.50"
+func (m Money) Decimal() string {
+	def, _ := LookupCurrency(m.Currency)
+	whole, fraction := m.split(def.Exponent)
+
+	sign := ""
+	if m.Amount < 0 {
+		sign = "-"
+	}
+	if def.Exponent == 0 {
+		return sign + whole
+	}
+	return sign + whole + "." + fraction
+}
+
+// String returns the amount with its currency code, e.g. "1234.50 USD"
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// UnmarshalJSON validates the currency code while decoding
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Amount   int64  `json:"amount"`
+		Currency string `json:"currency"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	parsed, err := New(raw.Amount, raw.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// split returns the absolute whole and zero-padded fractional digits
+func (m Money) split(exponent int) (string, string) {
+	amount := m.Amount
+	digits := fmt.Sprintf("%d", amount)
+	digits = strings.TrimPrefix(digits, "-")
+
+	if exponent == 0 {
+		return digits, ""
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	return digits[:len(digits)-exponent], digits[len(digits)-exponent:]
+}
+
+func (m Money) sameCurrency(other Money) error {
+	if m.Currency != other.Currency {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	return nil
+}
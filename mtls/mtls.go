@@ -0,0 +1,251 @@
+// Package mtls secures internal traffic with mutual TLS. Certificates are
+// loaded through config.GetSecret (Secret Manager or env) and polled so that a
+// rotated certificate is picked up without a restart:
+//
+//	credentials, err := mtls.NewFromConfig()
+//	defer credentials.Close()
+//
+//	go mtls.Listen(app, ":8443", credentials)
+//	client := credentials.HTTPClient(10 * time.Second)
+package mtls
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/config"
+)
+
+// DefaultReloadInterval is how often certificates are re-read when
+// Options.ReloadInterval is zero
+const DefaultReloadInterval = 5 * time.Minute
+
+var ErrNoTrustedCAs = errors.New("mtls: no trusted CA certificates")
+
+// Options names the secrets holding PEM encoded credentials
+type Options struct {
+	CertSecret, CertEnv string // Certificate chain (default "mtls-cert" / MTLS_CERT)
+	KeySecret, KeyEnv   string // Private key (default "mtls-key" / MTLS_KEY)
+	CASecret, CAEnv     string // CAs trusted for peers (default "mtls-ca" / MTLS_CA)
+
+	ReloadInterval time.Duration // How often to check for rotation (default 5m, negative disables)
+}
+
+// Credentials holds the current certificate and trusted CAs and builds TLS
+// configs that always use the latest ones
+type Credentials struct {
+	options Options
+
+	certificate *tls.Certificate
+	roots       *x509.CertPool
+	checksum    [sha256.Size]byte
+	mu          sync.RWMutex
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// New loads the credentials and starts watching them for rotation
+func New(options Options) (*Credentials, error) {
+	options = withDefaults(options)
+	credentials := &Credentials{options: options, stop: make(chan struct{})}
+	if err := credentials.Reload(); err != nil {
+		return nil, err
+	}
+
+	if options.ReloadInterval > 0 {
+		go credentials.watch()
+	}
+	return credentials, nil
+}
+
+// NewFromConfig loads credentials from the default secrets, polling every
+// MTLS_RELOAD_INTERVAL
+func NewFromConfig() (*Credentials, error) {
+	options := Options{}
+	if value := config.GetEnv("MTLS_RELOAD_INTERVAL", ""); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MTLS_RELOAD_INTERVAL %q: %w", value, err)
+		}
+		options.ReloadInterval = interval
+	}
+	return New(options)
+}
+
+func withDefaults(options Options) Options {
+	if options.CertSecret == "" && options.CertEnv == "" {
+		options.CertSecret, options.CertEnv = "mtls-cert", "MTLS_CERT"
+	}
+	if options.KeySecret == "" && options.KeyEnv == "" {
+		options.KeySecret, options.KeyEnv = "mtls-key", "MTLS_KEY"
+	}
+	if options.CASecret == "" && options.CAEnv == "" {
+		options.CASecret, options.CAEnv = "mtls-ca", "MTLS_CA"
+	}
+	if options.ReloadInterval == 0 {
+		options.ReloadInterval = DefaultReloadInterval
+	}
+	return options
+}
+
+// Reload re-reads the secrets, swapping in the new credentials when they
+// changed. Connections already established keep their certificates.
+func (c *Credentials) Reload() error {
+	certPEM, err := config.GetSecret(c.options.CertSecret, c.options.CertEnv)
+	if err != nil {
+		return fmt.Errorf("mtls certificate: %w", err)
+	}
+	keyPEM, err := config.GetSecret(c.options.KeySecret, c.options.KeyEnv)
+	if err != nil {
+		return fmt.Errorf("mtls key: %w", err)
+	}
+	caPEM, err := config.GetSecret(c.options.CASecret, c.options.CAEnv)
+	if err != nil {
+		return fmt.Errorf("mtls CA: %w", err)
+	}
+
+	checksum := sha256.Sum256([]byte(certPEM + "\n" + keyPEM + "\n" + caPEM))
+	c.mu.RLock()
+	unchanged := c.certificate != nil && checksum == c.checksum
+	c.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	certificate, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		return fmt.Errorf("mtls key pair: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM([]byte(caPEM)) {
+		return ErrNoTrustedCAs
+	}
+
+	c.mu.Lock()
+	reloaded := c.certificate != nil
+	c.certificate, c.roots, c.checksum = &certificate, roots, checksum
+	c.mu.Unlock()
+
+	if reloaded {
+		log.Println("🔄 mTLS certificates reloaded")
+	}
+	return nil
+}
+
+// Close stops watching for rotation
+func (c *Credentials) Close() {
+	c.stopOnce.Do(func() { close(c.stop) })
+}
+
+func (c *Credentials) watch() {
+	ticker := time.NewTicker(c.options.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.Reload(); err != nil {
+				// Keep serving with the previous certificates
+				log.Printf("⚠️  Failed to reload mTLS certificates: %v", err)
+			}
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+func (c *Credentials) current() (*tls.Certificate, *x509.CertPool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.certificate, c.roots
+}
+
+// ServerTLSConfig requires clients to present a certificate signed by the trusted CAs
+func (c *Credentials) ServerTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			certificate, roots := c.current()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*certificate},
+				ClientCAs:    roots,
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+			}, nil
+		},
+	}
+}
+
+// ClientTLSConfig presents the current certificate and verifies the server
+// against the trusted CAs (the hostname dialed unless serverName is set)
+func (c *Credentials) ClientTLSConfig(serverName string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			certificate, _ := c.current()
+			return certificate, nil
+		},
+		// Verification is done in VerifyConnection so that rotated CAs apply to
+		// new connections; the standard check would pin the pool at creation
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			_, roots := c.current()
+			return verifyPeer(state, roots, state.ServerName, x509.ExtKeyUsageServerAuth)
+		},
+	}
+}
+
+// HTTPClient returns a client that authenticates with the current certificate
+func (c *Credentials) HTTPClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = c.ClientTLSConfig("")
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// Listen serves app over mutual TLS on addr
+func Listen(app *fiber.App, addr string, credentials *Credentials) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return app.Listener(tls.NewListener(listener, credentials.ServerTLSConfig()))
+}
+
+// PeerName returns the common name of the verified client certificate, or
+// empty when the request did not arrive over mutual TLS
+func PeerName(c *fiber.Ctx) string {
+	state := c.Context().TLSConnectionState()
+	if state == nil || len(state.VerifiedChains) == 0 {
+		return ""
+	}
+	return state.VerifiedChains[0][0].Subject.CommonName
+}
+
+// verifyPeer checks the peer chain against roots
+func verifyPeer(state tls.ConnectionState, roots *x509.CertPool, dnsName string, usage x509.ExtKeyUsage) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("mtls: peer presented no certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, certificate := range state.PeerCertificates[1:] {
+		intermediates.AddCert(certificate)
+	}
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       dnsName,
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	})
+	return err
+}
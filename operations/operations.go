@@ -0,0 +1,257 @@
+// Package operations runs slow tasks (media processing, bulk imports) in the
+// background and tracks them as Operation documents that clients poll at
+// GET /operations/:id, see routes.SetupOperationRoutes:
+//
+//	op, err := operations.Start(c.UserContext(), operations.Spec{
+//		Type:           "media.transcode",
+//		OrganizationID: organizationID,
+//		CreatedBy:      userID,
+//	}, func(ctx context.Context, progress *operations.Progress) (interface{}, error) {
+//		progress.Update(50, "Transcoding")
+//		return transcode(ctx, upload)
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	return operations.Accepted(c, op)
+//
+// When an operation finishes it is published on CompletedSubject and, when
+// the Spec has a WebhookURL, posted there. Clients see the message of a
+// failure only when the task returns an *Error; other errors are logged and
+// reported as "operation failed".
+package operations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/messaging"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/repo"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// CompletedSubject carries every finished operation
+const CompletedSubject = "operations.completed"
+
+// Spec describes an operation to start
+type Spec struct {
+	Type           string // What the operation does, e.g. "import.users"
+	OrganizationID string // Only this organization can read the operation
+	CreatedBy      string
+	WebhookURL     string // Receives the finished operation as JSON, optional
+}
+
+// Task performs the work of an operation; its result is stored on success
+type Task func(ctx context.Context, progress *Progress) (interface{}, error)
+
+// Error is a task failure whose message is meant for the operation's clients,
+// e.g. "row 12: invalid email"
+type Error struct {
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Errorf returns an *Error with a formatted message
+func Errorf(format string, args ...interface{}) error {
+	return &Error{Message: fmt.Sprintf(format, args...)}
+}
+
+// genericFailure is what clients see of errors that are not an *Error
+const genericFailure = "operation failed"
+
+var (
+	store   = repo.New[models.Operation](models.Operation{}.CollectionName(), repo.WithULIDKeys())
+	running sync.WaitGroup
+
+	// webhookClient bounds each delivery attempt, so an unresponsive
+	// receiver cannot hold a finished operation's goroutine
+	webhookClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// Start stores a pending operation and runs task in the background. The task
+// outlives the request: its context keeps ctx's values but not its deadline.
+func Start(ctx context.Context, spec Spec, task Task) (*models.Operation, error) {
+	now := utils.Now()
+	op := &models.Operation{
+		ID:             utils.NewID(),
+		OrganizationID: spec.OrganizationID,
+		Type:           spec.Type,
+		Status:         models.OperationPending,
+		WebhookURL:     spec.WebhookURL,
+		CreatedBy:      spec.CreatedBy,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := store.Insert(ctx, op); err != nil {
+		return nil, fmt.Errorf("failed to start %s operation: %w", spec.Type, err)
+	}
+
+	running.Add(1)
+	go run(context.WithoutCancel(ctx), *op, task)
+	return op, nil
+}
+
+// Get returns an operation of organizationID; others are repo.ErrNotFound
+func Get(ctx context.Context, id, organizationID string) (*models.Operation, error) {
+	if !utils.IsValidID(id) {
+		return nil, repo.ErrNotFound
+	}
+	return store.FindOne(ctx, bson.M{"_id": id, "organization_id": organizationID})
+}
+
+// Accepted responds 202 with the operation and a Location to poll
+func Accepted(c *fiber.Ctx, op *models.Operation) error {
+	c.Location("/operations/" + op.ID)
+	return c.Status(http.StatusAccepted).JSON(op)
+}
+
+// Wait blocks until every operation started by this process has finished or
+// ctx is done. Operations interrupted by a shutdown stay "running".
+func Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("operations still running at shutdown: %w", ctx.Err())
+	}
+}
+
+// Progress reports how far a running operation has got
+type Progress struct {
+	ctx context.Context
+	id  string
+}
+
+// Update stores the percent complete (clamped to 0-100) and a status message
+func (p *Progress) Update(percent int, message string) error {
+	percent = max(0, min(percent, 100))
+	return store.UpdateByID(p.ctx, p.id, bson.M{"$set": bson.M{
+		"progress":   percent,
+		"message":    message,
+		"updated_at": utils.Now(),
+	}})
+}
+
+// run executes task and records its outcome
+func run(ctx context.Context, op models.Operation, task Task) {
+	defer running.Done()
+
+	if err := store.UpdateByID(ctx, op.ID, bson.M{"$set": bson.M{
+		"status":     models.OperationRunning,
+		"updated_at": utils.Now(),
+	}}); err != nil {
+		utils.Log(ctx).Warn("failed to mark operation running", "operation_id", op.ID, "error", err)
+	}
+
+	result, err := execute(ctx, task, &Progress{ctx: ctx, id: op.ID})
+
+	now := utils.Now()
+	op.UpdatedAt = now
+	op.CompletedAt = &now
+	if err == nil && result != nil {
+		if op.Result, err = json.Marshal(result); err != nil {
+			err = fmt.Errorf("operation result is not JSON: %w", err)
+		}
+	}
+	outcome := bson.M{"updated_at": op.UpdatedAt, "completed_at": op.CompletedAt}
+	if err != nil {
+		op.Status = models.OperationFailed
+		op.Error = publicError(err)
+		outcome["error"] = op.Error
+		utils.Log(ctx).Error("operation failed", "operation_id", op.ID, "type", op.Type, "error", err)
+	} else {
+		op.Status = models.OperationSucceeded
+		op.Progress = 100
+		outcome["progress"] = op.Progress
+		outcome["result"] = op.Result
+	}
+	outcome["status"] = op.Status
+
+	if err := store.UpdateByID(ctx, op.ID, bson.M{"$set": outcome}); err != nil {
+		utils.Log(ctx).Error("failed to record operation outcome", "operation_id", op.ID, "error", err)
+	}
+
+	notify(ctx, op)
+}
+
+// publicError returns the message of err that clients may see
+func publicError(err error) string {
+	var public *Error
+	if errors.As(err, &public) {
+		return public.Message
+	}
+	return genericFailure
+}
+
+// execute runs task, turning a panic into a failure so the operation still completes
+func execute(ctx context.Context, task Task, progress *Progress) (result interface{}, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("operation panicked: %v", recovered)
+		}
+	}()
+	return task(ctx, progress)
+}
+
+// notify publishes the finished operation and posts it to its webhook
+func notify(ctx context.Context, op models.Operation) {
+	if messaging.Default() != nil {
+		if err := messaging.Publish(ctx, CompletedSubject, op); err != nil {
+			utils.Log(ctx).Warn("failed to publish operation completion", "operation_id", op.ID, "error", err)
+		}
+	}
+
+	if op.WebhookURL != "" {
+		if err := postWebhook(ctx, op); err != nil {
+			utils.Log(ctx).Warn("failed to deliver operation webhook", "operation_id", op.ID, "error", err)
+		}
+	}
+}
+
+// postWebhook delivers a finished operation, retrying network errors and 5xx responses
+func postWebhook(ctx context.Context, op models.Operation) error {
+	body, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+
+	return utils.Retry(ctx, utils.DefaultRetryPolicy, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, op.WebhookURL, bytes.NewReader(body))
+		if err != nil {
+			return utils.PermanentError(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := webhookClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+		}
+		if resp.StatusCode >= 300 {
+			return utils.PermanentError(fmt.Errorf("webhook returned status %d", resp.StatusCode))
+		}
+		return nil
+	})
+}
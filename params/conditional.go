@@ -0,0 +1,38 @@
+package params
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/repo"
+)
+
+// SetVersion sets the ETag of a versioned document so clients can send it
+// back in If-Match when they edit it
+func SetVersion(c *fiber.Ctx, version int64) {
+	c.Set(fiber.HeaderETag, repo.ETag(version))
+}
+
+// IfMatch returns the document version the client last read, from the
+// If-Match header. A missing header fails with 428 Precondition Required so
+// clients cannot overwrite changes blindly; a tag that is not a version fails
+// with 412 Precondition Failed. Pass the version to a versioned write:
+//
+//	version, err := params.IfMatch(c)
+//	if err != nil {
+//		return params.Respond(c, err)
+//	}
+//	next, err := experiences.UpdateByIDVersion(ctx, id, version, update)
+//	if err != nil {
+//		return params.Respond(c, err) // 412 with the current ETag on conflict
+//	}
+//	params.SetVersion(c, next)
+func IfMatch(c *fiber.Ctx) (int64, error) {
+	header := c.Get(fiber.HeaderIfMatch)
+	if header == "" {
+		return 0, fiber.NewError(fiber.StatusPreconditionRequired, "If-Match header is required")
+	}
+	version, err := repo.ParseETag(header)
+	if err != nil {
+		return 0, fiber.NewError(fiber.StatusPreconditionFailed, "If-Match does not name a document version")
+	}
+	return version, nil
+}
@@ -0,0 +1,190 @@
+// Package params extracts typed path and query parameters with consistent
+// validation errors:
+//
+//	page, err := params.IntBetween(c, "page", 1, 1, 1000)
+//	if err != nil {
+//		return params.Respond(c, err)
+//	}
+//
+// Each extractor reads the route parameter of that name, falling back to the
+// query string. Invalid values produce an *Error, which Respond (and the app
+// error handler) render as 400 {"error": "Invalid page: ...", "field": "page"}.
+package params
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/repo"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Error is a parameter that failed validation
+type Error struct {
+	Field   string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("Invalid %s: %s", e.Field, e.Message)
+}
+
+func invalid(name, format string, args ...interface{}) *Error {
+	return &Error{Field: name, Message: fmt.Sprintf(format, args...)}
+}
+
+// Respond writes a 400 for parameter errors, a 412 with the current ETag for
+// version conflicts and the status and message of a *fiber.Error, and returns
+// any other error unchanged
+func Respond(c *fiber.Ctx, err error) error {
+	var paramErr *Error
+	if errors.As(err, &paramErr) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": paramErr.Error(),
+			"field": paramErr.Field,
+		})
+	}
+	var conflict *repo.ConflictError
+	if errors.As(err, &conflict) {
+		SetVersion(c, conflict.Current)
+		return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+			"error":           "Document was modified by someone else",
+			"current_version": conflict.Current,
+		})
+	}
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{"error": fiberErr.Message})
+	}
+	return err
+}
+
+// value returns the route parameter name, or the query parameter when there is none
+func value(c *fiber.Ctx, name string) string {
+	if param := c.Params(name); param != "" {
+		return param
+	}
+	return c.Query(name)
+}
+
+// String returns the parameter, or def when it is absent
+func String(c *fiber.Ctx, name, def string) string {
+	if raw := value(c, name); raw != "" {
+		return raw
+	}
+	return def
+}
+
+// Required returns the parameter, failing when it is absent
+func Required(c *fiber.Ctx, name string) (string, error) {
+	raw := value(c, name)
+	if raw == "" {
+		return "", invalid(name, "is required")
+	}
+	return raw, nil
+}
+
+// Int parses an integer parameter, returning def when it is absent
+func Int(c *fiber.Ctx, name string, def int) (int, error) {
+	raw := value(c, name)
+	if raw == "" {
+		return def, nil
+	}
+	parsed, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, invalid(name, "must be an integer")
+	}
+	return parsed, nil
+}
+
+// IntBetween is Int limited to min..max inclusive
+func IntBetween(c *fiber.Ctx, name string, def, min, max int) (int, error) {
+	parsed, err := Int(c, name, def)
+	if err != nil {
+		return 0, err
+	}
+	if parsed < min || parsed > max {
+		return 0, invalid(name, "must be between %d and %d", min, max)
+	}
+	return parsed, nil
+}
+
+// Bool parses true/false (also 1/0), returning def when the parameter is absent
+func Bool(c *fiber.Ctx, name string, def bool) (bool, error) {
+	raw := value(c, name)
+	if raw == "" {
+		return def, nil
+	}
+	parsed, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, invalid(name, "must be true or false")
+	}
+	return parsed, nil
+}
+
+// Date parses an RFC 3339 timestamp or YYYY-MM-DD date; an absent parameter
+// is the zero time
+func Date(c *fiber.Ctx, name string) (time.Time, error) {
+	raw := value(c, name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	parsed, err := utils.ParseTime(raw)
+	if err != nil {
+		return time.Time{}, invalid(name, "must be an RFC 3339 time or YYYY-MM-DD date")
+	}
+	return parsed, nil
+}
+
+// Period parses a look-back period such as 24h, 7d or 4w (see utils.ParsePeriod),
+// returning def when the parameter is absent
+func Period(c *fiber.Ctx, name, def string) (time.Duration, error) {
+	parsed, err := utils.ParsePeriod(String(c, name, def))
+	if err != nil {
+		return 0, invalid(name, "must be a period like 24h, 7d or 4w")
+	}
+	return parsed, nil
+}
+
+// Enum returns the parameter when it is one of allowed; an absent parameter is empty
+func Enum(c *fiber.Ctx, name string, allowed ...string) (string, error) {
+	raw := value(c, name)
+	if raw == "" {
+		return "", nil
+	}
+	for _, option := range allowed {
+		if raw == option {
+			return raw, nil
+		}
+	}
+	return "", invalid(name, "must be one of %s", strings.Join(allowed, ", "))
+}
+
+// ObjectID parses a Mongo ObjectID parameter, failing when it is absent
+func ObjectID(c *fiber.Ctx, name string) (primitive.ObjectID, error) {
+	raw := value(c, name)
+	if raw == "" {
+		return primitive.NilObjectID, invalid(name, "is required")
+	}
+	parsed, err := utils.ToObjectIDE(raw)
+	if err != nil {
+		return primitive.NilObjectID, invalid(name, "must be a 24 character hex ID")
+	}
+	return parsed, nil
+}
+
+// List splits a comma separated parameter, dropping empty items
+func List(c *fiber.Ctx, name string) []string {
+	var items []string
+	for _, item := range strings.Split(value(c, name), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,141 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/praleedsuvarna/shared-libs/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// EarthRadiusMeters converts distances to the radians $centerSphere expects
+const EarthRadiusMeters = 6378100.0
+
+// distanceField carries the $geoNear distance out of the aggregation
+const distanceField = "_geo_distance"
+
+// NearQuery selects documents around a point; the field queried needs a
+// models.GeoIndex
+type NearQuery struct {
+	Point       models.GeoPoint
+	MaxDistance float64 // Meters; 0 means unlimited
+	MinDistance float64 // Meters
+	Filter      bson.M  // Additional conditions, e.g. the organization
+}
+
+// Nearby is a document with its distance from the queried point
+type Nearby[T any] struct {
+	Item     T       `json:"item"`
+	Distance float64 `json:"distance"` // Meters
+}
+
+// Near returns a page of the documents whose GeoJSON field is closest to
+// query.Point, nearest first; page.Sort is ignored
+func (r *Repository[T]) Near(ctx context.Context, field string, query NearQuery, page Page) (*PageResult[Nearby[T]], error) {
+	if err := query.Point.Validate(); err != nil {
+		return nil, r.wrap("near", err)
+	}
+	page = page.normalized()
+
+	geoNear := bson.M{
+		"near":          query.Point,
+		"key":           field,
+		"distanceField": distanceField,
+		"spherical":     true,
+	}
+	if query.MaxDistance > 0 {
+		geoNear["maxDistance"] = query.MaxDistance
+	}
+	if query.MinDistance > 0 {
+		geoNear["minDistance"] = query.MinDistance
+	}
+	if len(query.Filter) > 0 {
+		geoNear["query"] = query.Filter
+	}
+
+	// $near cannot be counted, so the page and the total come from one $facet
+	pipeline := mongo.Pipeline{
+		{{Key: "$geoNear", Value: geoNear}},
+		{{Key: "$facet", Value: bson.M{
+			"items": bson.A{
+				bson.M{"$skip": (page.Page - 1) * page.Limit},
+				bson.M{"$limit": page.Limit},
+			},
+			"total": bson.A{bson.M{"$count": "count"}},
+		}}},
+	}
+
+	ctx, done, err := r.begin(ctx)
+	if err != nil {
+		return nil, r.wrap("near", err)
+	}
+	defer done()
+
+	cursor, err := r.Collection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, r.wrap("near", err)
+	}
+	defer cursor.Close(ctx)
+
+	var facets []struct {
+		Items []bson.Raw `bson:"items"`
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+	}
+	if err := cursor.All(ctx, &facets); err != nil {
+		return nil, r.wrap("near", err)
+	}
+
+	result := &PageResult[Nearby[T]]{Items: []Nearby[T]{}, Page: page.Page, Limit: page.Limit}
+	if len(facets) == 0 {
+		return result, nil
+	}
+	if len(facets[0].Total) > 0 {
+		result.Total = facets[0].Total[0].Count
+	}
+	for _, raw := range facets[0].Items {
+		var nearby Nearby[T]
+		if err := bson.Unmarshal(raw, &nearby.Item); err != nil {
+			return nil, r.wrap("near", err)
+		}
+		nearby.Distance, _ = raw.Lookup(distanceField).DoubleOK()
+		result.Items = append(result.Items, nearby)
+	}
+	return result, nil
+}
+
+// Within returns a page of the documents whose GeoJSON field lies inside polygon
+func (r *Repository[T]) Within(ctx context.Context, field string, polygon models.GeoPolygon, filter bson.M, page Page) (*PageResult[T], error) {
+	return r.Find(ctx, withGeoFilter(filter, GeoWithin(field, polygon)), page)
+}
+
+// WithinRadius returns a page of the documents whose GeoJSON field lies
+// within meters of center, unordered; use Near to sort by distance
+func (r *Repository[T]) WithinRadius(ctx context.Context, field string, center models.GeoPoint, meters float64, filter bson.M, page Page) (*PageResult[T], error) {
+	if err := center.Validate(); err != nil {
+		return nil, r.wrap("find", err)
+	}
+	return r.Find(ctx, withGeoFilter(filter, GeoWithinRadius(field, center, meters)), page)
+}
+
+// GeoWithin is the filter matching documents whose field lies inside polygon
+func GeoWithin(field string, polygon models.GeoPolygon) bson.M {
+	return bson.M{field: bson.M{"$geoWithin": bson.M{"$geometry": polygon}}}
+}
+
+// GeoWithinRadius is the filter matching documents whose field lies within
+// meters of center
+func GeoWithinRadius(field string, center models.GeoPoint, meters float64) bson.M {
+	return bson.M{field: bson.M{"$geoWithin": bson.M{
+		"$centerSphere": bson.A{center.Coordinates, meters / EarthRadiusMeters},
+	}}}
+}
+
+// withGeoFilter combines a geo condition with the caller's filter
+func withGeoFilter(filter, geo bson.M) bson.M {
+	if len(filter) == 0 {
+		return geo
+	}
+	return bson.M{"$and": bson.A{filter, geo}}
+}
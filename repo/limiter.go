@@ -0,0 +1,124 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ErrOverloaded is returned when an operation waited longer than the queue
+// timeout for a free concurrency slot
+var ErrOverloaded = errors.New("too many concurrent operations")
+
+var (
+	mongoOperationsInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "mongo_operations_in_flight",
+		Help: "Repository operations currently holding a concurrency slot, partitioned by collection.",
+	}, []string{"collection"})
+
+	mongoOperationQueueWait = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "mongo_operation_queue_wait_seconds",
+		Help:    "Time repository operations waited for a concurrency slot, partitioned by collection.",
+		Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"collection"})
+
+	mongoOperationsRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mongo_operations_rejected_total",
+		Help: "Repository operations rejected after waiting for a concurrency slot, partitioned by collection.",
+	}, []string{"collection"})
+)
+
+// Limiter caps the number of concurrent operations on a collection so a burst
+// of slow queries queues briefly and then fails fast instead of exhausting the
+// connection pool
+type Limiter struct {
+	collection   string
+	slots        chan struct{}
+	queueTimeout time.Duration
+}
+
+var (
+	limiters   = map[string]*Limiter{}
+	limitersMu sync.Mutex
+)
+
+// WithConcurrencyLimit allows at most max concurrent operations on the
+// collection; an operation that cannot get a slot within queueTimeout (default
+// 1s) fails with ErrOverloaded. Repositories over the same collection share
+// the limit set by the first one created.
+func WithConcurrencyLimit(max int, queueTimeout time.Duration) Option {
+	return func(o *settings) {
+		o.maxConcurrent = max
+		o.queueTimeout = queueTimeout
+	}
+}
+
+// limiterFor returns the shared limiter for collection, creating it on first use
+func limiterFor(collection string, max int, queueTimeout time.Duration) *Limiter {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+
+	if limiter, ok := limiters[collection]; ok {
+		return limiter
+	}
+	if queueTimeout <= 0 {
+		queueTimeout = time.Second
+	}
+	limiter := &Limiter{
+		collection:   collection,
+		slots:        make(chan struct{}, max),
+		queueTimeout: queueTimeout,
+	}
+	limiters[collection] = limiter
+	return limiter
+}
+
+// Acquire waits for a free slot and returns the function that releases it.
+// It fails with ErrOverloaded after the queue timeout, or with ctx's error.
+func (l *Limiter) Acquire(ctx context.Context) (func(), error) {
+	started := time.Now()
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+	case <-timer.C:
+		mongoOperationsRejected.WithLabelValues(l.collection).Inc()
+		return nil, ErrOverloaded
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	mongoOperationQueueWait.WithLabelValues(l.collection).Observe(time.Since(started).Seconds())
+	mongoOperationsInFlight.WithLabelValues(l.collection).Inc()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-l.slots
+			mongoOperationsInFlight.WithLabelValues(l.collection).Dec()
+		})
+	}, nil
+}
+
+// begin takes a concurrency slot when the repository is limited and applies
+// the operation timeout; the returned function must be called when done
+func (r *Repository[T]) begin(ctx context.Context) (context.Context, func(), error) {
+	release := func() {}
+	if r.limiter != nil {
+		var err error
+		if release, err = r.limiter.Acquire(ctx); err != nil {
+			return ctx, nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	return ctx, func() {
+		cancel()
+		release()
+	}, nil
+}
@@ -0,0 +1,368 @@
+// Package repo provides a generic MongoDB repository shared by services.
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Sentinel errors returned by repositories; match them with errors.Is
+var (
+	ErrNotFound  = errors.New("document not found")
+	ErrInvalidID = errors.New("invalid id")
+)
+
+// IDStrategy selects the primary key type of a collection
+type IDStrategy int
+
+const (
+	ObjectIDKeys IDStrategy = iota // _id is a primitive.ObjectID
+	ULIDKeys                       // _id is a ULID string from utils.NewID
+)
+
+// Default and maximum page sizes for Find
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// Repository performs CRUD operations on one collection, decoding into T
+type Repository[T any] struct {
+	collectionName string
+	idStrategy     IDStrategy
+	timeout        time.Duration
+	limiter        *Limiter // nil when concurrency is unlimited
+}
+
+// Option customizes a Repository
+type Option func(*settings)
+
+type settings struct {
+	idStrategy    IDStrategy
+	timeout       time.Duration
+	maxConcurrent int
+	queueTimeout  time.Duration
+}
+
+// WithULIDKeys stores string ULID primary keys instead of ObjectIDs
+func WithULIDKeys() Option {
+	return func(o *settings) { o.idStrategy = ULIDKeys }
+}
+
+// WithTimeout sets the per-operation timeout (default 10s)
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *settings) { o.timeout = timeout }
+}
+
+// New creates a repository for a collection; the stored name carries
+// COLLECTION_PREFIX (see config.CollectionName)
+func New[T any](collectionName string, opts ...Option) *Repository[T] {
+	o := settings{idStrategy: ObjectIDKeys, timeout: 10 * time.Second}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	r := &Repository[T]{
+		collectionName: collectionName,
+		idStrategy:     o.idStrategy,
+		timeout:        o.timeout,
+	}
+	if o.maxConcurrent > 0 {
+		r.limiter = limiterFor(collectionName, o.maxConcurrent, o.queueTimeout)
+	}
+	return r
+}
+
+// Collection returns the underlying Mongo collection, with the configured prefix
+func (r *Repository[T]) Collection() *mongo.Collection {
+	return config.GetCollection(r.collectionName)
+}
+
+// Page describes a page of results
+type Page struct {
+	Page  int    // 1-based page number
+	Limit int    // Page size, capped at MaxPageSize
+	Sort  bson.D // Defaults to _id descending
+}
+
+// normalized applies the default and maximum page size
+func (p Page) normalized() Page {
+	if p.Page < 1 {
+		p.Page = 1
+	}
+	if p.Limit <= 0 {
+		p.Limit = DefaultPageSize
+	}
+	if p.Limit > MaxPageSize {
+		p.Limit = MaxPageSize
+	}
+	return p
+}
+
+// PageResult is a page of documents with the total match count
+type PageResult[T any] struct {
+	Items []T   `json:"items"`
+	Total int64 `json:"total"`
+	Page  int   `json:"page"`
+	Limit int   `json:"limit"`
+}
+
+// NewID generates a primary key for the repository's strategy
+func (r *Repository[T]) NewID() interface{} {
+	if r.idStrategy == ULIDKeys {
+		return utils.NewID()
+	}
+	return primitive.NewObjectID()
+}
+
+// ParseID converts an API-facing ID string into the stored key type
+func (r *Repository[T]) ParseID(id string) (interface{}, error) {
+	if r.idStrategy == ULIDKeys {
+		if !utils.IsValidID(id) {
+			return nil, fmt.Errorf("%w %q", ErrInvalidID, id)
+		}
+		return id, nil
+	}
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q", ErrInvalidID, id)
+	}
+	return oid, nil
+}
+
+// Insert stores doc, assigning a primary key when its _id is empty and starting
+// a zero VersionField at 1. The generated values are written back into doc.
+func (r *Repository[T]) Insert(ctx context.Context, doc *T) error {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return r.wrap("insert", err)
+	}
+
+	var fields bson.D
+	if err := bson.Unmarshal(raw, &fields); err != nil {
+		return r.wrap("insert", err)
+	}
+
+	fields = withInitialVersion(withID(fields, r.NewID))
+
+	ctx, done, err := r.begin(ctx)
+	if err != nil {
+		return r.wrap("insert", err)
+	}
+	defer done()
+
+	if _, err := r.Collection().InsertOne(ctx, fields); err != nil {
+		return r.wrap("insert", err)
+	}
+
+	// Reflect the stored document (including a generated _id) back into doc
+	raw, err = bson.Marshal(fields)
+	if err != nil {
+		return r.wrap("insert", err)
+	}
+	return r.wrap("insert", bson.Unmarshal(raw, doc))
+}
+
+// FindByID returns the document with the given ID
+func (r *Repository[T]) FindByID(ctx context.Context, id string) (*T, error) {
+	key, err := r.ParseID(id)
+	if err != nil {
+		return nil, r.wrap("find", err)
+	}
+	return r.FindOne(ctx, bson.M{"_id": key})
+}
+
+// FindByIDs returns the documents with the given IDs keyed by ID; IDs that do
+// not exist are absent from the map
+func (r *Repository[T]) FindByIDs(ctx context.Context, ids []string) (map[string]*T, error) {
+	keys := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		key, err := r.ParseID(id)
+		if err != nil {
+			return nil, r.wrap("find", err)
+		}
+		keys = append(keys, key)
+	}
+
+	ctx, done, err := r.begin(ctx)
+	if err != nil {
+		return nil, r.wrap("find", err)
+	}
+	defer done()
+
+	cursor, err := r.Collection().Find(ctx, bson.M{"_id": bson.M{"$in": keys}})
+	if err != nil {
+		return nil, r.wrap("find", err)
+	}
+	defer cursor.Close(ctx)
+
+	docs := make(map[string]*T, len(ids))
+	for cursor.Next(ctx) {
+		var doc T
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, r.wrap("find", err)
+		}
+
+		var id string
+		switch key := cursor.Current.Lookup("_id"); key.Type {
+		case bson.TypeObjectID:
+			id = key.ObjectID().Hex()
+		default:
+			id, _ = key.StringValueOK()
+		}
+		docs[id] = &doc
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, r.wrap("find", err)
+	}
+	return docs, nil
+}
+
+// FindOne returns the first document matching filter
+func (r *Repository[T]) FindOne(ctx context.Context, filter interface{}) (*T, error) {
+	ctx, done, err := r.begin(ctx)
+	if err != nil {
+		return nil, r.wrap("find", err)
+	}
+	defer done()
+
+	var doc T
+	err = r.Collection().FindOne(ctx, filter).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, r.wrap("find", ErrNotFound)
+	}
+	if err != nil {
+		return nil, r.wrap("find", err)
+	}
+	return &doc, nil
+}
+
+// Find returns a page of documents matching filter
+func (r *Repository[T]) Find(ctx context.Context, filter interface{}, page Page) (*PageResult[T], error) {
+	if filter == nil {
+		filter = bson.M{}
+	}
+	page = page.normalized()
+	if page.Sort == nil {
+		page.Sort = bson.D{{Key: "_id", Value: -1}}
+	}
+
+	ctx, done, err := r.begin(ctx)
+	if err != nil {
+		return nil, r.wrap("find", err)
+	}
+	defer done()
+
+	collection := r.Collection()
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, r.wrap("count", err)
+	}
+
+	findOptions := options.Find().
+		SetSort(page.Sort).
+		SetSkip(int64((page.Page - 1) * page.Limit)).
+		SetLimit(int64(page.Limit))
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, r.wrap("find", err)
+	}
+	defer cursor.Close(ctx)
+
+	items := []T{}
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, r.wrap("find", err)
+	}
+
+	return &PageResult[T]{Items: items, Total: total, Page: page.Page, Limit: page.Limit}, nil
+}
+
+// UpdateByID applies a Mongo update document to the document with the given ID
+func (r *Repository[T]) UpdateByID(ctx context.Context, id string, update interface{}) error {
+	key, err := r.ParseID(id)
+	if err != nil {
+		return r.wrap("update", err)
+	}
+
+	ctx, done, err := r.begin(ctx)
+	if err != nil {
+		return r.wrap("update", err)
+	}
+	defer done()
+
+	result, err := r.Collection().UpdateOne(ctx, bson.M{"_id": key}, update)
+	if err != nil {
+		return r.wrap("update", err)
+	}
+	if result.MatchedCount == 0 {
+		return r.wrap("update", ErrNotFound)
+	}
+	return nil
+}
+
+// DeleteByID removes the document with the given ID
+func (r *Repository[T]) DeleteByID(ctx context.Context, id string) error {
+	key, err := r.ParseID(id)
+	if err != nil {
+		return r.wrap("delete", err)
+	}
+
+	ctx, done, err := r.begin(ctx)
+	if err != nil {
+		return r.wrap("delete", err)
+	}
+	defer done()
+
+	result, err := r.Collection().DeleteOne(ctx, bson.M{"_id": key})
+	if err != nil {
+		return r.wrap("delete", err)
+	}
+	if result.DeletedCount == 0 {
+		return r.wrap("delete", ErrNotFound)
+	}
+	return nil
+}
+
+// wrap adds the collection and operation to err, keeping it matchable with errors.Is
+func (r *Repository[T]) wrap(operation string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%s %s: %w", r.collectionName, operation, err)
+}
+
+// withID sets _id using newID when it is missing or empty
+func withID(fields bson.D, newID func() interface{}) bson.D {
+	for i, field := range fields {
+		if field.Key != "_id" {
+			continue
+		}
+		switch value := field.Value.(type) {
+		case primitive.ObjectID:
+			if !value.IsZero() {
+				return fields
+			}
+		case string:
+			if value != "" {
+				return fields
+			}
+		case nil:
+		default:
+			return fields
+		}
+		fields[i].Value = newID()
+		return fields
+	}
+	return append(bson.D{{Key: "_id", Value: newID()}}, fields...)
+}
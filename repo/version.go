@@ -0,0 +1,247 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// VersionField holds the document version for optimistic concurrency. Models
+// opt in with a field such as
+//
+//	Version int64 `bson:"version" json:"version"`
+//
+// Insert starts it at 1 and the versioned updates increment it.
+const VersionField = "version"
+
+// ErrConflict matches every *ConflictError with errors.Is
+var ErrConflict = errors.New("version conflict")
+
+// ConflictError reports that a document changed since the caller read it
+type ConflictError struct {
+	ID       string
+	Expected int64 // Version the caller read
+	Current  int64 // Version now stored
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("version conflict on %s: expected version %d, found %d", e.ID, e.Expected, e.Current)
+}
+
+// Is makes errors.Is(err, ErrConflict) match
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// ETag renders a document version as a strong entity tag such as "v3", for
+// the ETag header of reads and the If-Match header of writes
+func ETag(version int64) string {
+	return `"v` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ParseETag returns the version carried by an entity tag produced by ETag;
+// weak tags (W/"v3") are accepted
+func ParseETag(etag string) (int64, error) {
+	tag := strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+	tag = strings.TrimPrefix(strings.TrimSuffix(strings.TrimPrefix(tag, `"`), `"`), "v")
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("invalid entity tag %q", etag)
+	}
+	return version, nil
+}
+
+// Version returns the stored version of the document with the given ID; a
+// document written before the model had a version is at 0
+func (r *Repository[T]) Version(ctx context.Context, id string) (int64, error) {
+	key, err := r.ParseID(id)
+	if err != nil {
+		return 0, r.wrap("find", err)
+	}
+
+	ctx, done, err := r.begin(ctx)
+	if err != nil {
+		return 0, r.wrap("find", err)
+	}
+	defer done()
+
+	var stored bson.M
+	err = r.Collection().FindOne(ctx, bson.M{"_id": key},
+		options.FindOne().SetProjection(bson.M{VersionField: 1})).Decode(&stored)
+	if err == mongo.ErrNoDocuments {
+		return 0, r.wrap("find", ErrNotFound)
+	}
+	if err != nil {
+		return 0, r.wrap("find", err)
+	}
+	return versionNumber(stored[VersionField]), nil
+}
+
+// UpdateByIDVersion applies update when the stored document is still at
+// version, incrementing the version, and returns the new version. A document
+// changed by someone else fails with a *ConflictError.
+func (r *Repository[T]) UpdateByIDVersion(ctx context.Context, id string, version int64, update bson.M) (int64, error) {
+	key, err := r.ParseID(id)
+	if err != nil {
+		return 0, r.wrap("update", err)
+	}
+
+	withVersion := bson.M{}
+	for operator, fields := range update {
+		withVersion[operator] = fields
+	}
+	increments := bson.M{VersionField: 1}
+	if existing, ok := update["$inc"]; ok {
+		// Any document type is accepted: bson.M, bson.D, maps or structs
+		raw, err := bson.Marshal(existing)
+		if err != nil {
+			return 0, r.wrap("update", fmt.Errorf("invalid $inc: %w", err))
+		}
+		var fields bson.D
+		if err := bson.Unmarshal(raw, &fields); err != nil {
+			return 0, r.wrap("update", fmt.Errorf("invalid $inc: %w", err))
+		}
+		for _, field := range fields {
+			if field.Key == VersionField {
+				return 0, r.wrap("update", fmt.Errorf("invalid $inc: %s is managed by the repository", VersionField))
+			}
+			increments[field.Key] = field.Value
+		}
+	}
+	withVersion["$inc"] = increments
+
+	ctx, done, err := r.begin(ctx)
+	if err != nil {
+		return 0, r.wrap("update", err)
+	}
+	defer done()
+
+	result, err := r.Collection().UpdateOne(ctx, versionFilter(key, version), withVersion)
+	if err != nil {
+		return 0, r.wrap("update", err)
+	}
+	if result.MatchedCount == 0 {
+		return 0, r.wrap("update", r.versionMismatch(ctx, id, key, version))
+	}
+	return version + 1, nil
+}
+
+// ReplaceVersioned stores doc over the document with its _id when the stored
+// version equals doc's, then increments the version in doc. A document
+// changed by someone else fails with a *ConflictError.
+func (r *Repository[T]) ReplaceVersioned(ctx context.Context, doc *T) error {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return r.wrap("replace", err)
+	}
+	var fields bson.D
+	if err := bson.Unmarshal(raw, &fields); err != nil {
+		return r.wrap("replace", err)
+	}
+
+	var key interface{}
+	version, hasVersion := int64(0), false
+	for i, field := range fields {
+		switch field.Key {
+		case "_id":
+			key = field.Value
+		case VersionField:
+			version, hasVersion = versionNumber(field.Value), true
+			fields[i].Value = version + 1
+		}
+	}
+	if key == nil {
+		return r.wrap("replace", fmt.Errorf("%w: document has no _id", ErrInvalidID))
+	}
+	if !hasVersion {
+		return r.wrap("replace", fmt.Errorf("document has no %s field", VersionField))
+	}
+
+	ctx, done, err := r.begin(ctx)
+	if err != nil {
+		return r.wrap("replace", err)
+	}
+	defer done()
+
+	result, err := r.Collection().ReplaceOne(ctx, versionFilter(key, version), fields)
+	if err != nil {
+		return r.wrap("replace", err)
+	}
+	if result.MatchedCount == 0 {
+		return r.wrap("replace", r.versionMismatch(ctx, keyString(key), key, version))
+	}
+
+	raw, err = bson.Marshal(fields)
+	if err != nil {
+		return r.wrap("replace", err)
+	}
+	return r.wrap("replace", bson.Unmarshal(raw, doc))
+}
+
+// versionFilter matches the document at version; version 0 also matches
+// documents stored before the model had a version
+func versionFilter(key interface{}, version int64) bson.M {
+	if version == 0 {
+		return bson.M{"_id": key, "$or": bson.A{
+			bson.M{VersionField: 0},
+			bson.M{VersionField: bson.M{"$exists": false}},
+		}}
+	}
+	return bson.M{"_id": key, VersionField: version}
+}
+
+// versionMismatch explains a versioned write that matched nothing: the
+// document is gone, or another writer moved it to a new version
+func (r *Repository[T]) versionMismatch(ctx context.Context, id string, key interface{}, expected int64) error {
+	var stored bson.M
+	err := r.Collection().FindOne(ctx, bson.M{"_id": key},
+		options.FindOne().SetProjection(bson.M{VersionField: 1})).Decode(&stored)
+	if err == mongo.ErrNoDocuments {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return &ConflictError{ID: id, Expected: expected, Current: versionNumber(stored[VersionField])}
+}
+
+// keyString renders a primary key for errors
+func keyString(key interface{}) string {
+	if oid, ok := key.(primitive.ObjectID); ok {
+		return oid.Hex()
+	}
+	return fmt.Sprint(key)
+}
+
+// versionNumber reads a version stored as any BSON number; missing is 0
+func versionNumber(value interface{}) int64 {
+	switch v := value.(type) {
+	case int64:
+		return v
+	case int32:
+		return int64(v)
+	case int:
+		return int64(v)
+	case float64:
+		return int64(v)
+	default:
+		return 0
+	}
+}
+
+// withInitialVersion starts documents that carry a zero version at 1
+func withInitialVersion(fields bson.D) bson.D {
+	for i, field := range fields {
+		if field.Key == VersionField && versionNumber(field.Value) == 0 {
+			fields[i].Value = int64(1)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,120 @@
+// Package retry runs operations again with exponential backoff and jitter.
+// It has no internal dependencies so that config can use it; services usually
+// reach it through utils.Retry.
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// Policy controls how an operation is retried
+type Policy struct {
+	MaxAttempts    int           // Total attempts including the first; 0 means no limit
+	InitialBackoff time.Duration // Delay before the second attempt
+	MaxBackoff     time.Duration // Upper bound for a single delay; 0 means no bound
+	Multiplier     float64       // Backoff growth per attempt (default 2)
+	Jitter         float64       // Randomizes each delay by ±Jitter (0 to 1)
+	MaxElapsed     time.Duration // Stop when the next delay would exceed this budget; 0 means no budget
+
+	// Retryable classifies errors; nil retries every error not marked Permanent
+	Retryable func(err error) bool
+	// OnRetry is called before waiting for the next attempt
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// DefaultPolicy makes three attempts starting with a 200ms delay
+var DefaultPolicy = Policy{
+	MaxAttempts:    3,
+	InitialBackoff: 200 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// permanentError marks an error that must not be retried
+type permanentError struct {
+	err error
+}
+
+func (p *permanentError) Error() string { return p.err.Error() }
+func (p *permanentError) Unwrap() error { return p.err }
+
+// Permanent wraps err so Do returns it immediately instead of retrying
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// Do calls fn until it succeeds, returns a non-retryable error, or the policy is
+// exhausted. The last error is returned unwrapped from Permanent so callers can
+// match it with errors.Is. Waiting stops early when ctx is done.
+func Do(ctx context.Context, policy Policy, fn func() error) error {
+	if policy.Multiplier <= 0 {
+		policy.Multiplier = 2
+	}
+
+	start := time.Now()
+	backoff := policy.InitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if policy.Retryable != nil && !policy.Retryable(err) {
+			return err
+		}
+		if policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts {
+			return err
+		}
+
+		delay := withJitter(backoff, policy.Jitter)
+		if policy.MaxElapsed > 0 && time.Since(start)+delay > policy.MaxElapsed {
+			return err
+		}
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt, err, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+
+		backoff = time.Duration(float64(backoff) * policy.Multiplier)
+		if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
+
+// withJitter spreads delay uniformly over ±jitter of its value
+func withJitter(delay time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 || delay <= 0 {
+		return delay
+	}
+	if jitter > 1 {
+		jitter = 1
+	}
+	spread := float64(delay) * jitter
+	return time.Duration(float64(delay) - spread + rand.Float64()*2*spread)
+}
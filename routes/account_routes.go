@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupAccountRoutes adds account deactivation and deletion endpoints to your application
+func SetupAccountRoutes(app *fiber.App) {
+	accountGroup := app.Group("/account", middleware.AuthMiddleware)
+
+	accountGroup.Get("/status", sharedControllers.GetMyAccountStatus)
+	accountGroup.Post("/deactivate", sharedControllers.DeactivateMyAccount)
+	accountGroup.Post("/reactivate", sharedControllers.CancelMyAccountDeletion)
+
+	adminGroup := app.Group("/admin/accounts",
+		middleware.AuthMiddleware,
+		middleware.SuperAdminOnly(),
+	)
+	adminGroup.Post("/:userId/deactivate", sharedControllers.DeactivateUserAccount)
+	adminGroup.Post("/:userId/reactivate", sharedControllers.CancelUserAccountDeletion)
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupActivityRoutes adds the organization activity feed endpoints to your application
+func SetupActivityRoutes(app *fiber.App) {
+	activityGroup := app.Group("/activity", middleware.AuthMiddleware)
+
+	activityGroup.Get("/", sharedControllers.GetActivityFeed)
+	activityGroup.Get("/unread-count", sharedControllers.GetUnreadActivityCount)
+	activityGroup.Post("/read", sharedControllers.MarkActivityRead)
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupAdminDirectoryRoutes adds user and organization search and export for
+// super admins. Configure utils.UsersDirectory and utils.OrganizationsDirectory
+// when the service's collections differ from the defaults.
+func SetupAdminDirectoryRoutes(app *fiber.App) {
+	adminGroup := app.Group("/admin",
+		middleware.AuthMiddleware,
+		middleware.SuperAdminOnly(),
+	)
+
+	adminGroup.Get("/users", sharedControllers.SearchUsers)
+	adminGroup.Get("/users/export", sharedControllers.ExportUsers) // Streamed JSON/CSV export
+	adminGroup.Get("/organizations", sharedControllers.SearchOrganizations)
+	adminGroup.Get("/organizations/export", sharedControllers.ExportOrganizations)
+}
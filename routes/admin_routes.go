@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupAdminRoutes adds an embedded admin dashboard for deployments without a
+// separate admin frontend. The page itself is a static shell; everything it
+// shows comes from the summary endpoint, which requires a super admin.
+func SetupAdminRoutes(app *fiber.App) {
+	app.Get("/admin/dashboard", sharedControllers.AdminDashboardPage)
+
+	dashboardGroup := app.Group("/admin/dashboard/api",
+		middleware.AuthMiddleware,
+		middleware.SuperAdminOnly(),
+	)
+
+	dashboardGroup.Get("/summary", sharedControllers.GetAdminDashboardSummary) // Health, config, flags, DLQ and audit stats
+}
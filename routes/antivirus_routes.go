@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupAntivirusRoutes adds file scan verdicts to your application: uploaders
+// poll their scans, organization admins list infected files
+func SetupAntivirusRoutes(app *fiber.App) {
+	antivirusGroup := app.Group("/antivirus/scans", middleware.AuthMiddleware)
+
+	antivirusGroup.Get("/", middleware.AdminOnly(), sharedControllers.ListFileScans) // Infected files by default
+	antivirusGroup.Get("/:scanId", sharedControllers.GetFileScan)
+}
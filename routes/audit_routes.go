@@ -15,7 +15,18 @@ func SetupAuditRoutes(app *fiber.App) {
 	)
 
 	// Audit log endpoints
-	auditGroup.Get("/logs", sharedControllers.GetAuditLogs)                       // All logs (super admin only)
-	auditGroup.Get("/admin/:adminId", sharedControllers.GetAdminAuditLogs)        // Admin-specific logs
-	auditGroup.Get("/resource/:targetId", sharedControllers.GetResourceAuditLogs) // Resource-specific logs
+	auditGroup.Get("/logs", sharedControllers.GetAuditLogs)                                    // All logs (super admin only)
+	auditGroup.Get("/admin/:adminId", sharedControllers.GetAdminAuditLogs)                     // Admin-specific logs
+	auditGroup.Get("/resource/:targetId", sharedControllers.GetResourceAuditLogs)              // Resource-specific logs
+	auditGroup.Get("/resources", sharedControllers.GetResourcesAuditLogs)                      // Logs for several resources (?ids=a,b,c)
+	auditGroup.Get("/export", sharedControllers.ExportAuditLogs)                               // Streamed JSON/CSV export
+	auditGroup.Get("/stats", sharedControllers.GetAuditStats)                                  // Aggregated activity trends
+	auditGroup.Get("/stream", sharedControllers.TailAuditLogs)                                 // Live tail (server-sent events)
+	auditGroup.Get("/verify", middleware.SuperAdminOnly(), sharedControllers.VerifyAuditChain) // Hash chain integrity
+
+	// Anomaly detection
+	auditGroup.Get("/anomaly-settings", sharedControllers.GetAnomalySettings)
+	auditGroup.Put("/anomaly-settings", sharedControllers.UpdateAnomalySettings)
+	auditGroup.Get("/alerts", sharedControllers.ListAuditAlerts)
+	auditGroup.Post("/alerts/:alertId/acknowledge", sharedControllers.AcknowledgeAuditAlert)
 }
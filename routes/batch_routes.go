@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+)
+
+// Batch adds POST /batch, which runs several API calls in one round-trip for
+// clients on slow networks. Each sub-request goes through the application's
+// middleware and authentication like a direct call.
+func Batch(app *fiber.App) {
+	app.Post("/batch", sharedControllers.BatchRequests(app))
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupBillingRoutes adds the Stripe webhook and the entitlements endpoint
+func SetupBillingRoutes(app *fiber.App) {
+	// Authenticated by the Stripe signature rather than a JWT
+	app.Post("/webhooks/stripe", sharedControllers.HandleStripeWebhook)
+
+	billingGroup := app.Group("/billing", middleware.AuthMiddleware)
+
+	billingGroup.Get("/entitlements", sharedControllers.GetEntitlements) // Plan, features and limits of the caller's organization
+}
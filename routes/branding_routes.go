@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupBrandingRoutes adds organization branding endpoints: public reads for
+// frontends, by organization or by custom domain, and management for
+// organization admins
+func SetupBrandingRoutes(app *fiber.App) {
+	app.Get("/branding", middleware.CustomDomain(), sharedControllers.GetHostBranding)
+	app.Get("/branding/:organizationId", sharedControllers.GetPublicBranding)
+
+	brandingGroup := app.Group("/organization/branding", middleware.AuthMiddleware)
+
+	brandingGroup.Get("/", sharedControllers.GetBranding)
+	brandingGroup.Put("/", middleware.AdminOnly(), sharedControllers.UpdateBranding)
+	brandingGroup.Delete("/", middleware.AdminOnly(), sharedControllers.ResetBranding)
+}
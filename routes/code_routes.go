@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupCodeRoutes adds invite/promotion code redemption and management endpoints
+func SetupCodeRoutes(app *fiber.App) {
+	codeGroup := app.Group("/codes", middleware.AuthMiddleware)
+
+	// Any authenticated user can redeem a code
+	codeGroup.Post("/redeem", sharedControllers.RedeemCode)
+
+	// Issuing codes is limited to super admins
+	codeGroup.Get("/", middleware.SuperAdminOnly(), sharedControllers.ListCodes)
+	codeGroup.Post("/", middleware.SuperAdminOnly(), sharedControllers.CreateCode)
+	codeGroup.Delete("/:code", middleware.SuperAdminOnly(), sharedControllers.DisableCode)
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupConfigRoutes adds configuration admin endpoints to your application
+func SetupConfigRoutes(app *fiber.App) {
+	configGroup := app.Group("/admin/config",
+		middleware.AuthMiddleware,
+		middleware.SuperAdminOnly(), // Secret refresh is restricted to super admins
+	)
+
+	configGroup.Post("/refresh", sharedControllers.RefreshConfig) // Reload secrets after key rotation
+	configGroup.Get("/bundle", sharedControllers.ExportConfigBundle)
+	configGroup.Post("/bundle/import", sharedControllers.ImportConfigBundle) // ?dry_run=true previews the diff
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupCustomDomainRoutes adds custom domain management for organization admins
+func SetupCustomDomainRoutes(app *fiber.App) {
+	domainGroup := app.Group("/organization/domains",
+		middleware.AuthMiddleware,
+		middleware.AdminOnly(),
+	)
+
+	domainGroup.Get("/", sharedControllers.ListDomains)
+	domainGroup.Post("/", sharedControllers.AddDomain)
+	domainGroup.Post("/:domainId/verify", sharedControllers.VerifyDomain)
+	domainGroup.Delete("/:domainId", sharedControllers.RemoveDomain)
+}
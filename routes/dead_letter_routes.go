@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupDeadLetterRoutes adds endpoints to inspect and replay dead-lettered messages
+func SetupDeadLetterRoutes(app *fiber.App) {
+	deadLetterGroup := app.Group("/admin/dead-letters",
+		middleware.AuthMiddleware,
+		middleware.SuperAdminOnly(), // Messages span organizations
+	)
+
+	deadLetterGroup.Get("/", sharedControllers.ListDeadLetters)
+	deadLetterGroup.Get("/:deadLetterId", sharedControllers.GetDeadLetter)
+	deadLetterGroup.Post("/:deadLetterId/replay", sharedControllers.ReplayDeadLetter)
+}
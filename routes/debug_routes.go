@@ -0,0 +1,42 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/expvar"
+	"github.com/gofiber/fiber/v2/middleware/pprof"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// SetupDebugRoutes adds profiling and runtime diagnostics for production
+// incidents: pprof under /debug/pprof/, expvar at /debug/vars, goroutine dumps
+// and GC statistics. They are restricted to super admins connecting from an
+// address allowed by IP_ALLOWLIST_DEBUG (or an ip_rules entry of scope
+// "debug"); without such a rule every request is refused. Each access is
+// recorded in the audit log.
+//
+//	curl -H "Authorization: Bearer $TOKEN" https://api.example.com/debug/pprof/profile?seconds=30 > cpu.pprof
+//	go tool pprof cpu.pprof
+func SetupDebugRoutes(app *fiber.App) {
+	debugGroup := app.Group("/debug",
+		middleware.IPAllowlist("debug"),
+		middleware.AuthMiddleware,
+		middleware.SuperAdminOnly(),
+		auditDebugAccess,
+	)
+
+	debugGroup.Get("/goroutines", sharedControllers.GetGoroutineDump)
+	debugGroup.Get("/runtime", sharedControllers.GetRuntimeStats) // Memory and GC statistics
+	debugGroup.Use(pprof.New())
+	debugGroup.Use(expvar.New())
+}
+
+// auditDebugAccess records who looked at the process internals
+func auditDebugAccess(c *fiber.Ctx) error {
+	adminID, _ := c.Locals("user_id").(string)
+	utils.LogAuditContext(c.UserContext(), adminID, "debug_accessed", c.Path(), map[string]interface{}{
+		"query": string(c.Request().URI().QueryString()),
+	})
+	return c.Next()
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupDeviceRoutes adds endpoints for users to register and manage their devices
+func SetupDeviceRoutes(app *fiber.App) {
+	deviceGroup := app.Group("/devices", middleware.AuthMiddleware)
+
+	deviceGroup.Post("/", sharedControllers.RegisterDevice) // Called by apps after sign-in and on launch
+	deviceGroup.Get("/", sharedControllers.ListDevices)
+	deviceGroup.Put("/:deviceId/push-token", sharedControllers.UpdateDevicePushToken)
+	deviceGroup.Delete("/:deviceId", sharedControllers.DeleteDevice)
+}
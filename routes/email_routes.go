@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupEmailRoutes adds the SendGrid event webhook and email admin endpoints
+func SetupEmailRoutes(app *fiber.App) {
+	// Authenticated by the SendGrid signature rather than a JWT
+	app.Post("/webhooks/sendgrid", sharedControllers.HandleSendGridWebhook)
+
+	// Suppressions are shared by every organization, so like template
+	// previews and test sends they are limited to super admins
+	emailGroup := app.Group("/admin/email/suppressions",
+		middleware.AuthMiddleware,
+		middleware.SuperAdminOnly(),
+	)
+
+	emailGroup.Get("/", sharedControllers.ListEmailSuppressions)
+	emailGroup.Delete("/:email", sharedControllers.DeleteEmailSuppression)
+
+	templateGroup := app.Group("/admin/email/templates",
+		middleware.AuthMiddleware,
+		middleware.SuperAdminOnly(),
+	)
+
+	templateGroup.Get("/", sharedControllers.ListEmailTemplates)
+	templateGroup.Post("/:name/preview", sharedControllers.PreviewEmailTemplate)
+	templateGroup.Post("/:name/test", sharedControllers.SendTestEmail)
+}
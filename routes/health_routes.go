@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupHealthRoutes adds liveness, readiness and metrics endpoints to your application
+func SetupHealthRoutes(app *fiber.App) {
+	app.Get("/healthz", sharedControllers.Liveness)  // Process is alive
+	app.Get("/readyz", sharedControllers.Readiness)  // Dependencies are reachable
+	app.Get("/metrics", middleware.MetricsHandler()) // Prometheus scrape endpoint
+}
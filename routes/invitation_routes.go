@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupInvitationRoutes adds organization invitation endpoints to your application
+func SetupInvitationRoutes(app *fiber.App) {
+	invitationGroup := app.Group("/invitations", middleware.AuthMiddleware)
+
+	// Any authenticated user can accept an invitation addressed to them
+	invitationGroup.Post("/accept", sharedControllers.AcceptInvitation)
+
+	// Managing invitations requires admin privileges in the organization
+	invitationGroup.Get("/", middleware.AdminOnly(), sharedControllers.ListInvitations)
+	invitationGroup.Post("/", middleware.AdminOnly(), sharedControllers.CreateInvitation)
+	invitationGroup.Post("/:invitationId/resend", middleware.AdminOnly(), sharedControllers.ResendInvitation)
+	invitationGroup.Delete("/:invitationId", middleware.AdminOnly(), sharedControllers.RevokeInvitation)
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupIPRuleRoutes adds IP allow/deny list management endpoints to your application
+func SetupIPRuleRoutes(app *fiber.App) {
+	ipRuleGroup := app.Group("/admin/ip-rules",
+		middleware.AuthMiddleware,
+		middleware.SuperAdminOnly(), // Network restrictions are managed by super admins
+	)
+
+	ipRuleGroup.Get("/", sharedControllers.ListIPRules)
+	ipRuleGroup.Post("/", sharedControllers.CreateIPRule)
+	ipRuleGroup.Delete("/:ruleId", sharedControllers.DeleteIPRule)
+}
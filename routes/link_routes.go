@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupLinkRoutes adds the public short link redirect and link management endpoints
+func SetupLinkRoutes(app *fiber.App) {
+	// Public, so QR codes work without signing in
+	app.Get("/l/:token", sharedControllers.FollowLink)
+	// The short URL of a token never changes, so its QR code can be cached
+	app.Get("/l/:token/qr", middleware.CacheControl(middleware.CachePolicy{MaxAge: time.Hour}), sharedControllers.LinkQRCode)
+
+	linkGroup := app.Group("/links", middleware.AuthMiddleware)
+
+	linkGroup.Post("/", sharedControllers.CreateLink)
+	linkGroup.Get("/:token", sharedControllers.GetLink) // Target and hit count
+	linkGroup.Delete("/:token", sharedControllers.DeleteLink)
+}
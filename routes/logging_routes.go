@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupLoggingRoutes adds runtime log level control; the app package mounts it
+func SetupLoggingRoutes(app *fiber.App) {
+	loggingGroup := app.Group("/admin/logging",
+		middleware.AuthMiddleware,
+		middleware.SuperAdminOnly(),
+	)
+
+	loggingGroup.Get("/", sharedControllers.GetLogSettings)
+	loggingGroup.Put("/", sharedControllers.UpdateLogSettings) // {"level": "debug", "duration": "5m"}
+}
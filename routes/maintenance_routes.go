@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupMaintenanceRoutes adds the maintenance mode toggle to your application.
+// Maintenance mode affects every organization, so only super admins may use it.
+func SetupMaintenanceRoutes(app *fiber.App) {
+	maintenanceGroup := app.Group("/admin/maintenance",
+		middleware.AuthMiddleware,
+		middleware.SuperAdminOnly(),
+	)
+
+	maintenanceGroup.Get("/", sharedControllers.GetMaintenanceState)
+	maintenanceGroup.Post("/enable", sharedControllers.EnableMaintenance)
+	maintenanceGroup.Post("/disable", sharedControllers.DisableMaintenance)
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupModerationRoutes adds the content moderation review queue for organization admins
+func SetupModerationRoutes(app *fiber.App) {
+	moderationGroup := app.Group("/moderation/items",
+		middleware.AuthMiddleware,
+		middleware.AdminOnly(),
+	)
+
+	moderationGroup.Get("/", sharedControllers.ListModerationItems) // Quarantined items by default
+	moderationGroup.Get("/:itemId", sharedControllers.GetModerationItem)
+	moderationGroup.Post("/:itemId/review", sharedControllers.ReviewModerationItem)
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupOperationRoutes adds the long-running operation status endpoint to your application
+func SetupOperationRoutes(app *fiber.App) {
+	operationGroup := app.Group("/operations", middleware.AuthMiddleware)
+
+	operationGroup.Get("/:operationId", sharedControllers.GetOperation) // Poll status and result
+}
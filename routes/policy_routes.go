@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupPolicyRoutes adds access policy management endpoints to your application
+func SetupPolicyRoutes(app *fiber.App) {
+	policyGroup := app.Group("/admin/policies",
+		middleware.AuthMiddleware,
+		middleware.SuperAdminOnly(), // Only super admins can change access rules
+	)
+
+	policyGroup.Get("/", sharedControllers.ListPolicies)
+	policyGroup.Post("/", sharedControllers.CreatePolicy)
+	policyGroup.Delete("/:policyId", sharedControllers.DeletePolicy)
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupPreferencesRoutes adds user preference endpoints to your application
+func SetupPreferencesRoutes(app *fiber.App) {
+	preferencesGroup := app.Group("/preferences", middleware.AuthMiddleware)
+
+	preferencesGroup.Get("/", sharedControllers.GetMyPreferences)
+	preferencesGroup.Put("/", sharedControllers.UpdateMyPreferences)
+	preferencesGroup.Delete("/", sharedControllers.ResetMyPreferences)
+	preferencesGroup.Get("/users/:userId", middleware.AdminOnly(), sharedControllers.GetUserPreferences)
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupSAMLRoutes adds the SAML service provider endpoints, and connection
+// management for organization admins
+func SetupSAMLRoutes(app *fiber.App) {
+	connectionGroup := app.Group("/saml/connection",
+		middleware.AuthMiddleware,
+		middleware.AdminOnly(),
+	)
+	connectionGroup.Get("/", sharedControllers.GetSAMLConnection)
+	connectionGroup.Put("/", sharedControllers.SaveSAMLConnection)
+	connectionGroup.Delete("/", sharedControllers.DeleteSAMLConnection)
+
+	samlGroup := app.Group("/saml/:organizationId")
+	samlGroup.Get("/metadata", sharedControllers.SAMLMetadata)
+	samlGroup.Get("/login", sharedControllers.SAMLLogin)
+	samlGroup.Post("/acs", middleware.RateLimit(middleware.RateLimitOptions{Requests: 30}), sharedControllers.SAMLAssertionConsumer)
+}
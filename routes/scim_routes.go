@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+	"github.com/praleedsuvarna/shared-libs/scim"
+)
+
+// SetupSCIMRoutes serves the SCIM 2.0 API at /scim/v2 for identity providers,
+// and SCIM token management for organization admins
+func SetupSCIMRoutes(app *fiber.App) {
+	scim.Mount(app, "/scim/v2", scim.Options{})
+
+	tokenGroup := app.Group("/scim/tokens",
+		middleware.AuthMiddleware,
+		middleware.AdminOnly(),
+	)
+
+	tokenGroup.Post("/", sharedControllers.CreateSCIMToken)
+	tokenGroup.Get("/", sharedControllers.ListSCIMTokens)
+	tokenGroup.Delete("/:tokenId", sharedControllers.RevokeSCIMToken)
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupTenantRoutes adds organization data export and import for super admins
+func SetupTenantRoutes(app *fiber.App) {
+	tenantGroup := app.Group("/admin/organizations/:organizationId/data",
+		middleware.AuthMiddleware,
+		middleware.SuperAdminOnly(),
+	)
+
+	tenantGroup.Get("/export", sharedControllers.ExportOrganizationData)  // Streamed tenant archive
+	tenantGroup.Post("/import", sharedControllers.ImportOrganizationData) // Runs as an operation
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupTokenEndpoint mounts a scoped token endpoint for client SDKs at path,
+// rate limited per user. For example, viewer tokens for one experience:
+//
+//	routes.SetupTokenEndpoint(app, "/experiences/:experienceId/viewer-token",
+//		sharedControllers.TokenGrant{Scopes: []string{utils.ScopeView}, ResourceParam: "experienceId"},
+//		middleware.RateLimitOptions{Requests: 30})
+func SetupTokenEndpoint(router fiber.Router, path string, grant sharedControllers.TokenGrant, limit middleware.RateLimitOptions) {
+	router.Post(path, middleware.AuthMiddleware, middleware.RateLimit(limit), sharedControllers.IssueScopedToken(grant))
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupTranscodeRoutes adds the transcode job status endpoint to your application
+func SetupTranscodeRoutes(app *fiber.App) {
+	transcodeGroup := app.Group("/transcode", middleware.AuthMiddleware)
+
+	transcodeGroup.Get("/jobs/:jobId", sharedControllers.GetTranscodeJob) // Poll status and progress
+}
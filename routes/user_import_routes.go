@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	sharedControllers "github.com/praleedsuvarna/shared-libs/controllers"
+	"github.com/praleedsuvarna/shared-libs/middleware"
+)
+
+// SetupUserImportRoutes adds bulk user import and export for organization admins
+func SetupUserImportRoutes(app *fiber.App) {
+	bulkGroup := app.Group("/users/bulk",
+		middleware.AuthMiddleware,
+		middleware.AdminOnly(),
+	)
+
+	bulkGroup.Post("/import", sharedControllers.ImportUsers)            // CSV or JSON; ?dry_run=true to validate only
+	bulkGroup.Get("/export", sharedControllers.ExportOrganizationUsers) // ?format=csv&mask=true
+}
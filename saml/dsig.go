@@ -0,0 +1,371 @@
+package saml
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Namespaces and algorithms of XML signatures
+const (
+	nsDSig    = "http://www.w3.org/2000/09/xmldsig#"
+	nsXML     = "http://www.w3.org/XML/1998/namespace"
+	algExcC14 = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	algEnvSig = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+)
+
+var signatureMethods = map[string]crypto.Hash{
+	"http://www.w3.org/2000/09/xmldsig#rsa-sha1":        crypto.SHA1,
+	"http://www.w3.org/2001/04/xmldsig-more#rsa-sha256": crypto.SHA256,
+	"http://www.w3.org/2001/04/xmldsig-more#rsa-sha512": crypto.SHA512,
+}
+
+var digestMethods = map[string]crypto.Hash{
+	"http://www.w3.org/2000/09/xmldsig#sha1":  crypto.SHA1,
+	"http://www.w3.org/2001/04/xmlenc#sha256": crypto.SHA256,
+	"http://www.w3.org/2001/04/xmlenc#sha512": crypto.SHA512,
+}
+
+// ErrInvalidSignature is returned when an element is unsigned or its
+// signature does not verify
+var ErrInvalidSignature = errors.New("invalid SAML signature")
+
+// element is a parsed XML element that keeps namespace prefixes as written,
+// which canonicalization needs and encoding/xml's Token does not preserve
+type element struct {
+	prefix   string
+	local    string
+	attrs    []xml.Attr // Name.Space holds the prefix
+	children []interface{}
+	parent   *element
+}
+
+// parseXML reads a document into an element tree. Document type declarations
+// are rejected, so entity expansion cannot be abused.
+func parseXML(data []byte) (*element, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var root, current *element
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			e := &element{prefix: t.Name.Space, local: t.Name.Local, attrs: t.Copy().Attr, parent: current}
+			if current == nil {
+				if root != nil {
+					return nil, errors.New("more than one root element")
+				}
+				root = e
+			} else {
+				current.children = append(current.children, e)
+			}
+			current = e
+		case xml.EndElement:
+			if current == nil || t.Name.Local != current.local || t.Name.Space != current.prefix {
+				return nil, fmt.Errorf("unexpected end element %s", t.Name.Local)
+			}
+			current = current.parent
+		case xml.CharData:
+			if current != nil {
+				current.children = append(current.children, string(t))
+			}
+		case xml.Directive:
+			return nil, errors.New("document type declarations are not allowed")
+		}
+	}
+	if root == nil || current != nil {
+		return nil, errors.New("incomplete XML document")
+	}
+	return root, nil
+}
+
+// namespace resolves a prefix in the scope of e; "" is the default namespace
+func (e *element) namespace(prefix string) string {
+	if prefix == "xml" {
+		return nsXML
+	}
+	for scope := e; scope != nil; scope = scope.parent {
+		for _, attr := range scope.attrs {
+			if (prefix == "" && attr.Name.Space == "" && attr.Name.Local == "xmlns") ||
+				(prefix != "" && attr.Name.Space == "xmlns" && attr.Name.Local == prefix) {
+				return attr.Value
+			}
+		}
+	}
+	return ""
+}
+
+// is reports whether e is the element local in namespace
+func (e *element) is(namespace, local string) bool {
+	return e.local == local && e.namespace(e.prefix) == namespace
+}
+
+// attr returns the value of an unprefixed attribute
+func (e *element) attr(name string) string {
+	for _, attr := range e.attrs {
+		if attr.Name.Space == "" && attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// child returns the first child element local in namespace
+func (e *element) child(namespace, local string) *element {
+	for _, node := range e.children {
+		if c, ok := node.(*element); ok && c.is(namespace, local) {
+			return c
+		}
+	}
+	return nil
+}
+
+// childrenNamed returns the child elements local in namespace
+func (e *element) childrenNamed(namespace, local string) []*element {
+	var list []*element
+	for _, node := range e.children {
+		if c, ok := node.(*element); ok && c.is(namespace, local) {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+// text returns the character data of e
+func (e *element) text() string {
+	var b strings.Builder
+	for _, node := range e.children {
+		if s, ok := node.(string); ok {
+			b.WriteString(s)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// canonicalize renders e with Exclusive XML Canonicalization (without
+// comments), leaving out skip. inclusive lists the prefixes of an
+// InclusiveNamespaces PrefixList.
+func canonicalize(e, skip *element, inclusive []string) []byte {
+	var b bytes.Buffer
+	writeCanonical(&b, e, skip, inclusive, map[string]string{})
+	return b.Bytes()
+}
+
+func writeCanonical(b *bytes.Buffer, e, skip *element, inclusive []string, rendered map[string]string) {
+	// Declare the namespaces the element and its attributes use, unless an
+	// output ancestor already declared the same value
+	used := map[string]bool{e.prefix: true}
+	var attrs []xml.Attr
+	for _, attr := range e.attrs {
+		if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			continue
+		}
+		if attr.Name.Space != "" && attr.Name.Space != "xml" {
+			used[attr.Name.Space] = true
+		}
+		attrs = append(attrs, attr)
+	}
+	for _, prefix := range inclusive {
+		if prefix == "#default" {
+			prefix = ""
+		}
+		if prefix == "" || e.namespace(prefix) != "" {
+			used[prefix] = true
+		}
+	}
+
+	scope := make(map[string]string, len(rendered))
+	for prefix, value := range rendered {
+		scope[prefix] = value
+	}
+	var prefixes []string
+	for prefix := range used {
+		value := e.namespace(prefix)
+		previous, declared := rendered[prefix]
+		if (declared && previous == value) || (!declared && value == "") {
+			continue
+		}
+		scope[prefix] = value
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	sort.SliceStable(attrs, func(i, j int) bool {
+		ni, nj := e.namespace(attrs[i].Name.Space), e.namespace(attrs[j].Name.Space)
+		if attrs[i].Name.Space == "" {
+			ni = ""
+		}
+		if attrs[j].Name.Space == "" {
+			nj = ""
+		}
+		if ni != nj {
+			return ni < nj
+		}
+		return attrs[i].Name.Local < attrs[j].Name.Local
+	})
+
+	name := qualifiedName(e.prefix, e.local)
+	b.WriteString("<" + name)
+	for _, prefix := range prefixes {
+		if prefix == "" {
+			b.WriteString(` xmlns="` + escapeAttr(scope[prefix]) + `"`)
+		} else {
+			b.WriteString(" xmlns:" + prefix + `="` + escapeAttr(scope[prefix]) + `"`)
+		}
+	}
+	for _, attr := range attrs {
+		b.WriteString(" " + qualifiedName(attr.Name.Space, attr.Name.Local) + `="` + escapeAttr(attr.Value) + `"`)
+	}
+	b.WriteString(">")
+
+	for _, node := range e.children {
+		switch n := node.(type) {
+		case string:
+			b.WriteString(escapeText(n))
+		case *element:
+			if n != skip {
+				writeCanonical(b, n, skip, inclusive, scope)
+			}
+		}
+	}
+	b.WriteString("</" + name + ">")
+}
+
+func qualifiedName(prefix, local string) string {
+	if prefix == "" {
+		return local
+	}
+	return prefix + ":" + local
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;",
+		"\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+)
+
+func escapeText(s string) string { return textEscaper.Replace(s) }
+func escapeAttr(s string) string { return attrEscaper.Replace(s) }
+
+// verifySignature checks the enveloped signature of e against certificates.
+// Only a signature over e itself, by its ID, is accepted, so a signed element
+// cannot be swapped for another.
+func verifySignature(e *element, certificates []*x509.Certificate) error {
+	signature := e.child(nsDSig, "Signature")
+	if signature == nil {
+		return fmt.Errorf("%w: %s is not signed", ErrInvalidSignature, e.local)
+	}
+	signedInfo := signature.child(nsDSig, "SignedInfo")
+	if signedInfo == nil {
+		return fmt.Errorf("%w: missing SignedInfo", ErrInvalidSignature)
+	}
+
+	method := signedInfo.child(nsDSig, "CanonicalizationMethod")
+	if method == nil || method.attr("Algorithm") != algExcC14 {
+		return fmt.Errorf("%w: unsupported canonicalization", ErrInvalidSignature)
+	}
+	signatureHash, ok := signatureMethods[attrOf(signedInfo.child(nsDSig, "SignatureMethod"), "Algorithm")]
+	if !ok {
+		return fmt.Errorf("%w: unsupported signature method", ErrInvalidSignature)
+	}
+
+	references := signedInfo.childrenNamed(nsDSig, "Reference")
+	id := e.attr("ID")
+	if len(references) != 1 || id == "" || references[0].attr("URI") != "#"+id {
+		return fmt.Errorf("%w: signature does not reference %s", ErrInvalidSignature, e.local)
+	}
+	reference := references[0]
+
+	var inclusive []string
+	if transforms := reference.child(nsDSig, "Transforms"); transforms != nil {
+		for _, transform := range transforms.childrenNamed(nsDSig, "Transform") {
+			switch transform.attr("Algorithm") {
+			case algEnvSig:
+			case algExcC14:
+				inclusive = inclusivePrefixes(transform)
+			default:
+				return fmt.Errorf("%w: unsupported transform %s", ErrInvalidSignature, transform.attr("Algorithm"))
+			}
+		}
+	}
+
+	digestHash, ok := digestMethods[attrOf(reference.child(nsDSig, "DigestMethod"), "Algorithm")]
+	if !ok {
+		return fmt.Errorf("%w: unsupported digest method", ErrInvalidSignature)
+	}
+	expected, err := base64.StdEncoding.DecodeString(textOf(reference.child(nsDSig, "DigestValue")))
+	if err != nil {
+		return fmt.Errorf("%w: invalid digest value", ErrInvalidSignature)
+	}
+	if subtle.ConstantTimeCompare(digest(digestHash, canonicalize(e, signature, inclusive)), expected) != 1 {
+		return fmt.Errorf("%w: digest mismatch", ErrInvalidSignature)
+	}
+
+	value, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(textOf(signature.child(nsDSig, "SignatureValue"))), ""))
+	if err != nil {
+		return fmt.Errorf("%w: invalid signature value", ErrInvalidSignature)
+	}
+	hashed := digest(signatureHash, canonicalize(signedInfo, nil, inclusivePrefixes(method)))
+	for _, certificate := range certificates {
+		key, ok := certificate.PublicKey.(*rsa.PublicKey)
+		if ok && rsa.VerifyPKCS1v15(key, signatureHash, hashed, value) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: not signed by a configured certificate", ErrInvalidSignature)
+}
+
+// inclusivePrefixes reads the InclusiveNamespaces PrefixList of a transform
+func inclusivePrefixes(transform *element) []string {
+	for _, node := range transform.children {
+		if c, ok := node.(*element); ok && c.local == "InclusiveNamespaces" && c.namespace(c.prefix) == algExcC14 {
+			return strings.Fields(c.attr("PrefixList"))
+		}
+	}
+	return nil
+}
+
+func digest(hash crypto.Hash, data []byte) []byte {
+	switch hash {
+	case crypto.SHA1:
+		sum := sha1.Sum(data)
+		return sum[:]
+	case crypto.SHA512:
+		sum := sha512.Sum512(data)
+		return sum[:]
+	default:
+		sum := sha256.Sum256(data)
+		return sum[:]
+	}
+}
+
+func attrOf(e *element, name string) string {
+	if e == nil {
+		return ""
+	}
+	return e.attr(name)
+}
+
+func textOf(e *element) string {
+	if e == nil {
+		return ""
+	}
+	return e.text()
+}
@@ -0,0 +1,381 @@
+package saml
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/authz"
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	nsProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	nsAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+
+	statusSuccess = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	bearerMethod  = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+)
+
+// Identity is the verified subject of an assertion, mapped to our claims
+type Identity struct {
+	OrganizationID string              `json:"organization_id"`
+	NameID         string              `json:"name_id"`
+	Email          string              `json:"email"`
+	Name           string              `json:"name,omitempty"`
+	Role           string              `json:"role,omitempty"` // Empty when no role attribute was mapped
+	Groups         []string            `json:"groups,omitempty"`
+	Attributes     map[string][]string `json:"attributes,omitempty"`
+	SessionIndex   string              `json:"session_index,omitempty"`
+
+	connection *models.SAMLConnection
+}
+
+// Tokens is the result of a SAML sign-in
+type Tokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	UserID       string `json:"user_id"`
+	Role         string `json:"role"`
+	Provisioned  bool   `json:"provisioned"` // The user was created by this sign-in
+}
+
+// ParseResponse validates a base64 SAMLResponse posted to the ACS of an
+// organization: its signature, issuer, audience, recipient and validity
+// window. The assertion is consumed, so it cannot be posted again.
+func ParseResponse(ctx context.Context, organizationID, encoded string) (*Identity, error) {
+	connection, err := GetConnection(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	if !connection.Enabled {
+		return nil, ErrNotConfigured
+	}
+	certificates, err := parseCertificates(connection.Certificates)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encoded), ""))
+	if err != nil {
+		return nil, fmt.Errorf("%w: not base64", ErrInvalidResponse)
+	}
+	response, err := parseXML(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	if !response.is(nsProtocol, "Response") {
+		return nil, fmt.Errorf("%w: not a SAML Response", ErrInvalidResponse)
+	}
+
+	acsURL := ACSURL(organizationID)
+	if destination := response.attr("Destination"); destination != "" && destination != acsURL {
+		return nil, fmt.Errorf("%w: destination %s is not this service", ErrInvalidResponse, destination)
+	}
+	if status := attrOf(childOf(response.child(nsProtocol, "Status"), nsProtocol, "StatusCode"), "Value"); status != statusSuccess {
+		return nil, fmt.Errorf("%w: status %s", ErrInvalidResponse, status)
+	}
+
+	// Data is read only from the signed elements; encrypted assertions are
+	// not supported
+	assertions := response.childrenNamed(nsAssertion, "Assertion")
+	if len(assertions) != 1 {
+		return nil, fmt.Errorf("%w: expected one unencrypted assertion", ErrInvalidResponse)
+	}
+	assertion := assertions[0]
+	if response.child(nsDSig, "Signature") != nil {
+		if err := verifySignature(response, certificates); err != nil {
+			return nil, err
+		}
+	} else if err := verifySignature(assertion, certificates); err != nil {
+		return nil, err
+	}
+
+	if issuer := textOf(assertion.child(nsAssertion, "Issuer")); issuer != connection.IdPEntityID {
+		return nil, fmt.Errorf("%w: unexpected issuer %s", ErrInvalidResponse, issuer)
+	}
+
+	now := utils.Now()
+	expiresAt, err := validateConditions(assertion, EntityID(organizationID), now)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := assertion.child(nsAssertion, "Subject")
+	nameID := textOf(childOf(subject, nsAssertion, "NameID"))
+	if nameID == "" {
+		return nil, fmt.Errorf("%w: missing NameID", ErrInvalidResponse)
+	}
+	confirmed := false
+	for _, confirmation := range childrenOf(subject, nsAssertion, "SubjectConfirmation") {
+		data := confirmation.child(nsAssertion, "SubjectConfirmationData")
+		if confirmation.attr("Method") != bearerMethod || data == nil || data.attr("Recipient") != acsURL {
+			continue
+		}
+		notOnOrAfter, err := parseInstant(data.attr("NotOnOrAfter"))
+		if err != nil || !now.Before(notOnOrAfter.Add(ClockSkew)) {
+			continue
+		}
+		confirmed = true
+		if notOnOrAfter.After(expiresAt) {
+			expiresAt = notOnOrAfter
+		}
+	}
+	if !confirmed {
+		return nil, fmt.Errorf("%w: no valid bearer confirmation for %s", ErrInvalidResponse, acsURL)
+	}
+
+	if err := consume(ctx, organizationID, assertion.attr("ID"), expiresAt); err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{
+		OrganizationID: organizationID,
+		NameID:         nameID,
+		Attributes:     attributes(assertion),
+		SessionIndex:   attrOf(assertion.child(nsAssertion, "AuthnStatement"), "SessionIndex"),
+		connection:     connection,
+	}
+	identity.mapClaims()
+	if utils.ValidateEmailSyntax(identity.Email) != nil {
+		return nil, fmt.Errorf("%w: no email address in the assertion", ErrInvalidResponse)
+	}
+	return identity, nil
+}
+
+// validateConditions checks the validity window and audience of an assertion
+// and returns when it expires
+func validateConditions(assertion *element, audience string, now time.Time) (time.Time, error) {
+	expiresAt := now.Add(time.Hour)
+	conditions := assertion.child(nsAssertion, "Conditions")
+	if conditions == nil {
+		return time.Time{}, fmt.Errorf("%w: missing Conditions", ErrInvalidResponse)
+	}
+	if value := conditions.attr("NotBefore"); value != "" {
+		notBefore, err := parseInstant(value)
+		if err != nil || now.Add(ClockSkew).Before(notBefore) {
+			return time.Time{}, fmt.Errorf("%w: assertion is not yet valid", ErrInvalidResponse)
+		}
+	}
+	if value := conditions.attr("NotOnOrAfter"); value != "" {
+		notOnOrAfter, err := parseInstant(value)
+		if err != nil || !now.Before(notOnOrAfter.Add(ClockSkew)) {
+			return time.Time{}, fmt.Errorf("%w: assertion has expired", ErrInvalidResponse)
+		}
+		expiresAt = notOnOrAfter
+	}
+
+	// Every AudienceRestriction must name this service provider
+	for _, restriction := range conditions.childrenNamed(nsAssertion, "AudienceRestriction") {
+		found := false
+		for _, candidate := range restriction.childrenNamed(nsAssertion, "Audience") {
+			found = found || candidate.text() == audience
+		}
+		if !found {
+			return time.Time{}, fmt.Errorf("%w: audience is not %s", ErrInvalidResponse, audience)
+		}
+	}
+	return expiresAt.Add(ClockSkew), nil
+}
+
+// consume records an assertion ID, failing when it was seen before
+func consume(ctx context.Context, organizationID, id string, expiresAt time.Time) error {
+	if id == "" {
+		return fmt.Errorf("%w: assertion has no ID", ErrInvalidResponse)
+	}
+	_, err := config.GetCollection(models.SAMLAssertion{}.CollectionName()).InsertOne(ctx, models.SAMLAssertion{
+		ID:             organizationID + ":" + id,
+		OrganizationID: organizationID,
+		ExpiresAt:      expiresAt,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrReplayed
+	}
+	return err
+}
+
+// attributes reads the AttributeStatement, keyed by Name and FriendlyName
+func attributes(assertion *element) map[string][]string {
+	values := map[string][]string{}
+	for _, statement := range assertion.childrenNamed(nsAssertion, "AttributeStatement") {
+		for _, attribute := range statement.childrenNamed(nsAssertion, "Attribute") {
+			var list []string
+			for _, value := range attribute.childrenNamed(nsAssertion, "AttributeValue") {
+				list = append(list, value.text())
+			}
+			for _, name := range []string{attribute.attr("Name"), attribute.attr("FriendlyName")} {
+				if name != "" {
+					values[name] = append(values[name], list...)
+				}
+			}
+		}
+	}
+	return values
+}
+
+// mapClaims fills the claims from the attributes through the connection's
+// AttributeMap and RoleMap
+func (i *Identity) mapClaims() {
+	i.Email = strings.ToLower(strings.TrimSpace(i.first(ClaimEmail)))
+	if i.Email == "" && utils.ValidateEmailSyntax(i.NameID) == nil {
+		i.Email = strings.ToLower(i.NameID)
+	}
+	i.Name = i.first(ClaimName)
+	i.Groups = i.values(ClaimGroups)
+
+	// The first mapped value wins; unmapped values are never used as roles
+	for _, value := range append(i.values(ClaimRole), i.Groups...) {
+		if role, ok := i.connection.RoleMap[value]; ok {
+			i.Role = role
+			break
+		}
+	}
+}
+
+func (i *Identity) values(claim string) []string {
+	if name, ok := i.connection.AttributeMap[claim]; ok {
+		return i.Attributes[name]
+	}
+	for _, name := range defaultAttributes[claim] {
+		if values := i.Attributes[name]; len(values) > 0 {
+			return values
+		}
+	}
+	return nil
+}
+
+func (i *Identity) first(claim string) string {
+	if values := i.values(claim); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// SignIn issues our JWT pair for the user of a verified identity. Unknown
+// users are created when the connection provisions users; a mapped role is
+// written to the user on every sign-in. Only users created over SAML are
+// signed in, so an identity provider cannot take over password or social
+// accounts, and never users whose role outranks admin.
+func SignIn(ctx context.Context, identity *Identity) (*Tokens, error) {
+	users := config.GetCollection(utils.UsersDirectory.Collection)
+	roleField := utils.UsersDirectory.RoleField
+	filter := bson.M{"organization_id": identity.OrganizationID, "email": identity.Email}
+
+	var user bson.M
+	err := users.FindOne(ctx, filter).Decode(&user)
+	provisioned := false
+	switch {
+	case err == mongo.ErrNoDocuments:
+		if !identity.connection.AutoProvision {
+			return nil, ErrUnknownUser
+		}
+		now := utils.Now()
+		user = bson.M{
+			"_id":             primitive.NewObjectID(),
+			"organization_id": identity.OrganizationID,
+			"email":           identity.Email,
+			"name":            identity.Name,
+			roleField:         identity.defaultRole(),
+			"status":          "active",
+			"auth_provider":   "saml",
+			"created_at":      now,
+			"updated_at":      now,
+		}
+		if _, err := users.InsertOne(ctx, user); err != nil {
+			return nil, err
+		}
+		provisioned = true
+	case err != nil:
+		return nil, err
+	}
+	if provider, _ := user["auth_provider"].(string); provider != "saml" {
+		return nil, ErrNotSAMLUser
+	}
+
+	if status, _ := user[utils.UsersDirectory.StatusField].(string); slices.Contains([]string{"deactivated", "suspended", "deleted"}, status) {
+		return nil, ErrUserDisabled
+	}
+
+	role, _ := user[roleField].(string)
+	if authz.HasRole(role, authz.RoleSuperAdmin) {
+		return nil, ErrRoleNotAllowed
+	}
+	if identity.Role != "" && identity.Role != role {
+		_, err := users.UpdateOne(ctx, bson.M{"_id": user["_id"]}, bson.M{"$set": bson.M{
+			roleField:    identity.Role,
+			"updated_at": utils.Now(),
+		}})
+		if err != nil {
+			return nil, err
+		}
+		role = identity.Role
+	}
+
+	userID := fmt.Sprint(user["_id"])
+	if id, ok := user["_id"].(primitive.ObjectID); ok {
+		userID = id.Hex()
+	}
+	accessToken, refreshToken, err := utils.GenerateTokenPair(userID, identity.OrganizationID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	utils.LogAuditContext(ctx, userID, "saml_sign_in", identity.OrganizationID, map[string]interface{}{
+		"name_id":     identity.NameID,
+		"role":        role,
+		"provisioned": provisioned,
+	})
+	return &Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		UserID:       userID,
+		Role:         role,
+		Provisioned:  provisioned,
+	}, nil
+}
+
+// RedirectURL is where the ACS sends the browser after a sign-in; empty
+// responds with JSON. It is checked again on every sign-in, so a custom
+// domain that was removed no longer receives tokens.
+func (i *Identity) RedirectURL(ctx context.Context) string {
+	if i.connection.RedirectURL == "" || !allowedRedirect(ctx, i.OrganizationID, i.connection.RedirectURL) {
+		return ""
+	}
+	return i.connection.RedirectURL
+}
+
+func (i *Identity) defaultRole() string {
+	if i.Role != "" {
+		return i.Role
+	}
+	if i.connection.DefaultRole != "" {
+		return i.connection.DefaultRole
+	}
+	return authz.RoleViewer
+}
+
+func parseInstant(value string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+func childOf(e *element, namespace, local string) *element {
+	if e == nil {
+		return nil
+	}
+	return e.child(namespace, local)
+}
+
+func childrenOf(e *element, namespace, local string) []*element {
+	if e == nil {
+		return nil
+	}
+	return e.childrenNamed(namespace, local)
+}
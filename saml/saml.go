@@ -0,0 +1,276 @@
+// Package saml makes the service a SAML 2.0 service provider, so enterprise
+// organizations can sign in through their identity provider. Each
+// organization has one models.SAMLConnection; a verified assertion is mapped
+// to a user of the organization and exchanged for our JWT pair:
+//
+//	identity, err := saml.ParseResponse(ctx, organizationID, c.FormValue("SAMLResponse"))
+//	if err == nil {
+//		tokens, err = saml.SignIn(ctx, identity)
+//	}
+//
+// The service provider URLs are served under SAML_BASE_URL, e.g.
+// https://api.example.com/saml/{organizationID}/acs.
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/authz"
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/domains"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrNotConfigured is returned for organizations without an enabled connection
+	ErrNotConfigured = errors.New("SAML is not configured for this organization")
+	// ErrInvalidConnection is returned when a connection fails validation
+	ErrInvalidConnection = errors.New("invalid SAML connection")
+	// ErrInvalidResponse is returned for responses that fail validation
+	ErrInvalidResponse = errors.New("invalid SAML response")
+	// ErrReplayed is returned for an assertion that was already consumed
+	ErrReplayed = errors.New("SAML assertion was already used")
+	// ErrUnknownUser is returned when the user does not exist and the
+	// connection does not provision users
+	ErrUnknownUser = errors.New("no user for the SAML identity")
+	// ErrUserDisabled is returned for deactivated users
+	ErrUserDisabled = errors.New("user is deactivated")
+	// ErrNotSAMLUser is returned for existing users who sign in another way;
+	// an identity provider cannot take over their accounts
+	ErrNotSAMLUser = errors.New("user does not sign in with SAML")
+	// ErrRoleNotAllowed is returned for users whose role outranks what SAML may grant
+	ErrRoleNotAllowed = errors.New("role cannot sign in over SAML")
+)
+
+// ClockSkew is the tolerance for assertion validity times
+const ClockSkew = 3 * time.Minute
+
+// Claims that an AttributeMap can map
+const (
+	ClaimEmail  = "email"
+	ClaimName   = "name"
+	ClaimRole   = "role"
+	ClaimGroups = "groups"
+)
+
+// defaultAttributes are the attribute names tried for unmapped claims, as
+// sent by Okta, Azure AD and Google Workspace
+var defaultAttributes = map[string][]string{
+	ClaimEmail: {"email", "mail", "emailaddress",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress"},
+	ClaimName: {"name", "displayName",
+		"http://schemas.microsoft.com/identity/claims/displayname",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/name"},
+	ClaimRole: {"role",
+		"http://schemas.microsoft.com/ws/2008/06/identity/claims/role"},
+	ClaimGroups: {"groups",
+		"http://schemas.microsoft.com/ws/2008/06/identity/claims/groups"},
+}
+
+// EntityID is the service provider entity ID of an organization, which is
+// also the URL of its metadata
+func EntityID(organizationID string) string {
+	return spURL(organizationID, "metadata")
+}
+
+// ACSURL is the assertion consumer service URL of an organization
+func ACSURL(organizationID string) string {
+	return spURL(organizationID, "acs")
+}
+
+func spURL(organizationID, endpoint string) string {
+	base := strings.TrimRight(config.GetEnv("SAML_BASE_URL", ""), "/")
+	return base + "/saml/" + url.PathEscape(organizationID) + "/" + endpoint
+}
+
+func connections() *mongo.Collection {
+	return config.GetCollection(models.SAMLConnection{}.CollectionName())
+}
+
+// GetConnection returns the connection of an organization
+func GetConnection(ctx context.Context, organizationID string) (*models.SAMLConnection, error) {
+	var connection models.SAMLConnection
+	err := connections().FindOne(ctx, bson.M{"organization_id": organizationID}).Decode(&connection)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotConfigured
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &connection, nil
+}
+
+// SaveConnection validates and stores the connection of an organization,
+// replacing any previous one
+func SaveConnection(ctx context.Context, connection *models.SAMLConnection) error {
+	if connection.OrganizationID == "" || connection.IdPEntityID == "" {
+		return fmt.Errorf("%w: organization and IdP entity ID are required", ErrInvalidConnection)
+	}
+	if parsed, err := url.Parse(connection.SSOURL); err != nil || parsed.Scheme != "https" {
+		return fmt.Errorf("%w: sso_url must be an https URL", ErrInvalidConnection)
+	}
+	if _, err := parseCertificates(connection.Certificates); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConnection, err)
+	}
+	if connection.RedirectURL != "" && !allowedRedirect(ctx, connection.OrganizationID, connection.RedirectURL) {
+		return fmt.Errorf("%w: redirect_url must be on FRONTEND_URL or a verified custom domain of the organization", ErrInvalidConnection)
+	}
+	for _, role := range append(mapValues(connection.RoleMap), connection.DefaultRole) {
+		if role == "" {
+			continue
+		}
+		if _, ok := authz.GetRole(role); !ok || authz.HasRole(role, authz.RoleSuperAdmin) {
+			return fmt.Errorf("%w: role %q cannot be granted over SAML", ErrInvalidConnection, role)
+		}
+	}
+
+	now := utils.Now()
+	connection.UpdatedAt = now
+	if connection.ID.IsZero() {
+		connection.ID = primitive.NewObjectID()
+	}
+	if connection.CreatedAt.IsZero() {
+		connection.CreatedAt = now
+	}
+	_, err := connections().ReplaceOne(ctx, bson.M{"organization_id": connection.OrganizationID}, connection,
+		options.Replace().SetUpsert(true))
+	return err
+}
+
+// DeleteConnection removes the connection of an organization
+func DeleteConnection(ctx context.Context, organizationID string) error {
+	result, err := connections().DeleteOne(ctx, bson.M{"organization_id": organizationID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotConfigured
+	}
+	return nil
+}
+
+// allowedRedirect reports whether tokens may be sent to rawURL: an https URL
+// on the host of FRONTEND_URL or on a verified custom domain of the organization
+func allowedRedirect(ctx context.Context, organizationID, rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" || parsed.User != nil {
+		return false
+	}
+	if frontend, err := url.Parse(config.GetEnv("FRONTEND_URL", "")); err == nil && frontend.Host != "" &&
+		strings.EqualFold(frontend.Host, parsed.Host) {
+		return true
+	}
+	owner, ok := domains.Resolve(ctx, parsed.Host)
+	return ok && owner == organizationID
+}
+
+func mapValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, value := range m {
+		values = append(values, value)
+	}
+	return values
+}
+
+// parseCertificates decodes PEM certificates; bare base64, as copied from IdP
+// metadata, is accepted too
+func parseCertificates(encoded []string) ([]*x509.Certificate, error) {
+	var certificates []*x509.Certificate
+	for _, value := range encoded {
+		der := []byte(nil)
+		if block, _ := pem.Decode([]byte(value)); block != nil {
+			der = block.Bytes
+		} else if decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), "")); err == nil {
+			der = decoded
+		}
+		certificate, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IdP certificate: %w", err)
+		}
+		certificates = append(certificates, certificate)
+	}
+	if len(certificates) == 0 {
+		return nil, errors.New("at least one IdP certificate is required")
+	}
+	return certificates, nil
+}
+
+var metadataTemplate = template.Must(template.New("metadata").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="{{.EntityID}}">
+  <md:SPSSODescriptor AuthnRequestsSigned="false" WantAssertionsSigned="true" protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <md:NameIDFormat>urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress</md:NameIDFormat>
+    <md:AssertionConsumerService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" Location="{{.ACSURL}}" index="0" isDefault="true"/>
+  </md:SPSSODescriptor>
+</md:EntityDescriptor>
+`))
+
+// Metadata renders the service provider metadata of an organization, for
+// the identity provider's configuration
+func Metadata(organizationID string) ([]byte, error) {
+	var b bytes.Buffer
+	err := metadataTemplate.Execute(&b, map[string]string{
+		"EntityID": xmlEscape(EntityID(organizationID)),
+		"ACSURL":   xmlEscape(ACSURL(organizationID)),
+	})
+	return b.Bytes(), err
+}
+
+// LoginURL returns the IdP URL that starts a service-provider initiated
+// sign-in, with an AuthnRequest in the HTTP-Redirect binding. relayState is
+// returned to the ACS unchanged.
+func LoginURL(ctx context.Context, organizationID, relayState string) (string, error) {
+	connection, err := GetConnection(ctx, organizationID)
+	if err != nil {
+		return "", err
+	}
+	if !connection.Enabled {
+		return "", ErrNotConfigured
+	}
+
+	request := fmt.Sprintf(`<samlp:AuthnRequest xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" `+
+		`xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="_%s" Version="2.0" IssueInstant="%s" `+
+		`Destination="%s" AssertionConsumerServiceURL="%s" ProtocolBinding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST">`+
+		`<saml:Issuer>%s</saml:Issuer>`+
+		`<samlp:NameIDPolicy Format="urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress" AllowCreate="true"/>`+
+		`</samlp:AuthnRequest>`,
+		utils.NewID(), utils.Now().UTC().Format(time.RFC3339), xmlEscape(connection.SSOURL),
+		xmlEscape(ACSURL(organizationID)), xmlEscape(EntityID(organizationID)))
+
+	var compressed bytes.Buffer
+	writer, err := flate.NewWriter(&compressed, flate.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	writer.Write([]byte(request))
+	writer.Close()
+
+	query := url.Values{"SAMLRequest": {base64.StdEncoding.EncodeToString(compressed.Bytes())}}
+	if relayState != "" {
+		query.Set("RelayState", relayState)
+	}
+	separator := "?"
+	if strings.Contains(connection.SSOURL, "?") {
+		separator = "&"
+	}
+	return connection.SSOURL + separator + query.Encode(), nil
+}
+
+func xmlEscape(s string) string {
+	return attrEscaper.Replace(s)
+}
@@ -0,0 +1,339 @@
+package scim
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Filter is a parsed SCIM filter expression (RFC 7644 section 3.4.2.2)
+type Filter interface {
+	// bson converts the filter to a Mongo filter, mapping attribute paths to
+	// document fields with attributes
+	bson(attributes map[string]Attribute) (bson.M, error)
+	// matches evaluates the filter on an element of a multi-valued attribute,
+	// as in the path members[value eq "id"]
+	matches(element map[string]interface{}) bool
+}
+
+// comparison is "attrPath op value", or "attrPath pr"
+type comparison struct {
+	path     string
+	operator string
+	value    interface{}
+}
+
+type logical struct {
+	operator string // "and" or "or"
+	left     Filter
+	right    Filter
+}
+
+type negation struct {
+	filter Filter
+}
+
+// ParseFilter parses the filter query parameter. Attribute paths and
+// operators are case-insensitive; value paths such as emails[type eq "work"]
+// are reduced to their attribute.
+func ParseFilter(input string) (Filter, error) {
+	tokens, err := tokenizeFilter(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, invalidFilter("unexpected %q", p.tokens[p.pos].text)
+	}
+	return filter, nil
+}
+
+func invalidFilter(format string, args ...interface{}) error {
+	return &Error{Status: 400, Type: "invalidFilter", Detail: fmt.Sprintf(format, args...)}
+}
+
+type filterToken struct {
+	text   string
+	quoted bool // A string literal
+}
+
+// tokenizeFilter splits the input into words, string literals and parentheses
+func tokenizeFilter(input string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, filterToken{text: string(r)})
+			i++
+		case r == '"':
+			var literal strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				literal.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, invalidFilter("unterminated string")
+			}
+			tokens = append(tokens, filterToken{text: literal.String(), quoted: true})
+			i++
+		default:
+			start := i
+			depth := 0
+			for ; i < len(runes); i++ {
+				// Brackets of value paths may contain spaces and quotes
+				if runes[i] == '[' {
+					depth++
+				} else if runes[i] == ']' {
+					depth--
+				} else if depth == 0 && (unicode.IsSpace(runes[i]) || runes[i] == '(' || runes[i] == ')') {
+					break
+				}
+			}
+			tokens = append(tokens, filterToken{text: string(runes[start:i])})
+		}
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) peekKeyword(keyword string) bool {
+	return p.pos < len(p.tokens) && !p.tokens[p.pos].quoted && strings.EqualFold(p.tokens[p.pos].text, keyword)
+}
+
+func (p *filterParser) parseOr() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{operator: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (Filter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("and") {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{operator: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (Filter, error) {
+	if p.peekKeyword("not") {
+		p.pos++
+		filter, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &negation{filter: filter}, nil
+	}
+	if p.peekKeyword("(") {
+		p.pos++
+		filter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peekKeyword(")") {
+			return nil, invalidFilter("missing )")
+		}
+		p.pos++
+		return filter, nil
+	}
+	return p.parseComparison()
+}
+
+var valuePathPattern = regexp.MustCompile(`^([A-Za-z][\w.:-]*)\[(.*)\](?:\.(\w+))?$`)
+
+func (p *filterParser) parseComparison() (Filter, error) {
+	if p.pos+1 >= len(p.tokens) {
+		return nil, invalidFilter("incomplete expression")
+	}
+	path := p.tokens[p.pos].text
+	operator := strings.ToLower(p.tokens[p.pos+1].text)
+	p.pos += 2
+
+	// emails[type eq "work"].value compares the sub-attribute of any element;
+	// the element condition is not checked since fields hold a single value
+	if match := valuePathPattern.FindStringSubmatch(path); match != nil {
+		path = match[1]
+		if match[3] != "" {
+			path += "." + match[3]
+		}
+	}
+
+	if operator == "pr" {
+		return &comparison{path: path, operator: operator}, nil
+	}
+	switch operator {
+	case "eq", "ne", "co", "sw", "ew", "gt", "ge", "lt", "le":
+	default:
+		return nil, invalidFilter("unsupported operator %q", operator)
+	}
+	if p.pos >= len(p.tokens) {
+		return nil, invalidFilter("missing value for %s", path)
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	return &comparison{path: path, operator: operator, value: literalValue(token)}, nil
+}
+
+// literalValue converts true, false, null and numbers; quoted tokens stay strings
+func literalValue(token filterToken) interface{} {
+	if token.quoted {
+		return token.text
+	}
+	switch strings.ToLower(token.text) {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	if n, err := strconv.ParseFloat(token.text, 64); err == nil {
+		return n
+	}
+	return token.text
+}
+
+func (c *comparison) bson(attributes map[string]Attribute) (bson.M, error) {
+	attribute, ok := lookupAttribute(attributes, c.path)
+	if !ok {
+		return nil, invalidFilter("unknown attribute %q", c.path)
+	}
+
+	value := c.value
+	if attribute.ToField != nil && value != nil {
+		converted, err := attribute.ToField(value)
+		if err != nil {
+			return nil, invalidFilter("%s: %v", c.path, err)
+		}
+		value = converted
+	}
+
+	field := attribute.Field
+	text, isText := value.(string)
+	switch c.operator {
+	case "pr":
+		return bson.M{field: bson.M{"$exists": true, "$nin": bson.A{nil, ""}}}, nil
+	case "eq":
+		if isText && !attribute.CaseExact {
+			return bson.M{field: caseInsensitive("^" + regexp.QuoteMeta(text) + "$")}, nil
+		}
+		return bson.M{field: value}, nil
+	case "ne":
+		if isText && !attribute.CaseExact {
+			return bson.M{field: bson.M{"$not": caseInsensitive("^" + regexp.QuoteMeta(text) + "$")}}, nil
+		}
+		return bson.M{field: bson.M{"$ne": value}}, nil
+	case "co", "sw", "ew":
+		if !isText {
+			return nil, invalidFilter("%s needs a string value", c.operator)
+		}
+		pattern := regexp.QuoteMeta(text)
+		switch c.operator {
+		case "sw":
+			pattern = "^" + pattern
+		case "ew":
+			pattern += "$"
+		}
+		return bson.M{field: caseInsensitive(pattern)}, nil
+	default:
+		return bson.M{field: bson.M{"$" + c.operator: value}}, nil
+	}
+}
+
+func caseInsensitive(pattern string) primitive.Regex {
+	return primitive.Regex{Pattern: pattern, Options: "i"}
+}
+
+func (l *logical) bson(attributes map[string]Attribute) (bson.M, error) {
+	left, err := l.left.bson(attributes)
+	if err != nil {
+		return nil, err
+	}
+	right, err := l.right.bson(attributes)
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{"$" + l.operator: bson.A{left, right}}, nil
+}
+
+func (n *negation) bson(attributes map[string]Attribute) (bson.M, error) {
+	inner, err := n.filter.bson(attributes)
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{"$nor": bson.A{inner}}, nil
+}
+
+func (c *comparison) matches(element map[string]interface{}) bool {
+	actual, ok := lookupKey(element, c.path)
+	if c.operator == "pr" {
+		return ok && actual != nil && actual != ""
+	}
+	if !ok {
+		return c.operator == "ne"
+	}
+
+	actualText, want := fmt.Sprint(actual), fmt.Sprint(c.value)
+	switch c.operator {
+	case "eq":
+		return strings.EqualFold(actualText, want)
+	case "ne":
+		return !strings.EqualFold(actualText, want)
+	case "co":
+		return strings.Contains(strings.ToLower(actualText), strings.ToLower(want))
+	case "sw":
+		return strings.HasPrefix(strings.ToLower(actualText), strings.ToLower(want))
+	case "ew":
+		return strings.HasSuffix(strings.ToLower(actualText), strings.ToLower(want))
+	default:
+		return false // Ordering is not used in value paths
+	}
+}
+
+func (l *logical) matches(element map[string]interface{}) bool {
+	if l.operator == "and" {
+		return l.left.matches(element) && l.right.matches(element)
+	}
+	return l.left.matches(element) || l.right.matches(element)
+}
+
+func (n *negation) matches(element map[string]interface{}) bool {
+	return !n.filter.matches(element)
+}
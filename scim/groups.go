@@ -0,0 +1,380 @@
+package scim
+
+import (
+	"context"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/authz"
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Group is the SCIM Group resource
+type Group struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	DisplayName string   `json:"displayName"`
+	Members     []Member `json:"members,omitempty"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+// GroupAttributes maps Group attribute paths to fields of the groups
+// collection, for filters
+var GroupAttributes = map[string]Attribute{
+	"id":                {Field: "_id", CaseExact: true, ToField: objectIDValue},
+	"displayName":       {Field: "display_name"},
+	"externalId":        {Field: "external_id", CaseExact: true},
+	"members":           {Field: "members", CaseExact: true},
+	"members.value":     {Field: "members", CaseExact: true},
+	"meta.created":      {Field: "created_at", ToField: timeValue},
+	"meta.lastModified": {Field: "updated_at", ToField: timeValue},
+}
+
+func groups() *mongo.Collection {
+	return config.GetCollection(models.SCIMGroup{}.CollectionName())
+}
+
+// groupsOfUser returns the groups a user is a member of
+func groupsOfUser(c *fiber.Ctx, organizationID, userID string) ([]models.SCIMGroup, error) {
+	cursor, err := groups().Find(c.UserContext(), bson.M{"organization_id": organizationID, "members": userID})
+	if err != nil {
+		return nil, err
+	}
+	list := []models.SCIMGroup{}
+	err = cursor.All(c.UserContext(), &list)
+	return list, err
+}
+
+func groupResource(c *fiber.Ctx, group models.SCIMGroup) Group {
+	id := group.ID.Hex()
+	resource := Group{
+		Schemas:     []string{SchemaGroup},
+		ID:          id,
+		ExternalID:  group.ExternalID,
+		DisplayName: group.DisplayName,
+		Members:     []Member{},
+		Meta: &Meta{
+			ResourceType: "Group",
+			Created:      utils.FormatTime(group.CreatedAt),
+			LastModified: utils.FormatTime(group.UpdatedAt),
+			Location:     location(c, "Groups", id),
+		},
+	}
+	for _, member := range group.Members {
+		resource.Members = append(resource.Members, Member{Value: member, Ref: location(c, "Users", member)})
+	}
+	return resource
+}
+
+// groupMembers returns the distinct member IDs of a Group that are users of
+// the organization
+func groupMembers(ctx context.Context, organizationID string, members []Member) ([]string, error) {
+	ids := []string{}
+	for _, member := range members {
+		if member.Value != "" && !slices.Contains(ids, member.Value) {
+			ids = append(ids, member.Value)
+		}
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	values := bson.A{}
+	for _, id := range ids {
+		if objectID, err := primitive.ObjectIDFromHex(id); err == nil {
+			values = append(values, objectID)
+		} else {
+			values = append(values, id)
+		}
+	}
+	count, err := users().CountDocuments(ctx, bson.M{"organization_id": organizationID, "_id": bson.M{"$in": values}})
+	if err != nil {
+		return nil, err
+	}
+	if int(count) != len(ids) {
+		return nil, badRequest("invalidValue", "members must be users of the organization")
+	}
+	return ids, nil
+}
+
+func loadGroup(c *fiber.Ctx, organizationID, id string) (*models.SCIMGroup, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, notFound("Group", id)
+	}
+	var group models.SCIMGroup
+	err = groups().FindOne(c.UserContext(), bson.M{"_id": objectID, "organization_id": organizationID}).Decode(&group)
+	if err == mongo.ErrNoDocuments {
+		return nil, notFound("Group", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (s *server) listGroups(c *fiber.Ctx, organizationID string) (int, interface{}, error) {
+	filter, startIndex, count, err := listParams(c, GroupAttributes)
+	if err != nil {
+		return 0, nil, err
+	}
+	filter = userFilter(organizationID, filter)
+
+	total, err := groups().CountDocuments(c.UserContext(), filter)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	resources := []interface{}{}
+	if count > 0 {
+		cursor, err := groups().Find(c.UserContext(), filter, options.Find().
+			SetSort(bson.D{{Key: "_id", Value: 1}}).
+			SetSkip(int64(startIndex-1)).
+			SetLimit(int64(count)))
+		if err != nil {
+			return 0, nil, err
+		}
+		var list []models.SCIMGroup
+		if err := cursor.All(c.UserContext(), &list); err != nil {
+			return 0, nil, err
+		}
+		// Okta lists groups with excludedAttributes=members; sizes are unbounded
+		excludeMembers := c.Query("excludedAttributes") == "members"
+		for _, group := range list {
+			resource := groupResource(c, group)
+			if excludeMembers {
+				resource.Members = nil
+			}
+			resources = append(resources, resource)
+		}
+	}
+
+	return fiber.StatusOK, ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}, nil
+}
+
+func (s *server) getGroup(c *fiber.Ctx, organizationID string) (int, interface{}, error) {
+	group, err := loadGroup(c, organizationID, c.Params("id"))
+	if err != nil {
+		return 0, nil, err
+	}
+	return fiber.StatusOK, groupResource(c, *group), nil
+}
+
+func (s *server) createGroup(c *fiber.Ctx, organizationID string) (int, interface{}, error) {
+	var resource Group
+	if err := decodeBody(c, &resource); err != nil {
+		return 0, nil, err
+	}
+	if resource.DisplayName == "" {
+		return 0, nil, badRequest("invalidValue", "displayName is required")
+	}
+	members, err := groupMembers(c.UserContext(), organizationID, resource.Members)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	now := utils.Now()
+	group := models.SCIMGroup{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: organizationID,
+		DisplayName:    resource.DisplayName,
+		ExternalID:     resource.ExternalID,
+		Members:        members,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if _, err := groups().InsertOne(c.UserContext(), group); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return 0, nil, &Error{Status: fiber.StatusConflict, Type: "uniqueness", Detail: "displayName is already provisioned"}
+		}
+		return 0, nil, err
+	}
+	if err := s.syncRoles(c.UserContext(), organizationID, group.DisplayName, nil, members); err != nil {
+		return 0, nil, err
+	}
+
+	utils.LogAuditContext(c.UserContext(), "scim", "scim_group_provisioned", group.ID.Hex(), map[string]interface{}{
+		"display_name": group.DisplayName,
+		"members":      len(members),
+	})
+	return fiber.StatusCreated, groupResource(c, group), nil
+}
+
+// saveGroup replaces the name and members of a group
+func (s *server) saveGroup(c *fiber.Ctx, organizationID string, group *models.SCIMGroup, resource Group) (int, interface{}, error) {
+	if resource.DisplayName == "" {
+		return 0, nil, badRequest("invalidValue", "displayName is required")
+	}
+	members, err := groupMembers(c.UserContext(), organizationID, resource.Members)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	previousName, previousMembers := group.DisplayName, group.Members
+	group.DisplayName = resource.DisplayName
+	group.ExternalID = resource.ExternalID
+	group.Members = members
+	group.UpdatedAt = utils.Now()
+
+	_, err = groups().UpdateOne(c.UserContext(), bson.M{"_id": group.ID}, bson.M{"$set": bson.M{
+		"display_name": group.DisplayName,
+		"external_id":  group.ExternalID,
+		"members":      group.Members,
+		"updated_at":   group.UpdatedAt,
+	}})
+	if mongo.IsDuplicateKeyError(err) {
+		return 0, nil, &Error{Status: fiber.StatusConflict, Type: "uniqueness", Detail: "displayName is already provisioned"}
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if previousName != group.DisplayName {
+		// Members lose the role of the old name and gain the role of the new one
+		if err := s.syncRoles(c.UserContext(), organizationID, previousName, previousMembers, nil); err != nil {
+			return 0, nil, err
+		}
+		previousMembers = nil
+	}
+	if err := s.syncRoles(c.UserContext(), organizationID, group.DisplayName, previousMembers, group.Members); err != nil {
+		return 0, nil, err
+	}
+	return fiber.StatusOK, groupResource(c, *group), nil
+}
+
+func (s *server) replaceGroup(c *fiber.Ctx, organizationID string) (int, interface{}, error) {
+	group, err := loadGroup(c, organizationID, c.Params("id"))
+	if err != nil {
+		return 0, nil, err
+	}
+	var resource Group
+	if err := decodeBody(c, &resource); err != nil {
+		return 0, nil, err
+	}
+	return s.saveGroup(c, organizationID, group, resource)
+}
+
+func (s *server) patchGroup(c *fiber.Ctx, organizationID string) (int, interface{}, error) {
+	group, err := loadGroup(c, organizationID, c.Params("id"))
+	if err != nil {
+		return 0, nil, err
+	}
+	var request PatchRequest
+	if err := decodeBody(c, &request); err != nil {
+		return 0, nil, err
+	}
+
+	patched, err := applyPatch(groupResource(c, *group), request.Operations)
+	if err != nil {
+		return 0, nil, err
+	}
+	var resource Group
+	if err := remarshal(patched, &resource); err != nil {
+		return 0, nil, badRequest("invalidValue", "invalid patched group: %v", err)
+	}
+
+	status, body, err := s.saveGroup(c, organizationID, group, resource)
+	if err != nil {
+		return 0, nil, err
+	}
+	// Okta and Azure AD expect no body for member updates unless asked
+	if c.Query("attributes") == "" {
+		return fiber.StatusNoContent, nil, nil
+	}
+	return status, body, nil
+}
+
+func (s *server) deleteGroup(c *fiber.Ctx, organizationID string) (int, interface{}, error) {
+	group, err := loadGroup(c, organizationID, c.Params("id"))
+	if err != nil {
+		return 0, nil, err
+	}
+	if _, err := groups().DeleteOne(c.UserContext(), bson.M{"_id": group.ID}); err != nil {
+		return 0, nil, err
+	}
+	if err := s.syncRoles(c.UserContext(), organizationID, group.DisplayName, group.Members, nil); err != nil {
+		return 0, nil, err
+	}
+
+	utils.LogAuditContext(c.UserContext(), "scim", "scim_group_deprovisioned", group.ID.Hex(), map[string]interface{}{
+		"display_name": group.DisplayName,
+	})
+	return fiber.StatusNoContent, nil, nil
+}
+
+// syncRoles applies a role group's membership change: users added to a group
+// named after a registered role get that role, and removed users fall back to
+// the default role. Groups with other names carry no role, and neither do
+// groups named after super admin or a role inheriting it; users already
+// holding such a role are left alone.
+func (s *server) syncRoles(ctx context.Context, organizationID, groupName string, before, after []string) error {
+	if _, isRole := authz.GetRole(groupName); !isRole || authz.HasRole(groupName, authz.RoleSuperAdmin) {
+		return nil
+	}
+
+	var added, removed []string
+	for _, id := range after {
+		if !slices.Contains(before, id) {
+			added = append(added, id)
+		}
+	}
+	for _, id := range before {
+		if !slices.Contains(after, id) {
+			removed = append(removed, id)
+		}
+	}
+
+	for role, ids := range map[string][]string{groupName: added, s.options.DefaultRole: removed} {
+		if len(ids) == 0 {
+			continue
+		}
+		values := bson.A{}
+		for _, id := range ids {
+			if objectID, err := primitive.ObjectIDFromHex(id); err == nil {
+				values = append(values, objectID)
+			} else {
+				values = append(values, id)
+			}
+		}
+		filter := bson.M{"organization_id": organizationID, "_id": bson.M{"$in": values}}
+		if role == s.options.DefaultRole {
+			// Only users that still hold the group's role are downgraded
+			filter[utils.UsersDirectory.RoleField] = groupName
+		} else {
+			filter[utils.UsersDirectory.RoleField] = bson.M{"$nin": unmanagedRoles()}
+		}
+		_, err := users().UpdateMany(ctx, filter, bson.M{"$set": bson.M{
+			utils.UsersDirectory.RoleField: role,
+			"updated_at":                   utils.Now(),
+		}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unmanagedRoles are the roles SCIM never assigns or takes away: super admin
+// and every role inheriting it
+func unmanagedRoles() []string {
+	var names []string
+	for _, role := range authz.ListRoles() {
+		if authz.HasRole(role.Name, authz.RoleSuperAdmin) {
+			names = append(names, role.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,211 @@
+package scim
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// PatchRequest is the body of a PATCH request
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// PatchOperation adds, replaces or removes the value at path
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// applyPatch applies operations to resource, a SCIM resource rendered as
+// JSON and decoded into a map, so every attribute is patched the same way
+func applyPatch(resource interface{}, operations []PatchOperation) (map[string]interface{}, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	for _, operation := range operations {
+		// Azure AD capitalizes operations ("Replace")
+		op := strings.ToLower(operation.Op)
+		switch op {
+		case "add", "replace", "remove":
+		default:
+			return nil, badRequest("invalidSyntax", "unsupported operation %q", operation.Op)
+		}
+		if err := patchPath(document, op, stripSchema(operation.Path), operation.Value); err != nil {
+			return nil, err
+		}
+	}
+	return document, nil
+}
+
+// remarshal converts a patched document back into a resource struct
+func remarshal(document map[string]interface{}, resource interface{}) error {
+	data, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, resource)
+}
+
+// stripSchema removes a core schema URN prefix from a path
+func stripSchema(path string) string {
+	for _, schema := range []string{SchemaUser, SchemaGroup} {
+		if len(path) > len(schema) && strings.EqualFold(path[:len(schema)], schema) {
+			return strings.TrimPrefix(path[len(schema):], ":")
+		}
+	}
+	return path
+}
+
+func patchPath(document map[string]interface{}, op, path string, value interface{}) error {
+	if path == "" {
+		// Without a path the value holds attribute paths and their values
+		values, ok := value.(map[string]interface{})
+		if !ok || op == "remove" {
+			return badRequest("noTarget", "operation %s needs a path", op)
+		}
+		for key, v := range values {
+			if err := patchPath(document, op, stripSchema(key), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	attribute, filterExpression, sub := splitPath(path)
+	key := documentKey(document, attribute)
+	if filterExpression != "" {
+		return patchElements(document, key, op, filterExpression, sub, value)
+	}
+
+	if sub != "" {
+		child, _ := document[key].(map[string]interface{})
+		if child == nil {
+			if op == "remove" {
+				return nil
+			}
+			child = map[string]interface{}{}
+		}
+		if err := patchPath(child, op, sub, value); err != nil {
+			return err
+		}
+		document[key] = child
+		return nil
+	}
+
+	switch op {
+	case "remove":
+		delete(document, key)
+	case "add":
+		existing, isList := document[key].([]interface{})
+		switch added := value.(type) {
+		case []interface{}:
+			if isList {
+				document[key] = append(existing, added...)
+				return nil
+			}
+		case map[string]interface{}:
+			if current, ok := document[key].(map[string]interface{}); ok {
+				for k, v := range added {
+					current[documentKey(current, k)] = v
+				}
+				return nil
+			}
+			if isList {
+				document[key] = append(existing, added)
+				return nil
+			}
+		}
+		document[key] = value
+	default:
+		document[key] = value
+	}
+	return nil
+}
+
+// patchElements patches the elements of a multi-valued attribute matching a
+// filter, e.g. members[value eq "id"] or emails[type eq "work"].value
+func patchElements(document map[string]interface{}, key, op, filterExpression, sub string, value interface{}) error {
+	filter, err := ParseFilter(filterExpression)
+	if err != nil {
+		return err
+	}
+
+	elements, _ := document[key].([]interface{})
+	kept := make([]interface{}, 0, len(elements))
+	matched := false
+	for _, raw := range elements {
+		element, ok := raw.(map[string]interface{})
+		if !ok || !filter.matches(element) {
+			kept = append(kept, raw)
+			continue
+		}
+		matched = true
+
+		switch {
+		case op == "remove" && sub == "":
+			continue
+		case op == "remove":
+			delete(element, documentKey(element, sub))
+		case sub == "":
+			if replacement, ok := value.(map[string]interface{}); ok {
+				element = replacement
+			}
+		default:
+			element[documentKey(element, sub)] = value
+		}
+		kept = append(kept, element)
+	}
+
+	// Setting a sub-attribute of a missing element creates it, as Azure AD
+	// does for emails[type eq "work"].value
+	if !matched && op != "remove" && sub != "" {
+		element := map[string]interface{}{sub: value}
+		if c, ok := filter.(*comparison); ok && c.operator == "eq" {
+			element[c.path] = c.value
+		}
+		kept = append(kept, element)
+	}
+	document[key] = kept
+	return nil
+}
+
+// splitPath splits attr[filter].sub, or attr.sub
+func splitPath(path string) (attribute, filter, sub string) {
+	if match := valuePathPattern.FindStringSubmatch(path); match != nil {
+		return match[1], match[2], match[3]
+	}
+	attribute, sub, _ = strings.Cut(path, ".")
+	return attribute, "", sub
+}
+
+// documentKey returns the existing key matching name case-insensitively, or name
+func documentKey(document map[string]interface{}, name string) string {
+	for key := range document {
+		if strings.EqualFold(key, name) {
+			return key
+		}
+	}
+	return name
+}
+
+// lookupKey reads a possibly dotted path case-insensitively
+func lookupKey(document map[string]interface{}, path string) (interface{}, bool) {
+	head, rest, nested := strings.Cut(path, ".")
+	value, ok := document[documentKey(document, head)]
+	if !ok || !nested {
+		return value, ok
+	}
+	child, isMap := value.(map[string]interface{})
+	if !isMap {
+		return nil, false
+	}
+	return lookupKey(child, rest)
+}
@@ -0,0 +1,353 @@
+// Package scim serves a SCIM 2.0 API (RFC 7643/7644) for identity providers
+// such as Okta and Azure AD to provision the users and groups of an
+// organization. Users are stored in utils.UsersDirectory; groups whose display
+// name is a registered role grant that role to their members.
+//
+//	scim.Mount(app, "/scim/v2", scim.Options{})
+//
+// Identity providers authenticate with bearer tokens from CreateToken, each
+// bound to one organization.
+package scim
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/authz"
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Schema URNs
+const (
+	SchemaUser         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaSPConfig     = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+)
+
+// ContentType is the media type of SCIM responses
+const ContentType = "application/scim+json"
+
+// MaxResults caps the count of one list request
+const MaxResults = 200
+
+// ErrTokenNotFound is returned when revoking an unknown token
+var ErrTokenNotFound = errors.New("scim token not found")
+
+// Options configures the SCIM endpoints
+type Options struct {
+	DefaultRole string // Role of provisioned users outside role groups; default viewer
+	// SoftDelete marks deleted users inactive instead of removing them
+	SoftDelete bool
+}
+
+// Error is a SCIM error response
+type Error struct {
+	Status int
+	Type   string // scimType, e.g. "uniqueness" or "invalidFilter"
+	Detail string
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+// MarshalJSON renders the RFC 7644 error body, whose status is a string
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Schemas  []string `json:"schemas"`
+		Status   string   `json:"status"`
+		ScimType string   `json:"scimType,omitempty"`
+		Detail   string   `json:"detail,omitempty"`
+	}{[]string{SchemaError}, strconv.Itoa(e.Status), e.Type, e.Detail})
+}
+
+func badRequest(scimType, format string, args ...interface{}) *Error {
+	return &Error{Status: fiber.StatusBadRequest, Type: scimType, Detail: fmt.Sprintf(format, args...)}
+}
+
+func notFound(resource, id string) *Error {
+	return &Error{Status: fiber.StatusNotFound, Detail: fmt.Sprintf("%s %s not found", resource, id)}
+}
+
+// Meta is the resource metadata
+type Meta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Location     string `json:"location,omitempty"`
+}
+
+// ListResponse is a page of resources
+type ListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int64         `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+// Attribute maps a SCIM attribute path to a document field
+type Attribute struct {
+	Field     string
+	CaseExact bool
+	// ToField converts a filter value to the stored form, e.g. active to a status
+	ToField func(value interface{}) (interface{}, error)
+}
+
+// lookupAttribute finds a path case-insensitively, ignoring the schema URN prefix
+func lookupAttribute(attributes map[string]Attribute, path string) (Attribute, bool) {
+	path = stripSchema(path)
+	for name, attribute := range attributes {
+		if strings.EqualFold(name, path) {
+			return attribute, true
+		}
+	}
+	return Attribute{}, false
+}
+
+// objectIDValue converts an id filter value to an ObjectID when it is one
+func objectIDValue(value interface{}) (interface{}, error) {
+	if text, ok := value.(string); ok {
+		if id, err := primitive.ObjectIDFromHex(text); err == nil {
+			return id, nil
+		}
+	}
+	return value, nil
+}
+
+// Mount serves the Users, Groups, ServiceProviderConfig and ResourceTypes
+// endpoints under path, authenticated with SCIM tokens
+func Mount(router fiber.Router, path string, options Options) {
+	if options.DefaultRole == "" {
+		options.DefaultRole = authz.RoleViewer
+	}
+	s := &server{options: options}
+
+	group := router.Group(path, Auth)
+	group.Get("/ServiceProviderConfig", serviceProviderConfig)
+	group.Get("/ResourceTypes", resourceTypes)
+
+	group.Get("/Users", s.handle(s.listUsers))
+	group.Post("/Users", s.handle(s.createUser))
+	group.Get("/Users/:id", s.handle(s.getUser))
+	group.Put("/Users/:id", s.handle(s.replaceUser))
+	group.Patch("/Users/:id", s.handle(s.patchUser))
+	group.Delete("/Users/:id", s.handle(s.deleteUser))
+
+	group.Get("/Groups", s.handle(s.listGroups))
+	group.Post("/Groups", s.handle(s.createGroup))
+	group.Get("/Groups/:id", s.handle(s.getGroup))
+	group.Put("/Groups/:id", s.handle(s.replaceGroup))
+	group.Patch("/Groups/:id", s.handle(s.patchGroup))
+	group.Delete("/Groups/:id", s.handle(s.deleteGroup))
+}
+
+type server struct {
+	options Options
+}
+
+// handle renders the result of a SCIM handler: a resource with its status, or an error
+func (s *server) handle(fn func(c *fiber.Ctx, organizationID string) (int, interface{}, error)) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		organizationID, _ := c.Locals("organization_id").(string)
+		status, body, err := fn(c, organizationID)
+		if err != nil {
+			var scimErr *Error
+			if !errors.As(err, &scimErr) {
+				utils.LogError(fmt.Sprintf("SCIM %s %s failed: %v", c.Method(), c.Path(), err))
+				scimErr = &Error{Status: fiber.StatusInternalServerError, Detail: "Internal server error"}
+			}
+			return respond(c, scimErr.Status, scimErr)
+		}
+		if body == nil {
+			return c.SendStatus(status)
+		}
+		return respond(c, status, body)
+	}
+}
+
+func respond(c *fiber.Ctx, status int, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, ContentType)
+	return c.Status(status).Send(data)
+}
+
+// decodeBody parses a JSON request body
+func decodeBody(c *fiber.Ctx, v interface{}) error {
+	if err := json.Unmarshal(c.Body(), v); err != nil {
+		return badRequest("invalidSyntax", "invalid JSON body: %v", err)
+	}
+	return nil
+}
+
+// listParams reads startIndex (1-based), count and filter
+func listParams(c *fiber.Ctx, attributes map[string]Attribute) (bson.M, int, int, error) {
+	startIndex := max(c.QueryInt("startIndex", 1), 1)
+	count := min(max(c.QueryInt("count", 100), 0), MaxResults)
+
+	filter := bson.M{}
+	if expression := c.Query("filter"); expression != "" {
+		parsed, err := ParseFilter(expression)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		if filter, err = parsed.bson(attributes); err != nil {
+			return nil, 0, 0, err
+		}
+	}
+	return filter, startIndex, count, nil
+}
+
+// location returns the URL of a resource under the mount path
+func location(c *fiber.Ctx, resource, id string) string {
+	base := strings.TrimSuffix(c.BaseURL()+c.Path(), "/")
+	if i := strings.LastIndex(base, "/"+resource); i >= 0 {
+		base = base[:i]
+	}
+	return base + "/" + resource + "/" + id
+}
+
+func serviceProviderConfig(c *fiber.Ctx) error {
+	supported := func(value bool) fiber.Map { return fiber.Map{"supported": value} }
+	return respond(c, fiber.StatusOK, fiber.Map{
+		"schemas":        []string{SchemaSPConfig},
+		"patch":          supported(true),
+		"bulk":           fiber.Map{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         fiber.Map{"supported": true, "maxResults": MaxResults},
+		"changePassword": supported(false),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []fiber.Map{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer token",
+			"description": "Organization SCIM token",
+		}},
+	})
+}
+
+func resourceTypes(c *fiber.Ctx) error {
+	types := []interface{}{
+		fiber.Map{"schemas": []string{SchemaResourceType}, "id": "User", "name": "User", "endpoint": "/Users", "schema": SchemaUser},
+		fiber.Map{"schemas": []string{SchemaResourceType}, "id": "Group", "name": "Group", "endpoint": "/Groups", "schema": SchemaGroup},
+	}
+	return respond(c, fiber.StatusOK, ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: int64(len(types)),
+		StartIndex:   1,
+		ItemsPerPage: len(types),
+		Resources:    types,
+	})
+}
+
+// Auth authenticates an identity provider by its SCIM bearer token and sets
+// the organization_id local
+func Auth(c *fiber.Ctx) error {
+	token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	if !ok || token == "" {
+		return respond(c, fiber.StatusUnauthorized, &Error{Status: fiber.StatusUnauthorized, Detail: "Bearer token required"})
+	}
+
+	now := utils.Now()
+	var record models.SCIMToken
+	err := tokens().FindOneAndUpdate(c.UserContext(),
+		bson.M{"token_hash": hashToken(token)},
+		bson.M{"$set": bson.M{"last_used_at": now}},
+	).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		return respond(c, fiber.StatusUnauthorized, &Error{Status: fiber.StatusUnauthorized, Detail: "Invalid token"})
+	}
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to verify SCIM token: %v", err))
+		return respond(c, fiber.StatusInternalServerError, &Error{Status: fiber.StatusInternalServerError, Detail: "Internal server error"})
+	}
+
+	c.Locals("organization_id", record.OrganizationID)
+	c.Locals("scim_token_id", record.ID.Hex())
+	c.SetUserContext(utils.WithAuditOrganization(c.UserContext(), record.OrganizationID))
+	return c.Next()
+}
+
+func tokens() *mongo.Collection {
+	return config.GetCollection(models.SCIMToken{}.CollectionName())
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateToken issues a SCIM token for an organization. The token is only
+// returned here; the database keeps its hash.
+func CreateToken(ctx context.Context, organizationID, description, createdBy string) (string, *models.SCIMToken, error) {
+	if organizationID == "" {
+		return "", nil, errors.New("organization is required")
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	token := "scim_" + base64.RawURLEncoding.EncodeToString(secret)
+
+	record := &models.SCIMToken{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: organizationID,
+		Description:    description,
+		TokenHash:      hashToken(token),
+		CreatedBy:      createdBy,
+		CreatedAt:      utils.Now(),
+	}
+	if _, err := tokens().InsertOne(ctx, record); err != nil {
+		return "", nil, err
+	}
+	return token, record, nil
+}
+
+// ListTokens returns the SCIM tokens of an organization
+func ListTokens(ctx context.Context, organizationID string) ([]models.SCIMToken, error) {
+	cursor, err := tokens().Find(ctx, bson.M{"organization_id": organizationID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	list := []models.SCIMToken{}
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// RevokeToken deletes a SCIM token of an organization
+func RevokeToken(ctx context.Context, organizationID string, id primitive.ObjectID) error {
+	result, err := tokens().DeleteOne(ctx, bson.M{"_id": id, "organization_id": organizationID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrTokenNotFound
+	}
+	return nil
+}
@@ -0,0 +1,471 @@
+package scim
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// User statuses written for the SCIM active attribute
+const (
+	StatusActive   = "active"
+	StatusInactive = "deactivated"
+)
+
+// User is the SCIM User resource
+type User struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	UserName    string   `json:"userName"`
+	Name        *Name    `json:"name,omitempty"`
+	DisplayName string   `json:"displayName,omitempty"`
+	Emails      []Email  `json:"emails,omitempty"`
+	Active      *bool    `json:"active,omitempty"`
+	Groups      []Member `json:"groups,omitempty"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+// Name is the name of a User
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// Email is an address of a User
+type Email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// Member references a user from a Group, or a group from a User
+type Member struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// UserAttributes maps User attribute paths to fields of the users collection,
+// for filters
+var UserAttributes = map[string]Attribute{
+	"id":                {Field: "_id", CaseExact: true, ToField: objectIDValue},
+	"userName":          {Field: "email"},
+	"emails":            {Field: "email"},
+	"emails.value":      {Field: "email"},
+	"externalId":        {Field: "scim_external_id", CaseExact: true},
+	"displayName":       {Field: "name"},
+	"name.formatted":    {Field: "name"},
+	"name.givenName":    {Field: "given_name"},
+	"name.familyName":   {Field: "family_name"},
+	"active":            {Field: "status", ToField: activeStatus},
+	"meta.created":      {Field: "created_at", ToField: timeValue},
+	"meta.lastModified": {Field: "updated_at", ToField: timeValue},
+}
+
+// activeStatus converts an active filter value to a status
+func activeStatus(value interface{}) (interface{}, error) {
+	active, ok := parseBool(value)
+	if !ok {
+		return nil, fmt.Errorf("active must be true or false")
+	}
+	if active {
+		return StatusActive, nil
+	}
+	return StatusInactive, nil
+}
+
+func timeValue(value interface{}) (interface{}, error) {
+	text, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected a timestamp")
+	}
+	return time.Parse(time.RFC3339, text)
+}
+
+// parseBool accepts booleans and the "True"/"False" strings Azure AD sends
+func parseBool(value interface{}) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		parsed, err := strconv.ParseBool(v)
+		return parsed, err == nil
+	}
+	return false, false
+}
+
+func users() *mongo.Collection {
+	return config.GetCollection(utils.UsersDirectory.Collection)
+}
+
+// userFilter limits a filter to one organization
+func userFilter(organizationID string, filter bson.M) bson.M {
+	if len(filter) == 0 {
+		return bson.M{"organization_id": organizationID}
+	}
+	return bson.M{"$and": bson.A{bson.M{"organization_id": organizationID}, filter}}
+}
+
+// userIDFilter matches a user by the id used in SCIM URLs
+func userIDFilter(organizationID, id string) bson.M {
+	var value interface{} = id
+	if objectID, err := primitive.ObjectIDFromHex(id); err == nil {
+		value = objectID
+	}
+	return bson.M{"_id": value, "organization_id": organizationID}
+}
+
+// documentID renders a document _id for SCIM
+func documentID(value interface{}) string {
+	if id, ok := value.(primitive.ObjectID); ok {
+		return id.Hex()
+	}
+	return fmt.Sprint(value)
+}
+
+func stringField(document bson.M, field string) string {
+	value, _ := document[field].(string)
+	return value
+}
+
+func timeField(document bson.M, field string) string {
+	switch value := document[field].(type) {
+	case primitive.DateTime:
+		return utils.FormatTime(value.Time())
+	case time.Time:
+		return utils.FormatTime(value)
+	}
+	return ""
+}
+
+// userResource renders a user document as a SCIM User
+func (s *server) userResource(c *fiber.Ctx, document bson.M, groups []models.SCIMGroup) User {
+	id := documentID(document["_id"])
+	email := stringField(document, "email")
+	active := stringField(document, utils.UsersDirectory.StatusField) != StatusInactive
+
+	user := User{
+		Schemas:     []string{SchemaUser},
+		ID:          id,
+		ExternalID:  stringField(document, "scim_external_id"),
+		UserName:    email,
+		DisplayName: stringField(document, "name"),
+		Active:      &active,
+		Meta: &Meta{
+			ResourceType: "User",
+			Created:      timeField(document, "created_at"),
+			LastModified: timeField(document, "updated_at"),
+			Location:     location(c, "Users", id),
+		},
+	}
+	if email != "" {
+		user.Emails = []Email{{Value: email, Type: "work", Primary: true}}
+	}
+	name := Name{
+		Formatted:  stringField(document, "name"),
+		GivenName:  stringField(document, "given_name"),
+		FamilyName: stringField(document, "family_name"),
+	}
+	if name != (Name{}) {
+		user.Name = &name
+	}
+	for _, group := range groups {
+		user.Groups = append(user.Groups, Member{Value: group.ID.Hex(), Display: group.DisplayName})
+	}
+	return user
+}
+
+// userFields converts a SCIM User to the fields written to the user document
+func userFields(user User) (bson.M, error) {
+	// userName is the identity; the primary email stands in when it is not an address
+	email := user.UserName
+	if utils.ValidateEmailSyntax(strings.TrimSpace(email)) != nil && len(user.Emails) > 0 {
+		email = user.Emails[0].Value
+		for _, address := range user.Emails {
+			if address.Primary {
+				email = address.Value
+			}
+		}
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	if err := utils.ValidateEmailSyntax(email); err != nil {
+		return nil, badRequest("invalidValue", "userName must be an email address")
+	}
+
+	name := user.DisplayName
+	statusField := utils.UsersDirectory.StatusField
+	fields := bson.M{
+		"email":            email,
+		"scim_external_id": user.ExternalID,
+		statusField:        StatusActive,
+	}
+	if user.Name != nil {
+		if name == "" {
+			name = user.Name.Formatted
+		}
+		if name == "" {
+			name = strings.TrimSpace(user.Name.GivenName + " " + user.Name.FamilyName)
+		}
+		fields["given_name"] = user.Name.GivenName
+		fields["family_name"] = user.Name.FamilyName
+	}
+	fields["name"] = name
+	if user.Active != nil && !*user.Active {
+		fields[statusField] = StatusInactive
+	}
+	return fields, nil
+}
+
+// loadUser returns a user document of the organization
+func loadUser(c *fiber.Ctx, organizationID, id string) (bson.M, error) {
+	var document bson.M
+	err := users().FindOne(c.UserContext(), userIDFilter(organizationID, id), hiddenFields()).Decode(&document)
+	if err == mongo.ErrNoDocuments {
+		return nil, notFound("User", id)
+	}
+	return document, err
+}
+
+// managedUserFilter matches a user of the organization the identity provider
+// may change. Users whose role the provider does not manage, such as super
+// admins, are left alone, so a SCIM token cannot take them over.
+func managedUserFilter(organizationID, id string) bson.M {
+	filter := userIDFilter(organizationID, id)
+	filter[utils.UsersDirectory.RoleField] = bson.M{"$nin": unmanagedRoles()}
+	return filter
+}
+
+// unmatchedUser explains why managedUserFilter matched nothing: the user does
+// not exist, or is not managed over SCIM
+func unmatchedUser(c *fiber.Ctx, organizationID, id string) error {
+	if _, err := loadUser(c, organizationID, id); err != nil {
+		return err
+	}
+	return &Error{Status: fiber.StatusForbidden, Detail: fmt.Sprintf("User %s is not managed by the identity provider", id)}
+}
+
+func hiddenFields() *options.FindOneOptions {
+	projection := bson.M{}
+	for _, field := range utils.UsersDirectory.HiddenFields {
+		projection[field] = 0
+	}
+	return options.FindOne().SetProjection(projection)
+}
+
+func (s *server) renderUser(c *fiber.Ctx, organizationID string, document bson.M) (User, error) {
+	groups, err := groupsOfUser(c, organizationID, documentID(document["_id"]))
+	if err != nil {
+		return User{}, err
+	}
+	return s.userResource(c, document, groups), nil
+}
+
+func (s *server) listUsers(c *fiber.Ctx, organizationID string) (int, interface{}, error) {
+	filter, startIndex, count, err := listParams(c, UserAttributes)
+	if err != nil {
+		return 0, nil, err
+	}
+	filter = userFilter(organizationID, filter)
+
+	total, err := users().CountDocuments(c.UserContext(), filter)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	resources := []interface{}{}
+	if count > 0 {
+		findOptions := options.Find().
+			SetSort(bson.D{{Key: "_id", Value: 1}}).
+			SetSkip(int64(startIndex - 1)).
+			SetLimit(int64(count)).
+			SetProjection(hiddenFields().Projection)
+		cursor, err := users().Find(c.UserContext(), filter, findOptions)
+		if err != nil {
+			return 0, nil, err
+		}
+		var documents []bson.M
+		if err := cursor.All(c.UserContext(), &documents); err != nil {
+			return 0, nil, err
+		}
+		for _, document := range documents {
+			user, err := s.renderUser(c, organizationID, document)
+			if err != nil {
+				return 0, nil, err
+			}
+			resources = append(resources, user)
+		}
+	}
+
+	return fiber.StatusOK, ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}, nil
+}
+
+func (s *server) getUser(c *fiber.Ctx, organizationID string) (int, interface{}, error) {
+	document, err := loadUser(c, organizationID, c.Params("id"))
+	if err != nil {
+		return 0, nil, err
+	}
+	user, err := s.renderUser(c, organizationID, document)
+	return fiber.StatusOK, user, err
+}
+
+func (s *server) createUser(c *fiber.Ctx, organizationID string) (int, interface{}, error) {
+	var user User
+	if err := decodeBody(c, &user); err != nil {
+		return 0, nil, err
+	}
+	fields, err := userFields(user)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	existing, err := users().CountDocuments(c.UserContext(),
+		bson.M{"organization_id": organizationID, "email": fields["email"]})
+	if err != nil {
+		return 0, nil, err
+	}
+	if existing > 0 {
+		return 0, nil, &Error{Status: fiber.StatusConflict, Type: "uniqueness", Detail: "userName is already provisioned"}
+	}
+
+	now := utils.Now()
+	document := bson.M{
+		"_id":                          primitive.NewObjectID(),
+		"organization_id":              organizationID,
+		utils.UsersDirectory.RoleField: s.options.DefaultRole,
+		"created_at":                   now,
+		"updated_at":                   now,
+	}
+	for field, value := range fields {
+		document[field] = value
+	}
+	if _, err := users().InsertOne(c.UserContext(), document); err != nil {
+		return 0, nil, err
+	}
+
+	utils.LogAuditContext(c.UserContext(), "scim", "scim_user_provisioned", documentID(document["_id"]), map[string]interface{}{
+		"email": fields["email"],
+	})
+	return fiber.StatusCreated, s.userResource(c, document, nil), nil
+}
+
+// saveUser writes the fields of user to an existing document
+func (s *server) saveUser(c *fiber.Ctx, organizationID, id string, user User) (int, interface{}, error) {
+	fields, err := userFields(user)
+	if err != nil {
+		return 0, nil, err
+	}
+	fields["updated_at"] = utils.Now()
+
+	var document bson.M
+	err = users().FindOneAndUpdate(c.UserContext(), managedUserFilter(organizationID, id),
+		bson.M{"$set": fields},
+		options.FindOneAndUpdate().
+			SetReturnDocument(options.After).
+			SetProjection(hiddenFields().Projection),
+	).Decode(&document)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil, unmatchedUser(c, organizationID, id)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if status := fields[utils.UsersDirectory.StatusField]; status == StatusInactive {
+		utils.LogAuditContext(c.UserContext(), "scim", "scim_user_deactivated", id, nil)
+	}
+	rendered, err := s.renderUser(c, organizationID, document)
+	return fiber.StatusOK, rendered, err
+}
+
+func (s *server) replaceUser(c *fiber.Ctx, organizationID string) (int, interface{}, error) {
+	id := c.Params("id")
+	if _, err := loadUser(c, organizationID, id); err != nil {
+		return 0, nil, err
+	}
+	var user User
+	if err := decodeBody(c, &user); err != nil {
+		return 0, nil, err
+	}
+	return s.saveUser(c, organizationID, id, user)
+}
+
+func (s *server) patchUser(c *fiber.Ctx, organizationID string) (int, interface{}, error) {
+	id := c.Params("id")
+	var request PatchRequest
+	if err := decodeBody(c, &request); err != nil {
+		return 0, nil, err
+	}
+	document, err := loadUser(c, organizationID, id)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	patched, err := applyPatch(s.userResource(c, document, nil), request.Operations)
+	if err != nil {
+		return 0, nil, err
+	}
+	// Azure AD sends active as "False"
+	if key := documentKey(patched, "active"); patched[key] != nil {
+		if active, ok := parseBool(patched[key]); ok {
+			patched[key] = active
+		}
+	}
+
+	var user User
+	if err := remarshal(patched, &user); err != nil {
+		return 0, nil, badRequest("invalidValue", "invalid patched user: %v", err)
+	}
+	return s.saveUser(c, organizationID, id, user)
+}
+
+func (s *server) deleteUser(c *fiber.Ctx, organizationID string) (int, interface{}, error) {
+	id := c.Params("id")
+	filter := managedUserFilter(organizationID, id)
+
+	if s.options.SoftDelete {
+		result, err := users().UpdateOne(c.UserContext(), filter,
+			bson.M{"$set": bson.M{utils.UsersDirectory.StatusField: StatusInactive, "updated_at": utils.Now()}})
+		if err != nil {
+			return 0, nil, err
+		}
+		if result.MatchedCount == 0 {
+			return 0, nil, unmatchedUser(c, organizationID, id)
+		}
+	} else {
+		result, err := users().DeleteOne(c.UserContext(), filter)
+		if err != nil {
+			return 0, nil, err
+		}
+		if result.DeletedCount == 0 {
+			return 0, nil, unmatchedUser(c, organizationID, id)
+		}
+	}
+
+	if _, err := groups().UpdateMany(c.UserContext(),
+		bson.M{"organization_id": organizationID, "members": id},
+		bson.M{"$pull": bson.M{"members": id}},
+	); err != nil {
+		return 0, nil, err
+	}
+
+	utils.LogAuditContext(c.UserContext(), "scim", "scim_user_deprovisioned", id, nil)
+	return fiber.StatusNoContent, nil, nil
+}
@@ -0,0 +1,112 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SSEHeartbeat is the interval of keep-alive comments on idle event streams,
+// which also detect clients that went away
+var SSEHeartbeat = 15 * time.Second
+
+// SSEWriter sends server-sent events; it is safe for concurrent use
+type SSEWriter struct {
+	w      *bufio.Writer
+	cancel context.CancelFunc
+	mu     sync.Mutex
+}
+
+// Event sends data encoded as JSON. id is echoed back by browsers in the
+// Last-Event-ID header when they reconnect; event and id may be empty.
+func (s *SSEWriter) Event(event, id string, data interface{}) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	var message strings.Builder
+	if event != "" {
+		fmt.Fprintf(&message, "event: %s\n", event)
+	}
+	if id != "" {
+		fmt.Fprintf(&message, "id: %s\n", id)
+	}
+	fmt.Fprintf(&message, "data: %s\n\n", encoded)
+	return s.send(message.String())
+}
+
+// Comment sends a comment line, which clients ignore
+func (s *SSEWriter) Comment(text string) error {
+	return s.send(": " + text + "\n\n")
+}
+
+func (s *SSEWriter) send(message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.w.WriteString(message); err != nil {
+		s.cancel()
+		return err
+	}
+	if err := s.w.Flush(); err != nil {
+		s.cancel()
+		return err
+	}
+	return nil
+}
+
+// SSE sends a text/event-stream response written by produce, uncompressed so
+// each event reaches the client immediately. Heartbeat comments are sent every
+// SSEHeartbeat; once the client disconnects the context passed to produce is
+// cancelled.
+func SSE(c *fiber.Ctx, produce func(ctx context.Context, w *SSEWriter) error) error {
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	// Stop nginx and similar proxies from buffering the stream
+	c.Set("X-Accel-Buffering", "no")
+
+	// The stream writer runs after the handler returns, so detach from request
+	// deadlines (such as middleware.Timeout) that end with the handler
+	parent := context.WithoutCancel(c.UserContext())
+	path := c.Path()
+
+	c.Context().SetBodyStreamWriter(func(buffered *bufio.Writer) {
+		ctx, cancel := context.WithCancel(parent)
+		defer cancel()
+		writer := &SSEWriter{w: buffered, cancel: cancel}
+
+		// The buffer belongs to the connection only until this function returns
+		var heartbeats sync.WaitGroup
+		defer heartbeats.Wait()
+		heartbeats.Add(1)
+		go func() {
+			defer heartbeats.Done()
+			ticker := time.NewTicker(SSEHeartbeat)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					writer.Comment("heartbeat")
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		if err := produce(ctx, writer); err != nil && ctx.Err() == nil {
+			log.Printf("❌ Event stream %s failed: %v", path, err)
+		}
+		cancel()
+	})
+
+	return nil
+}
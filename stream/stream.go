@@ -0,0 +1,135 @@
+// Package stream writes large JSON and CSV responses incrementally with
+// gzip/brotli compression, so exports never hold the full result in memory.
+//
+//	return stream.JSON(c, "audit-logs.json", func(ctx context.Context, w *stream.JSONWriter) error {
+//		return utils.StreamAuditLogs(ctx, filter, func(entry models.AuditLog) error {
+//			return w.Write(entry)
+//		})
+//	})
+package stream
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gofiber/fiber/v2"
+)
+
+// FlushEvery is the number of records written between flushes to the client
+var FlushEvery = 100
+
+// Producer writes the response body; it should stop when ctx is cancelled
+type Producer func(ctx context.Context, w io.Writer, flush func() error) error
+
+// compressor is implemented by gzip.Writer and brotli.Writer
+type compressor interface {
+	io.Writer
+	Flush() error
+	Close() error
+}
+
+// Stream sends the output of produce as an attachment named filename. The
+// encoding is negotiated from Accept-Encoding (brotli, then gzip). Every flush
+// pushes buffered data to the connection and blocks while the client is slow
+// to read, which bounds memory; once the client disconnects, writes fail and
+// the context passed to produce is cancelled.
+func Stream(c *fiber.Ctx, filename, contentType string, produce Producer) error {
+	encoding := negotiateEncoding(c.Get(fiber.HeaderAcceptEncoding))
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Set(fiber.HeaderVary, fiber.HeaderAcceptEncoding)
+	if encoding != "" {
+		c.Set(fiber.HeaderContentEncoding, encoding)
+	}
+
+	// The stream writer runs after the handler returns, so detach from request
+	// deadlines (such as middleware.Timeout) that end with the handler
+	parent := context.WithoutCancel(c.UserContext())
+	path := c.Path()
+
+	c.Context().SetBodyStreamWriter(func(buffered *bufio.Writer) {
+		ctx, cancel := context.WithCancel(parent)
+		defer cancel()
+
+		var out io.Writer = buffered
+		var comp compressor
+		switch encoding {
+		case "br":
+			comp = brotli.NewWriterLevel(buffered, brotli.DefaultCompression)
+			out = comp
+		case "gzip":
+			comp = gzip.NewWriter(buffered)
+			out = comp
+		}
+
+		// A failed write means the client went away; stop the producer
+		sink := &cancelOnError{w: out, cancel: cancel}
+		flush := func() error {
+			if comp != nil {
+				if err := comp.Flush(); err != nil {
+					cancel()
+					return err
+				}
+			}
+			if err := buffered.Flush(); err != nil {
+				cancel()
+				return err
+			}
+			return ctx.Err()
+		}
+
+		err := produce(ctx, sink, flush)
+		if comp != nil {
+			comp.Close()
+		}
+		buffered.Flush()
+
+		if err != nil && ctx.Err() == nil {
+			log.Printf("❌ Streaming %s failed: %v", path, err)
+		}
+	})
+
+	return nil
+}
+
+// negotiateEncoding picks brotli or gzip from an Accept-Encoding header
+func negotiateEncoding(acceptEncoding string) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.ReplaceAll(params, " ", "") == "q=0" {
+			continue
+		}
+		accepted[strings.ToLower(name)] = true
+	}
+
+	switch {
+	case accepted["br"]:
+		return "br"
+	case accepted["gzip"]:
+		return "gzip"
+	default:
+		return ""
+	}
+}
+
+// cancelOnError cancels the stream context on the first failed write
+type cancelOnError struct {
+	w      io.Writer
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnError) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	if err != nil {
+		c.cancel()
+	}
+	return n, err
+}
@@ -0,0 +1,139 @@
+package stream
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// JSONWriter writes values as the elements of a single JSON array
+type JSONWriter struct {
+	w      io.Writer
+	flush  func() error
+	count  int
+	closed bool
+}
+
+// NewJSONWriter starts a JSON array on w; flush may be nil
+func NewJSONWriter(w io.Writer, flush func() error) (*JSONWriter, error) {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return nil, err
+	}
+	return &JSONWriter{w: w, flush: flush}, nil
+}
+
+// Write appends one element, flushing every FlushEvery elements
+func (j *JSONWriter) Write(value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if j.count > 0 {
+		if _, err := io.WriteString(j.w, ","); err != nil {
+			return err
+		}
+	}
+	if _, err := j.w.Write(data); err != nil {
+		return err
+	}
+
+	j.count++
+	if j.flush != nil && j.count%FlushEvery == 0 {
+		return j.flush()
+	}
+	return nil
+}
+
+// Count returns the number of elements written
+func (j *JSONWriter) Count() int {
+	return j.count
+}
+
+// Close ends the JSON array
+func (j *JSONWriter) Close() error {
+	if j.closed {
+		return nil
+	}
+	j.closed = true
+	_, err := io.WriteString(j.w, "]")
+	return err
+}
+
+// CSVWriter writes rows as CSV after an optional header
+type CSVWriter struct {
+	writer *csv.Writer
+	flush  func() error
+	count  int
+}
+
+// NewCSVWriter writes header (when not empty) to w; flush may be nil
+func NewCSVWriter(w io.Writer, flush func() error, header []string) (*CSVWriter, error) {
+	writer := csv.NewWriter(w)
+	if len(header) > 0 {
+		if err := writer.Write(header); err != nil {
+			return nil, err
+		}
+	}
+	return &CSVWriter{writer: writer, flush: flush}, nil
+}
+
+// Write appends one row, flushing every FlushEvery rows
+func (c *CSVWriter) Write(row []string) error {
+	if err := c.writer.Write(row); err != nil {
+		return err
+	}
+
+	c.count++
+	if c.count%FlushEvery == 0 {
+		c.writer.Flush()
+		if err := c.writer.Error(); err != nil {
+			return err
+		}
+		if c.flush != nil {
+			return c.flush()
+		}
+	}
+	return nil
+}
+
+// Count returns the number of rows written, excluding the header
+func (c *CSVWriter) Count() int {
+	return c.count
+}
+
+// Close flushes buffered rows
+func (c *CSVWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// JSON streams a JSON array attachment produced by fn
+func JSON(c *fiber.Ctx, filename string, fn func(ctx context.Context, w *JSONWriter) error) error {
+	return Stream(c, filename, fiber.MIMEApplicationJSONCharsetUTF8, func(ctx context.Context, w io.Writer, flush func() error) error {
+		writer, err := NewJSONWriter(w, flush)
+		if err != nil {
+			return err
+		}
+		if err := fn(ctx, writer); err != nil {
+			return err
+		}
+		return writer.Close()
+	})
+}
+
+// CSV streams a CSV attachment with header, produced by fn
+func CSV(c *fiber.Ctx, filename string, header []string, fn func(ctx context.Context, w *CSVWriter) error) error {
+	return Stream(c, filename, "text/csv; charset=utf-8", func(ctx context.Context, w io.Writer, flush func() error) error {
+		writer, err := NewCSVWriter(w, flush, header)
+		if err != nil {
+			return err
+		}
+		if err := fn(ctx, writer); err != nil {
+			return err
+		}
+		return writer.Close()
+	})
+}
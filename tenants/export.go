@@ -0,0 +1,139 @@
+package tenants
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Export writes every registered collection's documents of an organization
+// to w as a gzipped tar archive: a manifest.json followed by one file per
+// collection of canonical Extended JSON lines, which keep BSON types intact.
+// Collections are staged in temporary files, so memory stays bounded.
+func Export(ctx context.Context, organizationID string, w io.Writer) (*Manifest, error) {
+	manifest := &Manifest{
+		Version:        ArchiveVersion,
+		OrganizationID: organizationID,
+		ExportedAt:     utils.Now(),
+	}
+
+	var staged []*os.File
+	defer func() {
+		for _, file := range staged {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+
+	for _, collection := range Registered() {
+		file, err := os.CreateTemp("", "tenant-export-*.jsonl")
+		if err != nil {
+			return nil, err
+		}
+		staged = append(staged, file)
+
+		count, err := exportCollection(ctx, collection, organizationID, file)
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %w", collection.Name, err)
+		}
+		manifest.Collections = append(manifest.Collections, CollectionInfo{
+			Name:      collection.Name,
+			File:      collection.Name + ".jsonl",
+			Documents: count,
+		})
+	}
+
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeEntry(archive, manifestName, int64(len(manifestJSON)), manifest.ExportedAt, bytes.NewReader(manifestJSON)); err != nil {
+		return nil, err
+	}
+	for i, file := range staged {
+		info, err := file.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if err := writeEntry(archive, manifest.Collections[i].File, info.Size(), manifest.ExportedAt, file); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// exportCollection writes the documents of one collection as Extended JSON lines
+func exportCollection(ctx context.Context, collection Collection, organizationID string, w io.Writer) (int, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	if len(collection.Omit) > 0 {
+		projection := bson.M{}
+		for _, field := range collection.Omit {
+			projection[field] = 0
+		}
+		findOptions.SetProjection(projection)
+	}
+
+	cursor, err := config.GetCollection(collection.Name).Find(ctx,
+		bson.M{collection.OrganizationField: organizationID}, findOptions)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	buffered := bufio.NewWriter(w)
+	count := 0
+	for cursor.Next(ctx) {
+		line, err := bson.MarshalExtJSON(cursor.Current, true, false)
+		if err != nil {
+			return count, err
+		}
+		buffered.Write(line)
+		if err := buffered.WriteByte('\n'); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := cursor.Err(); err != nil {
+		return count, err
+	}
+	return count, buffered.Flush()
+}
+
+func writeEntry(archive *tar.Writer, name string, size int64, modTime time.Time, content io.Reader) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    size,
+		ModTime: modTime,
+	}
+	if err := archive.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := io.Copy(archive, content)
+	return err
+}
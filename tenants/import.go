@@ -0,0 +1,500 @@
+package tenants
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ImportBatchSize is the number of documents inserted per request
+const ImportBatchSize = 500
+
+// maxLineSize bounds one Extended JSON document; BSON documents are at most
+// 16MB and their JSON is larger
+const maxLineSize = 64 << 20
+
+// ImportOptions configures Import
+type ImportOptions struct {
+	OrganizationID string   // Organization receiving the documents
+	DryRun         bool     // Read and remap everything, write nothing
+	Collections    []string // Limits the import; default every registered collection in the archive
+	// Progress is called after each batch with the documents processed so far
+	// and the total in the archive
+	Progress func(done, total int)
+}
+
+// ImportReport is the outcome of an import
+type ImportReport struct {
+	SourceOrganizationID string             `json:"source_organization_id"`
+	OrganizationID       string             `json:"organization_id"`
+	DryRun               bool               `json:"dry_run"`
+	Collections          []CollectionReport `json:"collections"`
+	// Skipped are archive collections that are not registered here
+	Skipped []string `json:"skipped,omitempty"`
+	// UnresolvedReferences counts IDs of documents outside the archive, such
+	// as users of other organizations; they are kept unchanged
+	UnresolvedReferences int `json:"unresolved_references"`
+	// Unresolved lists the archive IDs, by collection, of documents that
+	// conflicted with a document of another organization; documents
+	// referencing them were skipped
+	Unresolved map[string][]string `json:"unresolved,omitempty"`
+}
+
+// CollectionReport is the outcome for one collection
+type CollectionReport struct {
+	Name      string `json:"name"`
+	Documents int    `json:"documents"`
+	Inserted  int    `json:"inserted"`
+	Conflicts int    `json:"conflicts"` // Rejected by a unique index, e.g. a short link token in use
+	// Resolved are conflicts with a document already in the organization;
+	// references to them point to that document
+	Resolved int `json:"resolved"`
+	// Skipped are documents not inserted because they reference a
+	// conflicting document that could not be resolved
+	Skipped int `json:"skipped"`
+}
+
+// idMap maps old document IDs, by collection, to their new IDs
+type idMap map[string]map[string]interface{}
+
+// unresolvedID marks documents that were not imported and have no
+// counterpart in the organization
+type unresolvedID struct{}
+
+// Import reads an archive written by Export and inserts its documents into
+// options.OrganizationID. Every ObjectID and string _id is replaced, and
+// registered references are rewritten to the new IDs. A document rejected by
+// a unique index is matched to the document it conflicts with when that one
+// belongs to the organization; otherwise documents referencing it are
+// skipped and reported. The archive is read once to assign IDs and then once
+// per level of references, so referenced collections are inserted first; it
+// must be seekable.
+func Import(ctx context.Context, archive io.ReadSeeker, options ImportOptions) (*ImportReport, error) {
+	if options.OrganizationID == "" {
+		return nil, errors.New("target organization is required")
+	}
+
+	// First pass: the manifest and the IDs of every imported document
+	var manifest *Manifest
+	ids := idMap{}
+	err := readArchive(archive, func(name string, body io.Reader) error {
+		if name == manifestName {
+			manifest = &Manifest{}
+			if err := json.NewDecoder(body).Decode(manifest); err != nil {
+				return fmt.Errorf("%w: manifest: %v", ErrInvalidArchive, err)
+			}
+			if manifest.Version != ArchiveVersion {
+				return fmt.Errorf("%w: unsupported version %d", ErrInvalidArchive, manifest.Version)
+			}
+			return nil
+		}
+		collection, ok := importedCollection(manifest, name, options)
+		if !ok {
+			return nil
+		}
+		ids[collection.Name] = map[string]interface{}{}
+		return readDocuments(body, func(document bson.D) error {
+			if key, newID, ok := newDocumentID(documentField(document, "_id")); ok {
+				ids[collection.Name][key] = newID
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidArchive, manifestName)
+	}
+
+	report := &ImportReport{
+		SourceOrganizationID: manifest.OrganizationID,
+		OrganizationID:       options.OrganizationID,
+		DryRun:               options.DryRun,
+	}
+	total := 0
+	for _, info := range manifest.Collections {
+		if _, ok := ids[info.Name]; ok {
+			total += info.Documents
+		} else if _, isRegistered := registered(info.Name); !isRegistered {
+			report.Skipped = append(report.Skipped, info.Name)
+		}
+	}
+
+	// Then rewrite and insert, one pass per level of references
+	done := 0
+	for _, level := range importLevels(ids) {
+		if _, err := archive.Seek(0, io.SeekStart); err != nil {
+			return report, err
+		}
+		err = readArchive(archive, func(name string, body io.Reader) error {
+			collection, ok := importedCollection(manifest, name, options)
+			if !ok || !slices.Contains(level, collection.Name) {
+				return nil
+			}
+			collectionReport := CollectionReport{Name: collection.Name}
+			batch := make([]interface{}, 0, ImportBatchSize)
+			oldIDs := make([]interface{}, 0, ImportBatchSize)
+			flush := func() error {
+				if len(batch) == 0 {
+					return nil
+				}
+				inserted, conflicts, err := insertBatch(ctx, collection.Name, batch, options.DryRun)
+				if err != nil {
+					return fmt.Errorf("import %s: %w", collection.Name, err)
+				}
+				collectionReport.Inserted += inserted
+				collectionReport.Conflicts += len(conflicts)
+				for index, keyValue := range conflicts {
+					resolved, err := resolveConflict(ctx, collection, keyValue, oldIDs[index], ids, options.OrganizationID)
+					if err != nil {
+						return fmt.Errorf("import %s: %w", collection.Name, err)
+					}
+					if resolved {
+						collectionReport.Resolved++
+					} else {
+						report.addUnresolved(collection.Name, oldIDs[index])
+					}
+				}
+				done += len(batch)
+				batch, oldIDs = batch[:0], oldIDs[:0]
+				if options.Progress != nil {
+					options.Progress(done, total)
+				}
+				return nil
+			}
+
+			err := readDocuments(body, func(document bson.D) error {
+				collectionReport.Documents++
+				oldID := documentField(document, "_id")
+				unresolved, skip := remap(document, collection, ids, options.OrganizationID)
+				if skip {
+					// Its own dependants must be skipped as well
+					if key, ok := idKey(oldID); ok {
+						ids[collection.Name][key] = unresolvedID{}
+					}
+					collectionReport.Skipped++
+					done++
+					return nil
+				}
+				report.UnresolvedReferences += unresolved
+				batch = append(batch, document)
+				oldIDs = append(oldIDs, oldID)
+				if len(batch) == ImportBatchSize {
+					return flush()
+				}
+				return nil
+			})
+			if err == nil {
+				err = flush()
+			}
+			report.Collections = append(report.Collections, collectionReport)
+			return err
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// importLevels orders the imported collections so that every collection
+// comes after the collections it references. Collections referencing each
+// other share the last level.
+func importLevels(ids idMap) [][]string {
+	remaining := make([]string, 0, len(ids))
+	for name := range ids {
+		remaining = append(remaining, name)
+	}
+	sort.Strings(remaining)
+
+	placed := map[string]bool{}
+	var levels [][]string
+	for len(remaining) > 0 {
+		var level, rest []string
+		for _, name := range remaining {
+			collection, _ := registered(name)
+			ready := true
+			for _, reference := range collection.References {
+				_, imported := ids[reference.Collection]
+				if imported && reference.Collection != name && !placed[reference.Collection] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				level = append(level, name)
+			} else {
+				rest = append(rest, name)
+			}
+		}
+		if len(level) == 0 {
+			level, rest = rest, nil
+		}
+		for _, name := range level {
+			placed[name] = true
+		}
+		levels = append(levels, level)
+		remaining = rest
+	}
+	return levels
+}
+
+// resolveConflict maps a document rejected by a unique index to the document
+// it conflicts with, when that one belongs to organizationID. Otherwise the
+// document is marked unresolved so its dependants are skipped.
+func resolveConflict(ctx context.Context, collection Collection, keyValue bson.Raw, oldID interface{}, ids idMap, organizationID string) (bool, error) {
+	key, ok := idKey(oldID)
+	if !ok {
+		return false, nil
+	}
+	ids[collection.Name][key] = unresolvedID{}
+	if len(keyValue) == 0 {
+		return false, nil
+	}
+
+	var existing bson.M
+	err := config.GetCollection(collection.Name).FindOne(ctx, keyValue,
+		options.FindOne().SetProjection(bson.M{"_id": 1, collection.OrganizationField: 1}),
+	).Decode(&existing)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if existing[collection.OrganizationField] != organizationID {
+		return false, nil
+	}
+	ids[collection.Name][key] = existing["_id"]
+	return true, nil
+}
+
+func (r *ImportReport) addUnresolved(collection string, oldID interface{}) {
+	key, ok := idKey(oldID)
+	if !ok {
+		return
+	}
+	if r.Unresolved == nil {
+		r.Unresolved = map[string][]string{}
+	}
+	r.Unresolved[collection] = append(r.Unresolved[collection], key)
+}
+
+// importedCollection returns the registered collection an archive file
+// holds, when it is part of this import
+func importedCollection(manifest *Manifest, file string, options ImportOptions) (Collection, bool) {
+	if manifest == nil {
+		return Collection{}, false
+	}
+	for _, info := range manifest.Collections {
+		if info.File != file {
+			continue
+		}
+		if len(options.Collections) > 0 && !slices.Contains(options.Collections, info.Name) {
+			return Collection{}, false
+		}
+		return registered(info.Name)
+	}
+	return Collection{}, false
+}
+
+// readArchive calls fn with each file of a gzipped tar archive
+func readArchive(r io.Reader, fn func(name string, body io.Reader) error) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	defer gz.Close()
+
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := fn(header.Name, archive); err != nil {
+			return err
+		}
+	}
+}
+
+// readDocuments calls fn with each Extended JSON line of body
+func readDocuments(body io.Reader, fn func(document bson.D) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var document bson.D
+		if err := bson.UnmarshalExtJSON(scanner.Bytes(), true, &document); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		if err := fn(document); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// documentField returns a top-level value of document
+func documentField(document bson.D, key string) interface{} {
+	for _, element := range document {
+		if element.Key == key {
+			return element.Value
+		}
+	}
+	return nil
+}
+
+// newDocumentID assigns a new ID of the same kind as id; other kinds of IDs,
+// such as numbers, are kept
+func newDocumentID(id interface{}) (string, interface{}, bool) {
+	switch value := id.(type) {
+	case primitive.ObjectID:
+		return value.Hex(), primitive.NewObjectID(), true
+	case string:
+		return value, utils.NewID(), true
+	}
+	return "", nil, false
+}
+
+// remap rewrites the _id, organization and references of a document and
+// returns the number of references that point outside the archive. skip is
+// set when the document references an archive document that was not imported.
+func remap(document bson.D, collection Collection, ids idMap, organizationID string) (unresolved int, skip bool) {
+	for i := range document {
+		switch document[i].Key {
+		case "_id":
+			if newID, ok := lookupID(ids[collection.Name], document[i].Value); ok {
+				document[i].Value = newID
+			}
+		case collection.OrganizationField:
+			document[i].Value = organizationID
+		}
+	}
+
+	for _, reference := range collection.References {
+		rewriteField(document, strings.Split(reference.Field, "."), func(value interface{}) interface{} {
+			newID, ok := lookupID(ids[reference.Collection], value)
+			if !ok {
+				if value != "" && value != nil {
+					unresolved++
+				}
+				return value
+			}
+			if _, missing := newID.(unresolvedID); missing {
+				skip = true
+				return value
+			}
+			// Keep the representation: a hex string stays a string
+			if objectID, isObjectID := newID.(primitive.ObjectID); isObjectID {
+				if _, isString := value.(string); isString {
+					return objectID.Hex()
+				}
+			}
+			return newID
+		})
+	}
+	return unresolved, skip
+}
+
+// lookupID finds the new ID of an ObjectID or string reference
+func lookupID(ids map[string]interface{}, value interface{}) (interface{}, bool) {
+	key, ok := idKey(value)
+	if !ok {
+		return nil, false
+	}
+	newID, ok := ids[key]
+	return newID, ok
+}
+
+// idKey returns the idMap key of an ObjectID or string ID
+func idKey(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case primitive.ObjectID:
+		return v.Hex(), true
+	case string:
+		return v, true
+	}
+	return "", false
+}
+
+// rewriteField applies fn to the values at path, descending into embedded
+// documents and arrays
+func rewriteField(document bson.D, path []string, fn func(interface{}) interface{}) {
+	for i := range document {
+		if document[i].Key != path[0] {
+			continue
+		}
+		document[i].Value = rewriteValue(document[i].Value, path[1:], fn)
+	}
+}
+
+func rewriteValue(value interface{}, path []string, fn func(interface{}) interface{}) interface{} {
+	switch v := value.(type) {
+	case bson.A:
+		for i := range v {
+			v[i] = rewriteValue(v[i], path, fn)
+		}
+		return v
+	case bson.D:
+		if len(path) > 0 {
+			rewriteField(v, path, fn)
+		}
+		return v
+	}
+	if len(path) > 0 {
+		return value
+	}
+	return fn(value)
+}
+
+// insertBatch inserts documents and returns the duplicates, by their index
+// in documents, with the key each one conflicted on
+func insertBatch(ctx context.Context, collection string, documents []interface{}, dryRun bool) (int, map[int]bson.Raw, error) {
+	if dryRun {
+		return 0, nil, nil
+	}
+	result, err := config.GetCollection(collection).InsertMany(ctx, documents, options.InsertMany().SetOrdered(false))
+	inserted := 0
+	if result != nil {
+		inserted = len(result.InsertedIDs)
+	}
+
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+		conflicts := map[int]bson.Raw{}
+		for _, writeErr := range bulkErr.WriteErrors {
+			if !mongo.IsDuplicateKeyError(writeErr) {
+				return inserted, conflicts, err
+			}
+			keyValue, _ := writeErr.Raw.Lookup("keyValue").DocumentOK()
+			conflicts[writeErr.Index] = keyValue
+		}
+		return len(documents) - len(conflicts), conflicts, nil
+	}
+	return inserted, nil, err
+}
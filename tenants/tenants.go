@@ -0,0 +1,131 @@
+// Package tenants exports all data of an organization to a portable archive
+// and imports it into another environment or organization, for enterprise
+// migrations and sandbox-to-production promotion. Only registered
+// collections take part; services register their own next to the shared ones:
+//
+//	tenants.Register(tenants.Collection{
+//		Name:       "experiences",
+//		References: []tenants.Reference{{Field: "created_by", Collection: "users"}},
+//	})
+//
+//	manifest, err := tenants.Export(ctx, organizationID, archive)
+//	report, err := tenants.Import(ctx, archive, tenants.ImportOptions{OrganizationID: targetID})
+//
+// Imported documents get new IDs, and references between them are rewritten
+// to match.
+package tenants
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/utils"
+)
+
+// ArchiveVersion is the format version written to the manifest
+const ArchiveVersion = 1
+
+// manifestName is the archive entry holding the Manifest
+const manifestName = "manifest.json"
+
+// ErrInvalidArchive is returned for archives that cannot be read
+var ErrInvalidArchive = errors.New("invalid tenant archive")
+
+// Collection describes an organization-scoped collection
+type Collection struct {
+	Name              string
+	OrganizationField string      // Default "organization_id"
+	References        []Reference // Fields holding IDs of other exported documents
+	Omit              []string    // Fields never exported, e.g. tokens bound to one environment
+}
+
+// Reference is a field, possibly nested ("owner.id") or an array, holding the
+// IDs of documents in a collection; the IDs may be ObjectIDs or their hex
+type Reference struct {
+	Field      string
+	Collection string
+}
+
+// Manifest describes an archive
+type Manifest struct {
+	Version        int              `json:"version"`
+	OrganizationID string           `json:"organization_id"`
+	ExportedAt     time.Time        `json:"exported_at"`
+	Collections    []CollectionInfo `json:"collections"`
+}
+
+// CollectionInfo is the entry of one collection in a Manifest
+type CollectionInfo struct {
+	Name      string `json:"name"`
+	File      string `json:"file"`
+	Documents int    `json:"documents"`
+}
+
+var (
+	registry   = map[string]Collection{}
+	registryMu sync.RWMutex
+)
+
+// Register adds or replaces a collection taking part in exports and imports
+func Register(collection Collection) {
+	if collection.OrganizationField == "" {
+		collection.OrganizationField = "organization_id"
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[collection.Name] = collection
+}
+
+// Registered returns the registered collections, sorted by name
+func Registered() []Collection {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	collections := make([]Collection, 0, len(registry))
+	for _, collection := range registry {
+		collections = append(collections, collection)
+	}
+	sort.Slice(collections, func(i, j int) bool { return collections[i].Name < collections[j].Name })
+	return collections
+}
+
+func registered(name string) (Collection, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	collection, ok := registry[name]
+	return collection, ok
+}
+
+// The shared collections that belong to an organization. Audit logs, billing,
+// SSO and provisioning credentials and custom domains are bound to their
+// environment and stay behind.
+func init() {
+	users := utils.UsersDirectory.Collection
+	byUser := func(fields ...string) []Reference {
+		references := make([]Reference, 0, len(fields))
+		for _, field := range fields {
+			references = append(references, Reference{Field: field, Collection: users})
+		}
+		return references
+	}
+
+	Register(Collection{Name: users, Omit: []string{"refresh_token", "reset_token"}})
+	Register(Collection{Name: utils.MembershipsCollection, References: byUser("user_id")})
+	Register(Collection{Name: models.SCIMGroup{}.CollectionName(), References: byUser("members")})
+	Register(Collection{Name: models.Device{}.CollectionName(), References: byUser("user_id"),
+		Omit: []string{"push_token", "session_ids"}})
+	Register(Collection{Name: models.ActivityItem{}.CollectionName(), References: byUser("actor_id", "read_by")})
+	Register(Collection{Name: models.ShortLink{}.CollectionName(), References: byUser("created_by")})
+	Register(Collection{Name: models.Code{}.CollectionName(), References: byUser("created_by")})
+	Register(Collection{Name: models.CodeRedemption{}.CollectionName(), References: append(byUser("user_id"),
+		Reference{Field: "code_id", Collection: models.Code{}.CollectionName()})})
+	Register(Collection{Name: models.Branding{}.CollectionName(), References: byUser("updated_by")})
+	Register(Collection{Name: models.TranscodeJob{}.CollectionName(), References: byUser("created_by"),
+		Omit: []string{"external_id"}})
+	Register(Collection{Name: models.ModerationItem{}.CollectionName(), References: byUser("created_by", "reviewed_by")})
+	Register(Collection{Name: models.FileScan{}.CollectionName(), References: byUser("created_by")})
+}
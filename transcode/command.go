@@ -0,0 +1,93 @@
+package transcode
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/models"
+)
+
+// DefaultPresets are the command lines of the built-in presets. {input} and
+// {output} are replaced with the job's paths; both tools read and write
+// local files and HTTP URLs.
+var DefaultPresets = map[string][]string{
+	"video-720p": {
+		"ffmpeg", "-y", "-i", "{input}", "-vf", "scale=-2:720",
+		"-c:v", "libx264", "-preset", "medium", "-crf", "23",
+		"-c:a", "aac", "-b:a", "128k", "-movflags", "+faststart", "{output}",
+	},
+	"video-1080p": {
+		"ffmpeg", "-y", "-i", "{input}", "-vf", "scale=-2:1080",
+		"-c:v", "libx264", "-preset", "medium", "-crf", "22",
+		"-c:a", "aac", "-b:a", "160k", "-movflags", "+faststart", "{output}",
+	},
+	"video-poster": {
+		"ffmpeg", "-y", "-i", "{input}", "-frames:v", "1", "-q:v", "2", "{output}",
+	},
+	// glTF Transform (npm @gltf-transform/cli) for GLB optimization
+	"model-optimize": {
+		"gltf-transform", "optimize", "{input}", "{output}", "--texture-compress", "webp",
+	},
+	"model-draco": {
+		"gltf-transform", "draco", "{input}", "{output}",
+	},
+}
+
+// CommandAdapter runs each job as a local command, for ffmpeg workers. Jobs
+// finish inside Submit, so they are never polled.
+type CommandAdapter struct {
+	Presets map[string][]string
+	Timeout time.Duration // Per job; default 30 minutes
+}
+
+// NewCommandAdapter returns an adapter with a copy of DefaultPresets
+func NewCommandAdapter() *CommandAdapter {
+	presets := make(map[string][]string, len(DefaultPresets))
+	for name, args := range DefaultPresets {
+		presets[name] = append([]string(nil), args...)
+	}
+	return &CommandAdapter{Presets: presets, Timeout: 30 * time.Minute}
+}
+
+// Submit runs the preset's command and waits for it
+func (a *CommandAdapter) Submit(ctx context.Context, job *models.TranscodeJob) (Status, error) {
+	template, ok := a.Presets[job.Preset]
+	if !ok {
+		return Status{}, fmt.Errorf("unknown preset %q", job.Preset)
+	}
+
+	args := make([]string, len(template))
+	for i, arg := range template {
+		args[i] = strings.NewReplacer("{input}", job.Input, "{output}", job.Output).Replace(arg)
+	}
+
+	timeout := a.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+	if err != nil {
+		return Status{Done: true, Error: fmt.Sprintf("%s: %v: %s", args[0], err, tail(output, 500))}, nil
+	}
+	return Status{Done: true, Percent: 100}, nil
+}
+
+// Poll is never needed, as Submit finishes every job
+func (a *CommandAdapter) Poll(ctx context.Context, job *models.TranscodeJob) (Status, error) {
+	return Status{Done: true, Error: "command jobs cannot be polled"}, nil
+}
+
+// tail returns the end of a command's output, where tools print the cause of a failure
+func tail(output []byte, limit int) string {
+	text := strings.TrimSpace(string(output))
+	if len(text) > limit {
+		text = "…" + text[len(text)-limit:]
+	}
+	return text
+}
@@ -0,0 +1,160 @@
+package transcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"google.golang.org/api/option"
+	transcoder "google.golang.org/api/transcoder/v1"
+)
+
+// GoogleOptions configures a GoogleAdapter
+type GoogleOptions struct {
+	ProjectID       string
+	Location        string                           // Default "us-central1"
+	Templates       map[string]string                // Preset name to job template ID; unknown presets are used as template IDs
+	Configs         map[string]*transcoder.JobConfig // Preset name to an inline job config, taking precedence over Templates
+	CredentialsJSON []byte                           // Service account key; empty uses Application Default Credentials
+}
+
+// GoogleAdapter runs video jobs on the Google Transcoder API. Inputs and
+// outputs are gs:// URIs; the API writes the renditions under the output prefix.
+type GoogleAdapter struct {
+	service *transcoder.Service
+	options GoogleOptions
+}
+
+// DefaultGoogleTemplates map the built-in video presets to Transcoder templates
+var DefaultGoogleTemplates = map[string]string{
+	"video-720p": "preset/web-hd",
+}
+
+// DefaultGoogleConfigs define the built-in presets without a matching
+// Transcoder template; "preset/web-hd" tops out at 720p
+var DefaultGoogleConfigs = map[string]*transcoder.JobConfig{
+	"video-1080p": mp4Config("hd1080", 1920, 1080, 5_000_000),
+}
+
+// mp4Config is a single H.264/AAC MP4 rendition
+func mp4Config(key string, width, height, bitrate int64) *transcoder.JobConfig {
+	return &transcoder.JobConfig{
+		ElementaryStreams: []*transcoder.ElementaryStream{
+			{Key: "video-stream0", VideoStream: &transcoder.VideoStream{H264: &transcoder.H264CodecSettings{
+				WidthPixels: width, HeightPixels: height, BitrateBps: bitrate, FrameRate: 30,
+			}}},
+			{Key: "audio-stream0", AudioStream: &transcoder.AudioStream{Codec: "aac", BitrateBps: 128_000}},
+		},
+		MuxStreams: []*transcoder.MuxStream{
+			{Key: key, Container: "mp4", ElementaryStreams: []string{"video-stream0", "audio-stream0"}},
+		},
+	}
+}
+
+// NewGoogleAdapter creates a Transcoder API client
+func NewGoogleAdapter(ctx context.Context, options GoogleOptions) (*GoogleAdapter, error) {
+	if options.ProjectID == "" {
+		return nil, fmt.Errorf("transcoder project is required")
+	}
+	if options.Location == "" {
+		options.Location = "us-central1"
+	}
+	if options.Templates == nil {
+		options.Templates = DefaultGoogleTemplates
+	}
+	if options.Configs == nil {
+		options.Configs = DefaultGoogleConfigs
+	}
+
+	clientOptions := []option.ClientOption{option.WithScopes(transcoder.CloudPlatformScope)}
+	if len(options.CredentialsJSON) > 0 {
+		clientOptions = append(clientOptions, option.WithCredentialsJSON(options.CredentialsJSON))
+	}
+
+	service, err := transcoder.NewService(ctx, clientOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("create transcoder client: %w", err)
+	}
+	return &GoogleAdapter{service: service, options: options}, nil
+}
+
+// NewGoogleAdapterFromConfig builds an adapter from TRANSCODER_PROJECT (or
+// GOOGLE_CLOUD_PROJECT) and TRANSCODER_LOCATION. Credentials come from the
+// "transcoder-credentials" secret or TRANSCODER_CREDENTIALS, falling back to
+// Application Default Credentials.
+func NewGoogleAdapterFromConfig(ctx context.Context) (*GoogleAdapter, error) {
+	options := GoogleOptions{
+		ProjectID: config.GetEnv("TRANSCODER_PROJECT", config.GetEnv("GOOGLE_CLOUD_PROJECT", "")),
+		Location:  config.GetEnv("TRANSCODER_LOCATION", ""),
+	}
+
+	credentials, err := config.GetSecret("transcoder-credentials", "TRANSCODER_CREDENTIALS")
+	switch {
+	case err == nil:
+		options.CredentialsJSON = []byte(credentials)
+	case errors.Is(err, config.ErrSecretNotFound):
+		log.Println("🔐 No Transcoder credentials configured, using Application Default Credentials")
+	default:
+		return nil, err
+	}
+
+	return NewGoogleAdapter(ctx, options)
+}
+
+// Submit creates a Transcoder job from the preset's config or template
+func (a *GoogleAdapter) Submit(ctx context.Context, job *models.TranscodeJob) (Status, error) {
+	if job.Kind == models.TranscodeModel {
+		return Status{}, fmt.Errorf("the Transcoder API does not process 3D models")
+	}
+
+	request := &transcoder.Job{
+		InputUri:  job.Input,
+		OutputUri: job.Output,
+		Labels:    map[string]string{"job_id": job.ID},
+	}
+	if jobConfig, ok := a.options.Configs[job.Preset]; ok {
+		request.Config = jobConfig
+	} else if template, ok := a.options.Templates[job.Preset]; ok {
+		request.TemplateId = template
+	} else {
+		request.TemplateId = job.Preset
+	}
+
+	parent := fmt.Sprintf("projects/%s/locations/%s", a.options.ProjectID, a.options.Location)
+	created, err := a.service.Projects.Locations.Jobs.Create(parent, request).Context(ctx).Do()
+	if err != nil {
+		return Status{}, fmt.Errorf("create transcoder job: %w", err)
+	}
+	return googleStatus(created), nil
+}
+
+// Poll fetches the state of the Transcoder job
+func (a *GoogleAdapter) Poll(ctx context.Context, job *models.TranscodeJob) (Status, error) {
+	fetched, err := a.service.Projects.Locations.Jobs.Get(job.ExternalID).Context(ctx).Do()
+	if err != nil {
+		return Status{}, fmt.Errorf("get transcoder job: %w", err)
+	}
+	return googleStatus(fetched), nil
+}
+
+// googleStatus converts a Transcoder job state
+func googleStatus(job *transcoder.Job) Status {
+	status := Status{ExternalID: job.Name}
+	switch job.State {
+	case "SUCCEEDED":
+		status.Done = true
+		status.Percent = 100
+	case "FAILED":
+		status.Done = true
+		status.Error = "transcoder job failed"
+		if job.Error != nil && job.Error.Message != "" {
+			status.Error = job.Error.Message
+		}
+	case "RUNNING":
+		status.Percent = 50 // The API does not report progress
+	}
+	return status
+}
@@ -0,0 +1,427 @@
+// Package transcode is the shared media processing pipeline. Services enqueue
+// video transcodes and 3D model optimizations; workers pick the jobs up from
+// the message bus and hand them to an adapter, either a local command such as
+// ffmpeg (CommandAdapter) or the Google Transcoder API (GoogleAdapter):
+//
+//	job, err := transcode.Enqueue(ctx, transcode.Spec{
+//		Kind:   models.TranscodeVideo,
+//		Preset: "video-720p",
+//		Input:  "gs://uploads/raw.mp4",
+//		Output: "gs://media/720p/",
+//	}, userID)
+//
+// Worker processes call StartWorker and StartPoller. Failed submissions are
+// retried up to MaxAttempts, and jobs whose worker died are queued again once
+// its lease expires. Finished jobs are published on CompletedSubject.
+package transcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/messaging"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/praleedsuvarna/shared-libs/repo"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Message bus subjects and the queue group workers share
+const (
+	JobsSubject      = "transcode.jobs"
+	CompletedSubject = "transcode.completed"
+	WorkerGroup      = "transcode-workers"
+)
+
+// ErrUnknownAdapter is returned for jobs naming an adapter that is not registered
+var ErrUnknownAdapter = errors.New("unknown transcode adapter")
+
+// MaxAttempts is how often a job is submitted before it fails for good
+var MaxAttempts = 3
+
+// LeaseTimeout is how long a worker may go without renewing its claim on a
+// job before the poller queues the job again
+var LeaseTimeout = 5 * time.Minute
+
+// Status is an adapter's report on a job
+type Status struct {
+	ExternalID string // Provider job name, for adapters that run asynchronously
+	Done       bool
+	Percent    int
+	Error      string // Set when a done job failed
+}
+
+// Adapter runs jobs on a processing backend
+type Adapter interface {
+	// Submit starts a job. Adapters that do the work in-process return a done Status.
+	Submit(ctx context.Context, job *models.TranscodeJob) (Status, error)
+	// Poll reports on a job submitted earlier, identified by job.ExternalID
+	Poll(ctx context.Context, job *models.TranscodeJob) (Status, error)
+}
+
+// Spec describes a job to enqueue
+type Spec struct {
+	Kind           string // models.TranscodeVideo or models.TranscodeModel
+	Preset         string // Adapter-specific, e.g. "video-720p"
+	Input          string
+	Output         string
+	OrganizationID string
+	Adapter        string // Default TRANSCODE_ADAPTER, or "command"
+	Metadata       map[string]string
+}
+
+var (
+	store = repo.New[models.TranscodeJob](models.TranscodeJob{}.CollectionName(), repo.WithULIDKeys())
+
+	adapters   = map[string]Adapter{"command": NewCommandAdapter()}
+	adaptersMu sync.RWMutex
+)
+
+// jobMessage is published on JobsSubject
+type jobMessage struct {
+	JobID string `json:"job_id"`
+}
+
+// RegisterAdapter makes an adapter available to jobs under name
+func RegisterAdapter(name string, adapter Adapter) {
+	adaptersMu.Lock()
+	defer adaptersMu.Unlock()
+	adapters[name] = adapter
+}
+
+func lookupAdapter(name string) (Adapter, error) {
+	adaptersMu.RLock()
+	defer adaptersMu.RUnlock()
+	adapter, ok := adapters[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownAdapter, name)
+	}
+	return adapter, nil
+}
+
+// Enqueue stores a queued job and publishes it for the workers
+func Enqueue(ctx context.Context, spec Spec, createdBy string) (*models.TranscodeJob, error) {
+	if spec.Input == "" || spec.Output == "" || spec.Preset == "" {
+		return nil, fmt.Errorf("transcode input, output and preset are required")
+	}
+	if spec.Adapter == "" {
+		spec.Adapter = config.GetEnv("TRANSCODE_ADAPTER", "command")
+	}
+	if _, err := lookupAdapter(spec.Adapter); err != nil {
+		return nil, err
+	}
+
+	now := utils.Now()
+	job := &models.TranscodeJob{
+		ID:             utils.NewID(),
+		OrganizationID: spec.OrganizationID,
+		Kind:           spec.Kind,
+		Preset:         spec.Preset,
+		Input:          spec.Input,
+		Output:         spec.Output,
+		Adapter:        spec.Adapter,
+		Status:         models.TranscodeQueued,
+		Metadata:       spec.Metadata,
+		CreatedBy:      createdBy,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := store.Insert(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to store transcode job: %w", err)
+	}
+
+	if err := messaging.Publish(ctx, JobsSubject, jobMessage{JobID: job.ID}); err != nil {
+		// Nothing will pick the job up, so it must not stay queued
+		finish(ctx, job, Status{Done: true, Error: "failed to enqueue: " + err.Error()})
+		return nil, fmt.Errorf("failed to enqueue transcode job: %w", err)
+	}
+	return job, nil
+}
+
+// Get returns a job of organizationID; others are repo.ErrNotFound
+func Get(ctx context.Context, id, organizationID string) (*models.TranscodeJob, error) {
+	if !utils.IsValidID(id) {
+		return nil, repo.ErrNotFound
+	}
+	return store.FindOne(ctx, bson.M{"_id": id, "organization_id": organizationID})
+}
+
+// StartWorker consumes queued jobs in WorkerGroup, so each job runs on one
+// worker. Command jobs run inside the handler; run several workers for
+// parallelism.
+func StartWorker() (messaging.Subscription, error) {
+	return messaging.Subscribe(JobsSubject, WorkerGroup, messaging.Typed(
+		func(ctx context.Context, message jobMessage, _ *messaging.Envelope) error {
+			return process(ctx, message.JobID)
+		}))
+}
+
+// process claims a queued job and submits it to its adapter, renewing the
+// lease while the adapter works. Redelivered messages find the job already
+// claimed and are ignored.
+func process(ctx context.Context, id string) error {
+	now := utils.Now()
+	var job models.TranscodeJob
+	err := store.Collection().FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "status": models.TranscodeQueued},
+		bson.M{
+			"$set": bson.M{
+				"status":           models.TranscodeRunning,
+				"started_at":       now,
+				"updated_at":       now,
+				"lease_expires_at": now.Add(LeaseTimeout),
+			},
+			"$inc": bson.M{"attempts": 1},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	adapter, err := lookupAdapter(job.Adapter)
+	if err != nil {
+		finish(ctx, &job, Status{Done: true, Error: err.Error()})
+		return nil
+	}
+
+	stopRenewing := renewLease(ctx, job.ID)
+	status, err := adapter.Submit(ctx, &job)
+	stopRenewing()
+	if err != nil {
+		retryOrFail(ctx, &job, err.Error())
+		return nil
+	}
+	if status.Done {
+		finish(ctx, &job, status)
+		return nil
+	}
+	return recordProgress(ctx, &job, status)
+}
+
+// renewLease extends the lease of a job every third of LeaseTimeout until the
+// returned function is called
+func renewLease(ctx context.Context, id string) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(LeaseTimeout / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				err := store.UpdateByID(ctx, id, bson.M{"$set": bson.M{"lease_expires_at": utils.Now().Add(LeaseTimeout)}})
+				if err != nil {
+					utils.Log(ctx).Warn("failed to renew transcode lease", "job_id", id, "error", err)
+				}
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// retryOrFail queues a job whose attempt failed again, or fails it once
+// MaxAttempts is reached
+func retryOrFail(ctx context.Context, job *models.TranscodeJob, reason string) {
+	if job.Attempts >= MaxAttempts {
+		finish(ctx, job, Status{Done: true, Error: reason})
+		return
+	}
+
+	err := store.UpdateByID(ctx, job.ID, bson.M{
+		"$set":   bson.M{"status": models.TranscodeQueued, "error": reason, "updated_at": utils.Now()},
+		"$unset": bson.M{"lease_expires_at": "", "external_id": ""},
+	})
+	if err == nil {
+		err = messaging.Publish(ctx, JobsSubject, jobMessage{JobID: job.ID})
+	}
+	if err != nil {
+		finish(ctx, job, Status{Done: true, Error: reason + "; failed to retry: " + err.Error()})
+		return
+	}
+	utils.Log(ctx).Warn("retrying transcode job", "job_id", job.ID, "attempt", job.Attempts, "error", reason)
+}
+
+// ReclaimStale queues jobs again whose worker stopped renewing the lease,
+// e.g. because it crashed mid-transcode, and fails those out of attempts.
+// Jobs handed to a provider are followed by PollRunning instead. It returns
+// the number of jobs reclaimed.
+func ReclaimStale(ctx context.Context) (int, error) {
+	now := utils.Now()
+	cursor, err := store.Collection().Find(ctx, bson.M{
+		"status":      models.TranscodeRunning,
+		"external_id": bson.M{"$exists": false},
+		"$or": bson.A{
+			bson.M{"lease_expires_at": bson.M{"$lt": now}},
+			bson.M{"lease_expires_at": bson.M{"$exists": false}, "updated_at": bson.M{"$lt": now.Add(-LeaseTimeout)}},
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var stale []models.TranscodeJob
+	if err := cursor.All(ctx, &stale); err != nil {
+		return 0, err
+	}
+
+	reclaimed := 0
+	for i := range stale {
+		job := &stale[i]
+		// Take the job over only if its worker has not renewed in the meantime
+		result, err := store.Collection().UpdateOne(ctx,
+			bson.M{"_id": job.ID, "status": models.TranscodeRunning, "lease_expires_at": job.LeaseExpiresAt},
+			bson.M{"$set": bson.M{"lease_expires_at": now.Add(LeaseTimeout)}},
+		)
+		if err != nil {
+			return reclaimed, err
+		}
+		if result.ModifiedCount == 0 {
+			continue
+		}
+		retryOrFail(ctx, job, "worker stopped responding")
+		reclaimed++
+	}
+	return reclaimed, nil
+}
+
+// PollRunning asks the adapters about jobs running at a provider and
+// finishes those that are done. It returns the number of jobs finished.
+func PollRunning(ctx context.Context) (int, error) {
+	cursor, err := store.Collection().Find(ctx, bson.M{
+		"status":      models.TranscodeRunning,
+		"external_id": bson.M{"$exists": true},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var running []models.TranscodeJob
+	if err := cursor.All(ctx, &running); err != nil {
+		return 0, err
+	}
+
+	finished := 0
+	for i := range running {
+		job := &running[i]
+		adapter, err := lookupAdapter(job.Adapter)
+		if err != nil {
+			finish(ctx, job, Status{Done: true, Error: err.Error()})
+			finished++
+			continue
+		}
+
+		status, err := adapter.Poll(ctx, job)
+		if err != nil {
+			// Provider hiccups are retried on the next poll
+			utils.Log(ctx).Warn("failed to poll transcode job", "job_id", job.ID, "error", err)
+			continue
+		}
+		if status.Done {
+			finish(ctx, job, status)
+			finished++
+			continue
+		}
+		if err := recordProgress(ctx, job, status); err != nil {
+			return finished, err
+		}
+	}
+	return finished, nil
+}
+
+// StartPoller runs PollRunning and ReclaimStale periodically. Call the
+// returned function to stop the poller.
+func StartPoller(interval time.Duration) func() {
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				count, err := PollRunning(ctx)
+				if err != nil {
+					utils.LogError(fmt.Sprintf("Transcode poll failed: %v", err))
+				} else if count > 0 {
+					log.Printf("🎞️  Finished %d transcode jobs", count)
+				}
+				reclaimed, err := ReclaimStale(ctx)
+				cancel()
+				if err != nil {
+					utils.LogError(fmt.Sprintf("Transcode lease check failed: %v", err))
+				} else if reclaimed > 0 {
+					log.Printf("🎞️  Requeued %d stalled transcode jobs", reclaimed)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		stopOnce.Do(func() { close(stop) })
+	}
+}
+
+// recordProgress stores the provider job name and progress of a running job
+func recordProgress(ctx context.Context, job *models.TranscodeJob, status Status) error {
+	set := bson.M{"progress": max(0, min(status.Percent, 99)), "updated_at": utils.Now()}
+	if status.ExternalID != "" {
+		set["external_id"] = status.ExternalID
+	}
+	return store.UpdateByID(ctx, job.ID, bson.M{"$set": set})
+}
+
+// finish records the outcome of a job and publishes it on CompletedSubject
+func finish(ctx context.Context, job *models.TranscodeJob, status Status) {
+	now := utils.Now()
+	job.UpdatedAt = now
+	job.CompletedAt = &now
+	if status.ExternalID != "" {
+		job.ExternalID = status.ExternalID
+	}
+
+	set := bson.M{"updated_at": now, "completed_at": now}
+	if status.Error != "" {
+		job.Status = models.TranscodeFailed
+		job.Error = status.Error
+		set["error"] = job.Error
+	} else {
+		job.Status = models.TranscodeSucceeded
+		job.Progress = 100
+		set["progress"] = job.Progress
+	}
+	set["status"] = job.Status
+	if job.ExternalID != "" {
+		set["external_id"] = job.ExternalID
+	}
+
+	if err := store.UpdateByID(ctx, job.ID, bson.M{"$set": set}); err != nil {
+		utils.Log(ctx).Error("failed to record transcode outcome", "job_id", job.ID, "error", err)
+	}
+
+	if messaging.Default() != nil {
+		if err := messaging.Publish(ctx, CompletedSubject, job); err != nil {
+			utils.Log(ctx).Warn("failed to publish transcode completion", "job_id", job.ID, "error", err)
+		}
+	}
+}
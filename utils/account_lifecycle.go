@@ -0,0 +1,276 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// AccountLifecycleCollection stores deactivation and deletion records
+const AccountLifecycleCollection = "account_lifecycle"
+
+// AccountDeletionGracePeriod is how long a deactivated account can be restored
+var AccountDeletionGracePeriod = 30 * 24 * time.Hour
+
+// Account lifecycle errors
+var (
+	ErrAccountAlreadyDeactivated = errors.New("account is already deactivated")
+	ErrAccountNotDeactivated     = errors.New("account is not pending deletion")
+)
+
+// AccountLifecycleHook lets a service react to lifecycle transitions of a user,
+// e.g. hiding content on deactivation and purging it on deletion. Nil funcs are skipped.
+type AccountLifecycleHook struct {
+	Deactivate func(ctx context.Context, userID string) error
+	Reactivate func(ctx context.Context, userID string) error
+	Delete     func(ctx context.Context, userID string) error
+}
+
+type namedLifecycleHook struct {
+	name string
+	hook AccountLifecycleHook
+}
+
+var (
+	lifecycleHooks    []namedLifecycleHook
+	lifecycleHooksMux sync.RWMutex
+)
+
+func init() {
+	RegisterEmailTemplate(EmailTemplate{
+		Name:    "account_deactivated",
+		Subject: "Your account has been deactivated",
+		HTML: `
+        <h1>Your account has been deactivated</h1>
+        <p>Your account and its data will be permanently deleted on {{.ScheduledFor.Format "Jan 2, 2006"}}.</p>
+        <p>If you change your mind, sign in before then to restore your account.</p>
+    `,
+		SampleData: map[string]interface{}{"ScheduledFor": time.Now().Add(AccountDeletionGracePeriod)},
+	})
+	RegisterEmailTemplate(EmailTemplate{
+		Name:    "account_deleted",
+		Subject: "Your account has been deleted",
+		HTML: `
+        <h1>Your account has been deleted</h1>
+        <p>Your account and associated data have been permanently removed. Thank you for being with us.</p>
+    `,
+	})
+
+	// Preferences are owned by shared-libs, so clean them up like any service would
+	RegisterAccountLifecycleHook("user_preferences", AccountLifecycleHook{
+		Delete: func(_ context.Context, userID string) error {
+			return DeleteUserPreferences(userID)
+		},
+	})
+}
+
+// RegisterAccountLifecycleHook registers cleanup logic run on lifecycle transitions
+func RegisterAccountLifecycleHook(name string, hook AccountLifecycleHook) {
+	lifecycleHooksMux.Lock()
+	defer lifecycleHooksMux.Unlock()
+	lifecycleHooks = append(lifecycleHooks, namedLifecycleHook{name: name, hook: hook})
+}
+
+// DeactivateAccount deactivates a user and schedules hard deletion after the grace period
+func DeactivateAccount(userID, email, reason, requestedBy string) (*models.AccountLifecycle, error) {
+	if _, err := GetActiveAccountLifecycle(userID); err == nil {
+		return nil, ErrAccountAlreadyDeactivated
+	} else if !errors.Is(err, ErrAccountNotDeactivated) {
+		return nil, err
+	}
+
+	now := time.Now()
+	record := models.AccountLifecycle{
+		ID:            primitive.NewObjectID(),
+		UserID:        userID,
+		Email:         email,
+		Status:        models.AccountDeactivated,
+		Reason:        reason,
+		RequestedBy:   requestedBy,
+		DeactivatedAt: now,
+		ScheduledFor:  now.Add(AccountDeletionGracePeriod),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := config.GetCollection(AccountLifecycleCollection).InsertOne(ctx, record); err != nil {
+		return nil, err
+	}
+
+	record.HookErrors = runLifecycleHooks(ctx, userID, func(h AccountLifecycleHook) func(context.Context, string) error { return h.Deactivate })
+	LogAudit(requestedBy, "account_deactivated", userID)
+
+	if email != "" {
+		if err := NotifyUser(Notification{
+			UserID:   userID,
+			Email:    email,
+			Category: "security",
+			Template: "account_deactivated",
+			Data:     map[string]interface{}{"ScheduledFor": record.ScheduledFor},
+		}); err != nil {
+			LogWarning(fmt.Sprintf("Failed to send deactivation email to user %s: %v", userID, err))
+		}
+	}
+
+	return &record, nil
+}
+
+// CancelAccountDeletion restores a deactivated account within the grace period
+func CancelAccountDeletion(userID, requestedBy string) error {
+	collection := config.GetCollection(AccountLifecycleCollection)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"user_id": userID, "status": models.AccountDeactivated},
+		bson.M{"$set": bson.M{"status": models.AccountReactivated, "cancelled_at": now}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrAccountNotDeactivated
+	}
+
+	runLifecycleHooks(ctx, userID, func(h AccountLifecycleHook) func(context.Context, string) error { return h.Reactivate })
+	LogAudit(requestedBy, "account_reactivated", userID)
+	return nil
+}
+
+// GetActiveAccountLifecycle returns the pending deletion record for a user
+func GetActiveAccountLifecycle(userID string) (*models.AccountLifecycle, error) {
+	collection := config.GetCollection(AccountLifecycleCollection)
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	var record models.AccountLifecycle
+	err := collection.FindOne(ctx, bson.M{"user_id": userID, "status": models.AccountDeactivated}).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrAccountNotDeactivated
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// IsAccountDeactivated reports whether a user is deactivated and pending deletion
+func IsAccountDeactivated(userID string) bool {
+	_, err := GetActiveAccountLifecycle(userID)
+	return err == nil
+}
+
+// ProcessScheduledDeletions hard-deletes accounts whose grace period has ended
+func ProcessScheduledDeletions(ctx context.Context) (int, error) {
+	collection := config.GetCollection(AccountLifecycleCollection)
+
+	cursor, err := collection.Find(ctx, bson.M{
+		"status":        models.AccountDeactivated,
+		"scheduled_for": bson.M{"$lte": time.Now()},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var due []models.AccountLifecycle
+	if err = cursor.All(ctx, &due); err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, record := range due {
+		hookErrors := runLifecycleHooks(ctx, record.UserID, func(h AccountLifecycleHook) func(context.Context, string) error { return h.Delete })
+		if len(hookErrors) > 0 {
+			// Leave the record pending so the next run retries the failed cleanups
+			collection.UpdateOne(ctx, bson.M{"_id": record.ID}, bson.M{"$set": bson.M{"hook_errors": hookErrors}})
+			continue
+		}
+
+		now := time.Now()
+		_, err := collection.UpdateOne(ctx, bson.M{"_id": record.ID}, bson.M{
+			"$set":   bson.M{"status": models.AccountDeleted, "deleted_at": now},
+			"$unset": bson.M{"hook_errors": ""},
+		})
+		if err != nil {
+			return deleted, err
+		}
+
+		LogAudit("system", "account_deleted", record.UserID)
+		if record.Email != "" {
+			if err := SendTemplatedEmail(record.Email, "account_deleted", nil); err != nil {
+				LogWarning(fmt.Sprintf("Failed to send deletion email to user %s: %v", record.UserID, err))
+			}
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// StartAccountDeletionWorker runs ProcessScheduledDeletions periodically.
+// Call the returned function to stop the worker.
+func StartAccountDeletionWorker(interval time.Duration) func() {
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				count, err := ProcessScheduledDeletions(ctx)
+				cancel()
+				if err != nil {
+					LogError(fmt.Sprintf("Scheduled account deletion failed: %v", err))
+				} else if count > 0 {
+					log.Printf("🗑️  Deleted %d accounts after grace period", count)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		stopOnce.Do(func() { close(stop) })
+	}
+}
+
+// runLifecycleHooks calls the selected hook of every registration and collects failures
+func runLifecycleHooks(ctx context.Context, userID string, selectHook func(AccountLifecycleHook) func(context.Context, string) error) map[string]string {
+	lifecycleHooksMux.RLock()
+	hooks := append([]namedLifecycleHook{}, lifecycleHooks...)
+	lifecycleHooksMux.RUnlock()
+
+	failures := map[string]string{}
+	for _, registered := range hooks {
+		fn := selectHook(registered.hook)
+		if fn == nil {
+			continue
+		}
+		if err := fn(ctx, userID); err != nil {
+			LogError(fmt.Sprintf("Account lifecycle hook %s failed for user %s: %v", registered.name, userID, err))
+			failures[registered.name] = err.Error()
+		}
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+	return failures
+}
@@ -0,0 +1,202 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ActivityCollection stores per-organization feed items
+const ActivityCollection = "activity_feed"
+
+// ActivityEvent is the payload projected into the feed, from audit entries or NATS
+type ActivityEvent struct {
+	OrganizationID string                 `json:"organization_id"`
+	ActorID        string                 `json:"actor_id"`
+	Action         string                 `json:"action"`
+	TargetID       string                 `json:"target_id"`
+	Metadata       map[string]interface{} `json:"metadata,omitempty"`
+}
+
+var (
+	activityTemplates   = map[string]*template.Template{}
+	activityTemplateMux sync.RWMutex
+	activityFeedOnce    sync.Once
+)
+
+// RegisterActivityTemplate sets the message for an action, rendered with the
+// ActivityEvent fields, e.g. "{{.ActorID}} published experience {{.TargetID}}"
+func RegisterActivityTemplate(action, text string) {
+	tmpl := template.Must(template.New(action).Parse(text))
+
+	activityTemplateMux.Lock()
+	defer activityTemplateMux.Unlock()
+	activityTemplates[action] = tmpl
+}
+
+// RecordActivity renders and stores a feed item for an event. Events without a
+// registered template or organization are ignored.
+func RecordActivity(event ActivityEvent) error {
+	if event.OrganizationID == "" {
+		return nil
+	}
+
+	activityTemplateMux.RLock()
+	tmpl, ok := activityTemplates[event.Action]
+	activityTemplateMux.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	var message bytes.Buffer
+	if err := tmpl.Execute(&message, event); err != nil {
+		return fmt.Errorf("failed to render activity for %s: %w", event.Action, err)
+	}
+
+	item := models.ActivityItem{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: event.OrganizationID,
+		ActorID:        event.ActorID,
+		Action:         event.Action,
+		TargetID:       event.TargetID,
+		Message:        message.String(),
+		Metadata:       event.Metadata,
+		ReadBy:         []string{},
+		CreatedAt:      Now(),
+	}
+
+	collection := config.GetCollection(ActivityCollection)
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	_, err := collection.InsertOne(ctx, item)
+	return err
+}
+
+// EnableAuditActivityFeed projects audit entries belonging to an organization into the feed
+func EnableAuditActivityFeed() {
+	activityFeedOnce.Do(func() {
+		OnAuditLogged(func(entry models.AuditLog) {
+			err := RecordActivity(ActivityEvent{
+				OrganizationID: auditOrganizationID(entry),
+				ActorID:        entry.AdminID,
+				Action:         entry.Action,
+				TargetID:       entry.TargetID,
+				Metadata:       entry.Metadata,
+			})
+			if err != nil {
+				LogWarning(fmt.Sprintf("Failed to project audit entry into activity feed: %v", err))
+			}
+		})
+	})
+}
+
+// SubscribeActivityFeed projects ActivityEvent messages published on a NATS subject
+// (wildcards allowed) into the feed
+func SubscribeActivityFeed(subject string) (*nats.Subscription, error) {
+	if config.NATS == nil {
+		return nil, fmt.Errorf("subscribe %s: nats: %w", subject, config.ErrNotConnected)
+	}
+
+	sub, err := config.NATS.Subscribe(subject, func(msg *nats.Msg) {
+		var event ActivityEvent
+		if err := json.Unmarshal(msg.Data, &event); err != nil {
+			LogWarning(fmt.Sprintf("Invalid activity event on %s: %v", msg.Subject, err))
+			return
+		}
+		if event.Action == "" {
+			event.Action = msg.Subject
+		}
+		if err := RecordActivity(event); err != nil {
+			LogWarning(fmt.Sprintf("Failed to record activity from %s: %v", msg.Subject, err))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("📰 Activity feed subscribed to %s", subject)
+	return sub, nil
+}
+
+// GetActivityFeed returns a page of an organization's feed for a user, newest first
+func GetActivityFeed(organizationID, userID string, unreadOnly bool, page, limit int) ([]models.ActivityItem, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	filter := bson.M{"organization_id": organizationID}
+	if unreadOnly {
+		filter["read_by"] = bson.M{"$ne": userID}
+	}
+
+	collection := config.GetCollection(ActivityCollection)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cursor, err := collection.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((page-1)*limit)).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	items := []models.ActivityItem{}
+	if err = cursor.All(ctx, &items); err != nil {
+		return nil, 0, err
+	}
+
+	for i := range items {
+		items[i].Read = Contains(items[i].ReadBy, userID)
+	}
+	return items, total, nil
+}
+
+// CountUnreadActivity returns how many feed items the user has not read
+func CountUnreadActivity(organizationID, userID string) (int64, error) {
+	collection := config.GetCollection(ActivityCollection)
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	return collection.CountDocuments(ctx, bson.M{"organization_id": organizationID, "read_by": bson.M{"$ne": userID}})
+}
+
+// MarkActivityRead marks feed items as read by a user; no IDs marks the whole feed
+func MarkActivityRead(organizationID, userID string, ids []primitive.ObjectID) (int64, error) {
+	filter := bson.M{"organization_id": organizationID, "read_by": bson.M{"$ne": userID}}
+	if len(ids) > 0 {
+		filter["_id"] = bson.M{"$in": ids}
+	}
+
+	collection := config.GetCollection(ActivityCollection)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := collection.UpdateMany(ctx, filter, bson.M{"$addToSet": bson.M{"read_by": userID}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
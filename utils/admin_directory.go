@@ -0,0 +1,247 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Directory describes a service-owned collection, such as users or
+// organizations, that the shared admin endpoints search and export. Services
+// with different field names replace UsersDirectory or OrganizationsDirectory
+// at startup.
+type Directory struct {
+	Collection   string
+	SearchFields []string // Matched case-insensitively by the search term
+	RoleField    string
+	StatusField  string
+	CreatedField string
+	Fields       []string // Returned by searches; no other field leaves the collection
+	ExportFields []string // Columns of CSV exports, in order
+	HiddenFields []string // Never returned, even when listed in Fields, e.g. password hashes
+	PIIFields    []string // Masked in exports that ask for it
+}
+
+// UsersDirectory is the users collection searched by the admin endpoints
+var UsersDirectory = Directory{
+	Collection:   "users",
+	SearchFields: []string{"email", "name", "username"},
+	RoleField:    "role",
+	StatusField:  "status",
+	CreatedField: "created_at",
+	Fields: []string{"_id", "email", "name", "username", "role", "status", "organization_id",
+		"email_verified", "mfa_enabled", "last_login_at", "created_at", "updated_at"},
+	ExportFields: []string{"_id", "email", "name", "role", "status", "organization_id", "created_at"},
+	HiddenFields: []string{"password", "password_hash", "refresh_token", "mfa_secret", "reset_token"},
+	PIIFields:    []string{"email", "name", "username", "phone", "last_ip"},
+}
+
+// OrganizationsDirectory is the organizations collection searched by the admin endpoints
+var OrganizationsDirectory = Directory{
+	Collection:   "organizations",
+	SearchFields: []string{"name", "slug", "email"},
+	StatusField:  "status",
+	CreatedField: "created_at",
+	Fields:       []string{"_id", "name", "slug", "email", "status", "plan", "created_at", "updated_at"},
+	ExportFields: []string{"_id", "name", "slug", "status", "plan", "created_at"},
+	HiddenFields: []string{"api_keys", "webhook_secret"},
+	PIIFields:    []string{"email", "phone"},
+}
+
+// DirectoryQuery filters and pages a directory search
+type DirectoryQuery struct {
+	Search         string // Substring of any search field
+	Role           string
+	Status         string
+	OrganizationID string
+	CreatedFrom    time.Time
+	CreatedTo      time.Time
+	Page           int
+	Limit          int
+	OldestFirst    bool
+}
+
+// Filter builds the Mongo filter for query
+func (d Directory) Filter(query DirectoryQuery) bson.M {
+	filter := bson.M{}
+	if query.Search != "" && len(d.SearchFields) > 0 {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(query.Search), Options: "i"}
+		conditions := bson.A{}
+		for _, field := range d.SearchFields {
+			conditions = append(conditions, bson.M{field: pattern})
+		}
+		filter["$or"] = conditions
+	}
+	if query.Role != "" && d.RoleField != "" {
+		filter[d.RoleField] = query.Role
+	}
+	if query.Status != "" && d.StatusField != "" {
+		filter[d.StatusField] = query.Status
+	}
+	if query.OrganizationID != "" {
+		filter["organization_id"] = query.OrganizationID
+	}
+
+	created := bson.M{}
+	if !query.CreatedFrom.IsZero() {
+		created["$gte"] = query.CreatedFrom
+	}
+	if !query.CreatedTo.IsZero() {
+		created["$lte"] = query.CreatedTo
+	}
+	if len(created) > 0 && d.CreatedField != "" {
+		filter[d.CreatedField] = created
+	}
+	return filter
+}
+
+// findOptions sorts by creation date and projects Fields and ExportFields,
+// less the hidden ones; fields added to the collection later stay private
+func (d Directory) findOptions(oldestFirst bool) *options.FindOptions {
+	direction := -1
+	if oldestFirst {
+		direction = 1
+	}
+	sort := bson.D{{Key: "_id", Value: direction}}
+	if d.CreatedField != "" {
+		sort = bson.D{{Key: d.CreatedField, Value: direction}, {Key: "_id", Value: direction}}
+	}
+
+	projection := bson.M{"_id": 1}
+	for _, field := range append(append([]string{}, d.Fields...), d.ExportFields...) {
+		if !slices.Contains(d.HiddenFields, field) {
+			projection[field] = 1
+		}
+	}
+	if slices.Contains(d.HiddenFields, "_id") {
+		projection["_id"] = 0
+	}
+	return options.Find().SetSort(sort).SetProjection(projection)
+}
+
+// Search returns one page of matching documents and the total match count
+func (d Directory) Search(ctx context.Context, query DirectoryQuery) ([]bson.M, int64, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 {
+		query.Limit = 50
+	}
+
+	collection := config.GetCollection(d.Collection)
+	filter := d.Filter(query)
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("count %s: %w", d.Collection, err)
+	}
+
+	findOptions := d.findOptions(query.OldestFirst).
+		SetSkip(int64((query.Page - 1) * query.Limit)).
+		SetLimit(int64(query.Limit))
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, fmt.Errorf("search %s: %w", d.Collection, err)
+	}
+	defer cursor.Close(ctx)
+
+	items := []bson.M{}
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// Stream calls fn for every matching document, ignoring the page and limit
+func (d Directory) Stream(ctx context.Context, query DirectoryQuery, fn func(bson.M) error) error {
+	cursor, err := config.GetCollection(d.Collection).Find(ctx, d.Filter(query), d.findOptions(query.OldestFirst))
+	if err != nil {
+		return fmt.Errorf("export %s: %w", d.Collection, err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var document bson.M
+		if err := cursor.Decode(&document); err != nil {
+			return err
+		}
+		if err := fn(document); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// ExportRow renders the export fields of a document as CSV cells. Cells that
+// spreadsheets would evaluate as formulas are prefixed with a quote.
+func (d Directory) ExportRow(document bson.M) []string {
+	row := make([]string, len(d.ExportFields))
+	for i, field := range d.ExportFields {
+		switch value := document[field].(type) {
+		case nil:
+		case primitive.ObjectID:
+			row[i] = value.Hex()
+		case primitive.DateTime:
+			row[i] = FormatTime(value.Time())
+		case time.Time:
+			row[i] = FormatTime(value)
+		default:
+			row[i] = fmt.Sprint(value)
+		}
+		row[i] = EscapeCSVFormula(row[i])
+	}
+	return row
+}
+
+// EscapeCSVFormula prefixes value with a quote when it starts with a character
+// that makes spreadsheets treat a cell as a formula (=, +, -, @, tab or CR)
+func EscapeCSVFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// Mask replaces the PII fields of document with masked values in place
+func (d Directory) Mask(document bson.M) {
+	for _, field := range d.PIIFields {
+		value, ok := document[field].(string)
+		if !ok || value == "" {
+			continue
+		}
+		switch {
+		case strings.Contains(value, "@"):
+			document[field] = MaskEmail(value)
+		case field == "phone":
+			document[field] = MaskPhone(value)
+		default:
+			document[field] = MaskText(value)
+		}
+	}
+}
+
+// MaskEmail keeps the first character of the local part and the domain,
+// e.g. "j***@example.com"
+func MaskEmail(email string) string {
+	local, domain, found := strings.Cut(email, "@")
+	if !found || local == "" {
+		return "***"
+	}
+	return MaskText(local) + "@" + domain
+}
+
+// MaskText keeps the first character of value, e.g. "J***"
+func MaskText(value string) string {
+	for _, r := range value {
+		return string(r) + "***"
+	}
+	return "***"
+}
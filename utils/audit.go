@@ -2,31 +2,137 @@ package utils
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/praleedsuvarna/shared-libs/config"
 	"github.com/praleedsuvarna/shared-libs/models"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	auditHooks    []func(models.AuditLog)
+	auditHooksMux sync.RWMutex
 )
 
 // Log an admin action
 func LogAudit(adminID, action, targetID string) {
+	LogAuditWithMetadata(adminID, action, targetID, nil)
+}
+
+// LogAuditWithMetadata logs an admin action together with structured details.
+// The entry belongs to the organization named by metadata["organization_id"].
+func LogAuditWithMetadata(adminID, action, targetID string, metadata map[string]interface{}) {
+	organizationID, _ := metadata["organization_id"].(string)
+	logAudit(organizationID, adminID, action, targetID, metadata)
+}
+
+// LogAuditContext logs an admin action, adding the client IP and GeoIP location
+// carried by ctx (see middleware.RealIP and middleware.GeoIP) to the metadata.
+// The entry belongs to the caller's organization (see WithAuditOrganization),
+// falling back to metadata["organization_id"].
+func LogAuditContext(ctx context.Context, adminID, action, targetID string, metadata map[string]interface{}) {
+	if location := GeoLocationFromContext(ctx); location != nil {
+		enriched := make(map[string]interface{}, len(metadata)+3)
+		enriched["ip"] = location.IP
+		if location.Country != "" {
+			enriched["country"] = location.Country
+		}
+		if location.City != "" {
+			enriched["city"] = location.City
+		}
+		for key, value := range metadata {
+			enriched[key] = value
+		}
+		metadata = enriched
+	} else if ip := ClientIPFromContext(ctx); ip != "" {
+		enriched := make(map[string]interface{}, len(metadata)+1)
+		enriched["ip"] = ip
+		for key, value := range metadata {
+			enriched[key] = value
+		}
+		metadata = enriched
+	}
+
+	organizationID := AuditOrganizationFromContext(ctx)
+	if organizationID == "" {
+		organizationID, _ = metadata["organization_id"].(string)
+	}
+	logAudit(organizationID, adminID, action, targetID, metadata)
+}
+
+func logAudit(organizationID, adminID, action, targetID string, metadata map[string]interface{}) {
 	collection := config.GetCollection("oms_audit_logs")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	log := models.AuditLog{
-		ID:        primitive.NewObjectID(),
-		AdminID:   adminID,
-		Action:    action,
-		TargetID:  targetID,
-		Timestamp: time.Now(),
+		ID:             primitive.NewObjectID(),
+		OrganizationID: organizationID,
+		AdminID:        adminID,
+		Action:         action,
+		TargetID:       targetID,
+		Metadata:       metadata,
+		Timestamp:      Now(),
 	}
 
-	_, err := collection.InsertOne(ctx, log)
+	var err error
+	if auditChainEnabled() {
+		err = insertChainedAudit(ctx, collection, &log)
+	} else {
+		_, err = collection.InsertOne(ctx, log)
+	}
 	if err != nil {
 		println("Failed to log audit:", err.Error())
+		return
+	}
+
+	notifyAuditHooks(log)
+}
+
+// WithAuditOrganization returns a copy of ctx attributing audit entries to an
+// organization; middleware.AuthMiddleware sets it from the token
+func WithAuditOrganization(ctx context.Context, organizationID string) context.Context {
+	return context.WithValue(ctx, auditOrganizationContextKey{}, organizationID)
+}
+
+// AuditOrganizationFromContext returns the organization stored by WithAuditOrganization
+func AuditOrganizationFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	organizationID, _ := ctx.Value(auditOrganizationContextKey{}).(string)
+	return organizationID
+}
+
+type auditOrganizationContextKey struct{}
+
+// AuditOrganizationFilter matches the audit entries of an organization,
+// including entries logged before organization_id was stored on the entry
+// itself, which carry it in their metadata
+func AuditOrganizationFilter(organizationID string) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{"organization_id": organizationID},
+		bson.M{"organization_id": bson.M{"$exists": false}, "metadata.organization_id": organizationID},
+	}}
+}
+
+// OnAuditLogged registers a hook called after every audit entry is stored
+func OnAuditLogged(hook func(models.AuditLog)) {
+	auditHooksMux.Lock()
+	defer auditHooksMux.Unlock()
+	auditHooks = append(auditHooks, hook)
+}
+
+func notifyAuditHooks(entry models.AuditLog) {
+	auditHooksMux.RLock()
+	hooks := append([]func(models.AuditLog){}, auditHooks...)
+	auditHooksMux.RUnlock()
+
+	for _, hook := range hooks {
+		hook(entry)
 	}
 }
 
@@ -49,3 +155,96 @@ func GetAuditLogs(filter bson.M) ([]models.AuditLog, error) {
 
 	return logs, nil
 }
+
+// StreamAuditLogs calls fn for each audit log matching filter, oldest first,
+// without loading the result set into memory
+func StreamAuditLogs(ctx context.Context, filter bson.M, fn func(models.AuditLog) error) error {
+	collection := config.GetCollection("oms_audit_logs")
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var entry models.AuditLog
+		if err := cursor.Decode(&entry); err != nil {
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// MaxAuditTargets caps the number of targets in one GetAuditLogsForTargets query
+const MaxAuditTargets = 100
+
+// AuditTargetQuery pages and narrows a multi-target audit query
+type AuditTargetQuery struct {
+	OrganizationID string // Only entries of this organization; empty matches every organization
+	Page           int    // 1-based page number, defaults to 1
+	Limit          int    // Page size, defaults to 50 and capped at 200
+	Action         string // Only entries with this action
+	From           time.Time
+	To             time.Time
+}
+
+// GetAuditLogsForTargets returns a page of the audit trails of several
+// targets in one query, newest first, with the total match count
+func GetAuditLogsForTargets(targetIDs []string, query AuditTargetQuery) ([]models.AuditLog, int64, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit <= 0 {
+		query.Limit = 50
+	}
+	if query.Limit > 200 {
+		query.Limit = 200
+	}
+
+	filter := bson.M{"target_id": bson.M{"$in": targetIDs}}
+	if query.OrganizationID != "" {
+		for key, value := range AuditOrganizationFilter(query.OrganizationID) {
+			filter[key] = value
+		}
+	}
+	if query.Action != "" {
+		filter["action"] = query.Action
+	}
+	timestamp := bson.M{}
+	if !query.From.IsZero() {
+		timestamp["$gte"] = query.From
+	}
+	if !query.To.IsZero() {
+		timestamp["$lte"] = query.To
+	}
+	if len(timestamp) > 0 {
+		filter["timestamp"] = timestamp
+	}
+
+	collection := config.GetCollection("oms_audit_logs")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cursor, err := collection.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}}).
+		SetSkip(int64((query.Page-1)*query.Limit)).
+		SetLimit(int64(query.Limit)))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	logs := []models.AuditLog{}
+	if err = cursor.All(ctx, &logs); err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
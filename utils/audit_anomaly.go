@@ -0,0 +1,372 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collections used by audit anomaly detection
+const (
+	AnomalySettingsCollection = "audit_anomaly_settings"
+	AuditAlertsCollection     = "audit_alerts"
+)
+
+// AuditAnomalySubject is the NATS subject alerts are published on
+const AuditAnomalySubject = "audit.anomaly"
+
+// DefaultAnomalySettings returns the thresholds used for organizations without settings
+func DefaultAnomalySettings(organizationID string) models.AnomalySettings {
+	return models.AnomalySettings{
+		OrganizationID:     organizationID,
+		MassDeletionCount:  20,
+		MassDeletionWindow: 10,
+		OffHoursEnabled:    true,
+		BusinessStartHour:  8,
+		BusinessEndHour:    20,
+		Timezone:           "UTC",
+		NewCountryEnabled:  true,
+	}
+}
+
+func init() {
+	RegisterEmailTemplate(EmailTemplate{
+		Name:    "audit_anomaly_alert",
+		Subject: "Security alert: {{.Rule}}",
+		HTML: `
+        <h1>Suspicious activity detected</h1>
+        <p>{{.Message}}</p>
+        <p>Detected at {{.CreatedAt.Format "Jan 2, 2006 15:04 MST"}}. Review the audit log for details.</p>
+    `,
+		SampleData: map[string]interface{}{
+			"Rule":      models.AnomalyMassDeletion,
+			"Message":   "Admin 123 performed 25 delete actions within 10 minutes",
+			"CreatedAt": time.Now(),
+		},
+	})
+}
+
+// GetAnomalySettings returns an organization's thresholds, falling back to defaults
+func GetAnomalySettings(organizationID string) (models.AnomalySettings, error) {
+	collection := config.GetCollection(AnomalySettingsCollection)
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	settings := DefaultAnomalySettings(organizationID)
+	err := collection.FindOne(ctx, bson.M{"organization_id": organizationID}).Decode(&settings)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return settings, err
+	}
+	return settings, nil
+}
+
+// SaveAnomalySettings upserts an organization's thresholds
+func SaveAnomalySettings(settings models.AnomalySettings) error {
+	settings.UpdatedAt = Now()
+	settings.ID = primitive.NilObjectID
+
+	collection := config.GetCollection(AnomalySettingsCollection)
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	_, err := collection.ReplaceOne(ctx,
+		bson.M{"organization_id": settings.OrganizationID},
+		settings,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+// AuditAnomalyAnalyzer scans new audit entries for suspicious patterns
+type AuditAnomalyAnalyzer struct {
+	checkpoint time.Time
+	mu         sync.Mutex
+}
+
+// StartAuditAnomalyAnalyzer analyzes audit entries every interval. Call the
+// returned function to stop it.
+func StartAuditAnomalyAnalyzer(interval time.Duration) func() {
+	analyzer := &AuditAnomalyAnalyzer{checkpoint: Now()}
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				if alerts, err := analyzer.Run(ctx); err != nil {
+					LogError(fmt.Sprintf("Audit anomaly analysis failed: %v", err))
+				} else if alerts > 0 {
+					log.Printf("🚨 Raised %d audit anomaly alerts", alerts)
+				}
+				cancel()
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		stopOnce.Do(func() { close(stop) })
+	}
+}
+
+// Run analyzes entries logged since the previous run and returns the number of alerts raised
+func (a *AuditAnomalyAnalyzer) Run(ctx context.Context) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	until := Now()
+	cursor, err := config.GetCollection("oms_audit_logs").Find(ctx, bson.M{
+		"timestamp": bson.M{"$gt": a.checkpoint, "$lte": until},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.AuditLog
+	if err = cursor.All(ctx, &entries); err != nil {
+		return 0, err
+	}
+
+	settingsByOrg := map[string]models.AnomalySettings{}
+	raised := 0
+	for _, entry := range entries {
+		organizationID := auditOrganizationID(entry)
+		settings, ok := settingsByOrg[organizationID]
+		if !ok {
+			if settings, err = GetAnomalySettings(organizationID); err != nil {
+				return raised, err
+			}
+			settingsByOrg[organizationID] = settings
+		}
+
+		for _, alert := range a.evaluate(ctx, entry, settings) {
+			if err := raiseAuditAlert(ctx, alert, settings); err != nil {
+				LogError(fmt.Sprintf("Failed to raise audit alert %s: %v", alert.Rule, err))
+				continue
+			}
+			raised++
+		}
+	}
+
+	a.checkpoint = until
+	return raised, nil
+}
+
+// evaluate applies every rule to one audit entry
+func (a *AuditAnomalyAnalyzer) evaluate(ctx context.Context, entry models.AuditLog, settings models.AnomalySettings) []models.AuditAlert {
+	var alerts []models.AuditAlert
+	organizationID := settings.OrganizationID
+
+	newAlert := func(rule, message string, details map[string]interface{}) models.AuditAlert {
+		return models.AuditAlert{
+			ID:             primitive.NewObjectID(),
+			OrganizationID: organizationID,
+			Rule:           rule,
+			AdminID:        entry.AdminID,
+			Message:        message,
+			Details:        details,
+			CreatedAt:      Now(),
+		}
+	}
+
+	// Mass deletions by the same admin within the window
+	if strings.Contains(entry.Action, "delete") && settings.MassDeletionCount > 0 {
+		window := time.Duration(settings.MassDeletionWindow) * time.Minute
+		filter := bson.M{
+			"admin_id":  entry.AdminID,
+			"action":    bson.M{"$regex": "delete"},
+			"timestamp": bson.M{"$gte": entry.Timestamp.Add(-window), "$lte": entry.Timestamp},
+		}
+		count, err := config.GetCollection("oms_audit_logs").CountDocuments(ctx, filter)
+		if err == nil && count >= int64(settings.MassDeletionCount) && !recentAlertExists(ctx, organizationID, models.AnomalyMassDeletion, entry.AdminID, window) {
+			alerts = append(alerts, newAlert(models.AnomalyMassDeletion,
+				fmt.Sprintf("Admin %s performed %d delete actions within %s", entry.AdminID, count, window),
+				map[string]interface{}{"count": count, "window_minutes": settings.MassDeletionWindow}))
+		}
+	}
+
+	// Admin actions outside business hours
+	if settings.OffHoursEnabled && entry.AdminID != "" && entry.AdminID != "system" {
+		hours := BusinessHours{
+			Location:  InTimezone(entry.Timestamp, settings.Timezone).Location(),
+			StartHour: settings.BusinessStartHour,
+			EndHour:   settings.BusinessEndHour,
+			Weekdays:  DefaultBusinessHours.Weekdays,
+		}
+		if !hours.Contains(entry.Timestamp) && !recentAlertExists(ctx, organizationID, models.AnomalyOffHours, entry.AdminID, time.Hour) {
+			alerts = append(alerts, newAlert(models.AnomalyOffHours,
+				fmt.Sprintf("Admin %s performed %s outside business hours", entry.AdminID, entry.Action),
+				map[string]interface{}{"action": entry.Action, "target_id": entry.TargetID}))
+		}
+	}
+
+	// Logins from a country not previously seen for this user
+	country, _ := entry.Metadata["country"].(string)
+	if settings.NewCountryEnabled && country != "" && strings.Contains(entry.Action, "login") {
+		count, err := config.GetCollection("oms_audit_logs").CountDocuments(ctx, bson.M{
+			"admin_id":         entry.AdminID,
+			"action":           entry.Action,
+			"metadata.country": country,
+			"timestamp":        bson.M{"$lt": entry.Timestamp},
+		})
+		previous, prevErr := config.GetCollection("oms_audit_logs").CountDocuments(ctx, bson.M{
+			"admin_id":  entry.AdminID,
+			"action":    entry.Action,
+			"timestamp": bson.M{"$lt": entry.Timestamp},
+		})
+		// Only alert when the user has a login history to compare against
+		if err == nil && prevErr == nil && count == 0 && previous > 0 {
+			alerts = append(alerts, newAlert(models.AnomalyNewCountry,
+				fmt.Sprintf("User %s logged in from a new country: %s", entry.AdminID, country),
+				map[string]interface{}{"country": country}))
+		}
+	}
+
+	return alerts
+}
+
+// recentAlertExists prevents repeated alerts for the same rule and admin within window
+func recentAlertExists(ctx context.Context, organizationID, rule, adminID string, window time.Duration) bool {
+	count, err := config.GetCollection(AuditAlertsCollection).CountDocuments(ctx, bson.M{
+		"organization_id": organizationID,
+		"rule":            rule,
+		"admin_id":        adminID,
+		"created_at":      bson.M{"$gte": Now().Add(-window)},
+	})
+	return err == nil && count > 0
+}
+
+// raiseAuditAlert stores an alert and dispatches it by email, webhook and NATS
+func raiseAuditAlert(ctx context.Context, alert models.AuditAlert, settings models.AnomalySettings) error {
+	if _, err := config.GetCollection(AuditAlertsCollection).InsertOne(ctx, alert); err != nil {
+		return err
+	}
+
+	for _, email := range settings.NotificationEmails {
+		if err := SendTemplatedEmail(email, "audit_anomaly_alert", alert); err != nil {
+			LogWarning(fmt.Sprintf("Failed to email audit alert to %s: %v", email, err))
+		}
+	}
+
+	if settings.WebhookURL != "" {
+		if err := postAlertWebhook(ctx, settings.WebhookURL, alert); err != nil {
+			LogWarning(fmt.Sprintf("Failed to deliver audit alert webhook: %v", err))
+		}
+	}
+
+	if config.NATS != nil {
+		if err := PublishEvent(AuditAnomalySubject, alert); err != nil {
+			LogWarning(fmt.Sprintf("Failed to publish audit alert: %v", err))
+		}
+	}
+
+	return nil
+}
+
+// postAlertWebhook delivers an alert, retrying network errors and 5xx responses
+func postAlertWebhook(ctx context.Context, url string, alert models.AuditAlert) error {
+	// Settings saved before URLs were validated may still hold unsafe ones
+	if err := ValidateWebhookURL(url); err != nil {
+		return err
+	}
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	return Retry(ctx, DefaultRetryPolicy, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return PermanentError(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := webhookClient.Do(req)
+		if errors.Is(err, ErrUnsafeWebhookURL) {
+			return PermanentError(err)
+		}
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+		}
+		if resp.StatusCode >= 300 {
+			return PermanentError(fmt.Errorf("webhook returned status %d", resp.StatusCode))
+		}
+		return nil
+	})
+}
+
+// ListAuditAlerts returns an organization's alerts, newest first
+func ListAuditAlerts(organizationID string, unacknowledgedOnly bool, limit int64) ([]models.AuditAlert, error) {
+	filter := bson.M{"organization_id": organizationID}
+	if unacknowledgedOnly {
+		filter["acknowledged"] = false
+	}
+
+	collection := config.GetCollection(AuditAlertsCollection)
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	alerts := []models.AuditAlert{}
+	if err = cursor.All(ctx, &alerts); err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// AcknowledgeAuditAlert marks an alert as handled
+func AcknowledgeAuditAlert(organizationID string, id primitive.ObjectID) (bool, error) {
+	collection := config.GetCollection(AuditAlertsCollection)
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "organization_id": organizationID},
+		bson.M{"$set": bson.M{"acknowledged": true}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// auditOrganizationID returns the organization an audit entry belongs to,
+// falling back to the metadata of entries logged before it was stored
+func auditOrganizationID(entry models.AuditLog) string {
+	if entry.OrganizationID != "" {
+		return entry.OrganizationID
+	}
+	organizationID, _ := entry.Metadata["organization_id"].(string)
+	return organizationID
+}
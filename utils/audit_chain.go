@@ -0,0 +1,326 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	storage "google.golang.org/api/storage/v1"
+)
+
+// AuditTamperedSubject is the NATS subject published when verification finds a broken chain
+const AuditTamperedSubject = "audit.tampered"
+
+// auditChainInsertAttempts bounds retries when concurrent writers race for the same sequence
+const auditChainInsertAttempts = 5
+
+var auditChain struct {
+	sync.Mutex
+	enabled bool
+	bucket  string
+	storage *storage.Service
+}
+
+// AuditChainReport describes the result of verifying the audit hash chain
+type AuditChainReport struct {
+	Checked        int64     `json:"checked"`
+	Valid          bool      `json:"valid"`
+	BrokenSequence int64     `json:"broken_sequence,omitempty"`
+	BrokenID       string    `json:"broken_id,omitempty"`
+	Reason         string    `json:"reason,omitempty"`
+	VerifiedAt     time.Time `json:"verified_at"`
+}
+
+// EnableAuditHashChain makes every new audit entry store the hash of the previous
+// one so tampering can be detected. When bucket is set, entries are also written
+// to that GCS bucket, which should carry a retention policy to make it append-only.
+func EnableAuditHashChain(bucket string) error {
+	collection := config.GetCollection("oms_audit_logs")
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	// The unique index lets concurrent instances detect sequence collisions
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "sequence", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+			"sequence": bson.M{"$exists": true},
+		}),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create audit sequence index: %w", err)
+	}
+
+	var service *storage.Service
+	if bucket != "" {
+		service, err = storage.NewService(context.Background())
+		if err != nil {
+			return fmt.Errorf("failed to create storage client: %w", err)
+		}
+	}
+
+	auditChain.Lock()
+	auditChain.enabled = true
+	auditChain.bucket = bucket
+	auditChain.storage = service
+	auditChain.Unlock()
+
+	if bucket != "" {
+		log.Printf("🔗 Audit hash chain enabled (archiving to gs://%s)", bucket)
+	} else {
+		log.Println("🔗 Audit hash chain enabled")
+	}
+	return nil
+}
+
+func auditChainEnabled() bool {
+	auditChain.Lock()
+	defer auditChain.Unlock()
+	return auditChain.enabled
+}
+
+// insertChainedAudit links entry to the latest chained entry and stores it
+func insertChainedAudit(ctx context.Context, collection *mongo.Collection, entry *models.AuditLog) error {
+	// Mongo stores milliseconds, so hash what will be read back
+	entry.Timestamp = entry.Timestamp.UTC().Truncate(time.Millisecond)
+
+	auditChain.Lock()
+	defer auditChain.Unlock()
+
+	for attempt := 1; ; attempt++ {
+		var last models.AuditLog
+		err := collection.FindOne(ctx,
+			bson.M{"sequence": bson.M{"$exists": true}},
+			options.FindOne().SetSort(bson.D{{Key: "sequence", Value: -1}}),
+		).Decode(&last)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return err
+		}
+
+		entry.Sequence = last.Sequence + 1
+		entry.PrevHash = last.Hash
+		if entry.Hash, err = AuditEntryHash(*entry); err != nil {
+			return err
+		}
+
+		_, err = collection.InsertOne(ctx, entry)
+		if err == nil {
+			break
+		}
+		// Another instance claimed this sequence; rebuild on top of its entry
+		if !mongo.IsDuplicateKeyError(err) || attempt == auditChainInsertAttempts {
+			return err
+		}
+	}
+
+	if auditChain.bucket != "" {
+		if err := archiveAuditEntry(ctx, *entry); err != nil {
+			LogWarning(fmt.Sprintf("Failed to archive audit entry %d: %v", entry.Sequence, err))
+		}
+	}
+	return nil
+}
+
+// archiveAuditEntry writes an entry to the append-only bucket, refusing to overwrite
+func archiveAuditEntry(ctx context.Context, entry models.AuditLog) error {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	object := &storage.Object{
+		Name:        fmt.Sprintf("audit/%012d-%s.json", entry.Sequence, entry.ID.Hex()),
+		ContentType: "application/json",
+	}
+	_, err = auditChain.storage.Objects.Insert(auditChain.bucket, object).
+		Media(bytes.NewReader(body)).
+		IfGenerationMatch(0).
+		Context(ctx).
+		Do()
+	return err
+}
+
+// AuditEntryHash computes the chain hash of an audit entry
+func AuditEntryHash(entry models.AuditLog) (string, error) {
+	metadata, err := canonicalAuditMetadata(entry.Metadata)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(struct {
+		Sequence  int64       `json:"sequence"`
+		PrevHash  string      `json:"prev_hash"`
+		ID        string      `json:"id"`
+		AdminID   string      `json:"admin_id"`
+		Action    string      `json:"action"`
+		TargetID  string      `json:"target_id"`
+		Metadata  interface{} `json:"metadata"`
+		Timestamp string      `json:"timestamp"`
+		// Omitted when empty so entries chained before it was stored still verify
+		OrganizationID string `json:"organization_id,omitempty"`
+	}{
+		Sequence:       entry.Sequence,
+		PrevHash:       entry.PrevHash,
+		ID:             entry.ID.Hex(),
+		AdminID:        entry.AdminID,
+		Action:         entry.Action,
+		TargetID:       entry.TargetID,
+		Metadata:       metadata,
+		Timestamp:      entry.Timestamp.UTC().Truncate(time.Millisecond).Format(time.RFC3339Nano),
+		OrganizationID: entry.OrganizationID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalAuditMetadata round-trips metadata through BSON so the hash computed
+// at write time matches the one computed from the stored document
+func canonicalAuditMetadata(metadata map[string]interface{}) (interface{}, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+
+	raw, err := bson.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+	var decoded bson.M
+	if err := bson.Unmarshal(raw, &decoded); err != nil {
+		return nil, err
+	}
+	return normalizeBSONValue(decoded), nil
+}
+
+// normalizeBSONValue converts decoded documents to maps so JSON encoding sorts keys
+func normalizeBSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case primitive.M:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			out[key] = normalizeBSONValue(item)
+		}
+		return out
+	case primitive.D:
+		out := make(map[string]interface{}, len(v))
+		for _, item := range v {
+			out[item.Key] = normalizeBSONValue(item.Value)
+		}
+		return out
+	case primitive.A:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = normalizeBSONValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// VerifyAuditChain walks the chained audit entries in order and reports the first broken link
+func VerifyAuditChain(ctx context.Context) (*AuditChainReport, error) {
+	collection := config.GetCollection("oms_audit_logs")
+	cursor, err := collection.Find(ctx,
+		bson.M{"sequence": bson.M{"$exists": true}},
+		options.Find().SetSort(bson.D{{Key: "sequence", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	report := &AuditChainReport{Valid: true, VerifiedAt: Now()}
+	broken := func(entry models.AuditLog, reason string) (*AuditChainReport, error) {
+		report.Valid = false
+		report.BrokenSequence = entry.Sequence
+		report.BrokenID = entry.ID.Hex()
+		report.Reason = reason
+		return report, nil
+	}
+
+	var previous models.AuditLog
+	for cursor.Next(ctx) {
+		var entry models.AuditLog
+		if err := cursor.Decode(&entry); err != nil {
+			return nil, err
+		}
+		report.Checked++
+
+		if entry.Sequence != previous.Sequence+1 {
+			return broken(entry, fmt.Sprintf("expected sequence %d", previous.Sequence+1))
+		}
+		if entry.PrevHash != previous.Hash {
+			return broken(entry, "previous hash does not match")
+		}
+		hash, err := AuditEntryHash(entry)
+		if err != nil {
+			return nil, err
+		}
+		if hash != entry.Hash {
+			return broken(entry, "entry hash does not match contents")
+		}
+		previous = entry
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// StartAuditChainVerifier verifies the audit chain every interval and publishes an
+// event when tampering is detected. Call the returned function to stop it.
+func StartAuditChainVerifier(interval time.Duration) func() {
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				report, err := VerifyAuditChain(ctx)
+				cancel()
+
+				if err != nil {
+					LogError(fmt.Sprintf("Audit chain verification failed: %v", err))
+					continue
+				}
+				if report.Valid {
+					continue
+				}
+
+				log.Printf("❌ Audit chain broken at sequence %d: %s", report.BrokenSequence, report.Reason)
+				if config.NATS != nil {
+					if err := PublishEvent(AuditTamperedSubject, report); err != nil {
+						LogWarning(fmt.Sprintf("Failed to publish audit tamper event: %v", err))
+					}
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		stopOnce.Do(func() { close(stop) })
+	}
+}
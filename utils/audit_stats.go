@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// AuditStatsCacheTTL controls how long aggregated audit stats are cached
+var AuditStatsCacheTTL = time.Minute
+
+// AuditStat is one bucket of an audit aggregation
+type AuditStat struct {
+	Key   string `bson:"_id" json:"key"`
+	Count int64  `bson:"count" json:"count"`
+}
+
+// auditGroupFields maps supported group_by values to aggregation keys
+var auditGroupFields = map[string]interface{}{
+	"action": "$action",
+	"admin":  "$admin_id",
+	"target": "$target_id",
+	"day":    bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$timestamp"}},
+}
+
+type cachedAuditStats struct {
+	stats    []AuditStat
+	loadedAt time.Time
+}
+
+var (
+	auditStatsCache    = map[string]cachedAuditStats{}
+	auditStatsCacheMux sync.Mutex
+)
+
+// AuditGroupings returns the supported group_by values
+func AuditGroupings() []string {
+	return []string{"action", "admin", "target", "day"}
+}
+
+// ParsePeriod parses durations like "24h", "7d" or "4w" into a time.Duration
+func ParsePeriod(period string) (time.Duration, error) {
+	period = strings.TrimSpace(period)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if value, found := strings.CutSuffix(period, suffix); found {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid period %q", period)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	duration, err := time.ParseDuration(period)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("invalid period %q", period)
+	}
+	return duration, nil
+}
+
+// GetAuditStats counts audit entries in the last period grouped by action, admin,
+// target or day, with extra match conditions from filter
+func GetAuditStats(groupBy string, period time.Duration, filter bson.M) ([]AuditStat, error) {
+	groupField, ok := auditGroupFields[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported group_by %q", groupBy)
+	}
+
+	cacheKey := fmt.Sprintf("%s|%s|%v", groupBy, period, filter)
+	auditStatsCacheMux.Lock()
+	cached, hit := auditStatsCache[cacheKey]
+	auditStatsCacheMux.Unlock()
+	if hit && time.Since(cached.loadedAt) < AuditStatsCacheTTL {
+		return cached.stats, nil
+	}
+
+	match := bson.M{"timestamp": bson.M{"$gte": Now().Add(-period)}}
+	for key, value := range filter {
+		match[key] = value
+	}
+
+	sort := bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}
+	if groupBy == "day" {
+		sort = bson.D{{Key: "_id", Value: 1}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{"_id": groupField, "count": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: sort}},
+	}
+
+	collection := config.GetCollection("oms_audit_logs")
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	stats := []AuditStat{}
+	if err = cursor.All(ctx, &stats); err != nil {
+		return nil, err
+	}
+
+	auditStatsCacheMux.Lock()
+	auditStatsCache[cacheKey] = cachedAuditStats{stats: stats, loadedAt: time.Now()}
+	auditStatsCacheMux.Unlock()
+
+	return stats, nil
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditTail is an open change stream of new audit entries. Change streams
+// need a replica set or sharded cluster.
+type AuditTail struct {
+	stream *mongo.ChangeStream
+}
+
+// TailAuditLogs watches for new audit entries matching filter, which uses the
+// field names of models.AuditLog as in GetAuditLogs. A resume token from an
+// earlier tail continues after the last entry it delivered.
+func TailAuditLogs(ctx context.Context, filter bson.M, resumeToken string) (*AuditTail, error) {
+	match := bson.M{"operationType": "insert"}
+	for key, value := range prefixAuditFilter(filter) {
+		match[key] = value
+	}
+
+	watchOptions := options.ChangeStream()
+	if resumeToken != "" {
+		watchOptions.SetResumeAfter(bson.M{"_data": resumeToken})
+	}
+
+	stream, err := config.GetCollection("oms_audit_logs").Watch(ctx,
+		mongo.Pipeline{{{Key: "$match", Value: match}}}, watchOptions)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditTail{stream: stream}, nil
+}
+
+// Next blocks until the next entry arrives and returns it with the token to
+// resume after it. It fails when ctx ends or the stream breaks.
+func (t *AuditTail) Next(ctx context.Context) (models.AuditLog, string, error) {
+	var change struct {
+		FullDocument models.AuditLog `bson:"fullDocument"`
+	}
+
+	if !t.stream.Next(ctx) {
+		err := t.stream.Err()
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err == nil {
+			err = io.EOF
+		}
+		return change.FullDocument, "", err
+	}
+	if err := t.stream.Decode(&change); err != nil {
+		return change.FullDocument, "", err
+	}
+
+	token, _ := t.stream.ResumeToken().Lookup("_data").StringValueOK()
+	return change.FullDocument, token, nil
+}
+
+// Close stops watching
+func (t *AuditTail) Close(ctx context.Context) error {
+	return t.stream.Close(ctx)
+}
+
+// prefixAuditFilter moves a filter on audit entries onto the fullDocument of
+// change events, including conditions nested in $or and $and
+func prefixAuditFilter(filter bson.M) bson.M {
+	prefixed := bson.M{}
+	for key, value := range filter {
+		if !strings.HasPrefix(key, "$") {
+			prefixed["fullDocument."+key] = value
+			continue
+		}
+		if clauses, ok := value.(bson.A); ok {
+			nested := bson.A{}
+			for _, clause := range clauses {
+				if clauseFilter, ok := clause.(bson.M); ok {
+					clause = prefixAuditFilter(clauseFilter)
+				}
+				nested = append(nested, clause)
+			}
+			value = nested
+		}
+		prefixed[key] = value
+	}
+	return prefixed
+}
@@ -0,0 +1,319 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BrandingCacheTTL controls how long organization branding is cached in memory
+var BrandingCacheTTL = 5 * time.Minute
+
+// MaxCachedBrandings bounds the branding cache
+var MaxCachedBrandings = 10000
+
+// Limits on branding text
+const (
+	MaxSenderNameLength  = 64
+	MaxEmailFooterLength = 1000
+)
+
+var hexColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+type cachedBranding struct {
+	branding models.Branding
+	loadedAt time.Time
+}
+
+var (
+	brandingCache    = map[string]cachedBranding{}
+	brandingCacheMux sync.RWMutex
+)
+
+// DefaultSenderName is the "From" name of emails without organization
+// branding, from EMAIL_SENDER_NAME
+func DefaultSenderName() string {
+	return config.GetEnv("EMAIL_SENDER_NAME", "Your App Name")
+}
+
+// DefaultBranding returns the branding used when an organization has none stored
+func DefaultBranding(organizationID string) models.Branding {
+	return models.Branding{
+		OrganizationID: organizationID,
+		SenderName:     DefaultSenderName(),
+	}
+}
+
+// GetOrganizationBranding returns an organization's branding, falling back to
+// defaults for unset fields. Only stored branding is cached, so lookups of
+// arbitrary organization IDs cannot grow the cache.
+func GetOrganizationBranding(organizationID string) (models.Branding, error) {
+	brandingCacheMux.RLock()
+	cached, ok := brandingCache[organizationID]
+	brandingCacheMux.RUnlock()
+	if ok && time.Since(cached.loadedAt) < BrandingCacheTTL {
+		return cached.branding, nil
+	}
+
+	collection := config.GetCollection(models.Branding{}.CollectionName())
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	branding := DefaultBranding(organizationID)
+	err := collection.FindOne(ctx, bson.M{"organization_id": organizationID}).Decode(&branding)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return DefaultBranding(organizationID), err
+	}
+	if branding.SenderName == "" {
+		branding.SenderName = DefaultSenderName()
+	}
+
+	if err == nil {
+		cacheBranding(branding)
+	}
+	return branding, nil
+}
+
+// UpdateOrganizationBranding validates and upserts an organization's branding
+func UpdateOrganizationBranding(branding models.Branding) (models.Branding, error) {
+	if err := validateBranding(&branding); err != nil {
+		return branding, err
+	}
+	branding.UpdatedAt = Now()
+
+	collection := config.GetCollection(models.Branding{}.CollectionName())
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"organization_id": branding.OrganizationID},
+		bson.M{"$set": bson.M{
+			"logo_url":      branding.LogoURL,
+			"primary_color": branding.PrimaryColor,
+			"accent_color":  branding.AccentColor,
+			"email_footer":  branding.EmailFooter,
+			"sender_name":   branding.SenderName,
+			"updated_by":    branding.UpdatedBy,
+			"updated_at":    branding.UpdatedAt,
+		}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&branding)
+	if err != nil {
+		return branding, err
+	}
+
+	if branding.SenderName == "" {
+		branding.SenderName = DefaultSenderName()
+	}
+	cacheBranding(branding)
+	return branding, nil
+}
+
+// DeleteOrganizationBranding removes stored branding so defaults apply again
+func DeleteOrganizationBranding(organizationID string) error {
+	collection := config.GetCollection(models.Branding{}.CollectionName())
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	_, err := collection.DeleteOne(ctx, bson.M{"organization_id": organizationID})
+	InvalidateOrganizationBranding(organizationID)
+	return err
+}
+
+// InvalidateOrganizationBranding drops an organization's cached branding
+func InvalidateOrganizationBranding(organizationID string) {
+	brandingCacheMux.Lock()
+	defer brandingCacheMux.Unlock()
+	delete(brandingCache, organizationID)
+}
+
+func cacheBranding(branding models.Branding) {
+	brandingCacheMux.Lock()
+	defer brandingCacheMux.Unlock()
+	if len(brandingCache) >= MaxCachedBrandings {
+		pruneBrandingCache()
+	}
+	brandingCache[branding.OrganizationID] = cachedBranding{branding: branding, loadedAt: time.Now()}
+}
+
+// pruneBrandingCache drops expired branding, or all of it when none has
+// expired; the caller holds brandingCacheMux
+func pruneBrandingCache() {
+	for organizationID, cached := range brandingCache {
+		if time.Since(cached.loadedAt) >= BrandingCacheTTL {
+			delete(brandingCache, organizationID)
+		}
+	}
+	if len(brandingCache) >= MaxCachedBrandings {
+		brandingCache = map[string]cachedBranding{}
+	}
+}
+
+// validateBranding trims the fields of branding and checks them; the sender
+// name ends up in a mail header, so line breaks are rejected
+func validateBranding(branding *models.Branding) error {
+	branding.LogoURL = strings.TrimSpace(branding.LogoURL)
+	branding.PrimaryColor = strings.TrimSpace(branding.PrimaryColor)
+	branding.AccentColor = strings.TrimSpace(branding.AccentColor)
+	branding.EmailFooter = strings.TrimSpace(branding.EmailFooter)
+	branding.SenderName = strings.TrimSpace(branding.SenderName)
+
+	if branding.LogoURL != "" {
+		parsed, err := url.Parse(branding.LogoURL)
+		if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			return fmt.Errorf("%w: logo_url must be an https URL", ErrInvalidBranding)
+		}
+	}
+	for name, color := range map[string]string{"primary_color": branding.PrimaryColor, "accent_color": branding.AccentColor} {
+		if color != "" && !hexColorPattern.MatchString(color) {
+			return fmt.Errorf("%w: %s must be a hex color such as #1a73e8", ErrInvalidBranding, name)
+		}
+	}
+	if utf8.RuneCountInString(branding.SenderName) > MaxSenderNameLength || strings.ContainsAny(branding.SenderName, "\r\n") {
+		return fmt.Errorf("%w: sender_name must be one line of at most %d characters", ErrInvalidBranding, MaxSenderNameLength)
+	}
+	if utf8.RuneCountInString(branding.EmailFooter) > MaxEmailFooterLength {
+		return fmt.Errorf("%w: email_footer must be at most %d characters", ErrInvalidBranding, MaxEmailFooterLength)
+	}
+	return nil
+}
+
+// brandedLayout frames a rendered email body with the logo, colors and footer
+var brandedLayout = template.Must(template.New("branded_layout").Parse(`<div style="max-width:600px;margin:0 auto;font-family:Arial,sans-serif;{{if .Brand.PrimaryColor}}border-top:4px solid {{.Brand.PrimaryColor}};{{end}}">
+{{if .Brand.LogoURL}}<div style="padding:16px 0"><img src="{{.Brand.LogoURL}}" alt="{{.Brand.SenderName}}" style="max-height:48px"></div>{{end}}
+{{.Body}}
+{{if .Brand.EmailFooter}}<p style="margin-top:32px;color:#6b7280;font-size:12px;white-space:pre-line">{{.Brand.EmailFooter}}</p>{{end}}
+</div>`))
+
+// RenderBrandedEmail renders a registered template for an organization: the
+// data gains a Brand entry for templates that style themselves, and the body
+// is framed with the organization's logo and footer
+func RenderBrandedEmail(branding models.Branding, templateName string, data map[string]interface{}) (string, string, error) {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	data["Brand"] = branding
+
+	subject, body, err := RenderEmailTemplate(templateName, data)
+	if err != nil {
+		return "", "", err
+	}
+
+	var framed bytes.Buffer
+	err = brandedLayout.Execute(&framed, map[string]interface{}{
+		"Brand": branding,
+		"Body":  template.HTML(body), // Already escaped by its own template
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render branded layout of %s: %w", templateName, err)
+	}
+	return subject, framed.String(), nil
+}
+
+// SendBrandedEmail renders a registered template with an organization's
+// branding and sends it under the organization's sender name. Branding that
+// fails to load falls back to the defaults rather than dropping the email.
+func SendBrandedEmail(organizationID, email, templateName string, data map[string]interface{}) error {
+	branding, err := GetOrganizationBranding(organizationID)
+	if err != nil {
+		LogWarning(fmt.Sprintf("Failed to load branding of organization %s: %v", organizationID, err))
+	}
+
+	subject, htmlContent, err := RenderBrandedEmail(branding, templateName, data)
+	if err != nil {
+		return err
+	}
+	return sendEmail(branding.SenderName, email, subject, htmlContent)
+}
+
+// brandingBundleItem is the part of an organization's branding that is
+// promoted between environments
+type brandingBundleItem struct {
+	LogoURL      string `json:"logo_url,omitempty"`
+	PrimaryColor string `json:"primary_color,omitempty"`
+	AccentColor  string `json:"accent_color,omitempty"`
+	EmailFooter  string `json:"email_footer,omitempty"`
+	SenderName   string `json:"sender_name,omitempty"`
+}
+
+// Organization branding takes part in config bundles, keyed by organization
+// ID. Organizations of one environment are missing from the others, so
+// pruning never removes branding.
+func init() {
+	config.RegisterBundleSection("branding", config.BundleSection{
+		Export:      exportBrandingBundle,
+		Apply:       applyBrandingBundle,
+		KeepMissing: true,
+	})
+}
+
+func exportBrandingBundle(ctx context.Context) (map[string]json.RawMessage, error) {
+	cursor, err := config.GetCollection(models.Branding{}.CollectionName()).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	items := map[string]json.RawMessage{}
+	for cursor.Next(ctx) {
+		var branding models.Branding
+		if err := cursor.Decode(&branding); err != nil {
+			return nil, err
+		}
+		item, err := json.Marshal(brandingBundleItem{
+			LogoURL:      branding.LogoURL,
+			PrimaryColor: branding.PrimaryColor,
+			AccentColor:  branding.AccentColor,
+			EmailFooter:  branding.EmailFooter,
+			SenderName:   branding.SenderName,
+		})
+		if err != nil {
+			return nil, err
+		}
+		items[branding.OrganizationID] = item
+	}
+	return items, cursor.Err()
+}
+
+func applyBrandingBundle(ctx context.Context, changes []config.BundleChange) error {
+	for _, change := range changes {
+		if change.Kind == config.BundleRemoved {
+			if err := DeleteOrganizationBranding(change.Key); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var item brandingBundleItem
+		if err := json.Unmarshal(change.Bundle, &item); err != nil {
+			return fmt.Errorf("%w: branding of %s: %v", config.ErrInvalidBundle, change.Key, err)
+		}
+		_, err := UpdateOrganizationBranding(models.Branding{
+			OrganizationID: change.Key,
+			LogoURL:        item.LogoURL,
+			PrimaryColor:   item.PrimaryColor,
+			AccentColor:    item.AccentColor,
+			EmailFooter:    item.EmailFooter,
+			SenderName:     item.SenderName,
+			UpdatedBy:      "system",
+		})
+		if err != nil {
+			return fmt.Errorf("branding of %s: %w", change.Key, err)
+		}
+	}
+	return nil
+}
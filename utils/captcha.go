@@ -0,0 +1,245 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/breaker"
+	"github.com/praleedsuvarna/shared-libs/config"
+)
+
+// Captcha providers, selected with CAPTCHA_PROVIDER
+const (
+	CaptchaRecaptcha           = "recaptcha"            // reCAPTCHA v3
+	CaptchaRecaptchaEnterprise = "recaptcha_enterprise" // reCAPTCHA Enterprise assessments
+	CaptchaHCaptcha            = "hcaptcha"
+)
+
+// DefaultCaptchaMinScore is the lowest score accepted when CAPTCHA_MIN_SCORE
+// is not set
+const DefaultCaptchaMinScore = 0.5
+
+var (
+	ErrCaptchaNotConfigured = errors.New("captcha is not configured")
+	ErrCaptchaRequired      = errors.New("captcha token is required")
+	ErrCaptchaFailed        = errors.New("captcha verification failed")
+)
+
+// Verification endpoints of the providers
+const (
+	recaptchaVerifyURL     = "https://www.google.com/recaptcha/api/siteverify"
+	recaptchaEnterpriseURL = "https://recaptchaenterprise.googleapis.com/v1/projects/%s/assessments?key=%s"
+	hcaptchaVerifyURL      = "https://api.hcaptcha.com/siteverify"
+)
+
+// captchaBreaker stops calling the provider while it is failing; rejected
+// tokens do not count as failures
+var captchaBreaker = breaker.New("captcha", breaker.Options{})
+
+var captchaClient = &http.Client{Timeout: 5 * time.Second}
+
+// CaptchaResult is what the provider reported on a token. Score runs from 0 (a bot) to 1
+// (a person) for every provider; it is 1 when the provider gives none, as for
+// hCaptcha outside Enterprise.
+type CaptchaResult struct {
+	Provider string  `json:"provider"`
+	Score    float64 `json:"score"`
+	Action   string  `json:"action,omitempty"`
+	Hostname string  `json:"hostname,omitempty"`
+}
+
+// CaptchaProvider returns the configured provider, empty when captcha is off
+func CaptchaProvider() string {
+	return strings.ToLower(config.GetEnv("CAPTCHA_PROVIDER", ""))
+}
+
+// CaptchaMinScore returns the threshold of an action: CAPTCHA_MIN_SCORES
+// holds per action overrides as "login:0.7,register:0.5", CAPTCHA_MIN_SCORE
+// the default
+func CaptchaMinScore(action string) float64 {
+	for _, entry := range strings.Split(config.GetEnv("CAPTCHA_MIN_SCORES", ""), ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || name != action {
+			continue
+		}
+		if score, err := strconv.ParseFloat(value, 64); err == nil {
+			return score
+		}
+	}
+	if score, err := strconv.ParseFloat(config.GetEnv("CAPTCHA_MIN_SCORE", ""), 64); err == nil {
+		return score
+	}
+	return DefaultCaptchaMinScore
+}
+
+// VerifyCaptcha checks a captcha token with the configured provider. The
+// token must have been issued for action (where the provider reports actions)
+// and score at least CaptchaMinScore(action). Rejections wrap
+// ErrCaptchaFailed; other errors mean the provider could not be asked.
+func VerifyCaptcha(ctx context.Context, token, action string) (*CaptchaResult, error) {
+	provider := CaptchaProvider()
+	if provider == "" {
+		return nil, ErrCaptchaNotConfigured
+	}
+	if token == "" {
+		return nil, ErrCaptchaRequired
+	}
+	secret, err := config.GetSecret("captcha-secret", "CAPTCHA_SECRET")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCaptchaNotConfigured, err)
+	}
+
+	var result *CaptchaResult
+	switch provider {
+	case CaptchaRecaptcha:
+		result, err = verifyRecaptcha(ctx, secret, token)
+	case CaptchaRecaptchaEnterprise:
+		result, err = verifyRecaptchaEnterprise(ctx, secret, token, action)
+	case CaptchaHCaptcha:
+		result, err = verifyHCaptcha(ctx, secret, token)
+	default:
+		return nil, fmt.Errorf("%w: unknown provider %q", ErrCaptchaNotConfigured, provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if action != "" && result.Action != "" && result.Action != action {
+		return result, fmt.Errorf("%w: token was issued for action %q", ErrCaptchaFailed, result.Action)
+	}
+	if minScore := CaptchaMinScore(action); result.Score < minScore {
+		return result, fmt.Errorf("%w: score %.2f is below %.2f", ErrCaptchaFailed, result.Score, minScore)
+	}
+	return result, nil
+}
+
+// siteVerifyResponse is the reply of the reCAPTCHA and hCaptcha siteverify APIs
+type siteVerifyResponse struct {
+	Success    bool     `json:"success"`
+	Score      *float64 `json:"score"`
+	Action     string   `json:"action"`
+	Hostname   string   `json:"hostname"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+func verifyRecaptcha(ctx context.Context, secret, token string) (*CaptchaResult, error) {
+	var response siteVerifyResponse
+	if err := postCaptcha(ctx, recaptchaVerifyURL, url.Values{"secret": {secret}, "response": {token}}, &response); err != nil {
+		return nil, err
+	}
+	if !response.Success {
+		return nil, fmt.Errorf("%w: %s", ErrCaptchaFailed, strings.Join(response.ErrorCodes, ", "))
+	}
+
+	result := &CaptchaResult{Provider: CaptchaRecaptcha, Score: 1, Action: response.Action, Hostname: response.Hostname}
+	if response.Score != nil {
+		result.Score = *response.Score
+	}
+	return result, nil
+}
+
+func verifyHCaptcha(ctx context.Context, secret, token string) (*CaptchaResult, error) {
+	form := url.Values{"secret": {secret}, "response": {token}}
+	if siteKey := config.GetEnv("CAPTCHA_SITE_KEY", ""); siteKey != "" {
+		form.Set("sitekey", siteKey)
+	}
+
+	var response siteVerifyResponse
+	if err := postCaptcha(ctx, hcaptchaVerifyURL, form, &response); err != nil {
+		return nil, err
+	}
+	if !response.Success {
+		return nil, fmt.Errorf("%w: %s", ErrCaptchaFailed, strings.Join(response.ErrorCodes, ", "))
+	}
+
+	result := &CaptchaResult{Provider: CaptchaHCaptcha, Score: 1, Hostname: response.Hostname}
+	if response.Score != nil {
+		// hCaptcha Enterprise scores risk: 0 is safe, 1 is a bot
+		result.Score = 1 - *response.Score
+	}
+	return result, nil
+}
+
+// verifyRecaptchaEnterprise creates an assessment; the secret is an API key
+// of CAPTCHA_PROJECT
+func verifyRecaptchaEnterprise(ctx context.Context, apiKey, token, action string) (*CaptchaResult, error) {
+	project := config.GetEnv("CAPTCHA_PROJECT", "")
+	siteKey := config.GetEnv("CAPTCHA_SITE_KEY", "")
+	if project == "" || siteKey == "" {
+		return nil, fmt.Errorf("%w: CAPTCHA_PROJECT and CAPTCHA_SITE_KEY are required", ErrCaptchaNotConfigured)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"event": map[string]string{"token": token, "siteKey": siteKey, "expectedAction": action},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var assessment struct {
+		TokenProperties struct {
+			Valid         bool   `json:"valid"`
+			InvalidReason string `json:"invalidReason"`
+			Action        string `json:"action"`
+			Hostname      string `json:"hostname"`
+		} `json:"tokenProperties"`
+		RiskAnalysis struct {
+			Score float64 `json:"score"`
+		} `json:"riskAnalysis"`
+	}
+	endpoint := fmt.Sprintf(recaptchaEnterpriseURL, url.PathEscape(project), url.QueryEscape(apiKey))
+	err = captchaBreaker.Execute(func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return doCaptchaRequest(req, &assessment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !assessment.TokenProperties.Valid {
+		return nil, fmt.Errorf("%w: %s", ErrCaptchaFailed, assessment.TokenProperties.InvalidReason)
+	}
+
+	return &CaptchaResult{
+		Provider: CaptchaRecaptchaEnterprise,
+		Score:    assessment.RiskAnalysis.Score,
+		Action:   assessment.TokenProperties.Action,
+		Hostname: assessment.TokenProperties.Hostname,
+	}, nil
+}
+
+// postCaptcha posts a siteverify form through the breaker
+func postCaptcha(ctx context.Context, endpoint string, form url.Values, out interface{}) error {
+	return captchaBreaker.Execute(func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return doCaptchaRequest(req, out)
+	})
+}
+
+func doCaptchaRequest(req *http.Request, out interface{}) error {
+	resp, err := captchaClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("captcha provider returned status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,20 @@
+package utils
+
+import "context"
+
+type clientIPContextKey struct{}
+
+// WithClientIP returns a copy of ctx carrying the request's client IP, as
+// derived by middleware.RealIP
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPContextKey{}, ip)
+}
+
+// ClientIPFromContext returns the client IP stored by WithClientIP, or ""
+func ClientIPFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	ip, _ := ctx.Value(clientIPContextKey{}).(string)
+	return ip
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+)
+
+// Audit action and NATS subject used for configuration changes
+const (
+	AuditActionConfigChanged = "config_changed"
+	ConfigChangedSubject     = "config.changed"
+)
+
+var configAuditOnce sync.Once
+
+// EnableConfigChangeAudit records an audit entry and publishes a NATS event for every
+// configuration value change detected by config.Watch or config.Refresh
+func EnableConfigChangeAudit() {
+	configAuditOnce.Do(func() {
+		config.OnChange(func(change config.ConfigChange) {
+			LogAuditWithMetadata("system", AuditActionConfigChanged, change.Key, map[string]interface{}{
+				"old_hash": change.OldHash,
+				"new_hash": change.NewHash,
+				"source":   change.Source,
+			})
+
+			if config.NATS == nil {
+				return
+			}
+			if err := PublishEvent(ConfigChangedSubject, change); err != nil {
+				LogWarning(fmt.Sprintf("Failed to publish config change for %s: %v", change.Key, err))
+			}
+		})
+	})
+}
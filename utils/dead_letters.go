@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"context"
+	"errors"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DeadLettersCollection stores messages that exhausted their retries
+const DeadLettersCollection = "messaging_dead_letters"
+
+// ErrDeadLetterNotFound is returned when a dead letter does not exist
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetterFilter narrows ListDeadLetters; zero values match everything
+type DeadLetterFilter struct {
+	Subject  string
+	Replayed *bool
+	Limit    int64
+}
+
+// SaveDeadLetter records a dead-lettered message
+func SaveDeadLetter(ctx context.Context, deadLetter models.DeadLetter) error {
+	if deadLetter.CreatedAt.IsZero() {
+		deadLetter.CreatedAt = Now()
+	}
+
+	_, err := config.GetCollection(DeadLettersCollection).InsertOne(ctx, deadLetter)
+	return err
+}
+
+// ListDeadLetters returns dead letters newest first, without their envelopes
+func ListDeadLetters(filter DeadLetterFilter) ([]models.DeadLetter, error) {
+	query := bson.M{}
+	if filter.Subject != "" {
+		query["subject"] = filter.Subject
+	}
+	if filter.Replayed != nil {
+		if *filter.Replayed {
+			query["replay_count"] = bson.M{"$gt": 0}
+		} else {
+			query["replay_count"] = 0
+		}
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 50
+	}
+
+	collection := config.GetCollection(DeadLettersCollection)
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, query, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(filter.Limit).
+		SetProjection(bson.M{"envelope": 0}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	deadLetters := []models.DeadLetter{}
+	if err = cursor.All(ctx, &deadLetters); err != nil {
+		return nil, err
+	}
+	return deadLetters, nil
+}
+
+// GetDeadLetter returns a dead letter including its envelope
+func GetDeadLetter(id primitive.ObjectID) (*models.DeadLetter, error) {
+	collection := config.GetCollection(DeadLettersCollection)
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	var deadLetter models.DeadLetter
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&deadLetter)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrDeadLetterNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &deadLetter, nil
+}
+
+// MarkDeadLetterReplayed records that adminID replayed a dead letter
+func MarkDeadLetterReplayed(id primitive.ObjectID, adminID string) error {
+	collection := config.GetCollection(DeadLettersCollection)
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$inc": bson.M{"replay_count": 1},
+		"$set": bson.M{"last_replayed_at": Now(), "last_replayed_by": adminID},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrDeadLetterNotFound
+	}
+	return nil
+}
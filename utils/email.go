@@ -6,10 +6,12 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/sendgrid/sendgrid-go"
-	"github.com/sendgrid/sendgrid-go/helpers/mail"
+	"github.com/praleedsuvarna/shared-libs/breaker"
 )
 
+// sendGridBreaker stops hammering SendGrid while it is failing
+var sendGridBreaker = breaker.New("sendgrid", breaker.Options{})
+
 // GenerateEmailVerificationToken creates a secure random token
 func GenerateEmailVerificationToken() string {
 	b := make([]byte, 32)
@@ -19,30 +21,13 @@ func GenerateEmailVerificationToken() string {
 
 // SendVerificationEmail sends an email with verification link
 func SendVerificationEmail(email, verificationToken string) error {
-	from := mail.NewEmail("Your App Name", os.Getenv("SENDER_EMAIL"))
-	subject := "Verify Your Email"
-	to := mail.NewEmail("", email)
-
 	// Construct verification link
 	verificationLink := fmt.Sprintf("%s/verify-email?token=%s",
 		os.Getenv("FRONTEND_URL"),
 		verificationToken,
 	)
 
-	// HTML Content
-	htmlContent := fmt.Sprintf(`
-        <h1>Verify Your Email</h1>
-        <p>Click the link below to verify your email address:</p>
-        <a href="%s">Verify Email</a>
-        <p>If you did not create an account, please ignore this email.</p>
-    `, verificationLink)
-
-	message := mail.NewSingleEmail(from, subject, to, "", htmlContent)
-
-	// Create a new SendGrid client
-	client := sendgrid.NewSendClient(os.Getenv("SENDGRID_API_KEY"))
-
-	// Send the email
-	_, err := client.Send(message)
-	return err
+	return SendTemplatedEmail(email, "email_verification", map[string]interface{}{
+		"Link": verificationLink,
+	})
 }
@@ -0,0 +1,258 @@
+package utils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/breaker"
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/sendgrid/sendgrid-go"
+	"github.com/sendgrid/sendgrid-go/helpers/mail"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/time/rate"
+)
+
+// Collections used for email delivery tracking
+const (
+	EmailEventsCollection       = "email_events"
+	EmailSuppressionsCollection = "email_suppressions"
+)
+
+// Email delivery errors
+var (
+	ErrEmailSuppressed       = errors.New("email address is suppressed")
+	ErrInvalidWebhookSigning = errors.New("invalid webhook signature")
+)
+
+// suppressingEvents are SendGrid events after which an address must not be emailed again
+var suppressingEvents = map[string]bool{
+	"bounce":            true,
+	"dropped":           true,
+	"spamreport":        true,
+	"unsubscribe":       true,
+	"group_unsubscribe": true,
+}
+
+var (
+	emailLimiter     *rate.Limiter
+	emailLimiterOnce sync.Once
+)
+
+// deliverEmail sends a message through SendGrid, honouring the suppression list,
+// a process-wide send rate (EMAIL_RATE_PER_SECOND, default 10) and retrying
+// throttled or failed requests
+func deliverEmail(message *mail.SGMailV3, recipient string) error {
+	if IsEmailSuppressed(recipient) {
+		return fmt.Errorf("%w: %s", ErrEmailSuppressed, recipient)
+	}
+
+	emailLimiterOnce.Do(func() {
+		perSecond, err := strconv.ParseFloat(config.GetEnv("EMAIL_RATE_PER_SECOND", "10"), 64)
+		if err != nil || perSecond <= 0 {
+			perSecond = 10
+		}
+		emailLimiter = rate.NewLimiter(rate.Limit(perSecond), int(perSecond)+1)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	client := sendgrid.NewSendClient(os.Getenv("SENDGRID_API_KEY"))
+
+	return Retry(ctx, emailRetryPolicy, func() error {
+		if err := emailLimiter.Wait(ctx); err != nil {
+			return PermanentError(err)
+		}
+
+		return sendGridBreaker.Execute(func() error {
+			resp, err := client.Send(message)
+			if err != nil {
+				return err
+			}
+			if resp.StatusCode == 429 || resp.StatusCode >= 500 {
+				return fmt.Errorf("sendgrid returned status %d", resp.StatusCode)
+			}
+			if resp.StatusCode >= 400 {
+				return PermanentError(fmt.Errorf("sendgrid rejected message with status %d: %s", resp.StatusCode, resp.Body))
+			}
+			return nil
+		})
+	})
+}
+
+// emailRetryPolicy retries network errors, throttling and SendGrid outages
+var emailRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Second,
+	Jitter:         0.2,
+	Retryable: func(err error) bool {
+		return !errors.Is(err, breaker.ErrOpen)
+	},
+}
+
+// IsEmailSuppressed reports whether an address hard-bounced, complained or unsubscribed.
+// Without a database connection no address is considered suppressed.
+func IsEmailSuppressed(email string) bool {
+	if config.DB == nil {
+		return false
+	}
+
+	collection := config.GetCollection(EmailSuppressionsCollection)
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	count, err := collection.CountDocuments(ctx, bson.M{"email": strings.ToLower(email)})
+	if err != nil {
+		LogWarning(fmt.Sprintf("Suppression check failed for %s: %v", email, err))
+		return false
+	}
+	return count > 0
+}
+
+// ListEmailSuppressions returns suppressed addresses, newest first
+func ListEmailSuppressions(limit int64) ([]models.EmailSuppression, error) {
+	collection := config.GetCollection(EmailSuppressionsCollection)
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var suppressions []models.EmailSuppression
+	if err = cursor.All(ctx, &suppressions); err != nil {
+		return nil, err
+	}
+	return suppressions, nil
+}
+
+// RemoveEmailSuppression allows sending to an address again
+func RemoveEmailSuppression(email string) (bool, error) {
+	collection := config.GetCollection(EmailSuppressionsCollection)
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	result, err := collection.DeleteOne(ctx, bson.M{"email": strings.ToLower(email)})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// SendGridEvent is one entry of a SendGrid event webhook payload
+type SendGridEvent struct {
+	Email     string `json:"email"`
+	Event     string `json:"event"`
+	Timestamp int64  `json:"timestamp"`
+	EventID   string `json:"sg_event_id"`
+	MessageID string `json:"sg_message_id"`
+	Type      string `json:"type"`
+	Reason    string `json:"reason"`
+	Status    string `json:"status"`
+}
+
+// VerifySendGridSignature checks the ECDSA signature SendGrid attaches to event
+// webhooks, using the base64 public key from SENDGRID_WEBHOOK_PUBLIC_KEY
+func VerifySendGridSignature(payload []byte, signature, timestamp string) error {
+	publicKeyValue := config.GetEnv("SENDGRID_WEBHOOK_PUBLIC_KEY", "")
+	if publicKeyValue == "" {
+		return fmt.Errorf("%w: SENDGRID_WEBHOOK_PUBLIC_KEY not configured", ErrInvalidWebhookSigning)
+	}
+
+	der, err := base64.StdEncoding.DecodeString(publicKeyValue)
+	if err != nil {
+		return fmt.Errorf("invalid SendGrid public key: %w", err)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return fmt.Errorf("invalid SendGrid public key: %w", err)
+	}
+	publicKey, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("SendGrid public key is not an ECDSA key")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidWebhookSigning
+	}
+
+	digest := sha256.Sum256(append([]byte(timestamp), payload...))
+	if !ecdsa.VerifyASN1(publicKey, digest[:], sig) {
+		return ErrInvalidWebhookSigning
+	}
+	return nil
+}
+
+// RecordSendGridEvents stores delivery events idempotently and suppresses
+// addresses that bounced, were dropped, complained or unsubscribed
+func RecordSendGridEvents(events []SendGridEvent) error {
+	eventsCollection := config.GetCollection(EmailEventsCollection)
+	suppressions := config.GetCollection(EmailSuppressionsCollection)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	for _, event := range events {
+		email := strings.ToLower(event.Email)
+		record := models.EmailEvent{
+			EventID:    event.EventID,
+			MessageID:  event.MessageID,
+			Email:      email,
+			Event:      event.Event,
+			Type:       event.Type,
+			Reason:     event.Reason,
+			Status:     event.Status,
+			OccurredAt: time.Unix(event.Timestamp, 0),
+			ReceivedAt: now,
+		}
+
+		// SendGrid retries deliveries, so the event ID makes the insert idempotent
+		_, err := eventsCollection.UpdateOne(ctx,
+			bson.M{"sg_event_id": event.EventID},
+			bson.M{"$setOnInsert": record},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return err
+		}
+
+		// Soft bounces ("blocked") are temporary and do not suppress the address
+		if !suppressingEvents[event.Event] || (event.Event == "bounce" && event.Type == "blocked") {
+			continue
+		}
+
+		_, err = suppressions.UpdateOne(ctx,
+			bson.M{"email": email},
+			bson.M{"$setOnInsert": models.EmailSuppression{
+				Email:     email,
+				Event:     event.Event,
+				Reason:    event.Reason,
+				CreatedAt: now,
+			}},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"sort"
+	"sync"
+	texttemplate "text/template"
+
+	"github.com/sendgrid/sendgrid-go/helpers/mail"
+)
+
+// EmailTemplate is a named subject/body pair; the subject is rendered as plain text
+// and the body with html/template escaping
+type EmailTemplate struct {
+	Name       string
+	Subject    string
+	HTML       string
+	SampleData map[string]interface{} // Used for previews
+}
+
+var (
+	emailTemplates   = map[string]EmailTemplate{}
+	emailTemplateMux sync.RWMutex
+)
+
+func init() {
+	RegisterEmailTemplate(EmailTemplate{
+		Name:    "email_verification",
+		Subject: "Verify Your Email",
+		HTML: `
+        <h1>Verify Your Email</h1>
+        <p>Click the link below to verify your email address:</p>
+        <a href="{{.Link}}">Verify Email</a>
+        <p>If you did not create an account, please ignore this email.</p>
+    `,
+		SampleData: map[string]interface{}{"Link": "https://example.com/verify-email?token=sample"},
+	})
+}
+
+// RegisterEmailTemplate adds or replaces a template; it panics if the template does not parse
+func RegisterEmailTemplate(tmpl EmailTemplate) {
+	texttemplate.Must(texttemplate.New(tmpl.Name + ".subject").Parse(tmpl.Subject))
+	template.Must(template.New(tmpl.Name).Parse(tmpl.HTML))
+
+	emailTemplateMux.Lock()
+	defer emailTemplateMux.Unlock()
+	emailTemplates[tmpl.Name] = tmpl
+}
+
+// GetEmailTemplate returns a registered template by name
+func GetEmailTemplate(name string) (EmailTemplate, bool) {
+	emailTemplateMux.RLock()
+	defer emailTemplateMux.RUnlock()
+	tmpl, ok := emailTemplates[name]
+	return tmpl, ok
+}
+
+// ListEmailTemplates returns the names of all registered templates
+func ListEmailTemplates() []string {
+	emailTemplateMux.RLock()
+	defer emailTemplateMux.RUnlock()
+
+	names := make([]string, 0, len(emailTemplates))
+	for name := range emailTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RenderEmailTemplate renders the subject and HTML body of a registered template
+func RenderEmailTemplate(name string, data interface{}) (string, string, error) {
+	tmpl, ok := GetEmailTemplate(name)
+	if !ok {
+		return "", "", fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+
+	var subject, body bytes.Buffer
+	if err := texttemplate.Must(texttemplate.New(name+".subject").Parse(tmpl.Subject)).Execute(&subject, data); err != nil {
+		return "", "", fmt.Errorf("failed to render subject of %s: %w", name, err)
+	}
+	if err := template.Must(template.New(name).Parse(tmpl.HTML)).Execute(&body, data); err != nil {
+		return "", "", fmt.Errorf("failed to render body of %s: %w", name, err)
+	}
+
+	return subject.String(), body.String(), nil
+}
+
+// PreviewEmailTemplate renders a template with its sample data overlaid by data
+func PreviewEmailTemplate(name string, data map[string]interface{}) (string, string, error) {
+	tmpl, ok := GetEmailTemplate(name)
+	if !ok {
+		return "", "", fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+
+	merged := map[string]interface{}{}
+	for key, value := range tmpl.SampleData {
+		merged[key] = value
+	}
+	for key, value := range data {
+		merged[key] = value
+	}
+
+	return RenderEmailTemplate(name, merged)
+}
+
+// SendTestEmail sends a preview of a template to an address with a "[Test]" subject prefix
+func SendTestEmail(email, templateName string, data map[string]interface{}) error {
+	subject, htmlContent, err := PreviewEmailTemplate(templateName, data)
+	if err != nil {
+		return err
+	}
+
+	return sendEmail(DefaultSenderName(), email, "[Test] "+subject, htmlContent)
+}
+
+// SendTemplatedEmail renders a registered template and sends it via SendGrid
+func SendTemplatedEmail(email, templateName string, data interface{}) error {
+	subject, htmlContent, err := RenderEmailTemplate(templateName, data)
+	if err != nil {
+		return err
+	}
+
+	return sendEmail(DefaultSenderName(), email, subject, htmlContent)
+}
+
+// sendEmail sends a rendered email from SENDER_EMAIL under fromName
+func sendEmail(fromName, email, subject, htmlContent string) error {
+	from := mail.NewEmail(fromName, os.Getenv("SENDER_EMAIL"))
+	to := mail.NewEmail("", email)
+	message := mail.NewSingleEmail(from, subject, to, "", htmlContent)
+
+	return deliverEmail(message, email)
+}
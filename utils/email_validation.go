@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Email validation errors
+var (
+	ErrInvalidEmail    = errors.New("invalid email address")
+	ErrDisposableEmail = errors.New("disposable email addresses are not allowed")
+	ErrEmailNoMX       = errors.New("email domain cannot receive mail")
+)
+
+// EmailValidationOptions controls optional checks in ValidateEmail
+type EmailValidationOptions struct {
+	AllowDisposable bool
+	CheckMX         bool
+	MXTimeout       time.Duration // Default 3s
+}
+
+//go:embed disposable_domains.txt
+var disposableDomainList string
+
+var (
+	disposableDomains    = map[string]bool{}
+	disposableDomainsMux sync.RWMutex
+)
+
+func init() {
+	scanner := bufio.NewScanner(strings.NewReader(disposableDomainList))
+	var domains []string
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			domains = append(domains, line)
+		}
+	}
+	RegisterDisposableDomains(domains...)
+}
+
+// RegisterDisposableDomains adds domains to the disposable blocklist
+func RegisterDisposableDomains(domains ...string) {
+	disposableDomainsMux.Lock()
+	defer disposableDomainsMux.Unlock()
+	for _, domain := range domains {
+		disposableDomains[strings.ToLower(domain)] = true
+	}
+}
+
+// IsDisposableEmail reports whether the address uses a known disposable provider
+func IsDisposableEmail(email string) bool {
+	domain := strings.ToLower(ExtractDomain(email))
+
+	disposableDomainsMux.RLock()
+	defer disposableDomainsMux.RUnlock()
+
+	// Match subdomains of listed providers as well
+	for domain != "" {
+		if disposableDomains[domain] {
+			return true
+		}
+		_, parent, found := strings.Cut(domain, ".")
+		if !found {
+			break
+		}
+		domain = parent
+	}
+	return false
+}
+
+// ValidateEmailSyntax checks that email is a bare, well-formed address
+func ValidateEmailSyntax(email string) error {
+	if len(email) > 254 {
+		return fmt.Errorf("%w: too long", ErrInvalidEmail)
+	}
+
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email || address.Name != "" {
+		return ErrInvalidEmail
+	}
+
+	local, domain, _ := strings.Cut(email, "@")
+	if len(local) > 64 {
+		return fmt.Errorf("%w: local part too long", ErrInvalidEmail)
+	}
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return fmt.Errorf("%w: invalid domain", ErrInvalidEmail)
+	}
+	return nil
+}
+
+// NormalizeEmail lowercases an address and canonicalizes Gmail addresses by
+// removing dots and "+tag" suffixes from the local part
+func NormalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	local, domain, found := strings.Cut(email, "@")
+	if !found {
+		return email
+	}
+
+	if domain == "gmail.com" || domain == "googlemail.com" {
+		local, _, _ = strings.Cut(local, "+")
+		local = strings.ReplaceAll(local, ".", "")
+		domain = "gmail.com"
+	}
+	return local + "@" + domain
+}
+
+// HasMXRecords reports whether the domain can receive mail, accepting an A/AAAA
+// record as the implicit MX when no MX records exist
+func HasMXRecords(ctx context.Context, domain string) (bool, error) {
+	records, err := net.DefaultResolver.LookupMX(ctx, domain)
+	if err == nil && len(records) > 0 {
+		// A single "." MX is a null MX: the domain explicitly accepts no mail
+		return !(len(records) == 1 && records[0].Host == "."), nil
+	}
+
+	var dnsErr *net.DNSError
+	if err != nil && (!errors.As(err, &dnsErr) || !dnsErr.IsNotFound) {
+		return false, err
+	}
+
+	hosts, err := net.DefaultResolver.LookupHost(ctx, domain)
+	if err != nil {
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return len(hosts) > 0, nil
+}
+
+// ValidateEmail validates and normalizes an address for registration. DNS
+// failures other than "not found" do not reject the address.
+func ValidateEmail(ctx context.Context, email string, options EmailValidationOptions) (string, error) {
+	email = strings.TrimSpace(email)
+	if err := ValidateEmailSyntax(email); err != nil {
+		return "", err
+	}
+
+	if !options.AllowDisposable && IsDisposableEmail(email) {
+		return "", ErrDisposableEmail
+	}
+
+	if options.CheckMX {
+		timeout := options.MXTimeout
+		if timeout == 0 {
+			timeout = 3 * time.Second
+		}
+		mxCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		ok, err := HasMXRecords(mxCtx, strings.ToLower(ExtractDomain(email)))
+		if err != nil {
+			LogWarning(fmt.Sprintf("MX lookup failed for %s: %v", ExtractDomain(email), err))
+		} else if !ok {
+			return "", ErrEmailNoMX
+		}
+	}
+
+	return NormalizeEmail(email), nil
+}
@@ -0,0 +1,13 @@
+package utils
+
+import "errors"
+
+// General sentinel errors returned by utils; feature-specific errors live next
+// to their feature (e.g. ErrInvitationNotFound). Match them with errors.Is.
+var (
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrTemplateNotFound   = errors.New("email template not registered")
+	ErrInvalidPreferences = errors.New("invalid preferences")
+	ErrInvalidBranding    = errors.New("invalid branding")
+	ErrInvalidObjectID    = errors.New("invalid ObjectID")
+)
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/praleedsuvarna/shared-libs/config"
+)
+
+// natsPublishRetryPolicy retries transient publish failures such as a full
+// reconnect buffer; a closed connection will not recover by retrying
+var natsPublishRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 100 * time.Millisecond,
+	Jitter:         0.2,
+	Retryable: func(err error) bool {
+		return !errors.Is(err, nats.ErrConnectionClosed)
+	},
+}
+
+// PublishEvent marshals payload as JSON and publishes it on a NATS subject
+func PublishEvent(subject string, payload interface{}) error {
+	if config.NATS == nil {
+		return fmt.Errorf("publish %s: nats: %w", subject, config.ErrNotConnected)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event for %s: %w", subject, err)
+	}
+
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	return Retry(ctx, natsPublishRetryPolicy, func() error {
+		return config.NATS.Publish(subject, data)
+	})
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// GeoLocation is the result of a GeoIP lookup
+type GeoLocation struct {
+	IP          string  `json:"ip"`
+	Country     string  `json:"country,omitempty"` // ISO 3166-1 alpha-2 code
+	CountryName string  `json:"country_name,omitempty"`
+	City        string  `json:"city,omitempty"`
+	Latitude    float64 `json:"latitude,omitempty"`
+	Longitude   float64 `json:"longitude,omitempty"`
+}
+
+type geoLocationContextKey struct{}
+
+var (
+	geoIPReader *geoip2.Reader
+	geoIPMux    sync.RWMutex
+)
+
+// OpenGeoIP loads a MaxMind GeoIP2/GeoLite2 City or Country database. Lookups
+// are disabled until a database is opened.
+func OpenGeoIP(path string) error {
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return fmt.Errorf("open geoip database %s: %w", path, err)
+	}
+
+	geoIPMux.Lock()
+	previous := geoIPReader
+	geoIPReader = reader
+	geoIPMux.Unlock()
+
+	if previous != nil {
+		previous.Close()
+	}
+
+	log.Printf("🌍 GeoIP database loaded: %s (%s)", path, reader.Metadata().DatabaseType)
+	return nil
+}
+
+// CloseGeoIP releases the GeoIP database
+func CloseGeoIP() {
+	geoIPMux.Lock()
+	defer geoIPMux.Unlock()
+
+	if geoIPReader != nil {
+		geoIPReader.Close()
+		geoIPReader = nil
+	}
+}
+
+// GeoIPEnabled reports whether a GeoIP database is loaded
+func GeoIPEnabled() bool {
+	geoIPMux.RLock()
+	defer geoIPMux.RUnlock()
+	return geoIPReader != nil
+}
+
+// LookupGeoIP resolves the country and city of an IP address. It returns nil
+// without error when no database is loaded or the address is not found.
+func LookupGeoIP(ip string) (*GeoLocation, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return nil, fmt.Errorf("invalid IP address %q", ip)
+	}
+
+	geoIPMux.RLock()
+	defer geoIPMux.RUnlock()
+	if geoIPReader == nil {
+		return nil, nil
+	}
+
+	// City databases are a superset of Country databases; fall back for the latter
+	record, err := geoIPReader.City(parsed)
+	if err != nil {
+		country, countryErr := geoIPReader.Country(parsed)
+		if countryErr != nil {
+			return nil, fmt.Errorf("geoip lookup %s: %w", ip, err)
+		}
+		return &GeoLocation{
+			IP:          ip,
+			Country:     country.Country.IsoCode,
+			CountryName: country.Country.Names["en"],
+		}, nil
+	}
+
+	if record.Country.IsoCode == "" {
+		return nil, nil
+	}
+	return &GeoLocation{
+		IP:          ip,
+		Country:     record.Country.IsoCode,
+		CountryName: record.Country.Names["en"],
+		City:        record.City.Names["en"],
+		Latitude:    record.Location.Latitude,
+		Longitude:   record.Location.Longitude,
+	}, nil
+}
+
+// WithGeoLocation returns a copy of ctx carrying the request's location
+func WithGeoLocation(ctx context.Context, location *GeoLocation) context.Context {
+	return context.WithValue(ctx, geoLocationContextKey{}, location)
+}
+
+// GeoLocationFromContext returns the location stored by WithGeoLocation, or nil
+func GeoLocationFromContext(ctx context.Context) *GeoLocation {
+	if ctx == nil {
+		return nil
+	}
+	location, _ := ctx.Value(geoLocationContextKey{}).(*GeoLocation)
+	return location
+}
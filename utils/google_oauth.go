@@ -5,10 +5,14 @@ import (
 	"encoding/json"
 	"os"
 
+	"github.com/praleedsuvarna/shared-libs/breaker"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 )
 
+// googleOAuthBreaker guards calls to the Google userinfo endpoint
+var googleOAuthBreaker = breaker.New("google_oauth", breaker.Options{})
+
 // GetGoogleOAuthConfig creates and returns a oauth2 configuration
 func GetGoogleOAuthConfig() *oauth2.Config {
 	return &oauth2.Config{
@@ -29,14 +33,17 @@ func FetchGoogleUserInfo(accessToken string) (*GoogleUserInfo, error) {
 		&oauth2.Token{AccessToken: accessToken},
 	))
 
-	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
 	var userInfo GoogleUserInfo
-	if err := json.NewDecoder(resp.Body).Decode(&userInfo); err != nil {
+	err := googleOAuthBreaker.Execute(func() error {
+		resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		return json.NewDecoder(resp.Body).Decode(&userInfo)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -16,13 +16,7 @@ func HashPassword(password string) (string, error) {
 	}
 
 	// Step 2: Encode to base64 string
-	encodedHash := base64.StdEncoding.EncodeToString(hashedBytes)
-
-	// For debugging
-	fmt.Printf("HashPassword: Original: %q, Hashed length: %d, Encoded: %q\n",
-		password, len(hashedBytes), encodedHash)
-
-	return encodedHash, nil
+	return base64.StdEncoding.EncodeToString(hashedBytes), nil
 }
 
 // ComparePasswords compares a hashed password with a plaintext password
@@ -2,9 +2,11 @@ package utils
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"time"
 
+	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -14,6 +16,9 @@ func GetContext() (context.Context, context.CancelFunc) {
 }
 
 // ToObjectID converts a string ID to MongoDB ObjectID
+//
+// Deprecated: invalid input yields NilObjectID, which silently matches nothing
+// in queries. Use ToObjectIDE, MustObjectID or ObjectIDParam.
 func ToObjectID(id string) primitive.ObjectID {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -22,6 +27,42 @@ func ToObjectID(id string) primitive.ObjectID {
 	return objID
 }
 
+// ToObjectIDE converts a string ID to MongoDB ObjectID, returning
+// ErrInvalidObjectID for anything but 24 hex characters
+func ToObjectIDE(id string) (primitive.ObjectID, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("%w: %q", ErrInvalidObjectID, id)
+	}
+	return objID, nil
+}
+
+// MustObjectID converts a string ID known to be valid, such as a constant, and
+// panics otherwise
+func MustObjectID(id string) primitive.ObjectID {
+	objID, err := ToObjectIDE(id)
+	if err != nil {
+		panic(err)
+	}
+	return objID
+}
+
+// ObjectIDParam reads an ObjectID route parameter. An invalid value returns a
+// 400 fiber.Error for the handler to return as is; the app error handler
+// renders it as {"error": "Invalid <name>"}:
+//
+//	id, err := utils.ObjectIDParam(c, "ruleId")
+//	if err != nil {
+//		return err
+//	}
+func ObjectIDParam(c *fiber.Ctx, name string) (primitive.ObjectID, error) {
+	objID, err := ToObjectIDE(c.Params(name))
+	if err != nil {
+		return primitive.NilObjectID, fiber.NewError(fiber.StatusBadRequest, "Invalid "+name)
+	}
+	return objID, nil
+}
+
 // ExtractDomain extracts the domain from an email address
 func ExtractDomain(email string) string {
 	parts := strings.Split(email, "@")
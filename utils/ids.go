@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ulidEntropy    = ulid.Monotonic(rand.Reader, 0)
+	ulidEntropyMux sync.Mutex
+)
+
+// NewID returns a new ULID: 26 URL-safe characters that sort by creation time
+func NewID() string {
+	ulidEntropyMux.Lock()
+	defer ulidEntropyMux.Unlock()
+	return ulid.MustNew(ulid.Timestamp(Now()), ulidEntropy).String()
+}
+
+// IsValidID reports whether id is a well-formed ULID
+func IsValidID(id string) bool {
+	_, err := ulid.ParseStrict(id)
+	return err == nil
+}
+
+// IDTime returns the creation time encoded in a ULID
+func IDTime(id string) (time.Time, error) {
+	parsed, err := ulid.ParseStrict(id)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid ULID %q: %w", id, err)
+	}
+	return ulid.Time(parsed.Time()), nil
+}
+
+// ObjectIDToULID converts an ObjectID into a ULID carrying the same timestamp and
+// bytes, so ObjectIDToULID followed by ULIDToObjectID returns the original ObjectID
+func ObjectIDToULID(oid primitive.ObjectID) string {
+	var id ulid.ULID
+	if err := id.SetTime(ulid.Timestamp(oid.Timestamp())); err != nil {
+		return ""
+	}
+	// The 8 bytes after the ObjectID timestamp become the leading entropy bytes
+	copy(id[6:], oid[4:])
+	return id.String()
+}
+
+// ULIDToObjectID converts a ULID into an ObjectID. Millisecond precision and the
+// last two entropy bytes are dropped, so only ULIDs produced by ObjectIDToULID
+// round-trip exactly.
+func ULIDToObjectID(id string) (primitive.ObjectID, error) {
+	parsed, err := ulid.ParseStrict(id)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("invalid ULID %q: %w", id, err)
+	}
+
+	var oid primitive.ObjectID
+	binary.BigEndian.PutUint32(oid[0:4], uint32(parsed.Time()/1000))
+	copy(oid[4:], parsed[6:14])
+	return oid, nil
+}
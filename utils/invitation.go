@@ -0,0 +1,255 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collections used by the invitation workflow
+const (
+	InvitationsCollection = "organization_invitations"
+	MembershipsCollection = "organization_memberships"
+)
+
+// InvitationTTL is how long an invitation token stays valid
+var InvitationTTL = 7 * 24 * time.Hour
+
+// Invitation workflow errors
+var (
+	ErrInvitationNotFound = errors.New("invitation not found")
+	ErrInvitationExpired  = errors.New("invitation expired")
+	ErrInvitationUsed     = errors.New("invitation is no longer pending")
+	ErrAlreadyMember      = errors.New("user is already a member of the organization")
+)
+
+func init() {
+	RegisterEmailTemplate(EmailTemplate{
+		Name:    "organization_invitation",
+		Subject: "You've been invited to join {{.OrganizationName}}",
+		HTML: `
+        <h1>You're invited!</h1>
+        <p>You have been invited to join <strong>{{.OrganizationName}}</strong> as {{.Role}}.</p>
+        <a href="{{.Link}}">Accept Invitation</a>
+        <p>This invitation expires on {{.ExpiresAt.Format "Jan 2, 2006"}}. If you were not expecting it, please ignore this email.</p>
+    `,
+		SampleData: map[string]interface{}{
+			"OrganizationName": "Acme Studios",
+			"Role":             "editor",
+			"Link":             "https://example.com/accept-invitation?token=sample",
+			"ExpiresAt":        Now().Add(InvitationTTL),
+		},
+	})
+}
+
+// CreateInvitation stores a pending invitation and emails the invite link
+func CreateInvitation(organizationID, organizationName, email, role, invitedBy string) (*models.Invitation, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" || role == "" {
+		return nil, fmt.Errorf("email and role are required")
+	}
+
+	token := GenerateEmailVerificationToken()
+	now := Now()
+	invitation := models.Invitation{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: organizationID,
+		Email:          email,
+		Role:           role,
+		TokenHash:      hashInvitationToken(token),
+		Status:         models.InvitationPending,
+		InvitedBy:      invitedBy,
+		ExpiresAt:      now.Add(InvitationTTL),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	collection := config.GetCollection(InvitationsCollection)
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	if _, err := collection.InsertOne(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	if err := sendInvitationEmail(&invitation, organizationName, token); err != nil {
+		return &invitation, fmt.Errorf("invitation created but email failed: %w", err)
+	}
+
+	LogAudit(invitedBy, "invitation_created", invitation.ID.Hex())
+	return &invitation, nil
+}
+
+// ListInvitations returns the invitations of an organization, optionally filtered by status
+func ListInvitations(organizationID, status string) ([]models.Invitation, error) {
+	filter := bson.M{"organization_id": organizationID}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	collection := config.GetCollection(InvitationsCollection)
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var invitations []models.Invitation
+	if err = cursor.All(ctx, &invitations); err != nil {
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+// ResendInvitation issues a fresh token and expiry for a pending invitation and emails it again
+func ResendInvitation(organizationID, organizationName string, invitationID primitive.ObjectID, adminID string) (*models.Invitation, error) {
+	token := GenerateEmailVerificationToken()
+	now := Now()
+
+	collection := config.GetCollection(InvitationsCollection)
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	var invitation models.Invitation
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": invitationID, "organization_id": organizationID, "status": models.InvitationPending},
+		bson.M{"$set": bson.M{
+			"token_hash": hashInvitationToken(token),
+			"expires_at": now.Add(InvitationTTL),
+			"updated_at": now,
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&invitation)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrInvitationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sendInvitationEmail(&invitation, organizationName, token); err != nil {
+		return &invitation, fmt.Errorf("invitation updated but email failed: %w", err)
+	}
+
+	LogAudit(adminID, "invitation_resent", invitation.ID.Hex())
+	return &invitation, nil
+}
+
+// RevokeInvitation cancels a pending invitation
+func RevokeInvitation(organizationID string, invitationID primitive.ObjectID, adminID string) error {
+	collection := config.GetCollection(InvitationsCollection)
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": invitationID, "organization_id": organizationID, "status": models.InvitationPending},
+		bson.M{"$set": bson.M{"status": models.InvitationRevoked, "updated_at": Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrInvitationNotFound
+	}
+
+	LogAudit(adminID, "invitation_revoked", invitationID.Hex())
+	return nil
+}
+
+// AcceptInvitation redeems an invitation token for userID and creates the membership
+func AcceptInvitation(token, userID string) (*models.Membership, error) {
+	collection := config.GetCollection(InvitationsCollection)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var invitation models.Invitation
+	err := collection.FindOne(ctx, bson.M{"token_hash": hashInvitationToken(token)}).Decode(&invitation)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrInvitationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if invitation.Status != models.InvitationPending {
+		return nil, ErrInvitationUsed
+	}
+	if Now().After(invitation.ExpiresAt) {
+		return nil, ErrInvitationExpired
+	}
+
+	memberships := config.GetCollection(MembershipsCollection)
+	count, err := memberships.CountDocuments(ctx, bson.M{"organization_id": invitation.OrganizationID, "user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrAlreadyMember
+	}
+
+	// Claim the invitation atomically so a token cannot be redeemed twice
+	now := Now()
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": invitation.ID, "status": models.InvitationPending},
+		bson.M{"$set": bson.M{
+			"status":      models.InvitationAccepted,
+			"accepted_by": userID,
+			"accepted_at": now,
+			"updated_at":  now,
+		}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if result.ModifiedCount == 0 {
+		return nil, ErrInvitationUsed
+	}
+
+	membership := models.Membership{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: invitation.OrganizationID,
+		UserID:         userID,
+		Role:           invitation.Role,
+		InvitationID:   invitation.ID,
+		CreatedAt:      now,
+	}
+	if _, err := memberships.InsertOne(ctx, membership); err != nil {
+		return nil, err
+	}
+
+	LogAudit(userID, "invitation_accepted", invitation.ID.Hex())
+	return &membership, nil
+}
+
+// sendInvitationEmail emails the accept link for an invitation
+func sendInvitationEmail(invitation *models.Invitation, organizationName, token string) error {
+	link := fmt.Sprintf("%s/accept-invitation?token=%s", os.Getenv("FRONTEND_URL"), token)
+
+	return SendBrandedEmail(invitation.OrganizationID, invitation.Email, "organization_invitation", map[string]interface{}{
+		"OrganizationName": organizationName,
+		"Role":             invitation.Role,
+		"Link":             link,
+		"ExpiresAt":        invitation.ExpiresAt,
+	})
+}
+
+// hashInvitationToken stores tokens as SHA-256 so a database leak does not expose them
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
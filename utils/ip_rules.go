@@ -0,0 +1,241 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// IPRulesCollection stores CIDR allow/deny rules
+const IPRulesCollection = "ip_rules"
+
+// IPRulesCacheTTL controls how long Mongo rules are cached before reloading
+var IPRulesCacheTTL = time.Minute
+
+// ErrInvalidIPRule is returned for rules with a bad CIDR, action or scope
+var ErrInvalidIPRule = errors.New("invalid ip rule")
+
+var (
+	ipRulesCache    []models.IPRule
+	ipRulesLoadedAt time.Time
+	ipRulesMux      sync.RWMutex
+)
+
+// ParsePrefix parses a CIDR or a bare IP address, which is treated as a single-host range
+func ParsePrefix(value string) (netip.Prefix, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+// ParsePrefixList parses a comma-separated list of CIDRs, skipping invalid entries with a warning
+func ParsePrefixList(value string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		prefix, err := ParsePrefix(entry)
+		if err != nil {
+			LogWarning(fmt.Sprintf("Ignoring invalid CIDR %q: %v", entry, err))
+			continue
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes
+}
+
+// CheckIP reports whether ip may access scope. Deny rules win; when any allow rule
+// applies to the scope the address must match one of them. Rules come from
+// IP_ALLOWLIST/IP_DENYLIST (all scopes), IP_ALLOWLIST_<SCOPE>/IP_DENYLIST_<SCOPE>
+// and the ip_rules collection.
+func CheckIP(ip, scope string) (bool, error) {
+	return checkIP(ip, scope, false)
+}
+
+// CheckIPAllowlisted is CheckIP for scopes that must be closed by default:
+// only allow rules for scope itself, not global ones, admit an address, and
+// without any no address is permitted
+func CheckIPAllowlisted(ip, scope string) (bool, error) {
+	return checkIP(ip, scope, true)
+}
+
+func checkIP(ip, scope string, requireAllow bool) (bool, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false, fmt.Errorf("%w: client address %q", ErrInvalidIPRule, ip)
+	}
+	addr = addr.Unmap()
+
+	rules, err := cachedIPRules()
+	if err != nil {
+		return false, err
+	}
+	rules = append(rules, envIPRules(scope)...)
+
+	hasAllow, allowed := false, false
+	for _, rule := range rules {
+		if rule.Scope != "*" && rule.Scope != scope {
+			continue
+		}
+		prefix, err := ParsePrefix(rule.CIDR)
+		if err != nil {
+			continue
+		}
+
+		matched := prefix.Contains(addr)
+		if rule.Action == models.IPRuleDeny && matched {
+			return false, nil
+		}
+		if rule.Action == models.IPRuleAllow {
+			if requireAllow && rule.Scope != scope {
+				// Global allow rules admit ordinary traffic, not closed scopes
+				continue
+			}
+			hasAllow = true
+			allowed = allowed || matched
+		}
+	}
+
+	if !hasAllow {
+		return !requireAllow, nil
+	}
+	return allowed, nil
+}
+
+// envIPRules builds rules from the IP_ALLOWLIST/IP_DENYLIST environment variables
+func envIPRules(scope string) []models.IPRule {
+	var rules []models.IPRule
+	add := func(key, action, ruleScope string) {
+		for _, prefix := range ParsePrefixList(config.GetEnv(key, "")) {
+			rules = append(rules, models.IPRule{CIDR: prefix.String(), Action: action, Scope: ruleScope})
+		}
+	}
+
+	add("IP_ALLOWLIST", models.IPRuleAllow, "*")
+	add("IP_DENYLIST", models.IPRuleDeny, "*")
+	if scope != "" && scope != "*" {
+		suffix := "_" + strings.ToUpper(strings.ReplaceAll(scope, "-", "_"))
+		add("IP_ALLOWLIST"+suffix, models.IPRuleAllow, scope)
+		add("IP_DENYLIST"+suffix, models.IPRuleDeny, scope)
+	}
+	return rules
+}
+
+// cachedIPRules returns stored rules, reloading after IPRulesCacheTTL. Without a
+// database connection only environment rules apply.
+func cachedIPRules() ([]models.IPRule, error) {
+	if config.DB == nil {
+		return nil, nil
+	}
+
+	ipRulesMux.RLock()
+	if ipRulesCache != nil && time.Since(ipRulesLoadedAt) < IPRulesCacheTTL {
+		rules := append([]models.IPRule{}, ipRulesCache...)
+		ipRulesMux.RUnlock()
+		return rules, nil
+	}
+	ipRulesMux.RUnlock()
+
+	rules, err := ListIPRules()
+	if err != nil {
+		return nil, err
+	}
+
+	ipRulesMux.Lock()
+	ipRulesCache = rules
+	ipRulesLoadedAt = time.Now()
+	ipRulesMux.Unlock()
+
+	return append([]models.IPRule{}, rules...), nil
+}
+
+// ListIPRules returns all stored IP rules
+func ListIPRules() ([]models.IPRule, error) {
+	collection := config.GetCollection(IPRulesCollection)
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rules := []models.IPRule{}
+	if err = cursor.All(ctx, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// CreateIPRule validates and stores a rule, invalidating the cache
+func CreateIPRule(rule models.IPRule) (models.IPRule, error) {
+	prefix, err := ParsePrefix(rule.CIDR)
+	if err != nil {
+		return rule, fmt.Errorf("%w: %w", ErrInvalidIPRule, err)
+	}
+	if rule.Action != models.IPRuleAllow && rule.Action != models.IPRuleDeny {
+		return rule, fmt.Errorf("%w: action must be %q or %q", ErrInvalidIPRule, models.IPRuleAllow, models.IPRuleDeny)
+	}
+	if rule.Scope == "" {
+		rule.Scope = "*"
+	}
+
+	rule.ID = primitive.NewObjectID()
+	rule.CIDR = prefix.String()
+	rule.CreatedAt = Now()
+
+	collection := config.GetCollection(IPRulesCollection)
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	if _, err := collection.InsertOne(ctx, rule); err != nil {
+		return rule, err
+	}
+
+	InvalidateIPRules()
+	return rule, nil
+}
+
+// DeleteIPRule removes a rule by ID, invalidating the cache
+func DeleteIPRule(id primitive.ObjectID) (bool, error) {
+	collection := config.GetCollection(IPRulesCollection)
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return false, err
+	}
+
+	InvalidateIPRules()
+	return result.DeletedCount > 0, nil
+}
+
+// InvalidateIPRules forces the next check to reload rules from Mongo
+func InvalidateIPRules() {
+	ipRulesMux.Lock()
+	defer ipRulesMux.Unlock()
+	ipRulesCache = nil
+	ipRulesLoadedAt = time.Time{}
+}
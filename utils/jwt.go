@@ -8,12 +8,18 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
+// Token expiry is checked against the package clock, so SetClock moves
+// validation together with generation
+func init() {
+	jwt.TimeFunc = Now
+}
+
 // GenerateToken creates a JWT token for a user
 func GenerateToken(userID string, role string) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,
 		"role":    role,
-		"exp":     time.Now().Add(time.Hour * 72).Unix(),
+		"exp":     Now().Add(time.Hour * 72).Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
@@ -27,8 +33,8 @@ func GenerateTokenPair(userID string, organizationID string, role string) (strin
 		"organization_id": organizationID,
 		"role":            role,
 		"type":            "access",
-		"exp":             time.Now().Add(time.Hour * 1).Unix(), // Short-lived access token
-		"iat":             time.Now().Unix(),
+		"exp":             Now().Add(time.Hour * 1).Unix(), // Short-lived access token
+		"iat":             Now().Unix(),
 	}
 	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessTokenClaims)
 	accessTokenString, err := accessToken.SignedString([]byte(os.Getenv("JWT_SECRET")))
@@ -40,7 +46,7 @@ func GenerateTokenPair(userID string, organizationID string, role string) (strin
 	refreshTokenClaims := jwt.MapClaims{
 		"user_id": userID,
 		"type":    "refresh",
-		"exp":     time.Now().Add(time.Hour * 24 * 7).Unix(), // Longer-lived refresh token
+		"exp":     Now().Add(time.Hour * 24 * 7).Unix(), // Longer-lived refresh token
 	}
 	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshTokenClaims)
 	refreshTokenString, err := refreshToken.SignedString([]byte(os.Getenv("JWT_SECRET")))
@@ -58,17 +64,17 @@ func VerifyRefreshToken(tokenString string) (*jwt.Token, jwt.MapClaims, error) {
 	})
 
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok || !token.Valid {
-		return nil, nil, fmt.Errorf("invalid refresh token")
+		return nil, nil, fmt.Errorf("%w: refresh token failed validation", ErrInvalidToken)
 	}
 
 	// Additional check to ensure it's a refresh token
 	if claims["type"] != "refresh" {
-		return nil, nil, fmt.Errorf("not a refresh token")
+		return nil, nil, fmt.Errorf("%w: not a refresh token", ErrInvalidToken)
 	}
 
 	return token, claims, nil
@@ -0,0 +1,234 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+)
+
+// LogControlSubject carries LogControl messages to every instance, see
+// messaging.SubscribeLogControl
+const LogControlSubject = "logging.control"
+
+// Debug logging is verbose and may record request details, so it always
+// reverts: after DefaultDebugLogDuration unless a shorter duration is asked
+// for, and never later than MaxDebugLogDuration
+const (
+	DefaultDebugLogDuration = 15 * time.Minute
+	MaxDebugLogDuration     = time.Hour
+)
+
+// ErrInvalidLogSettings is returned for unknown levels and sample rates outside 0 to 1
+var ErrInvalidLogSettings = errors.New("invalid log settings")
+
+var (
+	logLevel      = new(slog.LevelVar)
+	logSampleRate atomic.Uint64 // math.Float64bits of the rate, 1 until configured
+
+	logSettingsMu    sync.Mutex
+	logSettingsUntil *time.Time
+	logRevertTimer   *time.Timer
+)
+
+func init() {
+	logSampleRate.Store(math.Float64bits(1))
+}
+
+// LogSettings is the runtime state of the base Logger
+type LogSettings struct {
+	Level      string     `json:"level"`       // debug, info, warn or error
+	SampleRate float64    `json:"sample_rate"` // Share of debug and info records written; warnings and errors always are
+	Until      *time.Time `json:"until,omitempty"`
+}
+
+// LogControl asks instances to change their log settings. Duration (e.g.
+// "5m") reverts them to the configured baseline afterwards; Service limits
+// the change to instances of one service.
+type LogControl struct {
+	Level      string   `json:"level,omitempty"` // Empty keeps the current level
+	SampleRate *float64 `json:"sample_rate,omitempty"`
+	Duration   string   `json:"duration,omitempty"`
+	Service    string   `json:"service,omitempty"`
+	Reset      bool     `json:"reset,omitempty"` // Return to the baseline, ignoring the other fields
+}
+
+// ConfigureLogging sets the baseline level and sampling of the base Logger
+// from LOG_LEVEL (default info) and LOG_SAMPLE_RATE (default 1). Call it
+// after the configuration is loaded; the app package does.
+func ConfigureLogging() error {
+	settings, err := baselineLogSettings()
+	if err != nil {
+		return err
+	}
+	applyLogSettings(settings)
+	return nil
+}
+
+// CurrentLogSettings returns the settings the base Logger runs with
+func CurrentLogSettings() LogSettings {
+	logSettingsMu.Lock()
+	defer logSettingsMu.Unlock()
+	return LogSettings{
+		Level:      strings.ToLower(logLevel.Level().String()),
+		SampleRate: math.Float64frombits(logSampleRate.Load()),
+		Until:      logSettingsUntil,
+	}
+}
+
+// SetLogSettings changes the level and sample rate of the base Logger on this
+// instance. A positive duration reverts to the baseline afterwards, e.g. to
+// debug for five minutes without forgetting to switch back.
+func SetLogSettings(level string, sampleRate float64, duration time.Duration) (LogSettings, error) {
+	if _, err := parseLogLevel(level); err != nil {
+		return LogSettings{}, err
+	}
+	if sampleRate < 0 || sampleRate > 1 {
+		return LogSettings{}, fmt.Errorf("%w: sample rate %v is outside 0 to 1", ErrInvalidLogSettings, sampleRate)
+	}
+
+	logSettingsMu.Lock()
+	if logRevertTimer != nil {
+		logRevertTimer.Stop()
+		logRevertTimer = nil
+	}
+	logSettingsUntil = nil
+	if duration > 0 {
+		until := Now().Add(duration)
+		logSettingsUntil = &until
+		logRevertTimer = time.AfterFunc(duration, func() {
+			if err := ResetLogSettings(); err != nil {
+				LogWarning(fmt.Sprintf("Failed to revert log settings: %v", err))
+			}
+		})
+	}
+	logSettingsMu.Unlock()
+
+	applyLogSettings(LogSettings{Level: level, SampleRate: sampleRate})
+	log.Printf("🔧 Log level %s, sample rate %v (for %v)", level, sampleRate, duration)
+	return CurrentLogSettings(), nil
+}
+
+// ResetLogSettings returns the base Logger to the configured baseline
+func ResetLogSettings() error {
+	settings, err := baselineLogSettings()
+	if err != nil {
+		return err
+	}
+
+	logSettingsMu.Lock()
+	if logRevertTimer != nil {
+		logRevertTimer.Stop()
+		logRevertTimer = nil
+	}
+	logSettingsUntil = nil
+	logSettingsMu.Unlock()
+
+	applyLogSettings(settings)
+	return nil
+}
+
+// ApplyLogControl changes this instance's settings as asked by control,
+// unless it targets another service. service is this instance's service name.
+func ApplyLogControl(control LogControl, service string) (LogSettings, error) {
+	if control.Service != "" && control.Service != service {
+		return CurrentLogSettings(), nil
+	}
+	if control.Reset {
+		if err := ResetLogSettings(); err != nil {
+			return LogSettings{}, err
+		}
+		return CurrentLogSettings(), nil
+	}
+
+	current := CurrentLogSettings()
+	level, sampleRate := current.Level, current.SampleRate
+	if control.Level != "" {
+		level = strings.ToLower(control.Level)
+	}
+	if control.SampleRate != nil {
+		sampleRate = *control.SampleRate
+	}
+	var duration time.Duration
+	if control.Duration != "" {
+		var err error
+		if duration, err = time.ParseDuration(control.Duration); err != nil || duration <= 0 {
+			return LogSettings{}, fmt.Errorf("%w: duration %q", ErrInvalidLogSettings, control.Duration)
+		}
+	}
+	if level == "debug" {
+		if duration == 0 {
+			duration = DefaultDebugLogDuration
+		}
+		if duration > MaxDebugLogDuration {
+			return LogSettings{}, fmt.Errorf("%w: debug logging lasts at most %v", ErrInvalidLogSettings, MaxDebugLogDuration)
+		}
+	}
+	return SetLogSettings(level, sampleRate, duration)
+}
+
+func baselineLogSettings() (LogSettings, error) {
+	settings := LogSettings{Level: strings.ToLower(config.GetEnv("LOG_LEVEL", "info")), SampleRate: 1}
+	if _, err := parseLogLevel(settings.Level); err != nil {
+		return LogSettings{}, fmt.Errorf("LOG_LEVEL: %w", err)
+	}
+	if value := config.GetEnv("LOG_SAMPLE_RATE", ""); value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return LogSettings{}, fmt.Errorf("%w: LOG_SAMPLE_RATE %q is not a number from 0 to 1", ErrInvalidLogSettings, value)
+		}
+		settings.SampleRate = rate
+	}
+	return settings, nil
+}
+
+func applyLogSettings(settings LogSettings) {
+	level, _ := parseLogLevel(settings.Level)
+	logLevel.Set(level)
+	logSampleRate.Store(math.Float64bits(settings.SampleRate))
+}
+
+func parseLogLevel(level string) (slog.Level, error) {
+	var parsed slog.Level
+	if err := parsed.UnmarshalText([]byte(level)); err != nil {
+		return 0, fmt.Errorf("%w: unknown level %q", ErrInvalidLogSettings, level)
+	}
+	return parsed, nil
+}
+
+// samplingHandler drops a share of debug and info records, keeping
+// warnings and errors
+type samplingHandler struct {
+	next slog.Handler
+}
+
+func (h *samplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level < slog.LevelWarn {
+		if rate := math.Float64frombits(logSampleRate.Load()); rate < 1 && rand.Float64() >= rate {
+			return nil
+		}
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{next: h.next.WithAttrs(attrs)}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{next: h.next.WithGroup(name)}
+}
@@ -2,9 +2,23 @@
 package utils
 
 import (
+	"context"
 	"log"
+	"log/slog"
+	"os"
+
+	"github.com/praleedsuvarna/shared-libs/redact"
 )
 
+type loggerContextKey struct{}
+
+// Logger is the base structured logger; request-scoped loggers are derived from
+// it. Credentials in connection strings are masked before anything is written.
+// Its level and sampling can change at runtime, see SetLogSettings.
+var Logger = slog.New(redact.Handler(&samplingHandler{
+	next: slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel}),
+}))
+
 func LogWarning(message string) {
 	log.Printf("[WARNING] %s", message)
 }
@@ -12,3 +26,18 @@ func LogWarning(message string) {
 func LogError(message string) {
 	log.Printf("[ERROR] %s", message)
 }
+
+// WithLogger returns a copy of ctx carrying the given logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// Log returns the request-scoped logger stored in ctx, or the base Logger if none is set
+func Log(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerContextKey{}).(*slog.Logger); ok {
+			return logger
+		}
+	}
+	return Logger
+}
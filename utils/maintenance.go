@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Where the maintenance flag is stored: Redis when configured, otherwise Mongo
+const (
+	MaintenanceRedisKey       = "maintenance_mode"
+	ServiceSettingsCollection = "service_settings"
+	maintenanceDocumentID     = "maintenance"
+)
+
+// DefaultMaintenanceRetryAfter is sent in Retry-After when none is configured
+const DefaultMaintenanceRetryAfter = 300
+
+// MaintenanceCacheTTL bounds how often each instance reads the shared flag
+var MaintenanceCacheTTL = 5 * time.Second
+
+var (
+	maintenanceCache    models.MaintenanceState
+	maintenanceLoadedAt time.Time
+	maintenanceMux      sync.RWMutex
+)
+
+// GetMaintenanceState returns the current maintenance state. MAINTENANCE_MODE=true
+// forces maintenance regardless of the stored flag. Lookup failures are logged and
+// treated as "not in maintenance" so an outage of the flag store does not take
+// the service down.
+func GetMaintenanceState() models.MaintenanceState {
+	if config.GetEnv("MAINTENANCE_MODE", "") == "true" {
+		return models.MaintenanceState{
+			Enabled:    true,
+			Message:    config.GetEnv("MAINTENANCE_MESSAGE", ""),
+			RetryAfter: DefaultMaintenanceRetryAfter,
+			Source:     "env",
+		}
+	}
+
+	maintenanceMux.RLock()
+	cached, loadedAt := maintenanceCache, maintenanceLoadedAt
+	maintenanceMux.RUnlock()
+	if time.Since(loadedAt) < MaintenanceCacheTTL {
+		return cached
+	}
+
+	state, err := loadMaintenanceState()
+	if err != nil {
+		LogWarning("Failed to load maintenance state: " + err.Error())
+		return cached
+	}
+
+	// A scheduled end switches maintenance off without another admin call
+	if state.Enabled && state.EndsAt != nil && Now().After(*state.EndsAt) {
+		state.Enabled = false
+	}
+
+	maintenanceMux.Lock()
+	maintenanceCache, maintenanceLoadedAt = state, time.Now()
+	maintenanceMux.Unlock()
+	return state
+}
+
+// SetMaintenanceState stores the maintenance flag for all instances
+func SetMaintenanceState(state models.MaintenanceState) (models.MaintenanceState, error) {
+	if state.RetryAfter <= 0 {
+		state.RetryAfter = DefaultMaintenanceRetryAfter
+	}
+	state.UpdatedAt = Now()
+	state.Source = ""
+
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	var err error
+	if config.Redis != nil {
+		var data []byte
+		if data, err = json.Marshal(state); err == nil {
+			err = config.Redis.Set(ctx, MaintenanceRedisKey, data, 0).Err()
+		}
+	} else {
+		_, err = config.GetCollection(ServiceSettingsCollection).ReplaceOne(ctx,
+			bson.M{"_id": maintenanceDocumentID},
+			state,
+			options.Replace().SetUpsert(true),
+		)
+	}
+	if err != nil {
+		return state, err
+	}
+
+	maintenanceMux.Lock()
+	maintenanceCache, maintenanceLoadedAt = state, time.Now()
+	maintenanceMux.Unlock()
+	return state, nil
+}
+
+func loadMaintenanceState() (models.MaintenanceState, error) {
+	var state models.MaintenanceState
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if config.Redis != nil {
+		data, err := config.Redis.Get(ctx, MaintenanceRedisKey).Bytes()
+		if err == redis.Nil {
+			return state, nil
+		}
+		if err != nil {
+			return state, err
+		}
+		return state, json.Unmarshal(data, &state)
+	}
+
+	if config.DB == nil {
+		return state, nil
+	}
+	err := config.GetCollection(ServiceSettingsCollection).FindOne(ctx, bson.M{"_id": maintenanceDocumentID}).Decode(&state)
+	if err == mongo.ErrNoDocuments {
+		return state, nil
+	}
+	return state, err
+}
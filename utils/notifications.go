@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"fmt"
+)
+
+// Notification channels
+const (
+	ChannelEmail = "email"
+	ChannelPush  = "push"
+	ChannelSMS   = "sms"
+)
+
+// Notification is a message to a user, delivered over the channels they accept
+type Notification struct {
+	UserID   string
+	Email    string
+	Category string                 // e.g. "security", "billing", "activity"
+	Template string                 // Registered email template name
+	Data     map[string]interface{} // Template data
+	// OrganizationID brands the email with the organization's branding; empty
+	// sends it with the default sender name
+	OrganizationID string
+}
+
+// NotifyUser dispatches a notification according to the user's preferences.
+// Email is rendered in the user's locale when a localized template exists.
+func NotifyUser(notification Notification) error {
+	if notification.Email == "" || !WantsNotification(notification.UserID, ChannelEmail, notification.Category) {
+		return nil
+	}
+
+	if notification.OrganizationID != "" {
+		name, data := localizeEmail(notification.UserID, notification.Template, notification.Data)
+		return SendBrandedEmail(notification.OrganizationID, notification.Email, name, data)
+	}
+	return SendLocalizedEmail(notification.UserID, notification.Email, notification.Template, notification.Data)
+}
+
+// SendLocalizedEmail sends a templated email using the "<template>.<locale>" variant
+// for the user's locale if registered, otherwise the base template
+func SendLocalizedEmail(userID, email, templateName string, data map[string]interface{}) error {
+	name, data := localizeEmail(userID, templateName, data)
+	return SendTemplatedEmail(email, name, data)
+}
+
+// localizeEmail picks the template variant for the user's locale and adds the
+// Locale and Timezone to the data
+func localizeEmail(userID, templateName string, data map[string]interface{}) (string, map[string]interface{}) {
+	preferences, err := GetUserPreferences(userID)
+	if err != nil {
+		preferences = DefaultUserPreferences(userID)
+	}
+
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	data["Locale"] = preferences.Locale
+	data["Timezone"] = preferences.Timezone
+
+	name := templateName
+	if localized := fmt.Sprintf("%s.%s", templateName, preferences.Locale); hasEmailTemplate(localized) {
+		name = localized
+	}
+	return name, data
+}
+
+func hasEmailTemplate(name string) bool {
+	_, ok := GetEmailTemplate(name)
+	return ok
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+// DefaultPhoneRegion is used for numbers written without a country code
+var DefaultPhoneRegion = "US"
+
+// PhoneNumber is a validated phone number
+type PhoneNumber struct {
+	E164     string `json:"e164"`     // +14155552671
+	Region   string `json:"region"`   // ISO 3166-1 alpha-2, e.g. "US"
+	National string `json:"national"` // (415) 555-2671
+}
+
+// ParsePhone parses and validates a phone number. Numbers without a leading "+"
+// are interpreted in defaultRegion (DefaultPhoneRegion when empty).
+func ParsePhone(number, defaultRegion string) (*PhoneNumber, error) {
+	if defaultRegion == "" {
+		defaultRegion = DefaultPhoneRegion
+	}
+
+	parsed, err := phonenumbers.Parse(number, strings.ToUpper(defaultRegion))
+	if err != nil {
+		return nil, fmt.Errorf("invalid phone number %q: %w", number, err)
+	}
+	if !phonenumbers.IsValidNumber(parsed) {
+		return nil, fmt.Errorf("invalid phone number %q", number)
+	}
+
+	return &PhoneNumber{
+		E164:     phonenumbers.Format(parsed, phonenumbers.E164),
+		Region:   phonenumbers.GetRegionCodeForNumber(parsed),
+		National: phonenumbers.Format(parsed, phonenumbers.NATIONAL),
+	}, nil
+}
+
+// NormalizePhone returns the E.164 form of a phone number, e.g. "+14155552671"
+func NormalizePhone(number, defaultRegion string) (string, error) {
+	parsed, err := ParsePhone(number, defaultRegion)
+	if err != nil {
+		return "", err
+	}
+	return parsed.E164, nil
+}
+
+// IsValidPhone reports whether number is a valid phone number
+func IsValidPhone(number, defaultRegion string) bool {
+	_, err := ParsePhone(number, defaultRegion)
+	return err == nil
+}
+
+// PhoneRegion returns the ISO country code a phone number belongs to
+func PhoneRegion(number string) (string, error) {
+	parsed, err := ParsePhone(number, "")
+	if err != nil {
+		return "", err
+	}
+	return parsed.Region, nil
+}
+
+// FormatPhoneInternational formats a number for display, e.g. "+1 415-555-2671"
+func FormatPhoneInternational(number string) (string, error) {
+	parsed, err := phonenumbers.Parse(number, DefaultPhoneRegion)
+	if err != nil {
+		return "", fmt.Errorf("invalid phone number %q: %w", number, err)
+	}
+	return phonenumbers.Format(parsed, phonenumbers.INTERNATIONAL), nil
+}
+
+// MaskPhone hides all but the country code and last four digits, e.g. "+1 ***-***-2671"
+func MaskPhone(number string) string {
+	formatted, err := FormatPhoneInternational(number)
+	if err != nil {
+		return "***"
+	}
+
+	countryCode, rest, found := strings.Cut(formatted, " ")
+	if !found {
+		return "***"
+	}
+
+	// Count digits from the end so the last four stay visible
+	runes := []rune(rest)
+	visible := 0
+	for i := len(runes) - 1; i >= 0; i-- {
+		if runes[i] < '0' || runes[i] > '9' {
+			continue
+		}
+		if visible < 4 {
+			visible++
+			continue
+		}
+		runes[i] = '*'
+	}
+	return countryCode + " " + string(runes)
+}
@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PreferencesCollection stores user preferences
+const PreferencesCollection = "user_preferences"
+
+// PreferencesCacheTTL controls how long preferences are cached in memory
+var PreferencesCacheTTL = 5 * time.Minute
+
+// Defaults applied to users without stored preferences
+const (
+	DefaultTimezone = "UTC"
+	DefaultLocale   = "en"
+)
+
+type cachedPreferences struct {
+	preferences models.UserPreferences
+	loadedAt    time.Time
+}
+
+var (
+	preferencesCache    = map[string]cachedPreferences{}
+	preferencesCacheMux sync.RWMutex
+)
+
+// DefaultUserPreferences returns the preferences used when a user has none stored
+func DefaultUserPreferences(userID string) models.UserPreferences {
+	return models.UserPreferences{
+		UserID:   userID,
+		Timezone: DefaultTimezone,
+		Locale:   DefaultLocale,
+		Notifications: models.NotificationSettings{
+			Email: true,
+			Push:  true,
+		},
+	}
+}
+
+// GetUserPreferences returns a user's preferences, falling back to defaults
+func GetUserPreferences(userID string) (models.UserPreferences, error) {
+	preferencesCacheMux.RLock()
+	cached, ok := preferencesCache[userID]
+	preferencesCacheMux.RUnlock()
+	if ok && time.Since(cached.loadedAt) < PreferencesCacheTTL {
+		return cached.preferences, nil
+	}
+
+	collection := config.GetCollection(PreferencesCollection)
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	preferences := DefaultUserPreferences(userID)
+	err := collection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&preferences)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return preferences, err
+	}
+
+	cachePreferences(preferences)
+	return preferences, nil
+}
+
+// UpdateUserPreferences validates and upserts a user's preferences
+func UpdateUserPreferences(preferences models.UserPreferences) (models.UserPreferences, error) {
+	if preferences.Timezone == "" {
+		preferences.Timezone = DefaultTimezone
+	}
+	if preferences.Locale == "" {
+		preferences.Locale = DefaultLocale
+	}
+	if _, err := time.LoadLocation(preferences.Timezone); err != nil {
+		return preferences, fmt.Errorf("%w: unknown timezone %q", ErrInvalidPreferences, preferences.Timezone)
+	}
+
+	preferences.UpdatedAt = time.Now()
+
+	collection := config.GetCollection(PreferencesCollection)
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"user_id": preferences.UserID},
+		bson.M{"$set": bson.M{
+			"timezone":      preferences.Timezone,
+			"locale":        preferences.Locale,
+			"notifications": preferences.Notifications,
+			"updated_at":    preferences.UpdatedAt,
+		}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&preferences)
+	if err != nil {
+		return preferences, err
+	}
+
+	cachePreferences(preferences)
+	return preferences, nil
+}
+
+// DeleteUserPreferences removes stored preferences so defaults apply again
+func DeleteUserPreferences(userID string) error {
+	collection := config.GetCollection(PreferencesCollection)
+	ctx, cancel := GetContext()
+	defer cancel()
+
+	_, err := collection.DeleteOne(ctx, bson.M{"user_id": userID})
+	InvalidateUserPreferences(userID)
+	return err
+}
+
+// InvalidateUserPreferences drops a user's cached preferences
+func InvalidateUserPreferences(userID string) {
+	preferencesCacheMux.Lock()
+	defer preferencesCacheMux.Unlock()
+	delete(preferencesCache, userID)
+}
+
+// GetUserLocale returns the user's locale, or DefaultLocale if unavailable
+func GetUserLocale(userID string) string {
+	preferences, err := GetUserPreferences(userID)
+	if err != nil || preferences.Locale == "" {
+		return DefaultLocale
+	}
+	return preferences.Locale
+}
+
+// GetUserLocation returns the user's time zone, or UTC if unavailable
+func GetUserLocation(userID string) *time.Location {
+	preferences, err := GetUserPreferences(userID)
+	if err != nil {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(preferences.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// WantsNotification reports whether the user accepts a notification on a channel
+// ("email", "push" or "sms") for a category
+func WantsNotification(userID, channel, category string) bool {
+	preferences, err := GetUserPreferences(userID)
+	if err != nil {
+		preferences = DefaultUserPreferences(userID)
+	}
+
+	settings := preferences.Notifications
+	if enabled, ok := settings.Categories[category]; ok && !enabled {
+		return false
+	}
+
+	switch channel {
+	case "email":
+		return settings.Email
+	case "push":
+		return settings.Push
+	case "sms":
+		return settings.SMS
+	default:
+		return false
+	}
+}
+
+func cachePreferences(preferences models.UserPreferences) {
+	preferencesCacheMux.Lock()
+	defer preferencesCacheMux.Unlock()
+	preferencesCache[preferences.UserID] = cachedPreferences{preferences: preferences, loadedAt: time.Now()}
+}
@@ -0,0 +1,248 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+// QRFormat is the image format of a generated QR code
+type QRFormat string
+
+// Supported QR code formats
+const (
+	QRFormatPNG QRFormat = "png"
+	QRFormatSVG QRFormat = "svg"
+)
+
+// ContentType returns the MIME type of the format
+func (f QRFormat) ContentType() string {
+	if f == QRFormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// QRErrorCorrection is how much of a symbol can be damaged or covered and
+// still scan: about 7%, 15%, 25% and 30%
+type QRErrorCorrection int
+
+// Error correction levels
+const (
+	QRCorrectionLow QRErrorCorrection = iota
+	QRCorrectionMedium
+	QRCorrectionQuartile
+	QRCorrectionHigh
+)
+
+// ParseQRErrorCorrection parses a level name: L, M, Q or H
+func ParseQRErrorCorrection(level string) (QRErrorCorrection, error) {
+	switch strings.ToUpper(level) {
+	case "L":
+		return QRCorrectionLow, nil
+	case "M":
+		return QRCorrectionMedium, nil
+	case "Q":
+		return QRCorrectionQuartile, nil
+	case "H":
+		return QRCorrectionHigh, nil
+	}
+	return 0, fmt.Errorf("invalid QR error correction level %q", level)
+}
+
+// ErrQRCodeTooLong is returned for content that does not fit the largest QR code
+var ErrQRCodeTooLong = errors.New("content too long for a QR code")
+
+// QRCodeOptions configures GenerateQRCode
+type QRCodeOptions struct {
+	Format          QRFormat          // Default PNG
+	Size            int               // Width in pixels, including the quiet zone; default 256
+	ErrorCorrection QRErrorCorrection // Default QRCorrectionMedium; raised to High with a logo
+	QuietZone       int               // Margin in modules; default 4, as the specification requires
+	Foreground      color.Color       // Default black
+	Background      color.Color       // Default white
+
+	// Logo is drawn over the centre of the code, using the error correction
+	// to make up for the modules it covers
+	Logo      image.Image
+	LogoScale float64 // Logo width as a fraction of the code; default 0.2, at most 0.3
+}
+
+// GenerateQRCode renders content, typically a URL, as a QR code image
+func GenerateQRCode(content string, opts QRCodeOptions) ([]byte, error) {
+	if content == "" {
+		return nil, errors.New("QR code content is empty")
+	}
+	if opts.Format == "" {
+		opts.Format = QRFormatPNG
+	}
+	if opts.Format != QRFormatPNG && opts.Format != QRFormatSVG {
+		return nil, fmt.Errorf("unsupported QR code format %q", opts.Format)
+	}
+	if opts.Size <= 0 {
+		opts.Size = 256
+	}
+	if opts.QuietZone <= 0 {
+		opts.QuietZone = 4
+	}
+	if opts.Foreground == nil {
+		opts.Foreground = color.Black
+	}
+	if opts.Background == nil {
+		opts.Background = color.White
+	}
+	if opts.ErrorCorrection < QRCorrectionLow || opts.ErrorCorrection > QRCorrectionHigh {
+		opts.ErrorCorrection = QRCorrectionMedium
+	}
+	if opts.Logo != nil {
+		opts.ErrorCorrection = QRCorrectionHigh
+		if opts.LogoScale <= 0 {
+			opts.LogoScale = 0.2
+		}
+		opts.LogoScale = min(opts.LogoScale, 0.3)
+	}
+
+	matrix, err := encodeQR([]byte(content), opts.ErrorCorrection)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Format == QRFormatSVG {
+		return renderQRSVG(matrix, opts)
+	}
+	return renderQRPNG(matrix, opts)
+}
+
+// renderQRPNG draws whole pixels per module, so the image may be slightly
+// smaller than opts.Size but never blurry
+func renderQRPNG(m *qrMatrix, opts QRCodeOptions) ([]byte, error) {
+	modules := m.size + 2*opts.QuietZone
+	scale := max(1, opts.Size/modules)
+	width := modules * scale
+
+	img := image.NewPaletted(image.Rect(0, 0, width, width), color.Palette{opts.Background, opts.Foreground})
+	for y := 0; y < m.size; y++ {
+		for x := 0; x < m.size; x++ {
+			if !m.modules[y][x] {
+				continue
+			}
+			x0, y0 := (x+opts.QuietZone)*scale, (y+opts.QuietZone)*scale
+			for py := y0; py < y0+scale; py++ {
+				for px := x0; px < x0+scale; px++ {
+					img.SetColorIndex(px, py, 1)
+				}
+			}
+		}
+	}
+
+	var out image.Image = img
+	if opts.Logo != nil {
+		rgba := image.NewRGBA(img.Bounds())
+		for y := 0; y < width; y++ {
+			for x := 0; x < width; x++ {
+				rgba.Set(x, y, img.At(x, y))
+			}
+		}
+		drawQRLogo(rgba, opts.Logo, int(float64(width)*opts.LogoScale), opts.Background)
+		out = rgba
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, out); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// drawQRLogo scales logo to fit a box of the given width, nearest neighbour,
+// and draws it centred on a background-colored pad
+func drawQRLogo(dst *image.RGBA, logo image.Image, box int, background color.Color) {
+	bounds := logo.Bounds()
+	if box <= 0 || bounds.Empty() {
+		return
+	}
+	w, h := box, box*bounds.Dy()/bounds.Dx()
+	if h > box {
+		w, h = box*bounds.Dx()/bounds.Dy(), box
+	}
+
+	center := dst.Bounds().Dx() / 2
+	pad := max(2, box/20)
+	for y := center - h/2 - pad; y < center+h/2+pad; y++ {
+		for x := center - w/2 - pad; x < center+w/2+pad; x++ {
+			dst.Set(x, y, background)
+		}
+	}
+
+	left, top := center-w/2, center-h/2
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := logo.At(bounds.Min.X+x*bounds.Dx()/w, bounds.Min.Y+y*bounds.Dy()/h)
+			r, g, b, a := c.RGBA()
+			if a == 0 {
+				continue
+			}
+			// Blend over the pad so transparent logos keep their edges
+			br, bg, bb, _ := background.RGBA()
+			blend := func(fg, bg uint32) uint8 { return uint8((fg + bg*(0xffff-a)/0xffff) >> 8) }
+			dst.Set(left+x, top+y, color.RGBA{blend(r, br), blend(g, bg), blend(b, bb), 0xff})
+		}
+	}
+}
+
+// renderQRSVG draws the dark modules as one path in module units, so the
+// image scales without loss
+func renderQRSVG(m *qrMatrix, opts QRCodeOptions) ([]byte, error) {
+	modules := m.size + 2*opts.QuietZone
+
+	var path strings.Builder
+	for y := 0; y < m.size; y++ {
+		for x := 0; x < m.size; x++ {
+			if m.modules[y][x] {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x+opts.QuietZone, y+opts.QuietZone)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		opts.Size, opts.Size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="100%%" height="100%%" fill="%s"/>`, svgColor(opts.Background))
+	fmt.Fprintf(&buf, `<path d="%s" fill="%s"/>`, path.String(), svgColor(opts.Foreground))
+
+	if opts.Logo != nil {
+		var logo bytes.Buffer
+		if err := png.Encode(&logo, opts.Logo); err != nil {
+			return nil, err
+		}
+		box := float64(modules) * opts.LogoScale
+		origin := (float64(modules) - box) / 2
+		pad := box / 20
+		fmt.Fprintf(&buf, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="%s"/>`,
+			origin-pad, origin-pad, box+2*pad, box+2*pad, svgColor(opts.Background))
+		fmt.Fprintf(&buf, `<image x="%.2f" y="%.2f" width="%.2f" height="%.2f" preserveAspectRatio="xMidYMid meet" href="data:image/png;base64,%s"/>`,
+			origin, origin, box, box, base64.StdEncoding.EncodeToString(logo.Bytes()))
+	}
+
+	buf.WriteString(`</svg>`)
+	return buf.Bytes(), nil
+}
+
+// svgColor formats a color as an SVG fill
+func svgColor(c color.Color) string {
+	r, g, b, a := c.RGBA()
+	if a == 0 {
+		return "none"
+	}
+	// Undo premultiplication
+	r, g, b = r*0xffff/a, g*0xffff/a, b*0xffff/a
+	if a == 0xffff {
+		return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+	}
+	return fmt.Sprintf("rgba(%d,%d,%d,%.3f)", r>>8, g>>8, b>>8, float64(a)/0xffff)
+}
@@ -0,0 +1,453 @@
+package utils
+
+// QR Code Model 2 encoder (ISO/IEC 18004) for byte mode content. It builds
+// the module matrix; rendering lives in qrcode.go.
+
+// Error correction codewords per block, indexed by [level][version]
+var qrECCPerBlock = [4][41]int{
+	{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{-1, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+// Error correction blocks, indexed by [level][version]
+var qrECCBlocks = [4][41]int{
+	{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{-1, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{-1, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// Format information bits of each level
+var qrFormatBits = [4]int{1, 0, 3, 2}
+
+// qrMatrix is the module grid of a symbol; true is dark
+type qrMatrix struct {
+	size       int
+	modules    [][]bool
+	isFunction [][]bool
+}
+
+// encodeQR encodes data at the smallest version that fits, raising the error
+// correction level while the version stays the same
+func encodeQR(data []byte, level QRErrorCorrection) (*qrMatrix, error) {
+	version := 0
+	for v := 1; v <= 40; v++ {
+		if qrDataBits(v, len(data)) <= qrDataCodewords(v, level)*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrQRCodeTooLong
+	}
+	for level < QRCorrectionHigh && qrDataBits(version, len(data)) <= qrDataCodewords(version, level+1)*8 {
+		level++
+	}
+
+	// Byte mode segment, terminator and padding
+	capacity := qrDataCodewords(version, level) * 8
+	var bits qrBits
+	bits.append(0x4, 4)
+	bits.append(len(data), qrCountBits(version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	bits.append(0, min(4, capacity-len(bits)))
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	codewords := make([]byte, len(bits)/8)
+	for i, bit := range bits {
+		if bit {
+			codewords[i>>3] |= 1 << (7 - i&7)
+		}
+	}
+
+	m := newQRMatrix(version)
+	m.drawFunctionPatterns(version, level)
+	m.drawCodewords(qrInterleave(codewords, version, level))
+
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		m.applyMask(mask)
+		m.drawFormatBits(level, mask)
+		if penalty := m.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		m.applyMask(mask) // Masking is its own inverse
+	}
+	m.applyMask(best)
+	m.drawFormatBits(level, best)
+	return m, nil
+}
+
+// qrBits is a bit buffer, most significant bit first
+type qrBits []bool
+
+func (b *qrBits) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*b = append(*b, (value>>i)&1 != 0)
+	}
+}
+
+// qrCountBits is the width of the byte mode character count
+func qrCountBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+// qrDataBits is the length of a byte mode segment of n bytes
+func qrDataBits(version, n int) int {
+	return 4 + qrCountBits(version) + 8*n
+}
+
+// qrRawModules is the number of modules available for codewords
+func qrRawModules(version int) int {
+	result := (16*version+128)*version + 64
+	if version >= 2 {
+		align := version/7 + 2
+		result -= (25*align-10)*align - 55
+		if version >= 7 {
+			result -= 36
+		}
+	}
+	return result
+}
+
+// qrDataCodewords is the number of data codewords of a version and level
+func qrDataCodewords(version int, level QRErrorCorrection) int {
+	return qrRawModules(version)/8 - qrECCPerBlock[level][version]*qrECCBlocks[level][version]
+}
+
+// qrInterleave splits data into blocks, appends their Reed-Solomon codewords
+// and interleaves them
+func qrInterleave(data []byte, version int, level QRErrorCorrection) []byte {
+	numBlocks := qrECCBlocks[level][version]
+	eccLen := qrECCPerBlock[level][version]
+	raw := qrRawModules(version) / 8
+	numShort := numBlocks - raw%numBlocks
+	shortLen := raw / numBlocks
+
+	divisor := qrRSDivisor(eccLen)
+	blocks := make([][]byte, numBlocks)
+	k := 0
+	for i := range blocks {
+		n := shortLen - eccLen
+		if i >= numShort {
+			n++
+		}
+		block := append([]byte(nil), data[k:k+n]...)
+		k += n
+		ecc := qrRSRemainder(block, divisor)
+		if i < numShort {
+			block = append(block, 0) // Placeholder so all blocks line up
+		}
+		blocks[i] = append(block, ecc...)
+	}
+
+	result := make([]byte, 0, raw)
+	for i := 0; i <= shortLen; i++ {
+		for j, block := range blocks {
+			if i != shortLen-eccLen || j >= numShort {
+				result = append(result, block[i])
+			}
+		}
+	}
+	return result
+}
+
+// qrRSDivisor returns the Reed-Solomon generator polynomial of a degree,
+// without its leading term
+func qrRSDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = qrGFMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = qrGFMultiply(root, 0x02)
+	}
+	return result
+}
+
+// qrRSRemainder returns the Reed-Solomon error correction codewords of data
+func qrRSRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coefficient := range divisor {
+			result[i] ^= qrGFMultiply(coefficient, factor)
+		}
+	}
+	return result
+}
+
+// qrGFMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func qrGFMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+func newQRMatrix(version int) *qrMatrix {
+	size := version*4 + 17
+	m := &qrMatrix{size: size, modules: make([][]bool, size), isFunction: make([][]bool, size)}
+	for i := 0; i < size; i++ {
+		m.modules[i] = make([]bool, size)
+		m.isFunction[i] = make([]bool, size)
+	}
+	return m
+}
+
+func (m *qrMatrix) setFunction(x, y int, dark bool) {
+	m.modules[y][x] = dark
+	m.isFunction[y][x] = true
+}
+
+// drawFunctionPatterns draws the finder, timing and alignment patterns and
+// reserves the format and version areas
+func (m *qrMatrix) drawFunctionPatterns(version int, level QRErrorCorrection) {
+	for i := 0; i < m.size; i++ {
+		m.setFunction(6, i, i%2 == 0)
+		m.setFunction(i, 6, i%2 == 0)
+	}
+
+	for _, corner := range [][2]int{{3, 3}, {m.size - 4, 3}, {3, m.size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := corner[0]+dx, corner[1]+dy
+				if x < 0 || x >= m.size || y < 0 || y >= m.size {
+					continue
+				}
+				distance := max(qrAbs(dx), qrAbs(dy))
+				m.setFunction(x, y, distance != 2 && distance != 4)
+			}
+		}
+	}
+
+	positions := qrAlignmentPositions(version)
+	last := len(positions) - 1
+	for i, cy := range positions {
+		for j, cx := range positions {
+			// Skip the three that would overlap finder patterns
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					m.setFunction(cx+dx, cy+dy, max(qrAbs(dx), qrAbs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	m.drawFormatBits(level, 0)
+	m.drawVersion(version)
+}
+
+// qrAlignmentPositions returns the centre coordinates of alignment patterns
+func qrAlignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	count := version/7 + 2
+	step := (version*8 + count*3 + 5) / (count*4 - 4) * 2
+	positions := make([]int, count)
+	positions[0] = 6
+	for i, pos := count-1, version*4+10; i >= 1; i, pos = i-1, pos-step {
+		positions[i] = pos
+	}
+	return positions
+}
+
+// drawFormatBits draws both copies of the level and mask information
+func (m *qrMatrix) drawFormatBits(level QRErrorCorrection, mask int) {
+	data := qrFormatBits[level]<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>i)&1 != 0 }
+
+	for i := 0; i <= 5; i++ {
+		m.setFunction(8, i, bit(i))
+	}
+	m.setFunction(8, 7, bit(6))
+	m.setFunction(8, 8, bit(7))
+	m.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		m.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		m.setFunction(m.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		m.setFunction(8, m.size-15+i, bit(i))
+	}
+	m.setFunction(8, m.size-8, true) // Always dark
+}
+
+// drawVersion draws both copies of the version information of versions 7 and up
+func (m *qrMatrix) drawVersion(version int) {
+	if version < 7 {
+		return
+	}
+	rem := version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := version<<12 | rem
+
+	for i := 0; i < 18; i++ {
+		dark := (bits>>i)&1 != 0
+		a, b := m.size-11+i%3, i/3
+		m.setFunction(a, b, dark)
+		m.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords places the codewords in the zigzag pattern, skipping function modules
+func (m *qrMatrix) drawCodewords(data []byte) {
+	i := 0
+	for right := m.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // Skip the vertical timing pattern
+		}
+		for vert := 0; vert < m.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = m.size - 1 - vert // Upward column
+				}
+				if !m.isFunction[y][x] && i < len(data)*8 {
+					m.modules[y][x] = (data[i>>3]>>(7-i&7))&1 != 0
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask XORs a mask pattern onto the non-function modules
+func (m *qrMatrix) applyMask(mask int) {
+	for y := 0; y < m.size; y++ {
+		for x := 0; x < m.size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !m.isFunction[y][x] {
+				m.modules[y][x] = !m.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores a masked symbol by the rules of the specification; lower is
+// easier to scan
+func (m *qrMatrix) penalty() int {
+	score := 0
+	at := func(x, y int, vertical bool) bool {
+		if vertical {
+			return m.modules[x][y]
+		}
+		return m.modules[y][x]
+	}
+
+	for _, vertical := range []bool{false, true} {
+		for y := 0; y < m.size; y++ {
+			// Runs of five or more modules of one color
+			run := 1
+			for x := 1; x < m.size; x++ {
+				if at(x, y, vertical) == at(x-1, y, vertical) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += run - 2
+				}
+				run = 1
+			}
+			if run >= 5 {
+				score += run - 2
+			}
+
+			// Patterns that look like a finder: dark-light-dark×3-light-dark
+			// next to four light modules
+			for x := 0; x+10 < m.size; x++ {
+				core := at(x+4, y, vertical) && !at(x+5, y, vertical) && at(x+6, y, vertical) &&
+					at(x+7, y, vertical) && at(x+8, y, vertical) && !at(x+9, y, vertical) && at(x+10, y, vertical)
+				if core && !at(x, y, vertical) && !at(x+1, y, vertical) && !at(x+2, y, vertical) && !at(x+3, y, vertical) {
+					score += 40
+				}
+				core = at(x, y, vertical) && !at(x+1, y, vertical) && at(x+2, y, vertical) &&
+					at(x+3, y, vertical) && at(x+4, y, vertical) && !at(x+5, y, vertical) && at(x+6, y, vertical)
+				if core && !at(x+7, y, vertical) && !at(x+8, y, vertical) && !at(x+9, y, vertical) && !at(x+10, y, vertical) {
+					score += 40
+				}
+			}
+		}
+	}
+
+	// 2×2 blocks of one color
+	dark := 0
+	for y := 0; y < m.size; y++ {
+		for x := 0; x < m.size; x++ {
+			if m.modules[y][x] {
+				dark++
+			}
+			if x+1 < m.size && y+1 < m.size {
+				c := m.modules[y][x]
+				if c == m.modules[y][x+1] && c == m.modules[y+1][x] && c == m.modules[y+1][x+1] {
+					score += 3
+				}
+			}
+		}
+	}
+
+	// Balance of dark and light modules, 10 points per 5% off 50%
+	total := m.size * m.size
+	deviation := qrAbs(dark*20 - total*10)
+	score += max(0, (deviation+total-1)/total-1) * 10
+	return score
+}
+
+func qrAbs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
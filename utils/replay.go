@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+)
+
+// Headers carrying the replay protection of a request, see VerifyRequestFreshness
+const (
+	HeaderRequestNonce     = "X-Request-Nonce"     // Unique per request, 16 to 128 of [A-Za-z0-9_-]
+	HeaderRequestTimestamp = "X-Request-Timestamp" // Unix seconds
+)
+
+// DefaultReplayMaxSkew is how far a request timestamp may drift from the
+// server clock when REPLAY_MAX_SKEW is not set
+const DefaultReplayMaxSkew = 5 * time.Minute
+
+var (
+	ErrReplayHeadersMissing = errors.New("request nonce and timestamp are required")
+	ErrInvalidRequestNonce  = errors.New("invalid request nonce")
+	ErrStaleRequest         = errors.New("request timestamp out of range")
+)
+
+const replayNonceRedisPrefix = "replay_nonce:"
+
+var requestNoncePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{16,128}$`)
+
+// ReplayMaxSkew returns the accepted clock skew from REPLAY_MAX_SKEW
+func ReplayMaxSkew() time.Duration {
+	if skew, err := time.ParseDuration(config.GetEnv("REPLAY_MAX_SKEW", "")); err == nil && skew > 0 {
+		return skew
+	}
+	return DefaultReplayMaxSkew
+}
+
+// VerifyRequestFreshness checks that timestamp lies within maxSkew of the
+// server clock and claims nonce for caller, returning ErrRequestReplayed when
+// caller already used it. Nonces are remembered for twice maxSkew, after which
+// their timestamp is stale anyway. Nothing ties the nonce to the request, so
+// this catches duplicate submissions; signed requests (VerifyRequestSignature)
+// are needed against deliberate replays.
+func VerifyRequestFreshness(ctx context.Context, caller, nonce, timestamp string, maxSkew time.Duration) error {
+	if nonce == "" || timestamp == "" {
+		return ErrReplayHeadersMissing
+	}
+	if !requestNoncePattern.MatchString(nonce) {
+		return ErrInvalidRequestNonce
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrStaleRequest, err)
+	}
+	skew := Now().Sub(time.Unix(seconds, 0))
+	if skew > maxSkew || skew < -maxSkew {
+		return ErrStaleRequest
+	}
+
+	return claimNonce(ctx, replayNonceRedisPrefix+caller+":"+nonce, 2*maxSkew)
+}
+
+// In-process nonce cache used when Redis is not configured
+var (
+	usedNonces     = map[string]time.Time{}
+	usedNoncesMu   sync.Mutex
+	noncesPrunedAt time.Time
+)
+
+// claimNonce records key as used for ttl, returning ErrRequestReplayed when it
+// already is. Redis is used when configured so that replays are caught across
+// instances.
+func claimNonce(ctx context.Context, key string, ttl time.Duration) error {
+	if config.Redis != nil {
+		claimed, err := config.Redis.SetNX(ctx, key, 1, ttl).Result()
+		if err != nil {
+			return fmt.Errorf("claim request nonce: %w", err)
+		}
+		if !claimed {
+			return ErrRequestReplayed
+		}
+		return nil
+	}
+
+	now := Now()
+	usedNoncesMu.Lock()
+	defer usedNoncesMu.Unlock()
+
+	if now.Sub(noncesPrunedAt) > time.Minute {
+		for cached, expires := range usedNonces {
+			if now.After(expires) {
+				delete(usedNonces, cached)
+			}
+		}
+		noncesPrunedAt = now
+	}
+
+	if expires, ok := usedNonces[key]; ok && now.Before(expires) {
+		return ErrRequestReplayed
+	}
+	usedNonces[key] = now.Add(ttl)
+	return nil
+}
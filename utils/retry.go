@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"context"
+
+	"github.com/praleedsuvarna/shared-libs/retry"
+)
+
+// RetryPolicy controls attempts, backoff, jitter and which errors are retried
+type RetryPolicy = retry.Policy
+
+// DefaultRetryPolicy makes three attempts starting with a 200ms delay
+var DefaultRetryPolicy = retry.DefaultPolicy
+
+// Retry calls fn until it succeeds, returns a non-retryable error, or policy is exhausted
+func Retry(ctx context.Context, policy RetryPolicy, fn func() error) error {
+	return retry.Do(ctx, policy, fn)
+}
+
+// PermanentError marks err so Retry returns it without further attempts
+func PermanentError(err error) error {
+	return retry.Permanent(err)
+}
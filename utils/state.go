@@ -0,0 +1,262 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/praleedsuvarna/shared-libs/config"
+)
+
+// Token formats: "<format>.<key ID>.<payload>[.<signature>]", all base64url
+const (
+	stateSealedFormat = "s1" // AES-GCM encrypted, unreadable by the client
+	stateSignedFormat = "h1" // HMAC-SHA256 signed, readable by the client
+)
+
+// maxStateCookieSize keeps state cookies under the 4KB browsers accept
+const maxStateCookieSize = 4000
+
+var (
+	ErrStateKeyNotConfigured = errors.New("state key is not configured")
+	ErrInvalidState          = errors.New("invalid state token")
+	ErrStateExpired          = errors.New("state token expired")
+	ErrStateTooLarge         = errors.New("state is too large for a cookie")
+)
+
+// stateEnvelope is what a token carries; Purpose binds it to one use, so an
+// OAuth state cannot be replayed as wizard progress
+type stateEnvelope struct {
+	Purpose   string          `json:"p"`
+	ExpiresAt int64           `json:"e"`
+	Data      json.RawMessage `json:"d"`
+}
+
+// StateKeys loads the keys of client-side state from the "state-keys" secret
+// (STATE_KEYS) as "id:base64key" entries. New tokens use STATE_KEY_ID; keep
+// retired keys listed until the tokens made with them have expired.
+func StateKeys() (map[string][]byte, error) {
+	return config.GetKeySet("state-keys", "STATE_KEYS")
+}
+
+// SealState encrypts v for storage on the client, e.g. in a cookie or URL,
+// valid for ttl and only for purpose. The client cannot read or alter it.
+//
+//	state, err := utils.SealState("oauth", OAuthState{Nonce: nonce, ReturnTo: returnTo}, 10*time.Minute)
+func SealState(purpose string, v interface{}, ttl time.Duration) (string, error) {
+	keyID, key, err := currentStateKey()
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := marshalState(purpose, v, ttl)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := stateAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(stateSealedFormat+"."+keyID))
+
+	return stateSealedFormat + "." + keyID + "." + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// SignState signs v for storage on the client, valid for ttl and only for
+// purpose. The client can read it but not alter it; use SealState for
+// anything it should not see.
+func SignState(purpose string, v interface{}, ttl time.Duration) (string, error) {
+	keyID, key, err := currentStateKey()
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := marshalState(purpose, v, ttl)
+	if err != nil {
+		return "", err
+	}
+
+	signed := stateSignedFormat + "." + keyID + "." + base64.RawURLEncoding.EncodeToString(plaintext)
+	signature, err := stateSignature(key, signed)
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// OpenState verifies a token made by SealState or SignState for purpose and
+// decodes its value into v. Tampered tokens, tokens of another purpose and
+// tokens of unknown keys are ErrInvalidState; old ones are ErrStateExpired.
+func OpenState(purpose, token string, v interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) < 3 {
+		return ErrInvalidState
+	}
+	format, keyID := parts[0], parts[1]
+
+	keys, err := StateKeys()
+	if err != nil {
+		return err
+	}
+	secret, ok := keys[keyID]
+	if !ok {
+		return fmt.Errorf("%w: unknown key %q", ErrInvalidState, keyID)
+	}
+
+	var plaintext []byte
+	switch {
+	case format == stateSealedFormat && len(parts) == 3:
+		sealed, err := base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			return ErrInvalidState
+		}
+		aead, err := stateAEAD(secret)
+		if err != nil {
+			return err
+		}
+		if len(sealed) < aead.NonceSize() {
+			return ErrInvalidState
+		}
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		plaintext, err = aead.Open(nil, nonce, ciphertext, []byte(format+"."+keyID))
+		if err != nil {
+			return ErrInvalidState
+		}
+	case format == stateSignedFormat && len(parts) == 4:
+		signature, err := base64.RawURLEncoding.DecodeString(parts[3])
+		if err != nil {
+			return ErrInvalidState
+		}
+		expected, err := stateSignature(secret, strings.Join(parts[:3], "."))
+		if err != nil {
+			return err
+		}
+		if !hmac.Equal(signature, expected) {
+			return ErrInvalidState
+		}
+		plaintext, err = base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			return ErrInvalidState
+		}
+	default:
+		return ErrInvalidState
+	}
+
+	var envelope stateEnvelope
+	if err := json.Unmarshal(plaintext, &envelope); err != nil {
+		return ErrInvalidState
+	}
+	if envelope.Purpose != purpose {
+		return fmt.Errorf("%w: issued for %q", ErrInvalidState, envelope.Purpose)
+	}
+	if Now().Unix() >= envelope.ExpiresAt {
+		return ErrStateExpired
+	}
+	return json.Unmarshal(envelope.Data, v)
+}
+
+// SetStateCookie seals v into an HTTP-only cookie that expires with it.
+// SameSite is Lax so the cookie survives redirects back from OAuth providers.
+func SetStateCookie(c *fiber.Ctx, name, purpose string, v interface{}, ttl time.Duration) error {
+	token, err := SealState(purpose, v, ttl)
+	if err != nil {
+		return err
+	}
+	if len(name)+len(token) > maxStateCookieSize {
+		return ErrStateTooLarge
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     name,
+		Value:    token,
+		Path:     "/",
+		Expires:  Now().Add(ttl),
+		Secure:   true,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	return nil
+}
+
+// ReadStateCookie opens the state cookie set by SetStateCookie into v
+func ReadStateCookie(c *fiber.Ctx, name, purpose string, v interface{}) error {
+	token := c.Cookies(name)
+	if token == "" {
+		return ErrInvalidState
+	}
+	return OpenState(purpose, token, v)
+}
+
+// ClearStateCookie removes a state cookie, e.g. once an OAuth flow completes
+func ClearStateCookie(c *fiber.Ctx, name string) {
+	c.Cookie(&fiber.Cookie{
+		Name:     name,
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		Secure:   true,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+func currentStateKey() (string, []byte, error) {
+	keys, err := StateKeys()
+	if err != nil {
+		return "", nil, err
+	}
+	keyID := config.GetEnv("STATE_KEY_ID", "")
+	key, ok := keys[keyID]
+	if !ok {
+		return "", nil, fmt.Errorf("%w: %q", ErrStateKeyNotConfigured, keyID)
+	}
+	if strings.Contains(keyID, ".") {
+		return "", nil, fmt.Errorf("%w: key ID %q must not contain dots", ErrStateKeyNotConfigured, keyID)
+	}
+	return keyID, key, nil
+}
+
+func marshalState(purpose string, v interface{}, ttl time.Duration) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(stateEnvelope{Purpose: purpose, ExpiresAt: Now().Add(ttl).Unix(), Data: data})
+}
+
+// stateAEAD derives the encryption key from a state secret, so secrets of any
+// length work and are never used for both encryption and signing
+func stateAEAD(secret []byte) (cipher.AEAD, error) {
+	key, err := hkdf.Key(sha256.New, secret, nil, "state-encryption", 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func stateSignature(secret []byte, signed string) ([]byte, error) {
+	key, err := hkdf.Key(sha256.New, secret, nil, "state-signing", 32)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(signed))
+	return mac.Sum(nil), nil
+}
@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Clock abstracts the current time so time-dependent code can be tested
+type Clock interface {
+	Now() time.Time
+}
+
+// RealClock reads the system clock
+type RealClock struct{}
+
+// Now returns the current system time
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// ManualClock is a Clock that only moves when told to; intended for tests
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewManualClock returns a ManualClock set to start
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+// Now returns the clock's current time
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set moves the clock to t
+func (c *ManualClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+var (
+	clock    Clock = RealClock{}
+	clockMux sync.RWMutex
+)
+
+// SetClock replaces the package clock and returns a function restoring the previous one
+func SetClock(c Clock) func() {
+	clockMux.Lock()
+	previous := clock
+	clock = c
+	clockMux.Unlock()
+
+	return func() {
+		clockMux.Lock()
+		clock = previous
+		clockMux.Unlock()
+	}
+}
+
+// Now returns the current time from the package clock
+func Now() time.Time {
+	clockMux.RLock()
+	defer clockMux.RUnlock()
+	return clock.Now()
+}
+
+// ParseTime parses RFC 3339 timestamps (with or without fractional seconds) and
+// plain YYYY-MM-DD dates, which are interpreted as midnight UTC
+func ParseTime(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: expected RFC 3339 or YYYY-MM-DD", value)
+}
+
+// FormatTime formats t as RFC 3339 in UTC, the format used in API responses
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// InTimezone converts t to the named IANA time zone, falling back to UTC
+func InTimezone(t time.Time, timezone string) time.Time {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return t.UTC()
+	}
+	return t.In(location)
+}
+
+// ToUserTime converts t to the time zone from the user's preferences
+func ToUserTime(t time.Time, userID string) time.Time {
+	return t.In(GetUserLocation(userID))
+}
+
+// BusinessHours describes the working window of a team or organization
+type BusinessHours struct {
+	Location  *time.Location
+	StartHour int // Inclusive, 0-23
+	EndHour   int // Exclusive, 1-24
+	Weekdays  []time.Weekday
+}
+
+// DefaultBusinessHours is 09:00-18:00 Monday to Friday in UTC
+var DefaultBusinessHours = BusinessHours{
+	Location:  time.UTC,
+	StartHour: 9,
+	EndHour:   18,
+	Weekdays:  []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+}
+
+// Contains reports whether t falls within business hours
+func (b BusinessHours) Contains(t time.Time) bool {
+	local := t.In(b.location())
+	return b.isWorkday(local.Weekday()) && local.Hour() >= b.StartHour && local.Hour() < b.EndHour
+}
+
+// Next returns t if it is within business hours, otherwise the start of the next window
+func (b BusinessHours) Next(t time.Time) time.Time {
+	if b.Contains(t) || len(b.Weekdays) == 0 {
+		return t
+	}
+
+	local := t.In(b.location())
+	day := time.Date(local.Year(), local.Month(), local.Day(), b.StartHour, 0, 0, 0, b.location())
+	if !local.Before(day) {
+		day = day.AddDate(0, 0, 1)
+	}
+	for !b.isWorkday(day.Weekday()) {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// AddBusinessDays adds n working days to t, keeping the time of day
+func (b BusinessHours) AddBusinessDays(t time.Time, n int) time.Time {
+	if len(b.Weekdays) == 0 {
+		return t
+	}
+
+	local := t.In(b.location())
+	for n > 0 {
+		local = local.AddDate(0, 0, 1)
+		if b.isWorkday(local.Weekday()) {
+			n--
+		}
+	}
+	return local
+}
+
+func (b BusinessHours) location() *time.Location {
+	if b.Location == nil {
+		return time.UTC
+	}
+	return b.Location
+}
+
+func (b BusinessHours) isWorkday(day time.Weekday) bool {
+	for _, weekday := range b.Weekdays {
+		if weekday == day {
+			return true
+		}
+	}
+	return false
+}
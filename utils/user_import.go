@@ -0,0 +1,378 @@
+package utils
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/praleedsuvarna/shared-libs/authz"
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxUserImportRows caps the rows of one import
+const MaxUserImportRows = 5000
+
+// Duplicate handling of user imports, for emails that are already members or
+// invited
+const (
+	ImportSkipDuplicates   = "skip"   // Leave the existing user untouched
+	ImportUpdateDuplicates = "update" // Update the name, and the role when the row has one, of existing members
+	ImportFailOnDuplicates = "fail"   // Import nothing when any row is a duplicate
+)
+
+// Row outcomes of a user import
+const (
+	ImportRowInvited = "invited"
+	ImportRowUpdated = "updated"
+	ImportRowSkipped = "skipped"
+	ImportRowInvalid = "invalid"
+	ImportRowFailed  = "failed"
+)
+
+// User import errors
+var (
+	ErrImportTooLarge   = fmt.Errorf("imports are limited to %d rows", MaxUserImportRows)
+	ErrImportDuplicates = errors.New("import contains existing users")
+	ErrImportFormat     = errors.New("invalid import file")
+)
+
+// UserImportRecord is one user of an import file
+type UserImportRecord struct {
+	Line  int    `json:"-"` // Line (CSV) or position (JSON) in the file
+	Email string `json:"email"`
+	Name  string `json:"name"`
+	Role  string `json:"role"`
+}
+
+// UserImportOptions controls an import into one organization
+type UserImportOptions struct {
+	OrganizationID   string
+	OrganizationName string // Shown in invitation emails
+	ImportedBy       string
+	ImporterRole     string // Rows may only grant roles the importer holds
+	DefaultRole      string // For rows without a role; default viewer
+	OnDuplicate      string // ImportSkipDuplicates (default), ImportUpdateDuplicates or ImportFailOnDuplicates
+	DryRun           bool   // Validate and report without inviting or updating
+}
+
+// UserImportRow is the outcome of one record
+type UserImportRow struct {
+	Line   int    `json:"line"`
+	Email  string `json:"email"`
+	Role   string `json:"role,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// UserImportReport summarizes an import
+type UserImportReport struct {
+	DryRun  bool            `json:"dry_run"`
+	Total   int             `json:"total"`
+	Invited int             `json:"invited"`
+	Updated int             `json:"updated"`
+	Skipped int             `json:"skipped"`
+	Invalid int             `json:"invalid"`
+	Failed  int             `json:"failed"`
+	Rows    []UserImportRow `json:"rows"`
+}
+
+func (r *UserImportReport) add(row UserImportRow) {
+	switch row.Status {
+	case ImportRowInvited:
+		r.Invited++
+	case ImportRowUpdated:
+		r.Updated++
+	case ImportRowSkipped:
+		r.Skipped++
+	case ImportRowInvalid:
+		r.Invalid++
+	case ImportRowFailed:
+		r.Failed++
+	}
+	r.Rows = append(r.Rows, row)
+}
+
+// ParseUserImportCSV reads records from a CSV file whose header names the
+// email, name and role columns in any order; other columns are ignored
+func ParseUserImportCSV(r io.Reader) ([]UserImportRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing header: %v", ErrImportFormat, err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, fmt.Errorf("%w: header has no email column", ErrImportFormat)
+	}
+	cell := func(row []string, column string) string {
+		if i, ok := columns[column]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var records []UserImportRecord
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrImportFormat, err)
+		}
+		if len(records) == MaxUserImportRows {
+			return nil, ErrImportTooLarge
+		}
+		records = append(records, UserImportRecord{
+			Line:  line,
+			Email: cell(row, "email"),
+			Name:  cell(row, "name"),
+			Role:  cell(row, "role"),
+		})
+	}
+}
+
+// ParseUserImportJSON reads records from a JSON array
+func ParseUserImportJSON(r io.Reader) ([]UserImportRecord, error) {
+	var records []UserImportRecord
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrImportFormat, err)
+	}
+	if len(records) > MaxUserImportRows {
+		return nil, ErrImportTooLarge
+	}
+	for i := range records {
+		records[i].Line = i + 1
+	}
+	return records, nil
+}
+
+// plannedImport is a validated record and what will be done with it
+type plannedImport struct {
+	record       UserImportRecord
+	row          UserImportRow
+	roleSupplied bool        // The row named a role, rather than taking the default
+	memberID     interface{} // _id of the existing member, for updates
+}
+
+// existingImport is a record's email already known to the organization
+type existingImport struct {
+	status   string      // "member" or "invited"
+	memberID interface{} // _id of the member
+	role     string      // Current role of the member
+}
+
+// ImportUsers invites the records into an organization. Every record is
+// validated first; invalid rows and duplicates within the file are reported
+// and skipped. Members and pending invitations are handled per OnDuplicate.
+// Invitation emails are sent for new users unless DryRun is set.
+func ImportUsers(ctx context.Context, records []UserImportRecord, options UserImportOptions) (*UserImportReport, error) {
+	if len(records) > MaxUserImportRows {
+		return nil, ErrImportTooLarge
+	}
+	if options.DefaultRole == "" {
+		options.DefaultRole = authz.RoleViewer
+	}
+	if options.OnDuplicate == "" {
+		options.OnDuplicate = ImportSkipDuplicates
+	}
+
+	existing, err := existingImportEmails(ctx, options.OrganizationID, records)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &UserImportReport{DryRun: options.DryRun, Total: len(records)}
+	planned := make([]plannedImport, 0, len(records))
+	seen := map[string]bool{}
+	duplicates := false
+	for _, record := range records {
+		record.Email = strings.ToLower(strings.TrimSpace(record.Email))
+		roleSupplied := record.Role != ""
+		if !roleSupplied {
+			record.Role = options.DefaultRole
+		}
+		row := UserImportRow{Line: record.Line, Email: record.Email, Role: record.Role}
+
+		if err := validateImportRecord(record, options); err != nil {
+			row.Status, row.Error = ImportRowInvalid, err.Error()
+			report.add(row)
+			continue
+		}
+		if seen[record.Email] {
+			row.Status, row.Error = ImportRowSkipped, "duplicate row in file"
+			report.add(row)
+			continue
+		}
+		seen[record.Email] = true
+
+		match := existing[record.Email]
+		switch match.status {
+		case "":
+			row.Status = ImportRowInvited
+		case "member":
+			duplicates = true
+			row.Status, row.Error = ImportRowSkipped, "already a member"
+			if options.OnDuplicate != ImportUpdateDuplicates {
+				break
+			}
+			if options.ImporterRole != "" && match.role != "" && !authz.HasRole(options.ImporterRole, match.role) {
+				// Members above the importer cannot be changed, not even downgraded
+				row.Error = fmt.Sprintf("member's role %q exceeds your own", match.role)
+				break
+			}
+			row.Status, row.Error = ImportRowUpdated, ""
+			if !roleSupplied {
+				row.Role = match.role
+			}
+		case "invited":
+			duplicates = true
+			row.Status, row.Error = ImportRowSkipped, "already invited"
+		}
+		planned = append(planned, plannedImport{record: record, row: row, roleSupplied: roleSupplied, memberID: match.memberID})
+	}
+
+	if options.OnDuplicate == ImportFailOnDuplicates && duplicates {
+		for _, plan := range planned {
+			if plan.row.Status == ImportRowInvited {
+				plan.row.Status, plan.row.Error = ImportRowSkipped, "import aborted"
+			}
+			report.add(plan.row)
+		}
+		return report, ErrImportDuplicates
+	}
+
+	for _, plan := range planned {
+		if !options.DryRun {
+			plan.row = applyImport(ctx, plan, options)
+		}
+		report.add(plan.row)
+	}
+
+	if !options.DryRun {
+		LogAuditContext(ctx, options.ImportedBy, "users_imported", options.OrganizationID, map[string]interface{}{
+			"total":   report.Total,
+			"invited": report.Invited,
+			"updated": report.Updated,
+			"skipped": report.Skipped,
+			"invalid": report.Invalid,
+			"failed":  report.Failed,
+		})
+	}
+	return report, nil
+}
+
+// validateImportRecord checks the email and that the importer may grant the role
+func validateImportRecord(record UserImportRecord, options UserImportOptions) error {
+	if record.Email == "" {
+		return errors.New("email is required")
+	}
+	if err := ValidateEmailSyntax(record.Email); err != nil {
+		return err
+	}
+	if _, ok := authz.GetRole(record.Role); !ok {
+		return fmt.Errorf("unknown role %q", record.Role)
+	}
+	if options.ImporterRole != "" && !authz.HasRole(options.ImporterRole, record.Role) {
+		return fmt.Errorf("role %q exceeds your own", record.Role)
+	}
+	return nil
+}
+
+// existingImportEmails maps the lowercased emails of records that are already
+// members or have a pending invitation. Emails are matched case-insensitively,
+// as stored addresses keep the case they were registered with.
+func existingImportEmails(ctx context.Context, organizationID string, records []UserImportRecord) (map[string]existingImport, error) {
+	emails := make(bson.A, 0, len(records))
+	for _, record := range records {
+		if email := strings.TrimSpace(record.Email); email != "" {
+			emails = append(emails, primitive.Regex{Pattern: "^" + regexp.QuoteMeta(email) + "$", Options: "i"})
+		}
+	}
+	existing := map[string]existingImport{}
+
+	cursor, err := config.GetCollection(InvitationsCollection).Find(ctx, bson.M{
+		"organization_id": organizationID,
+		"email":           bson.M{"$in": emails},
+		"status":          models.InvitationPending,
+		"expires_at":      bson.M{"$gt": Now()},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load invitations: %w", err)
+	}
+	var invitations []models.Invitation
+	if err := cursor.All(ctx, &invitations); err != nil {
+		return nil, err
+	}
+	for _, invitation := range invitations {
+		existing[strings.ToLower(invitation.Email)] = existingImport{status: "invited"}
+	}
+
+	cursor, err = config.GetCollection(UsersDirectory.Collection).Find(ctx, bson.M{
+		"organization_id": organizationID,
+		"email":           bson.M{"$in": emails},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load users: %w", err)
+	}
+	var users []bson.M
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		if email, ok := user["email"].(string); ok {
+			match := existingImport{status: "member", memberID: user["_id"]}
+			if UsersDirectory.RoleField != "" {
+				match.role, _ = user[UsersDirectory.RoleField].(string)
+			}
+			existing[strings.ToLower(email)] = match
+		}
+	}
+	return existing, nil
+}
+
+// applyImport invites or updates one planned record
+func applyImport(ctx context.Context, plan plannedImport, options UserImportOptions) UserImportRow {
+	row := plan.row
+	switch row.Status {
+	case ImportRowInvited:
+		invitation, err := CreateInvitation(options.OrganizationID, options.OrganizationName, plan.record.Email, plan.record.Role, options.ImportedBy)
+		if err != nil && invitation == nil {
+			row.Status, row.Error = ImportRowFailed, err.Error()
+		} else if err != nil {
+			row.Error = "invitation created but email failed; resend it"
+		}
+	case ImportRowUpdated:
+		set := bson.M{"updated_at": Now()}
+		if UsersDirectory.RoleField != "" && plan.roleSupplied {
+			set[UsersDirectory.RoleField] = plan.record.Role
+		}
+		if plan.record.Name != "" {
+			set["name"] = plan.record.Name
+		}
+		result, err := config.GetCollection(UsersDirectory.Collection).UpdateOne(ctx,
+			bson.M{"organization_id": options.OrganizationID, "_id": plan.memberID},
+			bson.M{"$set": set},
+		)
+		if err != nil {
+			row.Status, row.Error = ImportRowFailed, err.Error()
+		} else if result.MatchedCount == 0 {
+			row.Status, row.Error = ImportRowFailed, "member no longer exists"
+		}
+	}
+	return row
+}
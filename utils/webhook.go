@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrUnsafeWebhookURL is returned for webhook URLs that are not https or that
+// point into a private network
+var ErrUnsafeWebhookURL = errors.New("webhook URL must use https and a public host")
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), private in practice
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// webhookClient delivers tenant-configured webhooks. It dials public
+// addresses only, checked after DNS resolution so a host cannot be
+// re-pointed at an internal service, and does not follow redirects.
+var webhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: rejectPrivateAddress,
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// ValidateWebhookURL checks that a tenant-supplied webhook URL uses https and
+// does not name a private, loopback or link-local host
+func ValidateWebhookURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Scheme != "https" || parsed.Hostname() == "" || parsed.User != nil {
+		return ErrUnsafeWebhookURL
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".internal") {
+		return ErrUnsafeWebhookURL
+	}
+	if ip := net.ParseIP(host); ip != nil && !isPublicIP(ip) {
+		return ErrUnsafeWebhookURL
+	}
+	return nil
+}
+
+// rejectPrivateAddress refuses connections to addresses that are not public
+func rejectPrivateAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrUnsafeWebhookURL, host)
+	}
+	return nil
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() &&
+		!sharedAddressSpace.Contains(ip)
+}
@@ -0,0 +1,298 @@
+// Package watchdog watches the heap and goroutine count of a long-running
+// process against limits, to catch slow leaks such as those of media
+// workers. Breaches are logged with the largest allocation sites and the most
+// common goroutine stacks; once a breach is sustained the watchdog can ask
+// the process to restart gracefully:
+//
+//	options, err := watchdog.OptionsFromEnv()
+//	stop := watchdog.Start(options)
+//	defer stop()
+//
+// The app package starts it when WATCHDOG_HEAP_LIMIT_MB or
+// WATCHDOG_GOROUTINE_LIMIT is set.
+package watchdog
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/praleedsuvarna/shared-libs/config"
+	"github.com/praleedsuvarna/shared-libs/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var breaches = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "watchdog_breaches_total",
+	Help: "Total number of watchdog checks that found a resource over its limit, by resource.",
+}, []string{"resource"})
+
+// Resources the watchdog checks
+const (
+	ResourceHeap       = "heap"
+	ResourceGoroutines = "goroutines"
+)
+
+// Options configures Start
+type Options struct {
+	HeapLimit      uint64        // Bytes of live heap; zero disables the check
+	GoroutineLimit int           // Zero disables the check
+	Interval       time.Duration // Default 30s
+	// Sustain is how many consecutive checks a limit must be exceeded before
+	// the process is restarted, so a burst of work does not restart it; default 3
+	Sustain int
+	Restart bool // Restart once a breach is sustained; otherwise only log
+	// OnRestart restarts the process; the default sends it SIGTERM, which
+	// app.Run answers with a graceful shutdown and the orchestrator with a
+	// fresh instance
+	OnRestart func(reason string)
+	TopN      int // Allocation sites and goroutine stacks logged; default 10
+}
+
+// OptionsFromEnv reads WATCHDOG_HEAP_LIMIT_MB, WATCHDOG_GOROUTINE_LIMIT,
+// WATCHDOG_INTERVAL, WATCHDOG_SUSTAIN and WATCHDOG_RESTART
+func OptionsFromEnv() (Options, error) {
+	var options Options
+	if value := config.GetEnv("WATCHDOG_HEAP_LIMIT_MB", ""); value != "" {
+		megabytes, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return options, fmt.Errorf("invalid WATCHDOG_HEAP_LIMIT_MB: %w", err)
+		}
+		options.HeapLimit = megabytes << 20
+	}
+	if value := config.GetEnv("WATCHDOG_GOROUTINE_LIMIT", ""); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return options, fmt.Errorf("invalid WATCHDOG_GOROUTINE_LIMIT: %w", err)
+		}
+		options.GoroutineLimit = limit
+	}
+	if value := config.GetEnv("WATCHDOG_INTERVAL", ""); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return options, fmt.Errorf("invalid WATCHDOG_INTERVAL: %w", err)
+		}
+		options.Interval = interval
+	}
+	if value := config.GetEnv("WATCHDOG_SUSTAIN", ""); value != "" {
+		sustain, err := strconv.Atoi(value)
+		if err != nil {
+			return options, fmt.Errorf("invalid WATCHDOG_SUSTAIN: %w", err)
+		}
+		options.Sustain = sustain
+	}
+	options.Restart = config.GetEnv("WATCHDOG_RESTART", "") == "true"
+	return options, nil
+}
+
+// Site is a place in the code holding memory or goroutines
+type Site struct {
+	Stack string `json:"stack"` // Innermost function first, outside the runtime
+	Count int64  `json:"count"` // Objects or goroutines
+	Bytes int64  `json:"bytes,omitempty"`
+}
+
+// Start checks the process every Interval until the returned function is called
+func Start(options Options) func() {
+	if options.Interval <= 0 {
+		options.Interval = 30 * time.Second
+	}
+	if options.Sustain <= 0 {
+		options.Sustain = 3
+	}
+	if options.TopN <= 0 {
+		options.TopN = 10
+	}
+	if options.OnRestart == nil {
+		options.OnRestart = signalRestart
+	}
+
+	stop := make(chan struct{})
+	var stopOnce, restartOnce sync.Once
+
+	go func() {
+		ticker := time.NewTicker(options.Interval)
+		defer ticker.Stop()
+
+		overHeap, overGoroutines := 0, 0
+		for {
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+
+			var memory runtime.MemStats
+			runtime.ReadMemStats(&memory)
+			goroutines := runtime.NumGoroutine()
+
+			reason := ""
+			if options.HeapLimit > 0 && memory.HeapAlloc > options.HeapLimit {
+				overHeap++
+				breaches.WithLabelValues(ResourceHeap).Inc()
+				utils.Logger.Warn("heap above watchdog limit",
+					"heap_alloc_bytes", memory.HeapAlloc,
+					"limit_bytes", options.HeapLimit,
+					"consecutive", overHeap,
+					"top_allocations", TopAllocations(options.TopN))
+				if overHeap >= options.Sustain {
+					reason = fmt.Sprintf("heap %d MB above %d MB for %d checks", memory.HeapAlloc>>20, options.HeapLimit>>20, overHeap)
+				}
+			} else {
+				overHeap = 0
+			}
+			if options.GoroutineLimit > 0 && goroutines > options.GoroutineLimit {
+				overGoroutines++
+				breaches.WithLabelValues(ResourceGoroutines).Inc()
+				utils.Logger.Warn("goroutines above watchdog limit",
+					"goroutines", goroutines,
+					"limit", options.GoroutineLimit,
+					"consecutive", overGoroutines,
+					"top_stacks", TopGoroutines(options.TopN))
+				if overGoroutines >= options.Sustain {
+					reason = fmt.Sprintf("%d goroutines above %d for %d checks", goroutines, options.GoroutineLimit, overGoroutines)
+				}
+			} else {
+				overGoroutines = 0
+			}
+
+			if reason != "" && options.Restart {
+				restartOnce.Do(func() {
+					utils.Logger.Error("watchdog restarting process", "reason", reason)
+					options.OnRestart(reason)
+				})
+			}
+		}
+	}()
+
+	log.Printf("🐕 Watchdog started (heap limit %d MB, goroutine limit %d)", options.HeapLimit>>20, options.GoroutineLimit)
+	return func() {
+		stopOnce.Do(func() { close(stop) })
+	}
+}
+
+// signalRestart asks the process to shut down gracefully
+func signalRestart(reason string) {
+	process, err := os.FindProcess(os.Getpid())
+	if err == nil {
+		err = process.Signal(syscall.SIGTERM)
+	}
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Watchdog failed to signal restart (%s): %v", reason, err))
+	}
+}
+
+// TopAllocations returns the sites holding the most live heap, estimated from
+// the runtime's sampled heap profile
+func TopAllocations(n int) []Site {
+	records := make([]runtime.MemProfileRecord, 256)
+	for {
+		count, ok := runtime.MemProfile(records, false)
+		if ok {
+			records = records[:count]
+			break
+		}
+		records = make([]runtime.MemProfileRecord, count+64)
+	}
+
+	rate := int64(runtime.MemProfileRate)
+	sites := map[string]*Site{}
+	for _, record := range records {
+		objects, bytes := scaleHeapSample(record.InUseObjects(), record.InUseBytes(), rate)
+		if bytes <= 0 {
+			continue
+		}
+		stack := describeStack(record.Stack())
+		site, ok := sites[stack]
+		if !ok {
+			site = &Site{Stack: stack}
+			sites[stack] = site
+		}
+		site.Count += objects
+		site.Bytes += bytes
+	}
+	return top(sites, n, func(a, b *Site) bool { return a.Bytes > b.Bytes })
+}
+
+// TopGoroutines returns the most common goroutine stacks
+func TopGoroutines(n int) []Site {
+	records := make([]runtime.StackRecord, runtime.NumGoroutine()+64)
+	for {
+		count, ok := runtime.GoroutineProfile(records)
+		if ok {
+			records = records[:count]
+			break
+		}
+		records = make([]runtime.StackRecord, count+64)
+	}
+
+	sites := map[string]*Site{}
+	for _, record := range records {
+		stack := describeStack(record.Stack())
+		site, ok := sites[stack]
+		if !ok {
+			site = &Site{Stack: stack}
+			sites[stack] = site
+		}
+		site.Count++
+	}
+	return top(sites, n, func(a, b *Site) bool { return a.Count > b.Count })
+}
+
+func top(sites map[string]*Site, n int, less func(a, b *Site) bool) []Site {
+	sorted := make([]Site, 0, len(sites))
+	for _, site := range sites {
+		sorted = append(sorted, *site)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return less(&sorted[i], &sorted[j]) })
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// describeStack names the three innermost frames outside the runtime, e.g.
+// "transcode.process (transcode.go:180) < messaging.(*Bus).handle (...)"
+func describeStack(pcs []uintptr) string {
+	frames := runtime.CallersFrames(pcs)
+	var described []string
+	for len(described) < 3 {
+		frame, more := frames.Next()
+		if frame.Function != "" && !strings.HasPrefix(frame.Function, "runtime.") {
+			function := frame.Function[strings.LastIndex(frame.Function, "/")+1:]
+			file := frame.File[strings.LastIndex(frame.File, "/")+1:]
+			described = append(described, fmt.Sprintf("%s (%s:%d)", function, file, frame.Line))
+		}
+		if !more {
+			break
+		}
+	}
+	if len(described) == 0 {
+		return "runtime"
+	}
+	return strings.Join(described, " < ")
+}
+
+// scaleHeapSample estimates the actual objects and bytes behind a sampled heap
+// profile record, as pprof does: allocations of average size s are sampled
+// with probability 1-exp(-s/rate)
+func scaleHeapSample(count, size, rate int64) (int64, int64) {
+	if count == 0 || size == 0 {
+		return 0, 0
+	}
+	if rate <= 1 {
+		return count, size
+	}
+	average := float64(size) / float64(count)
+	scale := 1 / (1 - math.Exp(-average/float64(rate)))
+	return int64(float64(count) * scale), int64(float64(size) * scale)
+}